@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"time"
+)
+
+const defaultOperationTimeoutSeconds = 30
+
+// IsUnhealthy returns TRUE if the environment has been probed and the last probe failed. Environments that were never
+// probed are not considered unhealthy.
+func (r *EnvironmentStatus) IsUnhealthy() bool {
+	return meta.IsStatusConditionFalse(r.Conditions, EnvironmentConditionReady)
+}
+
+// GetReadyMessage returns the message of the Ready condition
+func (r *EnvironmentStatus) GetReadyMessage() string {
+	condition := meta.FindStatusCondition(r.Conditions, EnvironmentConditionReady)
+	if condition == nil {
+		return ""
+	}
+
+	return condition.Message
+}
+
+// SetProbeResult records the result of a probe in the status
+func (r *EnvironmentStatus) SetProbeResult(serverVersion string, err error, now metav1.Time, generation int64) {
+	r.LastProbeTime = &now
+
+	if err != nil {
+		r.LastError = err.Error()
+		meta.SetStatusCondition(&r.Conditions, metav1.Condition{
+			Type:               EnvironmentConditionReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             EnvironmentReasonProbeFailed,
+			Message:            err.Error(),
+			ObservedGeneration: generation,
+			LastTransitionTime: now,
+		})
+		return
+	}
+
+	r.LastError = ""
+	r.ServerVersion = serverVersion
+	meta.SetStatusCondition(&r.Conditions, metav1.Condition{
+		Type:               EnvironmentConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             EnvironmentReasonProbeSucceeded,
+		Message:            "The server is reachable and the credentials are valid",
+		ObservedGeneration: generation,
+		LastTransitionTime: now,
+	})
+}
+
+// IsInMaintenance returns TRUE if the databases in the environment must not be reconciled
+func (r EnvironmentMode) IsInMaintenance() bool {
+	return r == EnvironmentModeMaintenance
+}
+
+// AcceptsNewDatabases returns TRUE if new site databases can be placed in the environment. An empty mode is active.
+func (r EnvironmentMode) AcceptsNewDatabases() bool {
+	return r != EnvironmentModeMaintenance && r != EnvironmentModeDraining
+}
+
+func (r MongoConfigSpec) GetOperationTimeout() time.Duration {
+	return getOperationTimeout(r.OperationTimeoutSeconds)
+}
+
+// IsSameServer returns TRUE if both configs point to the same mongo server. Only the first host is used for the
+// connections, so only that is compared
+func (r MongoConfigSpec) IsSameServer(other MongoConfigSpec) bool {
+	return strings.EqualFold(r.Host1, other.Host1) && r.Port == other.Port
+}
+
+func (r RedisConfigSpec) GetOperationTimeout() time.Duration {
+	return getOperationTimeout(r.OperationTimeoutSeconds)
+}
+
+func getOperationTimeout(seconds int32) time.Duration {
+	if seconds <= 0 {
+		return defaultOperationTimeoutSeconds * time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EnvironmentConditionReady is TRUE if the last probe could connect and authenticate to the server
+	EnvironmentConditionReady = "Ready"
+
+	EnvironmentReasonProbeSucceeded = "ProbeSucceeded"
+	EnvironmentReasonProbeFailed    = "ProbeFailed"
+)
+
+// +kubebuilder:validation:Enum=Active;Maintenance;Draining
+type EnvironmentMode string
+
+const (
+	// EnvironmentModeActive is the normal operation of the environment
+	EnvironmentModeActive EnvironmentMode = "Active"
+	// EnvironmentModeMaintenance denies new sites on the environment and pauses the reconciliation of its databases
+	EnvironmentModeMaintenance EnvironmentMode = "Maintenance"
+	// EnvironmentModeDraining denies new sites on the environment and excludes it from pool placement, while the
+	// existing databases are still reconciled, so they can be moved away
+	EnvironmentModeDraining EnvironmentMode = "Draining"
+)
+
+// EnvironmentStatus is the observed state of an environment server, shared by all environment config types
+type EnvironmentStatus struct {
+	// The conditions of the environment
+	//+optional
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The version reported by the server
+	//+optional
+	ServerVersion string `json:"serverVersion,omitempty"`
+
+	// The number of site databases provisioned in this environment
+	//+optional
+	ProvisionedDatabaseCount int32 `json:"provisionedDatabaseCount,omitempty"`
+
+	// The time of the last probe that changed the status. Probes that don't change anything else are not written
+	//+optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+
+	// The error of the last failed probe. Empty if the last probe succeeded
+	//+optional
+	LastError string `json:"lastError,omitempty"`
+}
@@ -51,21 +51,67 @@ type MongoConfigSpec struct {
 	// The port for the server - defaults to 27017
 	//+optional
 	Port uint16 `json:"port,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=30
+	// The maximum duration of a single operation on the server in seconds, including connecting to it. Operations
+	// that time out are retried with backoff - defaults to 30
+	//+optional
+	OperationTimeoutSeconds int32 `json:"operationTimeoutSeconds,omitempty"`
+
+	// Configures the periodic removal of orphaned databases and users from the server
+	//+optional
+	OrphanSweeper *OrphanSweeperSpec `json:"orphanSweeper,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	// The maximum number of sites that can be placed on this environment by a pool. 0 means unlimited. Sites
+	// explicitly referencing the environment are not limited, but are counted
+	//+optional
+	MaxSites int32 `json:"maxSites,omitempty"`
+
+	//+kubebuilder:default:=Active
+	// The operating mode of the environment. Maintenance denies new sites and pauses the reconciliation of the
+	// databases, while Draining denies new sites and excludes the environment from pools - defaults to Active
+	//+optional
+	Mode EnvironmentMode `json:"mode,omitempty"`
+}
+
+// MongoConfigStatus defines the observed state of MongoConfig
+type MongoConfigStatus struct {
+	EnvironmentStatus `json:",inline"`
+
+	// The time of the last completed orphan sweep
+	//+optional
+	LastOrphanSweepTime *metav1.Time `json:"lastOrphanSweepTime,omitempty"`
+
+	// The orphaned databases and users found by the last sweep
+	//+optional
+	Orphans []OrphanedResource `json:"orphans,omitempty"`
+
+	// The databases and users of the database tasks of this environment, recorded while the orphan sweeper is enabled.
+	// Only these are ever reported as orphans
+	//+optional
+	ManagedResources *ManagedResources `json:"managedResources,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Host1",type=string,JSONPath=`.spec.host1`
 //+kubebuilder:printcolumn:name="Host2",type=string,JSONPath=`.spec.host2`
 //+kubebuilder:printcolumn:name="Host3",type=string,JSONPath=`.spec.host3`
 //+kubebuilder:printcolumn:name="Port",type=string,JSONPath=`.spec.port`
 //+kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+//+kubebuilder:printcolumn:name="Databases",type=integer,JSONPath=`.status.provisionedDatabaseCount`
 
 // MongoConfig is the Schema for the mongoconfigs API
 type MongoConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec MongoConfigSpec `json:"spec,omitempty"`
+	Spec   MongoConfigSpec   `json:"spec,omitempty"`
+	Status MongoConfigStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
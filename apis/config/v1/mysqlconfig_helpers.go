@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	defaultMysqlConnectTimeoutSeconds = 10
+	defaultMysqlReadTimeoutSeconds    = 30
+)
+
+func (r MysqlConfigSpec) GetTlsMode() MysqlTlsMode {
+	if r.TlsMode == "" {
+		return MysqlTlsModeDisabled
+	}
+	return r.TlsMode
+}
+
+func (r MysqlConfigSpec) GetConnectTimeout() time.Duration {
+	if r.ConnectTimeoutSeconds <= 0 {
+		return defaultMysqlConnectTimeoutSeconds * time.Second
+	}
+	return time.Duration(r.ConnectTimeoutSeconds) * time.Second
+}
+
+func (r MysqlConfigSpec) GetReadTimeout() time.Duration {
+	if r.ReadTimeoutSeconds <= 0 {
+		return defaultMysqlReadTimeoutSeconds * time.Second
+	}
+	return time.Duration(r.ReadTimeoutSeconds) * time.Second
+}
+
+func (r MysqlConfigSpec) GetOperationTimeout() time.Duration {
+	return getOperationTimeout(r.OperationTimeoutSeconds)
+}
+
+// IsSameServer returns TRUE if both configs point to the same mysql server
+func (r MysqlConfigSpec) IsSameServer(other MysqlConfigSpec) bool {
+	return strings.EqualFold(r.Host, other.Host) && r.Port == other.Port
+}
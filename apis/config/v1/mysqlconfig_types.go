@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -41,19 +42,120 @@ type MysqlConfigSpec struct {
 	// The port for the server - defaults to 3306
 	//+optional
 	Port uint16 `json:"port,omitempty"`
+
+	//+kubebuilder:default:=Disabled
+	// The TLS mode to use when connecting to the server. Defaults to Disabled
+	//+optional
+	TlsMode MysqlTlsMode `json:"tlsMode,omitempty"`
+
+	// A key in a secret in the same namespace holding the PEM encoded CA certificate to verify the server certificate
+	// with. Only used by the VerifyCA and VerifyIdentity TLS modes. The system CA pool is used if not set
+	//+optional
+	TlsCaSecret *corev1.SecretKeySelector `json:"tlsCaSecret,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=10
+	// The timeout for establishing a connection to the server in seconds - defaults to 10
+	//+optional
+	ConnectTimeoutSeconds int32 `json:"connectTimeoutSeconds,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=30
+	// The timeout for reading a response from the server in seconds - defaults to 30
+	//+optional
+	ReadTimeoutSeconds int32 `json:"readTimeoutSeconds,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=30
+	// The maximum duration of a single operation on the server in seconds, including connecting to it. Operations
+	// that time out are retried with backoff - defaults to 30
+	//+optional
+	OperationTimeoutSeconds int32 `json:"operationTimeoutSeconds,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]*$`
+	// The character set for newly created databases. The server default is used if not set
+	//+optional
+	DefaultCharacterSet string `json:"defaultCharacterSet,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]*$`
+	// The collation for newly created databases. The server default is used if not set
+	//+optional
+	DefaultCollation string `json:"defaultCollation,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]*$`
+	// The authentication plugin of the created users. When set, the password of the users with a different plugin is
+	// reset with this plugin. The server default is used if not set
+	//+optional
+	AuthenticationPlugin string `json:"authenticationPlugin,omitempty"`
+
+	// Configures the periodic removal of orphaned databases and users from the server
+	//+optional
+	OrphanSweeper *OrphanSweeperSpec `json:"orphanSweeper,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	// The maximum number of sites that can be placed on this environment by a pool. 0 means unlimited. Sites
+	// explicitly referencing the environment are not limited, but are counted
+	//+optional
+	MaxSites int32 `json:"maxSites,omitempty"`
+
+	//+kubebuilder:default:=Active
+	// The operating mode of the environment. Maintenance denies new sites and pauses the reconciliation of the
+	// databases, while Draining denies new sites and excludes the environment from pools - defaults to Active
+	//+optional
+	Mode EnvironmentMode `json:"mode,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Disabled;Preferred;Required;VerifyCA;VerifyIdentity
+type MysqlTlsMode string
+
+const (
+	// MysqlTlsModeDisabled uses unencrypted connections
+	MysqlTlsModeDisabled MysqlTlsMode = "Disabled"
+	// MysqlTlsModePreferred uses TLS if the server supports it, without verifying the server certificate
+	MysqlTlsModePreferred MysqlTlsMode = "Preferred"
+	// MysqlTlsModeRequired requires TLS, but doesn't verify the server certificate
+	MysqlTlsModeRequired MysqlTlsMode = "Required"
+	// MysqlTlsModeVerifyCA requires TLS and verifies the server certificate against the CA, but not the hostname
+	MysqlTlsModeVerifyCA MysqlTlsMode = "VerifyCA"
+	// MysqlTlsModeVerifyIdentity requires TLS and verifies both the server certificate and the hostname
+	MysqlTlsModeVerifyIdentity MysqlTlsMode = "VerifyIdentity"
+)
+
+// MysqlConfigStatus defines the observed state of MysqlConfig
+type MysqlConfigStatus struct {
+	EnvironmentStatus `json:",inline"`
+
+	// The time of the last completed orphan sweep
+	//+optional
+	LastOrphanSweepTime *metav1.Time `json:"lastOrphanSweepTime,omitempty"`
+
+	// The orphaned databases and users found by the last sweep
+	//+optional
+	Orphans []OrphanedResource `json:"orphans,omitempty"`
+
+	// The databases and users of the database tasks of this environment, recorded while the orphan sweeper is enabled.
+	// Only these are ever reported as orphans
+	//+optional
+	ManagedResources *ManagedResources `json:"managedResources,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.spec.host`
 //+kubebuilder:printcolumn:name="Port",type=string,JSONPath=`.spec.port`
 //+kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
+//+kubebuilder:printcolumn:name="TLS-Mode",type=string,JSONPath=`.spec.tlsMode`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+//+kubebuilder:printcolumn:name="Databases",type=integer,JSONPath=`.status.provisionedDatabaseCount`
 
 // MysqlConfig is the Schema for the mysqlconfigs API
 type MysqlConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec MysqlConfigSpec `json:"spec,omitempty"`
+	Spec   MysqlConfigSpec   `json:"spec,omitempty"`
+	Status MysqlConfigStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
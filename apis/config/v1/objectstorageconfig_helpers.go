@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"time"
+)
+
+func (r ObjectStorageConfigSpec) GetOperationTimeout() time.Duration {
+	return getOperationTimeout(r.OperationTimeoutSeconds)
+}
+
+// IsTlsEnabled returns TRUE unless TLS is explicitly disabled
+func (r ObjectStorageConfigSpec) IsTlsEnabled() bool {
+	return r.UseTls == nil || *r.UseTls
+}
+
+// GetEndpointUrl returns the URL of the endpoint including the scheme
+func (r ObjectStorageConfigSpec) GetEndpointUrl() string {
+	if r.IsTlsEnabled() {
+		return "https://" + r.Endpoint
+	}
+
+	return "http://" + r.Endpoint
+}
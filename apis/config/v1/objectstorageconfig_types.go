@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ObjectStorageConfigSpec defines the desired state of ObjectStorageConfig
+type ObjectStorageConfigSpec struct {
+	//+kubebuilder:validation:MinLength=1
+	// The host and optional port of the MinIO endpoint, without the scheme (eg. minio.storage:9000). The access keys of
+	// the sites are managed with the MinIO admin API
+	Endpoint string `json:"endpoint"`
+
+	//+kubebuilder:default:=true
+	// Whether to connect to the endpoint over HTTPS - defaults to true
+	//+optional
+	UseTls *bool `json:"useTls,omitempty"`
+
+	// The region of the buckets. Left empty for backends that don't use regions
+	//+optional
+	Region string `json:"region,omitempty"`
+
+	// Whether to use path style requests (https://host/bucket) instead of virtual host style requests
+	// (https://bucket.host). Most self-hosted backends, including MinIO, need path style requests
+	//+optional
+	UsePathStyle bool `json:"usePathStyle,omitempty"`
+
+	// The key in a secret in the same namespace holding the access key of the admin credentials. The admin user must be
+	// allowed to manage the buckets and the service accounts used as the access keys of the sites
+	AccessKeySecret corev1.SecretKeySelector `json:"accessKeySecret"`
+
+	// The key in a secret in the same namespace holding the secret key of the admin credentials
+	SecretKeySecret corev1.SecretKeySelector `json:"secretKeySecret"`
+
+	//+kubebuilder:validation:Pattern=`^[a-z0-9][-a-z0-9]*$`
+	// Prefix of the names of the buckets created for the sites
+	//+optional
+	BucketNamePrefix string `json:"bucketNamePrefix,omitempty"`
+
+	// If set, the sites get a <site>/<service>/ prefix in this existing bucket instead of a bucket of their own
+	//+optional
+	SharedBucket string `json:"sharedBucket,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=30
+	// The maximum duration of a single operation on the endpoint in seconds. Operations that time out are retried
+	// with backoff - defaults to 30
+	//+optional
+	OperationTimeoutSeconds int32 `json:"operationTimeoutSeconds,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.spec.endpoint`
+//+kubebuilder:printcolumn:name="Shared-Bucket",type=string,JSONPath=`.spec.sharedBucket`
+
+// ObjectStorageConfig is the Schema for the objectstorageconfigs API
+type ObjectStorageConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ObjectStorageConfigSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ObjectStorageConfigList contains a list of ObjectStorageConfig
+type ObjectStorageConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ObjectStorageConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ObjectStorageConfig{}, &ObjectStorageConfigList{})
+}
@@ -0,0 +1,37 @@
+package v1
+
+import "time"
+
+func (r *OrphanSweeperSpec) IsEnabled() bool {
+	return r != nil && r.Enabled
+}
+
+func (r *OrphanSweeperSpec) GetInterval() time.Duration {
+	if r == nil || r.IntervalMinutes <= 0 {
+		return time.Hour
+	}
+
+	return time.Duration(r.IntervalMinutes) * time.Minute
+}
+
+func (r *OrphanSweeperSpec) GetGracePeriod() time.Duration {
+	if r == nil || r.GracePeriodMinutes <= 0 {
+		return 24 * time.Hour
+	}
+
+	return time.Duration(r.GracePeriodMinutes) * time.Minute
+}
+
+func (r *OrphanSweeperSpec) IsExcluded(name string) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, v := range r.ExcludedNames {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OrphanSweeperSpec configures the periodic search for databases and users on an environment server that were created
+// by the operator, but don't belong to any database task anymore
+type OrphanSweeperSpec struct {
+	// Enables the orphan sweeper for this environment
+	//+optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=60
+	// The interval between sweeps in minutes - defaults to 60
+	//+optional
+	IntervalMinutes int32 `json:"intervalMinutes,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=1440
+	// The time in minutes an orphan has to be continuously detected for before it's dropped - defaults to 1440 (1 day)
+	//+optional
+	GracePeriodMinutes int32 `json:"gracePeriodMinutes,omitempty"`
+
+	// If true, orphans are only reported in the status and metrics, never dropped
+	//+optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Database and user names that are never reported or dropped, even if they were created by the operator
+	//+optional
+	ExcludedNames []string `json:"excludedNames,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=Database;User
+type OrphanedResourceType string
+
+const (
+	OrphanedResourceTypeDatabase OrphanedResourceType = "Database"
+	OrphanedResourceTypeUser     OrphanedResourceType = "User"
+)
+
+// OrphanedResource is a database or user found on the server without a database task referencing it
+type OrphanedResource struct {
+	// The type of the resource
+	Type OrphanedResourceType `json:"type"`
+
+	// The name of the database or user
+	Name string `json:"name"`
+
+	// The time the orphan was first detected
+	FirstSeen metav1.Time `json:"firstSeen"`
+
+	// Set for the names that match the naming scheme of the operator, but were never recorded as created by it. These
+	// orphans are only reported and never dropped
+	//+optional
+	ReportOnly bool `json:"reportOnly,omitempty"`
+}
+
+// ManagedResources are the databases and users the operator created on an environment server for its database tasks
+type ManagedResources struct {
+	// The names of the databases
+	//+optional
+	Databases []string `json:"databases,omitempty"`
+
+	// The names of the users
+	//+optional
+	Users []string `json:"users,omitempty"`
+}
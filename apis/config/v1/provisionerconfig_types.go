@@ -0,0 +1,62 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ProvisionerConfigSpec defines the desired state of ProvisionerConfig
+type ProvisionerConfigSpec struct {
+	// The spec of the pod provisioning the backing service for a site, templated with the values of the site and the
+	// service using it, plus service.name. The pod may write its outputs to its termination message as a JSON object or
+	// as key=value lines, which become the provisioner.<name>.<key> template values of the service
+	ProvisionPodSpec corev1.PodSpec `json:"provisionPodSpec"`
+
+	// The spec of the pod removing the backing service when the site or the service is deleted. As the site may no
+	// longer exist, only the site.name, service.name and provisioner.<name>.<key> template values are available. If
+	// not set, nothing is run on deletion
+	//+optional
+	DeprovisionPodSpec *corev1.PodSpec `json:"deprovisionPodSpec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProvisionerConfig is the Schema for the provisionerconfigs API
+type ProvisionerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProvisionerConfigSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProvisionerConfigList contains a list of ProvisionerConfig
+type ProvisionerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProvisionerConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProvisionerConfig{}, &ProvisionerConfigList{})
+}
@@ -51,20 +51,53 @@ type RedisConfigSpec struct {
 	// The password to connect to the server
 	//+optional
 	Password string `json:"password,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=30
+	// The maximum duration of a single operation on the server in seconds, including connecting to it. Operations
+	// that time out are retried with backoff - defaults to 30
+	//+optional
+	OperationTimeoutSeconds int32 `json:"operationTimeoutSeconds,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	// The maximum number of sites that can be placed on this environment by a pool. 0 means unlimited. Sites
+	// explicitly referencing the environment are not limited, but are counted
+	//+optional
+	MaxSites int32 `json:"maxSites,omitempty"`
+
+	//+kubebuilder:default:=Active
+	// The operating mode of the environment. Maintenance denies new sites and pauses the reconciliation of the
+	// databases, while Draining denies new sites and excludes the environment from pools - defaults to Active
+	//+optional
+	Mode EnvironmentMode `json:"mode,omitempty"`
+}
+
+// RedisConfigStatus defines the observed state of RedisConfig
+type RedisConfigStatus struct {
+	EnvironmentStatus `json:",inline"`
+
+	// The number of databases on the server not yet reserved by a site
+	//+optional
+	FreeDatabaseCount int32 `json:"freeDatabaseCount,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.spec.host`
 //+kubebuilder:printcolumn:name="Port",type=string,JSONPath=`.spec.port`
 //+kubebuilder:printcolumn:name="Available-Database-Count",type=integer,JSONPath=`.spec.availableDatabaseCount`
 //+kubebuilder:printcolumn:name="Is-TLS-Enabled",type=boolean,JSONPath=`.spec.isTlsEnabled`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+//+kubebuilder:printcolumn:name="Free-Databases",type=integer,JSONPath=`.status.freeDatabaseCount`
 
 // RedisConfig is the Schema for the redisconfigs API
 type RedisConfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec RedisConfigSpec `json:"spec,omitempty"`
+	Spec   RedisConfigSpec   `json:"spec,omitempty"`
+	Status RedisConfigStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -17,9 +17,12 @@ limitations under the License.
 package v1
 
 import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -42,13 +45,25 @@ type ServiceConfigSpec struct {
 	//+optional
 	CustomTemplateValues map[string]string `json:"customTemplateValues"`
 
-	// The spec for the deployment to create
-	DeploymentPodSpec corev1.PodSpec `json:"deploymentPodSpec"`
+	// The spec for the deployment to create. May only be omitted if the workloads of the service are rendered from a
+	// helm chart
+	//+optional
+	DeploymentPodSpec *corev1.PodSpec `json:"deploymentPodSpec,omitempty"`
+
+	// A helm chart to render for the sites using this service, eg. to reuse the existing chart of the service instead
+	// of the deployment, service and ingress specs. The rendered objects are created in the namespace of the site
+	//+optional
+	HelmChart *ServiceHelmChart `json:"helmChart,omitempty"`
 
 	// The spec for the db init job. If not set, no db initialisation will be run
 	//+optional
 	DbInitPodSpec *corev1.PodSpec `json:"dbInitPodSpec"`
 
+	// The spec for the db sanitize job, which is run after the db init job and before the migrations to remove personal
+	// data from the initialised databases. Requires the db init pod spec to be set
+	//+optional
+	DbSanitizePodSpec *corev1.PodSpec `json:"dbSanitizePodSpec,omitempty"`
+
 	// The spec for the migration job. If not set, no db migration will be run
 	//+optional
 	MigrationJobPodSpec *corev1.PodSpec `json:"migrationJobPodSpec"`
@@ -57,6 +72,12 @@ type ServiceConfigSpec struct {
 	//+optional
 	BackupPodSpec *corev1.PodSpec `json:"backupPodSpec,omitempty"`
 
+	// The spec for the job copying the mysql or mongo database of a site when its environment is changed. The
+	// move.source.* and move.target.* template values hold the connection details of the two environments. If not
+	// set, changing the environment creates an empty database in the new environment
+	//+optional
+	DatabaseMovePodSpec *corev1.PodSpec `json:"databaseMovePodSpec,omitempty"`
+
 	// The spec for the service created for the deployment of this service. If not set, no service will be created
 	//+optional
 	ServiceSpec *corev1.ServiceSpec `json:"serviceSpec,omitempty"`
@@ -80,10 +101,259 @@ type ServiceConfigSpec struct {
 	// Name of the default redis environment if one is not specified on the site level
 	//+optional
 	DefaultRedisEnvironment string `json:"defaultRedisEnvironment"`
+
+	// Name of the default object storage config if one is not specified on the site level. The sites get a bucket
+	// or a prefix in a shared bucket for the service from it
+	//+optional
+	DefaultObjectStorageEnvironment string `json:"defaultObjectStorageEnvironment,omitempty"`
+
+	// Label selector for a pool of mongo environments, used if no environment is specified on the site level and
+	// DefaultMongoEnvironment is not set. The site's database is placed on the pool member with the fewest databases
+	//+optional
+	DefaultMongoEnvironmentPool *metav1.LabelSelector `json:"defaultMongoEnvironmentPool,omitempty"`
+
+	// Label selector for a pool of mysql environments, used if no environment is specified on the site level and
+	// DefaultMysqlEnvironment is not set. The site's database is placed on the pool member with the fewest databases
+	//+optional
+	DefaultMysqlEnvironmentPool *metav1.LabelSelector `json:"defaultMysqlEnvironmentPool,omitempty"`
+
+	// Label selector for a pool of redis environments, used if no environment is specified on the site level and
+	// DefaultRedisEnvironment is not set. The site's database is placed on the pool member with the most free databases
+	//+optional
+	DefaultRedisEnvironmentPool *metav1.LabelSelector `json:"defaultRedisEnvironmentPool,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]*$`
+	// The character set for the mysql databases of this service. Overrides the default of the mysql environment
+	//+optional
+	MysqlCharacterSet string `json:"mysqlCharacterSet,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]*$`
+	// The collation for the mysql databases of this service. Overrides the default of the mysql environment
+	//+optional
+	MysqlCollation string `json:"mysqlCollation,omitempty"`
+
+	// The privileges of the mysql user of this service. Defaults to all privileges, connecting from any host
+	//+optional
+	MysqlPrivileges *MysqlPrivilegeProfile `json:"mysqlPrivileges,omitempty"`
+
+	// Additional mysql users to create for the database of this service, keyed by a short name (eg. readonly). Their
+	// credentials are available as the database.mysql.users.<name>.username and .password template values
+	//+optional
+	MysqlAdditionalUsers map[string]MysqlPrivilegeProfile `json:"mysqlAdditionalUsers,omitempty"`
+
+	// The names of the provisioner configs to run for the sites using this service. The outputs of the provision pods
+	// are available as the provisioner.<name>.<key> template values
+	//+optional
+	Provisioners []string `json:"provisioners,omitempty"`
+
+	// Jobs to run at the lifecycle points of the sites using this service, eg. to seed fixtures after the migrations
+	// or to warm caches once the deployment is ready
+	//+optional
+	Hooks []ServiceHook `json:"hooks,omitempty"`
+
+	// Periodic jobs to create for the sites using this service (eg. queue drainers or report generators), keyed by
+	// name. The names must be lowercase dns labels of at most 20 characters
+	//+optional
+	CronJobs map[string]ServiceCronJob `json:"cronJobs,omitempty"`
+
+	// Additional workloads of the service (eg. queue consumers), keyed by name. They share the databases, configmaps
+	// and template values of the service. The names must be lowercase dns labels of at most 20 characters
+	//+optional
+	Workloads map[string]ServiceWorkload `json:"workloads,omitempty"`
+
+	// Additional namespaced kubernetes objects to create for the sites using this service (eg. service accounts or pod
+	// disruption budgets), as full manifests. Template variables are replaced in the manifests, and the object names
+	// must contain ${site.name} to be unique per site. The objects are created in the namespace of the site with server side
+	// apply, and are deleted when they are removed from the list
+	//+optional
+	ExtraObjects []runtime.RawExtension `json:"extraObjects,omitempty"`
+
+	// Autoscaling settings for the deployment of the service. If set, a HorizontalPodAutoscaler is created for the
+	// deployment of every site using this service, and the replica count of the site is ignored. May be overridden or
+	// disabled in the site
+	//+optional
+	Autoscaling *ServiceAutoscaling `json:"autoscaling,omitempty"`
+
+	// The outgoing connections allowed from the pods of the service and its additional workloads when the network
+	// isolation of the sites is enabled. Connections to the pods of the same site and to the cluster DNS are always
+	// allowed. If not set, the outgoing connections of the service are not restricted
+	//+optional
+	NetworkPolicyEgress []networkingv1.NetworkPolicyEgressRule `json:"networkPolicyEgress,omitempty"`
+
+	// Header based routing into the sites that include this service and use a baseline site. If set, a Gateway API
+	// HTTPRoute is created for every such site, attached to the service of the baseline site, that sends the requests
+	// carrying the routing header with the name of the site to the service of the site. All other requests are served
+	// by the baseline site. Requires a service mesh that supports HTTPRoutes attached to services (GAMMA). The requests
+	// are only routed to the site if every service between the entry point and this service copies the routing header
+	// from its incoming request to its outgoing requests, the same way as the tracing headers
+	//+optional
+	HeaderRouting *ServiceHeaderRouting `json:"headerRouting,omitempty"`
+}
+
+type ServiceHeaderRouting struct {
+	//+kubebuilder:default:=X-Stager-Site
+	// The name of the header holding the name of the site to route the requests to. Defaults to X-Stager-Site
+	//+optional
+	HeaderName string `json:"headerName,omitempty"`
+
+	// The port of the service to route the requests of. Defaults to the first port of the service spec
+	//+optional
+	Port *int32 `json:"port,omitempty"`
 }
 
+type ServiceAutoscaling struct {
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:default:=1
+	// The minimum replica count of the deployment. Defaults to 1
+	//+optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	// The maximum replica count of the deployment
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// The metrics to scale the deployment by. Template variables are replaced the same way as in the deployment.
+	// Defaults to 80% average cpu utilisation
+	//+optional
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+
+	// The scaling behavior of the autoscaler
+	//+optional
+	Behavior *autoscalingv2.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+}
+
+type ServiceHelmChart struct {
+	//+kubebuilder:validation:Pattern=`^oci://`
+	// The OCI repository of the chart without the tag, eg. oci://registry.example.com/charts/api. Requires the version
+	// to be set
+	//+optional
+	OCIRepository string `json:"ociRepository,omitempty"`
+
+	// The version of the chart in the OCI repository
+	//+optional
+	Version string `json:"version,omitempty"`
+
+	// The name of a secret in the namespace of the service config with the username and password keys to log in to
+	// the OCI registry with. If not set, the credentials of the helm and docker configs of the operator are used
+	//+optional
+	PullSecret string `json:"pullSecret,omitempty"`
+
+	// A configmap in the namespace of the service config holding the packaged chart, for clusters without access to
+	// the registry
+	//+optional
+	ConfigMap *ServiceHelmChartConfigMap `json:"configMap,omitempty"`
+
+	// The values of the chart as a YAML document. Template variables are replaced the same way as in the deployment
+	//+optional
+	ValuesTemplate string `json:"valuesTemplate,omitempty"`
+}
+
+type ServiceHelmChartConfigMap struct {
+	//+kubebuilder:validation:MinLength=1
+	// The name of the configmap
+	Name string `json:"name"`
+
+	//+kubebuilder:default:=chart.tgz
+	// The key of the packaged chart in the binary data of the configmap. Defaults to chart.tgz
+	//+optional
+	Key string `json:"key,omitempty"`
+}
+
+type ServiceWorkload struct {
+	//+kubebuilder:default:=Deployment
+	// The kind of the workload. Defaults to Deployment
+	//+optional
+	Kind WorkloadKind `json:"kind,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+	// The replica count of the workload. May be overridden in the site. Defaults to 1
+	//+optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// The spec for the pods of the workload. Template variables are replaced the same way as in the deployment
+	PodSpec corev1.PodSpec `json:"podSpec"`
+}
+
+// +kubebuilder:validation:Enum=Deployment;StatefulSet
+type WorkloadKind string
+
+const (
+	WorkloadKindDeployment  WorkloadKind = "Deployment"
+	WorkloadKindStatefulSet WorkloadKind = "StatefulSet"
+)
+
+type ServiceCronJob struct {
+	//+kubebuilder:validation:MinLength=1
+	// The schedule of the job in cron format
+	Schedule string `json:"schedule"`
+
+	//+kubebuilder:default:=Forbid
+	// How to treat concurrent runs of the job. Defaults to Forbid
+	//+optional
+	ConcurrencyPolicy batchv1.ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+	// The spec for the pods of the job. Template variables are replaced the same way as in the deployment
+	PodSpec corev1.PodSpec `json:"podSpec"`
+}
+
+type ServiceHook struct {
+	//+kubebuilder:validation:MinLength=1
+	//+kubebuilder:validation:MaxLength=20
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// The name of the hook. Must be unique within the service
+	Name string `json:"name"`
+
+	// The lifecycle point to run the hook at
+	Phase HookPhase `json:"phase"`
+
+	// The spec for the hook job. Template variables are replaced the same way as in the migration job
+	PodSpec corev1.PodSpec `json:"podSpec"`
+
+	//+kubebuilder:validation:Minimum=1
+	// The number of seconds the hook job has to complete in. Defaults to 600
+	//+optional
+	DeadlineSeconds int64 `json:"deadlineSeconds,omitempty"`
+
+	// Whether the site waits for the hook and fails if the hook fails. A failed blocking preDisable or preDelete
+	// hook stops the site from being disabled or deleted. Failures of other hooks are only recorded in their status
+	//+optional
+	BlockOnFailure bool `json:"blockOnFailure,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=postMigrate;postDeploy;preDisable;preDelete
+type HookPhase string
+
+const (
+	// HookPhasePostMigrate hooks run after the migrations complete, before the workloads are deployed
+	HookPhasePostMigrate HookPhase = "postMigrate"
+	// HookPhasePostDeploy hooks run once the workloads are ready
+	HookPhasePostDeploy HookPhase = "postDeploy"
+	// HookPhasePreDisable hooks run when the site gets disabled, before its workloads are removed
+	HookPhasePreDisable HookPhase = "preDisable"
+	// HookPhasePreDelete hooks run when the site is deleted, before its final backup
+	HookPhasePreDelete HookPhase = "preDelete"
+)
+
 type Configmap map[string]string
 
+type MysqlPrivilegeProfile struct {
+	// The privileges to grant on the database of the service. ALL grants every privilege except GRANT OPTION.
+	// Defaults to ALL
+	//+optional
+	Privileges []MysqlPrivilege `json:"privileges,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[-a-zA-Z0-9._%:]+$`
+	//+kubebuilder:default:=%
+	// The host pattern the user is allowed to connect from. Defaults to %
+	//+optional
+	Host string `json:"host,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=ALL;SELECT;INSERT;UPDATE;DELETE;CREATE;DROP;REFERENCES;INDEX;ALTER;CREATE TEMPORARY TABLES;LOCK TABLES;EXECUTE;CREATE VIEW;SHOW VIEW;CREATE ROUTINE;ALTER ROUTINE;EVENT;TRIGGER;GRANT OPTION
+type MysqlPrivilege string
+
+const MysqlPrivilegeAll MysqlPrivilege = "ALL"
+
 //+kubebuilder:object:root=true
 //+kubebuilder:printcolumn:name="Short-Name",type=string,JSONPath=`.spec.shortName`
 //+kubebuilder:printcolumn:name="Mysql",type=string,JSONPath=`.spec.defaultMongoEnvironment`
@@ -21,9 +21,11 @@ limitations under the License.
 package v1
 
 import (
+	"k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -47,12 +49,64 @@ func (in Configmap) DeepCopy() Configmap {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnvironmentStatus) DeepCopyInto(out *EnvironmentStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnvironmentStatus.
+func (in *EnvironmentStatus) DeepCopy() *EnvironmentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EnvironmentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedResources) DeepCopyInto(out *ManagedResources) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedResources.
+func (in *ManagedResources) DeepCopy() *ManagedResources {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoConfig) DeepCopyInto(out *MongoConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoConfig.
@@ -108,6 +162,11 @@ func (in *MongoConfigList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MongoConfigSpec) DeepCopyInto(out *MongoConfigSpec) {
 	*out = *in
+	if in.OrphanSweeper != nil {
+		in, out := &in.OrphanSweeper, &out.OrphanSweeper
+		*out = new(OrphanSweeperSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoConfigSpec.
@@ -120,12 +179,45 @@ func (in *MongoConfigSpec) DeepCopy() *MongoConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoConfigStatus) DeepCopyInto(out *MongoConfigStatus) {
+	*out = *in
+	in.EnvironmentStatus.DeepCopyInto(&out.EnvironmentStatus)
+	if in.LastOrphanSweepTime != nil {
+		in, out := &in.LastOrphanSweepTime, &out.LastOrphanSweepTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Orphans != nil {
+		in, out := &in.Orphans, &out.Orphans
+		*out = make([]OrphanedResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedResources != nil {
+		in, out := &in.ManagedResources, &out.ManagedResources
+		*out = new(ManagedResources)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoConfigStatus.
+func (in *MongoConfigStatus) DeepCopy() *MongoConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MysqlConfig) DeepCopyInto(out *MysqlConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlConfig.
@@ -181,6 +273,16 @@ func (in *MysqlConfigList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MysqlConfigSpec) DeepCopyInto(out *MysqlConfigSpec) {
 	*out = *in
+	if in.TlsCaSecret != nil {
+		in, out := &in.TlsCaSecret, &out.TlsCaSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OrphanSweeper != nil {
+		in, out := &in.OrphanSweeper, &out.OrphanSweeper
+		*out = new(OrphanSweeperSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlConfigSpec.
@@ -193,12 +295,260 @@ func (in *MysqlConfigSpec) DeepCopy() *MysqlConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlConfigStatus) DeepCopyInto(out *MysqlConfigStatus) {
+	*out = *in
+	in.EnvironmentStatus.DeepCopyInto(&out.EnvironmentStatus)
+	if in.LastOrphanSweepTime != nil {
+		in, out := &in.LastOrphanSweepTime, &out.LastOrphanSweepTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Orphans != nil {
+		in, out := &in.Orphans, &out.Orphans
+		*out = make([]OrphanedResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedResources != nil {
+		in, out := &in.ManagedResources, &out.ManagedResources
+		*out = new(ManagedResources)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlConfigStatus.
+func (in *MysqlConfigStatus) DeepCopy() *MysqlConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlPrivilegeProfile) DeepCopyInto(out *MysqlPrivilegeProfile) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]MysqlPrivilege, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlPrivilegeProfile.
+func (in *MysqlPrivilegeProfile) DeepCopy() *MysqlPrivilegeProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlPrivilegeProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageConfig) DeepCopyInto(out *ObjectStorageConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageConfig.
+func (in *ObjectStorageConfig) DeepCopy() *ObjectStorageConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ObjectStorageConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageConfigList) DeepCopyInto(out *ObjectStorageConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ObjectStorageConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageConfigList.
+func (in *ObjectStorageConfigList) DeepCopy() *ObjectStorageConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ObjectStorageConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageConfigSpec) DeepCopyInto(out *ObjectStorageConfigSpec) {
+	*out = *in
+	if in.UseTls != nil {
+		in, out := &in.UseTls, &out.UseTls
+		*out = new(bool)
+		**out = **in
+	}
+	in.AccessKeySecret.DeepCopyInto(&out.AccessKeySecret)
+	in.SecretKeySecret.DeepCopyInto(&out.SecretKeySecret)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageConfigSpec.
+func (in *ObjectStorageConfigSpec) DeepCopy() *ObjectStorageConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanSweeperSpec) DeepCopyInto(out *OrphanSweeperSpec) {
+	*out = *in
+	if in.ExcludedNames != nil {
+		in, out := &in.ExcludedNames, &out.ExcludedNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanSweeperSpec.
+func (in *OrphanSweeperSpec) DeepCopy() *OrphanSweeperSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanSweeperSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanedResource) DeepCopyInto(out *OrphanedResource) {
+	*out = *in
+	in.FirstSeen.DeepCopyInto(&out.FirstSeen)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanedResource.
+func (in *OrphanedResource) DeepCopy() *OrphanedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerConfig) DeepCopyInto(out *ProvisionerConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerConfig.
+func (in *ProvisionerConfig) DeepCopy() *ProvisionerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProvisionerConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerConfigList) DeepCopyInto(out *ProvisionerConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProvisionerConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerConfigList.
+func (in *ProvisionerConfigList) DeepCopy() *ProvisionerConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProvisionerConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerConfigSpec) DeepCopyInto(out *ProvisionerConfigSpec) {
+	*out = *in
+	in.ProvisionPodSpec.DeepCopyInto(&out.ProvisionPodSpec)
+	if in.DeprovisionPodSpec != nil {
+		in, out := &in.DeprovisionPodSpec, &out.DeprovisionPodSpec
+		*out = new(corev1.PodSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerConfigSpec.
+func (in *ProvisionerConfigSpec) DeepCopy() *ProvisionerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisConfig) DeepCopyInto(out *RedisConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisConfig.
@@ -276,6 +626,54 @@ func (in *RedisConfigSpec) DeepCopy() *RedisConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisConfigStatus) DeepCopyInto(out *RedisConfigStatus) {
+	*out = *in
+	in.EnvironmentStatus.DeepCopyInto(&out.EnvironmentStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisConfigStatus.
+func (in *RedisConfigStatus) DeepCopy() *RedisConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAutoscaling) DeepCopyInto(out *ServiceAutoscaling) {
+	*out = *in
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]v2.MetricSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Behavior != nil {
+		in, out := &in.Behavior, &out.Behavior
+		*out = new(v2.HorizontalPodAutoscalerBehavior)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAutoscaling.
+func (in *ServiceAutoscaling) DeepCopy() *ServiceAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceConfig) DeepCopyInto(out *ServiceConfig) {
 	*out = *in
@@ -362,12 +760,26 @@ func (in *ServiceConfigSpec) DeepCopyInto(out *ServiceConfigSpec) {
 			(*out)[key] = val
 		}
 	}
-	in.DeploymentPodSpec.DeepCopyInto(&out.DeploymentPodSpec)
+	if in.DeploymentPodSpec != nil {
+		in, out := &in.DeploymentPodSpec, &out.DeploymentPodSpec
+		*out = new(corev1.PodSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HelmChart != nil {
+		in, out := &in.HelmChart, &out.HelmChart
+		*out = new(ServiceHelmChart)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DbInitPodSpec != nil {
 		in, out := &in.DbInitPodSpec, &out.DbInitPodSpec
 		*out = new(corev1.PodSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DbSanitizePodSpec != nil {
+		in, out := &in.DbSanitizePodSpec, &out.DbSanitizePodSpec
+		*out = new(corev1.PodSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.MigrationJobPodSpec != nil {
 		in, out := &in.MigrationJobPodSpec, &out.MigrationJobPodSpec
 		*out = new(corev1.PodSpec)
@@ -378,6 +790,11 @@ func (in *ServiceConfigSpec) DeepCopyInto(out *ServiceConfigSpec) {
 		*out = new(corev1.PodSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DatabaseMovePodSpec != nil {
+		in, out := &in.DatabaseMovePodSpec, &out.DatabaseMovePodSpec
+		*out = new(corev1.PodSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ServiceSpec != nil {
 		in, out := &in.ServiceSpec, &out.ServiceSpec
 		*out = new(corev1.ServiceSpec)
@@ -395,6 +812,83 @@ func (in *ServiceConfigSpec) DeepCopyInto(out *ServiceConfigSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.DefaultMongoEnvironmentPool != nil {
+		in, out := &in.DefaultMongoEnvironmentPool, &out.DefaultMongoEnvironmentPool
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultMysqlEnvironmentPool != nil {
+		in, out := &in.DefaultMysqlEnvironmentPool, &out.DefaultMysqlEnvironmentPool
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultRedisEnvironmentPool != nil {
+		in, out := &in.DefaultRedisEnvironmentPool, &out.DefaultRedisEnvironmentPool
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MysqlPrivileges != nil {
+		in, out := &in.MysqlPrivileges, &out.MysqlPrivileges
+		*out = new(MysqlPrivilegeProfile)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MysqlAdditionalUsers != nil {
+		in, out := &in.MysqlAdditionalUsers, &out.MysqlAdditionalUsers
+		*out = make(map[string]MysqlPrivilegeProfile, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Provisioners != nil {
+		in, out := &in.Provisioners, &out.Provisioners
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]ServiceHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CronJobs != nil {
+		in, out := &in.CronJobs, &out.CronJobs
+		*out = make(map[string]ServiceCronJob, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make(map[string]ServiceWorkload, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.ExtraObjects != nil {
+		in, out := &in.ExtraObjects, &out.ExtraObjects
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(ServiceAutoscaling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NetworkPolicyEgress != nil {
+		in, out := &in.NetworkPolicyEgress, &out.NetworkPolicyEgress
+		*out = make([]networkingv1.NetworkPolicyEgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HeaderRouting != nil {
+		in, out := &in.HeaderRouting, &out.HeaderRouting
+		*out = new(ServiceHeaderRouting)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceConfigSpec.
@@ -406,3 +900,111 @@ func (in *ServiceConfigSpec) DeepCopy() *ServiceConfigSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceCronJob) DeepCopyInto(out *ServiceCronJob) {
+	*out = *in
+	in.PodSpec.DeepCopyInto(&out.PodSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceCronJob.
+func (in *ServiceCronJob) DeepCopy() *ServiceCronJob {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceCronJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceHeaderRouting) DeepCopyInto(out *ServiceHeaderRouting) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceHeaderRouting.
+func (in *ServiceHeaderRouting) DeepCopy() *ServiceHeaderRouting {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceHeaderRouting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceHelmChart) DeepCopyInto(out *ServiceHelmChart) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ServiceHelmChartConfigMap)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceHelmChart.
+func (in *ServiceHelmChart) DeepCopy() *ServiceHelmChart {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceHelmChart)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceHelmChartConfigMap) DeepCopyInto(out *ServiceHelmChartConfigMap) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceHelmChartConfigMap.
+func (in *ServiceHelmChartConfigMap) DeepCopy() *ServiceHelmChartConfigMap {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceHelmChartConfigMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceHook) DeepCopyInto(out *ServiceHook) {
+	*out = *in
+	in.PodSpec.DeepCopyInto(&out.PodSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceHook.
+func (in *ServiceHook) DeepCopy() *ServiceHook {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceWorkload) DeepCopyInto(out *ServiceWorkload) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.PodSpec.DeepCopyInto(&out.PodSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceWorkload.
+func (in *ServiceWorkload) DeepCopy() *ServiceWorkload {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceWorkload)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -66,6 +66,26 @@ type ProjectConfig struct {
 	// The config for the init job
 	//+optional
 	BackupJobConfig JobConfig `json:"backupJobConfig,omitempty"`
+
+	// The config for the provision and deprovision jobs of the provisioner configs
+	//+optional
+	ProvisionJobConfig JobConfig `json:"provisionJobConfig,omitempty"`
+
+	// The config for the hook jobs of the services. The deadline is set per hook
+	//+optional
+	HookJobConfig JobConfig `json:"hookJobConfig,omitempty"`
+
+	// The config for the pools of admin connections to the mysql and mongo environments
+	//+optional
+	DatabaseConnectionPool DatabaseConnectionPoolConfig `json:"databaseConnectionPool,omitempty"`
+
+	// The config for the network isolation of the sites
+	//+optional
+	NetworkIsolation NetworkIsolationConfig `json:"networkIsolation,omitempty"`
+
+	// The config for the hosts and TLS settings of the ingresses of the sites
+	//+optional
+	Ingress IngressConfig `json:"ingress,omitempty"`
 }
 
 // HealthConfig contains the controller health configuration.
@@ -108,6 +128,86 @@ type JobConfig struct {
 	BackoffLimit int32 `json:"backoffLimit,omitempty"`
 }
 
+type DatabaseConnectionPoolConfig struct {
+	// The maximum number of open admin connections to each environment, shared by all controllers
+	//+kubebuilder:default:=5
+	//+optional
+	MaxOpenConnections int32 `json:"maxOpenConnections,omitempty"`
+
+	// The pool of an environment is closed after it wasn't used for this many seconds
+	//+kubebuilder:default:=300
+	//+optional
+	IdleTimeoutSeconds int32 `json:"idleTimeoutSeconds,omitempty"`
+}
+
+type NetworkIsolationConfig struct {
+	// Whether to create NetworkPolicies that only allow connections to the pods of a site from the pods of the same
+	// site, the ingress controller namespaces and the allowed pods. Defaults to FALSE
+	//+optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// The namespaces of the ingress controllers, that are allowed to connect to the pods of every site
+	//+optional
+	IngressControllerNamespaces []string `json:"ingressControllerNamespaces,omitempty"`
+
+	// Selectors for the pods in the namespace of the sites that are allowed to connect to the pods of every site (eg.
+	// shared services that aren't part of a site)
+	//+optional
+	AllowedPodSelectors []metav1.LabelSelector `json:"allowedPodSelectors,omitempty"`
+}
+
+type IngressTLSMode string
+
+const (
+	// IngressTLSModeDisabled doesn't add any TLS settings to the ingresses
+	IngressTLSModeDisabled IngressTLSMode = "Disabled"
+	// IngressTLSModeCertManager adds a per-site secret name and the cert-manager issuer annotation to the ingresses
+	IngressTLSModeCertManager IngressTLSMode = "CertManager"
+	// IngressTLSModeWildcardSecret references an existing wildcard certificate secret from the ingresses
+	IngressTLSModeWildcardSecret IngressTLSMode = "WildcardSecret"
+)
+
+type CertManagerIssuerKind string
+
+const (
+	CertManagerIssuerKindClusterIssuer CertManagerIssuerKind = "ClusterIssuer"
+	CertManagerIssuerKindIssuer        CertManagerIssuerKind = "Issuer"
+)
+
+type IngressConfig struct {
+	// The base domain of the sites. If set, the ingress rules of the services without a host get the
+	// <domainPrefix>-<serviceShortName>.<baseDomain> host
+	//+optional
+	BaseDomain string `json:"baseDomain,omitempty"`
+
+	// The TLS settings that are added to the ingresses that don't define their own TLS blocks
+	//+optional
+	TLS IngressTLSConfig `json:"tls,omitempty"`
+}
+
+type IngressTLSConfig struct {
+	// How the TLS settings of the ingresses are populated. Defaults to Disabled
+	//+kubebuilder:validation:Enum=Disabled;CertManager;WildcardSecret
+	//+kubebuilder:default:=Disabled
+	//+optional
+	Mode IngressTLSMode `json:"mode,omitempty"`
+
+	// The name of the cert-manager issuer to use in CertManager mode
+	//+optional
+	CertManagerIssuer string `json:"certManagerIssuer,omitempty"`
+
+	// The kind of the cert-manager issuer to use in CertManager mode. Defaults to ClusterIssuer
+	//+kubebuilder:validation:Enum=ClusterIssuer;Issuer
+	//+kubebuilder:default:=ClusterIssuer
+	//+optional
+	CertManagerIssuerKind CertManagerIssuerKind `json:"certManagerIssuerKind,omitempty"`
+
+	// The name of the secret in the namespace of the sites that contains the wildcard certificate for the base domain,
+	// used in WildcardSecret mode
+	//+optional
+	WildcardSecretName string `json:"wildcardSecretName,omitempty"`
+}
+
 func init() {
 	SchemeBuilder.Register(&ProjectConfig{})
 }
@@ -21,9 +21,25 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseConnectionPoolConfig) DeepCopyInto(out *DatabaseConnectionPoolConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseConnectionPoolConfig.
+func (in *DatabaseConnectionPoolConfig) DeepCopy() *DatabaseConnectionPoolConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseConnectionPoolConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HealthConfig) DeepCopyInto(out *HealthConfig) {
 	*out = *in
@@ -39,6 +55,37 @@ func (in *HealthConfig) DeepCopy() *HealthConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressConfig) DeepCopyInto(out *IngressConfig) {
+	*out = *in
+	out.TLS = in.TLS
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressConfig.
+func (in *IngressConfig) DeepCopy() *IngressConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTLSConfig) DeepCopyInto(out *IngressTLSConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTLSConfig.
+func (in *IngressTLSConfig) DeepCopy() *IngressTLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JobConfig) DeepCopyInto(out *JobConfig) {
 	*out = *in
@@ -69,6 +116,33 @@ func (in *MetricsConfig) DeepCopy() *MetricsConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkIsolationConfig) DeepCopyInto(out *NetworkIsolationConfig) {
+	*out = *in
+	if in.IngressControllerNamespaces != nil {
+		in, out := &in.IngressControllerNamespaces, &out.IngressControllerNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedPodSelectors != nil {
+		in, out := &in.AllowedPodSelectors, &out.AllowedPodSelectors
+		*out = make([]metav1.LabelSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkIsolationConfig.
+func (in *NetworkIsolationConfig) DeepCopy() *NetworkIsolationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkIsolationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectConfig) DeepCopyInto(out *ProjectConfig) {
 	*out = *in
@@ -80,6 +154,11 @@ func (in *ProjectConfig) DeepCopyInto(out *ProjectConfig) {
 	out.InitJobConfig = in.InitJobConfig
 	out.MigrationJobConfig = in.MigrationJobConfig
 	out.BackupJobConfig = in.BackupJobConfig
+	out.ProvisionJobConfig = in.ProvisionJobConfig
+	out.HookJobConfig = in.HookJobConfig
+	out.DatabaseConnectionPool = in.DatabaseConnectionPool
+	in.NetworkIsolation.DeepCopyInto(&out.NetworkIsolation)
+	out.Ingress = in.Ingress
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectConfig.
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	"github.com/szeber/kube-stager/helpers"
+	corev1 "k8s.io/api/core/v1"
 	"strings"
 )
 
@@ -27,6 +31,17 @@ func MakeUsername(site *sitev1.StagingSite, service *configv1.ServiceConfig) str
 	return helpers.SanitiseAndShortenDbValue(fmt.Sprintf("%s_%s", site.Spec.Username, service.Spec.ShortName), 16)
 }
 
+func MakeMysqlAdditionalUsername(site *sitev1.StagingSite, service *configv1.ServiceConfig, name string) string {
+	return helpers.SanitiseAndShortenDbValue(fmt.Sprintf("%s_%s_%s", site.Spec.Username, service.Spec.ShortName, name), 16)
+}
+
+// MakeMysqlAdditionalUserPassword derives the password of an additional mysql user from the site password, so it
+// doesn't need to be stored, but differs from the password of the main user.
+func MakeMysqlAdditionalUserPassword(site *sitev1.StagingSite, service *configv1.ServiceConfig, name string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s", site.Spec.Password, service.Spec.ShortName, name)))
+	return hex.EncodeToString(hash[:])[0:32]
+}
+
 func MakeConfigmapName(site *sitev1.StagingSite, service *configv1.ServiceConfig, typeName string) string {
 	return helpers.MakeObjectName(site.Name, service.Spec.ShortName, string(typeName))
 }
@@ -39,10 +54,83 @@ func MakeIngressName(site *sitev1.StagingSite, service *configv1.ServiceConfig)
 	return helpers.MakeObjectName(site.Name, service.Spec.ShortName)
 }
 
+// MakeIngressHost returns the default host of the ingress of the service under the base domain of the sites
+func MakeIngressHost(site *sitev1.StagingSite, service *configv1.ServiceConfig, baseDomain string) string {
+	domainPrefix := site.Spec.DomainPrefix
+	if domainPrefix == "" {
+		domainPrefix = site.Name
+	}
+	return helpers.MakeObjectName(domainPrefix, service.Spec.ShortName) + "." + baseDomain
+}
+
+// MakeIngressTLSSecretName returns the name of the secret holding the certificate issued for the ingress of the service
+func MakeIngressTLSSecretName(site *sitev1.StagingSite, service *configv1.ServiceConfig) string {
+	return helpers.MakeObjectName(site.Name, service.Spec.ShortName, "tls")
+}
+
 func MakeDeploymentName(site *sitev1.StagingSite, service *configv1.ServiceConfig) string {
 	return helpers.MakeObjectName(site.Name, service.Spec.ShortName)
 }
 
+func MakeHorizontalPodAutoscalerName(site *sitev1.StagingSite, service *configv1.ServiceConfig) string {
+	return helpers.MakeObjectName(site.Name, service.Spec.ShortName)
+}
+
+// MakeHTTPRouteName returns the name of the route sending the requests with the routing header of the site to the
+// service of the site
+func MakeHTTPRouteName(site *sitev1.StagingSite, service *configv1.ServiceConfig) string {
+	return helpers.MakeObjectName(site.Name, service.Spec.ShortName)
+}
+
+// MakeSiteNetworkPolicyName returns the name of the network policy isolating the pods of the site
+func MakeSiteNetworkPolicyName(site *sitev1.StagingSite) string {
+	return helpers.MakeObjectName(site.Name, "isolation")
+}
+
+// MakeServiceNetworkPolicyName returns the name of the network policy restricting the egress of the pods of the service
+func MakeServiceNetworkPolicyName(site *sitev1.StagingSite, service *configv1.ServiceConfig) string {
+	return helpers.MakeObjectName(site.Name, service.Spec.ShortName, "egress")
+}
+
+// MakeWorkloadNetworkPolicyName returns the name of the network policy restricting the egress of the pods of an
+// additional workload of the service
+func MakeWorkloadNetworkPolicyName(site *sitev1.StagingSite, service *configv1.ServiceConfig, name string) string {
+	return helpers.MakeObjectName(site.Name, service.Spec.ShortName, name, "egress")
+}
+
+// MakeCronJobName returns the name of a cron job of the service. Cron job names are limited to 52 characters, as the
+// names of the jobs created from them get a timestamp suffix
+func MakeCronJobName(site *sitev1.StagingSite, service *configv1.ServiceConfig, name string) string {
+	suffix := "-" + service.Spec.ShortName + "-" + name
+	return helpers.ShortenHumanReadableValue(site.Name, 52-len(suffix)) + suffix
+}
+
+// MakeHelmReleaseName returns the name of the helm release the chart of the service is rendered as. Release names are
+// limited to 53 characters
+func MakeHelmReleaseName(site *sitev1.StagingSite, service *configv1.ServiceConfig) string {
+	suffix := "-" + service.Spec.ShortName
+	return helpers.ShortenHumanReadableValue(site.Name, 53-len(suffix)) + suffix
+}
+
+func MakeWorkloadName(site *sitev1.StagingSite, service *configv1.ServiceConfig, name string) string {
+	return helpers.MakeObjectName(site.Name, service.Spec.ShortName, name)
+}
+
 func MakeServiceUrl(site *sitev1.StagingSite, serviceName string) string {
 	return fmt.Sprintf("%s.%s.svc.cluster.local", helpers.MakeObjectName(site.Name, serviceName), site.Namespace)
 }
+
+// MakePodSpecHash returns a short hash of the pod spec, used to detect if a job was run with a different pod spec.
+// Returns an empty string for a nil pod spec
+func MakePodSpecHash(podSpec *corev1.PodSpec) string {
+	if podSpec == nil {
+		return ""
+	}
+
+	encoded, err := json.Marshal(podSpec)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])[0:16]
+}
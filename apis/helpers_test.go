@@ -6,6 +6,7 @@ import (
 
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -92,6 +93,33 @@ func TestMakeIngressName(t *testing.T) {
 	}
 }
 
+func TestMakeIngressHost(t *testing.T) {
+	t.Run("domain prefix", func(t *testing.T) {
+		site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+		site.Spec.DomainPrefix = "prefix"
+		got := MakeIngressHost(site, svc, "staging.example.com")
+		if got != "prefix-web.staging.example.com" {
+			t.Errorf("MakeIngressHost() = %q, want %q", got, "prefix-web.staging.example.com")
+		}
+	})
+
+	t.Run("falls back to the site name", func(t *testing.T) {
+		site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+		got := MakeIngressHost(site, svc, "staging.example.com")
+		if got != "mysite-web.staging.example.com" {
+			t.Errorf("MakeIngressHost() = %q, want %q", got, "mysite-web.staging.example.com")
+		}
+	})
+}
+
+func TestMakeIngressTLSSecretName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeIngressTLSSecretName(site, svc)
+	if got != "mysite-web-tls" {
+		t.Errorf("MakeIngressTLSSecretName() = %q, want %q", got, "mysite-web-tls")
+	}
+}
+
 func TestMakeDeploymentName(t *testing.T) {
 	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
 	got := MakeDeploymentName(site, svc)
@@ -100,6 +128,82 @@ func TestMakeDeploymentName(t *testing.T) {
 	}
 }
 
+func TestMakeHorizontalPodAutoscalerName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeHorizontalPodAutoscalerName(site, svc)
+	if got != "mysite-web" {
+		t.Errorf("MakeHorizontalPodAutoscalerName() = %q, want %q", got, "mysite-web")
+	}
+}
+
+func TestMakeHTTPRouteName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeHTTPRouteName(site, svc)
+	if got != "mysite-web" {
+		t.Errorf("MakeHTTPRouteName() = %q, want %q", got, "mysite-web")
+	}
+}
+
+func TestMakeSiteNetworkPolicyName(t *testing.T) {
+	site, _ := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeSiteNetworkPolicyName(site)
+	if got != "mysite-isolation" {
+		t.Errorf("MakeSiteNetworkPolicyName() = %q, want %q", got, "mysite-isolation")
+	}
+}
+
+func TestMakeServiceNetworkPolicyName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeServiceNetworkPolicyName(site, svc)
+	if got != "mysite-web-egress" {
+		t.Errorf("MakeServiceNetworkPolicyName() = %q, want %q", got, "mysite-web-egress")
+	}
+}
+
+func TestMakeWorkloadNetworkPolicyName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeWorkloadNetworkPolicyName(site, svc, "worker")
+	if got != "mysite-web-worker-egress" {
+		t.Errorf("MakeWorkloadNetworkPolicyName() = %q, want %q", got, "mysite-web-worker-egress")
+	}
+}
+
+func TestMakeWorkloadName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeWorkloadName(site, svc, "worker")
+	if got != "mysite-web-worker" {
+		t.Errorf("MakeWorkloadName() = %q, want %q", got, "mysite-web-worker")
+	}
+}
+
+func TestMakeHelmReleaseName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeHelmReleaseName(site, svc)
+	if got != "mysite-web" {
+		t.Errorf("MakeHelmReleaseName() = %q, want %q", got, "mysite-web")
+	}
+
+	site, _ = makeSiteAndService(strings.Repeat("a", 60), "mydb", "user", "web")
+	got = MakeHelmReleaseName(site, svc)
+	if len(got) != 53 || !strings.HasSuffix(got, "-web") {
+		t.Errorf("MakeHelmReleaseName() = %q, want a 53 character name ending in -web", got)
+	}
+}
+
+func TestMakeCronJobName(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "user", "web")
+	got := MakeCronJobName(site, svc, "report")
+	if got != "mysite-web-report" {
+		t.Errorf("MakeCronJobName() = %q, want %q", got, "mysite-web-report")
+	}
+
+	site.Name = strings.Repeat("a", 60)
+	got = MakeCronJobName(site, svc, "report")
+	if len(got) > 52 || !strings.HasSuffix(got, "-web-report") {
+		t.Errorf("MakeCronJobName() = %q, want at most 52 characters ending in -web-report", got)
+	}
+}
+
 func TestMakeServiceUrl(t *testing.T) {
 	site := &sitev1.StagingSite{
 		ObjectMeta: metav1.ObjectMeta{Name: "mysite", Namespace: "test-ns"},
@@ -110,3 +214,57 @@ func TestMakeServiceUrl(t *testing.T) {
 		t.Errorf("MakeServiceUrl() = %q, want %q", got, expected)
 	}
 }
+
+func TestMakeMysqlAdditionalUsername(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "usr", "web")
+	got := MakeMysqlAdditionalUsername(site, svc, "ro")
+	if got != "usr_web_ro" {
+		t.Errorf("MakeMysqlAdditionalUsername() = %q, want %q", got, "usr_web_ro")
+	}
+
+	site, svc = makeSiteAndService("mysite", "mydb", "verylongusername", "web")
+	got = MakeMysqlAdditionalUsername(site, svc, "readonly")
+	if len(got) > 16 {
+		t.Errorf("MakeMysqlAdditionalUsername() length = %d, want <= 16", len(got))
+	}
+	if got == MakeUsername(site, svc) {
+		t.Errorf("MakeMysqlAdditionalUsername() = %q, should differ from the main username", got)
+	}
+}
+
+func TestMakeMysqlAdditionalUserPassword(t *testing.T) {
+	site, svc := makeSiteAndService("mysite", "mydb", "usr", "web")
+	site.Spec.Password = "sitepass"
+
+	got := MakeMysqlAdditionalUserPassword(site, svc, "readonly")
+	if len(got) != 32 {
+		t.Errorf("MakeMysqlAdditionalUserPassword() length = %d, want 32", len(got))
+	}
+	if got != MakeMysqlAdditionalUserPassword(site, svc, "readonly") {
+		t.Error("MakeMysqlAdditionalUserPassword() should be deterministic")
+	}
+	if got == MakeMysqlAdditionalUserPassword(site, svc, "reporting") {
+		t.Error("MakeMysqlAdditionalUserPassword() should differ between users")
+	}
+}
+
+func TestMakePodSpecHash(t *testing.T) {
+	podSpec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "init", Image: "init:1"}}}
+
+	got := MakePodSpecHash(podSpec)
+	if len(got) != 16 {
+		t.Errorf("MakePodSpecHash() length = %d, want 16", len(got))
+	}
+	if got != MakePodSpecHash(podSpec.DeepCopy()) {
+		t.Error("MakePodSpecHash() should be deterministic")
+	}
+
+	changed := podSpec.DeepCopy()
+	changed.Containers[0].Image = "init:2"
+	if got == MakePodSpecHash(changed) {
+		t.Error("MakePodSpecHash() should differ for different pod specs")
+	}
+	if MakePodSpecHash(nil) != "" {
+		t.Error("MakePodSpecHash(nil) should be empty")
+	}
+}
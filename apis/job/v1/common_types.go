@@ -1,5 +1,10 @@
 package v1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
 type JobState string
 
 const (
@@ -16,3 +21,33 @@ func (r JobState) IsFinal() bool {
 
 	return false
 }
+
+// BatchJobStatus is the state of a job that runs a single batch job at a time
+type BatchJobStatus struct {
+	//+kubebuilder:default:=Pending
+	// State of the job
+	State JobState `json:"state"`
+
+	//+kubebuilder:default:=0
+	// Number of consecutive times the related batch job failed to load
+	JobNotFoundCount uint32 `json:"jobNotFoundCount"`
+
+	// The deadline for the job's completion, after which the job will be marked as failed if it didn't run to completion yet
+	DeadlineTimestamp *metav1.Time `json:"deadlineTimestamp"`
+}
+
+// RevisionedJob is implemented by the jobs that run their batch job once for every revision of their spec, and run it
+// again when the revision changes
+// +kubebuilder:object:generate=false
+type RevisionedJob interface {
+	client.Object
+	GetSiteName() string
+	GetServiceName() string
+	GetDeadlineSeconds() int64
+	// GetRevision returns the revision of the spec the batch job has to run for
+	GetRevision() string
+	// GetLastRunRevision returns the revision the batch job was last started for
+	GetLastRunRevision() string
+	SetLastRunRevision(revision string)
+	GetBatchJobStatus() *BatchJobStatus
+}
@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (r *DatabaseMove) PopulateFomSite(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	databaseType DatabaseMoveType,
+	sourceEnvironment string,
+	targetEnvironment string,
+) error {
+	if _, ok := site.Spec.Services[config.Name]; !ok {
+		return fmt.Errorf("service %s is not in the site spec", config.Name)
+	}
+
+	r.ObjectMeta = metav1.ObjectMeta{
+		Name: helpers.ShortenHumanReadableValue(site.Name, 44) + "-" + config.Spec.ShortName + "-" +
+			string(databaseType),
+		Namespace: site.Namespace,
+		Labels: map[string]string{
+			labels.Site:    site.Name,
+			labels.Service: config.Name,
+		},
+		Annotations: map[string]string{},
+	}
+	r.Spec = DatabaseMoveSpec{
+		SiteName:          site.Name,
+		ServiceName:       config.Name,
+		DatabaseType:      databaseType,
+		SourceEnvironment: sourceEnvironment,
+		TargetEnvironment: targetEnvironment,
+		DeadlineSeconds:   3600,
+	}
+
+	return nil
+}
+
+// IsInProgress returns TRUE if the move didn't finish yet, so the workloads of the site must stay paused
+func (r *DatabaseMove) IsInProgress() bool {
+	return !r.Status.State.IsFinal()
+}
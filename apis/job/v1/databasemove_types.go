@@ -0,0 +1,121 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseMoveSpec defines the desired state of DatabaseMove
+type DatabaseMoveSpec struct {
+	//+kubebuilder:validation:MinLength=1
+	// Name of the site owning the database
+	SiteName string `json:"siteName"`
+
+	//+kubebuilder:validation:MinLength=1
+	// Name of the service owning the database
+	ServiceName string `json:"serviceName"`
+
+	// The type of the database to move
+	DatabaseType DatabaseMoveType `json:"databaseType"`
+
+	//+kubebuilder:validation:MinLength=0
+	// Name of the environment the database is moved from. Defaults to the current environment of the database
+	//+optional
+	SourceEnvironment string `json:"sourceEnvironment,omitempty"`
+
+	//+kubebuilder:validation:MinLength=1
+	// Name of the environment the database is moved to
+	TargetEnvironment string `json:"targetEnvironment"`
+
+	//+kubebuilder:default:=3600
+	// The number of seconds to use as the completion deadline of the whole move. Defaults to 1 hour
+	//+optional
+	DeadlineSeconds int64 `json:"deadlineSeconds,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=mysql;mongo
+type DatabaseMoveType string
+
+const (
+	DatabaseMoveTypeMysql DatabaseMoveType = "mysql"
+	DatabaseMoveTypeMongo DatabaseMoveType = "mongo"
+)
+
+// +kubebuilder:validation:Enum=CreatingTargetDatabase;PausingWorkloads;CopyingData;Switching;Done
+type DatabaseMovePhase string
+
+const (
+	DatabaseMovePhaseCreatingTargetDatabase DatabaseMovePhase = "CreatingTargetDatabase"
+	DatabaseMovePhasePausingWorkloads       DatabaseMovePhase = "PausingWorkloads"
+	DatabaseMovePhaseCopyingData            DatabaseMovePhase = "CopyingData"
+	DatabaseMovePhaseSwitching              DatabaseMovePhase = "Switching"
+	DatabaseMovePhaseDone                   DatabaseMovePhase = "Done"
+)
+
+// DatabaseMoveStatus defines the observed state of DatabaseMove
+type DatabaseMoveStatus struct {
+	//+kubebuilder:default:=Pending
+	// State of the move
+	State JobState `json:"state"`
+
+	// The step of the move that is currently in progress
+	//+optional
+	Phase DatabaseMovePhase `json:"phase,omitempty"`
+
+	// Name of the environment the database is moved from
+	//+optional
+	SourceEnvironment string `json:"sourceEnvironment,omitempty"`
+
+	// The deadline for the move's completion, after which the move will be marked as failed if it didn't complete yet
+	DeadlineTimestamp *metav1.Time `json:"deadlineTimestamp"`
+
+	// The reason the move failed
+	//+optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.siteName`
+//+kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.spec.serviceName`
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.databaseType`
+//+kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.targetEnvironment`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+
+// DatabaseMove is the Schema for the databasemoves API
+type DatabaseMove struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseMoveSpec   `json:"spec,omitempty"`
+	Status DatabaseMoveStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DatabaseMoveList contains a list of DatabaseMove
+type DatabaseMoveList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseMove `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseMove{}, &DatabaseMoveList{})
+}
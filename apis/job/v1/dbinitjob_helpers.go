@@ -5,9 +5,11 @@ import (
 	api "github.com/szeber/kube-stager/apis"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
 	"github.com/szeber/kube-stager/helpers"
 	"github.com/szeber/kube-stager/helpers/labels"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
 )
 
 func (r *DbInitJob) PopulateFomSite(
@@ -40,6 +42,19 @@ func (r *DbInitJob) PopulateFomSite(
 		Username:         api.MakeUsername(site, config),
 		Password:         site.Spec.Password,
 		DeadlineSeconds:  600,
+		Sanitize:         config.Spec.DbSanitizePodSpec != nil,
+		InitPodSpecHash:  api.MakePodSpecHash(config.Spec.DbInitPodSpec),
+		ResetToken:       siteService.DbInitResetToken,
 	}
 	return nil
 }
+
+// MakeInitMarker returns the marker to record in the databases initialised by this job
+func (r *DbInitJob) MakeInitMarker(initialisedAt time.Time) taskv1.DatabaseInitMarker {
+	return taskv1.DatabaseInitMarker{
+		Source:        r.Spec.DbInitSource,
+		PodSpecHash:   r.Spec.InitPodSpecHash,
+		ResetToken:    r.Spec.ResetToken,
+		InitialisedAt: metav1.Time{Time: initialisedAt},
+	}
+}
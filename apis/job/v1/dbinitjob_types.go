@@ -64,6 +64,18 @@ type DbInitJobSpec struct {
 
 	// The number of seconds to use as the completion deadline
 	DeadlineSeconds int64 `json:"deadlineSeconds"`
+
+	// Whether the db sanitize job of the service has to run after the init job
+	//+optional
+	Sanitize bool `json:"sanitize,omitempty"`
+
+	// The hash of the db init pod spec of the service, recorded in the init marker of the databases
+	//+optional
+	InitPodSpecHash string `json:"initPodSpecHash,omitempty"`
+
+	// The init reset token of the site service, recorded in the init marker of the databases
+	//+optional
+	ResetToken string `json:"resetToken,omitempty"`
 }
 
 // DbInitJobStatus defines the observed state of DbInitJob
@@ -78,6 +90,10 @@ type DbInitJobStatus struct {
 
 	// The deadline for the job's completion, after which the job will be marked as failed if it didn't run to completion yet
 	DeadlineTimestamp *metav1.Time `json:"deadlineTimestamp"`
+
+	// State of the sanitize job. Empty until the init job completes, or if no sanitization is required
+	//+optional
+	SanitizeState JobState `json:"sanitizeState,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -86,6 +102,7 @@ type DbInitJobStatus struct {
 //+kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.spec.serviceName`
 //+kubebuilder:printcolumn:name="Init-Source",type=string,JSONPath=`.spec.dbInitSource`
 //+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Sanitize-State",type=string,JSONPath=`.status.sanitizeState`
 
 // DbInitJob is the Schema for the dbinitjobs API
 type DbInitJob struct {
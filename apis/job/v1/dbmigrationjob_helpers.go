@@ -41,3 +41,32 @@ func (r *DbMigrationJob) Matches(job *DbMigrationJob) bool {
 func (r *DbMigrationJob) UpdateFrom(job *DbMigrationJob) {
 	r.Spec = job.Spec
 }
+
+func (r *DbMigrationJob) GetSiteName() string {
+	return r.Spec.SiteName
+}
+
+func (r *DbMigrationJob) GetServiceName() string {
+	return r.Spec.ServiceName
+}
+
+func (r *DbMigrationJob) GetDeadlineSeconds() int64 {
+	return r.Spec.DeadlineSeconds
+}
+
+// GetRevision returns the image tag, as the migrations have to run again for every new image
+func (r *DbMigrationJob) GetRevision() string {
+	return r.Spec.ImageTag
+}
+
+func (r *DbMigrationJob) GetLastRunRevision() string {
+	return r.Status.LastMigratedImageTag
+}
+
+func (r *DbMigrationJob) SetLastRunRevision(revision string) {
+	r.Status.LastMigratedImageTag = revision
+}
+
+func (r *DbMigrationJob) GetBatchJobStatus() *BatchJobStatus {
+	return &r.Status.BatchJobStatus
+}
@@ -43,19 +43,10 @@ type DbMigrationJobSpec struct {
 
 // DbMigrationJobStatus defines the observed state of DbMigrationJob
 type DbMigrationJobStatus struct {
-	//+kubebuilder:default:=Pending
-	// State of the job
-	State JobState `json:"state"`
-
-	//+kubebuilder:default:=0
-	// Number of consecutive times the related batch job failed to load
-	JobNotFoundCount uint32 `json:"jobNotFoundCount"`
+	BatchJobStatus `json:",inline"`
 
 	// Name of the image that the last migration was executed
 	LastMigratedImageTag string `json:"lastMigratedImageTag"`
-
-	// The deadline for the job's completion, after which the job will be marked as failed if it didn't run to completion yet
-	DeadlineTimestamp *metav1.Time `json:"deadlineTimestamp"`
 }
 
 //+kubebuilder:object:root=true
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"testing"
+	"time"
 
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
@@ -73,6 +74,46 @@ func TestDbInitJob_PopulateFomSite(t *testing.T) {
 		if job.Spec.DeadlineSeconds != 600 {
 			t.Errorf("DeadlineSeconds = %d, want 600", job.Spec.DeadlineSeconds)
 		}
+		if job.Spec.Sanitize {
+			t.Error("Sanitize = true, want false without a db sanitize pod spec")
+		}
+	})
+
+	t.Run("sanitize is set from the service config", func(t *testing.T) {
+		site, config := makeJobTestSiteAndConfig()
+		config.Spec.DbSanitizePodSpec = &corev1.PodSpec{}
+		job := &DbInitJob{}
+		if err := job.PopulateFomSite(site, config, "mysql-env", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !job.Spec.Sanitize {
+			t.Error("Sanitize = false, want true")
+		}
+	})
+
+	t.Run("init marker fields are set from the site and service config", func(t *testing.T) {
+		site, config := makeJobTestSiteAndConfig()
+		config.Spec.DbInitPodSpec = &corev1.PodSpec{Containers: []corev1.Container{{Name: "init", Image: "init"}}}
+		service := site.Spec.Services[config.Name]
+		service.DbInitResetToken = "reset-1"
+		site.Spec.Services[config.Name] = service
+
+		job := &DbInitJob{}
+		if err := job.PopulateFomSite(site, config, "mysql-env", ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if job.Spec.InitPodSpecHash == "" {
+			t.Error("InitPodSpecHash is empty, want the hash of the db init pod spec")
+		}
+		if job.Spec.ResetToken != "reset-1" {
+			t.Errorf("ResetToken = %q, want %q", job.Spec.ResetToken, "reset-1")
+		}
+
+		marker := job.MakeInitMarker(time.Now())
+		if marker.Source != job.Spec.DbInitSource || marker.PodSpecHash != job.Spec.InitPodSpecHash ||
+			marker.ResetToken != "reset-1" {
+			t.Errorf("MakeInitMarker() = %+v, doesn't match the job spec", marker)
+		}
 	})
 
 	t.Run("service not in site returns error", func(t *testing.T) {
@@ -143,6 +184,78 @@ func TestDbMigrationJob_UpdateFrom(t *testing.T) {
 	}
 }
 
+func TestHookJob_PopulateFomSite(t *testing.T) {
+	hook := configv1.ServiceHook{
+		Name:           "seed",
+		Phase:          configv1.HookPhasePostMigrate,
+		PodSpec:        corev1.PodSpec{Containers: []corev1.Container{{Name: "seed", Image: "seed:latest"}}},
+		BlockOnFailure: true,
+	}
+
+	t.Run("populates all fields", func(t *testing.T) {
+		site, config := makeJobTestSiteAndConfig()
+		job := &HookJob{}
+		if err := job.PopulateFomSite(site, config, hook); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if job.Name != "test-site-svc-seed" {
+			t.Errorf("Name = %q, want %q", job.Name, "test-site-svc-seed")
+		}
+		if job.Spec.ImageTag != "v1.0" || job.Spec.HookName != "seed" || job.Spec.Phase != configv1.HookPhasePostMigrate {
+			t.Errorf("unexpected spec: %+v", job.Spec)
+		}
+		if job.Spec.DeadlineSeconds != DefaultHookDeadlineSeconds {
+			t.Errorf("DeadlineSeconds = %d, want %d", job.Spec.DeadlineSeconds, DefaultHookDeadlineSeconds)
+		}
+		if job.Spec.PodSpecHash == "" {
+			t.Error("expected PodSpecHash to be set")
+		}
+	})
+
+	t.Run("pod spec change changes the revision", func(t *testing.T) {
+		site, config := makeJobTestSiteAndConfig()
+		job := &HookJob{}
+		_ = job.PopulateFomSite(site, config, hook)
+		changedHook := hook
+		changedHook.PodSpec = corev1.PodSpec{Containers: []corev1.Container{{Name: "seed", Image: "seed:v2"}}}
+		changedJob := &HookJob{}
+		_ = changedJob.PopulateFomSite(site, config, changedHook)
+		if job.GetRevision() == changedJob.GetRevision() {
+			t.Error("expected the revision to change with the pod spec")
+		}
+		if job.Matches(changedJob) {
+			t.Error("expected Matches() to return false")
+		}
+	})
+
+	t.Run("service not in site returns error", func(t *testing.T) {
+		site, config := makeJobTestSiteAndConfig()
+		config.Name = "other"
+		job := &HookJob{}
+		if err := job.PopulateFomSite(site, config, hook); err == nil {
+			t.Error("expected error for a service missing from the site")
+		}
+	})
+}
+
+func TestHookJob_GetHook(t *testing.T) {
+	_, config := makeJobTestSiteAndConfig()
+	config.Spec.Hooks = []configv1.ServiceHook{
+		{Name: "seed", Phase: configv1.HookPhasePostMigrate},
+		{Name: "warm", Phase: configv1.HookPhasePostDeploy},
+	}
+
+	job := &HookJob{Spec: HookJobSpec{HookName: "warm", Phase: configv1.HookPhasePostDeploy}}
+	if hook, ok := job.GetHook(config); !ok || hook.Name != "warm" {
+		t.Errorf("GetHook() = %v, %v, want the warm hook", hook, ok)
+	}
+
+	job.Spec.Phase = configv1.HookPhasePreDelete
+	if _, ok := job.GetHook(config); ok {
+		t.Error("expected GetHook() to not find a hook with a different phase")
+	}
+}
+
 func TestJobState_IsFinal(t *testing.T) {
 	tests := []struct {
 		state    JobState
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"fmt"
+	api "github.com/szeber/kube-stager/apis"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const DefaultHookDeadlineSeconds = 600
+
+func (r *HookJob) PopulateFomSite(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	hook configv1.ServiceHook,
+) error {
+	siteService, ok := site.Spec.Services[config.Name]
+	if !ok {
+		return fmt.Errorf("service %s is not in the site spec", config.Name)
+	}
+
+	deadlineSeconds := hook.DeadlineSeconds
+	if deadlineSeconds == 0 {
+		deadlineSeconds = DefaultHookDeadlineSeconds
+	}
+
+	r.ObjectMeta = metav1.ObjectMeta{
+		Name:      helpers.MakeObjectName(site.Name, config.Spec.ShortName, hook.Name),
+		Namespace: site.Namespace,
+		Labels: map[string]string{
+			labels.Site:      site.Name,
+			labels.Service:   config.Name,
+			labels.HookPhase: string(hook.Phase),
+		},
+		Annotations: map[string]string{},
+	}
+	r.Spec = HookJobSpec{
+		SiteName:        site.Name,
+		ServiceName:     config.Name,
+		HookName:        hook.Name,
+		Phase:           hook.Phase,
+		ImageTag:        siteService.ImageTag,
+		PodSpecHash:     api.MakePodSpecHash(&hook.PodSpec),
+		DeadlineSeconds: deadlineSeconds,
+		BlockOnFailure:  hook.BlockOnFailure,
+	}
+
+	return nil
+}
+
+func (r *HookJob) Matches(job *HookJob) bool {
+	return r.Spec == job.Spec
+}
+
+func (r *HookJob) UpdateFrom(job *HookJob) {
+	r.Spec = job.Spec
+}
+
+func (r *HookJob) GetSiteName() string {
+	return r.Spec.SiteName
+}
+
+func (r *HookJob) GetServiceName() string {
+	return r.Spec.ServiceName
+}
+
+func (r *HookJob) GetDeadlineSeconds() int64 {
+	return r.Spec.DeadlineSeconds
+}
+
+// GetRevision returns the image tag and the pod spec hash, so the hook runs again for every new image or hook spec
+func (r *HookJob) GetRevision() string {
+	return r.Spec.ImageTag + "/" + r.Spec.PodSpecHash
+}
+
+func (r *HookJob) GetLastRunRevision() string {
+	return r.Status.LastRunRevision
+}
+
+func (r *HookJob) SetLastRunRevision(revision string) {
+	r.Status.LastRunRevision = revision
+}
+
+func (r *HookJob) GetBatchJobStatus() *BatchJobStatus {
+	return &r.Status.BatchJobStatus
+}
+
+// GetHook returns the hook of the job from the service config
+func (r *HookJob) GetHook(config *configv1.ServiceConfig) (configv1.ServiceHook, bool) {
+	for _, hook := range config.Spec.Hooks {
+		if hook.Name == r.Spec.HookName && hook.Phase == r.Spec.Phase {
+			return hook, true
+		}
+	}
+
+	return configv1.ServiceHook{}, false
+}
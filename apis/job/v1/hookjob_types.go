@@ -0,0 +1,97 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// HookJobSpec defines the desired state of HookJob
+type HookJobSpec struct {
+	//+kubebuilder:validate:MinLength=1
+	// Name of the site owning this job
+	SiteName string `json:"siteName"`
+
+	//+kubebuilder:validate:MinLength=1
+	// Name of the service.
+	ServiceName string `json:"serviceName"`
+
+	//+kubebuilder:validate:MinLength=1
+	// Name of the hook in the service config
+	HookName string `json:"hookName"`
+
+	// The lifecycle point the hook runs at
+	Phase configv1.HookPhase `json:"phase"`
+
+	//+kubebuilder:validate:MinLength=1
+	// The tag for the images to use
+	ImageTag string `json:"imageTag"`
+
+	// The hash of the pod spec of the hook. The hook is run again if it changes
+	//+optional
+	PodSpecHash string `json:"podSpecHash,omitempty"`
+
+	// The number of seconds to use as the completion deadline
+	DeadlineSeconds int64 `json:"deadlineSeconds"`
+
+	// Whether the site waits for the hook and fails if the hook fails
+	//+optional
+	BlockOnFailure bool `json:"blockOnFailure,omitempty"`
+}
+
+// HookJobStatus defines the observed state of HookJob
+type HookJobStatus struct {
+	BatchJobStatus `json:",inline"`
+
+	// The revision (image tag and pod spec hash) the hook was last run for
+	LastRunRevision string `json:"lastRunRevision"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.siteName`
+//+kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.spec.serviceName`
+//+kubebuilder:printcolumn:name="Hook",type=string,JSONPath=`.spec.hookName`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.spec.phase`
+//+kubebuilder:printcolumn:name="Blocking",type=boolean,JSONPath=`.spec.blockOnFailure`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+
+// HookJob is the Schema for the hookjobs API
+type HookJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HookJobSpec   `json:"spec,omitempty"`
+	Status HookJobStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HookJobList contains a list of HookJob
+type HookJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HookJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HookJob{}, &HookJobList{})
+}
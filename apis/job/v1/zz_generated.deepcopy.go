@@ -144,6 +144,118 @@ func (in *BackupStatusDetail) DeepCopy() *BackupStatusDetail {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BatchJobStatus) DeepCopyInto(out *BatchJobStatus) {
+	*out = *in
+	if in.DeadlineTimestamp != nil {
+		in, out := &in.DeadlineTimestamp, &out.DeadlineTimestamp
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BatchJobStatus.
+func (in *BatchJobStatus) DeepCopy() *BatchJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BatchJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMove) DeepCopyInto(out *DatabaseMove) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMove.
+func (in *DatabaseMove) DeepCopy() *DatabaseMove {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMove)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseMove) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMoveList) DeepCopyInto(out *DatabaseMoveList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseMove, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMoveList.
+func (in *DatabaseMoveList) DeepCopy() *DatabaseMoveList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMoveList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseMoveList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMoveSpec) DeepCopyInto(out *DatabaseMoveSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMoveSpec.
+func (in *DatabaseMoveSpec) DeepCopy() *DatabaseMoveSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMoveSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseMoveStatus) DeepCopyInto(out *DatabaseMoveStatus) {
+	*out = *in
+	if in.DeadlineTimestamp != nil {
+		in, out := &in.DeadlineTimestamp, &out.DeadlineTimestamp
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseMoveStatus.
+func (in *DatabaseMoveStatus) DeepCopy() *DatabaseMoveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseMoveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbInitJob) DeepCopyInto(out *DbInitJob) {
 	*out = *in
@@ -314,10 +426,7 @@ func (in *DbMigrationJobSpec) DeepCopy() *DbMigrationJobSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DbMigrationJobStatus) DeepCopyInto(out *DbMigrationJobStatus) {
 	*out = *in
-	if in.DeadlineTimestamp != nil {
-		in, out := &in.DeadlineTimestamp, &out.DeadlineTimestamp
-		*out = (*in).DeepCopy()
-	}
+	in.BatchJobStatus.DeepCopyInto(&out.BatchJobStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DbMigrationJobStatus.
@@ -329,3 +438,93 @@ func (in *DbMigrationJobStatus) DeepCopy() *DbMigrationJobStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookJob) DeepCopyInto(out *HookJob) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookJob.
+func (in *HookJob) DeepCopy() *HookJob {
+	if in == nil {
+		return nil
+	}
+	out := new(HookJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HookJob) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookJobList) DeepCopyInto(out *HookJobList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HookJob, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookJobList.
+func (in *HookJobList) DeepCopy() *HookJobList {
+	if in == nil {
+		return nil
+	}
+	out := new(HookJobList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HookJobList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookJobSpec) DeepCopyInto(out *HookJobSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookJobSpec.
+func (in *HookJobSpec) DeepCopy() *HookJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HookJobSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookJobStatus) DeepCopyInto(out *HookJobStatus) {
+	*out = *in
+	in.BatchJobStatus.DeepCopyInto(&out.BatchJobStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookJobStatus.
+func (in *HookJobStatus) DeepCopy() *HookJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HookJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -3,6 +3,7 @@ package v1
 import (
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
 	"time"
 )
 
@@ -39,6 +40,33 @@ func (r StagingSite) GetRedisConfigForService(serviceConfig configv1.ServiceConf
 	return serviceConfig.Spec.DefaultRedisEnvironment
 }
 
+// GetLocalServices returns the sorted names of the services included in the site
+func (r StagingSite) GetLocalServices() []string {
+	result := make([]string, 0, len(r.Spec.Services))
+	for name := range r.Spec.Services {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// GetInheritedServices returns the sorted names of the services of the baseline site that aren't included in the site
+func (r StagingSite) GetInheritedServices(baseline *StagingSite) []string {
+	var result []string
+	if baseline == nil {
+		return result
+	}
+	for name := range baseline.Spec.Services {
+		if _, ok := r.Spec.Services[name]; !ok {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
 func (r TimeInterval) ToDuration() time.Duration {
 	return time.Minute*time.Duration(r.Minutes) + time.Hour*time.Duration(r.Hours) + time.Hour*24*time.Duration(r.Days)
 }
@@ -141,6 +141,29 @@ func TestGetRedisConfigForService(t *testing.T) {
 	})
 }
 
+func TestGetLocalAndInheritedServices(t *testing.T) {
+	site := StagingSite{
+		Spec: StagingSiteSpec{
+			Services: map[string]StagingSiteService{"web": {}, "api": {}},
+		},
+	}
+	baseline := &StagingSite{
+		Spec: StagingSiteSpec{
+			Services: map[string]StagingSiteService{"web": {}, "search": {}, "auth": {}},
+		},
+	}
+
+	if got := site.GetLocalServices(); len(got) != 2 || got[0] != "api" || got[1] != "web" {
+		t.Errorf("GetLocalServices() = %v, want [api web]", got)
+	}
+	if got := site.GetInheritedServices(baseline); len(got) != 2 || got[0] != "auth" || got[1] != "search" {
+		t.Errorf("GetInheritedServices() = %v, want [auth search]", got)
+	}
+	if got := site.GetInheritedServices(nil); len(got) != 0 {
+		t.Errorf("GetInheritedServices(nil) = %v, want none", got)
+	}
+}
+
 func TestTimeInterval_ToDuration(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -85,6 +86,11 @@ type StagingSiteSpec struct {
 	// If set to TRUE, all services will be included and deployed with this staging site. Defaults to FALSE
 	//+optional
 	IncludeAllServices bool `json:"includeAllServices,omitempty"`
+
+	// The name of a site in the same namespace whose services are used by this site for the services it doesn't
+	// include. The service urls and database values of these services point to the baseline site
+	//+optional
+	BaselineSite string `json:"baselineSite,omitempty"`
 }
 
 type StagingSiteService struct {
@@ -104,6 +110,14 @@ type StagingSiteService struct {
 	//+optional
 	ResourceOverrides map[string]corev1.ResourceRequirements `json:"resourceOverrides,omitempty"`
 
+	// Overrides for the additional workloads of the service, keyed by the workload name
+	//+optional
+	Workloads map[string]StagingSiteWorkload `json:"workloads,omitempty"`
+
+	// Overrides for the autoscaling settings of the service config
+	//+optional
+	Autoscaling *StagingSiteAutoscaling `json:"autoscaling,omitempty"`
+
 	//+kubebuilder:validation:MinLength=1
 	// Name of the mysql environment to use for this service
 	MysqlEnvironment string `json:"mysqlEnvironment,omitempty"`
@@ -116,6 +130,15 @@ type StagingSiteService struct {
 	// Name of the redis environment to use for this service
 	RedisEnvironment string `json:"redisEnvironment,omitempty"`
 
+	//+kubebuilder:validation:MinLength=1
+	// Name of the object storage config to create the bucket of this service with
+	ObjectStorageEnvironment string `json:"objectStorageEnvironment,omitempty"`
+
+	// Name of the environment to use for this service, keyed by the name of a custom database provider. The built-in
+	// mysql, mongo and redis providers are configured with their own fields.
+	//+optional
+	Databases map[string]string `json:"databases,omitempty"`
+
 	//+kubebuilder:default:=false
 	// Whether to include the service in backups. Defaults to FALSE
 	//+optional
@@ -126,6 +149,11 @@ type StagingSiteService struct {
 	//+optional
 	DbInitSourceEnvironmentName string `json:"dumpSourceEnvironmentName,omitempty"`
 
+	// The databases of the service are only initialised once after they are created. Set this to a new value to
+	// initialise them again over their existing data
+	//+optional
+	DbInitResetToken string `json:"dbInitResetToken,omitempty"`
+
 	// Any extra environment variables to set for the staging site.
 	//+optional
 	ExtraEnvs map[string]string `json:"extraEnvs,omitempty"`
@@ -154,9 +182,15 @@ type StagingSiteStatus struct {
 	// Whether the database initialisation is complete
 	DatabaseInitialisationComplete bool `json:"databaseInitialisationComplete"`
 
+	// Whether the provision jobs of the provisioner configs used by the services are complete
+	ProvisioningComplete bool `json:"provisioningComplete"`
+
 	// Whether the database migrations have finished running everywhere
 	DatabaseMigrationsComplete bool `json:"databaseMigrationsComplete"`
 
+	// Whether the hooks of the current lifecycle phase of the site (post migration or pre disable) finished running
+	HooksComplete bool `json:"hooksComplete,omitempty"`
+
 	// Whether configuration type objects are created/updated (configmaps, secrets)
 	ConfigsAreCreated bool `json:"configsAreCreated"`
 
@@ -199,6 +233,88 @@ type StagingSiteStatus struct {
 	// The time the next backup is scheduled for
 	//+optional
 	NextBackupTime *metav1.Time `json:"nextBackupTime,omitempty"`
+
+	// The database moves of the site. The workloads are paused while any of them is in progress
+	//+optional
+	DatabaseMoves []StagingSiteDatabaseMoveStatus `json:"databaseMoves,omitempty"`
+
+	// The extra objects applied for the services of the site, used to delete the ones that are removed from the service
+	// configs
+	//+optional
+	ExtraObjects []ExtraObjectReference `json:"extraObjects,omitempty"`
+
+	// The objects rendered from the helm charts of the services of the site, used to delete the ones that are no
+	// longer rendered
+	//+optional
+	ChartObjects []ExtraObjectReference `json:"chartObjects,omitempty"`
+
+	// The services deployed by the site, set if the site has a baseline site
+	//+optional
+	LocalServices []string `json:"localServices,omitempty"`
+
+	// The services used from the baseline site, as they are not included in this site
+	//+optional
+	InheritedServices []string `json:"inheritedServices,omitempty"`
+
+	// The conditions of the site
+	//+optional
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+type StagingSiteDatabaseMoveStatus struct {
+	// The name of the DatabaseMove object
+	Name string `json:"name"`
+
+	// The name of the service whose database is moved
+	ServiceName string `json:"serviceName"`
+
+	// The type of the moved database (mysql or mongo)
+	DatabaseType string `json:"databaseType"`
+
+	// The environment the database is moved from
+	SourceEnvironment string `json:"sourceEnvironment,omitempty"`
+
+	// The environment the database is moved to
+	TargetEnvironment string `json:"targetEnvironment"`
+
+	// The state of the move
+	State string `json:"state,omitempty"`
+
+	// The phase of the move
+	Phase string `json:"phase,omitempty"`
+
+	// The error message of a failed move
+	Message string `json:"message,omitempty"`
+}
+
+type StagingSiteWorkload struct {
+	//+kubebuilder:validation:Minimum=0
+	// The replica count of the workload. Defaults to the replica count in the service config
+	//+optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Override resource requirements for the containers in the workload pods
+	//+optional
+	ResourceOverrides map[string]corev1.ResourceRequirements `json:"resourceOverrides,omitempty"`
+}
+
+type StagingSiteAutoscaling struct {
+	// Set to FALSE to disable the autoscaling of the service in this site, and use the replica count of the site
+	// instead. Defaults to TRUE if the service config has autoscaling settings
+	//+optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	// The minimum replica count of the deployment. Defaults to the one in the service config
+	//+optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+	// The maximum replica count of the deployment. Defaults to the one in the service config
+	//+optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
 }
 
 type StagingSiteServiceStatus struct {
@@ -211,10 +327,60 @@ type StagingSiteServiceStatus struct {
 	// The database number to use for redis connections
 	RedisDatabaseNumber uint32 `json:"redisDatabaseNumber"`
 
+	// Whether the db sanitize job ran successfully after the initialisation of the databases
+	//+optional
+	DatabaseSanitized bool `json:"databaseSanitized,omitempty"`
+
 	// The status subentity of the created deployment
 	DeploymentStatus appsv1.DeploymentStatus `json:"deploymentStatus,omitempty"`
+
+	// The status of the additional workloads of the service, keyed by the workload name
+	//+optional
+	Workloads map[string]StagingSiteWorkloadStatus `json:"workloads,omitempty"`
+
+	// The external URL of the service, computed from the first host of its ingress
+	//+optional
+	ExternalUrl string `json:"externalUrl,omitempty"`
 }
 
+type ExtraObjectReference struct {
+	// The name of the service the object was created for
+	ServiceName string `json:"serviceName"`
+
+	// The api version of the object
+	APIVersion string `json:"apiVersion"`
+
+	// The kind of the object
+	Kind string `json:"kind"`
+
+	// The name of the object
+	Name string `json:"name"`
+}
+
+type StagingSiteWorkloadStatus struct {
+	// The kind of the workload
+	Kind configv1.WorkloadKind `json:"kind"`
+
+	// The desired replica count of the workload
+	Replicas int32 `json:"replicas"`
+
+	// The number of ready replicas of the workload
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// The health of the workload
+	Health WorkloadHealth `json:"health"`
+}
+
+const (
+	// ConditionEnvironmentMaintenance is TRUE if any of the environments used by the site is in maintenance or is
+	// being drained
+	ConditionEnvironmentMaintenance = "EnvironmentMaintenance"
+
+	ReasonEnvironmentsActive       = "EnvironmentsActive"
+	ReasonEnvironmentInMaintenance = "EnvironmentInMaintenance"
+	ReasonEnvironmentDraining      = "EnvironmentDraining"
+)
+
 // +kubebuilder:validation:Enum=Pending;Complete;Failed
 type StagingSiteState string
 
@@ -237,6 +403,7 @@ const (
 //+kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
 //+kubebuilder:printcolumn:name="Init-Source",type=string,JSONPath=`.spec.dumpSourceEnvironmentName`
 //+kubebuilder:printcolumn:name="Enabled",type=boolean,JSONPath=`.spec.enabled`
+//+kubebuilder:printcolumn:name="Baseline",type=string,JSONPath=`.spec.baselineSite`,priority=1
 //+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
 //+kubebuilder:printcolumn:name="Workload-Health",type=string,JSONPath=`.status.workloadHealth`
 //+kubebuilder:printcolumn:name="Next-Backup",type=string,JSONPath=`.status.nextBackupTime`
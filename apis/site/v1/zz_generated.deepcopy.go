@@ -22,9 +22,25 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtraObjectReference) DeepCopyInto(out *ExtraObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtraObjectReference.
+func (in *ExtraObjectReference) DeepCopy() *ExtraObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtraObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StagingSite) DeepCopyInto(out *StagingSite) {
 	*out = *in
@@ -52,6 +68,51 @@ func (in *StagingSite) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagingSiteAutoscaling) DeepCopyInto(out *StagingSiteAutoscaling) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagingSiteAutoscaling.
+func (in *StagingSiteAutoscaling) DeepCopy() *StagingSiteAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(StagingSiteAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagingSiteDatabaseMoveStatus) DeepCopyInto(out *StagingSiteDatabaseMoveStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagingSiteDatabaseMoveStatus.
+func (in *StagingSiteDatabaseMoveStatus) DeepCopy() *StagingSiteDatabaseMoveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StagingSiteDatabaseMoveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StagingSiteDefaulter) DeepCopyInto(out *StagingSiteDefaulter) {
 	*out = *in
@@ -109,6 +170,25 @@ func (in *StagingSiteService) DeepCopyInto(out *StagingSiteService) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make(map[string]StagingSiteWorkload, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(StagingSiteAutoscaling)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.ExtraEnvs != nil {
 		in, out := &in.ExtraEnvs, &out.ExtraEnvs
 		*out = make(map[string]string, len(*in))
@@ -139,6 +219,13 @@ func (in *StagingSiteService) DeepCopy() *StagingSiteService {
 func (in *StagingSiteServiceStatus) DeepCopyInto(out *StagingSiteServiceStatus) {
 	*out = *in
 	in.DeploymentStatus.DeepCopyInto(&out.DeploymentStatus)
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make(map[string]StagingSiteWorkloadStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagingSiteServiceStatus.
@@ -210,6 +297,38 @@ func (in *StagingSiteStatus) DeepCopyInto(out *StagingSiteStatus) {
 		in, out := &in.NextBackupTime, &out.NextBackupTime
 		*out = (*in).DeepCopy()
 	}
+	if in.DatabaseMoves != nil {
+		in, out := &in.DatabaseMoves, &out.DatabaseMoves
+		*out = make([]StagingSiteDatabaseMoveStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraObjects != nil {
+		in, out := &in.ExtraObjects, &out.ExtraObjects
+		*out = make([]ExtraObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.ChartObjects != nil {
+		in, out := &in.ChartObjects, &out.ChartObjects
+		*out = make([]ExtraObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.LocalServices != nil {
+		in, out := &in.LocalServices, &out.LocalServices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InheritedServices != nil {
+		in, out := &in.InheritedServices, &out.InheritedServices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagingSiteStatus.
@@ -222,6 +341,48 @@ func (in *StagingSiteStatus) DeepCopy() *StagingSiteStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagingSiteWorkload) DeepCopyInto(out *StagingSiteWorkload) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ResourceOverrides != nil {
+		in, out := &in.ResourceOverrides, &out.ResourceOverrides
+		*out = make(map[string]corev1.ResourceRequirements, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagingSiteWorkload.
+func (in *StagingSiteWorkload) DeepCopy() *StagingSiteWorkload {
+	if in == nil {
+		return nil
+	}
+	out := new(StagingSiteWorkload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StagingSiteWorkloadStatus) DeepCopyInto(out *StagingSiteWorkloadStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StagingSiteWorkloadStatus.
+func (in *StagingSiteWorkloadStatus) DeepCopy() *StagingSiteWorkloadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StagingSiteWorkloadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TimeInterval) DeepCopyInto(out *TimeInterval) {
 	*out = *in
@@ -1,5 +1,10 @@
 package v1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
 type EnvironmentConfig struct {
 	// Name of the service for this database. Empty for the main app
 	//+optional
@@ -35,4 +40,43 @@ const (
 	Pending  TaskState = "Pending"
 	Failed   TaskState = "Failed"
 	Complete TaskState = "Complete"
+	// Running is only used by the tasks that are run as batch jobs
+	Running TaskState = "Running"
 )
+
+// StatefulTask is implemented by the database tasks of every database provider, so their state can be read without
+// knowing their type
+// +kubebuilder:object:generate=false
+type StatefulTask interface {
+	GetTaskState() TaskState
+}
+
+// DatabaseInitMarker records that a database was initialised by a db init job, so it isn't initialised again over its
+// existing data when the job is recreated
+type DatabaseInitMarker struct {
+	// The name of the environment the database was initialised from
+	Source string `json:"source"`
+
+	// The hash of the db init pod spec the database was initialised with
+	PodSpecHash string `json:"podSpecHash"`
+
+	// The init reset token of the site service the database was initialised for
+	//+optional
+	ResetToken string `json:"resetToken,omitempty"`
+
+	// The time the initialisation was completed at
+	InitialisedAt metav1.Time `json:"initialisedAt"`
+}
+
+// Matches returns TRUE if both markers record an initialisation from the same source, pod spec and reset token
+func (r DatabaseInitMarker) Matches(other DatabaseInitMarker) bool {
+	return r.Source == other.Source && r.PodSpecHash == other.PodSpecHash && r.ResetToken == other.ResetToken
+}
+
+// InitialisableTask is implemented by the database tasks that are initialised by db init jobs
+// +kubebuilder:object:generate=false
+type InitialisableTask interface {
+	client.Object
+	GetInitMarker() *DatabaseInitMarker
+	SetInitMarker(marker *DatabaseInitMarker)
+}
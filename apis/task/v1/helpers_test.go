@@ -216,6 +216,55 @@ func TestRedisDatabase_UpdateFromExpected(t *testing.T) {
 	}
 }
 
+func TestObjectStorageBucket_PopulateFomSite(t *testing.T) {
+	t.Run("dedicated bucket", func(t *testing.T) {
+		site, config := makeTestSiteAndConfig()
+		storageConfig := &configv1.ObjectStorageConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "test-ns"},
+			Spec:       configv1.ObjectStorageConfigSpec{BucketNamePrefix: "staging-"},
+		}
+		bucket := &ObjectStorageBucket{}
+		if err := bucket.PopulateFomSite(site, config, storageConfig); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bucket.Spec.BucketName != "staging-test-site-svc" {
+			t.Errorf("BucketName = %q, want %q", bucket.Spec.BucketName, "staging-test-site-svc")
+		}
+		if bucket.Spec.Prefix != "" {
+			t.Errorf("Prefix = %q, want empty", bucket.Spec.Prefix)
+		}
+		if bucket.Labels[labels.ObjectStorageEnvironment] != "minio" {
+			t.Errorf("Label ObjectStorageEnvironment = %q, want %q", bucket.Labels[labels.ObjectStorageEnvironment], "minio")
+		}
+	})
+
+	t.Run("shared bucket", func(t *testing.T) {
+		site, config := makeTestSiteAndConfig()
+		storageConfig := &configv1.ObjectStorageConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "minio", Namespace: "test-ns"},
+			Spec:       configv1.ObjectStorageConfigSpec{SharedBucket: "uploads"},
+		}
+		bucket := &ObjectStorageBucket{}
+		if err := bucket.PopulateFomSite(site, config, storageConfig); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if bucket.Spec.BucketName != "uploads" {
+			t.Errorf("BucketName = %q, want %q", bucket.Spec.BucketName, "uploads")
+		}
+		if bucket.Spec.Prefix != "test-site/myservice/" {
+			t.Errorf("Prefix = %q, want %q", bucket.Spec.Prefix, "test-site/myservice/")
+		}
+	})
+
+	t.Run("nil storage config returns error", func(t *testing.T) {
+		site, config := makeTestSiteAndConfig()
+		bucket := &ObjectStorageBucket{}
+		if err := bucket.PopulateFomSite(site, config, nil); err == nil {
+			t.Error("expected error for nil object storage config")
+		}
+	})
+}
+
 func TestEnvironmentConfig_Getters(t *testing.T) {
 	ec := EnvironmentConfig{
 		ServiceName: "svc",
@@ -232,3 +281,80 @@ func TestEnvironmentConfig_Getters(t *testing.T) {
 		t.Errorf("GetEnvironment() = %q, want %q", ec.GetEnvironment(), "env")
 	}
 }
+
+func TestMysqlDatabase_PopulateFomSite_CharacterSet(t *testing.T) {
+	site, config := makeTestSiteAndConfig()
+	config.Spec.MysqlCharacterSet = "utf8mb4"
+	config.Spec.MysqlCollation = "utf8mb4_bin"
+
+	db := &MysqlDatabase{}
+	if err := db.PopulateFomSite(site, config, "prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if db.Spec.CharacterSet != "utf8mb4" {
+		t.Errorf("CharacterSet = %q, want %q", db.Spec.CharacterSet, "utf8mb4")
+	}
+	if db.Spec.Collation != "utf8mb4_bin" {
+		t.Errorf("Collation = %q, want %q", db.Spec.Collation, "utf8mb4_bin")
+	}
+}
+
+func TestMysqlDatabase_PopulateFomSite_Privileges(t *testing.T) {
+	site, config := makeTestSiteAndConfig()
+	config.Spec.MysqlPrivileges = &configv1.MysqlPrivilegeProfile{
+		Privileges: []configv1.MysqlPrivilege{"SELECT", "INSERT"},
+		Host:       "10.%",
+	}
+	config.Spec.MysqlAdditionalUsers = map[string]configv1.MysqlPrivilegeProfile{
+		"ro": {Privileges: []configv1.MysqlPrivilege{"SELECT"}},
+	}
+
+	db := &MysqlDatabase{}
+	if err := db.PopulateFomSite(site, config, "prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(db.Spec.Privileges) != 2 || db.Spec.Host != "10.%" {
+		t.Errorf("Privileges = %v, Host = %q", db.Spec.Privileges, db.Spec.Host)
+	}
+	user, ok := db.Spec.AdditionalUsers["ro"]
+	if !ok {
+		t.Fatal("missing additional user ro")
+	}
+	if user.Username != "testuser_svc_ro" {
+		t.Errorf("Username = %q, want %q", user.Username, "testuser_svc_ro")
+	}
+	if user.Password == "" || user.Password == site.Spec.Password {
+		t.Errorf("Password = %q, should be derived from the site password", user.Password)
+	}
+	if len(user.Privileges) != 1 || user.Privileges[0] != "SELECT" {
+		t.Errorf("Privileges = %v, want [SELECT]", user.Privileges)
+	}
+}
+
+func TestProvisionerTask_PopulateFomSite(t *testing.T) {
+	site, config := makeTestSiteAndConfig()
+	task := &ProvisionerTask{}
+	if err := task.PopulateFomSite(site, config, "bucket"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if task.Spec.Provisioner != "bucket" || task.Spec.ServiceName != config.Name || task.Spec.SiteName != site.Name {
+		t.Errorf("unexpected spec: %+v", task.Spec)
+	}
+	if task.Labels[labels.Provisioner] != "bucket" {
+		t.Errorf("provisioner label = %q, want %q", task.Labels[labels.Provisioner], "bucket")
+	}
+	if err := task.PopulateFomSite(site, nil, "bucket"); err == nil {
+		t.Error("expected an error without a service config")
+	}
+}
+
+func TestProvisionerTask_GetTemplateValues(t *testing.T) {
+	task := &ProvisionerTask{
+		Spec:   ProvisionerTaskSpec{Provisioner: "bucket"},
+		Status: ProvisionerTaskStatus{Outputs: map[string]string{"name": "site-assets"}},
+	}
+	values := task.GetTemplateValues()
+	if len(values) != 1 || values["provisioner.bucket.name"] != "site-assets" {
+		t.Errorf("GetTemplateValues() = %v", values)
+	}
+}
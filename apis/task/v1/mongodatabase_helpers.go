@@ -58,3 +58,32 @@ func (r *MongoDatabase) UpdateFromExpected(expected MongoDatabase) {
 	r.Namespace = expected.Namespace
 	r.Labels = expected.Labels
 }
+
+// SetEnvironment points the database to a different environment, keeping the environment label in sync
+func (r *MongoDatabase) SetEnvironment(environment string) {
+	r.Spec.EnvironmentConfig.Environment = environment
+	if r.Labels == nil {
+		r.Labels = map[string]string{}
+	}
+	r.Labels[labels.MongoEnvironment] = environment
+}
+
+func (r *MongoDatabase) GetEnvironmentConfig() EnvironmentConfig {
+	return r.Spec.EnvironmentConfig
+}
+
+func (r *MongoDatabase) GetState() TaskState {
+	return r.Status.State
+}
+
+func (r *MongoDatabase) GetTaskState() TaskState {
+	return r.Status.State
+}
+
+func (r *MongoDatabase) GetInitMarker() *DatabaseInitMarker {
+	return r.Status.InitMarker
+}
+
+func (r *MongoDatabase) SetInitMarker(marker *DatabaseInitMarker) {
+	r.Status.InitMarker = marker
+}
@@ -46,6 +46,15 @@ type MongoDatabaseSpec struct {
 	Password string `json:"password"`
 }
 
+// MongoDatabaseStatus defines the observed state of MongoDatabase
+type MongoDatabaseStatus struct {
+	TaskStatus `json:",inline"`
+
+	// The marker of the initialisation of the database. Not set until the db init job of the service completes
+	//+optional
+	InitMarker *DatabaseInitMarker `json:"initMarker,omitempty"`
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.environmentConfig.siteName`
@@ -54,14 +63,15 @@ type MongoDatabaseSpec struct {
 //+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseName`
 //+kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
 //+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Initialised-From",type=string,JSONPath=`.status.initMarker.source`,priority=1
 
 // MongoDatabase is the Schema for the mongodatabases API
 type MongoDatabase struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   MongoDatabaseSpec `json:"spec,omitempty"`
-	Status TaskStatus        `json:"status,omitempty"`
+	Spec   MongoDatabaseSpec   `json:"spec,omitempty"`
+	Status MongoDatabaseStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
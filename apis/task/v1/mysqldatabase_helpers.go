@@ -39,7 +39,27 @@ func (r *MysqlDatabase) PopulateFomSite(
 		DatabaseName: api.MakeDatabaseName(site, config),
 		Username:     api.MakeUsername(site, config),
 		Password:     site.Spec.Password,
+		CharacterSet: config.Spec.MysqlCharacterSet,
+		Collation:    config.Spec.MysqlCollation,
 	}
+
+	if config.Spec.MysqlPrivileges != nil {
+		r.Spec.Privileges = config.Spec.MysqlPrivileges.Privileges
+		r.Spec.Host = config.Spec.MysqlPrivileges.Host
+	}
+
+	if len(config.Spec.MysqlAdditionalUsers) > 0 {
+		r.Spec.AdditionalUsers = make(map[string]MysqlDatabaseUser, len(config.Spec.MysqlAdditionalUsers))
+		for name, profile := range config.Spec.MysqlAdditionalUsers {
+			r.Spec.AdditionalUsers[name] = MysqlDatabaseUser{
+				Username:   api.MakeMysqlAdditionalUsername(site, config, name),
+				Password:   api.MakeMysqlAdditionalUserPassword(site, config, name),
+				Privileges: profile.Privileges,
+				Host:       profile.Host,
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -56,3 +76,32 @@ func (r *MysqlDatabase) UpdateFromExpected(expected MysqlDatabase) {
 	r.Namespace = expected.Namespace
 	r.Labels = expected.Labels
 }
+
+// SetEnvironment points the database to a different environment, keeping the environment label in sync
+func (r *MysqlDatabase) SetEnvironment(environment string) {
+	r.Spec.EnvironmentConfig.Environment = environment
+	if r.Labels == nil {
+		r.Labels = map[string]string{}
+	}
+	r.Labels[labels.MysqlEnvironment] = environment
+}
+
+func (r *MysqlDatabase) GetEnvironmentConfig() EnvironmentConfig {
+	return r.Spec.EnvironmentConfig
+}
+
+func (r *MysqlDatabase) GetState() TaskState {
+	return r.Status.State
+}
+
+func (r *MysqlDatabase) GetTaskState() TaskState {
+	return r.Status.State
+}
+
+func (r *MysqlDatabase) GetInitMarker() *DatabaseInitMarker {
+	return r.Status.InitMarker
+}
+
+func (r *MysqlDatabase) SetInitMarker(marker *DatabaseInitMarker) {
+	r.Status.InitMarker = marker
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,6 +45,68 @@ type MysqlDatabaseSpec struct {
 	//+kubebuilder:validation:MaxLength=32
 	// The password for the user
 	Password string `json:"password"`
+
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]*$`
+	// The character set to create the database with. The environment default is used if neither this nor the
+	// collation is set
+	//+optional
+	CharacterSet string `json:"characterSet,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]*$`
+	// The collation to create the database with. The environment default is used if neither this nor the character
+	// set is set
+	//+optional
+	Collation string `json:"collation,omitempty"`
+
+	// The privileges to grant to the user on the database. Defaults to ALL
+	//+optional
+	Privileges []configv1.MysqlPrivilege `json:"privileges,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[-a-zA-Z0-9._%:]*$`
+	// The host pattern the user is allowed to connect from. Defaults to %
+	//+optional
+	Host string `json:"host,omitempty"`
+
+	// Additional users to create with access to the database, keyed by their short name
+	//+optional
+	AdditionalUsers map[string]MysqlDatabaseUser `json:"additionalUsers,omitempty"`
+}
+
+type MysqlDatabaseUser struct {
+	//+kubebuilder:validation:Pattern=[_a-zA-Z0-9]+
+	//+kubebuilder:validation:MinLength=1
+	//+kubebuilder:validation:MaxLength=16
+	// The username for the user
+	Username string `json:"username"`
+
+	//+kubebuilder:validation:Pattern=[_a-zA-Z0-9]+
+	//+kubebuilder:validation:MinLength=1
+	//+kubebuilder:validation:MaxLength=32
+	// The password for the user
+	Password string `json:"password"`
+
+	// The privileges to grant to the user on the database. Defaults to ALL
+	//+optional
+	Privileges []configv1.MysqlPrivilege `json:"privileges,omitempty"`
+
+	//+kubebuilder:validation:Pattern=`^[-a-zA-Z0-9._%:]*$`
+	// The host pattern the user is allowed to connect from. Defaults to %
+	//+optional
+	Host string `json:"host,omitempty"`
+}
+
+// MysqlDatabaseStatus defines the observed state of MysqlDatabase
+type MysqlDatabaseStatus struct {
+	TaskStatus `json:",inline"`
+
+	// The usernames of the additional users provisioned on the server. Used to remove users that are no longer
+	// requested
+	//+optional
+	AdditionalUsers []string `json:"additionalUsers,omitempty"`
+
+	// The marker of the initialisation of the database. Not set until the db init job of the service completes
+	//+optional
+	InitMarker *DatabaseInitMarker `json:"initMarker,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -54,14 +117,15 @@ type MysqlDatabaseSpec struct {
 //+kubebuilder:printcolumn:name="Database",type=string,JSONPath=`.spec.databaseName`
 //+kubebuilder:printcolumn:name="Username",type=string,JSONPath=`.spec.username`
 //+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Initialised-From",type=string,JSONPath=`.status.initMarker.source`,priority=1
 
 // MysqlDatabase is the Schema for the mysqldatabases API
 type MysqlDatabase struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   MysqlDatabaseSpec `json:"spec,omitempty"`
-	Status TaskStatus        `json:"status,omitempty"`
+	Spec   MysqlDatabaseSpec   `json:"spec,omitempty"`
+	Status MysqlDatabaseStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
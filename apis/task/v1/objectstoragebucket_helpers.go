@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"errors"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"strings"
+)
+
+func (r *ObjectStorageBucket) PopulateFomSite(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	storageConfig *configv1.ObjectStorageConfig,
+) error {
+	if config == nil {
+		return errors.New("no service config provided")
+	}
+	if storageConfig == nil {
+		return errors.New("no object storage config provided")
+	}
+
+	r.ObjectMeta = metav1.ObjectMeta{
+		Name:      helpers.ShortenHumanReadableValue(site.Name, 50) + "-" + config.Spec.ShortName,
+		Namespace: site.Namespace,
+		Labels: map[string]string{
+			labels.Site:                     site.Name,
+			labels.Service:                  config.Name,
+			labels.ObjectStorageEnvironment: storageConfig.Name,
+		},
+		Annotations: map[string]string{},
+	}
+	r.Spec = ObjectStorageBucketSpec{
+		EnvironmentConfig: EnvironmentConfig{
+			ServiceName: config.Name,
+			SiteName:    site.Name,
+			Environment: storageConfig.Name,
+		},
+	}
+
+	if storageConfig.Spec.SharedBucket != "" {
+		r.Spec.BucketName = storageConfig.Spec.SharedBucket
+		r.Spec.Prefix = site.Name + "/" + config.Name + "/"
+	} else {
+		r.Spec.BucketName = strings.ToLower(
+			helpers.ShortenHumanReadableValue(storageConfig.Spec.BucketNamePrefix+site.Name+"-"+config.Spec.ShortName, 63),
+		)
+	}
+
+	return nil
+}
+
+func (r *ObjectStorageBucket) Matches(other ObjectStorageBucket) bool {
+	return reflect.DeepEqual(r.Spec, other.Spec) &&
+		r.Name == other.Name &&
+		r.Namespace == other.Namespace &&
+		reflect.DeepEqual(r.Labels, other.Labels)
+}
+
+func (r *ObjectStorageBucket) UpdateFromExpected(expected ObjectStorageBucket) {
+	r.Spec = expected.Spec
+	r.Name = expected.Name
+	r.Namespace = expected.Namespace
+	r.Labels = expected.Labels
+}
+
+func (r *ObjectStorageBucket) GetTaskState() TaskState {
+	return r.Status.State
+}
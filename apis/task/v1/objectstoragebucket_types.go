@@ -0,0 +1,83 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ObjectStorageBucketSpec defines the desired state of ObjectStorageBucket
+type ObjectStorageBucketSpec struct {
+	EnvironmentConfig EnvironmentConfig `json:"environmentConfig"`
+
+	//+kubebuilder:validation:MinLength=3
+	// Name of the bucket
+	BucketName string `json:"bucketName"`
+
+	// The prefix of the objects of the site in the bucket if the bucket is shared. Empty if the bucket belongs to the
+	// site
+	//+optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// ObjectStorageBucketStatus defines the observed state of ObjectStorageBucket
+type ObjectStorageBucketStatus struct {
+	// The state of the task. Pending/Failed/Complete
+	State TaskState `json:"state"`
+
+	// The access key the site uses to access the bucket
+	//+optional
+	AccessKey string `json:"accessKey,omitempty"`
+
+	// The secret key the site uses to access the bucket
+	//+optional
+	SecretKey string `json:"secretKey,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.environmentConfig.siteName`
+//+kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.spec.environmentConfig.serviceName`
+//+kubebuilder:printcolumn:name="Environment",type=string,JSONPath=`.spec.environmentConfig.environment`
+//+kubebuilder:printcolumn:name="Bucket",type=string,JSONPath=`.spec.bucketName`
+//+kubebuilder:printcolumn:name="Prefix",type=string,JSONPath=`.spec.prefix`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+
+// ObjectStorageBucket is the Schema for the objectstoragebuckets API
+type ObjectStorageBucket struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ObjectStorageBucketSpec   `json:"spec,omitempty"`
+	Status ObjectStorageBucketStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ObjectStorageBucketList contains a list of ObjectStorageBucket
+type ObjectStorageBucketList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ObjectStorageBucket `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ObjectStorageBucket{}, &ObjectStorageBucketList{})
+}
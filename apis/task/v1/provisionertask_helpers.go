@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"errors"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+)
+
+func (r *ProvisionerTask) PopulateFomSite(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	provisionerName string,
+) error {
+	if config == nil {
+		return errors.New("no service config provided")
+	}
+
+	r.ObjectMeta = metav1.ObjectMeta{
+		Name:      helpers.ShortenHumanReadableValue(site.Name+"-"+config.Spec.ShortName+"-"+provisionerName, 63),
+		Namespace: site.Namespace,
+		Labels: map[string]string{
+			labels.Site:        site.Name,
+			labels.Service:     config.Name,
+			labels.Provisioner: provisionerName,
+		},
+		Annotations: map[string]string{},
+	}
+	r.Spec = ProvisionerTaskSpec{
+		SiteName:        site.Name,
+		ServiceName:     config.Name,
+		Provisioner:     provisionerName,
+		DeadlineSeconds: 600,
+	}
+
+	return nil
+}
+
+func (r *ProvisionerTask) Matches(other ProvisionerTask) bool {
+	return reflect.DeepEqual(r.Spec, other.Spec) &&
+		r.Name == other.Name &&
+		r.Namespace == other.Namespace &&
+		reflect.DeepEqual(r.Labels, other.Labels)
+}
+
+func (r *ProvisionerTask) UpdateFromExpected(expected ProvisionerTask) {
+	r.Spec = expected.Spec
+	r.Name = expected.Name
+	r.Namespace = expected.Namespace
+	r.Labels = expected.Labels
+}
+
+func (r *ProvisionerTask) GetTaskState() TaskState {
+	return r.Status.State
+}
+
+// GetTemplateValues returns the outputs of the provision pod as provisioner.<name>.<key> values
+func (r *ProvisionerTask) GetTemplateValues() map[string]string {
+	result := make(map[string]string, len(r.Status.Outputs))
+	for key, value := range r.Status.Outputs {
+		result["provisioner."+r.Spec.Provisioner+"."+key] = value
+	}
+
+	return result
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ProvisionerTaskSpec defines the desired state of ProvisionerTask
+type ProvisionerTaskSpec struct {
+	//+kubebuilder:validation:MinLength=1
+	// Name of the site this task is associated with
+	SiteName string `json:"siteName"`
+
+	//+kubebuilder:validation:MinLength=1
+	// Name of the service using the provisioner
+	ServiceName string `json:"serviceName"`
+
+	//+kubebuilder:validation:MinLength=1
+	// Name of the provisioner config to run
+	Provisioner string `json:"provisioner"`
+
+	// The number of seconds to use as the completion deadline of the provision and deprovision jobs
+	DeadlineSeconds int64 `json:"deadlineSeconds"`
+}
+
+// ProvisionerTaskStatus defines the observed state of ProvisionerTask
+type ProvisionerTaskStatus struct {
+	// The state of the provisioning. Pending/Running/Failed/Complete
+	State TaskState `json:"state"`
+
+	// The state of the deprovisioning once the task is deleted. Pending/Running/Failed/Complete
+	//+optional
+	DeprovisionState TaskState `json:"deprovisionState,omitempty"`
+
+	//+kubebuilder:default:=0
+	// Number of consecutive times the related batch job failed to load
+	JobNotFoundCount uint32 `json:"jobNotFoundCount"`
+
+	// The deadline for the completion of the current job, after which the task will be marked as failed if the job
+	// didn't run to completion yet
+	DeadlineTimestamp *metav1.Time `json:"deadlineTimestamp"`
+
+	// The outputs written by the provision pod to its termination message
+	//+optional
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Site",type=string,JSONPath=`.spec.siteName`
+//+kubebuilder:printcolumn:name="Service",type=string,JSONPath=`.spec.serviceName`
+//+kubebuilder:printcolumn:name="Provisioner",type=string,JSONPath=`.spec.provisioner`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+
+// ProvisionerTask is the Schema for the provisionertasks API
+type ProvisionerTask struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProvisionerTaskSpec   `json:"spec,omitempty"`
+	Status ProvisionerTaskStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProvisionerTaskList contains a list of ProvisionerTask
+type ProvisionerTaskList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProvisionerTask `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProvisionerTask{}, &ProvisionerTaskList{})
+}
@@ -53,3 +53,7 @@ func (r *RedisDatabase) UpdateFromExpected(expected RedisDatabase) {
 	r.Namespace = expected.Namespace
 	r.Labels = expected.Labels
 }
+
+func (r *RedisDatabase) GetTaskState() TaskState {
+	return r.Status.State
+}
@@ -21,9 +21,26 @@ limitations under the License.
 package v1
 
 import (
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseInitMarker) DeepCopyInto(out *DatabaseInitMarker) {
+	*out = *in
+	in.InitialisedAt.DeepCopyInto(&out.InitialisedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseInitMarker.
+func (in *DatabaseInitMarker) DeepCopy() *DatabaseInitMarker {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseInitMarker)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EnvironmentConfig) DeepCopyInto(out *EnvironmentConfig) {
 	*out = *in
@@ -45,7 +62,7 @@ func (in *MongoDatabase) DeepCopyInto(out *MongoDatabase) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDatabase.
@@ -114,13 +131,34 @@ func (in *MongoDatabaseSpec) DeepCopy() *MongoDatabaseSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MongoDatabaseStatus) DeepCopyInto(out *MongoDatabaseStatus) {
+	*out = *in
+	out.TaskStatus = in.TaskStatus
+	if in.InitMarker != nil {
+		in, out := &in.InitMarker, &out.InitMarker
+		*out = new(DatabaseInitMarker)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MongoDatabaseStatus.
+func (in *MongoDatabaseStatus) DeepCopy() *MongoDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MongoDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MysqlDatabase) DeepCopyInto(out *MysqlDatabase) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabase.
@@ -177,6 +215,18 @@ func (in *MysqlDatabaseList) DeepCopyObject() runtime.Object {
 func (in *MysqlDatabaseSpec) DeepCopyInto(out *MysqlDatabaseSpec) {
 	*out = *in
 	out.EnvironmentConfig = in.EnvironmentConfig
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]configv1.MysqlPrivilege, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalUsers != nil {
+		in, out := &in.AdditionalUsers, &out.AdditionalUsers
+		*out = make(map[string]MysqlDatabaseUser, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabaseSpec.
@@ -189,6 +239,242 @@ func (in *MysqlDatabaseSpec) DeepCopy() *MysqlDatabaseSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlDatabaseStatus) DeepCopyInto(out *MysqlDatabaseStatus) {
+	*out = *in
+	out.TaskStatus = in.TaskStatus
+	if in.AdditionalUsers != nil {
+		in, out := &in.AdditionalUsers, &out.AdditionalUsers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitMarker != nil {
+		in, out := &in.InitMarker, &out.InitMarker
+		*out = new(DatabaseInitMarker)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabaseStatus.
+func (in *MysqlDatabaseStatus) DeepCopy() *MysqlDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlDatabaseUser) DeepCopyInto(out *MysqlDatabaseUser) {
+	*out = *in
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]configv1.MysqlPrivilege, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabaseUser.
+func (in *MysqlDatabaseUser) DeepCopy() *MysqlDatabaseUser {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlDatabaseUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageBucket) DeepCopyInto(out *ObjectStorageBucket) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageBucket.
+func (in *ObjectStorageBucket) DeepCopy() *ObjectStorageBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ObjectStorageBucket) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageBucketList) DeepCopyInto(out *ObjectStorageBucketList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ObjectStorageBucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageBucketList.
+func (in *ObjectStorageBucketList) DeepCopy() *ObjectStorageBucketList {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageBucketList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ObjectStorageBucketList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageBucketSpec) DeepCopyInto(out *ObjectStorageBucketSpec) {
+	*out = *in
+	out.EnvironmentConfig = in.EnvironmentConfig
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageBucketSpec.
+func (in *ObjectStorageBucketSpec) DeepCopy() *ObjectStorageBucketSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageBucketSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageBucketStatus) DeepCopyInto(out *ObjectStorageBucketStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageBucketStatus.
+func (in *ObjectStorageBucketStatus) DeepCopy() *ObjectStorageBucketStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageBucketStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerTask) DeepCopyInto(out *ProvisionerTask) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerTask.
+func (in *ProvisionerTask) DeepCopy() *ProvisionerTask {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerTask)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProvisionerTask) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerTaskList) DeepCopyInto(out *ProvisionerTaskList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProvisionerTask, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerTaskList.
+func (in *ProvisionerTaskList) DeepCopy() *ProvisionerTaskList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerTaskList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProvisionerTaskList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerTaskSpec) DeepCopyInto(out *ProvisionerTaskSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerTaskSpec.
+func (in *ProvisionerTaskSpec) DeepCopy() *ProvisionerTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerTaskStatus) DeepCopyInto(out *ProvisionerTaskStatus) {
+	*out = *in
+	if in.DeadlineTimestamp != nil {
+		in, out := &in.DeadlineTimestamp, &out.DeadlineTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerTaskStatus.
+func (in *ProvisionerTaskStatus) DeepCopy() *ProvisionerTaskStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerTaskStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisDatabase) DeepCopyInto(out *RedisDatabase) {
 	*out = *in
@@ -0,0 +1,59 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package environment
+
+import (
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"time"
+)
+
+// defaultProbeInterval is used if the reconciler doesn't have a probe interval set
+const defaultProbeInterval = time.Minute
+
+func getProbeInterval(probeInterval time.Duration) time.Duration {
+	if probeInterval <= 0 {
+		return defaultProbeInterval
+	}
+
+	return probeInterval
+}
+
+// getRequeueDelay returns the probe interval, or the delay until the next orphan sweep if that's sooner
+func getRequeueDelay(probeInterval time.Duration, sweepDelay time.Duration) time.Duration {
+	if sweepDelay > 0 && sweepDelay < probeInterval {
+		return sweepDelay
+	}
+
+	return probeInterval
+}
+
+func updateEnvironmentMetrics(namespace string, environment string, databaseType string, isUp bool, provisioned int32) {
+	up := 0.0
+	if isUp {
+		up = 1
+	}
+
+	appmetrics.EnvironmentUp.WithLabelValues(namespace, environment, databaseType).Set(up)
+	appmetrics.EnvironmentProvisionedDatabases.WithLabelValues(namespace, environment, databaseType).
+		Set(float64(provisioned))
+}
+
+func deleteEnvironmentMetrics(namespace string, environment string, databaseType string) {
+	appmetrics.EnvironmentUp.DeleteLabelValues(namespace, environment, databaseType)
+	appmetrics.EnvironmentProvisionedDatabases.DeleteLabelValues(namespace, environment, databaseType)
+	appmetrics.EnvironmentFreeDatabases.DeleteLabelValues(namespace, environment, databaseType)
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package environment
+
+import (
+	"context"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-logr/logr"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/handlers/database"
+	"github.com/szeber/kube-stager/helpers"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"time"
+)
+
+// MongoConfigReconciler reconciles a MongoConfig object
+type MongoConfigReconciler struct {
+	client.Client
+	Scheme             *runtime.Scheme
+	EnvironmentHandler database.MongoEnvironmentHandler
+	// APIReader lists the configs and database tasks of every namespace without caching them, so the orphan sweeper
+	// sees the other namespaces sharing the server
+	APIReader     client.Reader
+	Connections   *database.ConnectionManager
+	ProbeInterval time.Duration
+	Clock
+}
+
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=mongoconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=mongoconfigs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mongodatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=serviceconfigs,verbs=get;list;watch
+
+// Reconcile probes the server of the config and runs the orphan sweeper if it's enabled and a sweep is due
+func (r *MongoConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.doReconcile(ctx, req)
+
+	if err != nil {
+		appmetrics.Errors.WithLabelValues("mongoconfig", "false").Inc()
+		sentry.CaptureException(err)
+	}
+
+	return result, err
+}
+
+func (r *MongoConfigReconciler) doReconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var config configv1.MongoConfig
+	if err := r.Get(ctx, req.NamespacedName, &config); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to fetch mongo config")
+		} else {
+			deleteEnvironmentMetrics(req.Namespace, req.Name, "mongo")
+			deleteOrphanMetrics(req.Namespace, req.Name, "mongo")
+		}
+
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	originalStatus := config.Status.DeepCopy()
+
+	now := r.Now()
+
+	var databaseList taskv1.MongoDatabaseList
+	if err := r.List(ctx, &databaseList, client.InNamespace(config.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var provisionedCount int32
+	for _, db := range databaseList.Items {
+		if db.Spec.EnvironmentConfig.Environment == config.Name {
+			provisionedCount++
+		}
+	}
+
+	serverVersion, probeErr := r.EnvironmentHandler.Probe(ctx, config, logger)
+	if probeErr != nil {
+		logger.Error(probeErr, "Probing the mongo server failed")
+	}
+
+	config.Status.ProvisionedDatabaseCount = provisionedCount
+	config.Status.SetProbeResult(serverVersion, probeErr, metav1.NewTime(now), config.Generation)
+	updateEnvironmentMetrics(config.Namespace, config.Name, "mongo", probeErr == nil, provisionedCount)
+
+	if config.Spec.OrphanSweeper.IsEnabled() {
+		// The names of the tasks are recorded on every reconcile, so the sweeper only ever considers the databases and
+		// users that were created by the operator
+		var tasks []taskv1.MongoDatabase
+		for _, db := range databaseList.Items {
+			if db.Spec.EnvironmentConfig.Environment == config.Name {
+				tasks = append(tasks, db)
+			}
+		}
+		databases, users := getMongoTaskResourceNames(tasks)
+		config.Status.ManagedResources = database.RecordManagedResources(
+			config.Status.ManagedResources,
+			helpers.GetKeysFromStringBoolMap(databases),
+			helpers.GetKeysFromStringBoolMap(users),
+		)
+	}
+
+	var sweepDelay time.Duration
+	var sweepErr error
+	// The sweep would fail anyway if the server can't be reached, and must not touch a server in maintenance
+	if probeErr == nil && !config.Spec.Mode.IsInMaintenance() {
+		sweepDelay, sweepErr = r.sweepOrphans(ctx, &config, now, logger)
+	}
+
+	// The probe time alone is not written, so the periodic probes don't update the config if nothing else changed
+	originalStatus.LastProbeTime = config.Status.LastProbeTime
+	if !equality.Semantic.DeepEqual(*originalStatus, config.Status) {
+		if err := r.Status().Update(ctx, &config); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: getRequeueDelay(getProbeInterval(r.ProbeInterval), sweepDelay)}, sweepErr
+}
+
+// sweepOrphans runs the orphan sweeper if it's due and updates the status of the config with the result. Returns the
+// delay until the next sweep.
+func (r *MongoConfigReconciler) sweepOrphans(
+	ctx context.Context,
+	config *configv1.MongoConfig,
+	now time.Time,
+	logger logr.Logger,
+) (time.Duration, error) {
+	sweeper := config.Spec.OrphanSweeper
+
+	if !sweeper.IsEnabled() {
+		deleteOrphanMetrics(config.Namespace, config.Name, "mongo")
+		config.Status.LastOrphanSweepTime = nil
+		config.Status.Orphans = nil
+		config.Status.ManagedResources = nil
+
+		return 0, nil
+	}
+
+	if delay := getNextSweepDelay(sweeper, config.Status.LastOrphanSweepTime, now); delay > 0 {
+		return delay, nil
+	}
+
+	logger.Info("Sweeping orphaned databases and users")
+
+	databases, users, err := r.EnvironmentHandler.ListServerResources(ctx, *config, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	referencedDatabases, referencedUsers, err := r.getReferencedResources(ctx, config)
+	if err != nil {
+		return 0, err
+	}
+
+	shortNames, err := getServiceShortNames(ctx, r.APIReader)
+	if err != nil {
+		return 0, err
+	}
+
+	orphans, dropped, sweepErr := orphanSweep{
+		sweeper:             sweeper,
+		previousOrphans:     config.Status.Orphans,
+		now:                 now,
+		databases:           databases,
+		users:               users,
+		referencedDatabases: referencedDatabases,
+		referencedUsers:     referencedUsers,
+		managed:             config.Status.ManagedResources,
+		shortNames:          shortNames,
+		dropDatabase: func(name string) error {
+			return r.EnvironmentHandler.DropDatabase(ctx, *config, name, logger)
+		},
+		dropUser: func(name string) error {
+			return r.EnvironmentHandler.DropUser(ctx, *config, name, logger)
+		},
+	}.run(logger)
+
+	updateOrphanMetrics(config.Namespace, config.Name, "mongo", orphans, dropped)
+
+	config.Status.ManagedResources = database.PruneManagedResources(
+		config.Status.ManagedResources,
+		databases,
+		users,
+		dropped,
+		referencedDatabases,
+		referencedUsers,
+	)
+
+	sweepTime := metav1.NewTime(now)
+	config.Status.LastOrphanSweepTime = &sweepTime
+	config.Status.Orphans = orphans
+
+	logger.Info("Orphan sweep complete", "orphans", len(orphans), "dropped", len(dropped))
+
+	return sweeper.GetInterval(), sweepErr
+}
+
+// getReferencedResources returns the databases and users used by the database tasks of every namespace with a config
+// for the same server. Tasks pointing at other environments are also taken into account, so a name is never dropped
+// while any task could still own it
+func (r *MongoConfigReconciler) getReferencedResources(
+	ctx context.Context,
+	config *configv1.MongoConfig,
+) (map[string]bool, map[string]bool, error) {
+	var configList configv1.MongoConfigList
+	if err := r.APIReader.List(ctx, &configList); err != nil {
+		return nil, nil, err
+	}
+
+	namespaces := map[string]bool{config.Namespace: true}
+	for _, other := range configList.Items {
+		if other.Spec.IsSameServer(config.Spec) {
+			namespaces[other.Namespace] = true
+		}
+	}
+
+	var databaseList taskv1.MongoDatabaseList
+	if err := r.APIReader.List(ctx, &databaseList); err != nil {
+		return nil, nil, err
+	}
+
+	var tasks []taskv1.MongoDatabase
+	for _, db := range databaseList.Items {
+		if namespaces[db.Namespace] {
+			tasks = append(tasks, db)
+		}
+	}
+	databases, users := getMongoTaskResourceNames(tasks)
+
+	return databases, users, nil
+}
+
+func getMongoTaskResourceNames(tasks []taskv1.MongoDatabase) (map[string]bool, map[string]bool) {
+	databases := make(map[string]bool)
+	users := make(map[string]bool)
+	for _, db := range tasks {
+		databases[db.Spec.DatabaseName] = true
+		users[db.Spec.Username] = true
+	}
+
+	return databases, users
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MongoConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	if r.EnvironmentHandler == nil {
+		r.EnvironmentHandler = database.DefaultMongoEnvironmentHandler{Connections: r.Connections}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&configv1.MongoConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
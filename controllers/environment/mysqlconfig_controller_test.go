@@ -0,0 +1,334 @@
+package environment
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/internal/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newSweeperTestReconciler(
+	config *configv1.MysqlConfig,
+	handler *testutil.MockMysqlEnvironmentHandler,
+	clock *testutil.MockClock,
+	objs ...client.Object,
+) *MysqlConfigReconciler {
+	c := testutil.NewFakeClient(append(objs, config)...)
+	return &MysqlConfigReconciler{
+		Client:             c,
+		Scheme:             c.Scheme(),
+		EnvironmentHandler: handler,
+		APIReader:          c,
+		ProbeInterval:      24 * time.Hour,
+		Clock:              clock,
+	}
+}
+
+func reconcileMysqlConfig(t *testing.T, r *MysqlConfigReconciler) (ctrl.Result, configv1.MysqlConfig) {
+	t.Helper()
+	key := types.NamespacedName{Namespace: "test-ns", Name: "mysql1"}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var config configv1.MysqlConfig
+	if err := r.Get(context.Background(), key, &config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result, config
+}
+
+func TestMysqlConfigReconciler_SweeperDisabled(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	handler.Databases = []string{"dead_web"}
+	clock := &testutil.MockClock{}
+	clock.SetNow(time.Now())
+	r := newSweeperTestReconciler(config, handler, clock)
+
+	result, updated := reconcileMysqlConfig(t, r)
+
+	if result.RequeueAfter != 24*time.Hour {
+		t.Errorf("RequeueAfter = %v, want the probe interval", result.RequeueAfter)
+	}
+	if updated.Status.LastOrphanSweepTime != nil || len(updated.Status.Orphans) != 0 {
+		t.Errorf("status = %+v, want no sweep results", updated.Status)
+	}
+}
+
+func TestMysqlConfigReconciler_SweepWithGracePeriod(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	config.Spec.OrphanSweeper = &configv1.OrphanSweeperSpec{Enabled: true, IntervalMinutes: 60, GracePeriodMinutes: 90}
+	// dead_web was recorded from the task of a site that has been deleted since
+	config.Status.ManagedResources = &configv1.ManagedResources{Databases: []string{"dead_web"}, Users: []string{"dead_web"}}
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	handler.Databases = []string{"live_web", "dead_web", "other"}
+	handler.Users = []string{"testuser", "dead_web"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &testutil.MockClock{}
+	clock.SetNow(start)
+	db := testutil.NewTestMysqlDatabase("live_web", "test-ns", "site1", "web", "mysql1")
+	r := newSweeperTestReconciler(config, handler, clock, db)
+
+	result, updated := reconcileMysqlConfig(t, r)
+
+	if result.RequeueAfter != time.Hour {
+		t.Errorf("RequeueAfter = %v, want 1h", result.RequeueAfter)
+	}
+	if len(updated.Status.Orphans) != 2 {
+		t.Fatalf("Orphans = %+v, want the dead_web database and user", updated.Status.Orphans)
+	}
+	if len(handler.DroppedDatabases()) != 0 || len(handler.DroppedUsers()) != 0 {
+		t.Error("nothing should be dropped within the grace period")
+	}
+
+	// Not due yet
+	clock.SetNow(start.Add(30 * time.Minute))
+	result, _ = reconcileMysqlConfig(t, r)
+	if result.RequeueAfter != 30*time.Minute {
+		t.Errorf("RequeueAfter = %v, want 30m", result.RequeueAfter)
+	}
+
+	clock.SetNow(start.Add(2 * time.Hour))
+	_, updated = reconcileMysqlConfig(t, r)
+
+	if !reflect.DeepEqual(handler.DroppedDatabases(), []string{"dead_web"}) {
+		t.Errorf("DroppedDatabases = %v, want [dead_web]", handler.DroppedDatabases())
+	}
+	if !reflect.DeepEqual(handler.DroppedUsers(), []string{"dead_web"}) {
+		t.Errorf("DroppedUsers = %v, want [dead_web]", handler.DroppedUsers())
+	}
+	if len(updated.Status.Orphans) != 0 {
+		t.Errorf("Orphans = %+v, want none", updated.Status.Orphans)
+	}
+	expectedManaged := &configv1.ManagedResources{Databases: []string{"live_web"}, Users: []string{"testuser"}}
+	if !reflect.DeepEqual(updated.Status.ManagedResources, expectedManaged) {
+		t.Errorf("ManagedResources = %+v, want %+v", updated.Status.ManagedResources, expectedManaged)
+	}
+}
+
+func TestMysqlConfigReconciler_RecordsManagedResources(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	config.Spec.OrphanSweeper = &configv1.OrphanSweeperSpec{Enabled: true, IntervalMinutes: 60, GracePeriodMinutes: 1}
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	// Users and databases that look like the generated ones, but weren't created for a task of this environment
+	handler.Databases = []string{"live_web", "other_web"}
+	handler.Users = []string{"testuser", "replicator", "monitoring"}
+	clock := &testutil.MockClock{}
+	clock.SetNow(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := newSweeperTestReconciler(
+		config,
+		handler,
+		clock,
+		testutil.NewTestMysqlDatabase("live_web", "test-ns", "site1", "web", "mysql1"),
+		testutil.NewTestMysqlDatabase("elsewhere_web", "test-ns", "site2", "web", "mysql2"),
+	)
+
+	_, updated := reconcileMysqlConfig(t, r)
+
+	expectedManaged := &configv1.ManagedResources{Databases: []string{"live_web"}, Users: []string{"testuser"}}
+	if !reflect.DeepEqual(updated.Status.ManagedResources, expectedManaged) {
+		t.Errorf("ManagedResources = %+v, want %+v", updated.Status.ManagedResources, expectedManaged)
+	}
+
+	clock.SetNow(clock.Now().Add(48 * time.Hour))
+	_, updated = reconcileMysqlConfig(t, r)
+
+	if len(updated.Status.Orphans) != 0 || len(handler.DroppedDatabases()) != 0 || len(handler.DroppedUsers()) != 0 {
+		t.Errorf("Orphans = %+v, want the names not created by the operator to be left alone", updated.Status.Orphans)
+	}
+}
+
+func TestMysqlConfigReconciler_ReportsUnrecordedNames(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	config.Spec.OrphanSweeper = &configv1.OrphanSweeperSpec{Enabled: true, IntervalMinutes: 60, GracePeriodMinutes: 1}
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	// old_web matches the naming scheme, but was created before the managed resources were recorded
+	handler.Databases = []string{"old_web", "other"}
+	handler.Users = []string{"old_web", "replicator"}
+	clock := &testutil.MockClock{}
+	clock.SetNow(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := newSweeperTestReconciler(config, handler, clock, testutil.NewTestServiceConfig("web", "test-ns", "web"))
+
+	reconcileMysqlConfig(t, r)
+	clock.SetNow(clock.Now().Add(48 * time.Hour))
+	_, updated := reconcileMysqlConfig(t, r)
+
+	if len(updated.Status.Orphans) != 2 {
+		t.Fatalf("Orphans = %+v, want the old_web database and user", updated.Status.Orphans)
+	}
+	for _, orphan := range updated.Status.Orphans {
+		if orphan.Name != "old_web" || !orphan.ReportOnly {
+			t.Errorf("orphan = %+v, want old_web to be report only", orphan)
+		}
+	}
+	if len(handler.DroppedDatabases()) != 0 || len(handler.DroppedUsers()) != 0 {
+		t.Error("the unrecorded names should never be dropped")
+	}
+}
+
+func TestMysqlConfigReconciler_UnchangedStatusIsNotWritten(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	handler.SetProbeResult("8.0.36", nil)
+	clock := &testutil.MockClock{}
+	clock.SetNow(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := newSweeperTestReconciler(config, handler, clock)
+
+	_, first := reconcileMysqlConfig(t, r)
+	clock.SetNow(clock.Now().Add(48 * time.Hour))
+	_, second := reconcileMysqlConfig(t, r)
+
+	if second.ResourceVersion != first.ResourceVersion {
+		t.Errorf("ResourceVersion = %s, want %s as only the probe time changed", second.ResourceVersion, first.ResourceVersion)
+	}
+
+	handler.SetProbeResult("8.0.37", nil)
+	_, third := reconcileMysqlConfig(t, r)
+
+	if third.ResourceVersion == second.ResourceVersion || third.Status.ServerVersion != "8.0.37" {
+		t.Errorf("status = %+v, want the new server version to be written", third.Status.EnvironmentStatus)
+	}
+}
+
+func TestMysqlConfigReconciler_SharedServerAcrossNamespaces(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	config.Spec.OrphanSweeper = &configv1.OrphanSweeperSpec{Enabled: true, IntervalMinutes: 60, GracePeriodMinutes: 1}
+	seen := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	config.Status.ManagedResources = &configv1.ManagedResources{Databases: []string{"shared_web", "unrelated_web"}}
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	handler.Databases = []string{"shared_web", "unrelated_web"}
+	clock := &testutil.MockClock{}
+	clock.SetNow(seen.Add(48 * time.Hour))
+
+	sharedConfig := testutil.NewTestMysqlConfig("mysql", "other-ns")
+	sharedConfig.Spec.Host = "MYSQL.example.com"
+	otherServerConfig := testutil.NewTestMysqlConfig("mysql1", "third-ns")
+	otherServerConfig.Spec.Host = "other-mysql.example.com"
+	r := newSweeperTestReconciler(
+		config,
+		handler,
+		clock,
+		sharedConfig,
+		otherServerConfig,
+		testutil.NewTestMysqlDatabase("shared_web", "other-ns", "site1", "web", "mysql"),
+		testutil.NewTestMysqlDatabase("unrelated_web", "third-ns", "site1", "web", "mysql1"),
+	)
+
+	// The first sweep finds the orphans, the second one drops them after the grace period
+	reconcileMysqlConfig(t, r)
+	clock.SetNow(clock.Now().Add(2 * time.Hour))
+	_, updated := reconcileMysqlConfig(t, r)
+
+	if !reflect.DeepEqual(handler.DroppedDatabases(), []string{"unrelated_web"}) {
+		t.Errorf(
+			"DroppedDatabases = %v, want only the database not used by a namespace sharing the server",
+			handler.DroppedDatabases(),
+		)
+	}
+	if len(updated.Status.Orphans) != 0 {
+		t.Errorf("Orphans = %+v, want none", updated.Status.Orphans)
+	}
+}
+
+func TestMysqlConfigReconciler_DryRun(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	config.Spec.OrphanSweeper = &configv1.OrphanSweeperSpec{Enabled: true, IntervalMinutes: 60, GracePeriodMinutes: 1, DryRun: true}
+	config.Status.ManagedResources = &configv1.ManagedResources{Databases: []string{"dead_web"}}
+	seen := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	config.Status.Orphans = []configv1.OrphanedResource{
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "dead_web", FirstSeen: seen},
+	}
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	handler.Databases = []string{"dead_web"}
+	clock := &testutil.MockClock{}
+	clock.SetNow(seen.Add(48 * time.Hour))
+	r := newSweeperTestReconciler(config, handler, clock)
+
+	_, updated := reconcileMysqlConfig(t, r)
+
+	if len(handler.DroppedDatabases()) != 0 {
+		t.Errorf("DroppedDatabases = %v, want none in dry-run mode", handler.DroppedDatabases())
+	}
+	if len(updated.Status.Orphans) != 1 || !updated.Status.Orphans[0].FirstSeen.Equal(&seen) {
+		t.Errorf("Orphans = %+v, want dead_web first seen at %v", updated.Status.Orphans, seen)
+	}
+}
+
+func TestMysqlConfigReconciler_DropFailureKeepsOrphan(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	config.Spec.OrphanSweeper = &configv1.OrphanSweeperSpec{Enabled: true, IntervalMinutes: 60, GracePeriodMinutes: 1}
+	config.Status.ManagedResources = &configv1.ManagedResources{Databases: []string{"dead_web"}}
+	seen := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	config.Status.Orphans = []configv1.OrphanedResource{
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "dead_web", FirstSeen: seen},
+	}
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	handler.Databases = []string{"dead_web"}
+	handler.DropErr = errors.New("access denied")
+	clock := &testutil.MockClock{}
+	clock.SetNow(seen.Add(time.Hour))
+	r := newSweeperTestReconciler(config, handler, clock)
+
+	key := types.NamespacedName{Namespace: "test-ns", Name: "mysql1"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err == nil {
+		t.Fatal("expected the drop error to be returned")
+	}
+
+	var updated configv1.MysqlConfig
+	if err := r.Get(context.Background(), key, &updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(updated.Status.Orphans) != 1 {
+		t.Errorf("Orphans = %+v, want the orphan to be kept", updated.Status.Orphans)
+	}
+}
+
+func TestMysqlConfigReconciler_Probe(t *testing.T) {
+	config := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	config.Spec.OrphanSweeper = &configv1.OrphanSweeperSpec{Enabled: true}
+	handler := &testutil.MockMysqlEnvironmentHandler{}
+	handler.SetProbeResult("8.0.36", nil)
+	clock := &testutil.MockClock{}
+	clock.SetNow(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := newSweeperTestReconciler(
+		config,
+		handler,
+		clock,
+		testutil.NewTestMysqlDatabase("db1", "test-ns", "site1", "web", "mysql1"),
+		testutil.NewTestMysqlDatabase("db2", "test-ns", "site2", "web", "other"),
+	)
+
+	_, updated := reconcileMysqlConfig(t, r)
+
+	if updated.Status.IsUnhealthy() || updated.Status.ServerVersion != "8.0.36" {
+		t.Errorf("status = %+v, want ready with version 8.0.36", updated.Status.EnvironmentStatus)
+	}
+	if updated.Status.ProvisionedDatabaseCount != 1 {
+		t.Errorf("ProvisionedDatabaseCount = %d, want 1", updated.Status.ProvisionedDatabaseCount)
+	}
+
+	handler.SetProbeResult("", errors.New("access denied"))
+	handler.Databases = []string{"dead_web"}
+	clock.SetNow(clock.Now().Add(48 * time.Hour))
+
+	_, updated = reconcileMysqlConfig(t, r)
+
+	if !updated.Status.IsUnhealthy() || updated.Status.LastError != "access denied" {
+		t.Errorf("status = %+v, want not ready with the probe error", updated.Status.EnvironmentStatus)
+	}
+	if updated.Status.ServerVersion != "8.0.36" {
+		t.Errorf("ServerVersion = %q, want the last known version to be kept", updated.Status.ServerVersion)
+	}
+	if len(updated.Status.Orphans) != 0 {
+		t.Errorf("Orphans = %+v, the sweep should be skipped while the server is unreachable", updated.Status.Orphans)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package environment
+
+import (
+	"context"
+	"errors"
+	"github.com/go-logr/logr"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/handlers/database"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+// orphanSweep finds the databases and users created by the operator on an environment server that aren't referenced by
+// any database task and drops the ones that have been orphaned for longer than the grace period
+type orphanSweep struct {
+	sweeper             *configv1.OrphanSweeperSpec
+	previousOrphans     []configv1.OrphanedResource
+	now                 time.Time
+	databases           []string
+	users               []string
+	referencedDatabases map[string]bool
+	referencedUsers     map[string]bool
+	managed             *configv1.ManagedResources
+	shortNames          []string
+	dropDatabase        func(name string) error
+	dropUser            func(name string) error
+}
+
+// run returns the orphans remaining on the server and the dropped ones. Orphans that failed to be dropped are kept in
+// the remaining list and the errors are returned joined.
+func (r orphanSweep) run(logger logr.Logger) ([]configv1.OrphanedResource, []configv1.OrphanedResource, error) {
+	candidates := database.FindOrphans(
+		r.databases,
+		r.users,
+		r.managed,
+		r.referencedDatabases,
+		r.referencedUsers,
+		r.shortNames,
+		r.sweeper,
+	)
+	orphans := database.MergeOrphans(r.previousOrphans, candidates, r.now)
+
+	if r.sweeper.DryRun {
+		return orphans, nil, nil
+	}
+
+	var remaining []configv1.OrphanedResource
+	var dropped []configv1.OrphanedResource
+	var errs []error
+
+	for _, orphan := range orphans {
+		// The names that weren't recorded may belong to something else using the same naming scheme
+		if orphan.ReportOnly || !database.IsOrphanExpired(orphan, r.sweeper.GetGracePeriod(), r.now) {
+			remaining = append(remaining, orphan)
+			continue
+		}
+
+		logger.Info("Dropping orphaned resource", "type", orphan.Type, "name", orphan.Name)
+
+		var err error
+		switch orphan.Type {
+		case configv1.OrphanedResourceTypeDatabase:
+			err = r.dropDatabase(orphan.Name)
+		case configv1.OrphanedResourceTypeUser:
+			err = r.dropUser(orphan.Name)
+		}
+
+		if err != nil {
+			logger.Error(err, "Failed to drop orphaned resource", "type", orphan.Type, "name", orphan.Name)
+			errs = append(errs, err)
+			remaining = append(remaining, orphan)
+			continue
+		}
+
+		dropped = append(dropped, orphan)
+	}
+
+	return remaining, dropped, errors.Join(errs...)
+}
+
+// getServiceShortNames returns the short names of the service configs, used to recognise the databases and users
+// created by the operator that weren't recorded
+func getServiceShortNames(ctx context.Context, reader client.Reader) ([]string, error) {
+	var configList configv1.ServiceConfigList
+	if err := reader.List(ctx, &configList); err != nil {
+		return nil, err
+	}
+
+	shortNames := make([]string, 0, len(configList.Items))
+	for _, config := range configList.Items {
+		shortNames = append(shortNames, config.Spec.ShortName)
+	}
+
+	return shortNames, nil
+}
+
+// getNextSweepDelay returns how long to wait until the next sweep is due. Zero means a sweep is due now.
+func getNextSweepDelay(sweeper *configv1.OrphanSweeperSpec, lastSweepTime *metav1.Time, now time.Time) time.Duration {
+	if lastSweepTime == nil {
+		return 0
+	}
+
+	delay := lastSweepTime.Add(sweeper.GetInterval()).Sub(now)
+	if delay < 0 {
+		return 0
+	}
+
+	return delay
+}
+
+func updateOrphanMetrics(
+	namespace string,
+	environment string,
+	databaseType string,
+	orphans []configv1.OrphanedResource,
+	dropped []configv1.OrphanedResource,
+) {
+	counts := map[configv1.OrphanedResourceType]int{
+		configv1.OrphanedResourceTypeDatabase: 0,
+		configv1.OrphanedResourceTypeUser:     0,
+	}
+	for _, orphan := range orphans {
+		counts[orphan.Type]++
+	}
+	for resourceType, count := range counts {
+		appmetrics.OrphanedDatabaseResources.
+			WithLabelValues(namespace, environment, databaseType, string(resourceType)).
+			Set(float64(count))
+	}
+
+	for _, orphan := range dropped {
+		appmetrics.OrphanedDatabaseResourcesDropped.
+			WithLabelValues(namespace, environment, databaseType, string(orphan.Type)).
+			Inc()
+	}
+}
+
+func deleteOrphanMetrics(namespace string, environment string, databaseType string) {
+	for _, resourceType := range []configv1.OrphanedResourceType{
+		configv1.OrphanedResourceTypeDatabase,
+		configv1.OrphanedResourceTypeUser,
+	} {
+		appmetrics.OrphanedDatabaseResources.DeleteLabelValues(
+			namespace,
+			environment,
+			databaseType,
+			string(resourceType),
+		)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package environment
+
+import (
+	"context"
+	"github.com/getsentry/sentry-go"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/handlers/database"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"time"
+)
+
+// RedisConfigReconciler reconciles a RedisConfig object
+type RedisConfigReconciler struct {
+	client.Client
+	Scheme             *runtime.Scheme
+	EnvironmentHandler database.RedisEnvironmentHandler
+	ProbeInterval      time.Duration
+	Clock
+}
+
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=redisconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=redisconfigs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=redisdatabases,verbs=get;list;watch
+
+// Reconcile probes the server of the config and records the number of reserved and free databases
+func (r *RedisConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.doReconcile(ctx, req)
+
+	if err != nil {
+		appmetrics.Errors.WithLabelValues("redisconfig", "false").Inc()
+		sentry.CaptureException(err)
+	}
+
+	return result, err
+}
+
+func (r *RedisConfigReconciler) doReconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var config configv1.RedisConfig
+	if err := r.Get(ctx, req.NamespacedName, &config); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to fetch redis config")
+		} else {
+			deleteEnvironmentMetrics(req.Namespace, req.Name, "redis")
+		}
+
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	originalStatus := config.Status.DeepCopy()
+
+	var databaseList taskv1.RedisDatabaseList
+	if err := r.List(ctx, &databaseList, client.InNamespace(config.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	provisionedCount, freeCount := countRedisDatabases(config, databaseList)
+
+	serverVersion, probeErr := r.EnvironmentHandler.Probe(ctx, config, logger)
+	if probeErr != nil {
+		logger.Error(probeErr, "Probing the redis server failed")
+	}
+
+	config.Status.ProvisionedDatabaseCount = provisionedCount
+	config.Status.FreeDatabaseCount = freeCount
+	config.Status.SetProbeResult(serverVersion, probeErr, metav1.NewTime(r.Now()), config.Generation)
+	updateEnvironmentMetrics(config.Namespace, config.Name, "redis", probeErr == nil, provisionedCount)
+	appmetrics.EnvironmentFreeDatabases.WithLabelValues(config.Namespace, config.Name, "redis").Set(float64(freeCount))
+
+	// The probe time alone is not written, so the periodic probes don't update the config if nothing else changed
+	originalStatus.LastProbeTime = config.Status.LastProbeTime
+	if !equality.Semantic.DeepEqual(*originalStatus, config.Status) {
+		if err := r.Status().Update(ctx, &config); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: getProbeInterval(r.ProbeInterval)}, nil
+}
+
+// countRedisDatabases returns the number of databases reserved in the environment and the number of databases still
+// available for reservation
+func countRedisDatabases(config configv1.RedisConfig, databaseList taskv1.RedisDatabaseList) (int32, int32) {
+	var provisionedCount int32
+	reservedNumbers := make(map[uint32]bool)
+
+	for _, db := range databaseList.Items {
+		if db.Spec.EnvironmentConfig.Environment != config.Name {
+			continue
+		}
+		provisionedCount++
+		if db.Spec.DatabaseNumber < config.Spec.AvailableDatabaseCount {
+			reservedNumbers[db.Spec.DatabaseNumber] = true
+		}
+	}
+
+	return provisionedCount, int32(config.Spec.AvailableDatabaseCount) - int32(len(reservedNumbers))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RedisConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+	if r.EnvironmentHandler == nil {
+		r.EnvironmentHandler = database.DefaultRedisEnvironmentHandler{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&configv1.RedisConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
@@ -0,0 +1,61 @@
+package environment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/internal/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestCountRedisDatabases(t *testing.T) {
+	config := testutil.NewTestRedisConfig("redis1", "test-ns")
+	list := taskv1.RedisDatabaseList{Items: []taskv1.RedisDatabase{
+		*testutil.NewTestRedisDatabase("db1", "test-ns", "site1", "web", "redis1", 0),
+		*testutil.NewTestRedisDatabase("db2", "test-ns", "site2", "web", "redis1", 3),
+		*testutil.NewTestRedisDatabase("db3", "test-ns", "site3", "web", "other", 4),
+	}}
+
+	provisioned, free := countRedisDatabases(*config, list)
+
+	if provisioned != 2 {
+		t.Errorf("provisioned = %d, want 2", provisioned)
+	}
+	if free != 14 {
+		t.Errorf("free = %d, want 14", free)
+	}
+}
+
+func TestRedisConfigReconciler_Probe(t *testing.T) {
+	config := testutil.NewTestRedisConfig("redis1", "test-ns")
+	c := testutil.NewFakeClient(config, testutil.NewTestRedisDatabase("db1", "test-ns", "site1", "web", "redis1", 1))
+	handler := &testutil.MockRedisEnvironmentHandler{}
+	handler.SetProbeResult("7.2.4", nil)
+	clock := &testutil.MockClock{}
+	clock.SetNow(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := &RedisConfigReconciler{Client: c, Scheme: c.Scheme(), EnvironmentHandler: handler, Clock: clock}
+
+	key := types.NamespacedName{Namespace: "test-ns", Name: "redis1"}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != defaultProbeInterval {
+		t.Errorf("RequeueAfter = %v, want %v", result.RequeueAfter, defaultProbeInterval)
+	}
+
+	var updated configv1.RedisConfig
+	if err := c.Get(context.Background(), key, &updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Status.IsUnhealthy() || updated.Status.ServerVersion != "7.2.4" {
+		t.Errorf("status = %+v, want ready with version 7.2.4", updated.Status.EnvironmentStatus)
+	}
+	if updated.Status.ProvisionedDatabaseCount != 1 || updated.Status.FreeDatabaseCount != 15 {
+		t.Errorf("status = %+v, want 1 provisioned and 15 free databases", updated.Status)
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
 )
 
+// EnvironmentMaintenanceRequeueInterval is how often the objects waiting for an environment in maintenance are rechecked
+const EnvironmentMaintenanceRequeueInterval = time.Minute
+
 func SaveStatusUpdatesIfObjectChanged(
 	isChanged bool,
 	writer client.StatusWriter,
@@ -50,7 +50,7 @@ var _ = Describe("BackupController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "tsv",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -141,7 +141,7 @@ var _ = Describe("BackupController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "snb",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -229,7 +229,7 @@ var _ = Describe("BackupController", func() {
 					},
 					Spec: configv1.ServiceConfigSpec{
 						ShortName: svcName[:3],
-						DeploymentPodSpec: corev1.PodSpec{
+						DeploymentPodSpec: &corev1.PodSpec{
 							Containers: []corev1.Container{{
 								Name:  "app",
 								Image: "busybox:latest",
@@ -316,7 +316,7 @@ var _ = Describe("BackupController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "fin",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -404,7 +404,7 @@ var _ = Describe("BackupController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "tsf",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -0,0 +1,541 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/getsentry/sentry-go"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	controller "github.com/szeber/kube-stager/controllers"
+	"github.com/szeber/kube-stager/handlers/task"
+	"github.com/szeber/kube-stager/handlers/template"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/helpers/pod"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+// DatabaseMoveReconciler reconciles a DatabaseMove object
+type DatabaseMoveReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config controllerconfigv1.ProjectConfig
+}
+
+const (
+	// The interval for checking whether the workloads of the site have stopped
+	databaseMovePollInterval = 10 * time.Second
+	databaseMoveJobType      = "dbmove"
+)
+
+// movableDatabase is implemented by the task objects of the database types that can be moved between environments
+type movableDatabase interface {
+	client.Object
+	GetEnvironmentConfig() taskv1.EnvironmentConfig
+	GetState() taskv1.TaskState
+	SetEnvironment(environment string)
+}
+
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=site.operator.kube-stager.io,resources=stagingsites,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mysqldatabases,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mongodatabases,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=databasemoves,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=databasemoves/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=databasemoves/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.23.3/pkg/reconcile
+func (r *DatabaseMoveReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.doReconcile(ctx, req)
+
+	if err != nil {
+		appmetrics.Errors.WithLabelValues(databaseMoveJobType, "false").Inc()
+		sentry.CaptureException(err)
+	}
+
+	return result, err
+}
+
+func (r *DatabaseMoveReconciler) doReconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var move jobv1.DatabaseMove
+
+	if err := r.Get(ctx, req.NamespacedName, &move); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to fetch database move")
+		}
+
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if move.Status.State == "" || move.Status.DeadlineTimestamp == nil {
+		logger.V(0).Info("Initialising move state")
+		move.Status.State = jobv1.Running
+		move.Status.Phase = jobv1.DatabaseMovePhaseCreatingTargetDatabase
+		move.Status.DeadlineTimestamp = &metav1.Time{
+			Time: time.Now().Add(time.Duration(move.Spec.DeadlineSeconds) * time.Second),
+		}
+		return controller.SaveStatusUpdatesIfObjectChanged(
+			true,
+			r.Status(),
+			ctx,
+			&move,
+			ctrl.Result{Requeue: true},
+			nil,
+		)
+	}
+
+	if move.Status.State.IsFinal() {
+		logger.V(1).Info("Move is in final state, ignoring", "state", move.Status.State)
+		return ctrl.Result{}, nil
+	}
+
+	if move.Status.Phase != jobv1.DatabaseMovePhaseSwitching && time.Now().After(move.Status.DeadlineTimestamp.Time) {
+		logger.V(0).Info("The move deadline has expired. Failing move.")
+		r.failMove(&move, "the move didn't complete before its deadline")
+		return controller.SaveStatusUpdatesIfObjectChanged(true, r.Status(), ctx, &move, ctrl.Result{}, nil)
+	}
+
+	var changed bool
+	var result ctrl.Result
+	var err error
+
+	switch move.Status.Phase {
+	case jobv1.DatabaseMovePhaseCreatingTargetDatabase:
+		changed, err = r.ensureTargetDatabaseIsCreated(ctx, &move)
+	case jobv1.DatabaseMovePhasePausingWorkloads:
+		changed, result, err = r.ensureWorkloadsAreStopped(ctx, &move)
+	case jobv1.DatabaseMovePhaseCopyingData:
+		changed, err = r.ensureDataIsCopied(ctx, &move)
+	case jobv1.DatabaseMovePhaseSwitching:
+		changed, err = r.switchEnvironment(ctx, &move)
+	default:
+		return ctrl.Result{}, fmt.Errorf("unknown phase: %s", move.Status.Phase)
+	}
+
+	return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, &move, result, err)
+}
+
+func (r *DatabaseMoveReconciler) ensureTargetDatabaseIsCreated(ctx context.Context, move *jobv1.DatabaseMove) (
+	bool,
+	error,
+) {
+	logger := log.FromContext(ctx)
+
+	source, target, err := r.getDatabases(ctx, move)
+	if err != nil {
+		return false, err
+	}
+
+	if source == nil {
+		r.failMove(move, "the database of the service doesn't exist")
+		return true, nil
+	}
+
+	sourceEnvironment := source.GetEnvironmentConfig().Environment
+	changed := false
+	if move.Status.SourceEnvironment == "" {
+		if move.Spec.SourceEnvironment != "" && move.Spec.SourceEnvironment != sourceEnvironment {
+			r.failMove(
+				move,
+				fmt.Sprintf(
+					"the database is in environment %s, not in the source environment %s",
+					sourceEnvironment,
+					move.Spec.SourceEnvironment,
+				),
+			)
+			return true, nil
+		}
+		move.Status.SourceEnvironment = sourceEnvironment
+		changed = true
+	}
+
+	if target == nil && sourceEnvironment == move.Spec.TargetEnvironment {
+		logger.V(0).Info("The database is already in the target environment")
+		move.Status.Phase = jobv1.DatabaseMovePhaseDone
+		move.Status.State = jobv1.Complete
+		return true, nil
+	}
+
+	if target == nil {
+		logger.V(0).Info("Creating the database in the target environment", "environment", move.Spec.TargetEnvironment)
+		target, err = r.makeTargetDatabase(move, source)
+		if err != nil {
+			return changed, err
+		}
+		return changed, r.Create(ctx, target)
+	}
+
+	switch target.GetState() {
+	case taskv1.Complete:
+		logger.V(0).Info("The database is created in the target environment, pausing workloads")
+		move.Status.Phase = jobv1.DatabaseMovePhasePausingWorkloads
+		return true, nil
+	case taskv1.Failed:
+		r.failMove(move, "failed to create the database in the target environment")
+		return true, nil
+	}
+
+	logger.V(0).Info("Waiting for the database to be created in the target environment")
+	return changed, nil
+}
+
+// makeTargetDatabase copies the database of the site to a task owned by the move in the target environment, which
+// creates the database and users there
+func (r *DatabaseMoveReconciler) makeTargetDatabase(move *jobv1.DatabaseMove, source movableDatabase) (
+	movableDatabase,
+	error,
+) {
+	target := source.DeepCopyObject().(movableDatabase)
+	target.SetName(move.Name)
+	target.SetResourceVersion("")
+	target.SetUID("")
+	target.SetCreationTimestamp(metav1.Time{})
+	target.SetDeletionTimestamp(nil)
+	target.SetFinalizers(nil)
+	target.SetOwnerReferences(nil)
+	target.SetManagedFields(nil)
+
+	targetLabels := make(map[string]string, len(source.GetLabels())+1)
+	for k, v := range source.GetLabels() {
+		targetLabels[k] = v
+	}
+	targetLabels[labels.DatabaseMove] = move.Name
+	target.SetLabels(targetLabels)
+	target.SetEnvironment(move.Spec.TargetEnvironment)
+
+	if err := ctrl.SetControllerReference(move, target, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// ensureWorkloadsAreStopped waits until the site controller removed the deployments of the site and their pods are
+// gone, so nothing writes to the database while it's copied
+func (r *DatabaseMoveReconciler) ensureWorkloadsAreStopped(ctx context.Context, move *jobv1.DatabaseMove) (
+	bool,
+	ctrl.Result,
+	error,
+) {
+	logger := log.FromContext(ctx)
+	siteLabels := client.MatchingLabels{labels.Site: move.Spec.SiteName}
+
+	var deployments appsv1.DeploymentList
+	if err := r.List(ctx, &deployments, client.InNamespace(move.Namespace), siteLabels); err != nil {
+		return false, ctrl.Result{}, err
+	}
+	if len(deployments.Items) > 0 {
+		logger.V(0).Info("Waiting for the deployments of the site to be removed", "count", len(deployments.Items))
+		return false, ctrl.Result{RequeueAfter: databaseMovePollInterval}, nil
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(move.Namespace), siteLabels); err != nil {
+		return false, ctrl.Result{}, err
+	}
+	for _, sitePod := range pods.Items {
+		// Job pods are labelled with their type, only the workload pods need to be stopped
+		if sitePod.Labels[labels.Type] != "" {
+			continue
+		}
+		if sitePod.Status.Phase != corev1.PodSucceeded && sitePod.Status.Phase != corev1.PodFailed {
+			logger.V(0).Info("Waiting for the pods of the site to stop", "pod", sitePod.Name)
+			return false, ctrl.Result{RequeueAfter: databaseMovePollInterval}, nil
+		}
+	}
+
+	logger.V(0).Info("The workloads of the site are stopped, copying data")
+	move.Status.Phase = jobv1.DatabaseMovePhaseCopyingData
+
+	return true, ctrl.Result{}, nil
+}
+
+func (r *DatabaseMoveReconciler) ensureDataIsCopied(ctx context.Context, move *jobv1.DatabaseMove) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	var jobList batchv1.JobList
+	labelMatcher := client.MatchingLabels{labels.JobName: move.Name, labels.Type: databaseMoveJobType}
+	if err := r.List(ctx, &jobList, client.InNamespace(move.Namespace), labelMatcher); err != nil {
+		return false, err
+	}
+
+	if len(jobList.Items) == 0 {
+		serviceConfig := &configv1.ServiceConfig{}
+		if err := r.Get(
+			ctx,
+			client.ObjectKey{Namespace: move.Namespace, Name: move.Spec.ServiceName},
+			serviceConfig,
+		); err != nil {
+			return false, err
+		}
+		if serviceConfig.Spec.DatabaseMovePodSpec == nil {
+			r.failMove(move, "no database move pod spec is specified in the service config")
+			return true, nil
+		}
+
+		logger.V(0).Info("Creating the job copying the data")
+		batchJob, err := r.createJob(ctx, move, serviceConfig)
+		if err != nil {
+			return false, err
+		}
+
+		return false, r.Create(ctx, batchJob)
+	}
+
+	for _, v := range jobList.Items[0].Status.Conditions {
+		if v.Type == batchv1.JobComplete && v.Status == corev1.ConditionTrue {
+			logger.V(1).Info("The data is copied, switching the environment")
+			move.Status.Phase = jobv1.DatabaseMovePhaseSwitching
+			return true, nil
+		}
+
+		if v.Type == batchv1.JobFailed && v.Status == corev1.ConditionTrue {
+			logger.V(0).Info("Job failed", "status", v.Message, "reason", v.Reason)
+			r.failMove(move, "the job copying the data failed: "+v.Message)
+			return true, nil
+		}
+	}
+
+	logger.V(0).Info("Job is still running")
+	return false, nil
+}
+
+func (r *DatabaseMoveReconciler) createJob(
+	ctx context.Context,
+	move *jobv1.DatabaseMove,
+	serviceConfig *configv1.ServiceConfig,
+) (*batchv1.Job, error) {
+	var site sitev1.StagingSite
+	if err := r.Get(ctx, client.ObjectKey{Namespace: move.Namespace, Name: move.Spec.SiteName}, &site); err != nil {
+		return nil, err
+	}
+
+	backoffLimit := r.Config.InitJobConfig.BackoffLimit
+	deadlineSeconds := move.Spec.DeadlineSeconds
+	ttlSeconds := r.Config.InitJobConfig.TtlSeconds
+	jobLabels := map[string]string{
+		labels.Type:    databaseMoveJobType,
+		labels.JobName: move.Name,
+		labels.Site:    move.Spec.SiteName,
+		labels.Service: move.Spec.ServiceName,
+	}
+	templateHandler := template.NewSite(site, *serviceConfig)
+	if err := template.LoadConfigs(&templateHandler, ctx, r.Client); err != nil {
+		return nil, err
+	}
+	moveTemplateValues := &helpers.StringMapTemplateValueGetter{
+		StringMap: templateHandler.GetDatabaseMoveTemplateValues(
+			string(move.Spec.DatabaseType),
+			move.Status.SourceEnvironment,
+			move.Spec.TargetEnvironment,
+		),
+	}
+
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(
+		*serviceConfig.Spec.DatabaseMovePodSpec,
+		&templateHandler,
+		moveTemplateValues,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if podSpec.RestartPolicy != corev1.RestartPolicyOnFailure && podSpec.RestartPolicy != corev1.RestartPolicyNever {
+		podSpec.RestartPolicy = corev1.RestartPolicyNever
+	}
+
+	batchJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helpers.ShortenHumanReadableValue(fmt.Sprintf("dbmove-%s", move.Name), 50),
+			Namespace: move.Namespace,
+			Labels:    jobLabels,
+		},
+		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: &deadlineSeconds,
+			BackoffLimit:          &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: jobLabels,
+				},
+				Spec: pod.SetExtraEnvVarsOnPodSpec(podSpec, &site, serviceConfig),
+			},
+			TTLSecondsAfterFinished: &ttlSeconds,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(move, batchJob, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return batchJob, nil
+}
+
+// switchEnvironment points the site and its database to the target environment. The task in the target environment
+// is pointed to the source environment before it's deleted, so its finalizer drops the old database.
+func (r *DatabaseMoveReconciler) switchEnvironment(ctx context.Context, move *jobv1.DatabaseMove) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	if err := r.updateSiteEnvironment(ctx, move); err != nil {
+		return false, err
+	}
+
+	source, target, err := r.getDatabases(ctx, move)
+	if err != nil {
+		return false, err
+	}
+
+	if source != nil && source.GetEnvironmentConfig().Environment != move.Spec.TargetEnvironment {
+		logger.V(0).Info("Switching the database of the site to the target environment")
+		source.SetEnvironment(move.Spec.TargetEnvironment)
+		if err := r.Update(ctx, source); err != nil {
+			return false, err
+		}
+	}
+
+	if target != nil {
+		if target.GetEnvironmentConfig().Environment != move.Status.SourceEnvironment {
+			target.SetEnvironment(move.Status.SourceEnvironment)
+			if err := r.Update(ctx, target); err != nil {
+				return false, err
+			}
+		}
+		logger.V(0).Info("Removing the database from the source environment")
+		if err := r.Delete(ctx, target); client.IgnoreNotFound(err) != nil {
+			return false, err
+		}
+	}
+
+	move.Status.Phase = jobv1.DatabaseMovePhaseDone
+	move.Status.State = jobv1.Complete
+	appmetrics.JobCompletions.WithLabelValues(databaseMoveJobType, "success").Inc()
+
+	return true, nil
+}
+
+// updateSiteEnvironment sets the target environment in the site spec, which is needed when the move was created
+// directly instead of by changing the environment of the site
+func (r *DatabaseMoveReconciler) updateSiteEnvironment(ctx context.Context, move *jobv1.DatabaseMove) error {
+	var site sitev1.StagingSite
+	if err := r.Get(ctx, client.ObjectKey{Namespace: move.Namespace, Name: move.Spec.SiteName}, &site); err != nil {
+		return err
+	}
+
+	service, ok := site.Spec.Services[move.Spec.ServiceName]
+	if !ok {
+		return nil
+	}
+
+	switch move.Spec.DatabaseType {
+	case jobv1.DatabaseMoveTypeMysql:
+		if service.MysqlEnvironment == move.Spec.TargetEnvironment {
+			return nil
+		}
+		service.MysqlEnvironment = move.Spec.TargetEnvironment
+	case jobv1.DatabaseMoveTypeMongo:
+		if service.MongoEnvironment == move.Spec.TargetEnvironment {
+			return nil
+		}
+		service.MongoEnvironment = move.Spec.TargetEnvironment
+	}
+
+	site.Spec.Services[move.Spec.ServiceName] = service
+
+	return r.Update(ctx, &site)
+}
+
+// getDatabases returns the database task of the site and the one created by the move in the target environment.
+// Either may be nil if it doesn't exist.
+func (r *DatabaseMoveReconciler) getDatabases(ctx context.Context, move *jobv1.DatabaseMove) (
+	source movableDatabase,
+	target movableDatabase,
+	err error,
+) {
+	listOptions := []client.ListOption{
+		client.InNamespace(move.Namespace),
+		client.MatchingLabels{labels.Site: move.Spec.SiteName, labels.Service: move.Spec.ServiceName},
+	}
+
+	var databases []movableDatabase
+	switch move.Spec.DatabaseType {
+	case jobv1.DatabaseMoveTypeMysql:
+		var list taskv1.MysqlDatabaseList
+		if err = r.List(ctx, &list, listOptions...); err != nil {
+			return nil, nil, err
+		}
+		for i := range list.Items {
+			databases = append(databases, &list.Items[i])
+		}
+	case jobv1.DatabaseMoveTypeMongo:
+		var list taskv1.MongoDatabaseList
+		if err = r.List(ctx, &list, listOptions...); err != nil {
+			return nil, nil, err
+		}
+		for i := range list.Items {
+			databases = append(databases, &list.Items[i])
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported database type: %s", move.Spec.DatabaseType)
+	}
+
+	for _, database := range databases {
+		if database.GetLabels()[labels.DatabaseMove] == move.Name {
+			target = database
+		} else if !task.IsDatabaseMoveTarget(database) && database.GetDeletionTimestamp() == nil {
+			source = database
+		}
+	}
+
+	return source, target, nil
+}
+
+func (r *DatabaseMoveReconciler) failMove(move *jobv1.DatabaseMove, message string) {
+	move.Status.State = jobv1.Failed
+	move.Status.Message = message
+	appmetrics.JobCompletions.WithLabelValues(databaseMoveJobType, "failure").Inc()
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DatabaseMoveReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&jobv1.DatabaseMove{}).
+		Owns(&taskv1.MysqlDatabase{}).
+		Owns(&taskv1.MongoDatabase{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
@@ -0,0 +1,165 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers/labels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("DatabaseMoveController", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 200 * time.Millisecond
+	)
+
+	var (
+		ns          string
+		siteName    string
+		serviceName string
+	)
+
+	createSite := func() {
+		nsObj := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: ns},
+		}
+		Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+		serviceConfig := &configv1.ServiceConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      serviceName,
+				Namespace: ns,
+			},
+			Spec: configv1.ServiceConfigSpec{
+				ShortName: "msv",
+				DeploymentPodSpec: &corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "app",
+						Image: "busybox:latest",
+					}},
+				},
+				DatabaseMovePodSpec: &corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    "move",
+						Image:   "busybox:latest",
+						Command: []string{"echo", "${move.source.environment}", "${move.target.environment}"},
+					}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, serviceConfig)).To(Succeed())
+
+		site := &sitev1.StagingSite{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      siteName,
+				Namespace: ns,
+			},
+			Spec: sitev1.StagingSiteSpec{
+				Enabled: true,
+				Services: map[string]sitev1.StagingSiteService{
+					serviceName: {ImageTag: "latest", Replicas: 1, MysqlEnvironment: "mysql-1"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, site)).To(Succeed())
+	}
+
+	newMove := func(name string) *jobv1.DatabaseMove {
+		return &jobv1.DatabaseMove{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+			},
+			Spec: jobv1.DatabaseMoveSpec{
+				SiteName:          siteName,
+				ServiceName:       serviceName,
+				DatabaseType:      jobv1.DatabaseMoveTypeMysql,
+				TargetEnvironment: "mysql-2",
+				DeadlineSeconds:   300,
+			},
+		}
+	}
+
+	Describe("when the database of the service doesn't exist", func() {
+		BeforeEach(func() {
+			ns = fmt.Sprintf("dbmove-nodb-%d", GinkgoParallelProcess())
+			siteName = "move-site"
+			serviceName = "move-svc"
+			createSite()
+		})
+
+		It("should fail the move", func() {
+			move := newMove("move-nodb")
+			Expect(k8sClient.Create(ctx, move)).To(Succeed())
+
+			fetched := &jobv1.DatabaseMove{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(move), fetched)).To(Succeed())
+				g.Expect(fetched.Status.State).To(Equal(jobv1.Failed))
+			}, timeout, interval).Should(Succeed())
+
+			Expect(fetched.Status.Message).To(ContainSubstring("doesn't exist"))
+		})
+	})
+
+	Describe("when the database of the service exists", func() {
+		BeforeEach(func() {
+			ns = fmt.Sprintf("dbmove-create-%d", GinkgoParallelProcess())
+			siteName = "move-site"
+			serviceName = "move-svc"
+			createSite()
+
+			database := &taskv1.MysqlDatabase{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "move-site-msv",
+					Namespace: ns,
+					Labels: map[string]string{
+						labels.Site:    siteName,
+						labels.Service: serviceName,
+					},
+				},
+				Spec: taskv1.MysqlDatabaseSpec{
+					EnvironmentConfig: taskv1.EnvironmentConfig{
+						SiteName:    siteName,
+						ServiceName: serviceName,
+						Environment: "mysql-1",
+					},
+					DatabaseName: "testdb",
+					Username:     "testuser",
+					Password:     "testpass",
+				},
+			}
+			Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		})
+
+		It("should record the source environment and create the database in the target environment", func() {
+			move := newMove("move-create")
+			Expect(k8sClient.Create(ctx, move)).To(Succeed())
+
+			target := &taskv1.MysqlDatabase{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: ns, Name: move.Name}, target)).To(Succeed())
+			}, timeout, interval).Should(Succeed())
+
+			Expect(target.Spec.EnvironmentConfig.Environment).To(Equal("mysql-2"))
+			Expect(target.Spec.DatabaseName).To(Equal("testdb"))
+			Expect(target.Labels[labels.DatabaseMove]).To(Equal(move.Name))
+			Expect(metav1.IsControlledBy(target, move)).To(BeTrue())
+
+			fetched := &jobv1.DatabaseMove{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(move), fetched)).To(Succeed())
+			Expect(fetched.Status.SourceEnvironment).To(Equal("mysql-1"))
+			Expect(fetched.Status.Phase).To(Equal(jobv1.DatabaseMovePhaseCreatingTargetDatabase))
+		})
+	})
+})
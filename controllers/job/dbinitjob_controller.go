@@ -18,13 +18,13 @@ package job
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"github.com/getsentry/sentry-go"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	controller "github.com/szeber/kube-stager/controllers"
+	jobhandler "github.com/szeber/kube-stager/handlers/job"
 	"github.com/szeber/kube-stager/handlers/template"
 	"github.com/szeber/kube-stager/helpers"
 	labels "github.com/szeber/kube-stager/helpers/labels"
@@ -51,6 +51,9 @@ type DbInitJobReconciler struct {
 
 const (
 	DbInitMaxJobFailedLoadLimit = 5
+
+	dbInitJobType     = "dbinit"
+	dbSanitizeJobType = "dbsanitize"
 )
 
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
@@ -58,6 +61,10 @@ const (
 //+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=dbinitjobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=dbinitjobs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=dbinitjobs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mysqldatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mysqldatabases/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mongodatabases,verbs=get;list;watch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mongodatabases/status,verbs=get;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -116,7 +123,10 @@ func (r *DbInitJobReconciler) doReconcile(ctx context.Context, req ctrl.Request)
 	case jobv1.Pending:
 		changed, err := r.createJobIfNeeded(&job, ctx)
 		return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, &job, ctrl.Result{}, err)
-	case jobv1.Failed, jobv1.Complete:
+	case jobv1.Complete:
+		logger.V(1).Info("Job is complete, ensuring the databases are marked as initialised")
+		return ctrl.Result{}, r.ensureInitMarkersAreSet(&job, ctx)
+	case jobv1.Failed:
 		logger.V(1).Info("Job is in final state, ignoring", "state", job.Status.State)
 		return ctrl.Result{}, nil
 	case jobv1.Running:
@@ -129,9 +139,28 @@ func (r *DbInitJobReconciler) doReconcile(ctx context.Context, req ctrl.Request)
 
 func (r *DbInitJobReconciler) processRunningJob(job *jobv1.DbInitJob, ctx context.Context) (bool, error) {
 	logger := log.FromContext(ctx)
+
+	switch job.Status.SanitizeState {
+	case jobv1.Pending:
+		return r.createSanitizeJobIfNeeded(job, ctx)
+	case jobv1.Running:
+		logger.V(0).Info("Loading sanitize job")
+		return r.processRunningBatchJob(job, ctx, dbSanitizeJobType)
+	}
+
 	logger.V(0).Info("Loading init job")
+	return r.processRunningBatchJob(job, ctx, dbInitJobType)
+}
 
-	jobList, err := r.getOwnedInitJobs(job, ctx)
+// processRunningBatchJob updates the state of the job from the batch job of the current phase (init or sanitize)
+func (r *DbInitJobReconciler) processRunningBatchJob(
+	job *jobv1.DbInitJob,
+	ctx context.Context,
+	jobType string,
+) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	jobList, err := r.getOwnedBatchJobs(job, ctx, jobType)
 	if err != nil {
 		return false, err
 	}
@@ -141,7 +170,7 @@ func (r *DbInitJobReconciler) processRunningJob(job *jobv1.DbInitJob, ctx contex
 		job.Status.JobNotFoundCount = job.Status.JobNotFoundCount + 1
 		if job.Status.JobNotFoundCount > DbInitMaxJobFailedLoadLimit {
 			logger.V(0).Info("The maximum not found time exceeded the limit, failing the job")
-			job.Status.State = jobv1.Failed
+			r.setPhaseState(job, jobType, jobv1.Failed)
 		}
 		return true, nil
 	}
@@ -156,39 +185,103 @@ func (r *DbInitJobReconciler) processRunningJob(job *jobv1.DbInitJob, ctx contex
 
 	for _, v := range batchJob.Status.Conditions {
 		if v.Type == batchv1.JobComplete && v.Status == corev1.ConditionTrue {
-			logger.V(1).Info("Job finished successfully")
-			job.Status.State = jobv1.Complete
-			appmetrics.JobCompletions.WithLabelValues("dbinit", "success").Inc()
+			logger.V(1).Info("Job finished successfully", "type", jobType)
+			appmetrics.JobCompletions.WithLabelValues(jobType, "success").Inc()
 			if job.Status.DeadlineTimestamp != nil {
 				duration := time.Since(job.Status.DeadlineTimestamp.Add(-time.Duration(job.Spec.DeadlineSeconds) * time.Second))
-				appmetrics.JobDuration.WithLabelValues("dbinit").Observe(duration.Seconds())
+				appmetrics.JobDuration.WithLabelValues(jobType).Observe(duration.Seconds())
 			}
+			r.setPhaseState(job, jobType, jobv1.Complete)
 			return true, nil
 		}
 
 		if v.Type == batchv1.JobFailed && v.Status == corev1.ConditionTrue {
-			logger.V(0).Info("Job failed", "status", v.Message, "reason", v.Reason)
-			job.Status.State = jobv1.Failed
-			appmetrics.JobCompletions.WithLabelValues("dbinit", "failure").Inc()
+			logger.V(0).Info("Job failed", "type", jobType, "status", v.Message, "reason", v.Reason)
+			appmetrics.JobCompletions.WithLabelValues(jobType, "failure").Inc()
+			r.setPhaseState(job, jobType, jobv1.Failed)
 			return true, nil
 		}
 	}
 
 	if time.Now().After(job.Status.DeadlineTimestamp.Time) {
-		logger.V(0).Info("The job deadline has expired. Failing job.")
-		job.Status.State = jobv1.Failed
-		appmetrics.JobCompletions.WithLabelValues("dbinit", "failure").Inc()
+		logger.V(0).Info("The job deadline has expired. Failing job.", "type", jobType)
+		appmetrics.JobCompletions.WithLabelValues(jobType, "failure").Inc()
+		r.setPhaseState(job, jobType, jobv1.Failed)
 		return true, nil
 	}
 
-	logger.V(0).Info("Job is still running")
+	logger.V(0).Info("Job is still running", "type", jobType)
 	return changed, nil
 }
 
+// setPhaseState sets the state of the init or sanitize phase. A completed init job starts the sanitize phase if it's
+// required, and the job is only complete once the sanitize job is complete as well
+func (r *DbInitJobReconciler) setPhaseState(job *jobv1.DbInitJob, jobType string, state jobv1.JobState) {
+	if jobType == dbSanitizeJobType {
+		job.Status.SanitizeState = state
+		job.Status.State = state
+		return
+	}
+
+	if state == jobv1.Complete && job.Spec.Sanitize {
+		job.Status.SanitizeState = jobv1.Pending
+		job.Status.JobNotFoundCount = 0
+		// The sanitize job gets the same amount of time to complete as the init job
+		job.Status.DeadlineTimestamp = &metav1.Time{
+			Time: time.Now().Add(time.Duration(job.Spec.DeadlineSeconds) * time.Second),
+		}
+		return
+	}
+
+	job.Status.State = state
+}
+
+func (r *DbInitJobReconciler) createSanitizeJobIfNeeded(job *jobv1.DbInitJob, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Sanitize job is in Pending state")
+	jobList, err := r.getOwnedBatchJobs(job, ctx, dbSanitizeJobType)
+	if err != nil {
+		return false, err
+	}
+
+	if len(jobList.Items) > 0 {
+		logger.V(1).Info("There is already a sanitize job created, changing state to Running")
+		job.Status.SanitizeState = jobv1.Running
+		return true, nil
+	}
+
+	serviceConfig, err := r.getServiceConfig(ctx, job.Namespace, job.Spec.ServiceName)
+	if err != nil {
+		return false, err
+	}
+
+	if serviceConfig.Spec.DbSanitizePodSpec == nil {
+		logger.V(0).Info("No db sanitize pod spec is specified. Failing job")
+		job.Status.SanitizeState = jobv1.Failed
+		job.Status.State = jobv1.Failed
+		return true, nil
+	}
+
+	logger.V(0).Info("Creating sanitize job")
+	batchJob, err := r.createJob(ctx, job, serviceConfig, *serviceConfig.Spec.DbSanitizePodSpec, dbSanitizeJobType)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.Create(ctx, batchJob); err != nil {
+		return false, err
+	}
+
+	logger.V(0).Info("Sanitize job created, setting state to running")
+	job.Status.SanitizeState = jobv1.Running
+
+	return true, nil
+}
+
 func (r *DbInitJobReconciler) createJobIfNeeded(job *jobv1.DbInitJob, ctx context.Context) (bool, error) {
 	logger := log.FromContext(ctx)
 	logger.V(1).Info("Job is in Pending state")
-	jobList, err := r.getOwnedInitJobs(job, ctx)
+	jobList, err := r.getOwnedBatchJobs(job, ctx, dbInitJobType)
 	if err != nil {
 		return false, err
 	}
@@ -226,7 +319,7 @@ func (r *DbInitJobReconciler) createJobIfNeeded(job *jobv1.DbInitJob, ctx contex
 	}
 
 	logger.V(0).Info("Creating job")
-	batchJob, err := r.createJob(ctx, job, serviceConfig)
+	batchJob, err := r.createJob(ctx, job, serviceConfig, *serviceConfig.Spec.DbInitPodSpec, dbInitJobType)
 	if err != nil {
 		return false, err
 	}
@@ -241,6 +334,36 @@ func (r *DbInitJobReconciler) createJobIfNeeded(job *jobv1.DbInitJob, ctx contex
 	return true, nil
 }
 
+// ensureInitMarkersAreSet records the init marker of the completed job in the databases of the service, so they aren't
+// initialised again if the job is recreated. Databases created after the job are skipped, as the job didn't initialise
+// them
+func (r *DbInitJobReconciler) ensureInitMarkersAreSet(job *jobv1.DbInitJob, ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	tasks, err := jobhandler.ListDatabaseTasksOfService(ctx, r, job.Namespace, job.Spec.SiteName, job.Spec.ServiceName)
+	if err != nil {
+		return err
+	}
+
+	marker := job.MakeInitMarker(time.Now())
+	for _, task := range tasks {
+		if jobhandler.IsDatabaseNewerThanJob(task, job) {
+			continue
+		}
+		if existing := task.GetInitMarker(); existing != nil && existing.Matches(marker) {
+			continue
+		}
+
+		logger.V(0).Info("Setting the init marker of the database", "database", task.GetName())
+		task.SetInitMarker(&marker)
+		if err := r.Status().Update(ctx, task); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r *DbInitJobReconciler) getMongoConfig(ctx context.Context, namespace string, name string) (
 	*configv1.MongoConfig,
 	error,
@@ -286,11 +409,13 @@ func (r *DbInitJobReconciler) getServiceConfig(
 	return &config, nil
 }
 
-func (r *DbInitJobReconciler) createJob(ctx context.Context, job *jobv1.DbInitJob, serviceConfig *configv1.ServiceConfig) (*batchv1.Job, error) {
-	if serviceConfig.Spec.DbInitPodSpec == nil {
-		return nil, errors.New("no db init pod spec specified in the service config")
-	}
-
+func (r *DbInitJobReconciler) createJob(
+	ctx context.Context,
+	job *jobv1.DbInitJob,
+	serviceConfig *configv1.ServiceConfig,
+	sourcePodSpec corev1.PodSpec,
+	jobType string,
+) (*batchv1.Job, error) {
 	var site sitev1.StagingSite
 	err := r.Get(ctx, client.ObjectKey{Namespace: job.Namespace, Name: job.Spec.SiteName}, &site)
 	if err != nil {
@@ -301,7 +426,7 @@ func (r *DbInitJobReconciler) createJob(ctx context.Context, job *jobv1.DbInitJo
 	deadlineSeconds := int64(r.Config.InitJobConfig.DeadlineSeconds)
 	ttlSeconds := r.Config.InitJobConfig.TtlSeconds
 	jobLabels := map[string]string{
-		labels.Type:    "dbinit",
+		labels.Type:    jobType,
 		labels.JobName: job.Name,
 		labels.Site:    job.Spec.SiteName,
 		labels.Service: job.Spec.ServiceName,
@@ -311,7 +436,7 @@ func (r *DbInitJobReconciler) createJob(ctx context.Context, job *jobv1.DbInitJo
 		return nil, err
 	}
 
-	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(*serviceConfig.Spec.DbInitPodSpec, &templateHandler)
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(sourcePodSpec, &templateHandler)
 	if err != nil {
 		return nil, err
 	}
@@ -322,7 +447,7 @@ func (r *DbInitJobReconciler) createJob(ctx context.Context, job *jobv1.DbInitJo
 
 	batchJob := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      helpers.ShortenHumanReadableValue(fmt.Sprintf("dbinit-%s", job.Name), 50),
+			Name:      helpers.ShortenHumanReadableValue(fmt.Sprintf("%s-%s", jobType, job.Name), 50),
 			Namespace: job.Namespace,
 			Labels:    jobLabels,
 		},
@@ -346,9 +471,13 @@ func (r *DbInitJobReconciler) createJob(ctx context.Context, job *jobv1.DbInitJo
 	return batchJob, nil
 }
 
-func (r *DbInitJobReconciler) getOwnedInitJobs(job *jobv1.DbInitJob, ctx context.Context) (*batchv1.JobList, error) {
+func (r *DbInitJobReconciler) getOwnedBatchJobs(
+	job *jobv1.DbInitJob,
+	ctx context.Context,
+	jobType string,
+) (*batchv1.JobList, error) {
 	var jobList batchv1.JobList
-	labelMatcher := client.MatchingLabels{labels.JobName: job.Name, labels.Type: "dbinit"}
+	labelMatcher := client.MatchingLabels{labels.JobName: job.Name, labels.Type: jobType}
 	if err := r.List(ctx, &jobList, client.InNamespace(job.Namespace), labelMatcher); err != nil {
 		return nil, err
 	}
@@ -10,8 +10,12 @@ import (
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers/labels"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 	"github.com/szeber/kube-stager/internal/metricstest"
+	"github.com/szeber/kube-stager/internal/testutil"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -65,7 +69,7 @@ var _ = Describe("DbInitJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "isv",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -163,7 +167,7 @@ var _ = Describe("DbInitJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "ndb",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -261,7 +265,7 @@ var _ = Describe("DbInitJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "miv",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -375,7 +379,7 @@ var _ = Describe("DbInitJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "dlv",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -513,7 +517,7 @@ var _ = Describe("DbInitJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "npd",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -561,4 +565,237 @@ var _ = Describe("DbInitJobController", func() {
 			}, timeout, interval).Should(Succeed())
 		})
 	})
+
+	Describe("when the init job completes and the service has a DbSanitizePodSpec", func() {
+		var (
+			ns          string
+			siteName    string
+			serviceName string
+			mysqlName   string
+			jobName     string
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("dbinit-sanitize-%d", GinkgoParallelProcess())
+			siteName = "sanitize-site"
+			serviceName = "sanitize-svc"
+			mysqlName = "sanitize-mysql"
+			jobName = "sanitize-job"
+
+			nsObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: ns},
+			}
+			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+			mysqlConfig := &configv1.MysqlConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      mysqlName,
+					Namespace: ns,
+				},
+				Spec: configv1.MysqlConfigSpec{
+					Host:     "mysql.local",
+					Username: "root",
+					Password: "rootpass",
+					Port:     3306,
+				},
+			}
+			Expect(k8sClient.Create(ctx, mysqlConfig)).To(Succeed())
+
+			serviceConfig := &configv1.ServiceConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      serviceName,
+					Namespace: ns,
+				},
+				Spec: configv1.ServiceConfigSpec{
+					ShortName: "ssv",
+					DeploymentPodSpec: &corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:  "app",
+							Image: "busybox:latest",
+						}},
+					},
+					DbInitPodSpec: &corev1.PodSpec{
+						RestartPolicy: corev1.RestartPolicyNever,
+						Containers: []corev1.Container{{
+							Name:    "dbinit",
+							Image:   "busybox:latest",
+							Command: []string{"echo", "init"},
+						}},
+					},
+					DbSanitizePodSpec: &corev1.PodSpec{
+						RestartPolicy: corev1.RestartPolicyNever,
+						Containers: []corev1.Container{{
+							Name:    "dbsanitize",
+							Image:   "busybox:latest",
+							Command: []string{"echo", "sanitize"},
+						}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, serviceConfig)).To(Succeed())
+
+			site := &sitev1.StagingSite{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      siteName,
+					Namespace: ns,
+				},
+				Spec: sitev1.StagingSiteSpec{
+					Enabled: true,
+					Services: map[string]sitev1.StagingSiteService{
+						serviceName: {ImageTag: "latest", Replicas: 1},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, site)).To(Succeed())
+		})
+
+		It("should run the sanitize job before completing", func() {
+			job := &jobv1.DbInitJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      jobName,
+					Namespace: ns,
+				},
+				Spec: jobv1.DbInitJobSpec{
+					SiteName:         siteName,
+					ServiceName:      serviceName,
+					MysqlEnvironment: mysqlName,
+					DbInitSource:     "master",
+					DatabaseName:     "testdb",
+					Username:         "testuser",
+					Password:         "testpass",
+					DeadlineSeconds:  300,
+					Sanitize:         true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, job)).To(Succeed())
+
+			var batchJobs batchv1.JobList
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.List(
+					ctx,
+					&batchJobs,
+					client.InNamespace(ns),
+					client.MatchingLabels{labels.Type: "dbinit"},
+				)).To(Succeed())
+				g.Expect(batchJobs.Items).To(HaveLen(1))
+			}, timeout, interval).Should(Succeed())
+
+			// Simulate the completion of the init job. K8s 1.35+ requires startTime, completionTime and
+			// SuccessCriteriaMet before Complete on finished jobs.
+			batchJob := &batchJobs.Items[0]
+			now := metav1.Now()
+			batchJob.Status.StartTime = &now
+			batchJob.Status.CompletionTime = &now
+			batchJob.Status.Succeeded = 1
+			batchJob.Status.Conditions = append(batchJob.Status.Conditions,
+				batchv1.JobCondition{
+					Type:               batchv1.JobSuccessCriteriaMet,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: now,
+				},
+				batchv1.JobCondition{
+					Type:               batchv1.JobComplete,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: now,
+				},
+			)
+			Expect(k8sClient.Status().Update(ctx, batchJob)).To(Succeed())
+
+			fetched := &jobv1.DbInitJob{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), fetched)).To(Succeed())
+				g.Expect(fetched.Status.SanitizeState).To(Equal(jobv1.Running))
+				g.Expect(fetched.Status.State).To(Equal(jobv1.Running))
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.List(
+					ctx,
+					&batchJobs,
+					client.InNamespace(ns),
+					client.MatchingLabels{labels.Type: "dbsanitize"},
+				)).To(Succeed())
+				g.Expect(batchJobs.Items).To(HaveLen(1))
+				g.Expect(batchJobs.Items[0].Spec.Template.Spec.Containers[0].Name).To(Equal("dbsanitize"))
+			}, timeout, interval).Should(Succeed())
+		})
+	})
+
+	Describe("when a DbInitJob completes", func() {
+		var (
+			ns          string
+			siteName    string
+			serviceName string
+			mysqlName   string
+			database    *taskv1.MysqlDatabase
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("dbinit-marker-%d", GinkgoParallelProcess())
+			siteName = "marker-site"
+			serviceName = "marker-svc"
+			mysqlName = "marker-mysql"
+
+			nsObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: ns},
+			}
+			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+			Expect(k8sClient.Create(ctx, testutil.NewTestMysqlConfig(mysqlName, ns))).To(Succeed())
+
+			serviceConfig := testutil.NewTestServiceConfig(serviceName, ns, "msv")
+			serviceConfig.Spec.DbInitPodSpec = &corev1.PodSpec{
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers: []corev1.Container{{
+					Name:    "dbinit",
+					Image:   "busybox:latest",
+					Command: []string{"echo", "init"},
+				}},
+			}
+			Expect(k8sClient.Create(ctx, serviceConfig)).To(Succeed())
+
+			database = testutil.NewTestMysqlDatabase("marker-db", ns, siteName, serviceName, mysqlName)
+			database.Labels = map[string]string{labels.Site: siteName, labels.Service: serviceName}
+			Expect(k8sClient.Create(ctx, database)).To(Succeed())
+		})
+
+		It("should record the init marker in the databases of the service", func() {
+			job := &jobv1.DbInitJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "marker-job",
+					Namespace: ns,
+				},
+				Spec: jobv1.DbInitJobSpec{
+					SiteName:         siteName,
+					ServiceName:      serviceName,
+					MysqlEnvironment: mysqlName,
+					DbInitSource:     "master",
+					DatabaseName:     "testdb",
+					Username:         "testuser",
+					Password:         "testpass",
+					DeadlineSeconds:  300,
+					InitPodSpecHash:  "abc123",
+					ResetToken:       "reset-1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, job)).To(Succeed())
+
+			fetched := &jobv1.DbInitJob{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), fetched)).To(Succeed())
+				g.Expect(fetched.Status.State).To(Equal(jobv1.Running))
+				fetched.Status.State = jobv1.Complete
+				g.Expect(k8sClient.Status().Update(ctx, fetched)).To(Succeed())
+			}, timeout, interval).Should(Succeed())
+
+			fetchedDatabase := &taskv1.MysqlDatabase{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(database), fetchedDatabase)).To(Succeed())
+				g.Expect(fetchedDatabase.Status.InitMarker).NotTo(BeNil())
+				g.Expect(fetchedDatabase.Status.InitMarker.Source).To(Equal("master"))
+				g.Expect(fetchedDatabase.Status.InitMarker.PodSpecHash).To(Equal("abc123"))
+				g.Expect(fetchedDatabase.Status.InitMarker.ResetToken).To(Equal("reset-1"))
+			}, timeout, interval).Should(Succeed())
+		})
+	})
 })
@@ -49,7 +49,7 @@ var _ = Describe("DbMigrationJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "msv",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -144,7 +144,7 @@ var _ = Describe("DbMigrationJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "rtg",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -251,7 +251,7 @@ var _ = Describe("DbMigrationJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "dmv",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -369,7 +369,7 @@ var _ = Describe("DbMigrationJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "npm",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
@@ -452,7 +452,7 @@ var _ = Describe("DbMigrationJobController", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "dsv",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{{
 							Name:  "app",
 							Image: "busybox:latest",
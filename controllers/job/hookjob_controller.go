@@ -0,0 +1,105 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/getsentry/sentry-go"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// HookJobReconciler reconciles a HookJob object
+type HookJobReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config controllerconfigv1.ProjectConfig
+}
+
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete;deletecollection
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=hookjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=hookjobs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=hookjobs/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.23.3/pkg/reconcile
+func (r *HookJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.doReconcile(ctx, req)
+
+	if err != nil {
+		appmetrics.Errors.WithLabelValues("hook", "false").Inc()
+		sentry.CaptureException(err)
+	}
+
+	return result, err
+}
+
+func (r *HookJobReconciler) doReconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var job jobv1.HookJob
+
+	if err := r.Get(ctx, req.NamespacedName, &job); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to fetch hook job")
+		}
+
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return r.getRunner(&job).reconcile(ctx, &job)
+}
+
+func (r *HookJobReconciler) getRunner(job *jobv1.HookJob) revisionedJobRunner {
+	// The deadline is set per hook, the backoff limit and the TTL come from the controller config
+	jobConfig := r.Config.HookJobConfig
+	jobConfig.DeadlineSeconds = int32(job.Spec.DeadlineSeconds)
+
+	return revisionedJobRunner{
+		Client:    r.Client,
+		Scheme:    r.Scheme,
+		JobType:   "hook",
+		JobConfig: jobConfig,
+		GetPodSpec: func(_ jobv1.RevisionedJob, serviceConfig *configv1.ServiceConfig) (*corev1.PodSpec, error) {
+			hook, ok := job.GetHook(serviceConfig)
+			if !ok {
+				return nil, fmt.Errorf("hook %s is not found in the service config %s", job.Spec.HookName, serviceConfig.Name)
+			}
+			return &hook.PodSpec, nil
+		},
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HookJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&jobv1.HookJob{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
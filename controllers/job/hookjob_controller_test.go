@@ -0,0 +1,134 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/internal/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("HookJobController", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 200 * time.Millisecond
+	)
+
+	Describe("when a new HookJob is created for a configured hook", func() {
+		var (
+			ns          string
+			siteName    string
+			serviceName string
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("hook-new-%d", GinkgoParallelProcess())
+			siteName = "hook-site"
+			serviceName = "hook-svc"
+
+			nsObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: ns},
+			}
+			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+			serviceConfig := testutil.NewTestServiceConfig(serviceName, ns, "hks")
+			serviceConfig.Spec.Hooks = []configv1.ServiceHook{{
+				Name:  "seed",
+				Phase: configv1.HookPhasePostMigrate,
+				PodSpec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "seed",
+						Image:   "busybox:latest",
+						Command: []string{"echo", "seed"},
+					}},
+				},
+				DeadlineSeconds: 300,
+				BlockOnFailure:  true,
+			}}
+			Expect(k8sClient.Create(ctx, serviceConfig)).To(Succeed())
+
+			site := testutil.NewTestStagingSite(siteName, ns, map[string]sitev1.StagingSiteService{
+				serviceName: {ImageTag: "v1.0.0", Replicas: 1},
+			})
+			Expect(k8sClient.Create(ctx, site)).To(Succeed())
+
+			site.Status.State = sitev1.StatePending
+			site.Status.WorkloadHealth = sitev1.WorkloadHealthIncomplete
+			site.Status.Services = map[string]sitev1.StagingSiteServiceStatus{
+				serviceName: {DbName: "testdb", Username: "testuser"},
+			}
+			Expect(k8sClient.Status().Update(ctx, site)).To(Succeed())
+		})
+
+		It("should create a hook batch job and reach Running", func() {
+			job := testutil.NewTestHookJob("hook-job", ns, siteName, serviceName, "seed", configv1.HookPhasePostMigrate)
+			job.Spec.ImageTag = "v1.0.0"
+			Expect(k8sClient.Create(ctx, job)).To(Succeed())
+
+			fetched := &jobv1.HookJob{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), fetched)).To(Succeed())
+				g.Expect(fetched.Status.State).To(Equal(jobv1.Running))
+			}, timeout, interval).Should(Succeed())
+
+			Expect(fetched.Status.LastRunRevision).To(Equal(fetched.GetRevision()))
+
+			var batchJobs batchv1.JobList
+			Expect(k8sClient.List(
+				ctx,
+				&batchJobs,
+				client.InNamespace(ns),
+				client.MatchingLabels{labels.Type: "hook", labels.JobName: job.Name},
+			)).To(Succeed())
+			Expect(batchJobs.Items).To(HaveLen(1))
+			Expect(batchJobs.Items[0].Spec.Template.Spec.Containers[0].Name).To(Equal("seed"))
+		})
+	})
+
+	Describe("when the hook is not in the ServiceConfig", func() {
+		var (
+			ns          string
+			siteName    string
+			serviceName string
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("hook-missing-%d", GinkgoParallelProcess())
+			siteName = "missing-hook-site"
+			serviceName = "missing-hook-svc"
+
+			nsObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: ns},
+			}
+			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+			Expect(k8sClient.Create(ctx, testutil.NewTestServiceConfig(serviceName, ns, "mhs"))).To(Succeed())
+			site := testutil.NewTestStagingSite(siteName, ns, map[string]sitev1.StagingSiteService{
+				serviceName: {ImageTag: "v1.0.0", Replicas: 1},
+			})
+			Expect(k8sClient.Create(ctx, site)).To(Succeed())
+		})
+
+		It("should not complete", func() {
+			job := testutil.NewTestHookJob("missing-hook-job", ns, siteName, serviceName, "seed", configv1.HookPhasePostMigrate)
+			Expect(k8sClient.Create(ctx, job)).To(Succeed())
+
+			Consistently(func(g Gomega) {
+				fetched := &jobv1.HookJob{}
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), fetched)).To(Succeed())
+				g.Expect(fetched.Status.State).NotTo(Equal(jobv1.Complete))
+			}, 2*time.Second, interval).Should(Succeed())
+		})
+	})
+})
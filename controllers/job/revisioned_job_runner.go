@@ -0,0 +1,270 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	controller "github.com/szeber/kube-stager/controllers"
+	"github.com/szeber/kube-stager/handlers/template"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/helpers/pod"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+// revisionedJobRunner runs the batch job of a RevisionedJob once for every revision of its spec, and tracks the state
+// of the batch job in the status of the job. It's shared by the migration and hook jobs.
+type revisionedJobRunner struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// The type of the batch jobs, used in their names and labels and in the metrics
+	JobType string
+
+	// The deadline, backoff and TTL config of the batch jobs
+	JobConfig controllerconfigv1.JobConfig
+
+	// Returns the pod spec of the batch job from the service config, before the template variables are replaced
+	GetPodSpec func(job jobv1.RevisionedJob, serviceConfig *configv1.ServiceConfig) (*corev1.PodSpec, error)
+}
+
+func (r revisionedJobRunner) reconcile(ctx context.Context, job jobv1.RevisionedJob) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	status := job.GetBatchJobStatus()
+
+	if job.GetRevision() != job.GetLastRunRevision() {
+		logger.V(0).Info("Either this is a new job, or the revision has changed. Updating job state")
+		if err := r.deleteAssociatedJobs(job, ctx); err != nil {
+			return controller.SaveStatusUpdatesIfObjectChanged(false, r.Status(), ctx, job, ctrl.Result{}, err)
+		}
+		status.State = jobv1.Pending
+		job.SetLastRunRevision(job.GetRevision())
+		status.DeadlineTimestamp = &metav1.Time{Time: time.Now().Add(time.Duration(job.GetDeadlineSeconds()) * time.Second)}
+		return controller.SaveStatusUpdatesIfObjectChanged(
+			true,
+			r.Status(),
+			ctx,
+			job,
+			ctrl.Result{Requeue: true},
+			nil,
+		)
+	}
+
+	switch status.State {
+	case jobv1.Pending:
+		changed, err := r.createJobIfNeeded(job, ctx)
+		return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, job, ctrl.Result{}, err)
+	case jobv1.Failed, jobv1.Complete:
+		logger.V(0).Info("Job is in final state, ignoring", "state", status.State)
+		return ctrl.Result{}, nil
+	case jobv1.Running:
+		changed, err := r.processRunningJob(job, ctx)
+		return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, job, ctrl.Result{}, err)
+	default:
+		return ctrl.Result{}, fmt.Errorf("unknown state: %s", status.State)
+	}
+}
+
+func (r revisionedJobRunner) processRunningJob(job jobv1.RevisionedJob, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+	logger.V(0).Info("Loading batch job", "type", r.JobType)
+	status := job.GetBatchJobStatus()
+
+	jobList, err := r.getOwnedJobs(job, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if len(jobList.Items) != 1 {
+		logger.V(0).Info("Invalid number of batch jobs found. Expected to find 1 job.", "count", len(jobList.Items))
+		status.JobNotFoundCount = status.JobNotFoundCount + 1
+		if status.JobNotFoundCount > DbInitMaxJobFailedLoadLimit {
+			logger.V(0).Info("The maximum not found time exceeded the limit, failing the job")
+			status.State = jobv1.Failed
+		}
+		return true, nil
+	}
+
+	changed := false
+	if status.JobNotFoundCount > 0 {
+		status.JobNotFoundCount = 0
+		changed = true
+	}
+
+	batchJob := jobList.Items[0]
+
+	for _, v := range batchJob.Status.Conditions {
+		if v.Type == batchv1.JobComplete && v.Status == corev1.ConditionTrue {
+			logger.V(0).Info("Job finished successfully")
+			status.State = jobv1.Complete
+			appmetrics.JobCompletions.WithLabelValues(r.JobType, "success").Inc()
+			if status.DeadlineTimestamp != nil {
+				duration := time.Since(status.DeadlineTimestamp.Add(-time.Duration(job.GetDeadlineSeconds()) * time.Second))
+				appmetrics.JobDuration.WithLabelValues(r.JobType).Observe(duration.Seconds())
+			}
+			return true, nil
+		}
+
+		if v.Type == batchv1.JobFailed && v.Status == corev1.ConditionTrue {
+			logger.V(0).Info("Job failed", "status", v.Message, "reason", v.Reason)
+			status.State = jobv1.Failed
+			appmetrics.JobCompletions.WithLabelValues(r.JobType, "failure").Inc()
+			return true, nil
+		}
+	}
+
+	if time.Now().After(status.DeadlineTimestamp.Time) {
+		logger.V(0).Info("The job deadline has expired. Failing job.")
+		status.State = jobv1.Failed
+		appmetrics.JobCompletions.WithLabelValues(r.JobType, "failure").Inc()
+		return true, nil
+	}
+
+	logger.V(0).Info("Job is still running")
+	return changed, nil
+}
+
+func (r revisionedJobRunner) deleteAssociatedJobs(job jobv1.RevisionedJob, ctx context.Context) error {
+	batchJob := batchv1.Job{}
+	return r.DeleteAllOf(
+		ctx,
+		&batchJob,
+		client.InNamespace(job.GetNamespace()),
+		client.MatchingLabels{labels.JobName: job.GetName(), labels.Type: r.JobType},
+	)
+}
+
+func (r revisionedJobRunner) createJobIfNeeded(job jobv1.RevisionedJob, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Job is in Pending state")
+	jobList, err := r.getOwnedJobs(job, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if len(jobList.Items) > 0 {
+		logger.V(1).Info("There is already a job created, changing state to Running")
+		job.GetBatchJobStatus().State = jobv1.Running
+		return true, nil
+	}
+
+	serviceConfig, err := r.getServiceConfig(ctx, job.GetNamespace(), job.GetServiceName())
+	if err != nil {
+		return false, err
+	}
+
+	logger.V(0).Info("Creating job")
+	batchJob, err := r.createJob(ctx, job, serviceConfig)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.Create(ctx, batchJob); err != nil {
+		return false, err
+	}
+
+	logger.V(0).Info("Job created, setting state to running")
+	job.GetBatchJobStatus().State = jobv1.Running
+
+	return true, nil
+}
+
+func (r revisionedJobRunner) getServiceConfig(
+	ctx context.Context,
+	namespace string,
+	name string,
+) (*configv1.ServiceConfig, error) {
+	var config configv1.ServiceConfig
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (r revisionedJobRunner) getOwnedJobs(job jobv1.RevisionedJob, ctx context.Context) (*batchv1.JobList, error) {
+	var jobList batchv1.JobList
+	labelMatcher := client.MatchingLabels{labels.JobName: job.GetName(), labels.Type: r.JobType}
+	if err := r.List(ctx, &jobList, client.InNamespace(job.GetNamespace()), labelMatcher); err != nil {
+		return nil, err
+	}
+
+	return &jobList, nil
+}
+
+func (r revisionedJobRunner) createJob(
+	ctx context.Context,
+	job jobv1.RevisionedJob,
+	serviceConfig *configv1.ServiceConfig,
+) (*batchv1.Job, error) {
+	sourcePodSpec, err := r.GetPodSpec(job, serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var site sitev1.StagingSite
+	err = r.Get(ctx, client.ObjectKey{Namespace: job.GetNamespace(), Name: job.GetSiteName()}, &site)
+	if err != nil {
+		return nil, err
+	}
+
+	backoffLimit := r.JobConfig.BackoffLimit
+	deadlineSeconds := int64(r.JobConfig.DeadlineSeconds)
+	ttlSeconds := r.JobConfig.TtlSeconds
+	jobLabels := map[string]string{
+		labels.Type:    r.JobType,
+		labels.JobName: job.GetName(),
+		labels.Site:    job.GetSiteName(),
+		labels.Service: job.GetServiceName(),
+	}
+	templateHandler := template.NewSite(site, *serviceConfig)
+	err = template.LoadConfigs(&templateHandler, ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(*sourcePodSpec, &templateHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	if podSpec.RestartPolicy != corev1.RestartPolicyOnFailure && podSpec.RestartPolicy != corev1.RestartPolicyNever {
+		podSpec.RestartPolicy = corev1.RestartPolicyOnFailure
+	}
+
+	batchJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helpers.ShortenHumanReadableValue(fmt.Sprintf("%s-%s", r.JobType, job.GetName()), 50),
+			Namespace: job.GetNamespace(),
+			Labels:    jobLabels,
+		},
+		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: &deadlineSeconds,
+			BackoffLimit:          &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: jobLabels,
+				},
+				Spec: pod.SetExtraEnvVarsOnPodSpec(podSpec, &site, serviceConfig),
+			},
+			TTLSecondsAfterFinished: &ttlSeconds,
+		},
+	}
+
+	if err = ctrl.SetControllerReference(job, batchJob, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return batchJob, nil
+}
@@ -104,6 +104,13 @@ var _ = BeforeSuite(
 		}).SetupWithManager(mgr)
 		Expect(err).NotTo(HaveOccurred())
 
+		err = (&DatabaseMoveReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Config: controllerconfigv1.ProjectConfig{},
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
 		err = (&DbMigrationJobReconciler{
 			Client: mgr.GetClient(),
 			Scheme: mgr.GetScheme(),
@@ -111,6 +118,13 @@ var _ = BeforeSuite(
 		}).SetupWithManager(mgr)
 		Expect(err).NotTo(HaveOccurred())
 
+		err = (&HookJobReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Config: controllerconfigv1.ProjectConfig{},
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
 		go func() {
 			defer GinkgoRecover()
 			if err := mgr.Start(ctx); err != nil {
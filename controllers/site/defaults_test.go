@@ -56,7 +56,7 @@ var _ = Describe("Kubebuilder default values", func() {
 				},
 				Spec: configv1.ServiceConfigSpec{
 					ShortName: "web",
-					DeploymentPodSpec: corev1.PodSpec{
+					DeploymentPodSpec: &corev1.PodSpec{
 						Containers: []corev1.Container{
 							{Name: "nginx", Image: "nginx:latest"},
 						},
@@ -24,27 +24,45 @@ import (
 	"github.com/go-logr/logr"
 	api "github.com/szeber/kube-stager/apis"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
 	controller "github.com/szeber/kube-stager/controllers"
+	"github.com/szeber/kube-stager/handlers/chart"
 	"github.com/szeber/kube-stager/handlers/job"
+	"github.com/szeber/kube-stager/handlers/provider"
 	sitehandler "github.com/szeber/kube-stager/handlers/site"
 	"github.com/szeber/kube-stager/handlers/task"
 	"github.com/szeber/kube-stager/helpers"
 	"github.com/szeber/kube-stager/helpers/annotations"
 	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
 	"github.com/szeber/kube-stager/helpers/indexes"
+	"github.com/szeber/kube-stager/helpers/labels"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 	"hash/fnv"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"reflect"
+	"slices"
 	"sort"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 )
@@ -53,6 +71,8 @@ import (
 type StagingSiteReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	Config controllerconfigv1.ProjectConfig
+	Charts *chart.Loader
 	Clock
 }
 
@@ -67,16 +87,29 @@ type Clock interface {
 }
 
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=mongoconfigs,verbs=get;list;watch
 //+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=mysqlconfigs,verbs=get;list;watch
 //+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=redisconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=objectstorageconfigs,verbs=get;list;watch
 //+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=serviceconfigs,verbs=get;list;watch;update;patch;
 //+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mongodatabases,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mysqldatabases,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=redisdatabases,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=objectstoragebuckets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=provisionertasks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=databasemoves,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=job.operator.kube-stager.io,resources=hookjobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=site.operator.kube-stager.io,resources=stagingsites,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=site.operator.kube-stager.io,resources=stagingsites/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=site.operator.kube-stager.io,resources=stagingsites/finalizers,verbs=update
@@ -149,13 +182,46 @@ func (r *StagingSiteReconciler) doReconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	isSiteChanged := false
-	logger.V(0).Info("Ensuring databases are created")
-	if changed, err := r.ensureDatabasesAreCreated(site, ctx); err != nil {
+	logger.V(0).Info("Ensuring the environment condition is up to date")
+	if changed, err := r.ensureEnvironmentConditionIsUpToDate(site, ctx); err != nil {
 		return r.SaveStatusUpdatesIfObjectChanged(changed, ctx, site, ctrl.Result{}, err)
 	} else {
 		isSiteChanged = changed
 	}
 
+	logger.V(0).Info("Ensuring databases are created")
+	if changed, err := r.ensureDatabasesAreCreated(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	} else {
+		isSiteChanged = isSiteChanged || changed
+	}
+
+	logger.V(0).Info("Ensuring services are provisioned")
+	if changed, err := r.ensureServicesAreProvisioned(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	} else {
+		isSiteChanged = isSiteChanged || changed
+	}
+
+	logger.V(0).Info("Ensuring databases are moved")
+	if changed, err := r.ensureDatabasesAreMoved(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	} else {
+		isSiteChanged = isSiteChanged || changed
+	}
+
+	if !site.Status.ProvisioningComplete {
+		// The configs may use the outputs of the provisioners, so wait till they are available
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, nil)
+	}
+
+	logger.V(0).Info("Ensuring external urls are up to date")
+	if changed, err := r.ensureExternalUrlsAreUpToDate(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	} else {
+		isSiteChanged = isSiteChanged || changed
+	}
+
 	logger.V(0).Info("Ensuring configs are up to date")
 	if changed, err := r.ensureConfigsAreUpToDate(site, ctx); err != nil {
 		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
@@ -189,6 +255,37 @@ func (r *StagingSiteReconciler) doReconcile(ctx context.Context, req ctrl.Reques
 		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, nil)
 	}
 
+	logger.V(0).Info("Ensuring hooks are complete")
+	if changed, err := r.ensureHooksAreComplete(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	} else {
+		isSiteChanged = isSiteChanged || changed
+	}
+	if !site.Status.HooksComplete {
+		// Seeding has to finish before the workloads start, and cleanup before they are stopped
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, nil)
+	}
+
+	if isMoveInProgress(site) {
+		// The workloads must not write to the databases while they are being copied
+		logger.V(0).Info("Pausing workloads while databases are moved")
+		if err := r.ensureCronJobsAreSuspended(site, ctx); err != nil {
+			return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, err)
+		}
+		if err := r.ensureChartWorkloadsArePaused(site, ctx); err != nil {
+			return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, err)
+		}
+		changed, err := r.ensureWorkloadsArePaused(site, ctx)
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	}
+
+	logger.V(0).Info("Ensuring extra objects are up to date")
+	if changed, err := r.ensureExtraObjectsAreUpToDate(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	} else {
+		isSiteChanged = isSiteChanged || changed
+	}
+
 	logger.V(0).Info("Ensuring workloads are up to date")
 	if changed, err := r.ensureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
 		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
@@ -196,6 +293,23 @@ func (r *StagingSiteReconciler) doReconcile(ctx context.Context, req ctrl.Reques
 		isSiteChanged = isSiteChanged || changed
 	}
 
+	logger.V(0).Info("Ensuring helm chart objects are up to date")
+	if changed, err := r.ensureChartObjectsAreUpToDate(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
+	} else {
+		isSiteChanged = isSiteChanged || changed
+	}
+
+	logger.V(0).Info("Ensuring autoscalers are up to date")
+	if err := r.ensureAutoscalersAreUpToDate(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, err)
+	}
+
+	logger.V(0).Info("Ensuring cron jobs are up to date")
+	if err := r.ensureCronJobsAreUpToDate(site, ctx); err != nil {
+		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, err)
+	}
+
 	logger.V(0).Info("Ensuring networking objects are up to date")
 	if changed, err := r.ensureNetworkingObjectsAreUpToDate(site, ctx); err != nil {
 		return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged || changed, ctx, site, ctrl.Result{}, err)
@@ -203,6 +317,13 @@ func (r *StagingSiteReconciler) doReconcile(ctx context.Context, req ctrl.Reques
 		isSiteChanged = isSiteChanged || changed
 	}
 
+	if site.Status.Enabled && site.Status.WorkloadHealth == sitev1.WorkloadHealthHealthy {
+		logger.V(0).Info("Ensuring post deploy hooks are run")
+		if err := r.ensurePostDeployHooksAreRun(site, ctx); err != nil {
+			return r.SaveStatusUpdatesIfObjectChanged(isSiteChanged, ctx, site, ctrl.Result{}, err)
+		}
+	}
+
 	if site.Status.State != sitev1.StateComplete {
 		site.Status.State = sitev1.StateComplete
 		isSiteChanged = true
@@ -276,6 +397,17 @@ func (r *StagingSiteReconciler) handleDelete(ctx context.Context, logger logr.Lo
 		return r.SaveStatusUpdatesIfObjectChanged(true, ctx, site, ctrl.Result{Requeue: true}, nil)
 	}
 
+	logger.Info("Running the pre delete hooks")
+	hookHandler := job.HookJobHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+	if _, err := hookHandler.EnsureHooksAreCreated(site, ctx, configv1.HookPhasePreDelete); err != nil {
+		return ctrl.Result{}, err
+	}
+	// A failed blocking hook is retried with a backoff, the deletion continues once it's removed from the service config
+	if complete, err := hookHandler.EnsureHooksAreComplete(site, ctx, configv1.HookPhasePreDelete); err != nil ||
+		!complete {
+		return ctrl.Result{}, err
+	}
+
 	if site.Spec.BackupBeforeDelete {
 		logger.Info("Making a backup of the databases before deletion")
 		backupHandler := job.BackupHandler{
@@ -454,6 +586,12 @@ func (r *StagingSiteReconciler) ensureStatusIsUpToDate(site *sitev1.StagingSite,
 		}
 	}
 
+	baselineStatusChanged, err := r.ensureBaselineStatusIsUpToDate(site, ctx)
+	if err != nil {
+		return isChanged, err
+	}
+	isChanged = isChanged || baselineStatusChanged
+
 	if site.Status.WorkloadHealth == "" {
 		site.Status.WorkloadHealth = sitev1.WorkloadHealthIncomplete
 	}
@@ -540,6 +678,38 @@ func (r *StagingSiteReconciler) ensureStatusIsUpToDate(site *sitev1.StagingSite,
 	return isChanged, nil
 }
 
+// ensureBaselineStatusIsUpToDate lists the services deployed by the site and the ones used from its baseline site in the
+// status. A missing baseline site has no inherited services
+func (r *StagingSiteReconciler) ensureBaselineStatusIsUpToDate(site *sitev1.StagingSite, ctx context.Context) (
+	bool,
+	error,
+) {
+	var localServices []string
+	var inheritedServices []string
+
+	if site.Spec.BaselineSite != "" {
+		localServices = site.GetLocalServices()
+
+		baselineSite := &sitev1.StagingSite{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: site.Spec.BaselineSite}, baselineSite)
+		if err == nil {
+			inheritedServices = site.GetInheritedServices(baselineSite)
+		} else if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	if slices.Equal(site.Status.LocalServices, localServices) &&
+		slices.Equal(site.Status.InheritedServices, inheritedServices) {
+		return false, nil
+	}
+
+	site.Status.LocalServices = localServices
+	site.Status.InheritedServices = inheritedServices
+
+	return true, nil
+}
+
 func (r *StagingSiteReconciler) resetStatusErrors(site *sitev1.StagingSite) {
 	if site.Status.State == sitev1.StateFailed {
 		// If we are in a failed state reset to pending and clear the error message so they can be set and populated if
@@ -588,12 +758,21 @@ func (r *StagingSiteReconciler) getNextBackupTimeForSite(site *sitev1.StagingSit
 	return &metav1.Time{Time: nextRunAt}, nil
 }
 
+func (r *StagingSiteReconciler) ensureEnvironmentConditionIsUpToDate(
+	site *sitev1.StagingSite,
+	ctx context.Context,
+) (bool, error) {
+	handler := sitehandler.EnvironmentHandler{Reader: r}
+
+	return handler.EnsureEnvironmentConditionIsUpToDate(site, ctx)
+}
+
 func (r *StagingSiteReconciler) ensureDatabasesAreCreated(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
-	handlers := []task.TaskHandler{
-		task.MysqlTaskHandler{Reader: r, Writer: r, Scheme: r.Scheme},
-		task.MongoTaskHandler{Reader: r, Writer: r, Scheme: r.Scheme},
-		task.RedisTaskHandler{Reader: r, Writer: r, Scheme: r.Scheme},
+	var handlers []task.TaskHandler
+	for _, databaseProvider := range provider.All() {
+		handlers = append(handlers, databaseProvider.NewTaskHandler(r, r, r.Scheme))
 	}
+	handlers = append(handlers, task.ObjectStorageTaskHandler{Reader: r, Writer: r, Scheme: r.Scheme})
 
 	originalCompletion := site.Status.DatabaseCreationComplete
 	site.Status.DatabaseCreationComplete = true
@@ -619,6 +798,54 @@ func (r *StagingSiteReconciler) ensureDatabasesAreCreated(site *sitev1.StagingSi
 	return originalCompletion != site.Status.DatabaseCreationComplete, nil
 }
 
+func (r *StagingSiteReconciler) ensureServicesAreProvisioned(site *sitev1.StagingSite, ctx context.Context) (
+	bool,
+	error,
+) {
+	handler := task.ProvisionerTaskHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	originalCompletion := site.Status.ProvisioningComplete
+	site.Status.ProvisioningComplete = true
+
+	isComplete, err := handler.EnsureDatabasesAreCreated(site, ctx)
+	if err != nil {
+		return false, err
+	}
+	site.Status.ProvisioningComplete = site.Status.ProvisioningComplete && isComplete
+
+	isComplete, err = handler.EnsureDatabasesAreReady(site, ctx)
+	if err != nil {
+		return false, err
+	}
+	site.Status.ProvisioningComplete = site.Status.ProvisioningComplete && isComplete
+
+	return originalCompletion != site.Status.ProvisioningComplete, nil
+}
+
+func (r *StagingSiteReconciler) ensureDatabasesAreMoved(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	handler := job.DatabaseMoveHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	originalMoves := site.Status.DatabaseMoves
+
+	if _, err := handler.EnsureMovesAreCreated(site, ctx); err != nil {
+		return false, err
+	}
+
+	_, err := handler.EnsureMovesAreComplete(site, ctx)
+
+	return !reflect.DeepEqual(originalMoves, site.Status.DatabaseMoves), err
+}
+
+// isMoveInProgress returns TRUE if any of the database moves listed in the status of the site didn't finish yet
+func isMoveInProgress(site *sitev1.StagingSite) bool {
+	for _, move := range site.Status.DatabaseMoves {
+		if !jobv1.JobState(move.State).IsFinal() {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *StagingSiteReconciler) ensureDatabasesAreInitialised(site *sitev1.StagingSite, ctx context.Context) (
 	bool,
 	error,
@@ -626,6 +853,10 @@ func (r *StagingSiteReconciler) ensureDatabasesAreInitialised(site *sitev1.Stagi
 	handler := job.DbInitJobHandler{Reader: r, Writer: r, Scheme: r.Scheme}
 
 	originalCompletion := site.Status.DatabaseInitialisationComplete
+	originalServices := make(map[string]sitev1.StagingSiteServiceStatus, len(site.Status.Services))
+	for name, service := range site.Status.Services {
+		originalServices[name] = service
+	}
 	site.Status.DatabaseInitialisationComplete = true
 
 	isComplete, err := handler.EnsureJobsAreCreated(site, ctx)
@@ -640,7 +871,8 @@ func (r *StagingSiteReconciler) ensureDatabasesAreInitialised(site *sitev1.Stagi
 	}
 	site.Status.DatabaseInitialisationComplete = site.Status.DatabaseInitialisationComplete && isComplete
 
-	return originalCompletion != site.Status.DatabaseInitialisationComplete, nil
+	return originalCompletion != site.Status.DatabaseInitialisationComplete ||
+		!reflect.DeepEqual(originalServices, site.Status.Services), nil
 }
 
 func (r *StagingSiteReconciler) ensureDatabaseMigrationJobsAreCreated(
@@ -667,6 +899,53 @@ func (r *StagingSiteReconciler) ensureDatabaseMigrationJobsAreCreated(
 	return originalCompletion != site.Status.DatabaseMigrationsComplete, nil
 }
 
+// ensureHooksAreComplete runs the post migration hooks of enabled sites and the pre disable hooks of disabled ones. The
+// hooks of the other phases are deleted, so they run again the next time the site reaches their phase
+func (r *StagingSiteReconciler) ensureHooksAreComplete(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	handler := job.HookJobHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	originalCompletion := site.Status.HooksComplete
+	site.Status.HooksComplete = true
+
+	phase := configv1.HookPhasePostMigrate
+	phasesToDelete := []configv1.HookPhase{configv1.HookPhasePreDisable}
+	if !site.Status.Enabled {
+		phase = configv1.HookPhasePreDisable
+		phasesToDelete = []configv1.HookPhase{configv1.HookPhasePostMigrate, configv1.HookPhasePostDeploy}
+	}
+
+	if _, err := handler.DeleteHooks(site, ctx, phasesToDelete...); err != nil {
+		return false, err
+	}
+
+	isComplete, err := handler.EnsureHooksAreCreated(site, ctx, phase)
+	if err != nil {
+		return false, err
+	}
+	site.Status.HooksComplete = site.Status.HooksComplete && isComplete
+
+	isComplete, err = handler.EnsureHooksAreComplete(site, ctx, phase)
+	if err != nil {
+		return false, err
+	}
+	site.Status.HooksComplete = site.Status.HooksComplete && isComplete
+
+	return originalCompletion != site.Status.HooksComplete, nil
+}
+
+// ensurePostDeployHooksAreRun runs the post deploy hooks once the workloads are healthy. Nothing waits for them, but the
+// failure of a blocking one still fails the site
+func (r *StagingSiteReconciler) ensurePostDeployHooksAreRun(site *sitev1.StagingSite, ctx context.Context) error {
+	handler := job.HookJobHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	if _, err := handler.EnsureHooksAreCreated(site, ctx, configv1.HookPhasePostDeploy); err != nil {
+		return err
+	}
+	_, err := handler.EnsureHooksAreComplete(site, ctx, configv1.HookPhasePostDeploy)
+
+	return err
+}
+
 func (r *StagingSiteReconciler) ensureConfigsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
 	handler := sitehandler.ConfigHandler{Reader: r, Writer: r, Scheme: r.Scheme}
 	isChanged := false
@@ -694,11 +973,75 @@ func (r *StagingSiteReconciler) ensureWorkloadObjectsAreUpToDate(site *sitev1.St
 	return isChanged, nil
 }
 
+func (r *StagingSiteReconciler) ensureWorkloadsArePaused(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	handler := sitehandler.WorkloadHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	return handler.EnsureWorkloadsArePaused(site, ctx)
+}
+
+func (r *StagingSiteReconciler) ensureAutoscalersAreUpToDate(site *sitev1.StagingSite, ctx context.Context) error {
+	handler := sitehandler.AutoscalerHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	return handler.EnsureAutoscalersAreUpToDate(site, ctx)
+}
+
+func (r *StagingSiteReconciler) ensureCronJobsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) error {
+	handler := sitehandler.CronJobHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	return handler.EnsureCronJobsAreUpToDate(site, ctx)
+}
+
+func (r *StagingSiteReconciler) ensureExtraObjectsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	handler := sitehandler.ExtraObjectHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	return handler.EnsureExtraObjectsAreUpToDate(site, ctx)
+}
+
+func (r *StagingSiteReconciler) ensureChartObjectsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	return r.newChartHandler().EnsureChartObjectsAreUpToDate(site, ctx)
+}
+
+func (r *StagingSiteReconciler) ensureChartWorkloadsArePaused(site *sitev1.StagingSite, ctx context.Context) error {
+	return r.newChartHandler().EnsureChartWorkloadsArePaused(site, ctx)
+}
+
+func (r *StagingSiteReconciler) newChartHandler() sitehandler.ChartHandler {
+	return sitehandler.ChartHandler{
+		Reader:     r,
+		Writer:     r,
+		Scheme:     r.Scheme,
+		RESTMapper: r.RESTMapper(),
+		Charts:     r.Charts,
+	}
+}
+
+func (r *StagingSiteReconciler) ensureCronJobsAreSuspended(site *sitev1.StagingSite, ctx context.Context) error {
+	handler := sitehandler.CronJobHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+
+	return handler.EnsureCronJobsAreSuspended(site, ctx)
+}
+
+func (r *StagingSiteReconciler) ensureExternalUrlsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	handler := r.newNetworkingHandler()
+
+	return handler.EnsureExternalUrlsAreUpToDate(site, ctx)
+}
+
+func (r *StagingSiteReconciler) newNetworkingHandler() sitehandler.NetworkingHandler {
+	return sitehandler.NetworkingHandler{
+		Reader:           r,
+		Writer:           r,
+		Scheme:           r.Scheme,
+		NetworkIsolation: r.Config.NetworkIsolation,
+		Ingress:          r.Config.Ingress,
+	}
+}
+
 func (r *StagingSiteReconciler) ensureNetworkingObjectsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (
 	bool,
 	error,
 ) {
-	handler := sitehandler.NetworkingHandler{Reader: r, Writer: r, Scheme: r.Scheme}
+	handler := r.newNetworkingHandler()
 	isChanged := false
 
 	if changed, err := handler.EnsureNetworkingObjectsAreUpToDate(site, ctx); err != nil {
@@ -749,8 +1092,10 @@ func (r *StagingSiteReconciler) setSiteState(site *sitev1.StagingSite) {
 	}
 
 	if site.Status.DatabaseCreationComplete &&
+		site.Status.ProvisioningComplete &&
 		site.Status.DatabaseInitialisationComplete &&
 		site.Status.DatabaseMigrationsComplete &&
+		site.Status.HooksComplete &&
 		site.Status.ConfigsAreCreated &&
 		site.Status.NetworkingObjectsAreCreated &&
 		site.Status.WorkloadsAreCreated &&
@@ -826,14 +1171,140 @@ func (r *StagingSiteReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&sitev1.StagingSite{}).
-		Owns(&taskv1.MongoDatabase{}).
-		Owns(&taskv1.MysqlDatabase{}).
-		Owns(&taskv1.RedisDatabase{}).
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(), &sitev1.StagingSite{}, indexes.BaselineSite, func(rawObj client.Object) []string {
+			site := rawObj.(*sitev1.StagingSite)
+			return []string{site.Spec.BaselineSite}
+		},
+	); err != nil {
+		return err
+	}
+
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).For(&sitev1.StagingSite{})
+	for _, databaseProvider := range provider.All() {
+		// Only spec changes of the environments are watched, so the periodic probes don't trigger reconciles
+		controllerBuilder = controllerBuilder.
+			Owns(databaseProvider.NewTask()).
+			Watches(
+				databaseProvider.NewConfig(),
+				handler.EnqueueRequestsFromMapFunc(
+					r.mapEnvironmentToSites(labels.MakeEnvironmentsPrefix(databaseProvider.Name())),
+				),
+				builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+			)
+	}
+
+	return controllerBuilder.
+		Owns(&taskv1.ProvisionerTask{}).
+		Owns(&taskv1.ObjectStorageBucket{}).
 		Owns(&jobv1.DbInitJob{}).
 		Owns(&jobv1.DbMigrationJob{}).
+		Owns(&jobv1.HookJob{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&batchv1.CronJob{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&networkingv1.NetworkPolicy{}).
 		Owns(&jobv1.Backup{}).
+		Watches(
+			&jobv1.DatabaseMove{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+				// Manually created moves are not owned by the site, so they are mapped by the site name in their spec
+				move := object.(*jobv1.DatabaseMove)
+				return []reconcile.Request{
+					{NamespacedName: types.NamespacedName{Namespace: move.Namespace, Name: move.Spec.SiteName}},
+				}
+			}),
+		).
+		Watches(
+			&sitev1.StagingSite{},
+			handler.EnqueueRequestsFromMapFunc(r.mapBaselineSiteToSites),
+			builder.WithPredicates(baselineSiteChangedPredicate()),
+		).
+		Watches(
+			&configv1.ServiceConfig{},
+			handler.Funcs{
+				// Only the cached chart of a deleted service config is removed, the sites using it are not reconciled
+				DeleteFunc: func(
+					_ context.Context,
+					e event.DeleteEvent,
+					_ workqueue.TypedRateLimitingInterface[reconcile.Request],
+				) {
+					r.Charts.Forget(e.Object.GetNamespace(), e.Object.GetName())
+				},
+			},
+		).
 		Complete(r)
 }
+
+// baselineSiteChangedPredicate passes the spec changes of the sites, and the changes of their service statuses, which
+// hold the database names and external urls used in the template values of the sites using them as their baseline
+func baselineSiteChangedPredicate() predicate.Predicate {
+	return predicate.Or[client.Object](
+		predicate.GenerationChangedPredicate{},
+		predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				oldSite, ok := e.ObjectOld.(*sitev1.StagingSite)
+				if !ok {
+					return false
+				}
+				newSite, ok := e.ObjectNew.(*sitev1.StagingSite)
+				if !ok {
+					return false
+				}
+				return !equality.Semantic.DeepEqual(oldSite.Status.Services, newSite.Status.Services)
+			},
+		},
+	)
+}
+
+// mapBaselineSiteToSites returns the sites using the site as their baseline, whose template values and status depend on
+// its spec, and the baseline site of the site, whose network policy allows connections from the sites using it
+func (r *StagingSiteReconciler) mapBaselineSiteToSites(ctx context.Context, object client.Object) []reconcile.Request {
+	site := object.(*sitev1.StagingSite)
+
+	var list sitev1.StagingSiteList
+	if err := r.List(
+		ctx,
+		&list,
+		client.InNamespace(site.Namespace),
+		client.MatchingFields{indexes.BaselineSite: site.Name},
+	); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list the sites using the baseline site", "site", site.Name)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items)+1)
+	for _, dependentSite := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dependentSite)})
+	}
+	if site.Spec.BaselineSite != "" {
+		requests = append(
+			requests,
+			reconcile.Request{NamespacedName: types.NamespacedName{Namespace: site.Namespace, Name: site.Spec.BaselineSite}},
+		)
+	}
+	return requests
+}
+
+// mapEnvironmentToSites returns the sites using an environment, based on the environment labels set by the webhook
+func (r *StagingSiteReconciler) mapEnvironmentToSites(labelPrefix string) handler.MapFunc {
+	return func(ctx context.Context, object client.Object) []reconcile.Request {
+		var list sitev1.StagingSiteList
+		if err := r.List(
+			ctx,
+			&list,
+			client.InNamespace(object.GetNamespace()),
+			client.MatchingLabels{labelPrefix + object.GetName(): "true"},
+		); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list the sites of the environment", "environment", object.GetName())
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(list.Items))
+		for _, site := range list.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&site)})
+		}
+		return requests
+	}
+}
@@ -36,6 +36,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 func createNamespace() string {
@@ -452,6 +453,7 @@ var _ = Describe("StagingSite controller", func() {
 			Expect(fetched.Status.DatabaseCreationComplete).To(BeTrue())
 			Expect(fetched.Status.DatabaseInitialisationComplete).To(BeTrue())
 			Expect(fetched.Status.DatabaseMigrationsComplete).To(BeTrue())
+			Expect(fetched.Status.HooksComplete).To(BeTrue())
 			Expect(fetched.Status.ConfigsAreCreated).To(BeTrue())
 			Expect(fetched.Status.WorkloadsAreCreated).To(BeTrue())
 			Expect(fetched.Status.NetworkingObjectsAreCreated).To(BeTrue())
@@ -745,6 +747,57 @@ var _ = Describe("StagingSite controller", func() {
 		})
 	})
 
+	Describe("Post migrate hook gates the workloads", func() {
+		var (
+			ns       string
+			siteName string
+		)
+
+		BeforeEach(func() {
+			ns = createNamespace()
+			siteName = "site-hook"
+			testClock.SetNow(time.Now())
+
+			sc := testutil.NewTestServiceConfig("web", ns, "web")
+			sc.Spec.Hooks = []configv1.ServiceHook{{
+				Name:  "seed",
+				Phase: configv1.HookPhasePostMigrate,
+				PodSpec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "seed", Image: "busybox:latest"}},
+				},
+				DeadlineSeconds: 300,
+				BlockOnFailure:  true,
+			}}
+			Expect(k8sClient.Create(ctx, sc)).To(Succeed())
+
+			site := testutil.NewTestStagingSite(siteName, ns, map[string]sitev1.StagingSiteService{
+				"web": {
+					ImageTag: "latest",
+					Replicas: 1,
+				},
+			})
+			site.Annotations = map[string]string{}
+			Expect(k8sClient.Create(ctx, site)).To(Succeed())
+		})
+
+		It("should create the hook job and not create the Deployment until the hook is complete", func() {
+			Eventually(func(g Gomega) {
+				var hookJobs jobv1.HookJobList
+				g.Expect(k8sClient.List(ctx, &hookJobs, client.InNamespace(ns))).To(Succeed())
+				g.Expect(hookJobs.Items).To(HaveLen(1))
+				g.Expect(hookJobs.Items[0].Spec.HookName).To(Equal("seed"))
+				g.Expect(hookJobs.Items[0].Spec.Phase).To(Equal(configv1.HookPhasePostMigrate))
+			}, timeout, interval).Should(Succeed())
+
+			// The hook job controller is not running in this suite, so the hook never completes
+			Consistently(func(g Gomega) {
+				var deployments appsv1.DeploymentList
+				g.Expect(k8sClient.List(ctx, &deployments, client.InNamespace(ns))).To(Succeed())
+				g.Expect(deployments.Items).To(BeEmpty())
+			}, 2*time.Second, interval).Should(Succeed())
+		})
+	})
+
 	Describe("Missing ServiceConfig error handling", func() {
 		var (
 			ns       string
@@ -948,4 +1001,23 @@ var _ = Describe("StagingSite controller", func() {
 			}, timeout, interval).Should(Succeed())
 		})
 	})
+
+	Describe("Baseline site watch predicate", func() {
+		It("should pass spec and service status changes, but not other status changes", func() {
+			oldSite := &sitev1.StagingSite{ObjectMeta: metav1.ObjectMeta{Name: "master", Generation: 1}}
+			oldSite.Status.Services = map[string]sitev1.StagingSiteServiceStatus{"web": {}}
+
+			specChanged := oldSite.DeepCopy()
+			specChanged.Generation = 2
+			serviceStatusChanged := oldSite.DeepCopy()
+			serviceStatusChanged.Status.Services["web"] = sitev1.StagingSiteServiceStatus{ExternalUrl: "https://web"}
+			otherStatusChanged := oldSite.DeepCopy()
+			otherStatusChanged.Status.State = sitev1.StateComplete
+
+			p := baselineSiteChangedPredicate()
+			Expect(p.Update(event.UpdateEvent{ObjectOld: oldSite, ObjectNew: specChanged})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: oldSite, ObjectNew: serviceStatusChanged})).To(BeTrue())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: oldSite, ObjectNew: otherStatusChanged})).To(BeFalse())
+		})
+	})
 })
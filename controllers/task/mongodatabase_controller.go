@@ -24,6 +24,7 @@ import (
 	controller "github.com/szeber/kube-stager/controllers"
 	"github.com/szeber/kube-stager/handlers/database"
 	"github.com/szeber/kube-stager/helpers"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -36,6 +37,7 @@ type MongoDatabaseReconciler struct {
 	client.Client
 	Scheme             *runtime.Scheme
 	DatabaseReconciler database.MongoReconciler
+	Connections        *database.ConnectionManager
 }
 
 //+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mongodatabases,verbs=get;list;watch;create;update;patch;delete
@@ -52,7 +54,10 @@ func (r *MongoDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	if err != nil {
 		appmetrics.Errors.WithLabelValues("mongo", "false").Inc()
-		sentry.CaptureException(err)
+		// Timeouts are retried with backoff and are counted in the database operation metrics, so they are not reported
+		if !errorhelpers.IsDatabaseTimeoutError(err) {
+			sentry.CaptureException(err)
+		}
 	}
 
 	return result, err
@@ -80,10 +85,15 @@ func (r *MongoDatabaseReconciler) doReconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, err
 	}
 
+	if config.Spec.Mode.IsInMaintenance() {
+		logger.Info("The environment is in maintenance, skipping", "environment", config.Name)
+		return ctrl.Result{RequeueAfter: controller.EnvironmentMaintenanceRequeueInterval}, nil
+	}
+
 	isDbChanged := false
 
 	if !db.DeletionTimestamp.IsZero() {
-		if err := r.DatabaseReconciler.Delete(&db, config, logger); err != nil {
+		if err := r.DatabaseReconciler.Delete(ctx, &db, config, logger); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -107,7 +117,7 @@ func (r *MongoDatabaseReconciler) doReconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	changed, err := r.DatabaseReconciler.Reconcile(&db, config, logger)
+	changed, err := r.DatabaseReconciler.Reconcile(ctx, &db, config, logger)
 
 	isDbChanged = isDbChanged || changed
 
@@ -117,7 +127,7 @@ func (r *MongoDatabaseReconciler) doReconcile(ctx context.Context, req ctrl.Requ
 // SetupWithManager sets up the controller with the Manager.
 func (r *MongoDatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if r.DatabaseReconciler == nil {
-		r.DatabaseReconciler = database.DefaultMongoReconciler{}
+		r.DatabaseReconciler = database.DefaultMongoReconciler{Connections: r.Connections}
 	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&taskv1.MongoDatabase{}).
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -44,6 +45,7 @@ var _ = Describe("MongoDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMongoReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MongoDatabase,
 				config configv1.MongoConfig,
 				logger logr.Logger,
@@ -92,6 +94,7 @@ var _ = Describe("MongoDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMongoReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MongoDatabase,
 				config configv1.MongoConfig,
 				logger logr.Logger,
@@ -139,6 +142,7 @@ var _ = Describe("MongoDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMongoReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MongoDatabase,
 				config configv1.MongoConfig,
 				logger logr.Logger,
@@ -147,6 +151,7 @@ var _ = Describe("MongoDatabaseController", func() {
 				return true, nil
 			})
 			mockMongoReconciler.SetDeleteFunc(func(
+				ctx context.Context,
 				database *taskv1.MongoDatabase,
 				config configv1.MongoConfig,
 				logger logr.Logger,
@@ -204,6 +209,7 @@ var _ = Describe("MongoDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMongoReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MongoDatabase,
 				config configv1.MongoConfig,
 				logger logr.Logger,
@@ -24,6 +24,7 @@ import (
 	controller "github.com/szeber/kube-stager/controllers"
 	"github.com/szeber/kube-stager/handlers/database"
 	"github.com/szeber/kube-stager/helpers"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -36,9 +37,11 @@ type MysqlDatabaseReconciler struct {
 	client.Client
 	Scheme             *runtime.Scheme
 	DatabaseReconciler database.MysqlReconciler
+	Connections        *database.ConnectionManager
 }
 
 //+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=mysqlconfigs,verbs=get
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
 //+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mysqldatabases,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mysqldatabases/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=mysqldatabases/finalizers,verbs=update
@@ -53,7 +56,10 @@ func (r *MysqlDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	if err != nil {
 		appmetrics.Errors.WithLabelValues("mysql", "false").Inc()
-		sentry.CaptureException(err)
+		// Timeouts are retried with backoff and are counted in the database operation metrics, so they are not reported
+		if !errorhelpers.IsDatabaseTimeoutError(err) {
+			sentry.CaptureException(err)
+		}
 	}
 
 	return result, err
@@ -81,10 +87,15 @@ func (r *MysqlDatabaseReconciler) doReconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, err
 	}
 
+	if config.Spec.Mode.IsInMaintenance() {
+		logger.Info("The environment is in maintenance, skipping", "environment", config.Name)
+		return ctrl.Result{RequeueAfter: controller.EnvironmentMaintenanceRequeueInterval}, nil
+	}
+
 	isDbChanged := false
 
 	if !db.DeletionTimestamp.IsZero() {
-		if err := r.DatabaseReconciler.Delete(&db, config, logger); err != nil {
+		if err := r.DatabaseReconciler.Delete(ctx, &db, config, logger); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -108,7 +119,7 @@ func (r *MysqlDatabaseReconciler) doReconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	changed, err := r.DatabaseReconciler.Reconcile(&db, config, logger)
+	changed, err := r.DatabaseReconciler.Reconcile(ctx, &db, config, logger)
 
 	isDbChanged = isDbChanged || changed
 
@@ -118,7 +129,11 @@ func (r *MysqlDatabaseReconciler) doReconcile(ctx context.Context, req ctrl.Requ
 // SetupWithManager sets up the controller with the Manager.
 func (r *MysqlDatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if r.DatabaseReconciler == nil {
-		r.DatabaseReconciler = database.DefaultMysqlReconciler{}
+		// Secrets are read directly from the API server, so the manager doesn't cache every secret in the cluster
+		r.DatabaseReconciler = database.DefaultMysqlReconciler{
+			Reader:      mgr.GetAPIReader(),
+			Connections: r.Connections,
+		}
 	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&taskv1.MysqlDatabase{}).
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -44,6 +45,7 @@ var _ = Describe("MysqlDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMysqlReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MysqlDatabase,
 				config configv1.MysqlConfig,
 				logger logr.Logger,
@@ -92,6 +94,7 @@ var _ = Describe("MysqlDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMysqlReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MysqlDatabase,
 				config configv1.MysqlConfig,
 				logger logr.Logger,
@@ -119,6 +122,59 @@ var _ = Describe("MysqlDatabaseController", func() {
 		})
 	})
 
+	Describe("when the MysqlConfig is in maintenance", func() {
+		var (
+			ns        string
+			envName   string
+			dbName    string
+			configObj *configv1.MysqlConfig
+			dbObj     *taskv1.MysqlDatabase
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("mysql-maint-%d", GinkgoParallelProcess())
+			envName = "mysql-env-maint"
+			dbName = "mysql-db-maint"
+
+			nsObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: ns},
+			}
+			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+			mockMysqlReconciler.SetReconcileFunc(func(
+				ctx context.Context,
+				database *taskv1.MysqlDatabase,
+				config configv1.MysqlConfig,
+				logger logr.Logger,
+			) (bool, error) {
+				database.Status.State = taskv1.Complete
+				return true, nil
+			})
+			mockMysqlReconciler.SetDeleteFunc(nil)
+
+			configObj = testutil.NewTestMysqlConfig(envName, ns)
+			configObj.Spec.Mode = configv1.EnvironmentModeMaintenance
+			Expect(k8sClient.Create(ctx, configObj)).To(Succeed())
+
+			dbObj = testutil.NewTestMysqlDatabase(dbName, ns, "site1", "svc1", envName)
+			Expect(k8sClient.Create(ctx, dbObj)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			mockMysqlReconciler.SetReconcileFunc(nil)
+			mockMysqlReconciler.SetDeleteFunc(nil)
+		})
+
+		It("should not reconcile the database", func() {
+			fetched := &taskv1.MysqlDatabase{}
+			Consistently(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dbObj), fetched)).To(Succeed())
+				g.Expect(fetched.Finalizers).NotTo(ContainElement(helpers.MysqlFinalizerName))
+				g.Expect(fetched.Status.State).NotTo(Equal(taskv1.Complete))
+			}, 2*time.Second, interval).Should(Succeed())
+		})
+	})
+
 	Describe("when a reconciled MysqlDatabase is deleted", func() {
 		var (
 			ns        string
@@ -139,6 +195,7 @@ var _ = Describe("MysqlDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMysqlReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MysqlDatabase,
 				config configv1.MysqlConfig,
 				logger logr.Logger,
@@ -147,6 +204,7 @@ var _ = Describe("MysqlDatabaseController", func() {
 				return true, nil
 			})
 			mockMysqlReconciler.SetDeleteFunc(func(
+				ctx context.Context,
 				database *taskv1.MysqlDatabase,
 				config configv1.MysqlConfig,
 				logger logr.Logger,
@@ -204,6 +262,7 @@ var _ = Describe("MysqlDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockMysqlReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.MysqlDatabase,
 				config configv1.MysqlConfig,
 				logger logr.Logger,
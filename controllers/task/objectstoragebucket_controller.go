@@ -0,0 +1,135 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package task
+
+import (
+	"context"
+	"github.com/getsentry/sentry-go"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	controller "github.com/szeber/kube-stager/controllers"
+	"github.com/szeber/kube-stager/handlers/database"
+	"github.com/szeber/kube-stager/helpers"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ObjectStorageBucketReconciler reconciles a ObjectStorageBucket object
+type ObjectStorageBucketReconciler struct {
+	client.Client
+	Scheme             *runtime.Scheme
+	DatabaseReconciler database.ObjectStorageReconciler
+}
+
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=objectstorageconfigs,verbs=get
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=objectstoragebuckets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=objectstoragebuckets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=objectstoragebuckets/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.23.3/pkg/reconcile
+func (r *ObjectStorageBucketReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.doReconcile(ctx, req)
+
+	if err != nil {
+		appmetrics.Errors.WithLabelValues("objectstorage", "false").Inc()
+		// Timeouts are retried with backoff and are counted in the database operation metrics, so they are not reported
+		if !errorhelpers.IsDatabaseTimeoutError(err) {
+			sentry.CaptureException(err)
+		}
+	}
+
+	return result, err
+}
+
+func (r *ObjectStorageBucketReconciler) doReconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var bucket taskv1.ObjectStorageBucket
+
+	if err := r.Get(ctx, req.NamespacedName, &bucket); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to fetch bucket")
+		}
+
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	logger.Info("Fetched bucket, fetching config")
+
+	var config configv1.ObjectStorageConfig
+
+	configKey := client.ObjectKey{Namespace: bucket.Namespace, Name: bucket.Spec.EnvironmentConfig.Environment}
+	if err := r.Get(ctx, configKey, &config); err != nil {
+		if client.IgnoreNotFound(err) == nil && !bucket.DeletionTimestamp.IsZero() {
+			// Without the config the bucket can't be emptied, and the deletion of the site must not be blocked
+			logger.Info("The object storage config doesn't exist, removing the finalizer", "environment", configKey.Name)
+			return ctrl.Result{}, r.removeFinalizer(ctx, &bucket)
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !bucket.DeletionTimestamp.IsZero() {
+		if err := r.DatabaseReconciler.Delete(ctx, &bucket, config, logger); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, r.removeFinalizer(ctx, &bucket)
+	}
+
+	if !helpers.SliceContainsString(bucket.Finalizers, helpers.ObjectStorageFinalizerName) {
+		bucket.Finalizers = append(bucket.Finalizers, helpers.ObjectStorageFinalizerName)
+		if err := r.Update(ctx, &bucket); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	changed, err := r.DatabaseReconciler.Reconcile(ctx, &bucket, config, logger)
+
+	return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, &bucket, ctrl.Result{}, err)
+}
+
+func (r *ObjectStorageBucketReconciler) removeFinalizer(ctx context.Context, bucket *taskv1.ObjectStorageBucket) error {
+	previousFinalizersLength := len(bucket.Finalizers)
+	bucket.Finalizers = helpers.RemoveStringFromSlice(bucket.Finalizers, helpers.ObjectStorageFinalizerName)
+
+	if len(bucket.Finalizers) == previousFinalizersLength {
+		return nil
+	}
+
+	return r.Update(ctx, bucket)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ObjectStorageBucketReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.DatabaseReconciler == nil {
+		// Secrets are read directly from the API server, so the manager doesn't cache every secret in the cluster
+		r.DatabaseReconciler = database.DefaultObjectStorageReconciler{Reader: mgr.GetAPIReader()}
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&taskv1.ObjectStorageBucket{}).
+		Complete(r)
+}
@@ -0,0 +1,144 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ObjectStorageBucketController", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 200 * time.Millisecond
+	)
+
+	Describe("when an ObjectStorageBucket and ObjectStorageConfig exist", func() {
+		var (
+			ns          string
+			envName     string
+			bucketName  string
+			deleteCalls atomic.Int32
+			bucketObj   *taskv1.ObjectStorageBucket
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("storage-ok-%d", GinkgoParallelProcess())
+			envName = "storage-env"
+			bucketName = "storage-bucket"
+			deleteCalls.Store(0)
+
+			nsObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: ns},
+			}
+			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+			mockObjectStorageReconciler.SetReconcileFunc(func(
+				ctx context.Context,
+				bucket *taskv1.ObjectStorageBucket,
+				config configv1.ObjectStorageConfig,
+				logger logr.Logger,
+			) (bool, error) {
+				bucket.Status.State = taskv1.Complete
+				return true, nil
+			})
+			mockObjectStorageReconciler.SetDeleteFunc(func(
+				ctx context.Context,
+				bucket *taskv1.ObjectStorageBucket,
+				config configv1.ObjectStorageConfig,
+				logger logr.Logger,
+			) error {
+				deleteCalls.Add(1)
+				return nil
+			})
+
+			Expect(k8sClient.Create(ctx, testutil.NewTestObjectStorageConfig(envName, ns))).To(Succeed())
+
+			bucketObj = testutil.NewTestObjectStorageBucket(bucketName, ns, "site1", "svc1", envName)
+			Expect(k8sClient.Create(ctx, bucketObj)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			mockObjectStorageReconciler.SetReconcileFunc(nil)
+			mockObjectStorageReconciler.SetDeleteFunc(nil)
+		})
+
+		It("should reconcile to Complete with a finalizer", func() {
+			fetched := &taskv1.ObjectStorageBucket{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bucketObj), fetched)).To(Succeed())
+				g.Expect(fetched.Status.State).To(Equal(taskv1.Complete))
+				g.Expect(fetched.Finalizers).To(ContainElement(helpers.ObjectStorageFinalizerName))
+			}, timeout, interval).Should(Succeed())
+		})
+
+		It("should empty the bucket before it's deleted", func() {
+			fetched := &taskv1.ObjectStorageBucket{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bucketObj), fetched)).To(Succeed())
+				g.Expect(fetched.Finalizers).To(ContainElement(helpers.ObjectStorageFinalizerName))
+			}, timeout, interval).Should(Succeed())
+
+			Expect(k8sClient.Delete(ctx, fetched)).To(Succeed())
+
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(bucketObj), fetched))
+			}, timeout, interval).Should(BeTrue())
+			Expect(deleteCalls.Load()).To(BeNumerically(">=", 1))
+		})
+	})
+
+	Describe("when the ObjectStorageConfig does not exist", func() {
+		var (
+			ns        string
+			bucketObj *taskv1.ObjectStorageBucket
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("storage-noconfig-%d", GinkgoParallelProcess())
+
+			nsObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: ns},
+			}
+			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
+
+			mockObjectStorageReconciler.SetReconcileFunc(func(
+				ctx context.Context,
+				bucket *taskv1.ObjectStorageBucket,
+				config configv1.ObjectStorageConfig,
+				logger logr.Logger,
+			) (bool, error) {
+				bucket.Status.State = taskv1.Complete
+				return true, nil
+			})
+
+			bucketObj = testutil.NewTestObjectStorageBucket("storage-bucket-noconfig", ns, "site1", "svc1", "nonexistent-env")
+			Expect(k8sClient.Create(ctx, bucketObj)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			mockObjectStorageReconciler.SetReconcileFunc(nil)
+		})
+
+		It("should not reach Complete status", func() {
+			fetched := &taskv1.ObjectStorageBucket{}
+			Consistently(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bucketObj), fetched)).To(Succeed())
+				g.Expect(fetched.Status.State).NotTo(Equal(taskv1.Complete))
+			}, 2*time.Second, interval).Should(Succeed())
+		})
+	})
+})
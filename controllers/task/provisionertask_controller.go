@@ -0,0 +1,501 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"github.com/getsentry/sentry-go"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	controller "github.com/szeber/kube-stager/controllers"
+	"github.com/szeber/kube-stager/handlers/template"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/helpers/pod"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+// ProvisionerTaskReconciler reconciles a ProvisionerTask object
+type ProvisionerTaskReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config controllerconfigv1.ProjectConfig
+}
+
+const (
+	ProvisionerMaxJobFailedLoadLimit = 5
+
+	provisionJobType   = "provision"
+	deprovisionJobType = "deprovision"
+)
+
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=provisionerconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=config.operator.kube-stager.io,resources=serviceconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=site.operator.kube-stager.io,resources=stagingsites,verbs=get;list;watch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=provisionertasks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=provisionertasks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=task.operator.kube-stager.io,resources=provisionertasks/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.23.3/pkg/reconcile
+func (r *ProvisionerTaskReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.doReconcile(ctx, req)
+
+	if err != nil {
+		appmetrics.Errors.WithLabelValues("provisioner", "false").Inc()
+		sentry.CaptureException(err)
+	}
+
+	return result, err
+}
+
+func (r *ProvisionerTaskReconciler) doReconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var task taskv1.ProvisionerTask
+
+	if err := r.Get(ctx, req.NamespacedName, &task); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to fetch provisioner task")
+		}
+
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !task.DeletionTimestamp.IsZero() {
+		return r.handleDelete(ctx, &task)
+	}
+
+	if !helpers.SliceContainsString(task.Finalizers, helpers.ProvisionerFinalizerName) {
+		task.Finalizers = append(task.Finalizers, helpers.ProvisionerFinalizerName)
+		if err := r.Update(ctx, &task); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if task.Status.State == "" || task.Status.DeadlineTimestamp == nil {
+		logger.V(0).Info("Initialising task state")
+		task.Status.State = taskv1.Pending
+		task.Status.DeadlineTimestamp = r.makeDeadline(&task)
+		return controller.SaveStatusUpdatesIfObjectChanged(
+			true,
+			r.Status(),
+			ctx,
+			&task,
+			ctrl.Result{Requeue: true},
+			nil,
+		)
+	}
+
+	switch task.Status.State {
+	case taskv1.Pending:
+		changed, err := r.createProvisionJobIfNeeded(ctx, &task)
+		return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, &task, ctrl.Result{}, err)
+	case taskv1.Running:
+		changed, err := r.processRunningProvisionJob(ctx, &task)
+		return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, &task, ctrl.Result{}, err)
+	case taskv1.Failed, taskv1.Complete:
+		logger.V(1).Info("Task is in final state, ignoring", "state", task.Status.State)
+		return ctrl.Result{}, nil
+	default:
+		return ctrl.Result{}, fmt.Errorf("unknown state: %s", task.Status.State)
+	}
+}
+
+func (r *ProvisionerTaskReconciler) handleDelete(ctx context.Context, task *taskv1.ProvisionerTask) (
+	ctrl.Result,
+	error,
+) {
+	logger := log.FromContext(ctx)
+
+	if !helpers.SliceContainsString(task.Finalizers, helpers.ProvisionerFinalizerName) {
+		return ctrl.Result{}, nil
+	}
+
+	if task.Status.State == "" || task.Status.State == taskv1.Pending {
+		logger.V(0).Info("The task was never provisioned, skipping deprovisioning")
+		return ctrl.Result{}, r.removeFinalizer(ctx, task)
+	}
+
+	provisionerConfig, err := r.getProvisionerConfig(ctx, task.Namespace, task.Spec.Provisioner)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if provisionerConfig == nil || provisionerConfig.Spec.DeprovisionPodSpec == nil {
+		logger.V(0).Info("No deprovision pod spec is specified, skipping deprovisioning")
+		return ctrl.Result{}, r.removeFinalizer(ctx, task)
+	}
+
+	switch task.Status.DeprovisionState {
+	case "":
+		logger.V(0).Info("Initialising deprovisioning state")
+		task.Status.DeprovisionState = taskv1.Pending
+		task.Status.JobNotFoundCount = 0
+		task.Status.DeadlineTimestamp = r.makeDeadline(task)
+		return controller.SaveStatusUpdatesIfObjectChanged(
+			true,
+			r.Status(),
+			ctx,
+			task,
+			ctrl.Result{Requeue: true},
+			nil,
+		)
+	case taskv1.Pending:
+		changed, err := r.createDeprovisionJobIfNeeded(ctx, task, provisionerConfig)
+		return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, task, ctrl.Result{}, err)
+	case taskv1.Running:
+		changed, _, err := r.processRunningJob(ctx, task, deprovisionJobType, &task.Status.DeprovisionState)
+		return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, task, ctrl.Result{}, err)
+	case taskv1.Failed:
+		// A failed deprovisioning must not block the deletion of the site, the leftovers are reported in the logs
+		logger.Error(
+			fmt.Errorf("deprovisioning %s failed", task.Spec.Provisioner),
+			"The backing service may need to be removed manually",
+			"site",
+			task.Spec.SiteName,
+			"service",
+			task.Spec.ServiceName,
+		)
+		return ctrl.Result{}, r.removeFinalizer(ctx, task)
+	case taskv1.Complete:
+		return ctrl.Result{}, r.removeFinalizer(ctx, task)
+	default:
+		return ctrl.Result{}, fmt.Errorf("unknown deprovision state: %s", task.Status.DeprovisionState)
+	}
+}
+
+func (r *ProvisionerTaskReconciler) removeFinalizer(ctx context.Context, task *taskv1.ProvisionerTask) error {
+	task.Finalizers = helpers.RemoveStringFromSlice(task.Finalizers, helpers.ProvisionerFinalizerName)
+	return r.Update(ctx, task)
+}
+
+func (r *ProvisionerTaskReconciler) createProvisionJobIfNeeded(
+	ctx context.Context,
+	task *taskv1.ProvisionerTask,
+) (bool, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Task is in Pending state")
+	jobList, err := r.getOwnedJobs(ctx, task, provisionJobType)
+	if err != nil {
+		return false, err
+	}
+
+	if len(jobList.Items) > 0 {
+		logger.V(1).Info("There is already a job created, changing state to Running")
+		task.Status.State = taskv1.Running
+		return true, nil
+	}
+
+	provisionerConfig, err := r.getProvisionerConfig(ctx, task.Namespace, task.Spec.Provisioner)
+	if err != nil {
+		return false, err
+	}
+
+	var site sitev1.StagingSite
+	if err := r.Get(ctx, client.ObjectKey{Namespace: task.Namespace, Name: task.Spec.SiteName}, &site); err != nil {
+		return false, err
+	}
+
+	var serviceConfig configv1.ServiceConfig
+	if err := r.Get(
+		ctx,
+		client.ObjectKey{Namespace: task.Namespace, Name: task.Spec.ServiceName},
+		&serviceConfig,
+	); err != nil {
+		return false, err
+	}
+
+	templateHandler := template.NewSite(site, serviceConfig)
+	if err := template.LoadConfigs(&templateHandler, ctx, r.Client); err != nil {
+		return false, err
+	}
+
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(
+		provisionerConfig.Spec.ProvisionPodSpec,
+		&templateHandler,
+		helpers.StringMapTemplateValueGetter{StringMap: map[string]string{"service.name": task.Spec.ServiceName}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	logger.V(0).Info("Creating provision job")
+	if err := r.createJob(ctx, task, provisionJobType, pod.SetExtraEnvVarsOnPodSpec(podSpec, &site, &serviceConfig)); err != nil {
+		return false, err
+	}
+
+	logger.V(0).Info("Job created, setting state to running")
+	task.Status.State = taskv1.Running
+
+	return true, nil
+}
+
+func (r *ProvisionerTaskReconciler) createDeprovisionJobIfNeeded(
+	ctx context.Context,
+	task *taskv1.ProvisionerTask,
+	provisionerConfig *configv1.ProvisionerConfig,
+) (bool, error) {
+	logger := log.FromContext(ctx)
+	jobList, err := r.getOwnedJobs(ctx, task, deprovisionJobType)
+	if err != nil {
+		return false, err
+	}
+
+	if len(jobList.Items) > 0 {
+		logger.V(1).Info("There is already a deprovision job created, changing state to Running")
+		task.Status.DeprovisionState = taskv1.Running
+		return true, nil
+	}
+
+	// The site may already be deleted, so only the values stored in the task are available
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(
+		*provisionerConfig.Spec.DeprovisionPodSpec,
+		helpers.StringMapTemplateValueGetter{
+			StringMap: map[string]string{
+				"site.name":    task.Spec.SiteName,
+				"service.name": task.Spec.ServiceName,
+			},
+		},
+		task,
+	)
+	if err != nil {
+		// Retrying wouldn't resolve the templates, and the deletion of the task must not be blocked
+		logger.V(0).Info("Failed to template the deprovision pod spec. Failing deprovisioning", "error", err.Error())
+		task.Status.DeprovisionState = taskv1.Failed
+		return true, nil
+	}
+
+	logger.V(0).Info("Creating deprovision job")
+	if err := r.createJob(ctx, task, deprovisionJobType, podSpec); err != nil {
+		return false, err
+	}
+
+	logger.V(0).Info("Job created, setting deprovision state to running")
+	task.Status.DeprovisionState = taskv1.Running
+
+	return true, nil
+}
+
+func (r *ProvisionerTaskReconciler) processRunningProvisionJob(
+	ctx context.Context,
+	task *taskv1.ProvisionerTask,
+) (bool, error) {
+	changed, batchJob, err := r.processRunningJob(ctx, task, provisionJobType, &task.Status.State)
+	if err != nil || batchJob == nil {
+		return changed, err
+	}
+
+	outputs, err := r.getJobOutputs(ctx, batchJob)
+	if err != nil {
+		log.FromContext(ctx).V(0).Info("Failed to read the outputs of the provision job. Failing task", "error", err.Error())
+		task.Status.State = taskv1.Failed
+		return true, nil
+	}
+	task.Status.Outputs = outputs
+
+	return true, nil
+}
+
+// processRunningJob updates the state with the result of the batch job of the job type. The job is returned if it
+// finished successfully.
+func (r *ProvisionerTaskReconciler) processRunningJob(
+	ctx context.Context,
+	task *taskv1.ProvisionerTask,
+	jobType string,
+	state *taskv1.TaskState,
+) (bool, *batchv1.Job, error) {
+	logger := log.FromContext(ctx)
+	logger.V(0).Info("Loading job", "type", jobType)
+
+	jobList, err := r.getOwnedJobs(ctx, task, jobType)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if len(jobList.Items) != 1 {
+		logger.V(0).Info("Incorrect number of batch jobs found. Expected to find 1 job", "count", len(jobList.Items))
+		task.Status.JobNotFoundCount = task.Status.JobNotFoundCount + 1
+		if task.Status.JobNotFoundCount > ProvisionerMaxJobFailedLoadLimit {
+			logger.V(0).Info("The maximum not found time exceeded the limit, failing the job")
+			*state = taskv1.Failed
+		}
+		return true, nil, nil
+	}
+
+	changed := false
+	if task.Status.JobNotFoundCount > 0 {
+		task.Status.JobNotFoundCount = 0
+		changed = true
+	}
+
+	batchJob := jobList.Items[0]
+
+	for _, v := range batchJob.Status.Conditions {
+		if v.Type == batchv1.JobComplete && v.Status == corev1.ConditionTrue {
+			logger.V(1).Info("Job finished successfully")
+			*state = taskv1.Complete
+			appmetrics.JobCompletions.WithLabelValues(jobType, "success").Inc()
+			if task.Status.DeadlineTimestamp != nil {
+				duration := time.Since(task.Status.DeadlineTimestamp.Add(-time.Duration(task.Spec.DeadlineSeconds) * time.Second))
+				appmetrics.JobDuration.WithLabelValues(jobType).Observe(duration.Seconds())
+			}
+			return true, &batchJob, nil
+		}
+
+		if v.Type == batchv1.JobFailed && v.Status == corev1.ConditionTrue {
+			logger.V(0).Info("Job failed", "status", v.Message, "reason", v.Reason)
+			*state = taskv1.Failed
+			appmetrics.JobCompletions.WithLabelValues(jobType, "failure").Inc()
+			return true, nil, nil
+		}
+	}
+
+	if time.Now().After(task.Status.DeadlineTimestamp.Time) {
+		logger.V(0).Info("The job deadline has expired. Failing job.")
+		*state = taskv1.Failed
+		appmetrics.JobCompletions.WithLabelValues(jobType, "failure").Inc()
+		return true, nil, nil
+	}
+
+	logger.V(0).Info("Job is still running")
+	return changed, nil, nil
+}
+
+// getJobOutputs returns the outputs written to the termination messages of the successful pod of the job
+func (r *ProvisionerTaskReconciler) getJobOutputs(ctx context.Context, batchJob *batchv1.Job) (map[string]string, error) {
+	var podList corev1.PodList
+	if err := r.List(
+		ctx,
+		&podList,
+		client.InNamespace(batchJob.Namespace),
+		client.MatchingLabels(batchJob.Spec.Template.Labels),
+	); err != nil {
+		return nil, err
+	}
+
+	for _, jobPod := range podList.Items {
+		if jobPod.Status.Phase == corev1.PodSucceeded {
+			return pod.GetOutputsFromPod(jobPod)
+		}
+	}
+
+	return map[string]string{}, nil
+}
+
+func (r *ProvisionerTaskReconciler) createJob(
+	ctx context.Context,
+	task *taskv1.ProvisionerTask,
+	jobType string,
+	podSpec corev1.PodSpec,
+) error {
+	backoffLimit := r.Config.ProvisionJobConfig.BackoffLimit
+	deadlineSeconds := int64(r.Config.ProvisionJobConfig.DeadlineSeconds)
+	ttlSeconds := r.Config.ProvisionJobConfig.TtlSeconds
+	jobLabels := map[string]string{
+		labels.Type:        jobType,
+		labels.JobName:     task.Name,
+		labels.Site:        task.Spec.SiteName,
+		labels.Service:     task.Spec.ServiceName,
+		labels.Provisioner: task.Spec.Provisioner,
+	}
+
+	if podSpec.RestartPolicy != corev1.RestartPolicyOnFailure && podSpec.RestartPolicy != corev1.RestartPolicyNever {
+		podSpec.RestartPolicy = corev1.RestartPolicyOnFailure
+	}
+
+	batchJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helpers.ShortenHumanReadableValue(fmt.Sprintf("%s-%s", jobType, task.Name), 50),
+			Namespace: task.Namespace,
+			Labels:    jobLabels,
+		},
+		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds: &deadlineSeconds,
+			BackoffLimit:          &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: jobLabels,
+				},
+				Spec: podSpec,
+			},
+			TTLSecondsAfterFinished: &ttlSeconds,
+		},
+	}
+
+	if err := ctrl.SetControllerReference(task, batchJob, r.Scheme); err != nil {
+		return err
+	}
+
+	return r.Create(ctx, batchJob)
+}
+
+func (r *ProvisionerTaskReconciler) getProvisionerConfig(
+	ctx context.Context,
+	namespace string,
+	name string,
+) (*configv1.ProvisionerConfig, error) {
+	var config configv1.ProvisionerConfig
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (r *ProvisionerTaskReconciler) getOwnedJobs(
+	ctx context.Context,
+	task *taskv1.ProvisionerTask,
+	jobType string,
+) (*batchv1.JobList, error) {
+	var jobList batchv1.JobList
+	labelMatcher := client.MatchingLabels{labels.JobName: task.Name, labels.Type: jobType}
+	if err := r.List(ctx, &jobList, client.InNamespace(task.Namespace), labelMatcher); err != nil {
+		return nil, err
+	}
+
+	return &jobList, nil
+}
+
+func (r *ProvisionerTaskReconciler) makeDeadline(task *taskv1.ProvisionerTask) *metav1.Time {
+	return &metav1.Time{Time: time.Now().Add(time.Duration(task.Spec.DeadlineSeconds) * time.Second)}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ProvisionerTaskReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&taskv1.ProvisionerTask{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
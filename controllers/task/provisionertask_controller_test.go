@@ -0,0 +1,77 @@
+package task
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/internal/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ProvisionerTaskController", func() {
+	const (
+		timeout  = 10 * time.Second
+		interval = 200 * time.Millisecond
+	)
+
+	Describe("when a new ProvisionerTask is created", func() {
+		var (
+			ns          string
+			siteName    string
+			serviceName string
+			provisioner string
+		)
+
+		BeforeEach(func() {
+			ns = fmt.Sprintf("provisioner-new-%d", GinkgoParallelProcess())
+			siteName = "prov-site"
+			serviceName = "prov-svc"
+			provisioner = "bucket"
+
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})).To(Succeed())
+			Expect(k8sClient.Create(ctx, testutil.NewTestProvisionerConfig(provisioner, ns))).To(Succeed())
+
+			serviceConfig := testutil.NewTestServiceConfig(serviceName, ns, "psv")
+			serviceConfig.Spec.Provisioners = []string{provisioner}
+			Expect(k8sClient.Create(ctx, serviceConfig)).To(Succeed())
+
+			Expect(k8sClient.Create(ctx, testutil.NewTestStagingSite(siteName, ns, map[string]sitev1.StagingSiteService{
+				serviceName: {ImageTag: "latest", Replicas: 1},
+			}))).To(Succeed())
+		})
+
+		It("should add the finalizer and start the provision job", func() {
+			task := testutil.NewTestProvisionerTask("prov-task", ns, siteName, serviceName, provisioner)
+			Expect(k8sClient.Create(ctx, task)).To(Succeed())
+
+			fetched := &taskv1.ProvisionerTask{}
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(task), fetched)).To(Succeed())
+				g.Expect(fetched.Status.State).To(Equal(taskv1.Running))
+			}, timeout, interval).Should(Succeed())
+
+			Expect(fetched.Finalizers).To(ContainElement(helpers.ProvisionerFinalizerName))
+			Expect(fetched.Status.DeadlineTimestamp).NotTo(BeNil())
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(
+				ctx,
+				&jobs,
+				client.InNamespace(ns),
+				client.MatchingLabels{labels.JobName: task.Name, labels.Type: provisionJobType},
+			)).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(1))
+			Expect(jobs.Items[0].Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"create", siteName}))
+		})
+	})
+})
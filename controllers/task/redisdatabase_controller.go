@@ -22,6 +22,7 @@ import (
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	controller "github.com/szeber/kube-stager/controllers"
 	"github.com/szeber/kube-stager/handlers/database"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -52,7 +53,10 @@ func (r *RedisDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	if err != nil {
 		appmetrics.Errors.WithLabelValues("redis", "false").Inc()
-		sentry.CaptureException(err)
+		// Timeouts are retried with backoff and are counted in the database operation metrics, so they are not reported
+		if !errorhelpers.IsDatabaseTimeoutError(err) {
+			sentry.CaptureException(err)
+		}
 	}
 
 	return result, err
@@ -80,7 +84,12 @@ func (r *RedisDatabaseReconciler) doReconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, err
 	}
 
-	changed, err := r.DatabaseReconciler.Reconcile(&db, config, logger)
+	if config.Spec.Mode.IsInMaintenance() {
+		logger.Info("The environment is in maintenance, skipping", "environment", config.Name)
+		return ctrl.Result{RequeueAfter: controller.EnvironmentMaintenanceRequeueInterval}, nil
+	}
+
+	changed, err := r.DatabaseReconciler.Reconcile(ctx, &db, config, logger)
 
 	return controller.SaveStatusUpdatesIfObjectChanged(changed, r.Status(), ctx, &db, ctrl.Result{}, err)
 }
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -42,6 +43,7 @@ var _ = Describe("RedisDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockRedisReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.RedisDatabase,
 				config configv1.RedisConfig,
 				logger logr.Logger,
@@ -87,6 +89,7 @@ var _ = Describe("RedisDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockRedisReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.RedisDatabase,
 				config configv1.RedisConfig,
 				logger logr.Logger,
@@ -132,6 +135,7 @@ var _ = Describe("RedisDatabaseController", func() {
 			Expect(k8sClient.Create(ctx, nsObj)).To(Succeed())
 
 			mockRedisReconciler.SetReconcileFunc(func(
+				ctx context.Context,
 				database *taskv1.RedisDatabase,
 				config configv1.RedisConfig,
 				logger logr.Logger,
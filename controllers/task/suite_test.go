@@ -24,6 +24,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
 	"github.com/szeber/kube-stager/internal/testutil"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -45,6 +46,7 @@ var cancel context.CancelFunc
 var mockMysqlReconciler *testutil.MockMysqlReconciler
 var mockMongoReconciler *testutil.MockMongoReconciler
 var mockRedisReconciler *testutil.MockRedisReconciler
+var mockObjectStorageReconciler *testutil.MockObjectStorageReconciler
 
 func TestAPIs(t *testing.T) {
 	testutil.SafeTestMain(t)
@@ -83,6 +85,7 @@ var _ = BeforeSuite(
 		mockMysqlReconciler = &testutil.MockMysqlReconciler{}
 		mockMongoReconciler = &testutil.MockMongoReconciler{}
 		mockRedisReconciler = &testutil.MockRedisReconciler{}
+		mockObjectStorageReconciler = &testutil.MockObjectStorageReconciler{}
 
 		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 			Scheme: testScheme,
@@ -110,6 +113,20 @@ var _ = BeforeSuite(
 		}).SetupWithManager(mgr)
 		Expect(err).NotTo(HaveOccurred())
 
+		err = (&ObjectStorageBucketReconciler{
+			Client:             mgr.GetClient(),
+			Scheme:             mgr.GetScheme(),
+			DatabaseReconciler: mockObjectStorageReconciler,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&ProvisionerTaskReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Config: controllerconfigv1.ProjectConfig{},
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
 		go func() {
 			defer GinkgoRecover()
 			if err := mgr.Start(ctx); err != nil {
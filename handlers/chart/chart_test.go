@@ -0,0 +1,207 @@
+package chart
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testDeploymentTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}-web
+  namespace: {{ .Release.Namespace }}
+spec:
+  replicas: {{ .Values.replicas }}
+  template:
+    spec:
+      containers:
+        - name: web
+          image: {{ .Values.image }}
+`
+
+const testHookTemplate = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Release.Name }}-migrate
+  annotations:
+    helm.sh/hook: pre-install
+`
+
+const testMultiDocumentTemplate = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ .Release.Name }}-a
+---
+{{- if .Values.extra }}
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ .Release.Name }}-b
+{{- end }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-c
+`
+
+func newTestChartConfig(archive []byte) (*configv1.ServiceConfig, *corev1.ConfigMap) {
+	config := testutil.NewTestServiceConfig("my-service", "default", "svc")
+	config.Spec.HelmChart = &configv1.ServiceHelmChart{
+		ConfigMap: &configv1.ServiceHelmChartConfigMap{Name: "my-service-chart"},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-service-chart", Namespace: "default"},
+		BinaryData: map[string][]byte{DefaultConfigMapKey: archive},
+	}
+
+	return config, configMap
+}
+
+func TestLoader_Load_FromConfigMap(t *testing.T) {
+	archive := testutil.NewTestChartArchive("replicas: 1\n", map[string]string{"deployment.yaml": testDeploymentTemplate})
+	config, configMap := newTestChartConfig(archive)
+	fakeClient := testutil.NewFakeClient(configMap)
+
+	for _, loader := range []*Loader{NewLoader(), nil} {
+		chart, err := loader.Load(context.Background(), fakeClient, config)
+		if err != nil {
+			t.Fatalf("Load returned unexpected error: %v", err)
+		}
+		if chart.Name() != "app" || len(chart.Templates) != 1 {
+			t.Errorf("unexpected chart loaded: %s with %d templates", chart.Name(), len(chart.Templates))
+		}
+	}
+}
+
+func TestLoader_Load_MissingConfigMapKey(t *testing.T) {
+	config, configMap := newTestChartConfig(nil)
+	config.Spec.HelmChart.ConfigMap.Key = "other.tgz"
+	fakeClient := testutil.NewFakeClient(configMap)
+
+	_, err := NewLoader().Load(context.Background(), fakeClient, config)
+	if err == nil || !strings.Contains(err.Error(), "other.tgz") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+}
+
+func TestLoader_Load_CachedOCIChart(t *testing.T) {
+	archive := testutil.NewTestChartArchive("", map[string]string{"deployment.yaml": testDeploymentTemplate})
+	config := testutil.NewTestServiceConfig("my-service", "default", "svc")
+	config.Spec.HelmChart = &configv1.ServiceHelmChart{
+		OCIRepository: "oci://registry.invalid/charts/app",
+		Version:       "1.0.0",
+		PullSecret:    "registry",
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default", ResourceVersion: "1"},
+		Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+	}
+	fakeClient := testutil.NewFakeClient(secret)
+	if err := fakeClient.Get(context.Background(), client.ObjectKeyFromObject(secret), secret); err != nil {
+		t.Fatalf("failed to get the pull secret: %v", err)
+	}
+
+	loader := NewLoader()
+	loader.archives["default/my-service"] = cachedArchive{
+		reference:         "registry.invalid/charts/app:1.0.0",
+		pullSecretVersion: secret.ResourceVersion,
+		data:              archive,
+	}
+
+	chart, err := loader.Load(context.Background(), fakeClient, config)
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+	if chart.Name() != "app" {
+		t.Errorf("unexpected chart loaded: %s", chart.Name())
+	}
+
+	// The cached chart must not be used once the credentials change
+	secret.Data["password"] = []byte("new")
+	if err := fakeClient.Update(context.Background(), secret); err != nil {
+		t.Fatalf("failed to update the pull secret: %v", err)
+	}
+	if _, err := loader.Load(context.Background(), fakeClient, config); err == nil {
+		t.Error("expected the chart to be pulled again after the pull secret changed")
+	}
+}
+
+func TestLoader_Forget(t *testing.T) {
+	loader := NewLoader()
+	loader.archives["default/my-service"] = cachedArchive{reference: "registry.invalid/charts/app:1.0.0"}
+	loader.archives["default/other-service"] = cachedArchive{reference: "registry.invalid/charts/other:1.0.0"}
+
+	loader.Forget("default", "my-service")
+
+	if _, ok := loader.archives["default/my-service"]; ok {
+		t.Error("expected the chart of the deleted service config to be removed from the cache")
+	}
+	if _, ok := loader.archives["default/other-service"]; !ok {
+		t.Error("expected the chart of the other service config to stay cached")
+	}
+
+	// A nil loader has no cache
+	var nilLoader *Loader
+	nilLoader.Forget("default", "my-service")
+}
+
+func TestRender(t *testing.T) {
+	archive := testutil.NewTestChartArchive("replicas: 1\nimage: nginx:1\nextra: false\n", map[string]string{
+		"deployment.yaml": testDeploymentTemplate,
+		"hook.yaml":       testHookTemplate,
+		"multi.yaml":      testMultiDocumentTemplate,
+		"_helpers.tpl":    `{{- define "app.name" -}}app{{- end -}}`,
+		"NOTES.txt":       "Installed {{ .Release.Name }}",
+	})
+	config, configMap := newTestChartConfig(archive)
+	chart, err := NewLoader().Load(context.Background(), testutil.NewFakeClient(configMap), config)
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+
+	objects, err := Render(chart, "mysite-svc", "staging", map[string]interface{}{"replicas": 3})
+	if err != nil {
+		t.Fatalf("Render returned unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, object := range objects {
+		names = append(names, object.GetKind()+"/"+object.GetName())
+	}
+	expected := "Deployment/mysite-svc-web,ServiceAccount/mysite-svc-a,ConfigMap/mysite-svc-c"
+	if strings.Join(names, ",") != expected {
+		t.Fatalf("rendered objects = %v, want %s", names, expected)
+	}
+
+	deployment := objects[0]
+	if deployment.GetNamespace() != "staging" {
+		t.Errorf("expected the release namespace to be used, got %q", deployment.GetNamespace())
+	}
+	if replicas, _, _ := unstructured.NestedFieldNoCopy(deployment.Object, "spec", "replicas"); replicas != float64(3) {
+		t.Errorf("expected the values to override the chart defaults, got %v replicas", replicas)
+	}
+}
+
+func TestRender_InvalidManifest(t *testing.T) {
+	archive := testutil.NewTestChartArchive("", map[string]string{
+		"invalid.yaml": "apiVersion: v1\nkind: ConfigMap\n",
+	})
+	config, configMap := newTestChartConfig(archive)
+	chart, err := NewLoader().Load(context.Background(), testutil.NewFakeClient(configMap), config)
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+
+	if _, err := Render(chart, "mysite-svc", "staging", nil); err == nil {
+		t.Error("expected an error for a manifest without a name")
+	}
+}
@@ -0,0 +1,163 @@
+package chart
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultConfigMapKey is the key of the packaged chart in the configmaps if no key is set in the service config
+	DefaultConfigMapKey = "chart.tgz"
+
+	// pullTimeout limits the time of pulling a chart, so an unresponsive registry doesn't block the reconciles
+	pullTimeout = 2 * time.Minute
+)
+
+// Loader loads the helm charts of the service configs. The charts pulled from OCI registries are cached per service
+// config until the reference of the chart or the resource version of its pull secret changes, as the published
+// versions of a chart are not expected to change. The entries of the deleted service configs are removed with Forget.
+//
+// A nil loader pulls the charts for every render.
+type Loader struct {
+	mu       sync.Mutex
+	archives map[string]cachedArchive
+}
+
+type cachedArchive struct {
+	reference         string
+	pullSecretVersion string
+	data              []byte
+}
+
+// NewLoader returns a loader that caches the pulled charts
+func NewLoader() *Loader {
+	return &Loader{archives: make(map[string]cachedArchive)}
+}
+
+// Load returns the helm chart of the service config. Every call returns a new copy of the chart, so it may be modified
+// while rendering
+func (l *Loader) Load(ctx context.Context, reader client.Reader, config *configv1.ServiceConfig) (*helmchart.Chart, error) {
+	source := config.Spec.HelmChart
+	if source == nil {
+		return nil, fmt.Errorf("the service config %s has no helm chart", config.Name)
+	}
+
+	var data []byte
+	var err error
+	if source.ConfigMap != nil {
+		data, err = loadArchiveFromConfigMap(ctx, reader, config.Namespace, *source.ConfigMap)
+	} else {
+		data, err = l.pullArchive(ctx, reader, config, *source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the helm chart of the service config %s: %w", config.Name, err)
+	}
+
+	return result, nil
+}
+
+// Forget removes the cached chart of the service config
+func (l *Loader) Forget(namespace string, name string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	delete(l.archives, namespace+"/"+name)
+	l.mu.Unlock()
+}
+
+func loadArchiveFromConfigMap(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+	source configv1.ServiceHelmChartConfigMap,
+) ([]byte, error) {
+	key := source.Key
+	if key == "" {
+		key = DefaultConfigMapKey
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: source.Name}, configMap); err != nil {
+		return nil, err
+	}
+
+	data, ok := configMap.BinaryData[key]
+	if !ok {
+		return nil, fmt.Errorf("the configmap %s has no %s key in its binary data", source.Name, key)
+	}
+
+	return data, nil
+}
+
+func (l *Loader) pullArchive(
+	ctx context.Context,
+	reader client.Reader,
+	config *configv1.ServiceConfig,
+	source configv1.ServiceHelmChart,
+) ([]byte, error) {
+	reference := strings.TrimPrefix(source.OCIRepository, "oci://") + ":" + source.Version
+	cacheKey := config.Namespace + "/" + config.Name
+
+	options := []registry.ClientOption{registry.ClientOptHTTPClient(&http.Client{Timeout: pullTimeout})}
+	pullSecretVersion := ""
+	if source.PullSecret != "" {
+		secret := &corev1.Secret{}
+		if err := reader.Get(ctx, client.ObjectKey{Namespace: config.Namespace, Name: source.PullSecret}, secret); err != nil {
+			return nil, err
+		}
+		pullSecretVersion = secret.ResourceVersion
+		options = append(
+			options,
+			registry.ClientOptBasicAuth(string(secret.Data["username"]), string(secret.Data["password"])),
+		)
+	}
+
+	if l != nil {
+		l.mu.Lock()
+		cached, ok := l.archives[cacheKey]
+		l.mu.Unlock()
+		if ok && cached.reference == reference && cached.pullSecretVersion == pullSecretVersion {
+			return cached.data, nil
+		}
+	}
+
+	registryClient, err := registry.NewClient(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := registryClient.Pull(reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull the helm chart %s: %w", reference, err)
+	}
+
+	if l != nil {
+		l.mu.Lock()
+		l.archives[cacheKey] = cachedArchive{
+			reference:         reference,
+			pullSecretVersion: pullSecretVersion,
+			data:              result.Chart.Data,
+		}
+		l.mu.Unlock()
+	}
+
+	return result.Chart.Data, nil
+}
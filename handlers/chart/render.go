@@ -0,0 +1,87 @@
+package chart
+
+import (
+	"fmt"
+	helmchart "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"path"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+// Render renders the chart as a new release with the given name in the namespace, and returns the rendered objects in
+// the order of the templates. The hooks of the chart are skipped, as the lifecycle of the objects is managed by the
+// operator instead of helm
+func Render(
+	chart *helmchart.Chart,
+	releaseName string,
+	namespace string,
+	values map[string]interface{},
+) ([]*unstructured.Unstructured, error) {
+	if err := chartutil.ProcessDependenciesWithMerge(chart, values); err != nil {
+		return nil, err
+	}
+
+	renderValues, err := chartutil.ToRenderValues(
+		chart,
+		values,
+		chartutil.ReleaseOptions{Name: releaseName, Namespace: namespace, Revision: 1, IsInstall: true},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := engine.Render(chart, renderValues)
+	if err != nil {
+		return nil, err
+	}
+
+	fileNames := make([]string, 0, len(files))
+	for name := range files {
+		extension := path.Ext(name)
+		if (extension == ".yaml" || extension == ".yml") && !strings.HasPrefix(path.Base(name), "_") {
+			fileNames = append(fileNames, name)
+		}
+	}
+	sort.Strings(fileNames)
+
+	var result []*unstructured.Unstructured
+	for _, name := range fileNames {
+		manifests := releaseutil.SplitManifests(files[name])
+		manifestNames := make([]string, 0, len(manifests))
+		for manifestName := range manifests {
+			manifestNames = append(manifestNames, manifestName)
+		}
+		sort.Sort(releaseutil.BySplitManifestsOrder(manifestNames))
+
+		for _, manifestName := range manifestNames {
+			object := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(manifests[manifestName]), &object.Object); err != nil {
+				return nil, fmt.Errorf("invalid manifest in %s: %w", name, err)
+			}
+			if len(object.Object) == 0 {
+				// Empty documents, eg. from templates disabled by the values
+				continue
+			}
+			if _, ok := object.GetAnnotations()[release.HookAnnotation]; ok {
+				continue
+			}
+			if object.GetAPIVersion() == "" || object.GetKind() == "" || object.GetName() == "" {
+				return nil, fmt.Errorf(
+					"invalid manifest in %s: the apiVersion, kind and metadata.name fields are required",
+					name,
+				)
+			}
+
+			result = append(result, object)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,369 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxOpenConnections = 5
+	DefaultConnectionIdleTime = 5 * time.Minute
+
+	connectionEvictionInterval = 30 * time.Second
+	connectionCloseTimeout     = 10 * time.Second
+)
+
+// ConnectionManager keeps a pool of admin connections for every mysql and mongo environment config, so the operations
+// on the same server (including the password checks of the users) share a bounded number of connections instead of
+// opening new ones. The pools are keyed by the name of the config, and are replaced whenever the generation of the config
+// or the hash of its password and TLS CA certificate changes, so the status updates of the environment controllers
+// don't reopen them. The pools are closed once they were idle for the idle time.
+//
+// A nil manager opens a new connection for every operation.
+type ConnectionManager struct {
+	maxOpenConnections int
+	idleTime           time.Duration
+	now                func() time.Time
+
+	mu    sync.Mutex
+	pools map[connectionPoolKey]*connectionPool
+}
+
+type connectionPoolKey struct {
+	databaseType errorhelpers.DatabaseType
+	namespace    string
+	name         string
+}
+
+type connectionPool struct {
+	key       connectionPoolKey
+	version   string
+	mysql     *sql.DB
+	mongo     *mongo.Client
+	users     int
+	lastUsed  time.Time
+	isRetired bool
+}
+
+// NewConnectionManager returns a manager that opens at most maxOpenConnections connections to every environment, and
+// closes the pools that weren't used for idleTime. The defaults are used for values less than 1.
+func NewConnectionManager(maxOpenConnections int, idleTime time.Duration) *ConnectionManager {
+	if maxOpenConnections < 1 {
+		maxOpenConnections = DefaultMaxOpenConnections
+	}
+	if idleTime <= 0 {
+		idleTime = DefaultConnectionIdleTime
+	}
+
+	return &ConnectionManager{
+		maxOpenConnections: maxOpenConnections,
+		idleTime:           idleTime,
+		now:                time.Now,
+		pools:              make(map[connectionPoolKey]*connectionPool),
+	}
+}
+
+// Start periodically closes the idle pools until the context is done, then closes every pool. It implements the
+// manager.Runnable interface of controller-runtime.
+func (r *ConnectionManager) Start(ctx context.Context) error {
+	ticker := time.NewTicker(connectionEvictionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.Close()
+			return nil
+		case <-ticker.C:
+			r.EvictIdlePools()
+		}
+	}
+}
+
+// EvictIdlePools closes the pools that are not in use and weren't used for the idle time
+func (r *ConnectionManager) EvictIdlePools() {
+	r.mu.Lock()
+	var evicted []*connectionPool
+	now := r.now()
+	for key, pool := range r.pools {
+		if pool.users == 0 && now.Sub(pool.lastUsed) >= r.idleTime {
+			delete(r.pools, key)
+			evicted = append(evicted, pool)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, pool := range evicted {
+		pool.close()
+	}
+}
+
+// Close closes every pool. The pools in use are closed once they are released.
+func (r *ConnectionManager) Close() {
+	r.mu.Lock()
+	var closed []*connectionPool
+	for key, pool := range r.pools {
+		delete(r.pools, key)
+		if r.retire(pool) {
+			closed = append(closed, pool)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, pool := range closed {
+		pool.close()
+	}
+}
+
+// GetMysqlConnection returns the pooled admin connection for the config. The returned function must be called once the
+// connection is no longer used.
+func (r *ConnectionManager) GetMysqlConnection(config configv1.MysqlConfig, tlsCaCertificate []byte) (
+	*sql.DB,
+	func(),
+	error,
+) {
+	open := func() (*connectionPool, error) {
+		connection, err := openMysqlConnection(config, tlsCaCertificate, config.Spec.Username, config.Spec.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		return &connectionPool{mysql: connection}, nil
+	}
+
+	if r == nil {
+		pool, err := open()
+		if err != nil {
+			return nil, nil, err
+		}
+		return pool.mysql, pool.close, nil
+	}
+
+	key := connectionPoolKey{databaseType: errorhelpers.DatabaseTypeMysql, namespace: config.Namespace, name: config.Name}
+	version := makeConnectionPoolVersion(config.Generation, config.Spec.Password, tlsCaCertificate)
+	pool, release, err := r.acquire(key, version, open)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pool.mysql, release, nil
+}
+
+// GetMongoConnection returns the pooled admin connection for the config. New connections are pinged with the context
+// before they are returned. The returned function must be called once the connection is no longer used.
+func (r *ConnectionManager) GetMongoConnection(ctx context.Context, config configv1.MongoConfig) (
+	*mongo.Client,
+	func(),
+	error,
+) {
+	open := func() (*connectionPool, error) {
+		client, err := openMongoConnection(ctx, config, uint64(r.getMaxOpenConnections()), r.getIdleTime())
+		if err != nil {
+			return nil, err
+		}
+
+		return &connectionPool{mongo: client}, nil
+	}
+
+	if r == nil {
+		pool, err := open()
+		if err != nil {
+			return nil, nil, err
+		}
+		return pool.mongo, pool.close, nil
+	}
+
+	key := connectionPoolKey{databaseType: errorhelpers.DatabaseTypeMongo, namespace: config.Namespace, name: config.Name}
+	version := makeConnectionPoolVersion(config.Generation, config.Spec.Password, nil)
+	pool, release, err := r.acquire(key, version, open)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pool.mongo, release, nil
+}
+
+// acquire returns the pool for the key, opening a new one if there is none, or the existing one is for a different
+// version of the config. The pool is opened without holding the lock, so a slow server doesn't block the operations
+// on the other environments.
+func (r *ConnectionManager) acquire(
+	key connectionPoolKey,
+	version string,
+	open func() (*connectionPool, error),
+) (*connectionPool, func(), error) {
+	if pool := r.acquireExisting(key, version, nil); pool != nil {
+		return pool, r.makeReleaseFunc(pool), nil
+	}
+
+	newPool, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+	newPool.key = key
+	newPool.version = version
+
+	if newPool.mysql != nil {
+		newPool.mysql.SetMaxOpenConns(r.maxOpenConnections)
+		newPool.mysql.SetMaxIdleConns(r.maxOpenConnections)
+		newPool.mysql.SetConnMaxIdleTime(r.idleTime)
+	}
+
+	appmetrics.DatabaseConnectionPools.WithLabelValues(newPool.getMetricType()).Inc()
+
+	pool := r.acquireExisting(key, version, newPool)
+	if pool != newPool {
+		// Another operation opened the pool in the meantime
+		newPool.close()
+	}
+
+	return pool, r.makeReleaseFunc(pool), nil
+}
+
+// acquireExisting marks the current pool for the key as used and returns it. A pool for a different version of the
+// config is retired. If there is no current pool, the new pool is stored and returned if it's not nil.
+func (r *ConnectionManager) acquireExisting(
+	key connectionPoolKey,
+	version string,
+	newPool *connectionPool,
+) *connectionPool {
+	var retiredPool *connectionPool
+
+	r.mu.Lock()
+	pool := r.pools[key]
+	if pool != nil && pool.version != version {
+		delete(r.pools, key)
+		if r.retire(pool) {
+			retiredPool = pool
+		}
+		pool = nil
+	}
+	if pool == nil && newPool != nil {
+		pool = newPool
+		r.pools[key] = pool
+	}
+	if pool != nil {
+		pool.users++
+		pool.lastUsed = r.now()
+	}
+	r.mu.Unlock()
+
+	if retiredPool != nil {
+		retiredPool.close()
+	}
+
+	return pool
+}
+
+// retire marks the pool as not to be used anymore and returns TRUE if it can be closed right away. Must be called with
+// the lock held.
+func (r *ConnectionManager) retire(pool *connectionPool) bool {
+	pool.isRetired = true
+	return pool.users == 0
+}
+
+func (r *ConnectionManager) makeReleaseFunc(pool *connectionPool) func() {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			pool.users--
+			pool.lastUsed = r.now()
+			isClosable := pool.isRetired && pool.users == 0
+			r.mu.Unlock()
+
+			if isClosable {
+				pool.close()
+			}
+		})
+	}
+}
+
+// makeConnectionPoolVersion returns the version of the pool of a config. The password and the TLS CA certificate are
+// hashed, as the certificate is read from a secret that may change without the generation of the config changing
+func makeConnectionPoolVersion(generation int64, password string, tlsCaCertificate []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(password))
+	hash.Write([]byte{0})
+	hash.Write(tlsCaCertificate)
+
+	return fmt.Sprintf("%d-%x", generation, hash.Sum(nil))
+}
+
+func (r *ConnectionManager) getMaxOpenConnections() int {
+	if r == nil {
+		return 1
+	}
+	return r.maxOpenConnections
+}
+
+func (r *ConnectionManager) getIdleTime() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.idleTime
+}
+
+func (r *connectionPool) close() {
+	if r.mysql != nil {
+		_ = r.mysql.Close()
+	}
+	if r.mongo != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), connectionCloseTimeout)
+		defer cancel()
+		_ = r.mongo.Disconnect(ctx)
+	}
+	if r.key.databaseType != "" {
+		appmetrics.DatabaseConnectionPools.WithLabelValues(r.getMetricType()).Dec()
+	}
+}
+
+func (r *connectionPool) getMetricType() string {
+	return strings.ToLower(string(r.key.databaseType))
+}
+
+func openMongoConnection(
+	ctx context.Context,
+	config configv1.MongoConfig,
+	maxPoolSize uint64,
+	maxIdleTime time.Duration,
+) (*mongo.Client, error) {
+	uri := fmt.Sprintf(
+		"mongodb://%s:%s@%s:%d",
+		config.Spec.Username,
+		config.Spec.Password,
+		config.Spec.Host1,
+		config.Spec.Port,
+	)
+
+	timeout := config.Spec.GetOperationTimeout()
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetConnectTimeout(timeout).
+		SetServerSelectionTimeout(timeout).
+		SetMaxPoolSize(maxPoolSize).
+		SetMaxConnIdleTime(maxIdleTime)
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	return client, nil
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func isMysqlConnectionClosed(connection *sql.DB) bool {
+	// A closed pool fails before the context is checked
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := connection.PingContext(ctx)
+
+	return err != nil && err.Error() == "sql: database is closed"
+}
+
+func newTestConnectionManager(now *time.Time) *ConnectionManager {
+	manager := NewConnectionManager(3, time.Minute)
+	manager.now = func() time.Time { return *now }
+
+	return manager
+}
+
+func TestConnectionManager_ReusesThePoolOfTheConfig(t *testing.T) {
+	now := time.Now()
+	manager := newTestConnectionManager(&now)
+	config := newTestMysqlConfig()
+
+	first, releaseFirst, err := manager.GetMysqlConnection(config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, releaseSecond, err := manager.GetMysqlConnection(config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	releaseFirst()
+	releaseSecond()
+
+	if first != second {
+		t.Error("expected the pool to be reused")
+	}
+	if got := first.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("MaxOpenConnections = %d, want 3", got)
+	}
+
+	otherConfig := newTestMysqlConfig()
+	otherConfig.Name = "other-env"
+	other, release, err := manager.GetMysqlConnection(otherConfig, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+
+	if other == first {
+		t.Error("expected a separate pool for each config")
+	}
+}
+
+func TestConnectionManager_ReplacesThePoolWhenTheConfigChanges(t *testing.T) {
+	now := time.Now()
+	manager := newTestConnectionManager(&now)
+	config := newTestMysqlConfig()
+	config.Generation = 1
+
+	original, releaseOriginal, err := manager.GetMysqlConnection(config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config.Generation = 2
+	updated, releaseUpdated, err := manager.GetMysqlConnection(config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer releaseUpdated()
+
+	if updated == original {
+		t.Fatal("expected a new pool for the new generation")
+	}
+	if isMysqlConnectionClosed(original) {
+		t.Error("the outdated pool must not be closed while it's in use")
+	}
+
+	releaseOriginal()
+	releaseOriginal()
+
+	if !isMysqlConnectionClosed(original) {
+		t.Error("the outdated pool should be closed once it's released")
+	}
+
+	current, releaseCurrent, err := manager.GetMysqlConnection(config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	releaseCurrent()
+
+	if current != updated {
+		t.Error("expected the pool to be reused for the same generation")
+	}
+
+	withCa, releaseWithCa, err := manager.GetMysqlConnection(config, []byte("certificate"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	releaseWithCa()
+
+	if withCa == updated {
+		t.Error("expected a new pool when the TLS CA certificate changes")
+	}
+}
+
+func TestConnectionManager_ReusesThePoolAfterStatusUpdates(t *testing.T) {
+	now := time.Now()
+	manager := newTestConnectionManager(&now)
+	config := newTestMysqlConfig()
+	config.Generation = 1
+	config.ResourceVersion = "1"
+
+	original, releaseOriginal, err := manager.GetMysqlConnection(config, []byte("certificate"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	releaseOriginal()
+
+	// The probe results are written to the status, which only changes the resource version
+	config.ResourceVersion = "2"
+	config.Status.ProvisionedDatabaseCount = 3
+	current, releaseCurrent, err := manager.GetMysqlConnection(config, []byte("certificate"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	releaseCurrent()
+
+	if current != original {
+		t.Error("expected the pool to be reused after a status update")
+	}
+	if isMysqlConnectionClosed(original) {
+		t.Error("the pool must not be closed after a status update")
+	}
+}
+
+func TestConnectionManager_EvictIdlePools(t *testing.T) {
+	now := time.Now()
+	manager := newTestConnectionManager(&now)
+	idleConfig := newTestMysqlConfig()
+	busyConfig := newTestMysqlConfig()
+	busyConfig.Name = "busy-env"
+
+	idle, release, err := manager.GetMysqlConnection(idleConfig, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+
+	busy, releaseBusy, err := manager.GetMysqlConnection(busyConfig, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	manager.EvictIdlePools()
+
+	if isMysqlConnectionClosed(idle) {
+		t.Error("the pool should not be evicted before the idle time")
+	}
+
+	now = now.Add(time.Minute)
+	manager.EvictIdlePools()
+
+	if !isMysqlConnectionClosed(idle) {
+		t.Error("the idle pool should be evicted")
+	}
+	if isMysqlConnectionClosed(busy) {
+		t.Error("the pool in use must not be evicted")
+	}
+
+	releaseBusy()
+	manager.Close()
+
+	if !isMysqlConnectionClosed(busy) {
+		t.Error("the pool should be closed with the manager")
+	}
+
+	reopened, release, err := manager.GetMysqlConnection(idleConfig, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+
+	if reopened == idle || isMysqlConnectionClosed(reopened) {
+		t.Error("expected a new pool after the eviction")
+	}
+}
+
+func TestConnectionManager_NilManagerDoesNotPool(t *testing.T) {
+	var manager *ConnectionManager
+	config := newTestMysqlConfig()
+
+	first, releaseFirst, err := manager.GetMysqlConnection(config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, releaseSecond, err := manager.GetMysqlConnection(config, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first == second {
+		t.Error("expected a new connection for every call")
+	}
+
+	releaseFirst()
+	releaseSecond()
+
+	if !isMysqlConnectionClosed(first) || !isMysqlConnectionClosed(second) {
+		t.Error("the connections should be closed when released")
+	}
+}
@@ -1,52 +1,195 @@
 package database
 
 import (
+	"context"
 	"github.com/go-logr/logr"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type MysqlReconciler interface {
-	Reconcile(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error)
-	Delete(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error
+	Reconcile(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error)
+	Delete(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error
 }
 
 type MongoReconciler interface {
-	Reconcile(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error)
-	Delete(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error
+	Reconcile(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error)
+	Delete(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error
 }
 
 // RedisReconciler omits Delete because Redis databases are ephemeral and the
 // controller has no finalizer/cleanup logic.
 type RedisReconciler interface {
-	Reconcile(database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error)
+	Reconcile(ctx context.Context, database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error)
 }
 
-// DefaultMysqlReconciler provides the production implementation using real MySQL connections.
-type DefaultMysqlReconciler struct{}
+// ObjectStorageReconciler manages the buckets of the sites on S3 compatible endpoints
+type ObjectStorageReconciler interface {
+	Reconcile(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) (bool, error)
+	Delete(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) error
+}
+
+// MysqlEnvironmentHandler handles server level operations on a mysql environment
+type MysqlEnvironmentHandler interface {
+	Probe(ctx context.Context, config configv1.MysqlConfig, logger logr.Logger) (serverVersion string, err error)
+	ListServerResources(ctx context.Context, config configv1.MysqlConfig, logger logr.Logger) (databases []string, users []string, err error)
+	DropDatabase(ctx context.Context, config configv1.MysqlConfig, name string, logger logr.Logger) error
+	DropUser(ctx context.Context, config configv1.MysqlConfig, name string, logger logr.Logger) error
+}
+
+// MongoEnvironmentHandler handles server level operations on a mongo environment
+type MongoEnvironmentHandler interface {
+	Probe(ctx context.Context, config configv1.MongoConfig, logger logr.Logger) (serverVersion string, err error)
+	ListServerResources(ctx context.Context, config configv1.MongoConfig, logger logr.Logger) (databases []string, users []string, err error)
+	DropDatabase(ctx context.Context, config configv1.MongoConfig, name string, logger logr.Logger) error
+	DropUser(ctx context.Context, config configv1.MongoConfig, name string, logger logr.Logger) error
+}
 
-func (DefaultMysqlReconciler) Reconcile(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error) {
-	return ReconcileMysqlDatabase(database, config, logger)
+// RedisEnvironmentHandler handles server level operations on a redis environment
+type RedisEnvironmentHandler interface {
+	Probe(ctx context.Context, config configv1.RedisConfig, logger logr.Logger) (serverVersion string, err error)
 }
 
-func (DefaultMysqlReconciler) Delete(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error {
-	return DeleteMysqlDatabase(database, config, logger)
+// DefaultMysqlReconciler provides the production implementation using real MySQL connections. The Reader is used to
+// load the TLS CA secrets referenced by the configs, and the connections are taken from the pools of Connections.
+type DefaultMysqlReconciler struct {
+	Reader      client.Reader
+	Connections *ConnectionManager
 }
 
-// DefaultMongoReconciler provides the production implementation using real MongoDB connections.
-type DefaultMongoReconciler struct{}
+func (r DefaultMysqlReconciler) Reconcile(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error) {
+	tlsCaCertificate, err := LoadMysqlTlsCaCertificate(ctx, r.Reader, config)
+	if err != nil {
+		return false, err
+	}
+	return ReconcileMysqlDatabase(ctx, r.Connections, database, config, tlsCaCertificate, logger)
+}
+
+func (r DefaultMysqlReconciler) Delete(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error {
+	tlsCaCertificate, err := LoadMysqlTlsCaCertificate(ctx, r.Reader, config)
+	if err != nil {
+		return err
+	}
+	return DeleteMysqlDatabase(ctx, r.Connections, database, config, tlsCaCertificate, logger)
+}
+
+// DefaultMongoReconciler provides the production implementation using real MongoDB connections, taken from the pools
+// of Connections.
+type DefaultMongoReconciler struct {
+	Connections *ConnectionManager
+}
 
-func (DefaultMongoReconciler) Reconcile(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error) {
-	return ReconcileMongoDatabase(database, config, logger)
+func (r DefaultMongoReconciler) Reconcile(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error) {
+	return ReconcileMongoDatabase(ctx, r.Connections, database, config, logger)
 }
 
-func (DefaultMongoReconciler) Delete(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error {
-	return DeleteMongoDatabase(database, config, logger)
+func (r DefaultMongoReconciler) Delete(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error {
+	return DeleteMongoDatabase(ctx, r.Connections, database, config, logger)
 }
 
 // DefaultRedisReconciler provides the production implementation using real Redis connections.
 type DefaultRedisReconciler struct{}
 
-func (DefaultRedisReconciler) Reconcile(database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error) {
-	return ReconcileRedis(database, config, logger)
+func (DefaultRedisReconciler) Reconcile(ctx context.Context, database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error) {
+	return ReconcileRedis(ctx, database, config, logger)
+}
+
+// DefaultObjectStorageReconciler provides the production implementation using the MinIO S3 client. The Reader is used
+// to load the admin credentials referenced by the configs.
+type DefaultObjectStorageReconciler struct {
+	Reader client.Reader
+}
+
+func (r DefaultObjectStorageReconciler) Reconcile(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) (bool, error) {
+	adminCredentials, err := LoadObjectStorageAdminCredentials(ctx, r.Reader, config)
+	if err != nil {
+		return false, err
+	}
+	backend, err := NewMinioObjectStorageBackend(config, adminCredentials)
+	if err != nil {
+		return false, err
+	}
+	return ReconcileObjectStorageBucket(ctx, backend, bucket, config, logger)
+}
+
+func (r DefaultObjectStorageReconciler) Delete(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) error {
+	adminCredentials, err := LoadObjectStorageAdminCredentials(ctx, r.Reader, config)
+	if err != nil {
+		return err
+	}
+	backend, err := NewMinioObjectStorageBackend(config, adminCredentials)
+	if err != nil {
+		return err
+	}
+	return DeleteObjectStorageBucket(ctx, backend, bucket, config, logger)
+}
+
+// DefaultMysqlEnvironmentHandler provides the production implementation using real MySQL connections. The Reader is
+// used to load the TLS CA secrets referenced by the configs, and the connections are taken from the pools of
+// Connections.
+type DefaultMysqlEnvironmentHandler struct {
+	Reader      client.Reader
+	Connections *ConnectionManager
+}
+
+func (r DefaultMysqlEnvironmentHandler) Probe(ctx context.Context, config configv1.MysqlConfig, logger logr.Logger) (string, error) {
+	tlsCaCertificate, err := LoadMysqlTlsCaCertificate(ctx, r.Reader, config)
+	if err != nil {
+		return "", err
+	}
+	return ProbeMysqlServer(ctx, r.Connections, config, tlsCaCertificate, logger)
+}
+
+func (r DefaultMysqlEnvironmentHandler) ListServerResources(ctx context.Context, config configv1.MysqlConfig, logger logr.Logger) ([]string, []string, error) {
+	tlsCaCertificate, err := LoadMysqlTlsCaCertificate(ctx, r.Reader, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ListMysqlServerResources(ctx, r.Connections, config, tlsCaCertificate, logger)
+}
+
+func (r DefaultMysqlEnvironmentHandler) DropDatabase(ctx context.Context, config configv1.MysqlConfig, name string, logger logr.Logger) error {
+	tlsCaCertificate, err := LoadMysqlTlsCaCertificate(ctx, r.Reader, config)
+	if err != nil {
+		return err
+	}
+	return DropMysqlDatabase(ctx, r.Connections, config, tlsCaCertificate, name, logger)
+}
+
+func (r DefaultMysqlEnvironmentHandler) DropUser(ctx context.Context, config configv1.MysqlConfig, name string, logger logr.Logger) error {
+	tlsCaCertificate, err := LoadMysqlTlsCaCertificate(ctx, r.Reader, config)
+	if err != nil {
+		return err
+	}
+	return DropMysqlUser(ctx, r.Connections, config, tlsCaCertificate, name, logger)
+}
+
+// DefaultMongoEnvironmentHandler provides the production implementation using real MongoDB connections, taken from
+// the pools of Connections.
+type DefaultMongoEnvironmentHandler struct {
+	Connections *ConnectionManager
+}
+
+func (r DefaultMongoEnvironmentHandler) Probe(ctx context.Context, config configv1.MongoConfig, logger logr.Logger) (string, error) {
+	return ProbeMongoServer(ctx, r.Connections, config, logger)
+}
+
+func (r DefaultMongoEnvironmentHandler) ListServerResources(ctx context.Context, config configv1.MongoConfig, logger logr.Logger) ([]string, []string, error) {
+	return ListMongoServerResources(ctx, r.Connections, config, logger)
+}
+
+func (r DefaultMongoEnvironmentHandler) DropDatabase(ctx context.Context, config configv1.MongoConfig, name string, logger logr.Logger) error {
+	return DropMongoDatabase(ctx, r.Connections, config, name, logger)
+}
+
+func (r DefaultMongoEnvironmentHandler) DropUser(ctx context.Context, config configv1.MongoConfig, name string, logger logr.Logger) error {
+	return DropMongoUser(ctx, r.Connections, config, name, logger)
+}
+
+// DefaultRedisEnvironmentHandler provides the production implementation using real Redis connections.
+type DefaultRedisEnvironmentHandler struct{}
+
+func (DefaultRedisEnvironmentHandler) Probe(ctx context.Context, config configv1.RedisConfig, logger logr.Logger) (string, error) {
+	return ProbeRedisServer(ctx, config, logger)
 }
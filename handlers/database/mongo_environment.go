@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/helpers"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var mongoSystemDatabases = []string{"admin", "config", "local"}
+
+// ListMongoServerResources returns the non system databases and the users defined in the admin database, except the
+// admin user of the config
+func ListMongoServerResources(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MongoConfig,
+	logger logr.Logger,
+) (
+	[]string,
+	[]string,
+	error,
+) {
+	client, ctx, done, err := getMongoConnection(ctx, connections, config, logger)
+
+	if err != nil {
+		return nil, nil, operationError(errorhelpers.DatabaseTypeMongo, config.Name, "list", err)
+	}
+
+	defer done()
+
+	databases, err := client.ListDatabaseNames(ctx, bson.D{})
+	if err != nil {
+		return nil, nil, operationError(errorhelpers.DatabaseTypeMongo, config.Name, "list", err)
+	}
+
+	var usersInfo struct {
+		Users []struct {
+			User string `bson:"user"`
+		} `bson:"users"`
+	}
+
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "usersInfo", Value: 1}}).Decode(&usersInfo); err != nil {
+		return nil, nil, operationError(errorhelpers.DatabaseTypeMongo, config.Name, "list", err)
+	}
+
+	var resultDatabases []string
+	for _, database := range databases {
+		if !helpers.SliceContainsString(mongoSystemDatabases, database) {
+			resultDatabases = append(resultDatabases, database)
+		}
+	}
+
+	var resultUsers []string
+	for _, user := range usersInfo.Users {
+		if user.User != config.Spec.Username {
+			resultUsers = append(resultUsers, user.User)
+		}
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("mongo", "list", "success").Inc()
+
+	return resultDatabases, resultUsers, nil
+}
+
+func DropMongoDatabase(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MongoConfig,
+	name string,
+	logger logr.Logger,
+) error {
+	if helpers.SliceContainsString(mongoSystemDatabases, name) {
+		return fmt.Errorf("refusing to drop the system database %s", name)
+	}
+
+	return runMongoEnvironmentTask(
+		ctx,
+		connections,
+		config,
+		"drop_database",
+		logger,
+		func(task *mongoReconcileTask) error {
+			task.database = name
+			return task.removeDatabase()
+		},
+	)
+}
+
+func DropMongoUser(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MongoConfig,
+	name string,
+	logger logr.Logger,
+) error {
+	if name == config.Spec.Username {
+		return fmt.Errorf("refusing to drop the admin user %s", name)
+	}
+
+	return runMongoEnvironmentTask(
+		ctx,
+		connections,
+		config,
+		"drop_user",
+		logger,
+		func(task *mongoReconcileTask) error {
+			task.username = name
+			return task.removeUser()
+		},
+	)
+}
+
+func runMongoEnvironmentTask(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MongoConfig,
+	operation string,
+	logger logr.Logger,
+	f func(task *mongoReconcileTask) error,
+) error {
+	client, ctx, done, err := getMongoConnection(ctx, connections, config, logger)
+
+	if err != nil {
+		return operationError(errorhelpers.DatabaseTypeMongo, config.Name, operation, err)
+	}
+
+	defer done()
+
+	task := &mongoReconcileTask{
+		logger:     logger,
+		connection: client,
+		ctx:        ctx,
+	}
+
+	if err := f(task); err != nil {
+		return operationError(errorhelpers.DatabaseTypeMongo, config.Name, operation, err)
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("mongo", operation, "success").Inc()
+
+	return nil
+}
+
+// ProbeMongoServer connects to the server with the admin credentials and returns the server version
+func ProbeMongoServer(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MongoConfig,
+	logger logr.Logger,
+) (string, error) {
+	client, ctx, done, err := getMongoConnection(ctx, connections, config, logger)
+
+	if err != nil {
+		return "", operationError(errorhelpers.DatabaseTypeMongo, config.Name, "probe", err)
+	}
+
+	defer done()
+
+	var buildInfo struct {
+		Version string `bson:"version"`
+	}
+
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&buildInfo); err != nil {
+		return "", operationError(errorhelpers.DatabaseTypeMongo, config.Name, "probe", err)
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("mongo", "probe", "success").Inc()
+
+	return buildInfo.Version, nil
+}
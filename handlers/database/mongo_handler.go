@@ -7,12 +7,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
-	"time"
 )
 
 type mongoReconcileTask struct {
@@ -24,24 +22,24 @@ type mongoReconcileTask struct {
 	database   string
 }
 
-func ReconcileMongoDatabase(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (
-	bool,
-	error,
-) {
+func ReconcileMongoDatabase(
+	ctx context.Context,
+	connections *ConnectionManager,
+	database *taskv1.MongoDatabase,
+	config configv1.MongoConfig,
+	logger logr.Logger,
+) (bool, error) {
 	timer := prometheus.NewTimer(appmetrics.DatabaseOperationDuration.WithLabelValues("mongo", "reconcile"))
 	defer timer.ObserveDuration()
 
-	client, ctx, cancel, err := getMongoConnection(config, logger)
-
-	if cancel != nil {
-		defer cancel()
-	}
+	client, ctx, done, err := getMongoConnection(ctx, connections, config, logger)
 
 	if err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mongo", "reconcile", "error").Inc()
-		return false, err
+		return false, operationError(errorhelpers.DatabaseTypeMongo, config.Name, "reconcile", err)
 	}
 
+	defer done()
+
 	task := mongoReconcileTask{
 		logger:     logger,
 		connection: client,
@@ -52,8 +50,7 @@ func ReconcileMongoDatabase(database *taskv1.MongoDatabase, config configv1.Mong
 	}
 
 	if err := task.reconcileTask(); err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mongo", "reconcile", "error").Inc()
-		return false, err
+		return false, operationError(errorhelpers.DatabaseTypeMongo, config.Name, "reconcile", err)
 	}
 
 	appmetrics.DatabaseOperations.WithLabelValues("mongo", "reconcile", "success").Inc()
@@ -67,21 +64,24 @@ func ReconcileMongoDatabase(database *taskv1.MongoDatabase, config configv1.Mong
 	return isChanged, nil
 }
 
-func DeleteMongoDatabase(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error {
+func DeleteMongoDatabase(
+	ctx context.Context,
+	connections *ConnectionManager,
+	database *taskv1.MongoDatabase,
+	config configv1.MongoConfig,
+	logger logr.Logger,
+) error {
 	timer := prometheus.NewTimer(appmetrics.DatabaseOperationDuration.WithLabelValues("mongo", "delete"))
 	defer timer.ObserveDuration()
 
-	client, ctx, cancel, err := getMongoConnection(config, logger)
-
-	if cancel != nil {
-		defer cancel()
-	}
+	client, ctx, done, err := getMongoConnection(ctx, connections, config, logger)
 
 	if err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mongo", "delete", "error").Inc()
-		return err
+		return operationError(errorhelpers.DatabaseTypeMongo, config.Name, "delete", err)
 	}
 
+	defer done()
+
 	task := mongoReconcileTask{
 		logger:     logger,
 		connection: client,
@@ -92,45 +92,38 @@ func DeleteMongoDatabase(database *taskv1.MongoDatabase, config configv1.MongoCo
 	}
 
 	if err := task.deleteTask(); err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mongo", "delete", "error").Inc()
-		return err
+		return operationError(errorhelpers.DatabaseTypeMongo, config.Name, "delete", err)
 	}
 
 	appmetrics.DatabaseOperations.WithLabelValues("mongo", "delete", "success").Inc()
 	return nil
 }
 
-func getMongoConnection(config configv1.MongoConfig, logger logr.Logger) (
-	*mongo.Client,
-	context.Context,
-	context.CancelFunc,
-	error,
-) {
+// getMongoConnection returns the pooled connection to the server of the config and a context that expires after the
+// operation timeout of the config, which must be used for every operation on the connection. The returned function
+// must be called once the connection is no longer used.
+func getMongoConnection(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MongoConfig,
+	logger logr.Logger,
+) (*mongo.Client, context.Context, func(), error) {
 	logger.Info("Connecting to database " + config.Name)
 
-	uri := fmt.Sprintf(
-		"mongodb://%s:%s@%s:%d",
-		config.Spec.Username,
-		config.Spec.Password,
-		config.Spec.Host1,
-		config.Spec.Port,
-	)
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
 
-	connectionCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	ctx := context.Background()
-
-	client, err := mongo.Connect(connectionCtx, options.Client().ApplyURI(uri))
+	client, release, err := connections.GetMongoConnection(ctx, config)
 	if err != nil {
-		return nil, nil, cancel, err
-	}
-
-	if err = client.Ping(ctx, readpref.Primary()); err != nil {
-		return nil, nil, cancel, err
+		cancel()
+		return nil, nil, nil, err
 	}
 
 	logger.Info("Connected")
 
-	return client, ctx, cancel, nil
+	return client, ctx, func() {
+		release()
+		cancel()
+	}, nil
 }
 
 func (r *mongoReconcileTask) reconcileTask() error {
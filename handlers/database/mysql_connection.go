@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+)
+
+// LoadMysqlTlsCaCertificate returns the PEM encoded CA certificate referenced by the config, or nil if the config
+// doesn't reference one.
+func LoadMysqlTlsCaCertificate(ctx context.Context, reader client.Reader, config configv1.MysqlConfig) (
+	[]byte,
+	error,
+) {
+	if config.Spec.TlsCaSecret == nil {
+		return nil, nil
+	}
+
+	if reader == nil {
+		return nil, errors.New("no reader configured to load the TLS CA secret of mysql config " + config.Name)
+	}
+
+	var secret corev1.Secret
+	if err := reader.Get(
+		ctx,
+		client.ObjectKey{Namespace: config.Namespace, Name: config.Spec.TlsCaSecret.Name},
+		&secret,
+	); err != nil {
+		return nil, err
+	}
+
+	certificate, ok := secret.Data[config.Spec.TlsCaSecret.Key]
+	if !ok {
+		return nil, fmt.Errorf(
+			"the key %s doesn't exist in the TLS CA secret %s of mysql config %s",
+			config.Spec.TlsCaSecret.Key,
+			config.Spec.TlsCaSecret.Name,
+			config.Name,
+		)
+	}
+
+	return certificate, nil
+}
+
+func openMysqlConnection(
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	username string,
+	password string,
+) (*sql.DB, error) {
+	driverConfig, err := makeMysqlDriverConfig(config, tlsCaCertificate, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := mysql.NewConnector(driverConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.OpenDB(connector), nil
+}
+
+func makeMysqlDriverConfig(
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	username string,
+	password string,
+) (*mysql.Config, error) {
+	driverConfig := mysql.NewConfig()
+	driverConfig.User = username
+	driverConfig.Passwd = password
+	driverConfig.Net = "tcp"
+	driverConfig.Addr = net.JoinHostPort(config.Spec.Host, strconv.Itoa(int(config.Spec.Port)))
+	driverConfig.DBName = "mysql"
+	driverConfig.Timeout = config.Spec.GetConnectTimeout()
+	driverConfig.ReadTimeout = config.Spec.GetReadTimeout()
+
+	switch config.Spec.GetTlsMode() {
+	case configv1.MysqlTlsModeDisabled:
+		driverConfig.TLSConfig = "false"
+	case configv1.MysqlTlsModePreferred:
+		driverConfig.TLSConfig = "preferred"
+	case configv1.MysqlTlsModeRequired:
+		driverConfig.TLS = &tls.Config{InsecureSkipVerify: true}
+	case configv1.MysqlTlsModeVerifyCA:
+		rootCAs, err := makeMysqlCertPool(tlsCaCertificate)
+		if err != nil {
+			return nil, err
+		}
+		driverConfig.TLS = &tls.Config{
+			// The hostname is not verified in this mode, so the chain is verified in VerifyPeerCertificate instead
+			InsecureSkipVerify:    true,
+			VerifyPeerCertificate: makeCertificateChainVerifier(rootCAs),
+		}
+	case configv1.MysqlTlsModeVerifyIdentity:
+		rootCAs, err := makeMysqlCertPool(tlsCaCertificate)
+		if err != nil {
+			return nil, err
+		}
+		driverConfig.TLS = &tls.Config{RootCAs: rootCAs, ServerName: config.Spec.Host}
+	default:
+		return nil, fmt.Errorf("invalid TLS mode %s in mysql config %s", config.Spec.TlsMode, config.Name)
+	}
+
+	return driverConfig, nil
+}
+
+// makeMysqlCertPool returns a pool with the provided CA certificate, or nil to use the system pool if none is provided
+func makeMysqlCertPool(tlsCaCertificate []byte) (*x509.CertPool, error) {
+	if len(tlsCaCertificate) == 0 {
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(tlsCaCertificate) {
+		return nil, errors.New("failed to parse the mysql TLS CA certificate")
+	}
+
+	return pool, nil
+}
+
+func makeCertificateChainVerifier(rootCAs *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("the server didn't present a certificate")
+		}
+
+		certificates := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, rawCert := range rawCerts {
+			certificate, err := x509.ParseCertificate(rawCert)
+			if err != nil {
+				return err
+			}
+			certificates = append(certificates, certificate)
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, certificate := range certificates[1:] {
+			intermediates.AddCert(certificate)
+		}
+
+		_, err := certificates[0].Verify(x509.VerifyOptions{Roots: rootCAs, Intermediates: intermediates})
+
+		return err
+	}
+}
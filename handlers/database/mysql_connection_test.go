@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestMysqlConfig() configv1.MysqlConfig {
+	return configv1.MysqlConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysql-env", Namespace: "test-ns"},
+		Spec: configv1.MysqlConfigSpec{
+			Host:     "mysql.example.com",
+			Username: "admin",
+			Password: "adminpass",
+			Port:     3306,
+		},
+	}
+}
+
+func TestMakeMysqlDriverConfig_Defaults(t *testing.T) {
+	config := newTestMysqlConfig()
+
+	driverConfig, err := makeMysqlDriverConfig(config, nil, "user", "pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if driverConfig.Addr != "mysql.example.com:3306" {
+		t.Errorf("Addr = %q, want %q", driverConfig.Addr, "mysql.example.com:3306")
+	}
+	if driverConfig.User != "user" || driverConfig.Passwd != "pass" {
+		t.Errorf("unexpected credentials: %q/%q", driverConfig.User, driverConfig.Passwd)
+	}
+	if driverConfig.Timeout != 10*time.Second {
+		t.Errorf("Timeout = %v, want 10s", driverConfig.Timeout)
+	}
+	if driverConfig.ReadTimeout != 30*time.Second {
+		t.Errorf("ReadTimeout = %v, want 30s", driverConfig.ReadTimeout)
+	}
+	if driverConfig.TLS != nil || driverConfig.TLSConfig != "false" {
+		t.Errorf("expected TLS to be disabled, got TLS=%v TLSConfig=%q", driverConfig.TLS, driverConfig.TLSConfig)
+	}
+}
+
+func TestMakeMysqlDriverConfig_TlsModes(t *testing.T) {
+	config := newTestMysqlConfig()
+	config.Spec.ConnectTimeoutSeconds = 3
+	config.Spec.ReadTimeoutSeconds = 7
+
+	t.Run("required skips verification", func(t *testing.T) {
+		config.Spec.TlsMode = configv1.MysqlTlsModeRequired
+		driverConfig, err := makeMysqlDriverConfig(config, nil, "user", "pass")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if driverConfig.TLS == nil || !driverConfig.TLS.InsecureSkipVerify {
+			t.Errorf("expected TLS without verification, got %v", driverConfig.TLS)
+		}
+		if driverConfig.Timeout != 3*time.Second || driverConfig.ReadTimeout != 7*time.Second {
+			t.Errorf("unexpected timeouts: %v/%v", driverConfig.Timeout, driverConfig.ReadTimeout)
+		}
+	})
+
+	t.Run("verify identity sets the server name", func(t *testing.T) {
+		config.Spec.TlsMode = configv1.MysqlTlsModeVerifyIdentity
+		driverConfig, err := makeMysqlDriverConfig(config, nil, "user", "pass")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if driverConfig.TLS == nil || driverConfig.TLS.InsecureSkipVerify {
+			t.Fatalf("expected verified TLS, got %v", driverConfig.TLS)
+		}
+		if driverConfig.TLS.ServerName != "mysql.example.com" {
+			t.Errorf("ServerName = %q, want %q", driverConfig.TLS.ServerName, "mysql.example.com")
+		}
+	})
+
+	t.Run("verify CA verifies the chain itself", func(t *testing.T) {
+		config.Spec.TlsMode = configv1.MysqlTlsModeVerifyCA
+		driverConfig, err := makeMysqlDriverConfig(config, nil, "user", "pass")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if driverConfig.TLS == nil || driverConfig.TLS.VerifyPeerCertificate == nil {
+			t.Fatalf("expected a peer certificate verifier, got %v", driverConfig.TLS)
+		}
+		if err := driverConfig.TLS.VerifyPeerCertificate(nil, nil); err == nil {
+			t.Error("expected an error when the server presents no certificate")
+		}
+	})
+
+	t.Run("invalid CA certificate", func(t *testing.T) {
+		config.Spec.TlsMode = configv1.MysqlTlsModeVerifyIdentity
+		if _, err := makeMysqlDriverConfig(config, []byte("not a certificate"), "user", "pass"); err == nil {
+			t.Error("expected an error for an invalid CA certificate")
+		}
+	})
+}
+
+func TestLoadMysqlTlsCaCertificate(t *testing.T) {
+	ctx := context.Background()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysql-ca", Namespace: "test-ns"},
+		Data:       map[string][]byte{"ca.crt": []byte("certificate")},
+	}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+	config := newTestMysqlConfig()
+
+	t.Run("no secret configured", func(t *testing.T) {
+		certificate, err := LoadMysqlTlsCaCertificate(ctx, c, config)
+		if err != nil || certificate != nil {
+			t.Errorf("expected no certificate and no error, got %q, %v", certificate, err)
+		}
+	})
+
+	t.Run("loads the referenced key", func(t *testing.T) {
+		config.Spec.TlsCaSecret = &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "mysql-ca"},
+			Key:                  "ca.crt",
+		}
+		certificate, err := LoadMysqlTlsCaCertificate(ctx, c, config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(certificate) != "certificate" {
+			t.Errorf("certificate = %q, want %q", certificate, "certificate")
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		config.Spec.TlsCaSecret.Key = "missing"
+		if _, err := LoadMysqlTlsCaCertificate(ctx, c, config); err == nil {
+			t.Error("expected an error for a missing key")
+		}
+	})
+}
+
+func TestGetMysqlCharacterSetAndCollation(t *testing.T) {
+	config := newTestMysqlConfig()
+	config.Spec.DefaultCharacterSet = "utf8mb4"
+	config.Spec.DefaultCollation = "utf8mb4_unicode_ci"
+
+	t.Run("falls back to the environment defaults", func(t *testing.T) {
+		characterSet, collation := getMysqlCharacterSetAndCollation(&taskv1.MysqlDatabase{}, config)
+		if characterSet != "utf8mb4" || collation != "utf8mb4_unicode_ci" {
+			t.Errorf("got %q/%q", characterSet, collation)
+		}
+	})
+
+	t.Run("database settings are used as a pair", func(t *testing.T) {
+		database := &taskv1.MysqlDatabase{Spec: taskv1.MysqlDatabaseSpec{CharacterSet: "latin1"}}
+		characterSet, collation := getMysqlCharacterSetAndCollation(database, config)
+		if characterSet != "latin1" || collation != "" {
+			t.Errorf("got %q/%q", characterSet, collation)
+		}
+	})
+}
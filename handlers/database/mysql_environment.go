@@ -0,0 +1,200 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-logr/logr"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/helpers"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+)
+
+var mysqlSystemDatabases = []string{"information_schema", "mysql", "performance_schema", "sys"}
+
+// ListMysqlServerResources returns the non system databases and the users on the server, except the admin user of the
+// config
+func ListMysqlServerResources(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	logger logr.Logger,
+) ([]string, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
+	defer cancel()
+
+	logger.Info("Connecting to database " + config.Name)
+
+	connection, release, err := connections.GetMysqlConnection(config, tlsCaCertificate)
+	if err != nil {
+		return nil, nil, operationError(errorhelpers.DatabaseTypeMysql, config.Name, "list", err)
+	}
+
+	defer release()
+
+	databases, err := queryMysqlStrings(ctx, connection, "SHOW DATABASES")
+	if err != nil {
+		return nil, nil, operationError(errorhelpers.DatabaseTypeMysql, config.Name, "list", err)
+	}
+
+	users, err := queryMysqlStrings(
+		ctx,
+		connection,
+		"SELECT DISTINCT User FROM user WHERE User <> ?",
+		config.Spec.Username,
+	)
+	if err != nil {
+		return nil, nil, operationError(errorhelpers.DatabaseTypeMysql, config.Name, "list", err)
+	}
+
+	var result []string
+	for _, database := range databases {
+		if !helpers.SliceContainsString(mysqlSystemDatabases, database) {
+			result = append(result, database)
+		}
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("mysql", "list", "success").Inc()
+
+	return result, users, nil
+}
+
+func DropMysqlDatabase(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	name string,
+	logger logr.Logger,
+) error {
+	if helpers.SliceContainsString(mysqlSystemDatabases, name) {
+		return fmt.Errorf("refusing to drop the system database %s", name)
+	}
+
+	return runMysqlEnvironmentTask(
+		ctx,
+		connections,
+		config,
+		tlsCaCertificate,
+		"drop_database",
+		logger,
+		func(task *mysqlReconcileTask) error {
+			task.database = name
+			return task.removeDatabase()
+		},
+	)
+}
+
+// DropMysqlUser drops the user from every host it's defined for
+func DropMysqlUser(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	name string,
+	logger logr.Logger,
+) error {
+	if name == config.Spec.Username {
+		return fmt.Errorf("refusing to drop the admin user %s", name)
+	}
+
+	return runMysqlEnvironmentTask(
+		ctx,
+		connections,
+		config,
+		tlsCaCertificate,
+		"drop_user",
+		logger,
+		func(task *mysqlReconcileTask) error {
+			return task.removeUser(name)
+		},
+	)
+}
+
+func runMysqlEnvironmentTask(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	operation string,
+	logger logr.Logger,
+	f func(task *mysqlReconcileTask) error,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
+	defer cancel()
+
+	logger.Info("Connecting to database " + config.Name)
+
+	connection, release, err := connections.GetMysqlConnection(config, tlsCaCertificate)
+	if err != nil {
+		return operationError(errorhelpers.DatabaseTypeMysql, config.Name, operation, err)
+	}
+
+	defer release()
+
+	task := &mysqlReconcileTask{
+		logger:     logger,
+		ctx:        ctx,
+		connection: connection,
+	}
+
+	if err := f(task); err != nil {
+		return operationError(errorhelpers.DatabaseTypeMysql, config.Name, operation, err)
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("mysql", operation, "success").Inc()
+
+	return nil
+}
+
+func queryMysqlStrings(ctx context.Context, connection *sql.DB, query string, args ...any) ([]string, error) {
+	var values []string
+
+	result, err := connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return values, err
+	}
+
+	defer func() { _ = result.Close() }()
+
+	var value string
+
+	for result.Next() {
+		if err := result.Scan(&value); err != nil {
+			return values, err
+		}
+		values = append(values, value)
+	}
+
+	return values, result.Err()
+}
+
+// ProbeMysqlServer connects to the server with the admin credentials and returns the server version
+func ProbeMysqlServer(
+	ctx context.Context,
+	connections *ConnectionManager,
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	logger logr.Logger,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
+	defer cancel()
+
+	connection, release, err := connections.GetMysqlConnection(config, tlsCaCertificate)
+	if err != nil {
+		return "", operationError(errorhelpers.DatabaseTypeMysql, config.Name, "probe", err)
+	}
+
+	defer release()
+
+	var version string
+	if err := connection.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return "", operationError(errorhelpers.DatabaseTypeMysql, config.Name, "probe", err)
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("mysql", "probe", "success").Inc()
+
+	return version, nil
+}
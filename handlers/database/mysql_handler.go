@@ -1,72 +1,96 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/go-logr/logr"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/prometheus/client_golang/prometheus"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"reflect"
+	"strings"
 )
 
+const defaultMysqlUserHost = "%"
+
 type mysqlReconcileTask struct {
-	logger     logr.Logger
-	connection *sql.DB
-	host       string
-	port       uint16
+	logger          logr.Logger
+	ctx             context.Context
+	connection      *sql.DB
+	user            mysqlAccount
+	additionalUsers []mysqlAccount
+	obsoleteUsers   []string
+	database        string
+	characterSet    string
+	collation       string
+	// The authentication plugin of the users, the server default is used if empty
+	authenticationPlugin string
+	// The namespaced name of the environment, used to log the unverifiable passwords only once per server
+	environment string
+}
+
+type mysqlAccount struct {
 	username   string
 	password   string
-	database   string
+	host       string
+	privileges []configv1.MysqlPrivilege
 }
 
 type mysqlUserResult struct {
 	User         string
+	Plugin       string
 	PasswordHash string
 }
 
-func ReconcileMysqlDatabase(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (
-	bool,
-	error,
-) {
+func ReconcileMysqlDatabase(
+	ctx context.Context,
+	connections *ConnectionManager,
+	database *taskv1.MysqlDatabase,
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	logger logr.Logger,
+) (bool, error) {
 	timer := prometheus.NewTimer(appmetrics.DatabaseOperationDuration.WithLabelValues("mysql", "reconcile"))
 	defer timer.ObserveDuration()
 
-	logger.Info("Connecting to database " + config.Name)
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
+	defer cancel()
 
-	dataSourceName := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/mysql",
-		config.Spec.Username,
-		config.Spec.Password,
-		config.Spec.Host,
-		config.Spec.Port,
-	)
+	logger.Info("Connecting to database " + config.Name)
 
-	connection, err := sql.Open("mysql", dataSourceName)
+	connection, release, err := connections.GetMysqlConnection(config, tlsCaCertificate)
 
 	if err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mysql", "reconcile", "error").Inc()
-		return false, err
+		return false, operationError(errorhelpers.DatabaseTypeMysql, config.Name, "reconcile", err)
 	}
 
-	defer func() { _ = connection.Close() }()
+	defer release()
 
 	logger.Info("Connected")
 
+	characterSet, collation := getMysqlCharacterSetAndCollation(database, config)
+	additionalUsers := getMysqlAdditionalAccounts(database)
+
 	task := mysqlReconcileTask{
-		logger:     logger,
-		connection: connection,
-		host:       config.Spec.Host,
-		port:       config.Spec.Port,
-		username:   database.Spec.Username,
-		password:   database.Spec.Password,
-		database:   database.Spec.DatabaseName,
+		logger:          logger,
+		ctx:             ctx,
+		connection:      connection,
+		user:            getMysqlMainAccount(database),
+		additionalUsers: additionalUsers,
+		obsoleteUsers:   getObsoleteMysqlUsernames(database.Status.AdditionalUsers, additionalUsers),
+		database:        database.Spec.DatabaseName,
+		characterSet:    characterSet,
+		collation:       collation,
+
+		authenticationPlugin: config.Spec.AuthenticationPlugin,
+		environment:          config.Namespace + "/" + config.Name,
 	}
 
 	if err := task.reconcileTask(); err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mysql", "reconcile", "error").Inc()
-		return false, err
+		return false, operationError(errorhelpers.DatabaseTypeMysql, config.Name, "reconcile", err)
 	}
 
 	appmetrics.DatabaseOperations.WithLabelValues("mysql", "reconcile", "success").Inc()
@@ -77,64 +101,148 @@ func ReconcileMysqlDatabase(database *taskv1.MysqlDatabase, config configv1.Mysq
 		database.Status.State = taskv1.Complete
 	}
 
+	provisionedUsers := sortedMysqlUsernames(additionalUsers)
+	if len(provisionedUsers) == 0 {
+		provisionedUsers = nil
+	}
+	if !reflect.DeepEqual(database.Status.AdditionalUsers, provisionedUsers) {
+		isChanged = true
+		database.Status.AdditionalUsers = provisionedUsers
+	}
+
 	return isChanged, nil
 }
 
-func DeleteMysqlDatabase(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error {
+func DeleteMysqlDatabase(
+	ctx context.Context,
+	connections *ConnectionManager,
+	database *taskv1.MysqlDatabase,
+	config configv1.MysqlConfig,
+	tlsCaCertificate []byte,
+	logger logr.Logger,
+) error {
 	timer := prometheus.NewTimer(appmetrics.DatabaseOperationDuration.WithLabelValues("mysql", "delete"))
 	defer timer.ObserveDuration()
 
-	logger.Info("Connecting to database " + config.Name)
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
+	defer cancel()
 
-	dataSourceName := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/mysql",
-		config.Spec.Username,
-		config.Spec.Password,
-		config.Spec.Host,
-		config.Spec.Port,
-	)
+	logger.Info("Connecting to database " + config.Name)
 
-	connection, err := sql.Open("mysql", dataSourceName)
+	connection, release, err := connections.GetMysqlConnection(config, tlsCaCertificate)
 
 	if err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mysql", "delete", "error").Inc()
-		return err
+		return operationError(errorhelpers.DatabaseTypeMysql, config.Name, "delete", err)
 	}
 
-	defer func() { _ = connection.Close() }()
+	defer release()
 
 	logger.Info("Connected")
 
+	additionalUsers := getMysqlAdditionalAccounts(database)
+
 	task := mysqlReconcileTask{
-		logger:     logger,
-		connection: connection,
-		username:   database.Spec.Username,
-		password:   database.Spec.Password,
-		database:   database.Spec.DatabaseName,
+		logger:          logger,
+		ctx:             ctx,
+		connection:      connection,
+		user:            getMysqlMainAccount(database),
+		additionalUsers: additionalUsers,
+		obsoleteUsers:   getObsoleteMysqlUsernames(database.Status.AdditionalUsers, additionalUsers),
+		database:        database.Spec.DatabaseName,
 	}
 
 	if err := task.deleteTask(); err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("mysql", "delete", "error").Inc()
-		return err
+		return operationError(errorhelpers.DatabaseTypeMysql, config.Name, "delete", err)
 	}
 
 	appmetrics.DatabaseOperations.WithLabelValues("mysql", "delete", "success").Inc()
 	return nil
 }
 
+// getMysqlCharacterSetAndCollation returns the character set and collation to create the database with. The database
+// level settings are used as a pair if either of them is set, so a service level character set is never combined with
+// an incompatible environment level collation.
+func getMysqlCharacterSetAndCollation(database *taskv1.MysqlDatabase, config configv1.MysqlConfig) (string, string) {
+	if database.Spec.CharacterSet != "" || database.Spec.Collation != "" {
+		return database.Spec.CharacterSet, database.Spec.Collation
+	}
+
+	return config.Spec.DefaultCharacterSet, config.Spec.DefaultCollation
+}
+
+func getMysqlMainAccount(database *taskv1.MysqlDatabase) mysqlAccount {
+	return mysqlAccount{
+		username:   database.Spec.Username,
+		password:   database.Spec.Password,
+		host:       getMysqlUserHost(database.Spec.Host),
+		privileges: database.Spec.Privileges,
+	}
+}
+
+func getMysqlAdditionalAccounts(database *taskv1.MysqlDatabase) []mysqlAccount {
+	var accounts []mysqlAccount
+	for _, user := range database.Spec.AdditionalUsers {
+		accounts = append(accounts, mysqlAccount{
+			username:   user.Username,
+			password:   user.Password,
+			host:       getMysqlUserHost(user.Host),
+			privileges: user.Privileges,
+		})
+	}
+
+	return accounts
+}
+
+// getObsoleteMysqlUsernames returns the previously provisioned additional users that are no longer requested
+func getObsoleteMysqlUsernames(provisionedUsernames []string, accounts []mysqlAccount) []string {
+	var result []string
+	for _, username := range provisionedUsernames {
+		isRequested := false
+		for _, account := range accounts {
+			if account.username == username {
+				isRequested = true
+				break
+			}
+		}
+		if !isRequested {
+			result = append(result, username)
+		}
+	}
+
+	return result
+}
+
+func getMysqlUserHost(host string) string {
+	if host == "" {
+		return defaultMysqlUserHost
+	}
+
+	return host
+}
+
 func (r *mysqlReconcileTask) reconcileTask() error {
 	r.logger.Info("Reconciling task")
 
-	if err := r.reconcileUser(); err != nil {
-		return err
+	for _, account := range r.getAccounts() {
+		if err := r.reconcileUser(account); err != nil {
+			return err
+		}
 	}
 
 	if err := r.reconcileDatabase(); err != nil {
 		return err
 	}
 
-	if err := r.reconcilePermissions(); err != nil {
-		return err
+	for _, account := range r.getAccounts() {
+		if err := r.reconcilePermissions(account); err != nil {
+			return err
+		}
+	}
+
+	for _, username := range r.obsoleteUsers {
+		if err := r.removeUser(username); err != nil {
+			return err
+		}
 	}
 
 	r.logger.Info("Task successfully reconciled")
@@ -145,12 +253,15 @@ func (r *mysqlReconcileTask) reconcileTask() error {
 func (r *mysqlReconcileTask) deleteTask() error {
 	r.logger.Info("Deleting task")
 
-	if err := r.removeUser(); err != nil {
-		return err
+	usernames := append([]string{}, r.obsoleteUsers...)
+	for _, account := range r.getAccounts() {
+		usernames = append(usernames, account.username)
 	}
 
-	if err := r.revokePermissions(); err != nil {
-		return err
+	for _, username := range usernames {
+		if err := r.removeUser(username); err != nil {
+			return err
+		}
 	}
 
 	if err := r.removeDatabase(); err != nil {
@@ -162,36 +273,66 @@ func (r *mysqlReconcileTask) deleteTask() error {
 	return nil
 }
 
-func (r *mysqlReconcileTask) reconcileUser() error {
-	r.logger.Info("Starting user reconciliation")
+func (r *mysqlReconcileTask) getAccounts() []mysqlAccount {
+	return append([]mysqlAccount{r.user}, r.additionalUsers...)
+}
 
-	var user mysqlUserResult
-	if err := r.getUser(&user); err != nil {
+func (r *mysqlReconcileTask) reconcileUser(account mysqlAccount) error {
+	r.logger.Info("Starting user reconciliation for " + account.username)
+
+	if err := r.removeUserFromOtherHosts(account); err != nil {
 		return err
 	}
 
-	r.logger.Info(fmt.Sprintf("Existing user: %v", user))
-
-	if user.User == r.username {
-		passwordCorrect := r.checkUserCanLogin()
+	var user mysqlUserResult
+	if err := r.getUser(account, &user); err != nil {
+		return err
+	}
 
-		if passwordCorrect {
-			r.logger.Info("the mysql user is up to date")
+	r.logger.Info(fmt.Sprintf("Existing user: %v", user.User))
 
-			return nil
+	if user.User == account.username {
+		if r.authenticationPlugin != "" && user.Plugin != r.authenticationPlugin {
+			r.logger.Info(
+				fmt.Sprintf(
+					"the mysql user uses the %s plugin instead of %s, changing password",
+					user.Plugin,
+					r.authenticationPlugin,
+				),
+			)
 		} else {
-			r.logger.Info("the mysql password is incorrect for the user, changing password")
-
-			if err := r.changePassword(); err != nil {
-				return err
+			switch checkMysqlPassword(user.Plugin, user.PasswordHash, account.password) {
+			case mysqlPasswordCorrect:
+				r.logger.Info("the mysql user is up to date")
+
+				return nil
+			case mysqlPasswordUnknown:
+				accountName := makeMysqlAccountName(account.username, account.host)
+				if isFirstUnverifiableMysqlPassword(r.environment, accountName, user.Plugin) {
+					r.logger.Info(
+						fmt.Sprintf(
+							"the password of %s can't be checked for the %s plugin, leaving it unchanged",
+							accountName,
+							user.Plugin,
+						),
+					)
+				}
+
+				return nil
 			}
 
-			r.logger.Info("password updated")
+			r.logger.Info("the mysql password is incorrect for the user, changing password")
 		}
+
+		if err := r.changePassword(account); err != nil {
+			return err
+		}
+
+		r.logger.Info("password updated")
 	} else {
 		r.logger.Info("mysql user does not exist, creating it")
 
-		if err := r.createUser(); err != nil {
+		if err := r.createUser(account); err != nil {
 			return err
 		}
 
@@ -201,45 +342,124 @@ func (r *mysqlReconcileTask) reconcileUser() error {
 	return nil
 }
 
-func (r *mysqlReconcileTask) removeUser() error {
-	if r.username == "" {
-		return nil
+// removeUserFromOtherHosts drops the accounts with the same username, but a different host pattern. These exist if the
+// host of the user was changed since it was created.
+func (r *mysqlReconcileTask) removeUserFromOtherHosts(account mysqlAccount) error {
+	hosts, err := r.getUserHosts(account.username)
+	if err != nil {
+		return err
 	}
 
-	var user mysqlUserResult
-	if err := r.getUser(&user); err != nil {
-		return err
+	for _, host := range hosts {
+		if host == account.host {
+			continue
+		}
+
+		r.logger.Info(fmt.Sprintf("Removing user %s from host %s", account.username, host))
+		statement := "DROP USER " + makeMysqlAccountName(account.username, host)
+		if _, err := r.connection.ExecContext(r.ctx, statement); err != nil {
+			return err
+		}
 	}
 
-	if user.User == r.username {
-		r.logger.Info("Removing user")
+	return nil
+}
 
-		_, err := r.connection.Exec(fmt.Sprintf("DROP USER '%s'@'%%'", r.username))
+// removeUser drops every account with the username, regardless of the host
+func (r *mysqlReconcileTask) removeUser(username string) error {
+	if username == "" {
+		return nil
+	}
 
+	hosts, err := r.getUserHosts(username)
+	if err != nil {
 		return err
 	}
 
+	for _, host := range hosts {
+		r.logger.Info(fmt.Sprintf("Removing user %s from host %s", username, host))
+
+		if _, err := r.connection.ExecContext(r.ctx, "DROP USER "+makeMysqlAccountName(username, host)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (r *mysqlReconcileTask) getUserHosts(username string) ([]string, error) {
+	var hosts []string
+
+	if username == "" {
+		return hosts, nil
+	}
+
+	result, err := r.connection.QueryContext(r.ctx, "SELECT Host FROM user WHERE User = ?", username)
+
+	if err != nil {
+		return hosts, err
+	}
+
+	defer func() { _ = result.Close() }()
+
+	var host string
+
+	for result.Next() {
+		if err := result.Scan(&host); err != nil {
+			return hosts, err
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, result.Err()
+}
+
 func (r *mysqlReconcileTask) reconcileDatabase() error {
-	_, err := r.connection.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", r.database))
+	query := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", r.database)
+	if r.characterSet != "" {
+		query += fmt.Sprintf(" CHARACTER SET `%s`", r.characterSet)
+	}
+	if r.collation != "" {
+		query += fmt.Sprintf(" COLLATE `%s`", r.collation)
+	}
+
+	_, err := r.connection.ExecContext(r.ctx, query)
 
 	return err
 }
 
-func (r *mysqlReconcileTask) reconcilePermissions() error {
-	havePerms, err := r.revokeNotRequestedPermissions()
+func (r *mysqlReconcileTask) reconcilePermissions(account mysqlAccount) error {
+	if err := r.revokeNotRequestedPermissions(account); err != nil {
+		return err
+	}
+
+	expected, err := normaliseMysqlPrivileges(account.privileges)
+	if err != nil {
+		return err
+	}
 
+	current, err := r.getCurrentPrivileges(account)
 	if err != nil {
 		return err
 	}
 
-	if !havePerms {
-		r.logger.Info("Granting all privileges on db " + r.database)
-		_, err = r.connection.Exec(fmt.Sprintf("GRANT ALL ON `%s`.* TO '%s'@'%%'", r.database, r.username))
+	toGrant, toRevoke := diffMysqlPrivileges(current, expected)
+	accountName := makeMysqlAccountName(account.username, account.host)
+
+	if len(toRevoke) > 0 {
+		r.logger.Info(fmt.Sprintf("Revoking %s on db %s from %s", strings.Join(toRevoke, ", "), r.database, accountName))
 
-		if err != nil {
+		statement := makeMysqlRevokeStatement(toRevoke, r.database, accountName)
+		if _, err := r.connection.ExecContext(r.ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if len(toGrant) > 0 {
+		r.logger.Info(fmt.Sprintf("Granting %s on db %s to %s", strings.Join(toGrant, ", "), r.database, accountName))
+
+		statement := makeMysqlGrantStatement(toGrant, r.database, accountName)
+		if _, err := r.connection.ExecContext(r.ctx, statement); err != nil {
 			return err
 		}
 	}
@@ -247,14 +467,58 @@ func (r *mysqlReconcileTask) reconcilePermissions() error {
 	return nil
 }
 
-func (r *mysqlReconcileTask) getDatabasesWhereUserHasPermissions() ([]string, error) {
+// getCurrentPrivileges returns the privileges the account currently has on the task database
+func (r *mysqlReconcileTask) getCurrentPrivileges(account mysqlAccount) (map[string]bool, error) {
+	columns := make([]string, 0, len(mysqlPrivilegeColumns))
+	for _, v := range mysqlPrivilegeColumns {
+		columns = append(columns, v.column)
+	}
+
+	values := make([]sql.NullString, len(mysqlPrivilegeColumns))
+	scanTargets := make([]any, len(values))
+	for i := range values {
+		scanTargets[i] = &values[i]
+	}
+
+	result := make(map[string]bool)
+
+	err := r.connection.QueryRowContext(
+		r.ctx,
+		fmt.Sprintf("SELECT %s FROM db WHERE User = ? AND Host = ? AND Db = ?", strings.Join(columns, ", ")),
+		account.username,
+		account.host,
+		r.database,
+	).Scan(scanTargets...)
+
+	if err == sql.ErrNoRows {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range mysqlPrivilegeColumns {
+		if strings.EqualFold(values[i].String, "Y") {
+			result[v.privilege] = true
+		}
+	}
+
+	return result, nil
+}
+
+func (r *mysqlReconcileTask) getDatabasesWhereUserHasPermissions(account mysqlAccount) ([]string, error) {
 	var dbNames []string
 
-	if r.username == "" {
+	if account.username == "" {
 		return dbNames, nil
 	}
 
-	result, err := r.connection.Query("SELECT Db FROM db WHERE User = ? AND Host = ?", r.username, "%")
+	result, err := r.connection.QueryContext(
+		r.ctx,
+		"SELECT Db FROM db WHERE User = ? AND Host = ?",
+		account.username,
+		account.host,
+	)
 
 	if err != nil {
 		return dbNames, err
@@ -274,13 +538,20 @@ func (r *mysqlReconcileTask) getDatabasesWhereUserHasPermissions() ([]string, er
 	return dbNames, nil
 }
 
-func (r *mysqlReconcileTask) revokePermissionOnDatabase(dbName string) error {
+func (r *mysqlReconcileTask) revokePermissionOnDatabase(account mysqlAccount, dbName string) error {
 	if dbName == "" {
 		return nil
 	}
 
-	r.logger.Info("Revoking all privileges on db " + dbName)
-	_, err := r.connection.Exec(fmt.Sprintf("REVOKE ALL ON `%s`.* FROM '%s'@'%%'", dbName, r.username))
+	r.logger.Info("Revoking all privileges on db " + dbName + " from " + account.username)
+	_, err := r.connection.ExecContext(
+		r.ctx,
+		fmt.Sprintf(
+			"REVOKE ALL, GRANT OPTION ON `%s`.* FROM %s",
+			dbName,
+			makeMysqlAccountName(account.username, account.host),
+		),
+	)
 
 	if err != nil {
 		return err
@@ -289,45 +560,60 @@ func (r *mysqlReconcileTask) revokePermissionOnDatabase(dbName string) error {
 	return nil
 }
 
-func (r *mysqlReconcileTask) revokeNotRequestedPermissions() (bool, error) {
-	hasPermissionsOnTaskDatabase := false
-	dbNames, err := r.getDatabasesWhereUserHasPermissions()
+func (r *mysqlReconcileTask) revokeNotRequestedPermissions(account mysqlAccount) error {
+	dbNames, err := r.getDatabasesWhereUserHasPermissions(account)
 
 	if err != nil {
-		return hasPermissionsOnTaskDatabase, err
+		return err
 	}
 
 	for _, dbName := range dbNames {
-		if dbName == r.database {
-			hasPermissionsOnTaskDatabase = true
-		} else {
-			if err = r.revokePermissionOnDatabase(dbName); err != nil {
-				return hasPermissionsOnTaskDatabase, err
+		if dbName != r.database {
+			if err = r.revokePermissionOnDatabase(account, dbName); err != nil {
+				return err
 			}
 		}
 	}
 
-	return hasPermissionsOnTaskDatabase, nil
+	return nil
 }
 
-func (r *mysqlReconcileTask) createUser() error {
-	_, err := r.connection.Query(fmt.Sprintf("CREATE USER '%s'@'%%' IDENTIFIED BY '%s'", r.username, r.password))
+func (r *mysqlReconcileTask) createUser(account mysqlAccount) error {
+	_, err := r.connection.ExecContext(
+		r.ctx,
+		fmt.Sprintf(
+			"CREATE USER %s %s",
+			makeMysqlAccountName(account.username, account.host),
+			makeMysqlIdentifiedClause(r.authenticationPlugin, account.password),
+		),
+	)
 
 	if err != nil {
 		return err
 	}
 
-	_, err = r.connection.Query("FLUSH PRIVILEGES")
+	_, err = r.connection.ExecContext(r.ctx, "FLUSH PRIVILEGES")
 
 	return err
 }
 
-func (r *mysqlReconcileTask) getUser(user *mysqlUserResult) error {
-	err := r.connection.QueryRow(
-		"SELECT User, authentication_string from user WHERE User = ? AND Host = ?",
-		r.username,
-		"%",
-	).Scan(&user.User, &user.PasswordHash)
+// makeMysqlIdentifiedClause returns the IDENTIFIED clause of the CREATE USER and ALTER USER statements. The server
+// default plugin is used if the authentication plugin is empty
+func makeMysqlIdentifiedClause(authenticationPlugin string, password string) string {
+	if authenticationPlugin == "" {
+		return fmt.Sprintf("IDENTIFIED BY '%s'", password)
+	}
+
+	return fmt.Sprintf("IDENTIFIED WITH %s BY '%s'", authenticationPlugin, password)
+}
+
+func (r *mysqlReconcileTask) getUser(account mysqlAccount, user *mysqlUserResult) error {
+	err := r.connection.QueryRowContext(
+		r.ctx,
+		"SELECT User, plugin, authentication_string from user WHERE User = ? AND Host = ?",
+		account.username,
+		account.host,
+	).Scan(&user.User, &user.Plugin, &user.PasswordHash)
 
 	if err != nil && err != sql.ErrNoRows {
 		return err
@@ -336,55 +622,28 @@ func (r *mysqlReconcileTask) getUser(user *mysqlUserResult) error {
 	return nil
 }
 
-func (r *mysqlReconcileTask) changePassword() error {
-	_, err := r.connection.Query(fmt.Sprintf("ALTER USER '%s'@'%%' IDENTIFIED BY '%s'", r.username, r.password))
+func (r *mysqlReconcileTask) changePassword(account mysqlAccount) error {
+	_, err := r.connection.ExecContext(
+		r.ctx,
+		fmt.Sprintf(
+			"ALTER USER %s %s",
+			makeMysqlAccountName(account.username, account.host),
+			makeMysqlIdentifiedClause(r.authenticationPlugin, account.password),
+		),
+	)
 
 	if err != nil {
 		return err
 	}
 
-	_, err = r.connection.Query("FLUSH PRIVILEGES")
+	_, err = r.connection.ExecContext(r.ctx, "FLUSH PRIVILEGES")
 
 	return err
 }
 
 func (r *mysqlReconcileTask) removeDatabase() error {
 	r.logger.Info("Dropping database if it exists")
-	_, err := r.connection.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", r.database))
+	_, err := r.connection.ExecContext(r.ctx, fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", r.database))
 
 	return err
 }
-
-func (r *mysqlReconcileTask) revokePermissions() error {
-	dbNames, err := r.getDatabasesWhereUserHasPermissions()
-
-	if err != nil {
-		return err
-	}
-
-	for _, dbName := range dbNames {
-		if err := r.revokePermissionOnDatabase(dbName); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (r *mysqlReconcileTask) checkUserCanLogin() bool {
-	dataSourceName := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/mysql",
-		r.username,
-		r.password,
-		r.host,
-		r.port,
-	)
-
-	connection, err := sql.Open("mysql", dataSourceName)
-	if err != nil {
-		return false
-	}
-	defer func() { _ = connection.Close() }()
-
-	return connection.Ping() == nil
-}
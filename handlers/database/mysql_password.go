@@ -0,0 +1,187 @@
+package database
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	mysqlNativePasswordPlugin      = "mysql_native_password"
+	mysqlCachingSha2PasswordPlugin = "caching_sha2_password"
+
+	// caching_sha2_password authentication strings are $A$<rounds / 1000 as 3 digits>$<20 byte salt><43 byte digest>
+	mysqlCachingSha2Prefix       = "$A$"
+	mysqlCachingSha2SaltLength   = 20
+	mysqlCachingSha2DigestLength = 43
+
+	cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// mysqlPasswordCheckResult is the result of checking a password against the authentication string of an account
+type mysqlPasswordCheckResult int
+
+const (
+	mysqlPasswordCorrect mysqlPasswordCheckResult = iota
+	mysqlPasswordIncorrect
+	// The password can't be checked, as the account uses an unsupported authentication plugin
+	mysqlPasswordUnknown
+)
+
+// checkMysqlPassword checks if the authentication string stored for an account in the mysql.user table belongs to the
+// password. The password is checked against the stored hash instead of logging in, as the host pattern of the account
+// may not allow connections from the operator. Only the mysql_native_password and caching_sha2_password plugins are
+// supported, mysqlPasswordUnknown is returned for the others.
+func checkMysqlPassword(plugin string, authenticationString string, password string) mysqlPasswordCheckResult {
+	var expected string
+	switch plugin {
+	case mysqlNativePasswordPlugin:
+		expected = makeMysqlNativePasswordHash(password)
+	case mysqlCachingSha2PasswordPlugin:
+		expected = makeMysqlCachingSha2PasswordHash(authenticationString, password)
+	default:
+		return mysqlPasswordUnknown
+	}
+
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(authenticationString)) != 1 {
+		return mysqlPasswordIncorrect
+	}
+
+	return mysqlPasswordCorrect
+}
+
+// unverifiableMysqlPasswords holds the accounts that were already logged as having a password that can't be checked
+var unverifiableMysqlPasswords sync.Map
+
+// isFirstUnverifiableMysqlPassword returns TRUE the first time it's called for the account and plugin, so the accounts
+// with an unsupported plugin are only logged once instead of on every reconcile
+func isFirstUnverifiableMysqlPassword(environment string, accountName string, plugin string) bool {
+	_, loaded := unverifiableMysqlPasswords.LoadOrStore(environment+"/"+accountName+"/"+plugin, struct{}{})
+
+	return !loaded
+}
+
+// makeMysqlNativePasswordHash returns the mysql_native_password hash of the password, which is
+// '*' + HEX(SHA1(SHA1(password)))
+func makeMysqlNativePasswordHash(password string) string {
+	if password == "" {
+		return ""
+	}
+
+	first := sha1.Sum([]byte(password))
+	second := sha1.Sum(first[:])
+
+	return "*" + strings.ToUpper(hex.EncodeToString(second[:]))
+}
+
+// makeMysqlCachingSha2PasswordHash returns the caching_sha2_password authentication string of the password, using the
+// salt and rounds of the stored authentication string. Returns an empty string if the stored value can't be parsed.
+func makeMysqlCachingSha2PasswordHash(authenticationString string, password string) string {
+	if password == "" {
+		return ""
+	}
+
+	headerLength := len(mysqlCachingSha2Prefix) + 4
+	if len(authenticationString) != headerLength+mysqlCachingSha2SaltLength+mysqlCachingSha2DigestLength ||
+		!strings.HasPrefix(authenticationString, mysqlCachingSha2Prefix) ||
+		authenticationString[headerLength-1] != '$' {
+		return ""
+	}
+
+	rounds, err := strconv.ParseInt(authenticationString[len(mysqlCachingSha2Prefix):headerLength-1], 16, 32)
+	if err != nil || rounds < 1 {
+		return ""
+	}
+	salt := authenticationString[headerLength : headerLength+mysqlCachingSha2SaltLength]
+
+	return authenticationString[:headerLength] + salt + sha256Crypt([]byte(password), []byte(salt), int(rounds)*1000)
+}
+
+// sha256Crypt returns the encoded digest of the SHA-256 based crypt algorithm (as used by glibc and by mysql for the
+// caching_sha2_password plugin), without the salt and rounds prefix
+func sha256Crypt(password []byte, salt []byte, rounds int) string {
+	alternate := sha256.New()
+	alternate.Write(password)
+	alternate.Write(salt)
+	alternate.Write(password)
+	alternateSum := alternate.Sum(nil)
+
+	digest := sha256.New()
+	digest.Write(password)
+	digest.Write(salt)
+	digest.Write(repeatBytes(alternateSum, len(password)))
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			digest.Write(alternateSum)
+		} else {
+			digest.Write(password)
+		}
+	}
+	result := digest.Sum(nil)
+
+	passwordDigest := sha256.New()
+	for range password {
+		passwordDigest.Write(password)
+	}
+	passwordSequence := repeatBytes(passwordDigest.Sum(nil), len(password))
+
+	saltDigest := sha256.New()
+	for i := 0; i < 16+int(result[0]); i++ {
+		saltDigest.Write(salt)
+	}
+	saltSequence := repeatBytes(saltDigest.Sum(nil), len(salt))
+
+	for i := 0; i < rounds; i++ {
+		round := sha256.New()
+		if i&1 != 0 {
+			round.Write(passwordSequence)
+		} else {
+			round.Write(result)
+		}
+		if i%3 != 0 {
+			round.Write(saltSequence)
+		}
+		if i%7 != 0 {
+			round.Write(passwordSequence)
+		}
+		if i&1 != 0 {
+			round.Write(result)
+		} else {
+			round.Write(passwordSequence)
+		}
+		result = round.Sum(nil)
+	}
+
+	var encoded strings.Builder
+	for _, group := range [][3]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+	} {
+		encodeCryptBase64(&encoded, result[group[0]], result[group[1]], result[group[2]], 4)
+	}
+	encodeCryptBase64(&encoded, 0, result[31], result[30], 3)
+
+	return encoded.String()
+}
+
+// repeatBytes returns the value repeated to the given length
+func repeatBytes(value []byte, length int) []byte {
+	result := make([]byte, 0, length)
+	for len(result) < length {
+		result = append(result, value[:min(len(value), length-len(result))]...)
+	}
+
+	return result
+}
+
+func encodeCryptBase64(encoded *strings.Builder, b2 byte, b1 byte, b0 byte, length int) {
+	value := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for i := 0; i < length; i++ {
+		encoded.WriteByte(cryptAlphabet[value&0x3f])
+		value >>= 6
+	}
+}
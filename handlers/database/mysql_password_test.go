@@ -0,0 +1,83 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSha256Crypt(t *testing.T) {
+	// Test vectors verified with openssl passwd -5
+	tests := []struct {
+		password string
+		salt     string
+		rounds   int
+		expected string
+	}{
+		{"Hello world!", "saltstring", 5000, "5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5"},
+		{"Hello world!", "saltstringsaltst", 10000, "3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA"},
+	}
+
+	for _, tt := range tests {
+		if got := sha256Crypt([]byte(tt.password), []byte(tt.salt), tt.rounds); got != tt.expected {
+			t.Errorf("sha256Crypt(%q, %q, %d) = %q, want %q", tt.password, tt.salt, tt.rounds, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckMysqlPassword(t *testing.T) {
+	cachingSha2Hash := "$A$005$" + "0123456789abcdefghij" + strings.Repeat(".", mysqlCachingSha2DigestLength)
+	cachingSha2Hash = makeMysqlCachingSha2PasswordHash(cachingSha2Hash, "secret")
+
+	tests := []struct {
+		name                 string
+		plugin               string
+		authenticationString string
+		password             string
+		expected             mysqlPasswordCheckResult
+	}{
+		{"native", mysqlNativePasswordPlugin, "*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19", "password", mysqlPasswordCorrect},
+		{"native wrong password", mysqlNativePasswordPlugin, "*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19", "other", mysqlPasswordIncorrect},
+		{"caching sha2", mysqlCachingSha2PasswordPlugin, cachingSha2Hash, "secret", mysqlPasswordCorrect},
+		{"caching sha2 wrong password", mysqlCachingSha2PasswordPlugin, cachingSha2Hash, "other", mysqlPasswordIncorrect},
+		{"caching sha2 invalid hash", mysqlCachingSha2PasswordPlugin, "$A$005$short", "secret", mysqlPasswordIncorrect},
+		{"empty password", mysqlNativePasswordPlugin, "", "", mysqlPasswordIncorrect},
+		{"unsupported plugin", "auth_socket", "", "secret", mysqlPasswordUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkMysqlPassword(tt.plugin, tt.authenticationString, tt.password); got != tt.expected {
+				t.Errorf("checkMysqlPassword() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if len(cachingSha2Hash) != 7+mysqlCachingSha2SaltLength+mysqlCachingSha2DigestLength {
+		t.Errorf("caching sha2 hash %q has an unexpected length", cachingSha2Hash)
+	}
+}
+
+func TestIsFirstUnverifiableMysqlPassword(t *testing.T) {
+	if !isFirstUnverifiableMysqlPassword("test-ns/mysql1", "'site_web'@'%'", "auth_socket") {
+		t.Error("expected the first call to return true")
+	}
+	if isFirstUnverifiableMysqlPassword("test-ns/mysql1", "'site_web'@'%'", "auth_socket") {
+		t.Error("expected the account to be logged only once")
+	}
+	if !isFirstUnverifiableMysqlPassword("test-ns/mysql1", "'site_web'@'%'", "sha256_password") {
+		t.Error("expected a plugin change to be logged again")
+	}
+	if !isFirstUnverifiableMysqlPassword("other-ns/mysql1", "'site_web'@'%'", "auth_socket") {
+		t.Error("expected the account of another environment to be logged")
+	}
+}
+
+func TestMakeMysqlIdentifiedClause(t *testing.T) {
+	if got := makeMysqlIdentifiedClause("", "secret"); got != "IDENTIFIED BY 'secret'" {
+		t.Errorf("makeMysqlIdentifiedClause() = %q, want the server default plugin", got)
+	}
+	if got := makeMysqlIdentifiedClause(mysqlNativePasswordPlugin, "secret"); got !=
+		"IDENTIFIED WITH mysql_native_password BY 'secret'" {
+		t.Errorf("makeMysqlIdentifiedClause() = %q, want the configured plugin", got)
+	}
+}
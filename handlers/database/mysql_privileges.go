@@ -0,0 +1,128 @@
+package database
+
+import (
+	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"sort"
+	"strings"
+)
+
+const mysqlGrantOption = "GRANT OPTION"
+
+// mysqlPrivilegeColumns maps the database level privileges to their columns in the mysql.db table, in the order they
+// are listed in GRANT statements
+var mysqlPrivilegeColumns = []struct {
+	privilege string
+	column    string
+}{
+	{"SELECT", "Select_priv"},
+	{"INSERT", "Insert_priv"},
+	{"UPDATE", "Update_priv"},
+	{"DELETE", "Delete_priv"},
+	{"CREATE", "Create_priv"},
+	{"DROP", "Drop_priv"},
+	{"REFERENCES", "References_priv"},
+	{"INDEX", "Index_priv"},
+	{"ALTER", "Alter_priv"},
+	{"CREATE TEMPORARY TABLES", "Create_tmp_table_priv"},
+	{"LOCK TABLES", "Lock_tables_priv"},
+	{"EXECUTE", "Execute_priv"},
+	{"CREATE VIEW", "Create_view_priv"},
+	{"SHOW VIEW", "Show_view_priv"},
+	{"CREATE ROUTINE", "Create_routine_priv"},
+	{"ALTER ROUTINE", "Alter_routine_priv"},
+	{"EVENT", "Event_priv"},
+	{"TRIGGER", "Trigger_priv"},
+	{mysqlGrantOption, "Grant_priv"},
+}
+
+// normaliseMysqlPrivileges expands the requested privileges to the set of individual privileges. An empty list
+// means ALL, which is every privilege except GRANT OPTION, matching the behaviour of GRANT ALL.
+func normaliseMysqlPrivileges(privileges []configv1.MysqlPrivilege) (map[string]bool, error) {
+	result := make(map[string]bool)
+
+	if len(privileges) == 0 {
+		privileges = []configv1.MysqlPrivilege{configv1.MysqlPrivilegeAll}
+	}
+
+	for _, privilege := range privileges {
+		name := strings.Join(strings.Fields(strings.ToUpper(string(privilege))), " ")
+		if name == string(configv1.MysqlPrivilegeAll) || name == "ALL PRIVILEGES" {
+			for _, v := range mysqlPrivilegeColumns {
+				if v.privilege != mysqlGrantOption {
+					result[v.privilege] = true
+				}
+			}
+			continue
+		}
+		if !isKnownMysqlPrivilege(name) {
+			return nil, fmt.Errorf("unsupported mysql privilege: %s", privilege)
+		}
+		result[name] = true
+	}
+
+	return result, nil
+}
+
+func isKnownMysqlPrivilege(name string) bool {
+	for _, v := range mysqlPrivilegeColumns {
+		if v.privilege == name {
+			return true
+		}
+	}
+	return false
+}
+
+// diffMysqlPrivileges returns the privileges that need to be granted and revoked to get from the current set to the
+// expected one, in GRANT statement order
+func diffMysqlPrivileges(current map[string]bool, expected map[string]bool) (toGrant []string, toRevoke []string) {
+	for _, v := range mysqlPrivilegeColumns {
+		if expected[v.privilege] && !current[v.privilege] {
+			toGrant = append(toGrant, v.privilege)
+		}
+		if current[v.privilege] && !expected[v.privilege] {
+			toRevoke = append(toRevoke, v.privilege)
+		}
+	}
+	return toGrant, toRevoke
+}
+
+// makeMysqlGrantStatement builds the GRANT statement for the privileges. GRANT OPTION can't be listed as a privilege
+// in a GRANT statement, so it's converted to a WITH GRANT OPTION clause.
+func makeMysqlGrantStatement(privileges []string, database string, account string) string {
+	withGrantOption := false
+	var listed []string
+	for _, privilege := range privileges {
+		if privilege == mysqlGrantOption {
+			withGrantOption = true
+		} else {
+			listed = append(listed, privilege)
+		}
+	}
+	if len(listed) == 0 {
+		listed = []string{"USAGE"}
+	}
+
+	statement := fmt.Sprintf("GRANT %s ON `%s`.* TO %s", strings.Join(listed, ", "), database, account)
+	if withGrantOption {
+		statement += " WITH GRANT OPTION"
+	}
+	return statement
+}
+
+func makeMysqlRevokeStatement(privileges []string, database string, account string) string {
+	return fmt.Sprintf("REVOKE %s ON `%s`.* FROM %s", strings.Join(privileges, ", "), database, account)
+}
+
+func makeMysqlAccountName(username string, host string) string {
+	return fmt.Sprintf("'%s'@'%s'", username, host)
+}
+
+func sortedMysqlUsernames(users []mysqlAccount) []string {
+	result := make([]string, 0, len(users))
+	for _, user := range users {
+		result = append(result, user.username)
+	}
+	sort.Strings(result)
+	return result
+}
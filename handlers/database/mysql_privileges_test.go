@@ -0,0 +1,72 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+)
+
+func TestNormaliseMysqlPrivileges(t *testing.T) {
+	all, err := normaliseMysqlPrivileges(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != len(mysqlPrivilegeColumns)-1 || all[mysqlGrantOption] {
+		t.Errorf("default privileges = %v, want every privilege except GRANT OPTION", all)
+	}
+
+	privileges, err := normaliseMysqlPrivileges([]configv1.MysqlPrivilege{"select", "CREATE  TEMPORARY TABLES"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]bool{"SELECT": true, "CREATE TEMPORARY TABLES": true}
+	if !reflect.DeepEqual(privileges, expected) {
+		t.Errorf("privileges = %v, want %v", privileges, expected)
+	}
+
+	if _, err := normaliseMysqlPrivileges([]configv1.MysqlPrivilege{"SUPER"}); err == nil {
+		t.Error("expected an error for an unsupported privilege")
+	}
+}
+
+func TestDiffMysqlPrivileges(t *testing.T) {
+	current := map[string]bool{"SELECT": true, "DROP": true, mysqlGrantOption: true}
+	expected := map[string]bool{"SELECT": true, "INSERT": true, "UPDATE": true}
+
+	toGrant, toRevoke := diffMysqlPrivileges(current, expected)
+
+	if !reflect.DeepEqual(toGrant, []string{"INSERT", "UPDATE"}) {
+		t.Errorf("toGrant = %v", toGrant)
+	}
+	if !reflect.DeepEqual(toRevoke, []string{"DROP", mysqlGrantOption}) {
+		t.Errorf("toRevoke = %v", toRevoke)
+	}
+}
+
+func TestMakeMysqlGrantStatement(t *testing.T) {
+	tests := []struct {
+		privileges []string
+		expected   string
+	}{
+		{[]string{"SELECT", "INSERT"}, "GRANT SELECT, INSERT ON `db`.* TO 'u'@'%'"},
+		{[]string{"SELECT", mysqlGrantOption}, "GRANT SELECT ON `db`.* TO 'u'@'%' WITH GRANT OPTION"},
+		{[]string{mysqlGrantOption}, "GRANT USAGE ON `db`.* TO 'u'@'%' WITH GRANT OPTION"},
+	}
+
+	for _, tt := range tests {
+		if got := makeMysqlGrantStatement(tt.privileges, "db", makeMysqlAccountName("u", "%")); got != tt.expected {
+			t.Errorf("makeMysqlGrantStatement(%v) = %q, want %q", tt.privileges, got, tt.expected)
+		}
+	}
+}
+
+func TestGetObsoleteMysqlUsernames(t *testing.T) {
+	accounts := []mysqlAccount{{username: "site_web_ro"}}
+
+	got := getObsoleteMysqlUsernames([]string{"site_web_ro", "site_web_bi"}, accounts)
+
+	if !reflect.DeepEqual(got, []string{"site_web_bi"}) {
+		t.Errorf("getObsoleteMysqlUsernames() = %v, want [site_web_bi]", got)
+	}
+}
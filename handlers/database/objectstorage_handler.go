@@ -0,0 +1,383 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sethvargo/go-password/password"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// objectStorageAccessKeyPrefix is the prefix of the access keys created for the buckets. MinIO limits access keys to
+// 20 characters, so the rest of the key is a hash of the namespace and name of the task
+const objectStorageAccessKeyPrefix = "kst"
+
+// ObjectStorageCredentials are the credentials to access a bucket with
+type ObjectStorageCredentials struct {
+	AccessKey string
+	SecretKey string
+}
+
+// ObjectStorageBackend is the subset of the S3 API used to manage the buckets of the sites
+type ObjectStorageBackend interface {
+	BucketExists(ctx context.Context, bucketName string) (bool, error)
+	MakeBucket(ctx context.Context, bucketName string) error
+	RemoveBucket(ctx context.Context, bucketName string) error
+	// RemoveObjects removes every object and object version with the prefix from the bucket
+	RemoveObjects(ctx context.Context, bucketName string, prefix string) error
+	// SetAccessKey creates the access key, or updates its secret key and policy if it already exists. The access key
+	// doesn't expire
+	SetAccessKey(ctx context.Context, credentials ObjectStorageCredentials, policy string) error
+	// RemoveAccessKey removes the access key. Removing a missing access key is not an error
+	RemoveAccessKey(ctx context.Context, accessKey string) error
+}
+
+// ReconcileObjectStorageBucket ensures that the bucket of the task exists, and that the access key in the status of
+// the task exists and can only access the bucket, or the prefix of the task in the shared bucket. The secret key is
+// generated once and kept in the status, so the credentials of the site stay stable.
+func ReconcileObjectStorageBucket(
+	ctx context.Context,
+	backend ObjectStorageBackend,
+	bucket *taskv1.ObjectStorageBucket,
+	config configv1.ObjectStorageConfig,
+	logger logr.Logger,
+) (bool, error) {
+	timer := prometheus.NewTimer(appmetrics.DatabaseOperationDuration.WithLabelValues("objectstorage", "reconcile"))
+	defer timer.ObserveDuration()
+
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
+	defer cancel()
+
+	exists, err := backend.BucketExists(ctx, bucket.Spec.BucketName)
+	if err != nil {
+		return false, operationError(errorhelpers.DatabaseTypeObjectStorage, config.Name, "reconcile", err)
+	}
+	if !exists {
+		if bucket.Spec.Prefix != "" {
+			return false, fmt.Errorf(
+				"the shared bucket %s of object storage config %s doesn't exist",
+				bucket.Spec.BucketName,
+				config.Name,
+			)
+		}
+		logger.Info("Creating bucket " + bucket.Spec.BucketName)
+		if err := backend.MakeBucket(ctx, bucket.Spec.BucketName); err != nil {
+			return false, operationError(errorhelpers.DatabaseTypeObjectStorage, config.Name, "reconcile", err)
+		}
+	}
+
+	isChanged := false
+	accessKey := MakeObjectStorageAccessKey(bucket)
+	if bucket.Status.AccessKey != accessKey || bucket.Status.SecretKey == "" {
+		secretKey, err := password.Generate(40, 10, 0, false, true)
+		if err != nil {
+			return false, err
+		}
+		bucket.Status.AccessKey = accessKey
+		bucket.Status.SecretKey = secretKey
+		isChanged = true
+	}
+
+	policy, err := MakeObjectStorageBucketPolicy(bucket.Spec.BucketName, bucket.Spec.Prefix)
+	if err != nil {
+		return false, err
+	}
+	if err := backend.SetAccessKey(
+		ctx,
+		ObjectStorageCredentials{AccessKey: bucket.Status.AccessKey, SecretKey: bucket.Status.SecretKey},
+		policy,
+	); err != nil {
+		return false, operationError(errorhelpers.DatabaseTypeObjectStorage, config.Name, "reconcile", err)
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("objectstorage", "reconcile", "success").Inc()
+
+	if bucket.Status.State != taskv1.Complete {
+		isChanged = true
+		bucket.Status.State = taskv1.Complete
+	}
+
+	return isChanged, nil
+}
+
+// DeleteObjectStorageBucket removes the access key and every object of the task, and the bucket itself unless it's
+// shared
+func DeleteObjectStorageBucket(
+	ctx context.Context,
+	backend ObjectStorageBackend,
+	bucket *taskv1.ObjectStorageBucket,
+	config configv1.ObjectStorageConfig,
+	logger logr.Logger,
+) error {
+	timer := prometheus.NewTimer(appmetrics.DatabaseOperationDuration.WithLabelValues("objectstorage", "delete"))
+	defer timer.ObserveDuration()
+
+	ctx, cancel := context.WithTimeout(ctx, config.Spec.GetOperationTimeout())
+	defer cancel()
+
+	logger.Info("Removing the access key of bucket " + bucket.Spec.BucketName)
+	if err := backend.RemoveAccessKey(ctx, MakeObjectStorageAccessKey(bucket)); err != nil {
+		return operationError(errorhelpers.DatabaseTypeObjectStorage, config.Name, "delete", err)
+	}
+
+	exists, err := backend.BucketExists(ctx, bucket.Spec.BucketName)
+	if err != nil {
+		return operationError(errorhelpers.DatabaseTypeObjectStorage, config.Name, "delete", err)
+	}
+	if !exists {
+		logger.Info("The bucket " + bucket.Spec.BucketName + " doesn't exist, nothing to delete")
+		return nil
+	}
+
+	logger.Info("Removing the objects from bucket "+bucket.Spec.BucketName, "prefix", bucket.Spec.Prefix)
+	if err := backend.RemoveObjects(ctx, bucket.Spec.BucketName, bucket.Spec.Prefix); err != nil {
+		return operationError(errorhelpers.DatabaseTypeObjectStorage, config.Name, "delete", err)
+	}
+
+	if bucket.Spec.Prefix == "" {
+		logger.Info("Removing bucket " + bucket.Spec.BucketName)
+		if err := backend.RemoveBucket(ctx, bucket.Spec.BucketName); err != nil {
+			return operationError(errorhelpers.DatabaseTypeObjectStorage, config.Name, "delete", err)
+		}
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("objectstorage", "delete", "success").Inc()
+	return nil
+}
+
+// MakeObjectStorageAccessKey returns the access key of the task, derived from its namespace and name
+func MakeObjectStorageAccessKey(bucket *taskv1.ObjectStorageBucket) string {
+	sum := sha256.Sum256([]byte(bucket.Namespace + "/" + bucket.Name))
+
+	return objectStorageAccessKeyPrefix + hex.EncodeToString(sum[:])[:17]
+}
+
+// MakeObjectStorageBucketPolicy returns an IAM policy that only allows access to the bucket, or to the prefix in it
+func MakeObjectStorageBucketPolicy(bucketName string, prefix string) (string, error) {
+	listStatement := map[string]interface{}{
+		"Effect":   "Allow",
+		"Action":   []string{"s3:GetBucketLocation", "s3:ListBucket", "s3:ListBucketMultipartUploads"},
+		"Resource": []string{"arn:aws:s3:::" + bucketName},
+	}
+	if prefix != "" {
+		listStatement["Condition"] = map[string]interface{}{
+			"StringLike": map[string]interface{}{"s3:prefix": []string{prefix + "*"}},
+		}
+	}
+
+	policy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []interface{}{
+			listStatement,
+			map[string]interface{}{
+				"Effect": "Allow",
+				"Action": []string{
+					"s3:GetObject",
+					"s3:PutObject",
+					"s3:DeleteObject",
+					"s3:GetObjectVersion",
+					"s3:DeleteObjectVersion",
+					"s3:AbortMultipartUpload",
+					"s3:ListMultipartUploadParts",
+				},
+				"Resource": []string{"arn:aws:s3:::" + bucketName + "/" + prefix + "*"},
+			},
+		},
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// LoadObjectStorageAdminCredentials returns the admin credentials referenced by the config
+func LoadObjectStorageAdminCredentials(
+	ctx context.Context,
+	reader client.Reader,
+	config configv1.ObjectStorageConfig,
+) (ObjectStorageCredentials, error) {
+	if reader == nil {
+		return ObjectStorageCredentials{}, errors.New(
+			"no reader configured to load the credentials of object storage config " + config.Name,
+		)
+	}
+
+	accessKey, err := loadSecretValue(ctx, reader, config.Namespace, config.Spec.AccessKeySecret)
+	if err != nil {
+		return ObjectStorageCredentials{}, err
+	}
+	secretKey, err := loadSecretValue(ctx, reader, config.Namespace, config.Spec.SecretKeySecret)
+	if err != nil {
+		return ObjectStorageCredentials{}, err
+	}
+
+	return ObjectStorageCredentials{AccessKey: accessKey, SecretKey: secretKey}, nil
+}
+
+func loadSecretValue(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+	selector corev1.SecretKeySelector,
+) (string, error) {
+	var secret corev1.Secret
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: selector.Name}, &secret); err != nil {
+		return "", err
+	}
+
+	value, ok := secret.Data[selector.Key]
+	if !ok {
+		return "", fmt.Errorf("the key %s doesn't exist in the secret %s", selector.Key, selector.Name)
+	}
+
+	return string(value), nil
+}
+
+// MinioObjectStorageBackend implements ObjectStorageBackend with the MinIO S3 client. The access keys are managed as
+// service accounts of the admin user with the MinIO admin API.
+type MinioObjectStorageBackend struct {
+	client      *minio.Client
+	adminClient *madmin.AdminClient
+	config      configv1.ObjectStorageConfig
+}
+
+func NewMinioObjectStorageBackend(
+	config configv1.ObjectStorageConfig,
+	adminCredentials ObjectStorageCredentials,
+) (*MinioObjectStorageBackend, error) {
+	bucketLookup := minio.BucketLookupAuto
+	if config.Spec.UsePathStyle {
+		bucketLookup = minio.BucketLookupPath
+	}
+
+	creds := credentials.NewStaticV4(adminCredentials.AccessKey, adminCredentials.SecretKey, "")
+	minioClient, err := minio.New(config.Spec.Endpoint, &minio.Options{
+		Creds:        creds,
+		Secure:       config.Spec.IsTlsEnabled(),
+		Region:       config.Spec.Region,
+		BucketLookup: bucketLookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	adminClient, err := madmin.NewWithOptions(config.Spec.Endpoint, &madmin.Options{
+		Creds:  creds,
+		Secure: config.Spec.IsTlsEnabled(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MinioObjectStorageBackend{client: minioClient, adminClient: adminClient, config: config}, nil
+}
+
+func (r *MinioObjectStorageBackend) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	return r.client.BucketExists(ctx, bucketName)
+}
+
+func (r *MinioObjectStorageBackend) MakeBucket(ctx context.Context, bucketName string) error {
+	return r.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{Region: r.config.Spec.Region})
+}
+
+func (r *MinioObjectStorageBackend) RemoveBucket(ctx context.Context, bucketName string) error {
+	return r.client.RemoveBucket(ctx, bucketName)
+}
+
+func (r *MinioObjectStorageBackend) RemoveObjects(ctx context.Context, bucketName string, prefix string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var listErr error
+	objects := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objects)
+		for object := range r.client.ListObjects(
+			ctx,
+			bucketName,
+			minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithVersions: true},
+		) {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			select {
+			case objects <- object:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for removeErr := range r.client.RemoveObjects(ctx, bucketName, objects, minio.RemoveObjectsOptions{}) {
+		return fmt.Errorf("failed to remove object %s: %w", removeErr.ObjectName, removeErr.Err)
+	}
+
+	return listErr
+}
+
+func (r *MinioObjectStorageBackend) SetAccessKey(
+	ctx context.Context,
+	credentials ObjectStorageCredentials,
+	policy string,
+) error {
+	exists, err := r.accessKeyExists(ctx, credentials.AccessKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		_, err := r.adminClient.AddServiceAccount(ctx, madmin.AddServiceAccountReq{
+			Policy:      json.RawMessage(policy),
+			AccessKey:   credentials.AccessKey,
+			SecretKey:   credentials.SecretKey,
+			Description: "kube-stager",
+		})
+		return err
+	}
+
+	return r.adminClient.UpdateServiceAccount(ctx, credentials.AccessKey, madmin.UpdateServiceAccountReq{
+		NewPolicy:    json.RawMessage(policy),
+		NewSecretKey: credentials.SecretKey,
+	})
+}
+
+func (r *MinioObjectStorageBackend) RemoveAccessKey(ctx context.Context, accessKey string) error {
+	exists, err := r.accessKeyExists(ctx, accessKey)
+	if err != nil || !exists {
+		return err
+	}
+
+	return r.adminClient.DeleteServiceAccount(ctx, accessKey)
+}
+
+// accessKeyExists returns TRUE if the access key is a service account of the admin user
+func (r *MinioObjectStorageBackend) accessKeyExists(ctx context.Context, accessKey string) (bool, error) {
+	accounts, err := r.adminClient.ListServiceAccounts(ctx, "")
+	if err != nil {
+		return false, err
+	}
+
+	for _, account := range accounts.Accounts {
+		if account.AccessKey == accessKey {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
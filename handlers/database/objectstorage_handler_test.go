@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeObjectStorageBackend is an in-process ObjectStorageBackend keeping the object names per bucket, and the secret
+// key and policy per access key
+type fakeObjectStorageBackend struct {
+	buckets    map[string][]string
+	secretKeys map[string]string
+	policies   map[string]string
+}
+
+func newFakeObjectStorageBackend() *fakeObjectStorageBackend {
+	return &fakeObjectStorageBackend{
+		buckets:    map[string][]string{},
+		secretKeys: map[string]string{},
+		policies:   map[string]string{},
+	}
+}
+
+func (r *fakeObjectStorageBackend) BucketExists(_ context.Context, bucketName string) (bool, error) {
+	_, ok := r.buckets[bucketName]
+	return ok, nil
+}
+
+func (r *fakeObjectStorageBackend) MakeBucket(_ context.Context, bucketName string) error {
+	r.buckets[bucketName] = []string{}
+	return nil
+}
+
+func (r *fakeObjectStorageBackend) RemoveBucket(_ context.Context, bucketName string) error {
+	delete(r.buckets, bucketName)
+	return nil
+}
+
+func (r *fakeObjectStorageBackend) RemoveObjects(_ context.Context, bucketName string, prefix string) error {
+	var remaining []string
+	for _, object := range r.buckets[bucketName] {
+		if !strings.HasPrefix(object, prefix) {
+			remaining = append(remaining, object)
+		}
+	}
+	r.buckets[bucketName] = remaining
+	return nil
+}
+
+func (r *fakeObjectStorageBackend) SetAccessKey(
+	_ context.Context,
+	credentials ObjectStorageCredentials,
+	policy string,
+) error {
+	r.secretKeys[credentials.AccessKey] = credentials.SecretKey
+	r.policies[credentials.AccessKey] = policy
+	return nil
+}
+
+func (r *fakeObjectStorageBackend) RemoveAccessKey(_ context.Context, accessKey string) error {
+	delete(r.secretKeys, accessKey)
+	delete(r.policies, accessKey)
+	return nil
+}
+
+func newObjectStorageTestConfig() configv1.ObjectStorageConfig {
+	return configv1.ObjectStorageConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "default"},
+		Spec: configv1.ObjectStorageConfigSpec{
+			Endpoint:        "s3.example.com:9000",
+			AccessKeySecret: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "s3"}, Key: "accessKey"},
+			SecretKeySecret: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "s3"}, Key: "secretKey"},
+		},
+	}
+}
+
+func newObjectStorageTestBucket() *taskv1.ObjectStorageBucket {
+	return &taskv1.ObjectStorageBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "site-web", Namespace: "default"},
+		Spec: taskv1.ObjectStorageBucketSpec{
+			EnvironmentConfig: taskv1.EnvironmentConfig{SiteName: "site", ServiceName: "web", Environment: "s3"},
+			BucketName:        "site-web",
+		},
+	}
+}
+
+func TestReconcileObjectStorageBucket_CreatesBucketAndAccessKey(t *testing.T) {
+	backend := newFakeObjectStorageBackend()
+	bucket := newObjectStorageTestBucket()
+
+	changed, err := ReconcileObjectStorageBucket(context.Background(), backend, bucket, newObjectStorageTestConfig(), logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected the status to change")
+	}
+	if _, ok := backend.buckets["site-web"]; !ok {
+		t.Error("expected the bucket to be created")
+	}
+	if bucket.Status.State != taskv1.Complete {
+		t.Errorf("state = %s, want %s", bucket.Status.State, taskv1.Complete)
+	}
+	accessKey := MakeObjectStorageAccessKey(bucket)
+	if bucket.Status.AccessKey != accessKey || bucket.Status.SecretKey == "" {
+		t.Errorf("credentials = %s/%s, want the access key %s", bucket.Status.AccessKey, bucket.Status.SecretKey, accessKey)
+	}
+	if backend.secretKeys[accessKey] != bucket.Status.SecretKey {
+		t.Error("expected the access key to be created with the secret key in the status")
+	}
+	if !strings.Contains(backend.policies[accessKey], "arn:aws:s3:::site-web") {
+		t.Errorf("policy = %s, want a policy scoped to the bucket", backend.policies[accessKey])
+	}
+
+	// The secret key is kept, and the access key is recreated if it's missing from the backend
+	secretKey := bucket.Status.SecretKey
+	delete(backend.secretKeys, accessKey)
+	changed, err = ReconcileObjectStorageBucket(context.Background(), backend, bucket, newObjectStorageTestConfig(), logr.Discard())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed {
+		t.Error("expected no change on the second reconcile")
+	}
+	if bucket.Status.SecretKey != secretKey || backend.secretKeys[accessKey] != secretKey {
+		t.Error("expected the secret key to be kept")
+	}
+}
+
+func TestReconcileObjectStorageBucket_MissingSharedBucket(t *testing.T) {
+	backend := newFakeObjectStorageBackend()
+	bucket := newObjectStorageTestBucket()
+	bucket.Spec.BucketName = "shared"
+	bucket.Spec.Prefix = "site/web/"
+
+	if _, err := ReconcileObjectStorageBucket(
+		context.Background(),
+		backend,
+		bucket,
+		newObjectStorageTestConfig(),
+		logr.Discard(),
+	); err == nil {
+		t.Error("expected an error for a missing shared bucket")
+	}
+	if _, ok := backend.buckets["shared"]; ok {
+		t.Error("expected the shared bucket not to be created")
+	}
+}
+
+func TestMakeObjectStorageAccessKey(t *testing.T) {
+	bucket := newObjectStorageTestBucket()
+	accessKey := MakeObjectStorageAccessKey(bucket)
+	if len(accessKey) != 20 || !strings.HasPrefix(accessKey, "kst") {
+		t.Errorf("access key = %s, want 20 characters with the kst prefix", accessKey)
+	}
+
+	other := newObjectStorageTestBucket()
+	other.Namespace = "other"
+	if MakeObjectStorageAccessKey(other) == accessKey {
+		t.Error("expected different access keys for tasks in different namespaces")
+	}
+}
+
+func TestDeleteObjectStorageBucket(t *testing.T) {
+	backend := newFakeObjectStorageBackend()
+	backend.buckets["site-web"] = []string{"a.jpg", "b/c.jpg"}
+	backend.buckets["shared"] = []string{"site/web/a.jpg", "other/web/a.jpg"}
+
+	bucket := newObjectStorageTestBucket()
+	backend.secretKeys[MakeObjectStorageAccessKey(bucket)] = "secret"
+	if err := DeleteObjectStorageBucket(context.Background(), backend, bucket, newObjectStorageTestConfig(), logr.Discard()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := backend.buckets["site-web"]; ok {
+		t.Error("expected the bucket to be removed")
+	}
+	if _, ok := backend.secretKeys[MakeObjectStorageAccessKey(bucket)]; ok {
+		t.Error("expected the access key to be removed")
+	}
+
+	sharedBucket := newObjectStorageTestBucket()
+	sharedBucket.Spec.BucketName = "shared"
+	sharedBucket.Spec.Prefix = "site/web/"
+	if err := DeleteObjectStorageBucket(context.Background(), backend, sharedBucket, newObjectStorageTestConfig(), logr.Discard()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if objects := backend.buckets["shared"]; len(objects) != 1 || objects[0] != "other/web/a.jpg" {
+		t.Errorf("shared bucket objects = %v, want only the objects of other sites", objects)
+	}
+
+	// Deleting an already removed bucket succeeds
+	if err := DeleteObjectStorageBucket(context.Background(), backend, bucket, newObjectStorageTestConfig(), logr.Discard()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMakeObjectStorageBucketPolicy(t *testing.T) {
+	policy, err := MakeObjectStorageBucketPolicy("shared", "site/web/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Statement []struct {
+			Resource  []string
+			Condition map[string]map[string][]string
+		}
+	}
+	if err := json.Unmarshal([]byte(policy), &parsed); err != nil {
+		t.Fatalf("invalid policy JSON: %v", err)
+	}
+	if len(parsed.Statement) != 2 {
+		t.Fatalf("statements = %d, want 2", len(parsed.Statement))
+	}
+	if got := parsed.Statement[0].Condition["StringLike"]["s3:prefix"]; len(got) != 1 || got[0] != "site/web/*" {
+		t.Errorf("list prefix condition = %v, want [site/web/*]", got)
+	}
+	if got := parsed.Statement[1].Resource; len(got) != 1 || got[0] != "arn:aws:s3:::shared/site/web/*" {
+		t.Errorf("object resource = %v, want [arn:aws:s3:::shared/site/web/*]", got)
+	}
+
+	policy, err = MakeObjectStorageBucketPolicy("site-web", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(policy, "Condition") {
+		t.Error("expected no prefix condition for a dedicated bucket")
+	}
+}
+
+func TestLoadObjectStorageAdminCredentials(t *testing.T) {
+	config := newObjectStorageTestConfig()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: "default"},
+		Data:       map[string][]byte{"accessKey": []byte("admin"), "secretKey": []byte("admin-secret")},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	got, err := LoadObjectStorageAdminCredentials(context.Background(), reader, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.AccessKey != "admin" || got.SecretKey != "admin-secret" {
+		t.Errorf("credentials = %s/%s, want admin/admin-secret", got.AccessKey, got.SecretKey)
+	}
+
+	config.Spec.SecretKeySecret.Key = "missing"
+	if _, err := LoadObjectStorageAdminCredentials(context.Background(), reader, config); err == nil {
+		t.Error("expected an error for a missing secret key")
+	}
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/helpers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OrphanCandidate is a database or user on an environment server that was created by the operator, but isn't
+// referenced by any database task
+type OrphanCandidate struct {
+	Type       configv1.OrphanedResourceType
+	Name       string
+	ReportOnly bool
+}
+
+// FindOrphans returns the databases and users on the server that were recorded as managed by the operator, but aren't
+// referenced anymore. The names that weren't recorded, but match the naming scheme of the operator for one of the
+// service short names (eg. the databases created before the sweeper was enabled) are returned as report only
+// candidates, that are never dropped. Other names are never returned, so the other users and databases of the server
+// (eg. replication or monitoring users) are never touched
+func FindOrphans(
+	databases []string,
+	users []string,
+	managed *configv1.ManagedResources,
+	referencedDatabases map[string]bool,
+	referencedUsers map[string]bool,
+	shortNames []string,
+	sweeper *configv1.OrphanSweeperSpec,
+) []OrphanCandidate {
+	var result []OrphanCandidate
+
+	if managed == nil {
+		managed = &configv1.ManagedResources{}
+	}
+
+	collect := func(
+		names []string,
+		managedNames []string,
+		referenced map[string]bool,
+		resourceType configv1.OrphanedResourceType,
+	) {
+		for _, name := range names {
+			if referenced[name] || sweeper.IsExcluded(name) {
+				continue
+			}
+			if slices.Contains(managedNames, name) {
+				result = append(result, OrphanCandidate{Type: resourceType, Name: name})
+			} else if MatchesNamingScheme(name, shortNames) {
+				result = append(result, OrphanCandidate{Type: resourceType, Name: name, ReportOnly: true})
+			}
+		}
+	}
+
+	collect(databases, managed.Databases, referencedDatabases, configv1.OrphanedResourceTypeDatabase)
+	collect(users, managed.Users, referencedUsers, configv1.OrphanedResourceTypeUser)
+
+	return result
+}
+
+// MatchesNamingScheme returns TRUE if the name has the format of the database names and usernames generated by the
+// operator for one of the service short names: the database name or username of the site and the short name joined
+// with an underscore, optionally followed by the name of an additional user. Names shortened to a hash can't be
+// recognised
+func MatchesNamingScheme(name string, shortNames []string) bool {
+	for _, shortName := range shortNames {
+		suffix := "_" + helpers.SanitiseDbValue(shortName)
+		if suffix == "_" {
+			continue
+		}
+		if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+			return true
+		}
+		// The additional users of mysql have their name after the short name
+		if index := strings.Index(name, suffix+"_"); index > 0 && index+len(suffix)+1 < len(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RecordManagedResources returns the managed resources extended with the names used by the database tasks of the
+// environment
+func RecordManagedResources(
+	managed *configv1.ManagedResources,
+	databases []string,
+	users []string,
+) *configv1.ManagedResources {
+	result := &configv1.ManagedResources{}
+	if managed != nil {
+		result.Databases = append(result.Databases, managed.Databases...)
+		result.Users = append(result.Users, managed.Users...)
+	}
+	result.Databases = mergeNames(result.Databases, databases)
+	result.Users = mergeNames(result.Users, users)
+
+	return result
+}
+
+// PruneManagedResources returns the managed resources without the names that are no longer on the server and aren't
+// referenced by any database task
+func PruneManagedResources(
+	managed *configv1.ManagedResources,
+	databases []string,
+	users []string,
+	dropped []configv1.OrphanedResource,
+	referencedDatabases map[string]bool,
+	referencedUsers map[string]bool,
+) *configv1.ManagedResources {
+	if managed == nil {
+		return nil
+	}
+
+	isDropped := func(resourceType configv1.OrphanedResourceType, name string) bool {
+		return slices.ContainsFunc(dropped, func(orphan configv1.OrphanedResource) bool {
+			return orphan.Type == resourceType && orphan.Name == name
+		})
+	}
+	prune := func(
+		names []string,
+		existing []string,
+		referenced map[string]bool,
+		resourceType configv1.OrphanedResourceType,
+	) []string {
+		var result []string
+		for _, name := range names {
+			if referenced[name] || (slices.Contains(existing, name) && !isDropped(resourceType, name)) {
+				result = append(result, name)
+			}
+		}
+		return result
+	}
+
+	return &configv1.ManagedResources{
+		Databases: prune(managed.Databases, databases, referencedDatabases, configv1.OrphanedResourceTypeDatabase),
+		Users:     prune(managed.Users, users, referencedUsers, configv1.OrphanedResourceTypeUser),
+	}
+}
+
+func mergeNames(names []string, additional []string) []string {
+	for _, name := range additional {
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// MergeOrphans builds the new orphan list from the current candidates, keeping the first seen time of the orphans that
+// were already detected by a previous sweep
+func MergeOrphans(
+	previous []configv1.OrphanedResource,
+	candidates []OrphanCandidate,
+	now time.Time,
+) []configv1.OrphanedResource {
+	firstSeen := make(map[OrphanCandidate]metav1.Time, len(previous))
+	for _, orphan := range previous {
+		firstSeen[OrphanCandidate{Type: orphan.Type, Name: orphan.Name}] = orphan.FirstSeen
+	}
+
+	result := make([]configv1.OrphanedResource, 0, len(candidates))
+	for _, candidate := range candidates {
+		seen, ok := firstSeen[OrphanCandidate{Type: candidate.Type, Name: candidate.Name}]
+		if !ok {
+			seen = metav1.NewTime(now)
+		}
+		result = append(result, configv1.OrphanedResource{
+			Type:       candidate.Type,
+			Name:       candidate.Name,
+			FirstSeen:  seen,
+			ReportOnly: candidate.ReportOnly,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// IsOrphanExpired returns TRUE if the orphan has been detected for longer than the grace period
+func IsOrphanExpired(orphan configv1.OrphanedResource, gracePeriod time.Duration, now time.Time) bool {
+	return !orphan.FirstSeen.Add(gracePeriod).After(now)
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindOrphans(t *testing.T) {
+	sweeper := &configv1.OrphanSweeperSpec{Enabled: true, ExcludedNames: []string{"keep_web"}}
+	managed := &configv1.ManagedResources{
+		Databases: []string{"live_web", "dead_web", "keep_web"},
+		Users:     []string{"live_web", "dead_web"},
+	}
+
+	got := FindOrphans(
+		[]string{"live_web", "dead_web", "keep_web", "unrelated", "other_web"},
+		[]string{"live_web", "dead_web", "app", "replicator", "monitoring"},
+		managed,
+		map[string]bool{"live_web": true},
+		map[string]bool{"live_web": true},
+		[]string{"web"},
+		sweeper,
+	)
+
+	expected := []OrphanCandidate{
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "dead_web"},
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "other_web", ReportOnly: true},
+		{Type: configv1.OrphanedResourceTypeUser, Name: "dead_web"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("FindOrphans() = %v, want %v", got, expected)
+	}
+}
+
+func TestFindOrphans_UnrecordedNames(t *testing.T) {
+	// Names that look like the generated ones are only reported unless the operator recorded them
+	got := FindOrphans(
+		[]string{"mysite_web", "x7k2m9p4qa_web", "unrelated"},
+		[]string{"replicator", "monitoring", "bmu0fvnlxq"},
+		nil,
+		map[string]bool{},
+		map[string]bool{},
+		[]string{"web"},
+		&configv1.OrphanSweeperSpec{Enabled: true},
+	)
+
+	expected := []OrphanCandidate{
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "mysite_web", ReportOnly: true},
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "x7k2m9p4qa_web", ReportOnly: true},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("FindOrphans() = %v, want %v", got, expected)
+	}
+}
+
+func TestMatchesNamingScheme(t *testing.T) {
+	shortNames := []string{"web", "api-v2"}
+	tests := map[string]bool{
+		"mysite_web":          true,
+		"mysite_api_v2":       true,
+		"mysite_web_readonly": true,
+		"_web":                false,
+		"mysite_web_":         false,
+		"web":                 false,
+		"mysite_other":        false,
+		"replicator":          false,
+	}
+
+	for name, expected := range tests {
+		if got := MatchesNamingScheme(name, shortNames); got != expected {
+			t.Errorf("MatchesNamingScheme(%q) = %v, want %v", name, got, expected)
+		}
+	}
+}
+
+func TestRecordManagedResources(t *testing.T) {
+	got := RecordManagedResources(
+		&configv1.ManagedResources{Databases: []string{"site2_web"}, Users: []string{"site2_web"}},
+		[]string{"site1_web", "site2_web"},
+		[]string{"site1_web", ""},
+	)
+
+	expected := &configv1.ManagedResources{
+		Databases: []string{"site1_web", "site2_web"},
+		Users:     []string{"site1_web", "site2_web"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("RecordManagedResources() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestPruneManagedResources(t *testing.T) {
+	managed := &configv1.ManagedResources{
+		Databases: []string{"dropped_web", "gone_web", "live_web", "pending_web"},
+		Users:     []string{"dropped_web", "live_web"},
+	}
+	dropped := []configv1.OrphanedResource{
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "dropped_web"},
+		{Type: configv1.OrphanedResourceTypeUser, Name: "dropped_web"},
+	}
+
+	got := PruneManagedResources(
+		managed,
+		[]string{"dropped_web", "live_web"},
+		[]string{"dropped_web", "live_web"},
+		dropped,
+		map[string]bool{"pending_web": true},
+		map[string]bool{},
+	)
+
+	expected := &configv1.ManagedResources{
+		Databases: []string{"live_web", "pending_web"},
+		Users:     []string{"live_web"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("PruneManagedResources() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestMergeOrphans(t *testing.T) {
+	earlier := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	previous := []configv1.OrphanedResource{
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "old_web", FirstSeen: earlier},
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "gone_web", FirstSeen: earlier},
+	}
+	candidates := []OrphanCandidate{
+		{Type: configv1.OrphanedResourceTypeUser, Name: "new_web"},
+		{Type: configv1.OrphanedResourceTypeDatabase, Name: "old_web"},
+	}
+
+	got := MergeOrphans(previous, candidates, now)
+
+	if len(got) != 2 {
+		t.Fatalf("MergeOrphans() returned %d orphans, want 2", len(got))
+	}
+	if got[0].Name != "old_web" || !got[0].FirstSeen.Equal(&earlier) {
+		t.Errorf("got[0] = %+v, want old_web first seen at %v", got[0], earlier)
+	}
+	if got[1].Name != "new_web" || !got[1].FirstSeen.Time.Equal(now) {
+		t.Errorf("got[1] = %+v, want new_web first seen at %v", got[1], now)
+	}
+}
+
+func TestIsOrphanExpired(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	orphan := configv1.OrphanedResource{FirstSeen: metav1.NewTime(now.Add(-time.Hour))}
+
+	if !IsOrphanExpired(orphan, time.Hour, now) {
+		t.Error("expected the orphan to be expired after the grace period")
+	}
+	if IsOrphanExpired(orphan, 2*time.Hour, now) {
+		t.Error("expected the orphan not to be expired within the grace period")
+	}
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"github.com/go-logr/logr"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"strings"
+)
+
+// ProbeRedisServer connects to the server and returns the server version
+func ProbeRedisServer(ctx context.Context, config configv1.RedisConfig, logger logr.Logger) (string, error) {
+	client := newRedisClient(ctx, config, 0, logger)
+	defer func() { _ = client.Close() }()
+
+	info, err := client.Info("server").Result()
+	if err != nil {
+		return "", operationError(errorhelpers.DatabaseTypeRedis, config.Name, "probe", err)
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues("redis", "probe", "success").Inc()
+
+	return parseRedisVersion(info), nil
+}
+
+// parseRedisVersion returns the redis_version field from the output of the INFO command
+func parseRedisVersion(info string) string {
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "redis_version:"); ok {
+			return version
+		}
+	}
+
+	return ""
+}
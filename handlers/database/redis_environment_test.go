@@ -0,0 +1,14 @@
+package database
+
+import "testing"
+
+func TestParseRedisVersion(t *testing.T) {
+	info := "# Server\r\nredis_version:7.2.4\r\nredis_git_sha1:00000000\r\n"
+
+	if got := parseRedisVersion(info); got != "7.2.4" {
+		t.Errorf("parseRedisVersion() = %q, want %q", got, "7.2.4")
+	}
+	if got := parseRedisVersion("# Server\r\n"); got != "" {
+		t.Errorf("parseRedisVersion() = %q, want empty", got)
+	}
+}
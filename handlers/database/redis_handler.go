@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/go-logr/logr"
@@ -8,10 +9,16 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 )
 
-func ReconcileRedis(database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error) {
+func ReconcileRedis(
+	ctx context.Context,
+	database *taskv1.RedisDatabase,
+	config configv1.RedisConfig,
+	logger logr.Logger,
+) (bool, error) {
 	if database.Status.State == taskv1.Complete {
 		return false, nil
 	}
@@ -19,32 +26,14 @@ func ReconcileRedis(database *taskv1.RedisDatabase, config configv1.RedisConfig,
 	timer := prometheus.NewTimer(appmetrics.DatabaseOperationDuration.WithLabelValues("redis", "reconcile"))
 	defer timer.ObserveDuration()
 
-	var tlsConfig *tls.Config
-
-	if config.Spec.IsTlsEnabled != nil && *config.Spec.IsTlsEnabled {
-		tlsConfig = &tls.Config{}
-		if config.Spec.VerifyTlsServerCertificate != nil && !*config.Spec.VerifyTlsServerCertificate {
-			logger.Info("Disabling TLS server certificate verification")
-			tlsConfig.InsecureSkipVerify = true
-		}
-	}
-
 	logger.Info(fmt.Sprintf("Flushing redis database %d on connection %s", database.Spec.DatabaseNumber, config.Name))
-	client := redis.NewClient(
-		&redis.Options{
-			Addr:      config.Spec.Host + ":" + fmt.Sprint(config.Spec.Port),
-			DB:        int(database.Spec.DatabaseNumber),
-			Password:  config.Spec.Password,
-			TLSConfig: tlsConfig,
-		},
-	)
+	client := newRedisClient(ctx, config, int(database.Spec.DatabaseNumber), logger)
 	defer func() { _ = client.Close() }()
 
 	foo := client.FlushDB()
 
 	if err := foo.Err(); err != nil {
-		appmetrics.DatabaseOperations.WithLabelValues("redis", "reconcile", "error").Inc()
-		return false, err
+		return false, operationError(errorhelpers.DatabaseTypeRedis, config.Name, "reconcile", err)
 	}
 
 	appmetrics.DatabaseOperations.WithLabelValues("redis", "reconcile", "success").Inc()
@@ -53,3 +42,36 @@ func ReconcileRedis(database *taskv1.RedisDatabase, config configv1.RedisConfig,
 
 	return true, nil
 }
+
+// newRedisClient returns a client for the server of the config. Every network operation of the client is limited to the
+// operation timeout of the config.
+func newRedisClient(
+	ctx context.Context,
+	config configv1.RedisConfig,
+	databaseNumber int,
+	logger logr.Logger,
+) *redis.Client {
+	var tlsConfig *tls.Config
+
+	if config.Spec.IsTlsEnabled != nil && *config.Spec.IsTlsEnabled {
+		tlsConfig = &tls.Config{}
+		if config.Spec.VerifyTlsServerCertificate != nil && !*config.Spec.VerifyTlsServerCertificate {
+			logger.Info("Disabling TLS server certificate verification")
+			tlsConfig.InsecureSkipVerify = true
+		}
+	}
+
+	timeout := config.Spec.GetOperationTimeout()
+
+	return redis.NewClient(
+		&redis.Options{
+			Addr:         config.Spec.Host + ":" + fmt.Sprint(config.Spec.Port),
+			DB:           databaseNumber,
+			Password:     config.Spec.Password,
+			TLSConfig:    tlsConfig,
+			DialTimeout:  timeout,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+		},
+	).WithContext(ctx)
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"errors"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net"
+	"strings"
+)
+
+// isTimeoutError returns TRUE if the error was caused by the operation timeout expiring or by a network timeout
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return true
+	}
+
+	return mongo.IsTimeout(err)
+}
+
+// operationError records the failed operation in the metrics and returns the error to report. Timeouts are wrapped in
+// a DatabaseTimeoutError, so the controllers can retry them with backoff.
+func operationError(
+	databaseType errorhelpers.DatabaseType,
+	environment string,
+	operation string,
+	err error,
+) error {
+	metricType := strings.ToLower(string(databaseType))
+
+	if !isTimeoutError(err) {
+		appmetrics.DatabaseOperations.WithLabelValues(metricType, operation, "error").Inc()
+		return err
+	}
+
+	appmetrics.DatabaseOperations.WithLabelValues(metricType, operation, "timeout").Inc()
+
+	return errorhelpers.DatabaseTimeoutError{
+		DatabaseType: databaseType,
+		Environment:  environment,
+		Operation:    operation,
+		Err:          err,
+	}
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newHungServer returns the host and port of a server that accepts connections, but never responds
+func newHungServer(t *testing.T) (string, uint16) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	var connections []net.Conn
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections = append(connections, connection)
+		}
+	}()
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+		<-done
+		for _, connection := range connections {
+			_ = connection.Close()
+		}
+	})
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return host, uint16(portNumber)
+}
+
+func assertTimeoutError(t *testing.T, err error, started time.Time) {
+	t.Helper()
+
+	if !errorhelpers.IsDatabaseTimeoutError(err) {
+		t.Fatalf("expected a DatabaseTimeoutError, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("the operation took %v, expected it to time out after about a second", elapsed)
+	}
+}
+
+func TestIsTimeoutError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"wrapped deadline exceeded", fmt.Errorf("query failed: %w", context.DeadlineExceeded), true},
+		{"network timeout", &net.DNSError{Err: "i/o timeout", IsTimeout: true}, true},
+		{"network error", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"cancelled", context.Canceled, false},
+		{"plain error", errors.New("access denied"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTimeoutError(tt.err); got != tt.expected {
+				t.Errorf("isTimeoutError() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOperationError(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		err := operationError(errorhelpers.DatabaseTypeMongo, "mongo-env", "probe", context.DeadlineExceeded)
+
+		var timeoutError errorhelpers.DatabaseTimeoutError
+		if !errors.As(err, &timeoutError) {
+			t.Fatalf("expected a DatabaseTimeoutError, got %T", err)
+		}
+		if timeoutError.DatabaseType != errorhelpers.DatabaseTypeMongo || timeoutError.Environment != "mongo-env" ||
+			timeoutError.Operation != "probe" {
+			t.Errorf("unexpected error: %+v", timeoutError)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Error("the original error should be wrapped")
+		}
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		original := errors.New("access denied")
+		if err := operationError(errorhelpers.DatabaseTypeMysql, "mysql-env", "reconcile", original); err != original {
+			t.Errorf("expected the original error, got %v", err)
+		}
+	})
+}
+
+func TestProbeMysqlServer_TimesOut(t *testing.T) {
+	host, port := newHungServer(t)
+	config := newTestMysqlConfig()
+	config.Spec.Host = host
+	config.Spec.Port = port
+	config.Spec.OperationTimeoutSeconds = 1
+
+	started := time.Now()
+	_, err := ProbeMysqlServer(context.Background(), nil, config, nil, logr.Discard())
+
+	assertTimeoutError(t, err, started)
+}
+
+func TestProbeMongoServer_TimesOut(t *testing.T) {
+	host, port := newHungServer(t)
+	config := configv1.MongoConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "mongo-env", Namespace: "test-ns"},
+		Spec: configv1.MongoConfigSpec{
+			Host1:                   host,
+			Port:                    port,
+			Username:                "admin",
+			Password:                "adminpass",
+			OperationTimeoutSeconds: 1,
+		},
+	}
+
+	started := time.Now()
+	_, err := ProbeMongoServer(context.Background(), NewConnectionManager(0, 0), config, logr.Discard())
+
+	assertTimeoutError(t, err, started)
+}
+
+func TestReconcileRedis_TimesOut(t *testing.T) {
+	host, port := newHungServer(t)
+	config := configv1.RedisConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-env", Namespace: "test-ns"},
+		Spec: configv1.RedisConfigSpec{
+			Host:                    host,
+			Port:                    port,
+			OperationTimeoutSeconds: 1,
+		},
+	}
+	database := &taskv1.RedisDatabase{Spec: taskv1.RedisDatabaseSpec{DatabaseNumber: 1}}
+
+	started := time.Now()
+	changed, err := ReconcileRedis(context.Background(), database, config, logr.Discard())
+
+	assertTimeoutError(t, err, started)
+	if changed || database.Status.State == taskv1.Complete {
+		t.Error("the database should not be marked as complete")
+	}
+}
+
+func TestProbeMysqlServer_StopsWhenTheContextIsCancelled(t *testing.T) {
+	host, port := newHungServer(t)
+	config := newTestMysqlConfig()
+	config.Spec.Host = host
+	config.Spec.Port = port
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := ProbeMysqlServer(ctx, nil, config, nil, logr.Discard())
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("the operation took %v, expected it to stop with the context", elapsed)
+	}
+}
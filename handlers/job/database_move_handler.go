@@ -0,0 +1,264 @@
+package job
+
+import (
+	"context"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/handlers/task"
+	"github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+)
+
+type DatabaseMoveHandler struct {
+	Reader client.Reader
+	Writer client.Writer
+	Scheme *runtime.Scheme
+}
+
+type databaseMoveKey struct {
+	databaseType jobv1.DatabaseMoveType
+	serviceName  string
+}
+
+// EnsureMovesAreCreated creates a DatabaseMove for every database of the site whose environment was changed in the
+// site spec, and removes the moves created by the site that are no longer needed. Moves created manually are left
+// alone.
+func (r DatabaseMoveHandler) EnsureMovesAreCreated(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving database move list")
+
+	moves, err := r.listMoves(site, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	logger.V(1).Info("Retrieved list", "count", len(moves))
+	logger.V(1).Info("Getting changes required to reconcile the database moves")
+
+	movesToCreate, err := r.getExpectedMoves(site, ctx)
+	if err != nil {
+		return false, err
+	}
+	movesToDelete := make(map[databaseMoveKey]jobv1.DatabaseMove)
+
+	for _, move := range moves {
+		key := databaseMoveKey{databaseType: move.Spec.DatabaseType, serviceName: move.Spec.ServiceName}
+		expectedMove, ok := movesToCreate[key]
+
+		if !metav1.IsControlledBy(&move, site) {
+			// Manually created moves take precedence over the ones derived from the site spec
+			delete(movesToCreate, key)
+			continue
+		}
+
+		if ok && expectedMove.Spec.TargetEnvironment == move.Spec.TargetEnvironment {
+			delete(movesToCreate, key)
+			continue
+		}
+
+		if move.IsInProgress() && move.Status.Phase == jobv1.DatabaseMovePhaseSwitching {
+			// The switch can't be interrupted, the move is replaced once it's done
+			delete(movesToCreate, key)
+			continue
+		}
+
+		movesToDelete[key] = move
+		// The replacement is only created once the obsolete move is gone
+		delete(movesToCreate, key)
+	}
+
+	isComplete := len(movesToDelete) == 0 && len(movesToCreate) == 0
+
+	for key, move := range movesToDelete {
+		logger.V(1).Info("Deleting obsolete database move", "service", key.serviceName, "type", key.databaseType)
+		if err = r.Writer.Delete(ctx, &move); err != nil {
+			return isComplete, err
+		}
+	}
+	for key, move := range movesToCreate {
+		logger.V(1).Info("Creating database move", "service", key.serviceName, "type", key.databaseType)
+		if err = r.Writer.Create(ctx, &move); err != nil {
+			return isComplete, err
+		}
+	}
+
+	logger.V(0).Info("Database moves created")
+
+	return isComplete, nil
+}
+
+// EnsureMovesAreComplete reports the moves of the site in its status and returns TRUE if none of them is in progress.
+// A failed move fails the site.
+func (r DatabaseMoveHandler) EnsureMovesAreComplete(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	moves, err := r.listMoves(site, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	site.Status.DatabaseMoves = nil
+	isEverythingComplete := true
+	var moveError error
+
+	for _, move := range moves {
+		site.Status.DatabaseMoves = append(site.Status.DatabaseMoves, sitev1.StagingSiteDatabaseMoveStatus{
+			Name:              move.Name,
+			ServiceName:       move.Spec.ServiceName,
+			DatabaseType:      string(move.Spec.DatabaseType),
+			SourceEnvironment: move.Status.SourceEnvironment,
+			TargetEnvironment: move.Spec.TargetEnvironment,
+			State:             string(move.Status.State),
+			Phase:             string(move.Status.Phase),
+			Message:           move.Status.Message,
+		})
+
+		if move.Status.State == jobv1.Failed && moveError == nil {
+			moveError = errors.DatabaseMoveError{
+				SiteName:          site.Name,
+				ServiceName:       move.Spec.ServiceName,
+				TargetEnvironment: move.Spec.TargetEnvironment,
+				Reason:            move.Status.Message,
+			}
+		}
+		isEverythingComplete = isEverythingComplete && !move.IsInProgress()
+	}
+
+	if moveError != nil {
+		return false, moveError
+	}
+
+	if isEverythingComplete {
+		logger.V(1).Info("All database moves are complete")
+	} else {
+		logger.V(0).Info("Not all database moves are complete yet")
+	}
+
+	return isEverythingComplete, nil
+}
+
+// listMoves returns the moves of the site ordered by name. Moves are matched by their spec, as manually created ones
+// may not have the site labels.
+func (r DatabaseMoveHandler) listMoves(site *sitev1.StagingSite, ctx context.Context) ([]jobv1.DatabaseMove, error) {
+	var list jobv1.DatabaseMoveList
+	if err := r.Reader.List(ctx, &list, client.InNamespace(site.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var moves []jobv1.DatabaseMove
+	for _, move := range list.Items {
+		if move.Spec.SiteName == site.Name {
+			moves = append(moves, move)
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].Name < moves[j].Name
+	})
+
+	return moves, nil
+}
+
+// getExpectedMoves returns a move for every completed database whose environment differs from the one in the site
+// spec, if its service config supports moving the data
+func (r DatabaseMoveHandler) getExpectedMoves(site *sitev1.StagingSite, ctx context.Context) (
+	map[databaseMoveKey]jobv1.DatabaseMove,
+	error,
+) {
+	siteLabels := client.MatchingLabels{labels.Site: site.Name}
+	var mysqlList taskv1.MysqlDatabaseList
+	if err := r.Reader.List(ctx, &mysqlList, client.InNamespace(site.Namespace), siteLabels); err != nil {
+		return nil, err
+	}
+	var mongoList taskv1.MongoDatabaseList
+	if err := r.Reader.List(ctx, &mongoList, client.InNamespace(site.Namespace), siteLabels); err != nil {
+		return nil, err
+	}
+
+	type movableDatabase struct {
+		databaseType       jobv1.DatabaseMoveType
+		environment        string
+		state              taskv1.TaskState
+		serviceName        string
+		desiredEnvironment string
+	}
+	var databases []movableDatabase
+	for _, database := range mysqlList.Items {
+		if task.IsDatabaseMoveTarget(&database) {
+			continue
+		}
+		serviceName := database.Spec.EnvironmentConfig.ServiceName
+		databases = append(databases, movableDatabase{
+			databaseType:       jobv1.DatabaseMoveTypeMysql,
+			environment:        database.Spec.EnvironmentConfig.Environment,
+			state:              database.Status.State,
+			serviceName:        serviceName,
+			desiredEnvironment: site.Spec.Services[serviceName].MysqlEnvironment,
+		})
+	}
+	for _, database := range mongoList.Items {
+		if task.IsDatabaseMoveTarget(&database) {
+			continue
+		}
+		serviceName := database.Spec.EnvironmentConfig.ServiceName
+		databases = append(databases, movableDatabase{
+			databaseType:       jobv1.DatabaseMoveTypeMongo,
+			environment:        database.Spec.EnvironmentConfig.Environment,
+			state:              database.Status.State,
+			serviceName:        serviceName,
+			desiredEnvironment: site.Spec.Services[serviceName].MongoEnvironment,
+		})
+	}
+
+	moves := make(map[databaseMoveKey]jobv1.DatabaseMove)
+	serviceConfigs := make(map[string]*configv1.ServiceConfig)
+	for _, database := range databases {
+		if database.desiredEnvironment == "" || database.desiredEnvironment == database.environment {
+			continue
+		}
+
+		serviceConfig, ok := serviceConfigs[database.serviceName]
+		if !ok {
+			serviceConfig = &configv1.ServiceConfig{}
+			err := r.Reader.Get(
+				ctx,
+				client.ObjectKey{Namespace: site.Namespace, Name: database.serviceName},
+				serviceConfig,
+			)
+			if err != nil {
+				return nil, err
+			}
+			serviceConfigs[database.serviceName] = serviceConfig
+		}
+
+		if !task.IsDatabaseMoveRequired(database.state, serviceConfig) {
+			continue
+		}
+
+		move := jobv1.DatabaseMove{}
+		if err := move.PopulateFomSite(
+			site,
+			serviceConfig,
+			database.databaseType,
+			database.environment,
+			database.desiredEnvironment,
+		); err != nil {
+			return nil, err
+		}
+		if err := ctrl.SetControllerReference(site, &move, r.Scheme); err != nil {
+			return nil, err
+		}
+
+		moves[databaseMoveKey{databaseType: database.databaseType, serviceName: database.serviceName}] = move
+	}
+
+	return moves, nil
+}
@@ -0,0 +1,290 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newDatabaseMoveHandler(objs ...client.Object) DatabaseMoveHandler {
+	c := testutil.NewFakeClient(objs...)
+	return DatabaseMoveHandler{
+		Reader: c,
+		Writer: c,
+		Scheme: testutil.NewTestScheme(),
+	}
+}
+
+func newMovableServiceConfig() *configv1.ServiceConfig {
+	svcConfig := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+	svcConfig.Spec.DatabaseMovePodSpec = &corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "move", Image: "mover:latest"}},
+	}
+	return svcConfig
+}
+
+func newSiteMysqlDatabase(environment string, state taskv1.TaskState) *taskv1.MysqlDatabase {
+	database := testutil.NewTestMysqlDatabase("mysite-svc", "test-ns", "mysite", "mysvc", environment)
+	database.Labels = map[string]string{labels.Site: "mysite", labels.Service: "mysvc"}
+	database.Status.State = state
+	return database
+}
+
+func listDatabaseMoves(t *testing.T, handler DatabaseMoveHandler) []jobv1.DatabaseMove {
+	t.Helper()
+	moveList := &jobv1.DatabaseMoveList{}
+	if err := handler.Reader.List(context.Background(), moveList, client.InNamespace("test-ns")); err != nil {
+		t.Fatalf("error listing database moves: %v", err)
+	}
+	return moveList.Items
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreCreated_CreatesMoveOnEnvironmentChange(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-2"},
+	})
+	handler := newDatabaseMoveHandler(
+		site,
+		newMovableServiceConfig(),
+		newSiteMysqlDatabase("mysql-1", taskv1.Complete),
+	)
+
+	complete, err := handler.EnsureMovesAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false when a move was created")
+	}
+
+	moves := listDatabaseMoves(t, handler)
+	if len(moves) != 1 {
+		t.Fatalf("expected 1 move, got %d", len(moves))
+	}
+	move := moves[0]
+	if move.Name != "mysite-svc-mysql" {
+		t.Errorf("expected move name mysite-svc-mysql, got %s", move.Name)
+	}
+	if move.Spec.SourceEnvironment != "mysql-1" || move.Spec.TargetEnvironment != "mysql-2" {
+		t.Errorf("expected move from mysql-1 to mysql-2, got %s to %s", move.Spec.SourceEnvironment, move.Spec.TargetEnvironment)
+	}
+	if move.Spec.DatabaseType != jobv1.DatabaseMoveTypeMysql {
+		t.Errorf("expected database type mysql, got %s", move.Spec.DatabaseType)
+	}
+	if !metav1.IsControlledBy(&move, site) {
+		t.Error("expected the move to be controlled by the site")
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreCreated_NoMoveWithoutPodSpec(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-2"},
+	})
+	handler := newDatabaseMoveHandler(
+		site,
+		testutil.NewTestServiceConfig("mysvc", "test-ns", "svc"),
+		newSiteMysqlDatabase("mysql-1", taskv1.Complete),
+	)
+
+	complete, err := handler.EnsureMovesAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !complete {
+		t.Error("expected complete=true when the service doesn't support moves")
+	}
+	if moves := listDatabaseMoves(t, handler); len(moves) != 0 {
+		t.Errorf("expected 0 moves, got %d", len(moves))
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreCreated_NoMoveForIncompleteDatabase(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-2"},
+	})
+	handler := newDatabaseMoveHandler(
+		site,
+		newMovableServiceConfig(),
+		newSiteMysqlDatabase("mysql-1", taskv1.Pending),
+	)
+
+	if _, err := handler.EnsureMovesAreCreated(site, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if moves := listDatabaseMoves(t, handler); len(moves) != 0 {
+		t.Errorf("expected 0 moves for a database that isn't created yet, got %d", len(moves))
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreCreated_DeletesObsoleteMove(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-1"},
+	})
+	move := testutil.NewTestDatabaseMove("mysite-svc-mysql", "test-ns", "mysite", "mysvc", "mysql-2")
+	if err := ctrl.SetControllerReference(site, move, testutil.NewTestScheme()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	move.Status.State = jobv1.Running
+	move.Status.Phase = jobv1.DatabaseMovePhaseCopyingData
+	handler := newDatabaseMoveHandler(
+		site,
+		newMovableServiceConfig(),
+		newSiteMysqlDatabase("mysql-1", taskv1.Complete),
+		move,
+	)
+
+	complete, err := handler.EnsureMovesAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false when a move was deleted")
+	}
+	if moves := listDatabaseMoves(t, handler); len(moves) != 0 {
+		t.Errorf("expected the reverted move to be deleted, got %d moves", len(moves))
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreCreated_KeepsSwitchingMove(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-3"},
+	})
+	move := testutil.NewTestDatabaseMove("mysite-svc-mysql", "test-ns", "mysite", "mysvc", "mysql-2")
+	if err := ctrl.SetControllerReference(site, move, testutil.NewTestScheme()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	move.Status.State = jobv1.Running
+	move.Status.Phase = jobv1.DatabaseMovePhaseSwitching
+	handler := newDatabaseMoveHandler(
+		site,
+		newMovableServiceConfig(),
+		newSiteMysqlDatabase("mysql-1", taskv1.Complete),
+		move,
+	)
+
+	if _, err := handler.EnsureMovesAreCreated(site, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	moves := listDatabaseMoves(t, handler)
+	if len(moves) != 1 || moves[0].Spec.TargetEnvironment != "mysql-2" {
+		t.Errorf("expected the switching move to be kept, got %v", moves)
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreCreated_ManualMoveTakesPrecedence(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-2"},
+	})
+	handler := newDatabaseMoveHandler(
+		site,
+		newMovableServiceConfig(),
+		newSiteMysqlDatabase("mysql-1", taskv1.Complete),
+		testutil.NewTestDatabaseMove("manual-move", "test-ns", "mysite", "mysvc", "mysql-3"),
+	)
+
+	complete, err := handler.EnsureMovesAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !complete {
+		t.Error("expected complete=true when a manual move exists")
+	}
+	moves := listDatabaseMoves(t, handler)
+	if len(moves) != 1 || moves[0].Name != "manual-move" {
+		t.Errorf("expected only the manual move, got %v", moves)
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreComplete_ReportsInProgressMove(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", nil)
+	move := testutil.NewTestDatabaseMove("manual-move", "test-ns", "mysite", "mysvc", "mysql-2")
+	move.Status.State = jobv1.Running
+	move.Status.Phase = jobv1.DatabaseMovePhaseCopyingData
+	move.Status.SourceEnvironment = "mysql-1"
+	otherSiteMove := testutil.NewTestDatabaseMove("other-move", "test-ns", "othersite", "mysvc", "mysql-2")
+	handler := newDatabaseMoveHandler(site, move, otherSiteMove)
+
+	complete, err := handler.EnsureMovesAreComplete(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false while a move is in progress")
+	}
+	if len(site.Status.DatabaseMoves) != 1 {
+		t.Fatalf("expected 1 move in the status, got %d", len(site.Status.DatabaseMoves))
+	}
+	expected := sitev1.StagingSiteDatabaseMoveStatus{
+		Name:              "manual-move",
+		ServiceName:       "mysvc",
+		DatabaseType:      "mysql",
+		SourceEnvironment: "mysql-1",
+		TargetEnvironment: "mysql-2",
+		State:             "Running",
+		Phase:             "CopyingData",
+	}
+	if site.Status.DatabaseMoves[0] != expected {
+		t.Errorf("expected status %+v, got %+v", expected, site.Status.DatabaseMoves[0])
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreComplete_FailedMoveReturnsError(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", nil)
+	move := testutil.NewTestDatabaseMove("manual-move", "test-ns", "mysite", "mysvc", "mysql-2")
+	move.Status.State = jobv1.Failed
+	move.Status.Message = "the job copying the data failed"
+	handler := newDatabaseMoveHandler(site, move)
+
+	complete, err := handler.EnsureMovesAreComplete(site, ctx)
+	if complete {
+		t.Error("expected complete=false when a move failed")
+	}
+	var moveError errorhelpers.DatabaseMoveError
+	if !errors.As(err, &moveError) {
+		t.Fatalf("expected a DatabaseMoveError, got %v", err)
+	}
+	if moveError.Reason != "the job copying the data failed" || moveError.TargetEnvironment != "mysql-2" {
+		t.Errorf("unexpected error details: %+v", moveError)
+	}
+	if len(site.Status.DatabaseMoves) != 1 {
+		t.Errorf("expected the failed move in the status, got %d moves", len(site.Status.DatabaseMoves))
+	}
+}
+
+func TestDatabaseMoveHandler_EnsureMovesAreComplete_NoMoves(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", nil)
+	site.Status.DatabaseMoves = []sitev1.StagingSiteDatabaseMoveStatus{{Name: "deleted-move"}}
+	handler := newDatabaseMoveHandler(site)
+
+	complete, err := handler.EnsureMovesAreComplete(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !complete {
+		t.Error("expected complete=true without moves")
+	}
+	if site.Status.DatabaseMoves != nil {
+		t.Errorf("expected the deleted move to be removed from the status, got %v", site.Status.DatabaseMoves)
+	}
+}
@@ -5,12 +5,15 @@ import (
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/handlers/task"
 	"github.com/szeber/kube-stager/helpers/errors"
 	"github.com/szeber/kube-stager/helpers/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
 )
 
 type DbInitJobHandler struct {
@@ -35,6 +38,7 @@ func (r DbInitJobHandler) EnsureJobsAreCreated(site *sitev1.StagingSite, ctx con
 
 	jobsToDelete := make(map[string]jobv1.DbInitJob)
 	jobsToCreate := make(map[string]jobv1.DbInitJob)
+	databaseTasks := make(map[string][]taskv1.InitialisableTask)
 
 	for name, service := range site.Spec.Services {
 		if service.MysqlEnvironment == "" && service.MongoEnvironment == "" {
@@ -61,18 +65,50 @@ func (r DbInitJobHandler) EnsureJobsAreCreated(site *sitev1.StagingSite, ctx con
 		if err != nil {
 			return false, err
 		}
+
+		databaseTasks[name], err = ListDatabaseTasksOfService(ctx, r.Reader, site.Namespace, site.Name, name)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	for _, database := range list.Items {
 		serviceName := database.Spec.ServiceName
 
-		if _, ok := jobsToCreate[serviceName]; ok {
-			delete(jobsToCreate, serviceName)
-		} else {
+		expectedJob, ok := jobsToCreate[serviceName]
+		if !ok {
+			jobsToDelete[serviceName] = database
+			continue
+		}
+		delete(jobsToCreate, serviceName)
+
+		if database.Spec.ResetToken != expectedJob.Spec.ResetToken {
+			logger.V(0).Info("Database init reset requested, deleting the init job", "service", serviceName)
+			jobsToDelete[serviceName] = database
+		} else if IsAnyDatabaseNewerThanJob(databaseTasks[serviceName], &database) {
+			logger.V(0).Info("The databases were recreated since the init job ran, deleting the init job", "service", serviceName)
 			jobsToDelete[serviceName] = database
 		}
 	}
 
+	for serviceName, database := range jobsToCreate {
+		if !isInitialised(databaseTasks[serviceName], &database) {
+			continue
+		}
+
+		logger.V(0).Info("The databases are already initialised, not creating the init job", "service", serviceName)
+		for _, task := range databaseTasks[serviceName] {
+			if !task.GetInitMarker().Matches(database.MakeInitMarker(time.Now())) {
+				logger.V(0).Info(
+					"The database was initialised from a different source or init pod spec. Set the dbInitResetToken of the service to initialise it again",
+					"service", serviceName,
+					"database", task.GetName(),
+				)
+			}
+		}
+		delete(jobsToCreate, serviceName)
+	}
+
 	isComplete := len(jobsToDelete) == 0 && len(jobsToCreate) == 0
 
 	for serviceName, database := range jobsToDelete {
@@ -115,6 +151,11 @@ func (r DbInitJobHandler) EnsureJobsAreComplete(site *sitev1.StagingSite, ctx co
 			}
 		}
 		isEverythingReady = isEverythingReady && database.Status.State == jobv1.Complete
+
+		if service, ok := site.Status.Services[database.Spec.ServiceName]; ok {
+			service.DatabaseSanitized = database.Status.SanitizeState == jobv1.Complete
+			site.Status.Services[database.Spec.ServiceName] = service
+		}
 	}
 
 	if isEverythingReady {
@@ -143,3 +184,75 @@ func (r DbInitJobHandler) getPopulatedJob(
 
 	return job, nil
 }
+
+// ListDatabaseTasksOfService returns the database tasks of the service of a site that are initialised by its db init
+// job. The target tasks of database moves are skipped, as they get the data of the moved database.
+func ListDatabaseTasksOfService(
+	ctx context.Context,
+	reader client.Reader,
+	namespace string,
+	siteName string,
+	serviceName string,
+) ([]taskv1.InitialisableTask, error) {
+	var tasks []taskv1.InitialisableTask
+	listOptions := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingLabels{labels.Site: siteName, labels.Service: serviceName},
+	}
+
+	var mysqlDatabases taskv1.MysqlDatabaseList
+	if err := reader.List(ctx, &mysqlDatabases, listOptions...); err != nil {
+		return nil, err
+	}
+	for i := range mysqlDatabases.Items {
+		if !task.IsDatabaseMoveTarget(&mysqlDatabases.Items[i]) {
+			tasks = append(tasks, &mysqlDatabases.Items[i])
+		}
+	}
+
+	var mongoDatabases taskv1.MongoDatabaseList
+	if err := reader.List(ctx, &mongoDatabases, listOptions...); err != nil {
+		return nil, err
+	}
+	for i := range mongoDatabases.Items {
+		if !task.IsDatabaseMoveTarget(&mongoDatabases.Items[i]) {
+			tasks = append(tasks, &mongoDatabases.Items[i])
+		}
+	}
+
+	return tasks, nil
+}
+
+// IsAnyDatabaseNewerThanJob returns TRUE if any of the databases without an init marker was created after the db init
+// job, so it wasn't initialised by it
+func IsAnyDatabaseNewerThanJob(tasks []taskv1.InitialisableTask, job *jobv1.DbInitJob) bool {
+	for _, task := range tasks {
+		if task.GetInitMarker() == nil && IsDatabaseNewerThanJob(task, job) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsDatabaseNewerThanJob returns TRUE if the database was created after the db init job
+func IsDatabaseNewerThanJob(task taskv1.InitialisableTask, job *jobv1.DbInitJob) bool {
+	createdAt := task.GetCreationTimestamp()
+	return job.CreationTimestamp.Before(&createdAt)
+}
+
+// isInitialised returns TRUE if every database of the service has an init marker with the reset token of the job
+func isInitialised(tasks []taskv1.InitialisableTask, job *jobv1.DbInitJob) bool {
+	if len(tasks) == 0 {
+		return false
+	}
+
+	for _, task := range tasks {
+		marker := task.GetInitMarker()
+		if marker == nil || marker.ResetToken != job.Spec.ResetToken {
+			return false
+		}
+	}
+
+	return true
+}
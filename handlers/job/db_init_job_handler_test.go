@@ -3,12 +3,16 @@ package job
 import (
 	"context"
 	"testing"
+	"time"
 
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
 	"github.com/szeber/kube-stager/helpers/labels"
 	"github.com/szeber/kube-stager/internal/testutil"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -312,3 +316,193 @@ func TestDbInitJobHandler_EnsureJobsAreComplete_MixedCompleteAndPendingReturnsFa
 		t.Error("expected complete=false when at least one job is not Complete")
 	}
 }
+
+func TestDbInitJobHandler_EnsureJobsAreComplete_RecordsSanitization(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", nil)
+	site.Status.Services = map[string]sitev1.StagingSiteServiceStatus{"svc1": {}, "svc2": {}}
+	job1 := testutil.NewTestDbInitJob("mysite-svc1", "test-ns", "mysite", "svc1")
+	job1.Labels = map[string]string{labels.Site: "mysite"}
+	job1.Spec.Sanitize = true
+	job1.Status.State = jobv1.Complete
+	job1.Status.SanitizeState = jobv1.Complete
+	job2 := testutil.NewTestDbInitJob("mysite-svc2", "test-ns", "mysite", "svc2")
+	job2.Labels = map[string]string{labels.Site: "mysite"}
+	job2.Status.State = jobv1.Complete
+	handler := newDbInitJobHandler(site, job1, job2)
+
+	complete, err := handler.EnsureJobsAreComplete(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !complete {
+		t.Error("expected complete=true when all jobs are Complete")
+	}
+	if !site.Status.Services["svc1"].DatabaseSanitized {
+		t.Error("expected svc1 to be marked as sanitized")
+	}
+	if site.Status.Services["svc2"].DatabaseSanitized {
+		t.Error("expected svc2 not to be marked as sanitized")
+	}
+}
+
+func TestDbInitJobHandler_EnsureJobsAreComplete_RunningSanitizationReturnsFalse(t *testing.T) {
+	ctx := context.Background()
+	site := testutil.NewTestStagingSite("mysite", "test-ns", nil)
+	job1 := testutil.NewTestDbInitJob("mysite-svc1", "test-ns", "mysite", "svc1")
+	job1.Labels = map[string]string{labels.Site: "mysite"}
+	job1.Spec.Sanitize = true
+	job1.Status.State = jobv1.Running
+	job1.Status.SanitizeState = jobv1.Running
+	handler := newDbInitJobHandler(site, job1)
+
+	complete, err := handler.EnsureJobsAreComplete(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false while the sanitize job is running")
+	}
+}
+
+func newInitialisedMysqlDatabase(resetToken string) *taskv1.MysqlDatabase {
+	database := testutil.NewTestMysqlDatabase("mysite-svc", "test-ns", "mysite", "mysvc", "mysql-env")
+	database.Labels = map[string]string{labels.Site: "mysite", labels.Service: "mysvc"}
+	database.Status.InitMarker = &taskv1.DatabaseInitMarker{
+		Source:        "master",
+		PodSpecHash:   "previous",
+		ResetToken:    resetToken,
+		InitialisedAt: metav1.Now(),
+	}
+	return database
+}
+
+func newDbInitServiceConfig() *configv1.ServiceConfig {
+	svcConfig := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+	svcConfig.Spec.DbInitPodSpec = &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "init", Image: "init:latest"},
+		},
+	}
+	return svcConfig
+}
+
+func TestDbInitJobHandler_EnsureJobsAreCreated_InitialisedDatabaseCreatesNoJob(t *testing.T) {
+	ctx := context.Background()
+	services := map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-env"},
+	}
+	site := testutil.NewTestStagingSite("mysite", "test-ns", services)
+	handler := newDbInitJobHandler(site, newDbInitServiceConfig(), newInitialisedMysqlDatabase(""))
+
+	complete, err := handler.EnsureJobsAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !complete {
+		t.Error("expected complete=true when the database is already initialised")
+	}
+
+	jobList := &jobv1.DbInitJobList{}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 0 {
+		t.Errorf("expected 0 jobs for an initialised database, got %d", len(jobList.Items))
+	}
+}
+
+func TestDbInitJobHandler_EnsureJobsAreCreated_ResetTokenReinitialises(t *testing.T) {
+	ctx := context.Background()
+	services := map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-env", DbInitResetToken: "reset-1"},
+	}
+	site := testutil.NewTestStagingSite("mysite", "test-ns", services)
+	existingJob := testutil.NewTestDbInitJob("mysite-svc", "test-ns", "mysite", "mysvc")
+	existingJob.Labels = map[string]string{labels.Site: "mysite"}
+	existingJob.Status.State = jobv1.Complete
+	handler := newDbInitJobHandler(site, newDbInitServiceConfig(), newInitialisedMysqlDatabase(""), existingJob)
+
+	complete, err := handler.EnsureJobsAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false when the job is deleted for a reset")
+	}
+
+	jobList := &jobv1.DbInitJobList{}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 0 {
+		t.Fatalf("expected the previous job to be deleted, got %d jobs", len(jobList.Items))
+	}
+
+	if _, err = handler.EnsureJobsAreCreated(site, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 1 {
+		t.Fatalf("expected the job to be recreated, got %d jobs", len(jobList.Items))
+	}
+	if jobList.Items[0].Spec.ResetToken != "reset-1" {
+		t.Errorf("ResetToken = %q, want %q", jobList.Items[0].Spec.ResetToken, "reset-1")
+	}
+}
+
+func TestDbInitJobHandler_EnsureJobsAreCreated_RecreatedDatabaseReinitialises(t *testing.T) {
+	ctx := context.Background()
+	services := map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-env"},
+	}
+	site := testutil.NewTestStagingSite("mysite", "test-ns", services)
+	existingJob := testutil.NewTestDbInitJob("mysite-svc", "test-ns", "mysite", "mysvc")
+	existingJob.Labels = map[string]string{labels.Site: "mysite"}
+	existingJob.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	existingJob.Status.State = jobv1.Complete
+	database := testutil.NewTestMysqlDatabase("mysite-svc", "test-ns", "mysite", "mysvc", "mysql-env")
+	database.Labels = map[string]string{labels.Site: "mysite", labels.Service: "mysvc"}
+	database.CreationTimestamp = metav1.Now()
+	handler := newDbInitJobHandler(site, newDbInitServiceConfig(), database, existingJob)
+
+	if _, err := handler.EnsureJobsAreCreated(site, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	jobList := &jobv1.DbInitJobList{}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 0 {
+		t.Errorf("expected the job that predates the database to be deleted, got %d jobs", len(jobList.Items))
+	}
+}
+
+func TestDbInitJobHandler_EnsureJobsAreCreated_DatabaseMoveTargetDoesNotReinitialise(t *testing.T) {
+	ctx := context.Background()
+	services := map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1", MysqlEnvironment: "mysql-env"},
+	}
+	site := testutil.NewTestStagingSite("mysite", "test-ns", services)
+	existingJob := testutil.NewTestDbInitJob("mysite-svc", "test-ns", "mysite", "mysvc")
+	existingJob.Labels = map[string]string{labels.Site: "mysite"}
+	existingJob.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+	existingJob.Status.State = jobv1.Complete
+	database := testutil.NewTestMysqlDatabase("mysite-svc", "test-ns", "mysite", "mysvc", "mysql-env")
+	database.Labels = map[string]string{labels.Site: "mysite", labels.Service: "mysvc"}
+	database.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	// The target of a database move copies the labels of the source task, and is newer than the init job
+	moveTarget := testutil.NewTestMysqlDatabase("mysite-svc-move", "test-ns", "mysite", "mysvc", "other-env")
+	moveTarget.Labels = map[string]string{labels.Site: "mysite", labels.Service: "mysvc", labels.DatabaseMove: "mysite-svc"}
+	moveTarget.CreationTimestamp = metav1.Now()
+	handler := newDbInitJobHandler(site, newDbInitServiceConfig(), database, moveTarget, existingJob)
+
+	isComplete, err := handler.EnsureJobsAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !isComplete {
+		t.Error("expected complete=true while the database is moved")
+	}
+
+	jobList := &jobv1.DbInitJobList{}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 1 {
+		t.Errorf("expected the init job to be kept during the move, got %d jobs", len(jobList.Items))
+	}
+}
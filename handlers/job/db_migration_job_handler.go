@@ -6,6 +6,7 @@ import (
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	"github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
 	"github.com/szeber/kube-stager/helpers/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -75,8 +76,7 @@ func (r DbMigrationJobHandler) EnsureJobsAreCreated(site *sitev1.StagingSite, ct
 
 		if jobToCreate, ok := jobsToCreate[serviceName]; ok {
 			if !existingJob.Matches(&jobToCreate) {
-				existingJob.UpdateFrom(&jobToCreate)
-				jobsToUpdate[serviceName] = existingJob
+				jobsToUpdate[serviceName] = jobToCreate
 			}
 			delete(jobsToCreate, serviceName)
 		} else {
@@ -94,13 +94,13 @@ func (r DbMigrationJobHandler) EnsureJobsAreCreated(site *sitev1.StagingSite, ct
 	}
 	for serviceName, job := range jobsToUpdate {
 		logger.V(1).Info("Updating migration job for service " + serviceName)
-		if err = r.Writer.Update(ctx, &job); err != nil {
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &job); err != nil {
 			return isComplete, err
 		}
 	}
 	for serviceName, job := range jobsToCreate {
 		logger.V(1).Info("Creating migration job for service " + serviceName)
-		if err = r.Writer.Create(ctx, &job); err != nil {
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &job); err != nil {
 			return isComplete, err
 		}
 	}
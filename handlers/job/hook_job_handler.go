@@ -0,0 +1,208 @@
+package job
+
+import (
+	"context"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type HookJobHandler struct {
+	Reader client.Reader
+	Writer client.Writer
+	Scheme *runtime.Scheme
+}
+
+// EnsureHooksAreCreated creates or updates the hook jobs of the services of the site for the phase, and deletes the
+// hook jobs of the phase that are no longer configured. Returns TRUE if no jobs had to be created or deleted
+func (r HookJobHandler) EnsureHooksAreCreated(
+	site *sitev1.StagingSite,
+	ctx context.Context,
+	phase configv1.HookPhase,
+) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving hook job list", "phase", phase)
+
+	list, err := r.listHookJobs(site, ctx, phase)
+	if err != nil {
+		return false, err
+	}
+
+	logger.V(1).Info("Retrieved list", "count", len(list.Items))
+
+	jobsToCreate := make(map[string]jobv1.HookJob)
+	jobsToUpdate := make(map[string]jobv1.HookJob)
+	jobsToDelete := make(map[string]jobv1.HookJob)
+
+	for name := range site.Spec.Services {
+		var serviceConfig configv1.ServiceConfig
+		if err = r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, &serviceConfig); err != nil {
+			return false, err
+		}
+
+		for _, hook := range serviceConfig.Spec.Hooks {
+			if hook.Phase != phase {
+				continue
+			}
+
+			job, err := r.getPopulatedJob(site, &serviceConfig, hook)
+			if err != nil {
+				return false, err
+			}
+			jobsToCreate[job.Name] = job
+		}
+	}
+
+	for _, existingJob := range list.Items {
+		if jobToCreate, ok := jobsToCreate[existingJob.Name]; ok {
+			if !existingJob.Matches(&jobToCreate) {
+				jobsToUpdate[existingJob.Name] = jobToCreate
+			}
+			delete(jobsToCreate, existingJob.Name)
+		} else {
+			jobsToDelete[existingJob.Name] = existingJob
+		}
+	}
+
+	isComplete := len(jobsToDelete) == 0 && len(jobsToCreate) == 0
+
+	for name, job := range jobsToDelete {
+		logger.V(1).Info("Deleting hook job", "name", name)
+		if err = r.Writer.Delete(ctx, &job); err != nil {
+			return isComplete, err
+		}
+	}
+	for name, job := range jobsToUpdate {
+		logger.V(1).Info("Updating hook job", "name", name)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &job); err != nil {
+			return isComplete, err
+		}
+	}
+	for name, job := range jobsToCreate {
+		logger.V(1).Info("Creating hook job", "name", name)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &job); err != nil {
+			return isComplete, err
+		}
+	}
+
+	logger.V(0).Info("Hook jobs created", "phase", phase)
+
+	return isComplete, nil
+}
+
+// EnsureHooksAreComplete returns TRUE if all hook jobs of the phase that block on failure finished running. Returns a
+// HookError if one of them failed. The other hooks are not waited for, and their failures are only logged
+func (r HookJobHandler) EnsureHooksAreComplete(
+	site *sitev1.StagingSite,
+	ctx context.Context,
+	phase configv1.HookPhase,
+) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	list, err := r.listHookJobs(site, ctx, phase)
+	if err != nil {
+		return false, err
+	}
+
+	isEverythingReady := true
+
+	for _, job := range list.Items {
+		if !job.Spec.BlockOnFailure {
+			if job.Status.State == jobv1.Failed && job.GetRevision() == job.GetLastRunRevision() {
+				logger.V(0).Info("Non-blocking hook failed", "hook", job.Spec.HookName, "service", job.Spec.ServiceName)
+			}
+			continue
+		}
+
+		if job.GetRevision() != job.GetLastRunRevision() || !job.Status.State.IsFinal() {
+			// The state is stale until the controller picks up the changes of the spec
+			isEverythingReady = false
+			continue
+		}
+
+		if job.Status.State == jobv1.Failed {
+			return false, errors.HookError{
+				SiteName:    job.Spec.SiteName,
+				ServiceName: job.Spec.ServiceName,
+				Hook:        job.Spec.HookName,
+				Phase:       string(job.Spec.Phase),
+			}
+		}
+	}
+
+	if isEverythingReady {
+		logger.V(1).Info("All hook jobs are complete", "phase", phase)
+	} else {
+		logger.V(0).Info("Not all hook jobs are complete yet", "phase", phase)
+	}
+
+	return isEverythingReady, nil
+}
+
+// DeleteHooks deletes the hook jobs of the phases, so they run again the next time the site reaches the phase.
+// Returns TRUE if there was nothing to delete
+func (r HookJobHandler) DeleteHooks(
+	site *sitev1.StagingSite,
+	ctx context.Context,
+	phases ...configv1.HookPhase,
+) (bool, error) {
+	isComplete := true
+
+	for _, phase := range phases {
+		list, err := r.listHookJobs(site, ctx, phase)
+		if err != nil {
+			return false, err
+		}
+
+		for _, job := range list.Items {
+			log.FromContext(ctx).V(1).Info("Deleting hook job", "name", job.Name, "phase", phase)
+			isComplete = false
+			if err = r.Writer.Delete(ctx, &job); client.IgnoreNotFound(err) != nil {
+				return false, err
+			}
+		}
+	}
+
+	return isComplete, nil
+}
+
+func (r HookJobHandler) listHookJobs(
+	site *sitev1.StagingSite,
+	ctx context.Context,
+	phase configv1.HookPhase,
+) (jobv1.HookJobList, error) {
+	var list jobv1.HookJobList
+	err := r.Reader.List(
+		ctx,
+		&list,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{labels.Site: site.Name, labels.HookPhase: string(phase)},
+	)
+
+	return list, err
+}
+
+func (r HookJobHandler) getPopulatedJob(
+	site *sitev1.StagingSite,
+	serviceConfig *configv1.ServiceConfig,
+	hook configv1.ServiceHook,
+) (jobv1.HookJob, error) {
+	job := jobv1.HookJob{}
+	if err := job.PopulateFomSite(site, serviceConfig, hook); err != nil {
+		return job, err
+	}
+
+	if err := ctrl.SetControllerReference(site, &job, r.Scheme); err != nil {
+		return job, err
+	}
+
+	return job, nil
+}
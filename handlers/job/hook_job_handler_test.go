@@ -0,0 +1,238 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	errorhelpers "github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newHookJobHandler(objs ...client.Object) HookJobHandler {
+	c := testutil.NewFakeClient(objs...)
+	return HookJobHandler{
+		Reader: c,
+		Writer: c,
+		Scheme: testutil.NewTestScheme(),
+	}
+}
+
+func newHookTestServiceConfig() *configv1.ServiceConfig {
+	svcConfig := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+	svcConfig.Spec.Hooks = []configv1.ServiceHook{
+		{
+			Name:            "seed",
+			Phase:           configv1.HookPhasePostMigrate,
+			PodSpec:         corev1.PodSpec{Containers: []corev1.Container{{Name: "seed", Image: "seed:latest"}}},
+			DeadlineSeconds: 300,
+			BlockOnFailure:  true,
+		},
+		{
+			Name:            "cleanup",
+			Phase:           configv1.HookPhasePreDisable,
+			PodSpec:         corev1.PodSpec{Containers: []corev1.Container{{Name: "cleanup", Image: "cleanup:latest"}}},
+			DeadlineSeconds: 300,
+		},
+	}
+
+	return svcConfig
+}
+
+func newHookTestSite() *sitev1.StagingSite {
+	site := testutil.NewTestStagingSite("mysite", "test-ns", map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1"},
+	})
+	site.Status.Enabled = true
+
+	return site
+}
+
+func newPopulatedHookJob(
+	t *testing.T,
+	site *sitev1.StagingSite,
+	svcConfig *configv1.ServiceConfig,
+	hookIndex int,
+	state jobv1.JobState,
+) *jobv1.HookJob {
+	job := &jobv1.HookJob{}
+	if err := job.PopulateFomSite(site, svcConfig, svcConfig.Spec.Hooks[hookIndex]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	job.Status.State = state
+	job.Status.LastRunRevision = job.GetRevision()
+
+	return job
+}
+
+func TestHookJobHandler_EnsureHooksAreCreated_CreatesJobsOfThePhase(t *testing.T) {
+	ctx := context.Background()
+	site := newHookTestSite()
+	svcConfig := newHookTestServiceConfig()
+	handler := newHookJobHandler(site, svcConfig)
+
+	complete, err := handler.EnsureHooksAreCreated(site, ctx, configv1.HookPhasePostMigrate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false when a job had to be created")
+	}
+
+	jobList := &jobv1.HookJobList{}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(jobList.Items))
+	}
+	job := jobList.Items[0]
+	if job.Spec.HookName != "seed" || job.Spec.Phase != configv1.HookPhasePostMigrate {
+		t.Errorf("expected the seed postMigrate hook, got %s %s", job.Spec.HookName, job.Spec.Phase)
+	}
+	if job.Labels[labels.HookPhase] != string(configv1.HookPhasePostMigrate) {
+		t.Errorf("expected hook phase label %s, got %q", configv1.HookPhasePostMigrate, job.Labels[labels.HookPhase])
+	}
+	if !job.Spec.BlockOnFailure || job.Spec.DeadlineSeconds != 300 || job.Spec.ImageTag != "v1" {
+		t.Errorf("unexpected job spec: %+v", job.Spec)
+	}
+	if len(job.OwnerReferences) != 1 || job.OwnerReferences[0].Name != "mysite" {
+		t.Errorf("expected the job to be owned by the site, got %v", job.OwnerReferences)
+	}
+}
+
+func TestHookJobHandler_EnsureHooksAreCreated_RemovedHookIsDeleted(t *testing.T) {
+	ctx := context.Background()
+	site := newHookTestSite()
+	svcConfig := newHookTestServiceConfig()
+	existing := newPopulatedHookJob(t, site, svcConfig, 0, jobv1.Complete)
+	svcConfig.Spec.Hooks = nil
+	handler := newHookJobHandler(site, svcConfig, existing)
+
+	complete, err := handler.EnsureHooksAreCreated(site, ctx, configv1.HookPhasePostMigrate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false when a job had to be deleted")
+	}
+
+	jobList := &jobv1.HookJobList{}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 0 {
+		t.Errorf("expected 0 jobs, got %d", len(jobList.Items))
+	}
+}
+
+func TestHookJobHandler_EnsureHooksAreCreated_ImageTagChangeUpdatesJob(t *testing.T) {
+	ctx := context.Background()
+	site := newHookTestSite()
+	svcConfig := newHookTestServiceConfig()
+	existing := newPopulatedHookJob(t, site, svcConfig, 0, jobv1.Complete)
+	site.Spec.Services["mysvc"] = sitev1.StagingSiteService{ImageTag: "v2"}
+	handler := newHookJobHandler(site, svcConfig, existing)
+
+	complete, err := handler.EnsureHooksAreCreated(site, ctx, configv1.HookPhasePostMigrate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !complete {
+		t.Error("expected complete=true when the job only had to be updated")
+	}
+
+	updated := &jobv1.HookJob{}
+	if err = handler.Reader.Get(ctx, client.ObjectKeyFromObject(existing), updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Spec.ImageTag != "v2" {
+		t.Errorf("expected image tag v2, got %s", updated.Spec.ImageTag)
+	}
+
+	complete, err = handler.EnsureHooksAreComplete(site, ctx, configv1.HookPhasePostMigrate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false while the job state is from the previous revision")
+	}
+}
+
+func TestHookJobHandler_EnsureHooksAreComplete(t *testing.T) {
+	tests := []struct {
+		name             string
+		state            jobv1.JobState
+		blockOnFailure   bool
+		expectedComplete bool
+		expectedError    bool
+	}{
+		{name: "running", state: jobv1.Running, blockOnFailure: true, expectedComplete: false},
+		{name: "complete", state: jobv1.Complete, blockOnFailure: true, expectedComplete: true},
+		{name: "failed blocking", state: jobv1.Failed, blockOnFailure: true, expectedError: true},
+		{name: "failed non-blocking", state: jobv1.Failed, blockOnFailure: false, expectedComplete: true},
+		{name: "running non-blocking", state: jobv1.Running, blockOnFailure: false, expectedComplete: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			site := newHookTestSite()
+			svcConfig := newHookTestServiceConfig()
+			svcConfig.Spec.Hooks[0].BlockOnFailure = tt.blockOnFailure
+			existing := newPopulatedHookJob(t, site, svcConfig, 0, tt.state)
+			handler := newHookJobHandler(site, svcConfig, existing)
+
+			complete, err := handler.EnsureHooksAreComplete(site, ctx, configv1.HookPhasePostMigrate)
+			if tt.expectedError {
+				var hookError errorhelpers.HookError
+				if !errors.As(err, &hookError) {
+					t.Fatalf("expected a HookError, got %v", err)
+				}
+				if hookError.Hook != "seed" || hookError.ServiceName != "mysvc" {
+					t.Errorf("unexpected hook error: %+v", hookError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if complete != tt.expectedComplete {
+				t.Errorf("expected complete=%v, got %v", tt.expectedComplete, complete)
+			}
+		})
+	}
+}
+
+func TestHookJobHandler_DeleteHooks(t *testing.T) {
+	ctx := context.Background()
+	site := newHookTestSite()
+	svcConfig := newHookTestServiceConfig()
+	postMigrate := newPopulatedHookJob(t, site, svcConfig, 0, jobv1.Complete)
+	preDisable := newPopulatedHookJob(t, site, svcConfig, 1, jobv1.Complete)
+	handler := newHookJobHandler(site, svcConfig, postMigrate, preDisable)
+
+	complete, err := handler.DeleteHooks(site, ctx, configv1.HookPhasePreDisable, configv1.HookPhasePostDeploy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if complete {
+		t.Error("expected complete=false when a job had to be deleted")
+	}
+
+	jobList := &jobv1.HookJobList{}
+	_ = handler.Reader.List(ctx, jobList, client.InNamespace("test-ns"))
+	if len(jobList.Items) != 1 || jobList.Items[0].Name != postMigrate.Name {
+		t.Errorf("expected only the post migrate job to remain, got %v", jobList.Items)
+	}
+
+	complete, err = handler.DeleteHooks(site, ctx, configv1.HookPhasePreDisable)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !complete {
+		t.Error("expected complete=true when there was nothing to delete")
+	}
+}
@@ -0,0 +1,182 @@
+package provider
+
+import (
+	"fmt"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/handlers/task"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	MysqlProviderName = "mysql"
+	MongoProviderName = "mongo"
+	RedisProviderName = "redis"
+)
+
+func init() {
+	Register(MysqlProvider{})
+	Register(MongoProvider{})
+	Register(RedisProvider{})
+}
+
+// MysqlProvider is the built-in provider of the mysql databases
+type MysqlProvider struct{}
+
+func (r MysqlProvider) Name() string {
+	return MysqlProviderName
+}
+
+func (r MysqlProvider) NewConfig() client.Object {
+	return &configv1.MysqlConfig{}
+}
+
+func (r MysqlProvider) NewConfigList() client.ObjectList {
+	return &configv1.MysqlConfigList{}
+}
+
+func (r MysqlProvider) NewTask() client.Object {
+	return &taskv1.MysqlDatabase{}
+}
+
+func (r MysqlProvider) NewTaskList() client.ObjectList {
+	return &taskv1.MysqlDatabaseList{}
+}
+
+func (r MysqlProvider) GetEnvironmentMode(config client.Object) configv1.EnvironmentMode {
+	if mysqlConfig, ok := config.(*configv1.MysqlConfig); ok {
+		return mysqlConfig.Spec.Mode
+	}
+	return ""
+}
+
+func (r MysqlProvider) GetTemplateValues(config client.Object) map[string]string {
+	mysqlConfig, ok := config.(*configv1.MysqlConfig)
+	if !ok {
+		mysqlConfig = &configv1.MysqlConfig{}
+	}
+
+	return map[string]string{
+		"host": mysqlConfig.Spec.Host,
+		"port": fmt.Sprintf("%d", mysqlConfig.Spec.Port),
+	}
+}
+
+func (r MysqlProvider) NewTaskHandler(
+	reader client.Reader,
+	writer client.Writer,
+	scheme *runtime.Scheme,
+) task.TaskHandler {
+	return task.MysqlTaskHandler{Reader: reader, Writer: writer, Scheme: scheme}
+}
+
+// MongoProvider is the built-in provider of the mongo databases
+type MongoProvider struct{}
+
+func (r MongoProvider) Name() string {
+	return MongoProviderName
+}
+
+func (r MongoProvider) NewConfig() client.Object {
+	return &configv1.MongoConfig{}
+}
+
+func (r MongoProvider) NewConfigList() client.ObjectList {
+	return &configv1.MongoConfigList{}
+}
+
+func (r MongoProvider) NewTask() client.Object {
+	return &taskv1.MongoDatabase{}
+}
+
+func (r MongoProvider) NewTaskList() client.ObjectList {
+	return &taskv1.MongoDatabaseList{}
+}
+
+func (r MongoProvider) GetEnvironmentMode(config client.Object) configv1.EnvironmentMode {
+	if mongoConfig, ok := config.(*configv1.MongoConfig); ok {
+		return mongoConfig.Spec.Mode
+	}
+	return ""
+}
+
+func (r MongoProvider) GetTemplateValues(config client.Object) map[string]string {
+	mongoConfig, ok := config.(*configv1.MongoConfig)
+	if !ok {
+		mongoConfig = &configv1.MongoConfig{}
+	}
+
+	return map[string]string{
+		"host1": mongoConfig.Spec.Host1,
+		"host2": mongoConfig.Spec.Host2,
+		"host3": mongoConfig.Spec.Host3,
+		"port":  fmt.Sprintf("%d", mongoConfig.Spec.Port),
+	}
+}
+
+func (r MongoProvider) NewTaskHandler(
+	reader client.Reader,
+	writer client.Writer,
+	scheme *runtime.Scheme,
+) task.TaskHandler {
+	return task.MongoTaskHandler{Reader: reader, Writer: writer, Scheme: scheme}
+}
+
+// RedisProvider is the built-in provider of the redis databases
+type RedisProvider struct{}
+
+func (r RedisProvider) Name() string {
+	return RedisProviderName
+}
+
+func (r RedisProvider) NewConfig() client.Object {
+	return &configv1.RedisConfig{}
+}
+
+func (r RedisProvider) NewConfigList() client.ObjectList {
+	return &configv1.RedisConfigList{}
+}
+
+func (r RedisProvider) NewTask() client.Object {
+	return &taskv1.RedisDatabase{}
+}
+
+func (r RedisProvider) NewTaskList() client.ObjectList {
+	return &taskv1.RedisDatabaseList{}
+}
+
+func (r RedisProvider) GetEnvironmentMode(config client.Object) configv1.EnvironmentMode {
+	if redisConfig, ok := config.(*configv1.RedisConfig); ok {
+		return redisConfig.Spec.Mode
+	}
+	return ""
+}
+
+func (r RedisProvider) GetTemplateValues(config client.Object) map[string]string {
+	redisConfig, ok := config.(*configv1.RedisConfig)
+	if !ok {
+		redisConfig = &configv1.RedisConfig{}
+	}
+
+	scheme := "tcp"
+	if redisConfig.Spec.IsTlsEnabled != nil && *redisConfig.Spec.IsTlsEnabled {
+		scheme = "tls"
+	}
+
+	return map[string]string{
+		"scheme":   scheme,
+		"host":     redisConfig.Spec.Host,
+		"port":     fmt.Sprintf("%d", redisConfig.Spec.Port),
+		"password": redisConfig.Spec.Password,
+	}
+}
+
+func (r RedisProvider) NewTaskHandler(
+	reader client.Reader,
+	writer client.Writer,
+	scheme *runtime.Scheme,
+) task.TaskHandler {
+	return task.RedisTaskHandler{Reader: reader, Writer: writer, Scheme: scheme}
+}
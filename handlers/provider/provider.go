@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/handlers/task"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Provider is a database backend the staging sites can request databases from. The built-in mysql, mongo and redis
+// backends are registered by default, in-house backends can be added by registering their provider before the manager
+// is started. The config and task CRDs of a provider must be added to the scheme of the manager, and the provider is
+// responsible for running the controller of its task CRD.
+type Provider interface {
+	// Name returns the key of the provider in the databases map of the site services. It's used in label keys and
+	// template value names, so it must be a lowercase DNS label.
+	Name() string
+	// NewConfig returns an empty environment config object of the provider
+	NewConfig() client.Object
+	// NewConfigList returns an empty list of the environment configs of the provider
+	NewConfigList() client.ObjectList
+	// NewTask returns an empty database task object of the provider. It must implement taskv1.StatefulTask.
+	NewTask() client.Object
+	// NewTaskList returns an empty list of the database tasks of the provider
+	NewTaskList() client.ObjectList
+	// GetEnvironmentMode returns the mode of an environment config of the provider
+	GetEnvironmentMode(config client.Object) configv1.EnvironmentMode
+	// GetTemplateValues returns the template values of an environment config of the provider. The values are prefixed
+	// with database.<name>. by the template handler.
+	GetTemplateValues(config client.Object) map[string]string
+	// NewTaskHandler returns the handler that creates and deletes the database tasks of the sites
+	NewTaskHandler(reader client.Reader, writer client.Writer, scheme *runtime.Scheme) task.TaskHandler
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+type registry struct {
+	mu        sync.RWMutex
+	providers []Provider
+}
+
+var defaultRegistry = &registry{}
+
+// Register adds a provider to the registry. It panics if the name of the provider is invalid or already registered, as
+// that is a programming error.
+func Register(provider Provider) {
+	defaultRegistry.register(provider)
+}
+
+// Get returns the registered provider with the name
+func Get(name string) (Provider, bool) {
+	return defaultRegistry.get(name)
+}
+
+// All returns the registered providers in the order they were registered
+func All() []Provider {
+	return defaultRegistry.all()
+}
+
+// IsBuiltIn returns TRUE if the name belongs to one of the built-in providers, which are configured with their own
+// fields in the site and service specs instead of the databases map
+func IsBuiltIn(name string) bool {
+	return name == MysqlProviderName || name == MongoProviderName || name == RedisProviderName
+}
+
+// NewTaskLists returns an empty list of the database tasks of every registered provider, keyed by the provider name
+func NewTaskLists() map[string]client.ObjectList {
+	result := make(map[string]client.ObjectList)
+	for _, provider := range All() {
+		result[provider.Name()] = provider.NewTaskList()
+	}
+
+	return result
+}
+
+// ListEnvironments returns the environment configs of the provider in the namespace, keyed by their name
+func ListEnvironments(
+	ctx context.Context,
+	reader client.Reader,
+	provider Provider,
+	namespace string,
+) (map[string]client.Object, error) {
+	list := provider.NewConfigList()
+	if err := reader.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]client.Object, len(items))
+	for _, item := range items {
+		if config, ok := item.(client.Object); ok {
+			result[config.GetName()] = config
+		}
+	}
+
+	return result, nil
+}
+
+func (r *registry) register(provider Provider) {
+	name := provider.Name()
+	if !providerNamePattern.MatchString(name) || len(name) > 63 {
+		panic(fmt.Sprintf("invalid database provider name %q", name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, registered := range r.providers {
+		if registered.Name() == name {
+			panic(fmt.Sprintf("database provider %q is already registered", name))
+		}
+	}
+
+	r.providers = append(r.providers, provider)
+}
+
+func (r *registry) get(name string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, provider := range r.providers {
+		if provider.Name() == name {
+			return provider, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *registry) all() []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]Provider(nil), r.providers...)
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	"github.com/szeber/kube-stager/internal/testutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// namedProvider is a custom provider reusing the CRDs of the redis provider
+type namedProvider struct {
+	RedisProvider
+	name string
+}
+
+func (r namedProvider) Name() string {
+	return r.name
+}
+
+func expectPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected a panic", name)
+		}
+	}()
+	f()
+}
+
+func TestRegistry(t *testing.T) {
+	providers := &registry{}
+	providers.register(MysqlProvider{})
+	providers.register(namedProvider{name: "keydb"})
+
+	if got, ok := providers.get("keydb"); !ok || got.Name() != "keydb" {
+		t.Errorf("get(keydb) = %v, %v, want the keydb provider", got, ok)
+	}
+	if _, ok := providers.get("cassandra"); ok {
+		t.Error("expected no provider for an unregistered name")
+	}
+
+	all := providers.all()
+	if len(all) != 2 || all[0].Name() != MysqlProviderName || all[1].Name() != "keydb" {
+		t.Errorf("all() = %v, want the providers in the order of registration", all)
+	}
+
+	expectPanic(t, "duplicate", func() { providers.register(namedProvider{name: "keydb"}) })
+	expectPanic(t, "uppercase", func() { providers.register(namedProvider{name: "KeyDB"}) })
+	expectPanic(t, "dot", func() { providers.register(namedProvider{name: "key.db"}) })
+	expectPanic(t, "empty", func() { providers.register(namedProvider{}) })
+}
+
+func TestBuiltInProvidersAreRegistered(t *testing.T) {
+	for _, name := range []string{MysqlProviderName, MongoProviderName, RedisProviderName} {
+		if _, ok := Get(name); !ok {
+			t.Errorf("the %s provider is not registered", name)
+		}
+		if !IsBuiltIn(name) {
+			t.Errorf("IsBuiltIn(%s) = false, want true", name)
+		}
+	}
+	if IsBuiltIn("keydb") {
+		t.Error("IsBuiltIn(keydb) = true, want false")
+	}
+
+	lists := NewTaskLists()
+	if len(lists) != 3 || lists[MysqlProviderName] == nil || lists[RedisProviderName] == nil {
+		t.Errorf("NewTaskLists() = %v, want a list for every built-in provider", lists)
+	}
+}
+
+func TestListEnvironments(t *testing.T) {
+	c := testutil.NewFakeClient(
+		testutil.NewTestRedisConfig("redis1", "test-ns"),
+		testutil.NewTestRedisConfig("redis2", "test-ns"),
+		testutil.NewTestRedisConfig("redis3", "other-ns"),
+	)
+
+	environments, err := ListEnvironments(context.Background(), c, RedisProvider{}, "test-ns")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(environments) != 2 {
+		t.Fatalf("got %d environments, want 2", len(environments))
+	}
+	if _, ok := environments["redis1"].(*configv1.RedisConfig); !ok {
+		t.Errorf("environments[redis1] = %T, want *RedisConfig", environments["redis1"])
+	}
+}
+
+func TestBuiltInProviders(t *testing.T) {
+	mysqlConfig := testutil.NewTestMysqlConfig("mysql1", "test-ns")
+	mysqlConfig.Spec.Mode = configv1.EnvironmentModeDraining
+	redisConfig := testutil.NewTestRedisConfig("redis1", "test-ns")
+	isTlsEnabled := true
+	redisConfig.Spec.IsTlsEnabled = &isTlsEnabled
+
+	if got := (MysqlProvider{}).GetEnvironmentMode(mysqlConfig); got != configv1.EnvironmentModeDraining {
+		t.Errorf("GetEnvironmentMode() = %q, want Draining", got)
+	}
+	if got := (MongoProvider{}).GetEnvironmentMode(mysqlConfig); got != "" {
+		t.Errorf("GetEnvironmentMode() of another provider's config = %q, want empty", got)
+	}
+
+	values := RedisProvider{}.GetTemplateValues(redisConfig)
+	if values["scheme"] != "tls" || values["host"] != redisConfig.Spec.Host {
+		t.Errorf("unexpected redis template values: %v", values)
+	}
+
+	var missing client.Object
+	if values := (MongoProvider{}).GetTemplateValues(missing); values["port"] != "0" || values["host1"] != "" {
+		t.Errorf("unexpected template values for a missing config: %v", values)
+	}
+}
@@ -0,0 +1,183 @@
+package site
+
+import (
+	"context"
+	api "github.com/szeber/kube-stager/apis"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/template"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
+	"github.com/szeber/kube-stager/helpers/labels"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// The average cpu utilisation the deployments are scaled by if the service config doesn't list any metrics
+const defaultAutoscalingCpuUtilisation = int32(80)
+
+type AutoscalerHandler struct {
+	Reader client.Reader
+	Writer client.Writer
+	Scheme *runtime.Scheme
+}
+
+// EnsureAutoscalersAreUpToDate creates or updates the HorizontalPodAutoscalers of the services of the site that have
+// autoscaling enabled, and deletes the ones that are no longer needed. All autoscalers are deleted for disabled sites
+func (r AutoscalerHandler) EnsureAutoscalersAreUpToDate(site *sitev1.StagingSite, ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving autoscaler list")
+
+	var list autoscalingv2.HorizontalPodAutoscalerList
+	err := r.Reader.List(
+		ctx,
+		&list,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{
+			labels.Site: site.Name,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	autoscalersToApply := make(map[string]autoscalingv2.HorizontalPodAutoscaler)
+
+	if site.Status.Enabled {
+		for name := range site.Spec.Services {
+			config := &configv1.ServiceConfig{}
+			if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+				return err
+			}
+
+			autoscaling := getAutoscaling(site, config)
+			if autoscaling == nil {
+				continue
+			}
+
+			autoscaler, err := r.createAutoscaler(site, config, autoscaling, ctx)
+			if err != nil {
+				return err
+			}
+			autoscalersToApply[name] = *autoscaler
+		}
+	}
+
+	for _, autoscaler := range list.Items {
+		serviceName := autoscaler.Labels[labels.Service]
+
+		if _, ok := autoscalersToApply[serviceName]; !ok {
+			logger.V(1).Info("Deleting autoscaler for service " + serviceName)
+			if err = r.Writer.Delete(ctx, &autoscaler); err != nil {
+				return err
+			}
+		}
+	}
+	for serviceName, autoscaler := range autoscalersToApply {
+		logger.V(1).Info("Applying autoscaler for service " + serviceName)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &autoscaler); err != nil {
+			return err
+		}
+	}
+
+	logger.V(0).Info("Autoscalers created")
+
+	return nil
+}
+
+func (r AutoscalerHandler) createAutoscaler(
+	site *sitev1.StagingSite,
+	serviceConfig *configv1.ServiceConfig,
+	autoscaling *configv1.ServiceAutoscaling,
+	ctx context.Context,
+) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	templateHandler := template.NewSite(*site, *serviceConfig)
+	err := template.LoadConfigs(&templateHandler, ctx, r.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := autoscaling.Metrics
+	if len(metrics) == 0 {
+		cpuUtilisation := defaultAutoscalingCpuUtilisation
+		metrics = []autoscalingv2.MetricSpec{
+			{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: &cpuUtilisation,
+					},
+				},
+			},
+		}
+	}
+
+	spec, err := helpers.ReplaceTemplateVariablesInHorizontalPodAutoscalerSpec(
+		autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       api.MakeDeploymentName(site, serviceConfig),
+			},
+			MinReplicas: autoscaling.MinReplicas,
+			MaxReplicas: autoscaling.MaxReplicas,
+			Metrics:     metrics,
+			Behavior:    autoscaling.Behavior,
+		},
+		&templateHandler,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	autoscaler := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      api.MakeHorizontalPodAutoscalerName(site, serviceConfig),
+			Namespace: site.Namespace,
+			Labels: map[string]string{
+				labels.Site:    site.Name,
+				labels.Service: serviceConfig.Name,
+			},
+		},
+		Spec: spec,
+	}
+
+	if err := ctrl.SetControllerReference(site, autoscaler, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return autoscaler, nil
+}
+
+// getAutoscaling returns the autoscaling settings of the service in the site with the overrides of the site applied, or
+// nil if the service is not autoscaled in the site or has no deployment
+func getAutoscaling(site *sitev1.StagingSite, serviceConfig *configv1.ServiceConfig) *configv1.ServiceAutoscaling {
+	if serviceConfig.Spec.Autoscaling == nil || serviceConfig.Spec.DeploymentPodSpec == nil {
+		return nil
+	}
+
+	result := serviceConfig.Spec.Autoscaling.DeepCopy()
+	override := site.Spec.Services[serviceConfig.Name].Autoscaling
+	if override == nil {
+		return result
+	}
+	if override.Enabled != nil && !*override.Enabled {
+		return nil
+	}
+	if override.MinReplicas != nil {
+		result.MinReplicas = override.MinReplicas
+	}
+	if override.MaxReplicas != nil {
+		result.MaxReplicas = *override.MaxReplicas
+	}
+
+	return result
+}
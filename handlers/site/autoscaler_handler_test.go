@@ -0,0 +1,171 @@
+package site
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/internal/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newAutoscalerTestServiceConfig(svcName, namespace, shortName string) *configv1.ServiceConfig {
+	sc := testutil.NewTestServiceConfig(svcName, namespace, shortName)
+	minReplicas := int32(2)
+	sc.Spec.Autoscaling = &configv1.ServiceAutoscaling{
+		MinReplicas: &minReplicas,
+		MaxReplicas: 5,
+	}
+
+	return sc
+}
+
+func listAutoscalers(t *testing.T, c client.Client, namespace, siteName string) []autoscalingv2.HorizontalPodAutoscaler {
+	var list autoscalingv2.HorizontalPodAutoscalerList
+	if err := c.List(context.Background(), &list, client.InNamespace(namespace), client.MatchingLabels{
+		"operator.kube-stager.io/site": siteName,
+	}); err != nil {
+		t.Fatalf("failed to list HorizontalPodAutoscalers: %v", err)
+	}
+
+	return list.Items
+}
+
+func TestAutoscalerHandler_EnsureAutoscalersAreUpToDate_CreatesAutoscaler(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newAutoscalerTestServiceConfig(svcName, namespace, shortName)
+	maxReplicas := int32(8)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1, Autoscaling: &sitev1.StagingSiteAutoscaling{MaxReplicas: &maxReplicas}},
+	})
+	site.Status.Enabled = true
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := AutoscalerHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if err := handler.EnsureAutoscalersAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureAutoscalersAreUpToDate returned unexpected error: %v", err)
+	}
+
+	autoscalers := listAutoscalers(t, fakeClient, namespace, siteName)
+	if len(autoscalers) != 1 {
+		t.Fatalf("expected 1 HorizontalPodAutoscaler, got %d", len(autoscalers))
+	}
+	autoscaler := autoscalers[0]
+	if autoscaler.Spec.ScaleTargetRef.Kind != "Deployment" || autoscaler.Spec.ScaleTargetRef.Name != "test-site-svc" {
+		t.Errorf("unexpected scale target: %v", autoscaler.Spec.ScaleTargetRef)
+	}
+	if autoscaler.Spec.MinReplicas == nil || *autoscaler.Spec.MinReplicas != 2 {
+		t.Errorf("expected min replicas 2 from the service config, got %v", autoscaler.Spec.MinReplicas)
+	}
+	if autoscaler.Spec.MaxReplicas != 8 {
+		t.Errorf("expected max replicas 8 from the site override, got %d", autoscaler.Spec.MaxReplicas)
+	}
+	if len(autoscaler.Spec.Metrics) != 1 || autoscaler.Spec.Metrics[0].Resource == nil {
+		t.Errorf("expected the default cpu metric, got %v", autoscaler.Spec.Metrics)
+	}
+}
+
+func TestAutoscalerHandler_EnsureAutoscalersAreUpToDate_SiteDisablesAutoscaling(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newAutoscalerTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := AutoscalerHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if err := handler.EnsureAutoscalersAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureAutoscalersAreUpToDate returned unexpected error: %v", err)
+	}
+	if len(listAutoscalers(t, fakeClient, namespace, siteName)) != 1 {
+		t.Fatal("expected the HorizontalPodAutoscaler to be created")
+	}
+
+	disabled := false
+	service := site.Spec.Services[svcName]
+	service.Autoscaling = &sitev1.StagingSiteAutoscaling{Enabled: &disabled}
+	site.Spec.Services[svcName] = service
+
+	if err := handler.EnsureAutoscalersAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureAutoscalersAreUpToDate returned unexpected error: %v", err)
+	}
+	if autoscalers := listAutoscalers(t, fakeClient, namespace, siteName); len(autoscalers) != 0 {
+		t.Errorf("expected the HorizontalPodAutoscaler to be deleted, got %d", len(autoscalers))
+	}
+}
+
+func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_AutoscaledDeployment(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newAutoscalerTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+	site.Status.Services = map[string]sitev1.StagingSiteServiceStatus{svcName: {}}
+
+	replicas := int32(4)
+	existing := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-site-svc",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"operator.kube-stager.io/site":    siteName,
+				"operator.kube-stager.io/service": svcName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	autoscaler := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-site-svc", Namespace: namespace},
+		Spec:       autoscalingv2.HorizontalPodAutoscalerSpec{MaxReplicas: 5},
+	}
+
+	autoscaler.Status.DesiredReplicas = 3
+	existing.Status.ReadyReplicas = 3
+	fakeClient := testutil.NewFakeClient(site, sc, existing, autoscaler)
+
+	handler := WorkloadHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+	if _, err := handler.EnsureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureWorkloadObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	if site.Status.WorkloadHealth != sitev1.WorkloadHealthHealthy {
+		t.Errorf("expected the health to be based on the desired replicas of the autoscaler, got %s", site.Status.WorkloadHealth)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "test-site-svc"}, deployment); err != nil {
+		t.Fatalf("failed to get the deployment: %v", err)
+	}
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 4 {
+		t.Errorf("expected the replica count to be left to the autoscaler, got %v", deployment.Spec.Replicas)
+	}
+}
@@ -0,0 +1,266 @@
+package site
+
+import (
+	"context"
+	"fmt"
+	api "github.com/szeber/kube-stager/apis"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/chart"
+	"github.com/szeber/kube-stager/handlers/template"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type ChartHandler struct {
+	Reader     client.Reader
+	Writer     client.Writer
+	Scheme     *runtime.Scheme
+	RESTMapper meta.RESTMapper
+	Charts     *chart.Loader
+}
+
+// EnsureChartObjectsAreUpToDate renders the helm charts of the services of the site, applies the rendered objects and
+// deletes the previously applied ones that are no longer rendered. Every chart object is deleted while the site is
+// disabled, and the site is unhealthy until the deployments and stateful sets of the charts are ready. Returns TRUE if
+// the site status changed
+func (r ChartHandler) EnsureChartObjectsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+	previousHealth := site.Status.WorkloadHealth
+
+	var objects []*unstructured.Unstructured
+	if site.Status.Enabled {
+		for name := range site.Spec.Services {
+			config := &configv1.ServiceConfig{}
+			if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+				return false, err
+			}
+
+			serviceObjects, err := r.renderChart(site, config, ctx)
+			if err != nil {
+				return false, err
+			}
+			objects = append(objects, serviceObjects...)
+		}
+	}
+
+	appliedObjects, err := applyObjects(ctx, r.Writer, site.Namespace, objects, site.Status.ChartObjects)
+	if err != nil {
+		return false, err
+	}
+
+	isReady, err := r.areChartWorkloadsReady(site.Namespace, appliedObjects, ctx)
+	if err != nil {
+		return false, err
+	}
+	if !isReady && site.Status.WorkloadHealth == sitev1.WorkloadHealthHealthy {
+		site.Status.WorkloadHealth = sitev1.WorkloadHealthUnhealthy
+	}
+
+	if isReferenceListEqual(site.Status.ChartObjects, appliedObjects) {
+		return site.Status.WorkloadHealth != previousHealth, nil
+	}
+
+	if len(appliedObjects) == 0 {
+		site.Status.ChartObjects = nil
+	} else {
+		site.Status.ChartObjects = appliedObjects
+	}
+
+	logger.V(0).Info("Helm chart objects applied", "count", len(appliedObjects))
+
+	return true, nil
+}
+
+// EnsureChartWorkloadsArePaused removes the deployments and stateful sets rendered from the helm charts while the
+// databases of the site are being moved. They are recreated by EnsureChartObjectsAreUpToDate once the moves are done.
+func (r ChartHandler) EnsureChartWorkloadsArePaused(site *sitev1.StagingSite, ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	for _, reference := range site.Status.ChartObjects {
+		if !isChartWorkload(reference) {
+			continue
+		}
+
+		logger.V(1).Info("Pausing helm chart workload", "kind", reference.Kind, "name", reference.Name)
+		if err := deleteReferencedObject(ctx, r.Writer, site.Namespace, reference); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r ChartHandler) renderChart(
+	site *sitev1.StagingSite,
+	serviceConfig *configv1.ServiceConfig,
+	ctx context.Context,
+) ([]*unstructured.Unstructured, error) {
+	if serviceConfig.Spec.HelmChart == nil {
+		return nil, nil
+	}
+
+	templateHandler := template.NewSite(*site, *serviceConfig)
+	if err := template.LoadConfigs(&templateHandler, ctx, r.Reader); err != nil {
+		return nil, err
+	}
+
+	values, err := helpers.ReplaceTemplateVariablesInHelmValues(
+		serviceConfig.Spec.HelmChart.ValuesTemplate,
+		&templateHandler,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	helmChart, err := r.Charts.Load(ctx, r.Reader, serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	releaseName := api.MakeHelmReleaseName(site, serviceConfig)
+	objects, err := chart.Render(helmChart, releaseName, site.Namespace, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the helm chart of the service %s: %w", serviceConfig.Name, err)
+	}
+
+	for _, object := range objects {
+		gvk := object.GroupVersionKind()
+		mapping, err := r.RESTMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"invalid object %s %s in the helm chart of the service %s: %w",
+				object.GetKind(),
+				object.GetName(),
+				serviceConfig.Name,
+				err,
+			)
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return nil, fmt.Errorf(
+				"invalid object %s %s in the helm chart of the service %s: only namespaced objects are supported",
+				object.GetKind(),
+				object.GetName(),
+				serviceConfig.Name,
+			)
+		}
+		if object.GetNamespace() != "" && object.GetNamespace() != site.Namespace {
+			return nil, fmt.Errorf(
+				"invalid object %s %s in the helm chart of the service %s: the objects are created in the namespace of the site",
+				object.GetKind(),
+				object.GetName(),
+				serviceConfig.Name,
+			)
+		}
+		object.SetNamespace(site.Namespace)
+
+		// The objects don't get the site label, as the other handlers of the site delete the objects with the site
+		// label that they didn't create themselves
+		objectLabels := object.GetLabels()
+		if objectLabels == nil {
+			objectLabels = map[string]string{}
+		}
+		objectLabels[labels.Service] = serviceConfig.Name
+		objectLabels[labels.HelmRelease] = releaseName
+		object.SetLabels(objectLabels)
+
+		// The pods get the site label though, so they are covered by the network isolation of the site
+		if err := setPodTemplateLabels(object, map[string]string{labels.Site: site.Name}); err != nil {
+			return nil, err
+		}
+
+		if err := ctrl.SetControllerReference(site, object, r.Scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	return objects, nil
+}
+
+// areChartWorkloadsReady returns TRUE if all the deployments and stateful sets in the references have as many ready
+// replicas as desired
+func (r ChartHandler) areChartWorkloadsReady(
+	namespace string,
+	references []sitev1.ExtraObjectReference,
+	ctx context.Context,
+) (bool, error) {
+	for _, reference := range references {
+		if !isChartWorkload(reference) {
+			continue
+		}
+
+		key := client.ObjectKey{Namespace: namespace, Name: reference.Name}
+		var replicas *int32
+		var readyReplicas int32
+		if reference.Kind == "Deployment" {
+			deployment := &appsv1.Deployment{}
+			if err := r.Reader.Get(ctx, key, deployment); apierrors.IsNotFound(err) {
+				return false, nil
+			} else if err != nil {
+				return false, err
+			}
+			replicas, readyReplicas = deployment.Spec.Replicas, deployment.Status.ReadyReplicas
+		} else {
+			statefulSet := &appsv1.StatefulSet{}
+			if err := r.Reader.Get(ctx, key, statefulSet); apierrors.IsNotFound(err) {
+				return false, nil
+			} else if err != nil {
+				return false, err
+			}
+			replicas, readyReplicas = statefulSet.Spec.Replicas, statefulSet.Status.ReadyReplicas
+		}
+
+		desiredReplicas := int32(1)
+		if replicas != nil {
+			desiredReplicas = *replicas
+		}
+		if readyReplicas != desiredReplicas {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func isChartWorkload(reference sitev1.ExtraObjectReference) bool {
+	return reference.APIVersion == "apps/v1" && (reference.Kind == "Deployment" || reference.Kind == "StatefulSet")
+}
+
+// setPodTemplateLabels adds the labels to the pod template of the object if it's a workload
+func setPodTemplateLabels(object *unstructured.Unstructured, podLabels map[string]string) error {
+	var fields []string
+	switch object.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		schema.GroupKind{Group: "apps", Kind: "StatefulSet"},
+		schema.GroupKind{Group: "apps", Kind: "DaemonSet"},
+		schema.GroupKind{Group: "apps", Kind: "ReplicaSet"},
+		schema.GroupKind{Group: "batch", Kind: "Job"}:
+		fields = []string{"spec", "template", "metadata", "labels"}
+	case schema.GroupKind{Group: "batch", Kind: "CronJob"}:
+		fields = []string{"spec", "jobTemplate", "spec", "template", "metadata", "labels"}
+	default:
+		return nil
+	}
+
+	templateLabels, _, err := unstructured.NestedStringMap(object.Object, fields...)
+	if err != nil {
+		return fmt.Errorf("invalid pod template labels in %s %s: %w", object.GetKind(), object.GetName(), err)
+	}
+	if templateLabels == nil {
+		templateLabels = map[string]string{}
+	}
+	for name, value := range podLabels {
+		templateLabels[name] = value
+	}
+
+	return unstructured.SetNestedStringMap(object.Object, templateLabels, fields...)
+}
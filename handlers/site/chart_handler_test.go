@@ -0,0 +1,218 @@
+package site
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/chart"
+	"github.com/szeber/kube-stager/internal/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testChartConfigMapTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-config
+  labels:
+    app: {{ .Chart.Name }}
+data:
+  host: {{ .Values.host }}
+`
+
+const testChartDeploymentTemplate = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+spec:
+  selector:
+    matchLabels:
+      app: {{ .Release.Name }}
+  template:
+    metadata:
+      labels:
+        app: {{ .Release.Name }}
+    spec:
+      containers:
+        - name: web
+          image: nginx
+`
+
+func newChartTestObjects(templates map[string]string) (*configv1.ServiceConfig, *corev1.ConfigMap, *sitev1.StagingSite) {
+	sc := testutil.NewTestServiceConfig("my-service", "default", "svc")
+	sc.Spec.DeploymentPodSpec = nil
+	sc.Spec.HelmChart = &configv1.ServiceHelmChart{
+		ConfigMap:      &configv1.ServiceHelmChartConfigMap{Name: "my-service-chart"},
+		ValuesTemplate: "host: ${site.name}.example.com\n",
+	}
+	chartConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-service-chart", Namespace: "default"},
+		BinaryData: map[string][]byte{
+			chart.DefaultConfigMapKey: testutil.NewTestChartArchive("host: localhost\n", templates),
+		},
+	}
+	site := testutil.NewTestStagingSite("test-site", "default", map[string]sitev1.StagingSiteService{
+		"my-service": {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+	site.Status.WorkloadHealth = sitev1.WorkloadHealthHealthy
+
+	return sc, chartConfigMap, site
+}
+
+func newChartTestHandler(fakeClient client.Client) ChartHandler {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+
+	return ChartHandler{
+		Reader:     fakeClient,
+		Writer:     fakeClient,
+		Scheme:     testutil.NewTestScheme(),
+		RESTMapper: mapper,
+		Charts:     chart.NewLoader(),
+	}
+}
+
+func TestChartHandler_EnsureChartObjectsAreUpToDate_AppliesObjects(t *testing.T) {
+	ctx := context.Background()
+	sc, chartConfigMap, site := newChartTestObjects(map[string]string{
+		"configmap.yaml":  testChartConfigMapTemplate,
+		"deployment.yaml": testChartDeploymentTemplate,
+	})
+	fakeClient := testutil.NewFakeClient(site, sc, chartConfigMap)
+	handler := newChartTestHandler(fakeClient)
+
+	changed, err := handler.EnsureChartObjectsAreUpToDate(site, ctx)
+	if err != nil {
+		t.Fatalf("EnsureChartObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected the site status to change")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc-config"}, configMap); err != nil {
+		t.Fatalf("failed to get the rendered ConfigMap: %v", err)
+	}
+	if configMap.Data["host"] != "test-site.example.com" {
+		t.Errorf("expected the values template to be used, got %q", configMap.Data["host"])
+	}
+	if configMap.Labels["app"] != "app" ||
+		configMap.Labels["operator.kube-stager.io/service"] != "my-service" ||
+		configMap.Labels["operator.kube-stager.io/helm-release"] != "test-site-svc" {
+		t.Errorf("expected the chart, service and release labels to be set, got %v", configMap.Labels)
+	}
+	if _, ok := configMap.Labels["operator.kube-stager.io/site"]; ok {
+		t.Error("expected the rendered objects not to get the site label")
+	}
+	if len(configMap.OwnerReferences) != 1 || configMap.OwnerReferences[0].Name != "test-site" {
+		t.Errorf("expected the ConfigMap to be owned by the site, got %v", configMap.OwnerReferences)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc"}, deployment); err != nil {
+		t.Fatalf("failed to get the rendered Deployment: %v", err)
+	}
+	if deployment.Spec.Template.Labels["operator.kube-stager.io/site"] != "test-site" ||
+		deployment.Spec.Template.Labels["app"] != "test-site-svc" {
+		t.Errorf("expected the site label to be added to the pod template, got %v", deployment.Spec.Template.Labels)
+	}
+
+	if len(site.Status.ChartObjects) != 2 {
+		t.Fatalf("expected 2 chart objects in the status, got %v", site.Status.ChartObjects)
+	}
+	if site.Status.WorkloadHealth != sitev1.WorkloadHealthUnhealthy {
+		t.Errorf("expected the site to be unhealthy until the deployment is ready, got %s", site.Status.WorkloadHealth)
+	}
+}
+
+func TestChartHandler_EnsureChartObjectsAreUpToDate_PrunesObjects(t *testing.T) {
+	ctx := context.Background()
+	sc, chartConfigMap, site := newChartTestObjects(map[string]string{"configmap.yaml": testChartConfigMapTemplate})
+	stale := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "test-site-svc-old", Namespace: "default"}}
+	site.Status.ChartObjects = []sitev1.ExtraObjectReference{
+		{ServiceName: "my-service", APIVersion: "v1", Kind: "ConfigMap", Name: "test-site-svc-old"},
+	}
+	fakeClient := testutil.NewFakeClient(site, sc, chartConfigMap, stale)
+	handler := newChartTestHandler(fakeClient)
+
+	if _, err := handler.EnsureChartObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureChartObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc-old"}, &corev1.ConfigMap{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the object no longer rendered to be deleted, got %v", err)
+	}
+	if len(site.Status.ChartObjects) != 1 || site.Status.ChartObjects[0].Name != "test-site-svc-config" {
+		t.Errorf("expected only the rendered ConfigMap in the status, got %v", site.Status.ChartObjects)
+	}
+
+	changed, err := handler.EnsureChartObjectsAreUpToDate(site, ctx)
+	if err != nil {
+		t.Fatalf("second EnsureChartObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	if changed {
+		t.Error("expected no status change on the second run")
+	}
+
+	site.Status.Enabled = false
+	if _, err := handler.EnsureChartObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureChartObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	err = fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc-config"}, &corev1.ConfigMap{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the chart objects of the disabled site to be deleted, got %v", err)
+	}
+	if site.Status.ChartObjects != nil {
+		t.Errorf("expected no chart objects in the status, got %v", site.Status.ChartObjects)
+	}
+}
+
+func TestChartHandler_EnsureChartObjectsAreUpToDate_RejectsClusterScopedObjects(t *testing.T) {
+	ctx := context.Background()
+	sc, chartConfigMap, site := newChartTestObjects(map[string]string{
+		"clusterrole.yaml": "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: {{ .Release.Name }}\n",
+	})
+	fakeClient := testutil.NewFakeClient(site, sc, chartConfigMap)
+	handler := newChartTestHandler(fakeClient)
+
+	_, err := handler.EnsureChartObjectsAreUpToDate(site, ctx)
+	if err == nil || !strings.Contains(err.Error(), "only namespaced objects") {
+		t.Errorf("expected an error for the cluster scoped object, got %v", err)
+	}
+}
+
+func TestChartHandler_EnsureChartWorkloadsArePaused(t *testing.T) {
+	ctx := context.Background()
+	sc, chartConfigMap, site := newChartTestObjects(map[string]string{
+		"configmap.yaml":  testChartConfigMapTemplate,
+		"deployment.yaml": testChartDeploymentTemplate,
+	})
+	fakeClient := testutil.NewFakeClient(site, sc, chartConfigMap)
+	handler := newChartTestHandler(fakeClient)
+
+	if _, err := handler.EnsureChartObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureChartObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	if err := handler.EnsureChartWorkloadsArePaused(site, ctx); err != nil {
+		t.Fatalf("EnsureChartWorkloadsArePaused returned unexpected error: %v", err)
+	}
+
+	err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc"}, &appsv1.Deployment{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the Deployment to be deleted, got %v", err)
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc-config"}, &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the ConfigMap to be kept: %v", err)
+	}
+}
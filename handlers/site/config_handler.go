@@ -7,11 +7,11 @@ import (
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	"github.com/szeber/kube-stager/handlers/template"
 	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
 	"github.com/szeber/kube-stager/helpers/labels"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -56,8 +56,7 @@ func (r ConfigHandler) ensureConfigmapsAreUpToDate(site *sitev1.StagingSite, ctx
 		return false, err
 	}
 
-	dotenvsToCreate := make(map[string]corev1.ConfigMap)
-	dotenvsToUpdate := make(map[string]corev1.ConfigMap)
+	dotenvsToApply := make(map[string]corev1.ConfigMap)
 	dotenvsToDelete := make(map[string]corev1.ConfigMap)
 
 	for name := range site.Spec.Services {
@@ -71,7 +70,7 @@ func (r ConfigHandler) ensureConfigmapsAreUpToDate(site *sitev1.StagingSite, ctx
 			if err != nil {
 				return false, err
 			}
-			dotenvsToCreate[r.makeConfigmapKey(config.Name, key)] = dotenv
+			dotenvsToApply[r.makeConfigmapKey(config.Name, key)] = dotenv
 		}
 	}
 
@@ -81,13 +80,7 @@ func (r ConfigHandler) ensureConfigmapsAreUpToDate(site *sitev1.StagingSite, ctx
 			configMap.Labels[labels.Type],
 		)
 
-		if dotenvToCreate, ok := dotenvsToCreate[configmapKey]; ok {
-			if !reflect.DeepEqual(dotenvToCreate.Data, configMap.Data) {
-				configMap.Data = dotenvToCreate.Data
-				dotenvsToUpdate[configmapKey] = configMap
-			}
-			delete(dotenvsToCreate, configmapKey)
-		} else {
+		if _, ok := dotenvsToApply[configmapKey]; !ok {
 			dotenvsToDelete[configmapKey] = configMap
 		}
 	}
@@ -99,17 +92,10 @@ func (r ConfigHandler) ensureConfigmapsAreUpToDate(site *sitev1.StagingSite, ctx
 			return false, err
 		}
 	}
-	for serviceName, configMap := range dotenvsToUpdate {
-		configmapType := configMap.Labels[labels.Type]
-		logger.V(1).Info("Updating " + configmapType + " configmap for service " + serviceName)
-		if err = r.Writer.Update(ctx, &configMap); err != nil {
-			return false, err
-		}
-	}
-	for serviceName, configMap := range dotenvsToCreate {
+	for serviceName, configMap := range dotenvsToApply {
 		configmapType := configMap.Labels[labels.Type]
-		logger.V(1).Info("Creating " + configmapType + " configmap for service " + serviceName)
-		if err = r.Writer.Create(ctx, &configMap); err != nil {
+		logger.V(1).Info("Applying " + configmapType + " configmap for service " + serviceName)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &configMap); err != nil {
 			return false, err
 		}
 	}
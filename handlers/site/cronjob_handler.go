@@ -0,0 +1,170 @@
+package site
+
+import (
+	"context"
+	api "github.com/szeber/kube-stager/apis"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/template"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/helpers/pod"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type CronJobHandler struct {
+	Reader client.Reader
+	Writer client.Writer
+	Scheme *runtime.Scheme
+}
+
+// EnsureCronJobsAreUpToDate creates or updates the cron jobs of the services of the site, and deletes the ones that are
+// no longer configured or whose service was removed from the site. The cron jobs of disabled sites are suspended
+func (r CronJobHandler) EnsureCronJobsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) error {
+	return r.ensureCronJobsAreUpToDate(site, ctx, !site.Status.Enabled)
+}
+
+// EnsureCronJobsAreSuspended suspends the cron jobs of the site while its databases are being moved. They are resumed by
+// EnsureCronJobsAreUpToDate once the moves are done
+func (r CronJobHandler) EnsureCronJobsAreSuspended(site *sitev1.StagingSite, ctx context.Context) error {
+	return r.ensureCronJobsAreUpToDate(site, ctx, true)
+}
+
+func (r CronJobHandler) ensureCronJobsAreUpToDate(site *sitev1.StagingSite, ctx context.Context, suspend bool) error {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving cron job list")
+
+	var list batchv1.CronJobList
+	err := r.Reader.List(
+		ctx,
+		&list,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{
+			labels.Site: site.Name,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	cronJobsToApply := make(map[string]batchv1.CronJob)
+	cronJobsToDelete := make(map[string]batchv1.CronJob)
+
+	for name := range site.Spec.Services {
+		config := &configv1.ServiceConfig{}
+		if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+			return err
+		}
+
+		for cronJobName, cronJobConfig := range config.Spec.CronJobs {
+			cronJob, err := r.createCronJob(site, config, cronJobName, cronJobConfig, suspend, ctx)
+			if err != nil {
+				return err
+			}
+			cronJobsToApply[r.makeCronJobKey(config.Name, cronJobName)] = *cronJob
+		}
+	}
+
+	for _, existingCronJob := range list.Items {
+		key := r.makeCronJobKey(existingCronJob.Labels[labels.Service], existingCronJob.Labels[labels.CronJob])
+
+		if _, ok := cronJobsToApply[key]; !ok {
+			cronJobsToDelete[key] = existingCronJob
+		}
+	}
+
+	for key, cronJob := range cronJobsToDelete {
+		logger.V(1).Info("Deleting cron job", "key", key)
+		if err = r.Writer.Delete(ctx, &cronJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+			return err
+		}
+	}
+	for key, cronJob := range cronJobsToApply {
+		logger.V(1).Info("Applying cron job", "key", key)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &cronJob); err != nil {
+			return err
+		}
+	}
+
+	logger.V(0).Info("Cron jobs created", "suspended", suspend)
+
+	return nil
+}
+
+func (r CronJobHandler) makeCronJobKey(serviceName string, cronJobName string) string {
+	return serviceName + "." + cronJobName
+}
+
+func (r CronJobHandler) createCronJob(
+	site *sitev1.StagingSite,
+	serviceConfig *configv1.ServiceConfig,
+	name string,
+	cronJobConfig configv1.ServiceCronJob,
+	suspend bool,
+	ctx context.Context,
+) (*batchv1.CronJob, error) {
+	templateHandler := template.NewSite(*site, *serviceConfig)
+	err := template.LoadConfigs(&templateHandler, ctx, r.Reader)
+	if err != nil {
+		return nil, err
+	}
+	labelsMap := map[string]string{
+		labels.Site:    site.Name,
+		labels.Service: serviceConfig.Name,
+		labels.CronJob: name,
+	}
+
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(cronJobConfig.PodSpec, &templateHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	if podSpec.RestartPolicy != corev1.RestartPolicyOnFailure && podSpec.RestartPolicy != corev1.RestartPolicyNever {
+		podSpec.RestartPolicy = corev1.RestartPolicyOnFailure
+	}
+
+	concurrencyPolicy := cronJobConfig.ConcurrencyPolicy
+	if concurrencyPolicy == "" {
+		concurrencyPolicy = batchv1.ForbidConcurrent
+	}
+
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      api.MakeCronJobName(site, serviceConfig, name),
+			Namespace: site.Namespace,
+			Labels:    labelsMap,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          cronJobConfig.Schedule,
+			ConcurrencyPolicy: concurrencyPolicy,
+			Suspend:           &suspend,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labelsMap,
+				},
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labelsMap,
+						},
+						Spec: pod.SetExtraEnvVarsOnPodSpec(podSpec, site, serviceConfig),
+					},
+				},
+			},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(site, cronJob, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	return cronJob, nil
+}
@@ -0,0 +1,186 @@
+package site
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/internal/testutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newCronJobTestServiceConfig(svcName, namespace, shortName string) *configv1.ServiceConfig {
+	sc := testutil.NewTestServiceConfig(svcName, namespace, shortName)
+	sc.Spec.CronJobs = map[string]configv1.ServiceCronJob{
+		"report": {
+			Schedule: "0 3 * * *",
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:    "report",
+					Image:   "report:latest",
+					Command: []string{"report", "--site", "${site.name}"},
+				}},
+			},
+		},
+	}
+
+	return sc
+}
+
+func listCronJobs(t *testing.T, c client.Client, namespace, siteName string) []batchv1.CronJob {
+	var list batchv1.CronJobList
+	if err := c.List(context.Background(), &list, client.InNamespace(namespace), client.MatchingLabels{
+		"operator.kube-stager.io/site": siteName,
+	}); err != nil {
+		t.Fatalf("failed to list CronJobs: %v", err)
+	}
+
+	return list.Items
+}
+
+func TestCronJobHandler_EnsureCronJobsAreUpToDate_CreatesCronJob(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newCronJobTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := CronJobHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if err := handler.EnsureCronJobsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureCronJobsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	cronJobs := listCronJobs(t, fakeClient, namespace, siteName)
+	if len(cronJobs) != 1 {
+		t.Fatalf("expected 1 CronJob, got %d", len(cronJobs))
+	}
+	cronJob := cronJobs[0]
+	if cronJob.Name != "test-site-svc-report" {
+		t.Errorf("expected CronJob name test-site-svc-report, got %s", cronJob.Name)
+	}
+	if cronJob.Spec.Schedule != "0 3 * * *" {
+		t.Errorf("expected schedule 0 3 * * *, got %s", cronJob.Spec.Schedule)
+	}
+	if cronJob.Spec.ConcurrencyPolicy != batchv1.ForbidConcurrent {
+		t.Errorf("expected the concurrency policy to default to Forbid, got %s", cronJob.Spec.ConcurrencyPolicy)
+	}
+	if cronJob.Spec.Suspend == nil || *cronJob.Spec.Suspend {
+		t.Error("expected the CronJob of an enabled site not to be suspended")
+	}
+	podSpec := cronJob.Spec.JobTemplate.Spec.Template.Spec
+	if podSpec.RestartPolicy != corev1.RestartPolicyOnFailure {
+		t.Errorf("expected restart policy OnFailure, got %s", podSpec.RestartPolicy)
+	}
+	if got := podSpec.Containers[0].Command[2]; got != siteName {
+		t.Errorf("expected the site name template value to be replaced, got %s", got)
+	}
+}
+
+func TestCronJobHandler_EnsureCronJobsAreUpToDate_DisabledSiteSuspendsCronJob(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newCronJobTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := CronJobHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if err := handler.EnsureCronJobsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureCronJobsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	site.Status.Enabled = false
+	if err := handler.EnsureCronJobsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureCronJobsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	cronJobs := listCronJobs(t, fakeClient, namespace, siteName)
+	if len(cronJobs) != 1 {
+		t.Fatalf("expected 1 CronJob, got %d", len(cronJobs))
+	}
+	if cronJobs[0].Spec.Suspend == nil || !*cronJobs[0].Spec.Suspend {
+		t.Error("expected the CronJob of a disabled site to be suspended")
+	}
+}
+
+func TestCronJobHandler_EnsureCronJobsAreSuspended(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newCronJobTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := CronJobHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if err := handler.EnsureCronJobsAreSuspended(site, ctx); err != nil {
+		t.Fatalf("EnsureCronJobsAreSuspended returned unexpected error: %v", err)
+	}
+
+	cronJobs := listCronJobs(t, fakeClient, namespace, siteName)
+	if len(cronJobs) != 1 || cronJobs[0].Spec.Suspend == nil || !*cronJobs[0].Spec.Suspend {
+		t.Error("expected a suspended CronJob while the site is paused")
+	}
+}
+
+func TestCronJobHandler_EnsureCronJobsAreUpToDate_RemovedServiceDeletesCronJob(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newCronJobTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := CronJobHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if err := handler.EnsureCronJobsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureCronJobsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	site.Spec.Services = map[string]sitev1.StagingSiteService{}
+	if err := handler.EnsureCronJobsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureCronJobsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	if cronJobs := listCronJobs(t, fakeClient, namespace, siteName); len(cronJobs) != 0 {
+		t.Errorf("expected the CronJob to be deleted with the service, but found %d", len(cronJobs))
+	}
+}
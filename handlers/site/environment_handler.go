@@ -0,0 +1,104 @@
+package site
+
+import (
+	"context"
+	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/provider"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+type EnvironmentHandler struct {
+	Reader client.Reader
+}
+
+// EnsureEnvironmentConditionIsUpToDate sets the EnvironmentMaintenance condition of the site, explaining which of its
+// environments are in maintenance or are being drained
+func (r EnvironmentHandler) EnsureEnvironmentConditionIsUpToDate(
+	site *sitev1.StagingSite,
+	ctx context.Context,
+) (bool, error) {
+	mysqlEnvironments, err := kubernetes.GetMysqlEnvironmentsInNamespace(site.Namespace, r.Reader, ctx)
+	if err != nil {
+		return false, err
+	}
+	mongoEnvironments, err := kubernetes.GetMongoEnvironmentsInNamespace(site.Namespace, r.Reader, ctx)
+	if err != nil {
+		return false, err
+	}
+	redisEnvironments, err := kubernetes.GetRedisEnvironmentsInNamespace(site.Namespace, r.Reader, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	modes := make(map[string]configv1.EnvironmentMode)
+	for _, service := range site.Spec.Services {
+		if service.MysqlEnvironment != "" {
+			modes["mysql environment '"+service.MysqlEnvironment+"'"] = mysqlEnvironments[service.MysqlEnvironment].Spec.Mode
+		}
+		if service.MongoEnvironment != "" {
+			modes["mongo environment '"+service.MongoEnvironment+"'"] = mongoEnvironments[service.MongoEnvironment].Spec.Mode
+		}
+		if service.RedisEnvironment != "" {
+			modes["redis environment '"+service.RedisEnvironment+"'"] = redisEnvironments[service.RedisEnvironment].Spec.Mode
+		}
+	}
+
+	providerEnvironments := make(map[string]map[string]client.Object)
+	for _, service := range site.Spec.Services {
+		for providerName, environment := range service.Databases {
+			databaseProvider, ok := provider.Get(providerName)
+			if !ok {
+				continue
+			}
+			if _, ok := providerEnvironments[providerName]; !ok {
+				if providerEnvironments[providerName], err = provider.ListEnvironments(
+					ctx,
+					r.Reader,
+					databaseProvider,
+					site.Namespace,
+				); err != nil {
+					return false, err
+				}
+			}
+			if config, ok := providerEnvironments[providerName][environment]; ok {
+				modes[providerName+" environment '"+environment+"'"] = databaseProvider.GetEnvironmentMode(config)
+			}
+		}
+	}
+
+	var messages []string
+	reason := sitev1.ReasonEnvironmentsActive
+	for environment, mode := range modes {
+		if mode.AcceptsNewDatabases() {
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("The %s is in %s mode", environment, strings.ToLower(string(mode))))
+		if mode.IsInMaintenance() {
+			reason = sitev1.ReasonEnvironmentInMaintenance
+		} else if reason != sitev1.ReasonEnvironmentInMaintenance {
+			reason = sitev1.ReasonEnvironmentDraining
+		}
+	}
+	sort.Strings(messages)
+
+	condition := metav1.Condition{
+		Type:               sitev1.ConditionEnvironmentMaintenance,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            "All environments of the site are active",
+		ObservedGeneration: site.Generation,
+	}
+	if len(messages) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Message = strings.Join(messages, ". ")
+	}
+
+	return meta.SetStatusCondition(&site.Status.Conditions, condition), nil
+}
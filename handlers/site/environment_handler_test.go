@@ -0,0 +1,113 @@
+package site
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/provider"
+	"github.com/szeber/kube-stager/internal/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEnvironmentHandler_EnsureEnvironmentConditionIsUpToDate_AllActive(t *testing.T) {
+	ctx := context.Background()
+	const namespace = "default"
+
+	site := testutil.NewTestStagingSite("test-site", namespace, map[string]sitev1.StagingSiteService{
+		"my-service": {MysqlEnvironment: "mysql-env"},
+	})
+	mysqlConfig := testutil.NewTestMysqlConfig("mysql-env", namespace)
+	handler := EnvironmentHandler{Reader: testutil.NewFakeClient(site, mysqlConfig)}
+
+	changed, err := handler.EnsureEnvironmentConditionIsUpToDate(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected changed=true when the condition is set for the first time")
+	}
+
+	condition := meta.FindStatusCondition(site.Status.Conditions, sitev1.ConditionEnvironmentMaintenance)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != sitev1.ReasonEnvironmentsActive {
+		t.Errorf("expected an inactive maintenance condition, got %+v", condition)
+	}
+
+	changed, err = handler.EnsureEnvironmentConditionIsUpToDate(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed {
+		t.Error("expected changed=false when nothing changed")
+	}
+}
+
+func TestEnvironmentHandler_EnsureEnvironmentConditionIsUpToDate_Maintenance(t *testing.T) {
+	ctx := context.Background()
+	const namespace = "default"
+
+	site := testutil.NewTestStagingSite("test-site", namespace, map[string]sitev1.StagingSiteService{
+		"my-service": {MysqlEnvironment: "mysql-env", RedisEnvironment: "redis-env"},
+	})
+	mysqlConfig := testutil.NewTestMysqlConfig("mysql-env", namespace)
+	mysqlConfig.Spec.Mode = configv1.EnvironmentModeDraining
+	redisConfig := testutil.NewTestRedisConfig("redis-env", namespace)
+	redisConfig.Spec.Mode = configv1.EnvironmentModeMaintenance
+	handler := EnvironmentHandler{Reader: testutil.NewFakeClient(site, mysqlConfig, redisConfig)}
+
+	if _, err := handler.EnsureEnvironmentConditionIsUpToDate(site, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	condition := meta.FindStatusCondition(site.Status.Conditions, sitev1.ConditionEnvironmentMaintenance)
+	if condition == nil {
+		t.Fatal("expected the maintenance condition to be set")
+	}
+	if condition.Status != metav1.ConditionTrue || condition.Reason != sitev1.ReasonEnvironmentInMaintenance {
+		t.Errorf("expected an active maintenance condition, got %+v", condition)
+	}
+	expectedMessage := "The mysql environment 'mysql-env' is in draining mode. " +
+		"The redis environment 'redis-env' is in maintenance mode"
+	if condition.Message != expectedMessage {
+		t.Errorf("Message = %q, want %q", condition.Message, expectedMessage)
+	}
+}
+
+// keydbProvider is a custom database provider reusing the CRDs of the redis provider
+type keydbProvider struct {
+	provider.RedisProvider
+}
+
+func (r keydbProvider) Name() string {
+	return "keydb"
+}
+
+var registerKeydbProvider sync.Once
+
+func TestEnvironmentHandler_EnsureEnvironmentConditionIsUpToDate_CustomProvider(t *testing.T) {
+	registerKeydbProvider.Do(func() { provider.Register(keydbProvider{}) })
+	ctx := context.Background()
+	const namespace = "default"
+
+	site := testutil.NewTestStagingSite("test-site", namespace, map[string]sitev1.StagingSiteService{
+		"my-service": {Databases: map[string]string{"keydb": "keydb-env"}},
+	})
+	keydbConfig := testutil.NewTestRedisConfig("keydb-env", namespace)
+	keydbConfig.Spec.Mode = configv1.EnvironmentModeMaintenance
+	handler := EnvironmentHandler{Reader: testutil.NewFakeClient(site, keydbConfig)}
+
+	if _, err := handler.EnsureEnvironmentConditionIsUpToDate(site, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	condition := meta.FindStatusCondition(site.Status.Conditions, sitev1.ConditionEnvironmentMaintenance)
+	if condition == nil || condition.Reason != sitev1.ReasonEnvironmentInMaintenance {
+		t.Fatalf("expected a maintenance condition, got %+v", condition)
+	}
+	if condition.Message != "The keydb environment 'keydb-env' is in maintenance mode" {
+		t.Errorf("unexpected message: %q", condition.Message)
+	}
+}
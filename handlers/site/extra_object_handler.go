@@ -0,0 +1,200 @@
+package site
+
+import (
+	"context"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/template"
+	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/labels"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+)
+
+type ExtraObjectHandler struct {
+	Reader client.Reader
+	Writer client.Writer
+	Scheme *runtime.Scheme
+}
+
+// EnsureExtraObjectsAreUpToDate applies the extra objects of the services of the site, and deletes the previously
+// applied ones that are no longer configured or whose service was removed from the site. Returns TRUE if the list of
+// the applied objects in the site status changed
+func (r ExtraObjectHandler) EnsureExtraObjectsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	var objects []*unstructured.Unstructured
+	for name := range site.Spec.Services {
+		config := &configv1.ServiceConfig{}
+		if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+			return false, err
+		}
+
+		serviceObjects, err := r.createExtraObjects(site, config, ctx)
+		if err != nil {
+			return false, err
+		}
+		objects = append(objects, serviceObjects...)
+	}
+
+	appliedObjects, err := applyObjects(ctx, r.Writer, site.Namespace, objects, site.Status.ExtraObjects)
+	if err != nil {
+		return false, err
+	}
+
+	if isReferenceListEqual(site.Status.ExtraObjects, appliedObjects) {
+		return false, nil
+	}
+
+	if len(appliedObjects) == 0 {
+		site.Status.ExtraObjects = nil
+	} else {
+		site.Status.ExtraObjects = appliedObjects
+	}
+
+	logger.V(0).Info("Extra objects applied", "count", len(appliedObjects))
+
+	return true, nil
+}
+
+func (r ExtraObjectHandler) createExtraObjects(
+	site *sitev1.StagingSite,
+	serviceConfig *configv1.ServiceConfig,
+	ctx context.Context,
+) ([]*unstructured.Unstructured, error) {
+	if len(serviceConfig.Spec.ExtraObjects) == 0 {
+		return nil, nil
+	}
+
+	templateHandler := template.NewSite(*site, *serviceConfig)
+	err := template.LoadConfigs(&templateHandler, ctx, r.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*unstructured.Unstructured, 0, len(serviceConfig.Spec.ExtraObjects))
+	for _, rawObject := range serviceConfig.Spec.ExtraObjects {
+		object, err := helpers.ReplaceTemplateVariablesInRawObject(rawObject, &templateHandler)
+		if err != nil {
+			return nil, err
+		}
+
+		// The objects are always created in the namespace of the site, as they are owned by it
+		object.SetNamespace(site.Namespace)
+
+		objectLabels := object.GetLabels()
+		if objectLabels == nil {
+			objectLabels = map[string]string{}
+		}
+		objectLabels[labels.Site] = site.Name
+		objectLabels[labels.Service] = serviceConfig.Name
+		object.SetLabels(objectLabels)
+
+		if err := ctrl.SetControllerReference(site, object, r.Scheme); err != nil {
+			return nil, err
+		}
+
+		result = append(result, object)
+	}
+
+	return result, nil
+}
+
+// applyObjects applies the objects, and deletes the objects of the previous references that are no longer in the list.
+// Returns the references of the applied objects sorted by their type and name
+func applyObjects(
+	ctx context.Context,
+	writer client.Writer,
+	namespace string,
+	objects []*unstructured.Unstructured,
+	previous []sitev1.ExtraObjectReference,
+) ([]sitev1.ExtraObjectReference, error) {
+	logger := log.FromContext(ctx)
+
+	appliedObjects := make([]sitev1.ExtraObjectReference, 0, len(objects))
+	for _, object := range objects {
+		logger.V(1).Info("Applying object", "kind", object.GetKind(), "name", object.GetName())
+		if err := writer.Apply(
+			ctx,
+			client.ApplyConfigurationFromUnstructured(object),
+			client.FieldOwner(helpers.FieldManager),
+			client.ForceOwnership,
+		); err != nil {
+			return nil, err
+		}
+		appliedObjects = append(appliedObjects, makeReference(object))
+	}
+
+	sort.Slice(appliedObjects, func(i, j int) bool {
+		return makeReferenceKey(appliedObjects[i]) < makeReferenceKey(appliedObjects[j])
+	})
+
+	applied := make(map[string]bool, len(appliedObjects))
+	for _, reference := range appliedObjects {
+		applied[makeReferenceKey(reference)] = true
+	}
+
+	for _, reference := range previous {
+		if applied[makeReferenceKey(reference)] {
+			continue
+		}
+
+		logger.V(1).Info("Deleting object", "kind", reference.Kind, "name", reference.Name)
+		if err := deleteReferencedObject(ctx, writer, namespace, reference); err != nil {
+			return nil, err
+		}
+	}
+
+	return appliedObjects, nil
+}
+
+func deleteReferencedObject(
+	ctx context.Context,
+	writer client.Writer,
+	namespace string,
+	reference sitev1.ExtraObjectReference,
+) error {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion(reference.APIVersion)
+	object.SetKind(reference.Kind)
+	object.SetNamespace(namespace)
+	object.SetName(reference.Name)
+	err := writer.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func makeReference(object *unstructured.Unstructured) sitev1.ExtraObjectReference {
+	return sitev1.ExtraObjectReference{
+		ServiceName: object.GetLabels()[labels.Service],
+		APIVersion:  object.GetAPIVersion(),
+		Kind:        object.GetKind(),
+		Name:        object.GetName(),
+	}
+}
+
+func makeReferenceKey(reference sitev1.ExtraObjectReference) string {
+	return reference.APIVersion + "/" + reference.Kind + "/" + reference.Name
+}
+
+func isReferenceListEqual(a []sitev1.ExtraObjectReference, b []sitev1.ExtraObjectReference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,159 @@
+package site
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newExtraObjectTestServiceConfig(svcName, namespace, shortName string) *configv1.ServiceConfig {
+	sc := testutil.NewTestServiceConfig(svcName, namespace, shortName)
+	sc.Spec.ExtraObjects = []runtime.RawExtension{
+		{Raw: []byte(`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"${site.name}-worker"}}`)},
+		{Raw: []byte(
+			`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"${site.name}-extra","labels":{"app":"extra"}},` +
+				`"data":{"site":"${site.name}"}}`,
+		)},
+	}
+
+	return sc
+}
+
+func TestExtraObjectHandler_EnsureExtraObjectsAreUpToDate_AppliesObjects(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newExtraObjectTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := ExtraObjectHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	changed, err := handler.EnsureExtraObjectsAreUpToDate(site, ctx)
+	if err != nil {
+		t.Fatalf("EnsureExtraObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected the site status to change")
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "test-site-extra"}, configMap); err != nil {
+		t.Fatalf("failed to get the extra ConfigMap: %v", err)
+	}
+	if configMap.Data["site"] != siteName {
+		t.Errorf("expected the templates to be replaced, got %q", configMap.Data["site"])
+	}
+	if configMap.Labels["app"] != "extra" {
+		t.Errorf("expected the labels of the manifest to be kept, got %v", configMap.Labels)
+	}
+	if configMap.Labels["operator.kube-stager.io/site"] != siteName ||
+		configMap.Labels["operator.kube-stager.io/service"] != svcName {
+		t.Errorf("expected the site and service labels to be set, got %v", configMap.Labels)
+	}
+	if len(configMap.OwnerReferences) != 1 || configMap.OwnerReferences[0].Name != siteName {
+		t.Errorf("expected the ConfigMap to be owned by the site, got %v", configMap.OwnerReferences)
+	}
+
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "test-site-worker"}, serviceAccount); err != nil {
+		t.Fatalf("failed to get the extra ServiceAccount: %v", err)
+	}
+
+	if len(site.Status.ExtraObjects) != 2 {
+		t.Fatalf("expected 2 extra objects in the status, got %d", len(site.Status.ExtraObjects))
+	}
+
+	changed, err = handler.EnsureExtraObjectsAreUpToDate(site, ctx)
+	if err != nil {
+		t.Fatalf("second EnsureExtraObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	if changed {
+		t.Error("expected no status change on the second run")
+	}
+}
+
+func TestExtraObjectHandler_EnsureExtraObjectsAreUpToDate_PrunesRemovedObjects(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := newExtraObjectTestServiceConfig(svcName, namespace, shortName)
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := ExtraObjectHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if _, err := handler.EnsureExtraObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureExtraObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	sc.Spec.ExtraObjects = sc.Spec.ExtraObjects[:1]
+	if err := fakeClient.Update(ctx, sc); err != nil {
+		t.Fatalf("failed to update the service config: %v", err)
+	}
+
+	changed, err := handler.EnsureExtraObjectsAreUpToDate(site, ctx)
+	if err != nil {
+		t.Fatalf("EnsureExtraObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected the site status to change")
+	}
+
+	err = fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "test-site-extra"}, &corev1.ConfigMap{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected the removed ConfigMap to be deleted, got %v", err)
+	}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "test-site-worker"}, &corev1.ServiceAccount{}); err != nil {
+		t.Errorf("expected the ServiceAccount to be kept: %v", err)
+	}
+	if len(site.Status.ExtraObjects) != 1 || site.Status.ExtraObjects[0].Kind != "ServiceAccount" {
+		t.Errorf("expected only the ServiceAccount in the status, got %v", site.Status.ExtraObjects)
+	}
+}
+
+func TestExtraObjectHandler_EnsureExtraObjectsAreUpToDate_UnresolvedTemplate(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := testutil.NewTestServiceConfig(svcName, namespace, shortName)
+	sc.Spec.ExtraObjects = []runtime.RawExtension{
+		{Raw: []byte(`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"${unknown.value}"}}`)},
+	}
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := ExtraObjectHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if _, err := handler.EnsureExtraObjectsAreUpToDate(site, ctx); err == nil {
+		t.Fatal("expected an error for the unresolved template")
+	}
+}
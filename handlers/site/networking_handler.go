@@ -2,25 +2,75 @@ package site
 
 import (
 	"context"
+	"fmt"
 	api "github.com/szeber/kube-stager/apis"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	"github.com/szeber/kube-stager/handlers/template"
 	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
 	"github.com/szeber/kube-stager/helpers/labels"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"slices"
+	"sort"
+	"strings"
 )
 
+// httpRouteGroupVersionKind is the kind of the Gateway API routes. The routes are handled as unstructured objects, so
+// the operator works in clusters without the Gateway API
+var httpRouteGroupVersionKind = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Version: "v1",
+	Kind:    "HTTPRoute",
+}
+
 type NetworkingHandler struct {
-	Reader client.Reader
-	Writer client.Writer
-	Scheme *runtime.Scheme
+	Reader           client.Reader
+	Writer           client.Writer
+	Scheme           *runtime.Scheme
+	NetworkIsolation controllerconfigv1.NetworkIsolationConfig
+	Ingress          controllerconfigv1.IngressConfig
+}
+
+// EnsureExternalUrlsAreUpToDate stores the external URLs of the services with an ingress in the status of the site. It
+// runs before the configs are rendered, so the configs can use the URLs
+func (r NetworkingHandler) EnsureExternalUrlsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	isChanged := false
+
+	for name, serviceStatus := range site.Status.Services {
+		config := &configv1.ServiceConfig{}
+		if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+			return false, err
+		}
+
+		externalUrl := ""
+		if config.Spec.IngressSpec != nil {
+			ingress, err := r.createIngress(ctx, site, config)
+			if err != nil {
+				return false, err
+			}
+			externalUrl = getIngressExternalUrl(ingress.Spec)
+		}
+
+		if serviceStatus.ExternalUrl != externalUrl {
+			serviceStatus.ExternalUrl = externalUrl
+			site.Status.Services[name] = serviceStatus
+			isChanged = true
+		}
+	}
+
+	return isChanged, nil
 }
 
 func (r NetworkingHandler) EnsureNetworkingObjectsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (
@@ -42,6 +92,18 @@ func (r NetworkingHandler) EnsureNetworkingObjectsAreUpToDate(site *sitev1.Stagi
 		isComplete = isComplete && complete
 	}
 
+	if complete, err := r.ensureHTTPRoutesAreUpToDate(site, ctx); err != nil {
+		return false, err
+	} else {
+		isComplete = isComplete && complete
+	}
+
+	if complete, err := r.ensureNetworkPoliciesAreUpToDate(site, ctx); err != nil {
+		return false, err
+	} else {
+		isComplete = isComplete && complete
+	}
+
 	site.Status.NetworkingObjectsAreCreated = isComplete
 
 	return previousComplete != isComplete, nil
@@ -74,7 +136,7 @@ func (r NetworkingHandler) ensureServicesAreUpToDate(site *sitev1.StagingSite, c
 		return true, err
 	}
 
-	servicesToCreate := make(map[string]corev1.Service)
+	servicesToApply := make(map[string]corev1.Service)
 	servicesToDelete := make(map[string]corev1.Service)
 
 	for name := range site.Spec.Services {
@@ -87,7 +149,7 @@ func (r NetworkingHandler) ensureServicesAreUpToDate(site *sitev1.StagingSite, c
 			continue
 		}
 
-		servicesToCreate[name], err = r.createService(ctx, site, config)
+		servicesToApply[name], err = r.createService(ctx, site, config)
 		if err != nil {
 			return false, err
 		}
@@ -96,9 +158,7 @@ func (r NetworkingHandler) ensureServicesAreUpToDate(site *sitev1.StagingSite, c
 	for _, service := range list.Items {
 		serviceName := service.Labels[labels.Service]
 
-		if _, ok := servicesToCreate[serviceName]; ok {
-			delete(servicesToCreate, serviceName)
-		} else {
+		if _, ok := servicesToApply[serviceName]; !ok {
 			servicesToDelete[serviceName] = service
 		}
 	}
@@ -109,9 +169,9 @@ func (r NetworkingHandler) ensureServicesAreUpToDate(site *sitev1.StagingSite, c
 			return false, err
 		}
 	}
-	for serviceName, database := range servicesToCreate {
-		logger.V(1).Info("Creating service for service " + serviceName)
-		if err = r.Writer.Create(ctx, &database); err != nil {
+	for serviceName, service := range servicesToApply {
+		logger.V(1).Info("Applying service for service " + serviceName)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &service); err != nil {
 			return false, err
 		}
 	}
@@ -148,7 +208,7 @@ func (r NetworkingHandler) ensureIngressesAreUpToDate(site *sitev1.StagingSite,
 		return true, err
 	}
 
-	ingressesToCreate := make(map[string]networkingv1.Ingress)
+	ingressesToApply := make(map[string]networkingv1.Ingress)
 	ingressesToDelete := make(map[string]networkingv1.Ingress)
 
 	for name := range site.Spec.Services {
@@ -165,15 +225,13 @@ func (r NetworkingHandler) ensureIngressesAreUpToDate(site *sitev1.StagingSite,
 		if err != nil {
 			return false, err
 		}
-		ingressesToCreate[name] = ingress
+		ingressesToApply[name] = ingress
 	}
 
 	for _, ingress := range list.Items {
 		serviceName := ingress.Labels[labels.Service]
 
-		if _, ok := ingressesToCreate[serviceName]; ok {
-			delete(ingressesToCreate, serviceName)
-		} else {
+		if _, ok := ingressesToApply[serviceName]; !ok {
 			ingressesToDelete[serviceName] = ingress
 		}
 	}
@@ -184,9 +242,9 @@ func (r NetworkingHandler) ensureIngressesAreUpToDate(site *sitev1.StagingSite,
 			return false, err
 		}
 	}
-	for ingressName, database := range ingressesToCreate {
-		logger.V(1).Info("Creating ingress for service " + ingressName)
-		if err = r.Writer.Create(ctx, &database); err != nil {
+	for ingressName, ingress := range ingressesToApply {
+		logger.V(1).Info("Applying ingress for service " + ingressName)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &ingress); err != nil {
 			return false, err
 		}
 	}
@@ -196,6 +254,430 @@ func (r NetworkingHandler) ensureIngressesAreUpToDate(site *sitev1.StagingSite,
 	return true, nil
 }
 
+// ensureHTTPRoutesAreUpToDate creates the routes sending the requests with the routing header of the site to the
+// services of the site that have header routing enabled. Routes are only created for the services that are also
+// included in the baseline site of the site, and the ones that are no longer needed are deleted
+func (r NetworkingHandler) ensureHTTPRoutesAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	routesToApply, err := r.getHTTPRoutesToApply(site, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	logger.V(0).Info("Retrieving HTTP route list")
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(httpRouteGroupVersionKind.GroupVersion().WithKind(httpRouteGroupVersionKind.Kind + "List"))
+	if err := r.Reader.List(
+		ctx,
+		list,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{
+			labels.Site: site.Name,
+		},
+	); err != nil {
+		if !meta.IsNoMatchError(err) || len(routesToApply) > 0 {
+			return false, err
+		}
+		// The Gateway API is not installed in the cluster, so there can't be any routes to delete
+		return true, nil
+	}
+
+	for _, route := range list.Items {
+		if _, ok := routesToApply[route.GetName()]; !ok {
+			logger.V(1).Info("Deleting HTTP route " + route.GetName())
+			if err := r.Writer.Delete(ctx, &route); client.IgnoreNotFound(err) != nil {
+				return false, err
+			}
+		}
+	}
+	for name, route := range routesToApply {
+		logger.V(1).Info("Applying HTTP route " + name)
+		if err := kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, route); err != nil {
+			return false, err
+		}
+	}
+
+	logger.V(0).Info("HTTP routes created")
+
+	return true, nil
+}
+
+// getHTTPRoutesToApply returns the routes of the services of the site that have header routing enabled and are also
+// included in the baseline site, keyed by their name
+func (r NetworkingHandler) getHTTPRoutesToApply(site *sitev1.StagingSite, ctx context.Context) (
+	map[string]*unstructured.Unstructured,
+	error,
+) {
+	result := make(map[string]*unstructured.Unstructured)
+
+	if !site.Status.Enabled || site.Spec.BaselineSite == "" || site.Spec.BaselineSite == site.Name {
+		return result, nil
+	}
+
+	baselineSite := &sitev1.StagingSite{}
+	if err := r.Reader.Get(
+		ctx,
+		client.ObjectKey{Namespace: site.Namespace, Name: site.Spec.BaselineSite},
+		baselineSite,
+	); err != nil {
+		// Without a baseline site there is nothing to route the requests from
+		return result, client.IgnoreNotFound(err)
+	}
+
+	for name := range site.Spec.Services {
+		if _, ok := baselineSite.Spec.Services[name]; !ok {
+			continue
+		}
+
+		config := &configv1.ServiceConfig{}
+		if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+			return nil, err
+		}
+		if config.Spec.HeaderRouting == nil || config.Spec.ServiceSpec == nil {
+			continue
+		}
+
+		route, err := r.createHTTPRoute(site, baselineSite, config)
+		if err != nil {
+			return nil, err
+		}
+		result[route.GetName()] = route
+	}
+
+	return result, nil
+}
+
+// createHTTPRoute returns the route attached to the service of the baseline site, that sends the requests with the
+// routing header set to the name of the site to the service of the site. The rule without matches sends every other
+// request to the service of the baseline site, as the mesh rejects the requests not matching any rule of a route
+func (r NetworkingHandler) createHTTPRoute(
+	site *sitev1.StagingSite,
+	baselineSite *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+) (*unstructured.Unstructured, error) {
+	headerName := config.Spec.HeaderRouting.HeaderName
+	if headerName == "" {
+		headerName = helpers.DefaultRoutingHeader
+	}
+
+	var port int32
+	if config.Spec.HeaderRouting.Port != nil {
+		port = *config.Spec.HeaderRouting.Port
+	} else if len(config.Spec.ServiceSpec.Ports) > 0 {
+		port = config.Spec.ServiceSpec.Ports[0].Port
+	} else {
+		return nil, fmt.Errorf("the service spec of the service config '%s' has no ports to route", config.Name)
+	}
+
+	route := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"parentRefs": []interface{}{
+					map[string]interface{}{
+						"group": "",
+						"kind":  "Service",
+						"name":  api.MakeServiceName(baselineSite, config),
+						"port":  int64(port),
+					},
+				},
+				"rules": []interface{}{
+					map[string]interface{}{
+						"matches": []interface{}{
+							map[string]interface{}{
+								"headers": []interface{}{
+									map[string]interface{}{
+										"type":  "Exact",
+										"name":  headerName,
+										"value": site.Name,
+									},
+								},
+							},
+						},
+						"backendRefs": []interface{}{
+							map[string]interface{}{
+								"name": api.MakeServiceName(site, config),
+								"port": int64(port),
+							},
+						},
+					},
+					map[string]interface{}{
+						"backendRefs": []interface{}{
+							map[string]interface{}{
+								"name": api.MakeServiceName(baselineSite, config),
+								"port": int64(port),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	route.SetGroupVersionKind(httpRouteGroupVersionKind)
+	route.SetName(api.MakeHTTPRouteName(site, config))
+	route.SetNamespace(site.Namespace)
+	route.SetLabels(map[string]string{
+		labels.Site:    site.Name,
+		labels.Service: config.Name,
+	})
+
+	err := ctrl.SetControllerReference(site, route, r.Scheme)
+
+	return route, err
+}
+
+// ensureNetworkPoliciesAreUpToDate creates the network policies isolating the pods of the site if the network isolation
+// is enabled, and deletes them otherwise
+func (r NetworkingHandler) ensureNetworkPoliciesAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving network policy list")
+
+	var list networkingv1.NetworkPolicyList
+	err := r.Reader.List(
+		ctx,
+		&list,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{
+			labels.Site: site.Name,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	policiesToApply := make(map[string]networkingv1.NetworkPolicy)
+
+	if r.NetworkIsolation.Enabled && site.Status.Enabled {
+		relatedSiteNames, err := r.getRelatedSiteNames(site, ctx)
+		if err != nil {
+			return false, err
+		}
+
+		policy, err := r.createSiteNetworkPolicy(site, relatedSiteNames)
+		if err != nil {
+			return false, err
+		}
+		policiesToApply[policy.Name] = policy
+
+		for name := range site.Spec.Services {
+			config := &configv1.ServiceConfig{}
+			if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+				return false, err
+			}
+
+			if len(config.Spec.NetworkPolicyEgress) == 0 {
+				continue
+			}
+
+			policy, err := r.createServiceNetworkPolicy(site, config, relatedSiteNames)
+			if err != nil {
+				return false, err
+			}
+			policiesToApply[policy.Name] = policy
+
+			// The pods of the additional workloads don't have the service label, so they get a policy of their own
+			for workloadName := range config.Spec.Workloads {
+				policy, err := r.createWorkloadNetworkPolicy(site, config, workloadName, relatedSiteNames)
+				if err != nil {
+					return false, err
+				}
+				policiesToApply[policy.Name] = policy
+			}
+		}
+	}
+
+	for _, policy := range list.Items {
+		if _, ok := policiesToApply[policy.Name]; !ok {
+			logger.V(1).Info("Deleting network policy " + policy.Name)
+			if err = r.Writer.Delete(ctx, &policy); err != nil {
+				return false, err
+			}
+		}
+	}
+	for name, policy := range policiesToApply {
+		logger.V(1).Info("Applying network policy " + name)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &policy); err != nil {
+			return false, err
+		}
+	}
+
+	logger.V(0).Info("Network policies created")
+
+	return true, nil
+}
+
+// getRelatedSiteNames returns the sorted names of the baseline site of the site and the sites using the site as their
+// baseline site. Their pods call each other's services through the service urls of the baseline site and the header
+// routing
+func (r NetworkingHandler) getRelatedSiteNames(site *sitev1.StagingSite, ctx context.Context) ([]string, error) {
+	var list sitev1.StagingSiteList
+	if err := r.Reader.List(ctx, &list, client.InNamespace(site.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var result []string
+	if site.Spec.BaselineSite != "" && site.Spec.BaselineSite != site.Name {
+		result = append(result, site.Spec.BaselineSite)
+	}
+	for _, otherSite := range list.Items {
+		if otherSite.Spec.BaselineSite == site.Name && otherSite.Name != site.Name {
+			result = append(result, otherSite.Name)
+		}
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// createSiteNetworkPolicy returns the policy that only allows connections to the pods of the site from the pods of the
+// same site, the related baseline and dependent sites, the ingress controllers and the allowed shared pods
+func (r NetworkingHandler) createSiteNetworkPolicy(
+	site *sitev1.StagingSite,
+	relatedSiteNames []string,
+) (networkingv1.NetworkPolicy, error) {
+	sitePodSelector := metav1.LabelSelector{MatchLabels: map[string]string{labels.Site: site.Name}}
+
+	peers := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: sitePodSelector.DeepCopy()},
+	}
+	if len(relatedSiteNames) > 0 {
+		peers = append(peers, makeRelatedSitesPeer(relatedSiteNames))
+	}
+	if len(r.NetworkIsolation.IngressControllerNamespaces) > 0 {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      corev1.LabelMetadataName,
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   r.NetworkIsolation.IngressControllerNamespaces,
+					},
+				},
+			},
+		})
+	}
+	for _, selector := range r.NetworkIsolation.AllowedPodSelectors {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{PodSelector: selector.DeepCopy()})
+	}
+
+	policy := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      api.MakeSiteNetworkPolicyName(site),
+			Namespace: site.Namespace,
+			Labels: map[string]string{
+				labels.Site: site.Name,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: sitePodSelector,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: peers}},
+		},
+	}
+
+	err := ctrl.SetControllerReference(site, &policy, r.Scheme)
+
+	return policy, err
+}
+
+// createServiceNetworkPolicy returns the policy restricting the outgoing connections of the pods of the service to the
+// pods of the same site and the related sites, the cluster DNS and the egress rules of the service config
+func (r NetworkingHandler) createServiceNetworkPolicy(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	relatedSiteNames []string,
+) (networkingv1.NetworkPolicy, error) {
+	return r.createEgressNetworkPolicy(
+		site,
+		config,
+		api.MakeServiceNetworkPolicyName(site, config),
+		map[string]string{labels.Site: site.Name, labels.Service: config.Name},
+		relatedSiteNames,
+	)
+}
+
+// createWorkloadNetworkPolicy returns the policy restricting the outgoing connections of the pods of an additional
+// workload of the service the same way as the ones of the service
+func (r NetworkingHandler) createWorkloadNetworkPolicy(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	workloadName string,
+	relatedSiteNames []string,
+) (networkingv1.NetworkPolicy, error) {
+	return r.createEgressNetworkPolicy(
+		site,
+		config,
+		api.MakeWorkloadNetworkPolicyName(site, config, workloadName),
+		map[string]string{labels.Site: site.Name, labels.Workload: api.MakeWorkloadName(site, config, workloadName)},
+		relatedSiteNames,
+	)
+}
+
+func (r NetworkingHandler) createEgressNetworkPolicy(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	name string,
+	podLabels map[string]string,
+	relatedSiteNames []string,
+) (networkingv1.NetworkPolicy, error) {
+	dnsPort := intstr.FromInt32(53)
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+
+	sitePeers := []networkingv1.NetworkPolicyPeer{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labels.Site: site.Name}}},
+	}
+	if len(relatedSiteNames) > 0 {
+		sitePeers = append(sitePeers, makeRelatedSitesPeer(relatedSiteNames))
+	}
+
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{To: sitePeers},
+		{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &dnsPort},
+				{Protocol: &tcp, Port: &dnsPort},
+			},
+		},
+	}
+	for _, rule := range config.Spec.NetworkPolicyEgress {
+		egress = append(egress, *rule.DeepCopy())
+	}
+
+	policy := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: site.Namespace,
+			Labels: map[string]string{
+				labels.Site:    site.Name,
+				labels.Service: config.Name,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}
+
+	err := ctrl.SetControllerReference(site, &policy, r.Scheme)
+
+	return policy, err
+}
+
+// makeRelatedSitesPeer returns the network policy peer selecting the pods of the related sites
+func makeRelatedSitesPeer(relatedSiteNames []string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: labels.Site, Operator: metav1.LabelSelectorOpIn, Values: relatedSiteNames},
+			},
+		},
+	}
+}
+
 func (r NetworkingHandler) createService(
 	ctx context.Context,
 	site *sitev1.StagingSite,
@@ -243,7 +725,6 @@ func (r NetworkingHandler) createIngress(
 	if err != nil {
 		return networkingv1.Ingress{}, err
 	}
-	boolTrue := true
 	replacedSpec, err := helpers.ReplaceTemplateVariablesInIngressSpec(
 		*config.Spec.IngressSpec,
 		&siteTemplateHandler,
@@ -259,9 +740,11 @@ func (r NetworkingHandler) createIngress(
 	if err != nil {
 		return networkingv1.Ingress{}, err
 	}
-	if len(config.Spec.IngressAnnotations) == 0 {
-		config.Spec.IngressAnnotations = make(map[string]string)
+	annotations := make(map[string]string, len(config.Spec.IngressAnnotations))
+	for k, v := range config.Spec.IngressAnnotations {
+		annotations[k] = v
 	}
+	r.applyIngressHostsAndTLS(site, config, &replacedSpec, annotations)
 	ingress := networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      api.MakeIngressName(site, config),
@@ -270,17 +753,7 @@ func (r NetworkingHandler) createIngress(
 				labels.Site:    site.Name,
 				labels.Service: config.Name,
 			},
-			Annotations: config.Spec.IngressAnnotations,
-			OwnerReferences: []metav1.OwnerReference{
-				{
-					APIVersion:         site.APIVersion,
-					Kind:               site.Kind,
-					Name:               site.Name,
-					UID:                site.UID,
-					Controller:         &boolTrue,
-					BlockOwnerDeletion: &boolTrue,
-				},
-			},
+			Annotations: annotations,
 		},
 		Spec: replacedSpec,
 	}
@@ -291,3 +764,84 @@ func (r NetworkingHandler) createIngress(
 
 	return ingress, nil
 }
+
+// applyIngressHostsAndTLS sets the default host of the service on the rules without a host if a base domain is
+// configured, and adds the configured TLS settings to the ingresses that don't define their own
+func (r NetworkingHandler) applyIngressHostsAndTLS(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	spec *networkingv1.IngressSpec,
+	annotations map[string]string,
+) {
+	if r.Ingress.BaseDomain != "" {
+		for i := range spec.Rules {
+			if spec.Rules[i].Host == "" {
+				spec.Rules[i].Host = api.MakeIngressHost(site, config, r.Ingress.BaseDomain)
+			}
+		}
+	}
+
+	mode := r.Ingress.TLS.Mode
+	if mode == "" || mode == controllerconfigv1.IngressTLSModeDisabled || len(spec.TLS) > 0 {
+		return
+	}
+
+	var hosts []string
+	for _, rule := range spec.Rules {
+		if rule.Host != "" && !slices.Contains(hosts, rule.Host) {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	if len(hosts) == 0 {
+		return
+	}
+
+	tls := networkingv1.IngressTLS{Hosts: hosts}
+	switch mode {
+	case controllerconfigv1.IngressTLSModeCertManager:
+		tls.SecretName = api.MakeIngressTLSSecretName(site, config)
+		_, hasClusterIssuer := annotations[helpers.CertManagerClusterIssuerAnnotation]
+		_, hasIssuer := annotations[helpers.CertManagerIssuerAnnotation]
+		if !hasClusterIssuer && !hasIssuer {
+			if r.Ingress.TLS.CertManagerIssuerKind == controllerconfigv1.CertManagerIssuerKindIssuer {
+				annotations[helpers.CertManagerIssuerAnnotation] = r.Ingress.TLS.CertManagerIssuer
+			} else {
+				annotations[helpers.CertManagerClusterIssuerAnnotation] = r.Ingress.TLS.CertManagerIssuer
+			}
+		}
+	case controllerconfigv1.IngressTLSModeWildcardSecret:
+		tls.SecretName = r.Ingress.TLS.WildcardSecretName
+	}
+	spec.TLS = []networkingv1.IngressTLS{tls}
+}
+
+// getIngressExternalUrl returns the URL of the first host of the ingress, using https if the host is covered by the TLS
+// settings of the ingress
+func getIngressExternalUrl(spec networkingv1.IngressSpec) string {
+	for _, rule := range spec.Rules {
+		if rule.Host == "" {
+			continue
+		}
+		for _, tls := range spec.TLS {
+			for _, tlsHost := range tls.Hosts {
+				if tlsHost == rule.Host || isWildcardHostMatch(tlsHost, rule.Host) {
+					return "https://" + rule.Host
+				}
+			}
+		}
+		return "http://" + rule.Host
+	}
+
+	return ""
+}
+
+// isWildcardHostMatch returns TRUE if the host is covered by the wildcard host (eg. *.example.com)
+func isWildcardHostMatch(wildcardHost string, host string) bool {
+	if !strings.HasPrefix(wildcardHost, "*.") {
+		return false
+	}
+	domain := wildcardHost[1:]
+	prefix, found := strings.CutSuffix(host, domain)
+
+	return found && prefix != "" && !strings.Contains(prefix, ".")
+}
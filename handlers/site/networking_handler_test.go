@@ -4,13 +4,21 @@ import (
 	"context"
 	"testing"
 
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	controllerconfigv1 "github.com/szeber/kube-stager/apis/controller-config/v1"
+	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
 	"github.com/szeber/kube-stager/internal/testutil"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestNetworkingHandler_EnsureNetworkingObjectsAreUpToDate_CreatesService(t *testing.T) {
@@ -309,6 +317,231 @@ func TestNetworkingHandler_EnsureNetworkingObjectsAreUpToDate_IngressAnnotations
 	}
 }
 
+func newTestIngressServiceConfig(name, namespace, shortName string) *configv1.ServiceConfig {
+	sc := testutil.NewTestServiceConfigWithDefaults(name, namespace, shortName)
+	pathType := networkingv1.PathTypePrefix
+	sc.Spec.IngressSpec = &networkingv1.IngressSpec{
+		Rules: []networkingv1.IngressRule{
+			{
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{
+							{
+								Path:     "/",
+								PathType: &pathType,
+								Backend: networkingv1.IngressBackend{
+									Service: &networkingv1.IngressServiceBackend{
+										Name: "${ingress.serviceName}",
+										Port: networkingv1.ServiceBackendPort{Number: 80},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return sc
+}
+
+func TestNetworkingHandler_CreateIngress_CertManagerTLS(t *testing.T) {
+	sc := newTestIngressServiceConfig("web", "default", "web")
+	sc.Spec.IngressAnnotations = map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"}
+	site := testutil.NewTestStagingSite("mysite", "default", map[string]sitev1.StagingSiteService{"web": {}})
+	site.Spec.DomainPrefix = "feature"
+	fakeClient := testutil.NewFakeClient(site, sc)
+
+	handler := NetworkingHandler{
+		Reader: fakeClient,
+		Writer: fakeClient,
+		Scheme: testutil.NewTestScheme(),
+		Ingress: controllerconfigv1.IngressConfig{
+			BaseDomain: "staging.example.com",
+			TLS: controllerconfigv1.IngressTLSConfig{
+				Mode:              controllerconfigv1.IngressTLSModeCertManager,
+				CertManagerIssuer: "letsencrypt",
+			},
+		},
+	}
+
+	ingress, err := handler.createIngress(context.Background(), site, sc)
+	if err != nil {
+		t.Fatalf("createIngress returned unexpected error: %v", err)
+	}
+
+	if host := ingress.Spec.Rules[0].Host; host != "feature-web.staging.example.com" {
+		t.Errorf("expected the default host, got %q", host)
+	}
+	if len(ingress.Spec.TLS) != 1 {
+		t.Fatalf("expected one TLS entry, got %+v", ingress.Spec.TLS)
+	}
+	if tls := ingress.Spec.TLS[0]; tls.SecretName != "mysite-web-tls" ||
+		len(tls.Hosts) != 1 || tls.Hosts[0] != "feature-web.staging.example.com" {
+		t.Errorf("unexpected TLS entry: %+v", tls)
+	}
+	if ingress.Annotations["cert-manager.io/cluster-issuer"] != "letsencrypt" {
+		t.Errorf("expected the cluster issuer annotation, got %v", ingress.Annotations)
+	}
+	if ingress.Annotations["nginx.ingress.kubernetes.io/ssl-redirect"] != "true" {
+		t.Errorf("expected the configured annotations to be kept, got %v", ingress.Annotations)
+	}
+	if _, ok := sc.Spec.IngressAnnotations["cert-manager.io/cluster-issuer"]; ok {
+		t.Error("expected the annotations of the service config not to be modified")
+	}
+}
+
+func TestNetworkingHandler_CreateIngress_WildcardSecretTLS(t *testing.T) {
+	ingressConfig := controllerconfigv1.IngressConfig{
+		BaseDomain: "staging.example.com",
+		TLS: controllerconfigv1.IngressTLSConfig{
+			Mode:               controllerconfigv1.IngressTLSModeWildcardSecret,
+			WildcardSecretName: "wildcard-cert",
+		},
+	}
+
+	t.Run("adds the wildcard secret", func(t *testing.T) {
+		sc := newTestIngressServiceConfig("web", "default", "web")
+		site := testutil.NewTestStagingSite("mysite", "default", map[string]sitev1.StagingSiteService{"web": {}})
+		fakeClient := testutil.NewFakeClient(site, sc)
+		handler := NetworkingHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme(), Ingress: ingressConfig}
+
+		ingress, err := handler.createIngress(context.Background(), site, sc)
+		if err != nil {
+			t.Fatalf("createIngress returned unexpected error: %v", err)
+		}
+		if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != "wildcard-cert" {
+			t.Errorf("expected the wildcard secret TLS entry, got %+v", ingress.Spec.TLS)
+		}
+		if len(ingress.Annotations) != 0 {
+			t.Errorf("expected no cert-manager annotations, got %v", ingress.Annotations)
+		}
+	})
+
+	t.Run("keeps the TLS settings of the service config", func(t *testing.T) {
+		sc := newTestIngressServiceConfig("web", "default", "web")
+		sc.Spec.IngressSpec.Rules[0].Host = "${site.domainPrefix}.example.com"
+		sc.Spec.IngressSpec.TLS = []networkingv1.IngressTLS{
+			{Hosts: []string{"${site.domainPrefix}.example.com"}, SecretName: "custom"},
+		}
+		site := testutil.NewTestStagingSite("mysite", "default", map[string]sitev1.StagingSiteService{"web": {}})
+		fakeClient := testutil.NewFakeClient(site, sc)
+		handler := NetworkingHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme(), Ingress: ingressConfig}
+
+		ingress, err := handler.createIngress(context.Background(), site, sc)
+		if err != nil {
+			t.Fatalf("createIngress returned unexpected error: %v", err)
+		}
+		if host := ingress.Spec.Rules[0].Host; host != "mysite.example.com" {
+			t.Errorf("expected the configured host to be kept, got %q", host)
+		}
+		if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != "custom" {
+			t.Errorf("expected the configured TLS entry to be kept, got %+v", ingress.Spec.TLS)
+		}
+	})
+}
+
+func TestNetworkingHandler_EnsureExternalUrlsAreUpToDate(t *testing.T) {
+	webConfig := newTestIngressServiceConfig("web", "default", "web")
+	workerConfig := testutil.NewTestServiceConfigWithDefaults("worker", "default", "worker")
+	site := testutil.NewTestStagingSite("mysite", "default", map[string]sitev1.StagingSiteService{
+		"web":    {},
+		"worker": {},
+	})
+	site.Status.Services = map[string]sitev1.StagingSiteServiceStatus{
+		"web":    {},
+		"worker": {ExternalUrl: "http://stale.example.com"},
+	}
+	fakeClient := testutil.NewFakeClient(site, webConfig, workerConfig)
+
+	handler := NetworkingHandler{
+		Reader: fakeClient,
+		Writer: fakeClient,
+		Scheme: testutil.NewTestScheme(),
+		Ingress: controllerconfigv1.IngressConfig{
+			BaseDomain: "staging.example.com",
+			TLS: controllerconfigv1.IngressTLSConfig{
+				Mode:              controllerconfigv1.IngressTLSModeCertManager,
+				CertManagerIssuer: "letsencrypt",
+			},
+		},
+	}
+
+	changed, err := handler.EnsureExternalUrlsAreUpToDate(site, context.Background())
+	if err != nil {
+		t.Fatalf("EnsureExternalUrlsAreUpToDate returned unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected the status to be changed")
+	}
+	if got := site.Status.Services["web"].ExternalUrl; got != "https://mysite-web.staging.example.com" {
+		t.Errorf("expected the external url of the web service, got %q", got)
+	}
+	if got := site.Status.Services["worker"].ExternalUrl; got != "" {
+		t.Errorf("expected the external url of the service without an ingress to be cleared, got %q", got)
+	}
+
+	changed, err = handler.EnsureExternalUrlsAreUpToDate(site, context.Background())
+	if err != nil {
+		t.Fatalf("EnsureExternalUrlsAreUpToDate returned unexpected error: %v", err)
+	}
+	if changed {
+		t.Error("expected no change on the second run")
+	}
+}
+
+func TestGetIngressExternalUrl(t *testing.T) {
+	tests := []struct {
+		name string
+		spec networkingv1.IngressSpec
+		want string
+	}{
+		{
+			name: "no hosts",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{}}},
+			want: "",
+		},
+		{
+			name: "without TLS",
+			spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "web.example.com"}}},
+			want: "http://web.example.com",
+		},
+		{
+			name: "with TLS",
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{}, {Host: "web.example.com"}, {Host: "api.example.com"}},
+				TLS:   []networkingv1.IngressTLS{{Hosts: []string{"web.example.com"}}},
+			},
+			want: "https://web.example.com",
+		},
+		{
+			name: "with wildcard TLS",
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "web.example.com"}},
+				TLS:   []networkingv1.IngressTLS{{Hosts: []string{"*.example.com"}}},
+			},
+			want: "https://web.example.com",
+		},
+		{
+			name: "wildcard TLS only covers one level",
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "web.staging.example.com"}},
+				TLS:   []networkingv1.IngressTLS{{Hosts: []string{"*.example.com"}}},
+			},
+			want: "http://web.staging.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getIngressExternalUrl(tt.spec); got != tt.want {
+				t.Errorf("getIngressExternalUrl() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNetworkingHandler_EnsureNetworkingObjectsAreUpToDate_NoChangeWhenAlreadyComplete(t *testing.T) {
 	ctx := context.Background()
 	const (
@@ -345,3 +578,381 @@ func TestNetworkingHandler_EnsureNetworkingObjectsAreUpToDate_NoChangeWhenAlread
 		t.Error("expected changed=false when NetworkingObjectsAreCreated was already true and remains true")
 	}
 }
+
+// The fake client can't server side apply network policies, as it treats them as having a status subresource, so the
+// policies are built and checked without applying them
+func TestNetworkingHandler_CreateSiteNetworkPolicy(t *testing.T) {
+	const (
+		siteName  = "test-site"
+		namespace = "default"
+	)
+
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{})
+
+	handler := NetworkingHandler{
+		Scheme: testutil.NewTestScheme(),
+		NetworkIsolation: controllerconfigv1.NetworkIsolationConfig{
+			Enabled:                     true,
+			IngressControllerNamespaces: []string{"ingress-nginx"},
+			AllowedPodSelectors: []metav1.LabelSelector{
+				{MatchLabels: map[string]string{"app": "shared"}},
+			},
+		},
+	}
+
+	policy, err := handler.createSiteNetworkPolicy(site, []string{"feature"})
+	if err != nil {
+		t.Fatalf("createSiteNetworkPolicy returned unexpected error: %v", err)
+	}
+
+	if policy.Name != siteName+"-isolation" {
+		t.Errorf("expected policy name %s, got %s", siteName+"-isolation", policy.Name)
+	}
+	if policy.Spec.PodSelector.MatchLabels["operator.kube-stager.io/site"] != siteName {
+		t.Errorf("expected policy to select the pods of the site, got %v", policy.Spec.PodSelector)
+	}
+	if len(policy.Spec.Ingress) != 1 || len(policy.Spec.Ingress[0].From) != 4 {
+		t.Fatalf("expected one ingress rule with 4 peers, got %+v", policy.Spec.Ingress)
+	}
+	dependentPeer := policy.Spec.Ingress[0].From[1]
+	if dependentPeer.PodSelector == nil ||
+		len(dependentPeer.PodSelector.MatchExpressions) != 1 ||
+		dependentPeer.PodSelector.MatchExpressions[0].Values[0] != "feature" {
+		t.Errorf("expected dependent site peer, got %+v", dependentPeer)
+	}
+	namespacePeer := policy.Spec.Ingress[0].From[2]
+	if namespacePeer.NamespaceSelector == nil ||
+		len(namespacePeer.NamespaceSelector.MatchExpressions) != 1 ||
+		namespacePeer.NamespaceSelector.MatchExpressions[0].Values[0] != "ingress-nginx" {
+		t.Errorf("expected ingress controller namespace peer, got %+v", namespacePeer)
+	}
+	if policy.Spec.Ingress[0].From[3].PodSelector.MatchLabels["app"] != "shared" {
+		t.Errorf("expected allowed pod selector peer, got %+v", policy.Spec.Ingress[0].From[3])
+	}
+	if len(policy.OwnerReferences) != 1 || policy.OwnerReferences[0].Name != siteName {
+		t.Errorf("expected policy to be owned by the site, got %v", policy.OwnerReferences)
+	}
+}
+
+func TestNetworkingHandler_CreateServiceNetworkPolicy(t *testing.T) {
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := testutil.NewTestServiceConfigWithDefaults(svcName, namespace, shortName)
+	sc.Spec.NetworkPolicyEgress = []networkingv1.NetworkPolicyEgressRule{
+		{
+			To: []networkingv1.NetworkPolicyPeer{
+				{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}},
+			},
+		},
+	}
+
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+
+	handler := NetworkingHandler{
+		Scheme:           testutil.NewTestScheme(),
+		NetworkIsolation: controllerconfigv1.NetworkIsolationConfig{Enabled: true},
+	}
+
+	policy, err := handler.createServiceNetworkPolicy(site, sc, []string{"master"})
+	if err != nil {
+		t.Fatalf("createServiceNetworkPolicy returned unexpected error: %v", err)
+	}
+
+	if policy.Name != siteName+"-"+shortName+"-egress" {
+		t.Errorf("expected policy name %s, got %s", siteName+"-"+shortName+"-egress", policy.Name)
+	}
+	if policy.Spec.PodSelector.MatchLabels["operator.kube-stager.io/service"] != svcName {
+		t.Errorf("expected policy to select the pods of the service, got %v", policy.Spec.PodSelector)
+	}
+	if len(policy.Spec.PolicyTypes) != 1 || policy.Spec.PolicyTypes[0] != networkingv1.PolicyTypeEgress {
+		t.Errorf("expected an egress policy, got %v", policy.Spec.PolicyTypes)
+	}
+	if len(policy.Spec.Egress) != 3 {
+		t.Fatalf("expected 3 egress rules, got %d", len(policy.Spec.Egress))
+	}
+	if len(policy.Spec.Egress[0].To) != 2 || policy.Spec.Egress[0].To[1].PodSelector.MatchExpressions[0].Values[0] != "master" {
+		t.Errorf("expected egress to the baseline site, got %+v", policy.Spec.Egress[0])
+	}
+	if len(policy.Spec.Egress[1].Ports) != 2 || policy.Spec.Egress[1].Ports[0].Port.IntValue() != 53 {
+		t.Errorf("expected DNS egress rule, got %+v", policy.Spec.Egress[1])
+	}
+	if policy.Spec.Egress[2].To[0].IPBlock == nil || policy.Spec.Egress[2].To[0].IPBlock.CIDR != "10.0.0.0/8" {
+		t.Errorf("expected the configured egress rule to be appended, got %+v", policy.Spec.Egress[2])
+	}
+}
+
+func TestNetworkingHandler_EnsureNetworkingObjectsAreUpToDate_IsolationDisabledDeletesNetworkPolicies(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := testutil.NewTestServiceConfigWithDefaults(svcName, namespace, shortName)
+
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	existingPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      siteName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"operator.kube-stager.io/site": siteName,
+			},
+		},
+	}
+
+	fakeClient := testutil.NewFakeClient(site, sc, existingPolicy)
+	scheme := testutil.NewTestScheme()
+
+	handler := NetworkingHandler{
+		Reader: fakeClient,
+		Writer: fakeClient,
+		Scheme: scheme,
+	}
+
+	if _, err := handler.EnsureNetworkingObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureNetworkingObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	var list networkingv1.NetworkPolicyList
+	if err := fakeClient.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("failed to list NetworkPolicies: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("expected all NetworkPolicies to be deleted when isolation is disabled, but found %d", len(list.Items))
+	}
+}
+
+func TestNetworkingHandler_CreateWorkloadNetworkPolicy(t *testing.T) {
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := testutil.NewTestServiceConfigWithDefaults(svcName, namespace, shortName)
+	sc.Spec.NetworkPolicyEgress = []networkingv1.NetworkPolicyEgressRule{
+		{To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}}}},
+	}
+	sc.Spec.Workloads = map[string]configv1.ServiceWorkload{"worker": {}}
+
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+
+	handler := NetworkingHandler{
+		Scheme:           testutil.NewTestScheme(),
+		NetworkIsolation: controllerconfigv1.NetworkIsolationConfig{Enabled: true},
+	}
+
+	policy, err := handler.createWorkloadNetworkPolicy(site, sc, "worker", nil)
+	if err != nil {
+		t.Fatalf("createWorkloadNetworkPolicy returned unexpected error: %v", err)
+	}
+
+	if policy.Name != siteName+"-"+shortName+"-worker-egress" {
+		t.Errorf("expected policy name %s, got %s", siteName+"-"+shortName+"-worker-egress", policy.Name)
+	}
+	// The pods of the workload don't have the service label, so they are selected by the workload label
+	selector := policy.Spec.PodSelector.MatchLabels
+	if selector["operator.kube-stager.io/workload"] != siteName+"-"+shortName+"-worker" ||
+		selector["operator.kube-stager.io/service"] != "" {
+		t.Errorf("expected policy to select the pods of the workload, got %v", policy.Spec.PodSelector)
+	}
+	if len(policy.Spec.Egress) != 3 || policy.Spec.Egress[2].To[0].IPBlock.CIDR != "10.0.0.0/8" {
+		t.Errorf("expected the egress rules of the service, got %+v", policy.Spec.Egress)
+	}
+}
+
+// newHTTPRouteFakeClient returns a fake client whose scheme knows the HTTPRoute kind, as the Gateway API types are
+// not registered in the test scheme
+func newHTTPRouteFakeClient(t *testing.T, initObjs ...client.Object) client.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		configv1.AddToScheme,
+		jobv1.AddToScheme,
+		sitev1.AddToScheme,
+		taskv1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatalf("failed to build scheme: %v", err)
+		}
+	}
+	scheme.AddKnownTypeWithName(httpRouteGroupVersionKind, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(
+		httpRouteGroupVersionKind.GroupVersion().WithKind(httpRouteGroupVersionKind.Kind+"List"),
+		&unstructured.UnstructuredList{},
+	)
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(initObjs...).
+		WithStatusSubresource(&sitev1.StagingSite{}).
+		Build()
+}
+
+func TestNetworkingHandler_EnsureNetworkingObjectsAreUpToDate_CreatesHTTPRoute(t *testing.T) {
+	ctx := context.Background()
+	const (
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := testutil.NewTestServiceConfigWithDefaults(svcName, namespace, shortName)
+	sc.Spec.HeaderRouting = &configv1.ServiceHeaderRouting{}
+
+	baseline := testutil.NewTestStagingSite("master", namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site := testutil.NewTestStagingSite("feature", namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Spec.BaselineSite = "master"
+	site.Status.Enabled = true
+
+	fakeClient := newHTTPRouteFakeClient(t, site, baseline, sc)
+
+	handler := NetworkingHandler{
+		Reader: fakeClient,
+		Writer: fakeClient,
+		Scheme: fakeClient.Scheme(),
+	}
+
+	if _, err := handler.EnsureNetworkingObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureNetworkingObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGroupVersionKind)
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "feature-" + shortName}, route); err != nil {
+		t.Fatalf("failed to get HTTPRoute: %v", err)
+	}
+
+	parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+	if len(parentRefs) != 1 || parentRefs[0].(map[string]interface{})["name"] != "master-"+shortName {
+		t.Errorf("expected the route to be attached to the baseline service, got %v", parentRefs)
+	}
+	rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+	if len(rules) != 2 {
+		t.Fatalf("expected a header and a fallback rule, got %v", rules)
+	}
+	rule := rules[0].(map[string]interface{})
+	matches, _, _ := unstructured.NestedSlice(rule, "matches")
+	headers, _, _ := unstructured.NestedSlice(matches[0].(map[string]interface{}), "headers")
+	header := headers[0].(map[string]interface{})
+	if header["name"] != "X-Stager-Site" || header["value"] != "feature" {
+		t.Errorf("expected a match on the default routing header, got %v", header)
+	}
+	backendRefs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+	if len(backendRefs) != 1 || backendRefs[0].(map[string]interface{})["name"] != "feature-"+shortName {
+		t.Errorf("expected the route to send the requests to the service of the site, got %v", backendRefs)
+	}
+	fallbackRule := rules[1].(map[string]interface{})
+	if _, ok := fallbackRule["matches"]; ok {
+		t.Errorf("expected the fallback rule to match every request, got %v", fallbackRule)
+	}
+	fallbackBackendRefs, _, _ := unstructured.NestedSlice(fallbackRule, "backendRefs")
+	if len(fallbackBackendRefs) != 1 ||
+		fallbackBackendRefs[0].(map[string]interface{})["name"] != "master-"+shortName ||
+		fallbackBackendRefs[0].(map[string]interface{})["port"] != backendRefs[0].(map[string]interface{})["port"] {
+		t.Errorf("expected the fallback rule to send the requests to the baseline service, got %v", fallbackBackendRefs)
+	}
+	if len(route.GetOwnerReferences()) != 1 || route.GetOwnerReferences()[0].Name != "feature" {
+		t.Errorf("expected the route to be owned by the site, got %v", route.GetOwnerReferences())
+	}
+}
+
+func TestNetworkingHandler_EnsureNetworkingObjectsAreUpToDate_DeletesHTTPRouteWithoutBaseline(t *testing.T) {
+	ctx := context.Background()
+	const (
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := testutil.NewTestServiceConfigWithDefaults(svcName, namespace, shortName)
+	sc.Spec.HeaderRouting = &configv1.ServiceHeaderRouting{}
+
+	site := testutil.NewTestStagingSite("feature", namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	existingRoute := &unstructured.Unstructured{}
+	existingRoute.SetGroupVersionKind(httpRouteGroupVersionKind)
+	existingRoute.SetName("feature-" + shortName)
+	existingRoute.SetNamespace(namespace)
+	existingRoute.SetLabels(map[string]string{
+		"operator.kube-stager.io/site":    "feature",
+		"operator.kube-stager.io/service": svcName,
+	})
+
+	fakeClient := newHTTPRouteFakeClient(t, site, sc, existingRoute)
+
+	handler := NetworkingHandler{
+		Reader: fakeClient,
+		Writer: fakeClient,
+		Scheme: fakeClient.Scheme(),
+	}
+
+	if _, err := handler.EnsureNetworkingObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureNetworkingObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(httpRouteGroupVersionKind.GroupVersion().WithKind(httpRouteGroupVersionKind.Kind + "List"))
+	if err := fakeClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("failed to list HTTPRoutes: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("expected the HTTPRoute to be deleted for a site without a baseline site, but found %d", len(list.Items))
+	}
+}
+
+func TestNetworkingHandler_GetRelatedSiteNames(t *testing.T) {
+	ctx := context.Background()
+	const namespace = "default"
+
+	master := testutil.NewTestStagingSite("master", namespace, map[string]sitev1.StagingSiteService{})
+	feature := testutil.NewTestStagingSite("feature", namespace, map[string]sitev1.StagingSiteService{})
+	feature.Spec.BaselineSite = "master"
+	other := testutil.NewTestStagingSite("other", namespace, map[string]sitev1.StagingSiteService{})
+	other.Spec.BaselineSite = "master"
+
+	fakeClient := testutil.NewFakeClient(master, feature, other)
+	handler := NetworkingHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	got, err := handler.getRelatedSiteNames(master, ctx)
+	if err != nil {
+		t.Fatalf("getRelatedSiteNames returned unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "feature" || got[1] != "other" {
+		t.Errorf("expected the dependent sites of the baseline site, got %v", got)
+	}
+
+	got, err = handler.getRelatedSiteNames(feature, ctx)
+	if err != nil {
+		t.Fatalf("getRelatedSiteNames returned unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "master" {
+		t.Errorf("expected the baseline site of the site, got %v", got)
+	}
+}
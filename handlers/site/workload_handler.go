@@ -7,12 +7,16 @@ import (
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	"github.com/szeber/kube-stager/handlers/template"
 	"github.com/szeber/kube-stager/helpers"
+	"github.com/szeber/kube-stager/helpers/kubernetes"
 	"github.com/szeber/kube-stager/helpers/labels"
 	"github.com/szeber/kube-stager/helpers/pod"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -24,25 +28,85 @@ type WorkloadHandler struct {
 	Scheme *runtime.Scheme
 }
 
-type deploymentUpdate struct {
-	existing appsv1.Deployment
-	patch    client.Patch
-}
-
 func (r WorkloadHandler) EnsureWorkloadObjectsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
 	previousComplete := site.Status.WorkloadsAreCreated
 	previousHealth := site.Status.WorkloadHealth
 	isComplete := true
 
+	previousWorkloads := make(map[string]map[string]sitev1.StagingSiteWorkloadStatus, len(site.Status.Services))
+	for name, service := range site.Status.Services {
+		previousWorkloads[name] = service.Workloads
+	}
+
 	if complete, err := r.ensureDeploymentsAreUpToDate(site, ctx); err != nil {
 		return false, err
 	} else {
 		isComplete = isComplete && complete
 	}
 
+	if complete, err := r.ensureAdditionalWorkloadsAreUpToDate(site, ctx); err != nil {
+		return false, err
+	} else {
+		isComplete = isComplete && complete
+	}
+
 	site.Status.WorkloadsAreCreated = isComplete
 
-	return previousComplete != isComplete || site.Status.WorkloadHealth != previousHealth, nil
+	isWorkloadStatusChanged := false
+	for name, service := range site.Status.Services {
+		isWorkloadStatusChanged = isWorkloadStatusChanged || !reflect.DeepEqual(previousWorkloads[name], service.Workloads)
+	}
+
+	return previousComplete != isComplete || site.Status.WorkloadHealth != previousHealth || isWorkloadStatusChanged, nil
+}
+
+// EnsureWorkloadsArePaused removes the deployments of the site while its databases are being moved, so nothing writes
+// to them during the copy. They are recreated by EnsureWorkloadObjectsAreUpToDate once the moves are done.
+func (r WorkloadHandler) EnsureWorkloadsArePaused(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	var list appsv1.DeploymentList
+	err := r.Reader.List(
+		ctx,
+		&list,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{
+			labels.Site: site.Name,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if len(list.Items) > 0 {
+		logger.V(0).Info("Pausing workloads", "count", len(list.Items))
+		if err := r.deleteDeployments(list, ctx); err != nil {
+			return false, err
+		}
+	}
+
+	statefulSets, err := r.listStatefulSets(site, ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := r.deleteStatefulSets(statefulSets, ctx); err != nil {
+		return false, err
+	}
+
+	isChanged := site.Status.WorkloadsAreCreated || site.Status.WorkloadHealth != sitev1.WorkloadHealthIncomplete
+	site.Status.WorkloadsAreCreated = false
+	site.Status.WorkloadHealth = sitev1.WorkloadHealthIncomplete
+
+	return isChanged, nil
+}
+
+func (r WorkloadHandler) deleteDeployments(list appsv1.DeploymentList, ctx context.Context) error {
+	for _, deployment := range list.Items {
+		if err := r.Writer.Delete(ctx, &deployment); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r WorkloadHandler) ensureDeploymentsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
@@ -64,16 +128,10 @@ func (r WorkloadHandler) ensureDeploymentsAreUpToDate(site *sitev1.StagingSite,
 	}
 
 	if !site.Status.Enabled {
-		for _, service := range list.Items {
-			if err := r.Writer.Delete(ctx, &service); err != nil {
-				return false, err
-			}
-		}
-		return true, err
+		return true, r.deleteDeployments(list, ctx)
 	}
 
-	deploymentsToCreate := make(map[string]appsv1.Deployment)
-	deploymentsToUpdate := make(map[string]deploymentUpdate)
+	deploymentsToApply := make(map[string]appsv1.Deployment)
 	deploymentsToDelete := make(map[string]appsv1.Deployment)
 
 	for name := range site.Spec.Services {
@@ -81,34 +139,36 @@ func (r WorkloadHandler) ensureDeploymentsAreUpToDate(site *sitev1.StagingSite,
 		if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
 			return false, err
 		}
+		if config.Spec.DeploymentPodSpec == nil {
+			// The workloads of the service are rendered from its helm chart
+			continue
+		}
 
 		if deployment, err := r.createDeployment(site, config, ctx); err != nil {
 			return false, err
 		} else {
-			deploymentsToCreate[name] = *deployment
+			deploymentsToApply[name] = *deployment
 		}
 	}
 
 	isEverythingHealthy := true
 	for _, existingDeployment := range list.Items {
+		if _, ok := existingDeployment.Labels[labels.Workload]; ok {
+			// Additional workloads are handled by ensureAdditionalWorkloadsAreUpToDate
+			continue
+		}
 		serviceName := existingDeployment.Labels[labels.Service]
 
-		if _, ok := deploymentsToCreate[serviceName]; ok {
-			patch := client.MergeFrom(existingDeployment.DeepCopy())
-			r.updateDeploymentFromOther(&existingDeployment, deploymentsToCreate[serviceName])
-			deploymentsToUpdate[serviceName] = deploymentUpdate{
-				existing: existingDeployment,
-				patch:    patch,
-			}
-
+		if _, ok := deploymentsToApply[serviceName]; ok {
 			serviceStatus := site.Status.Services[serviceName]
 			serviceStatus.DeploymentStatus = *existingDeployment.Status.DeepCopy()
 			site.Status.Services[serviceName] = serviceStatus
 
-			isEverythingHealthy = isEverythingHealthy &&
-				(*existingDeployment.Spec.Replicas == existingDeployment.Status.ReadyReplicas)
-
-			delete(deploymentsToCreate, serviceName)
+			desiredReplicas, err := r.getDesiredReplicas(&existingDeployment, deploymentsToApply[serviceName], ctx)
+			if err != nil {
+				return false, err
+			}
+			isEverythingHealthy = isEverythingHealthy && desiredReplicas == existingDeployment.Status.ReadyReplicas
 		} else {
 			deploymentsToDelete[serviceName] = existingDeployment
 		}
@@ -126,19 +186,9 @@ func (r WorkloadHandler) ensureDeploymentsAreUpToDate(site *sitev1.StagingSite,
 			return false, err
 		}
 	}
-	for serviceName, deployment := range deploymentsToUpdate {
-		logger.V(1).Info("Updating deployment for service", "serviceName", serviceName)
-		if err := r.Writer.Patch(
-			ctx,
-			&deployment.existing,
-			deployment.patch,
-		); err != nil {
-			return false, err
-		}
-	}
-	for serviceName, deployment := range deploymentsToCreate {
-		logger.V(1).Info("Creating deployment for service " + serviceName)
-		if err = r.Writer.Create(ctx, &deployment); err != nil {
+	for serviceName, deployment := range deploymentsToApply {
+		logger.V(1).Info("Applying deployment for service", "serviceName", serviceName)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, &deployment); err != nil {
 			return false, err
 		}
 	}
@@ -168,7 +218,7 @@ func (r WorkloadHandler) createDeployment(
 		replicas = int32(1)
 	}
 
-	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(serviceConfig.Spec.DeploymentPodSpec, &templateHandler)
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(*serviceConfig.Spec.DeploymentPodSpec, &templateHandler)
 	if err != nil {
 		return nil, err
 	}
@@ -195,6 +245,11 @@ func (r WorkloadHandler) createDeployment(
 		},
 	}
 
+	if getAutoscaling(site, serviceConfig) != nil {
+		// The replica count is managed by the autoscaler
+		deployment.Spec.Replicas = nil
+	}
+
 	if err := ctrl.SetControllerReference(site, deployment, r.Scheme); err != nil {
 		return nil, err
 	}
@@ -202,9 +257,300 @@ func (r WorkloadHandler) createDeployment(
 	return deployment, nil
 }
 
-func (r WorkloadHandler) updateDeploymentFromOther(a *appsv1.Deployment, b appsv1.Deployment) {
-	a.Labels = b.Labels
-	a.Spec.Template.Labels = b.Spec.Template.Labels
-	a.Spec.Template.Spec = b.Spec.Template.Spec
-	a.Spec.Replicas = b.Spec.Replicas
+// getDesiredReplicas returns the replica count the deployment should be running. For autoscaled deployments this is
+// the replica count desired by the autoscaler, as the one in the deployment may not be updated yet
+func (r WorkloadHandler) getDesiredReplicas(
+	existing *appsv1.Deployment,
+	desired appsv1.Deployment,
+	ctx context.Context,
+) (int32, error) {
+	replicas := int32(1)
+	if existing.Spec.Replicas != nil {
+		replicas = *existing.Spec.Replicas
+	}
+	if desired.Spec.Replicas != nil {
+		return replicas, nil
+	}
+
+	// The autoscaler has the same name as the deployment
+	autoscaler := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := r.Reader.Get(ctx, client.ObjectKey{Namespace: existing.Namespace, Name: existing.Name}, autoscaler)
+	if apierrors.IsNotFound(err) {
+		return replicas, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	if autoscaler.Status.DesiredReplicas > 0 {
+		return autoscaler.Status.DesiredReplicas, nil
+	}
+
+	return replicas, nil
+}
+
+// additionalWorkload is the desired state of an additional workload of a service
+type additionalWorkload struct {
+	serviceName  string
+	workloadName string
+	kind         configv1.WorkloadKind
+	deployment   *appsv1.Deployment
+	statefulSet  *appsv1.StatefulSet
+}
+
+// ensureAdditionalWorkloadsAreUpToDate reconciles the additional workloads of the services of the site, and records
+// their health in the service statuses. An unhealthy additional workload makes the site unhealthy
+func (r WorkloadHandler) ensureAdditionalWorkloadsAreUpToDate(site *sitev1.StagingSite, ctx context.Context) (
+	bool,
+	error,
+) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving additional workload list")
+
+	var deploymentList appsv1.DeploymentList
+	err := r.Reader.List(
+		ctx,
+		&deploymentList,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{labels.Site: site.Name},
+		client.HasLabels{labels.Workload},
+	)
+	if err != nil {
+		return false, err
+	}
+	statefulSetList, err := r.listStatefulSets(site, ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if !site.Status.Enabled {
+		for name, service := range site.Status.Services {
+			service.Workloads = nil
+			site.Status.Services[name] = service
+		}
+		if err := r.deleteDeployments(deploymentList, ctx); err != nil {
+			return false, err
+		}
+		return true, r.deleteStatefulSets(statefulSetList, ctx)
+	}
+
+	workloads := make(map[string]additionalWorkload)
+	for name := range site.Spec.Services {
+		config := &configv1.ServiceConfig{}
+		if err := r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, config); err != nil {
+			return false, err
+		}
+
+		for workloadName, workloadConfig := range config.Spec.Workloads {
+			workload, err := r.createAdditionalWorkload(site, config, workloadName, workloadConfig, ctx)
+			if err != nil {
+				return false, err
+			}
+			workloads[api.MakeWorkloadName(site, config, workloadName)] = workload
+		}
+	}
+
+	statuses := make(map[string]map[string]sitev1.StagingSiteWorkloadStatus)
+	setStatus := func(workload additionalWorkload, replicas int32, readyReplicas int32, health sitev1.WorkloadHealth) {
+		if _, ok := statuses[workload.serviceName]; !ok {
+			statuses[workload.serviceName] = make(map[string]sitev1.StagingSiteWorkloadStatus)
+		}
+		statuses[workload.serviceName][workload.workloadName] = sitev1.StagingSiteWorkloadStatus{
+			Kind:          workload.kind,
+			Replicas:      replicas,
+			ReadyReplicas: readyReplicas,
+			Health:        health,
+		}
+	}
+	getHealth := func(replicas int32, readyReplicas int32) sitev1.WorkloadHealth {
+		if replicas == readyReplicas {
+			return sitev1.WorkloadHealthHealthy
+		}
+		return sitev1.WorkloadHealthUnhealthy
+	}
+
+	isEverythingHealthy := true
+	for _, existing := range deploymentList.Items {
+		workload, ok := workloads[existing.Name]
+		if !ok || workload.deployment == nil {
+			logger.V(1).Info("Deleting additional deployment", "name", existing.Name)
+			if err = r.Writer.Delete(ctx, &existing); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		logger.V(1).Info("Applying additional deployment", "name", existing.Name)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, workload.deployment); err != nil {
+			return false, err
+		}
+
+		health := getHealth(*existing.Spec.Replicas, existing.Status.ReadyReplicas)
+		isEverythingHealthy = isEverythingHealthy && health == sitev1.WorkloadHealthHealthy
+		setStatus(workload, *existing.Spec.Replicas, existing.Status.ReadyReplicas, health)
+		delete(workloads, existing.Name)
+	}
+	for _, existing := range statefulSetList.Items {
+		workload, ok := workloads[existing.Name]
+		if !ok || workload.statefulSet == nil {
+			logger.V(1).Info("Deleting additional stateful set", "name", existing.Name)
+			if err = r.Writer.Delete(ctx, &existing); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		logger.V(1).Info("Applying additional stateful set", "name", existing.Name)
+		if err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, workload.statefulSet); err != nil {
+			return false, err
+		}
+
+		health := getHealth(*existing.Spec.Replicas, existing.Status.ReadyReplicas)
+		isEverythingHealthy = isEverythingHealthy && health == sitev1.WorkloadHealthHealthy
+		setStatus(workload, *existing.Spec.Replicas, existing.Status.ReadyReplicas, health)
+		delete(workloads, existing.Name)
+	}
+
+	// Only the workloads not existing yet are left, including the ones whose kind was changed
+	for name, workload := range workloads {
+		logger.V(1).Info("Creating additional workload", "name", name, "kind", workload.kind)
+		var replicas int32
+		if workload.deployment != nil {
+			replicas = *workload.deployment.Spec.Replicas
+			err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, workload.deployment)
+		} else {
+			replicas = *workload.statefulSet.Spec.Replicas
+			err = kubernetes.Apply(ctx, r.Reader, r.Writer, r.Scheme, workload.statefulSet)
+		}
+		if err != nil {
+			return false, err
+		}
+		isEverythingHealthy = isEverythingHealthy && replicas == 0
+		setStatus(workload, replicas, 0, getHealth(replicas, 0))
+	}
+
+	for name, service := range site.Status.Services {
+		service.Workloads = statuses[name]
+		site.Status.Services[name] = service
+	}
+
+	if !isEverythingHealthy && site.Status.WorkloadHealth == sitev1.WorkloadHealthHealthy {
+		site.Status.WorkloadHealth = sitev1.WorkloadHealthUnhealthy
+	}
+
+	logger.V(0).Info("Additional workloads created")
+
+	return true, nil
+}
+
+func (r WorkloadHandler) createAdditionalWorkload(
+	site *sitev1.StagingSite,
+	serviceConfig *configv1.ServiceConfig,
+	name string,
+	workloadConfig configv1.ServiceWorkload,
+	ctx context.Context,
+) (additionalWorkload, error) {
+	templateHandler := template.NewSite(*site, *serviceConfig)
+	err := template.LoadConfigs(&templateHandler, ctx, r.Reader)
+	if err != nil {
+		return additionalWorkload{}, err
+	}
+
+	objectName := api.MakeWorkloadName(site, serviceConfig, name)
+	// The pods don't get the service label, so they are not selected by the deployment and the service of the service
+	podLabels := map[string]string{
+		labels.Site:     site.Name,
+		labels.Workload: objectName,
+	}
+	objectLabels := map[string]string{
+		labels.Site:     site.Name,
+		labels.Service:  serviceConfig.Name,
+		labels.Workload: objectName,
+	}
+
+	siteWorkload := site.Spec.Services[serviceConfig.Name].Workloads[name]
+	replicas := int32(1)
+	if siteWorkload.Replicas != nil {
+		replicas = *siteWorkload.Replicas
+	} else if workloadConfig.Replicas != nil {
+		replicas = *workloadConfig.Replicas
+	}
+
+	podSpec, err := helpers.ReplaceTemplateVariablesInPodSpec(workloadConfig.PodSpec, &templateHandler)
+	if err != nil {
+		return additionalWorkload{}, err
+	}
+	podTemplate := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: podLabels,
+		},
+		Spec: pod.SetExtraEnvVarsOnPodSpec(
+			pod.ApplyResourceOverrides(podSpec, siteWorkload.ResourceOverrides),
+			site,
+			serviceConfig,
+		),
+	}
+	objectMeta := metav1.ObjectMeta{
+		Name:      objectName,
+		Namespace: site.Namespace,
+		Labels:    objectLabels,
+	}
+
+	result := additionalWorkload{
+		serviceName:  serviceConfig.Name,
+		workloadName: name,
+		kind:         workloadConfig.Kind,
+	}
+	var object client.Object
+	if workloadConfig.Kind == configv1.WorkloadKindStatefulSet {
+		result.statefulSet = &appsv1.StatefulSet{
+			ObjectMeta: objectMeta,
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:    &replicas,
+				ServiceName: objectName,
+				Selector:    &metav1.LabelSelector{MatchLabels: podLabels},
+				Template:    podTemplate,
+			},
+		}
+		object = result.statefulSet
+	} else {
+		result.kind = configv1.WorkloadKindDeployment
+		result.deployment = &appsv1.Deployment{
+			ObjectMeta: objectMeta,
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+				Template: podTemplate,
+			},
+		}
+		object = result.deployment
+	}
+
+	if err := ctrl.SetControllerReference(site, object, r.Scheme); err != nil {
+		return additionalWorkload{}, err
+	}
+
+	return result, nil
+}
+
+func (r WorkloadHandler) listStatefulSets(site *sitev1.StagingSite, ctx context.Context) (appsv1.StatefulSetList, error) {
+	var list appsv1.StatefulSetList
+	err := r.Reader.List(
+		ctx,
+		&list,
+		client.InNamespace(site.Namespace),
+		client.MatchingLabels{labels.Site: site.Name},
+		client.HasLabels{labels.Workload},
+	)
+
+	return list, err
+}
+
+func (r WorkloadHandler) deleteStatefulSets(list appsv1.StatefulSetList, ctx context.Context) error {
+	for _, statefulSet := range list.Items {
+		if err := r.Writer.Delete(ctx, &statefulSet); err != nil {
+			return err
+		}
+	}
+	return nil
 }
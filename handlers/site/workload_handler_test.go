@@ -4,9 +4,13 @@ import (
 	"context"
 	"testing"
 
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/helpers/labels"
 	"github.com/szeber/kube-stager/internal/testutil"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -75,6 +79,61 @@ func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_CreatesDeployment(t *t
 	}
 }
 
+func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_HelmChartServiceHasNoDeployment(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	sc := testutil.NewTestServiceConfig(svcName, namespace, shortName)
+	sc.Spec.DeploymentPodSpec = nil
+	sc.Spec.HelmChart = &configv1.ServiceHelmChart{ConfigMap: &configv1.ServiceHelmChartConfigMap{Name: "chart"}}
+
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+
+	// The deployment created before the service was switched to the helm chart
+	replicas := int32(1)
+	existingDep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      siteName + "-" + shortName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"operator.kube-stager.io/site":    siteName,
+				"operator.kube-stager.io/service": svcName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+
+	fakeClient := testutil.NewFakeClient(site, sc, existingDep)
+	handler := WorkloadHandler{
+		Reader: fakeClient,
+		Writer: fakeClient,
+		Scheme: testutil.NewTestScheme(),
+	}
+
+	if _, err := handler.EnsureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureWorkloadObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	var depList appsv1.DeploymentList
+	if err := fakeClient.List(ctx, &depList, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("failed to list Deployments: %v", err)
+	}
+	if len(depList.Items) != 0 {
+		t.Errorf("expected no Deployments for a service rendered from a helm chart, found %d", len(depList.Items))
+	}
+	if site.Status.WorkloadHealth != sitev1.WorkloadHealthHealthy {
+		t.Errorf("expected the site to be healthy, got %s", site.Status.WorkloadHealth)
+	}
+}
+
 func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_SetsReplicaCount(t *testing.T) {
 	ctx := context.Background()
 	const (
@@ -191,6 +250,67 @@ func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_DisabledSiteDeletesDep
 	}
 }
 
+func TestWorkloadHandler_EnsureWorkloadsArePaused_DeletesDeployments(t *testing.T) {
+	ctx := context.Background()
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		namespace = "default"
+	)
+
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+	site.Status.WorkloadsAreCreated = true
+	site.Status.WorkloadHealth = sitev1.WorkloadHealthHealthy
+
+	existingDep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      siteName + "-svc",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"operator.kube-stager.io/site":    siteName,
+				"operator.kube-stager.io/service": svcName,
+			},
+		},
+	}
+
+	fakeClient := testutil.NewFakeClient(site, existingDep)
+	handler := WorkloadHandler{
+		Reader: fakeClient,
+		Writer: fakeClient,
+		Scheme: testutil.NewTestScheme(),
+	}
+
+	changed, err := handler.EnsureWorkloadsArePaused(site, ctx)
+	if err != nil {
+		t.Fatalf("EnsureWorkloadsArePaused returned unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected changed=true when the workloads were running")
+	}
+	if site.Status.WorkloadsAreCreated || site.Status.WorkloadHealth != sitev1.WorkloadHealthIncomplete {
+		t.Errorf("expected the workloads to be reported as paused, got %v/%s", site.Status.WorkloadsAreCreated, site.Status.WorkloadHealth)
+	}
+
+	var depList appsv1.DeploymentList
+	if err := fakeClient.List(ctx, &depList, client.InNamespace(namespace)); err != nil {
+		t.Fatalf("failed to list Deployments: %v", err)
+	}
+	if len(depList.Items) != 0 {
+		t.Errorf("expected all Deployments to be deleted while paused, but found %d", len(depList.Items))
+	}
+
+	changed, err = handler.EnsureWorkloadsArePaused(site, ctx)
+	if err != nil {
+		t.Fatalf("EnsureWorkloadsArePaused returned unexpected error: %v", err)
+	}
+	if changed {
+		t.Error("expected changed=false when the workloads are already paused")
+	}
+}
+
 func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_DisabledSiteDoesNotCreateDeployments(t *testing.T) {
 	ctx := context.Background()
 	const (
@@ -319,3 +439,152 @@ func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_DefaultReplicaCountIsO
 		t.Errorf("expected default Deployment replicas=1 when site replicas=0, got %d", *dep.Spec.Replicas)
 	}
 }
+
+func newAdditionalWorkloadTestObjects(kind configv1.WorkloadKind) (*sitev1.StagingSite, *configv1.ServiceConfig) {
+	const (
+		siteName  = "test-site"
+		svcName   = "my-service"
+		shortName = "svc"
+		namespace = "default"
+	)
+
+	workerReplicas := int32(2)
+	sc := testutil.NewTestServiceConfig(svcName, namespace, shortName)
+	sc.Spec.Workloads = map[string]configv1.ServiceWorkload{
+		"worker": {
+			Kind:     kind,
+			Replicas: &workerReplicas,
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "worker", Image: "worker:latest"}},
+			},
+		},
+	}
+
+	site := testutil.NewTestStagingSite(siteName, namespace, map[string]sitev1.StagingSiteService{
+		svcName: {ImageTag: "latest", Replicas: 1},
+	})
+	site.Status.Enabled = true
+	site.Status.Services = map[string]sitev1.StagingSiteServiceStatus{svcName: {}}
+
+	return site, sc
+}
+
+func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_CreatesAdditionalDeployment(t *testing.T) {
+	ctx := context.Background()
+	site, sc := newAdditionalWorkloadTestObjects(configv1.WorkloadKindDeployment)
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := WorkloadHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if _, err := handler.EnsureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureWorkloadObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	var depList appsv1.DeploymentList
+	if err := fakeClient.List(ctx, &depList, client.InNamespace("default")); err != nil {
+		t.Fatalf("failed to list Deployments: %v", err)
+	}
+	if len(depList.Items) != 2 {
+		t.Fatalf("expected the main and the worker Deployment, got %d", len(depList.Items))
+	}
+
+	worker := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc-worker"}, worker); err != nil {
+		t.Fatalf("failed to get the worker Deployment: %v", err)
+	}
+	if *worker.Spec.Replicas != 2 {
+		t.Errorf("expected 2 worker replicas, got %d", *worker.Spec.Replicas)
+	}
+	if _, ok := worker.Spec.Template.Labels[labels.Service]; ok {
+		t.Error("expected the worker pods not to have the service label, so the service doesn't select them")
+	}
+
+	status := site.Status.Services["my-service"].Workloads["worker"]
+	if status.Kind != configv1.WorkloadKindDeployment || status.Replicas != 2 ||
+		status.Health != sitev1.WorkloadHealthUnhealthy {
+		t.Errorf("unexpected worker status: %+v", status)
+	}
+
+	// The main deployment must not pick up the worker deployment as its own on the next run
+	if _, err := handler.EnsureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureWorkloadObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+	if err := fakeClient.List(ctx, &depList, client.InNamespace("default")); err != nil {
+		t.Fatalf("failed to list Deployments: %v", err)
+	}
+	if len(depList.Items) != 2 {
+		t.Errorf("expected 2 Deployments after the second run, got %d", len(depList.Items))
+	}
+	if site.Status.WorkloadHealth != sitev1.WorkloadHealthUnhealthy {
+		t.Errorf("expected the site to be unhealthy while the worker isn't ready, got %s", site.Status.WorkloadHealth)
+	}
+}
+
+func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_SiteOverridesAdditionalWorkload(t *testing.T) {
+	ctx := context.Background()
+	site, sc := newAdditionalWorkloadTestObjects(configv1.WorkloadKindStatefulSet)
+	replicas := int32(0)
+	service := site.Spec.Services["my-service"]
+	service.Workloads = map[string]sitev1.StagingSiteWorkload{
+		"worker": {
+			Replicas: &replicas,
+			ResourceOverrides: map[string]corev1.ResourceRequirements{
+				"worker": {Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}},
+			},
+		},
+	}
+	site.Spec.Services["my-service"] = service
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := WorkloadHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if _, err := handler.EnsureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureWorkloadObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	worker := &appsv1.StatefulSet{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-site-svc-worker"}, worker); err != nil {
+		t.Fatalf("failed to get the worker StatefulSet: %v", err)
+	}
+	if *worker.Spec.Replicas != 0 {
+		t.Errorf("expected the site to override the replicas to 0, got %d", *worker.Spec.Replicas)
+	}
+	limit := worker.Spec.Template.Spec.Containers[0].Resources.Limits[corev1.ResourceMemory]
+	if limit.String() != "256Mi" {
+		t.Errorf("expected a 256Mi memory limit, got %s", limit.String())
+	}
+	if status := site.Status.Services["my-service"].Workloads["worker"]; status.Health != sitev1.WorkloadHealthHealthy {
+		t.Errorf("expected a workload with 0 replicas to be healthy, got %+v", status)
+	}
+}
+
+func TestWorkloadHandler_EnsureWorkloadObjectsAreUpToDate_RemovedWorkloadIsDeleted(t *testing.T) {
+	ctx := context.Background()
+	site, sc := newAdditionalWorkloadTestObjects(configv1.WorkloadKindStatefulSet)
+
+	fakeClient := testutil.NewFakeClient(site, sc)
+	handler := WorkloadHandler{Reader: fakeClient, Writer: fakeClient, Scheme: testutil.NewTestScheme()}
+
+	if _, err := handler.EnsureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureWorkloadObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	sc.Spec.Workloads = nil
+	if err := fakeClient.Update(ctx, sc); err != nil {
+		t.Fatalf("failed to update the ServiceConfig: %v", err)
+	}
+	if _, err := handler.EnsureWorkloadObjectsAreUpToDate(site, ctx); err != nil {
+		t.Fatalf("EnsureWorkloadObjectsAreUpToDate returned unexpected error: %v", err)
+	}
+
+	var list appsv1.StatefulSetList
+	if err := fakeClient.List(ctx, &list, client.InNamespace("default")); err != nil {
+		t.Fatalf("failed to list StatefulSets: %v", err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("expected the StatefulSet to be deleted, found %d", len(list.Items))
+	}
+	if len(site.Status.Services["my-service"].Workloads) != 0 {
+		t.Errorf("expected no workload statuses, got %v", site.Status.Services["my-service"].Workloads)
+	}
+}
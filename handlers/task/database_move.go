@@ -0,0 +1,20 @@
+package task
+
+import (
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsDatabaseMoveRequired returns TRUE if changing the environment of an existing database has to be done by a
+// DatabaseMove copying its data, instead of just pointing the task to the new, empty environment
+func IsDatabaseMoveRequired(state taskv1.TaskState, config *configv1.ServiceConfig) bool {
+	return state == taskv1.Complete && config.Spec.DatabaseMovePodSpec != nil
+}
+
+// IsDatabaseMoveTarget returns TRUE if the task was created by a DatabaseMove in the target environment. These are
+// managed by the move, not by the site.
+func IsDatabaseMoveTarget(object metav1.Object) bool {
+	return object.GetLabels()[labels.DatabaseMove] != ""
+}
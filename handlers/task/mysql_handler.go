@@ -33,6 +33,7 @@ func (r MysqlTaskHandler) EnsureDatabasesAreCreated(site *sitev1.StagingSite, ct
 	logger.V(1).Info("Retrieved list.", "count", len(list.Items))
 	logger.V(1).Info("Getting changes required to reconcile the mysql databases")
 
+	serviceConfigs := make(map[string]*configv1.ServiceConfig)
 	databasesToDelete := make(map[string]taskv1.MysqlDatabase)
 	databasesToUpdate := make(map[string]taskv1.MysqlDatabase)
 	databasesToCreate := make(map[string]taskv1.MysqlDatabase)
@@ -46,6 +47,7 @@ func (r MysqlTaskHandler) EnsureDatabasesAreCreated(site *sitev1.StagingSite, ct
 		if err != nil {
 			return false, err
 		}
+		serviceConfigs[name] = &serviceConfig
 		databasesToCreate[name], err = r.getPopulatedDatabase(site, &serviceConfig, service.MysqlEnvironment)
 		if err != nil {
 			return false, err
@@ -53,9 +55,17 @@ func (r MysqlTaskHandler) EnsureDatabasesAreCreated(site *sitev1.StagingSite, ct
 	}
 
 	for _, database := range list.Items {
+		if IsDatabaseMoveTarget(&database) {
+			continue
+		}
 		serviceName := database.Spec.EnvironmentConfig.ServiceName
 
 		if expectedDatabase, ok := databasesToCreate[serviceName]; ok {
+			if database.Spec.EnvironmentConfig.Environment != expectedDatabase.Spec.EnvironmentConfig.Environment &&
+				IsDatabaseMoveRequired(database.Status.State, serviceConfigs[serviceName]) {
+				// The data is copied by a DatabaseMove, which switches the environment once it's done
+				expectedDatabase.SetEnvironment(database.Spec.EnvironmentConfig.Environment)
+			}
 			if !database.Matches(expectedDatabase) {
 				database.UpdateFromExpected(expectedDatabase)
 				databasesToUpdate[serviceName] = database
@@ -107,6 +117,9 @@ func (r MysqlTaskHandler) EnsureDatabasesAreReady(site *sitev1.StagingSite, ctx
 	isEverythingReady := true
 
 	for _, database := range list.Items {
+		if IsDatabaseMoveTarget(&database) {
+			continue
+		}
 		if database.Status.State == taskv1.Failed {
 			return false, errors.DatabaseCreationError{
 				DatabaseType:      errors.DatabaseTypeMysql,
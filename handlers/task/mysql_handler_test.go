@@ -10,6 +10,7 @@ import (
 	"github.com/szeber/kube-stager/helpers/errors"
 	"github.com/szeber/kube-stager/helpers/labels"
 	"github.com/szeber/kube-stager/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -165,6 +166,52 @@ func TestMysqlEnsureDatabasesAreCreated_DeletesOrphanedDatabase(t *testing.T) {
 	}
 }
 
+// TestMysqlEnsureDatabasesAreCreated_KeepsEnvironmentWhenMoveIsRequired verifies that changing the environment of a
+// created database doesn't repoint it when the service config supports moving the data, as the DatabaseMove switches
+// the environment after copying the data.
+func TestMysqlEnsureDatabasesAreCreated_KeepsEnvironmentWhenMoveIsRequired(t *testing.T) {
+	site := newMysqlSiteWithEnv()
+	sc := newMysqlServiceConfig()
+	sc.Spec.DatabaseMovePodSpec = &corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "move", Image: "mover:latest"}},
+	}
+
+	existingDB := &taskv1.MysqlDatabase{}
+	if err := existingDB.PopulateFomSite(site, sc, "old-mysql-env"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	existingDB.Status.State = taskv1.Complete
+	// A database created by a move in the target environment must be left alone
+	moveTargetDB := existingDB.DeepCopy()
+	moveTargetDB.Name = "test-move"
+	moveTargetDB.Labels[labels.DatabaseMove] = "test-move"
+	moveTargetDB.SetEnvironment(mysqlTestEnvName)
+
+	handler, c := newMysqlHandler(site, sc, existingDB, moveTargetDB)
+	ctx := context.Background()
+
+	done, err := handler.EnsureDatabasesAreCreated(site, ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !done {
+		t.Error("expected done=true as the database is waiting for the move")
+	}
+
+	var list taskv1.MysqlDatabaseList
+	if err := c.List(ctx, &list, client.InNamespace(mysqlTestNamespace), client.MatchingLabels{labels.Site: mysqlTestSiteName}); err != nil {
+		t.Fatalf("failed to list mysql databases: %v", err)
+	}
+	if len(list.Items) != 2 {
+		t.Fatalf("expected 2 MysqlDatabases, got %d", len(list.Items))
+	}
+	for _, db := range list.Items {
+		if db.Name == existingDB.Name && db.Spec.EnvironmentConfig.Environment != "old-mysql-env" {
+			t.Errorf("Environment = %q, want %q", db.Spec.EnvironmentConfig.Environment, "old-mysql-env")
+		}
+	}
+}
+
 // TestMysqlEnsureDatabasesAreReady_AllComplete verifies that when all databases are in the
 // Complete state, EnsureDatabasesAreReady returns true.
 func TestMysqlEnsureDatabasesAreReady_AllComplete(t *testing.T) {
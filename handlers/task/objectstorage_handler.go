@@ -0,0 +1,160 @@
+package task
+
+import (
+	"context"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type ObjectStorageTaskHandler struct {
+	Reader client.Reader
+	Writer client.Writer
+	Scheme *runtime.Scheme
+}
+
+func (r ObjectStorageTaskHandler) EnsureDatabasesAreCreated(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving object storage bucket list")
+
+	var list taskv1.ObjectStorageBucketList
+	err := r.Reader.List(ctx, &list, client.InNamespace(site.Namespace), client.MatchingLabels{labels.Site: site.Name})
+	if err != nil {
+		return false, err
+	}
+
+	logger.V(1).Info("Retrieved list.", "count", len(list.Items))
+	logger.V(1).Info("Getting changes required to reconcile the object storage buckets")
+
+	bucketsToDelete := make(map[string]taskv1.ObjectStorageBucket)
+	bucketsToUpdate := make(map[string]taskv1.ObjectStorageBucket)
+	bucketsToCreate := make(map[string]taskv1.ObjectStorageBucket)
+
+	for name, service := range site.Spec.Services {
+		if service.ObjectStorageEnvironment == "" {
+			continue
+		}
+		var serviceConfig configv1.ServiceConfig
+		err = r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, &serviceConfig)
+		if err != nil {
+			return false, err
+		}
+		var storageConfig configv1.ObjectStorageConfig
+		err = r.Reader.Get(
+			ctx,
+			client.ObjectKey{Namespace: site.Namespace, Name: service.ObjectStorageEnvironment},
+			&storageConfig,
+		)
+		if err != nil {
+			return false, err
+		}
+		bucketsToCreate[name], err = r.getPopulatedBucket(site, &serviceConfig, &storageConfig)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, bucket := range list.Items {
+		serviceName := bucket.Spec.EnvironmentConfig.ServiceName
+
+		if expectedBucket, ok := bucketsToCreate[serviceName]; ok {
+			if !bucket.Matches(expectedBucket) {
+				if bucket.Spec.EnvironmentConfig.Environment != expectedBucket.Spec.EnvironmentConfig.Environment ||
+					bucket.Spec.BucketName != expectedBucket.Spec.BucketName {
+					// The objects are not copied, the old bucket is emptied by its finalizer and a new one is created
+					bucketsToDelete[serviceName] = bucket
+					continue
+				}
+				bucket.UpdateFromExpected(expectedBucket)
+				bucketsToUpdate[serviceName] = bucket
+			}
+			delete(bucketsToCreate, serviceName)
+		} else {
+			bucketsToDelete[serviceName] = bucket
+		}
+	}
+
+	isComplete := len(bucketsToDelete) == 0 && len(bucketsToCreate) == 0 && len(bucketsToUpdate) == 0
+
+	for serviceName, bucket := range bucketsToDelete {
+		logger.V(1).Info("Deleting object storage bucket for service " + serviceName)
+		if err = r.Writer.Delete(ctx, &bucket); err != nil {
+			return isComplete, err
+		}
+		// The replacement is created once the old bucket is gone, as it has the same name
+		delete(bucketsToCreate, serviceName)
+	}
+	for serviceName, bucket := range bucketsToCreate {
+		logger.V(1).Info("Creating object storage bucket for service " + serviceName)
+		if err = r.Writer.Create(ctx, &bucket); err != nil {
+			return isComplete, err
+		}
+	}
+	for serviceName, bucket := range bucketsToUpdate {
+		logger.V(1).Info("Updating object storage bucket for service " + serviceName)
+		if err = r.Writer.Update(ctx, &bucket); err != nil {
+			return isComplete, err
+		}
+	}
+
+	logger.V(0).Info("Object storage buckets created/updated")
+
+	return isComplete, nil
+}
+
+func (r ObjectStorageTaskHandler) EnsureDatabasesAreReady(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving object storage bucket list")
+
+	var list taskv1.ObjectStorageBucketList
+	err := r.Reader.List(ctx, &list, client.InNamespace(site.Namespace), client.MatchingLabels{labels.Site: site.Name})
+	if err != nil {
+		return false, err
+	}
+	logger.V(1).Info("Retrieved list.", "count", len(list.Items))
+
+	isEverythingReady := true
+
+	for _, bucket := range list.Items {
+		if bucket.Status.State == taskv1.Failed {
+			return false, errors.DatabaseCreationError{
+				DatabaseType:      errors.DatabaseTypeObjectStorage,
+				EnvironmentConfig: bucket.Spec.EnvironmentConfig,
+			}
+		}
+		isEverythingReady = isEverythingReady && bucket.Status.State == taskv1.Complete
+	}
+
+	if isEverythingReady {
+		logger.V(1).Info("All object storage buckets are ready")
+	} else {
+		logger.V(0).Info("Not all object storage buckets are ready yet")
+	}
+
+	return isEverythingReady, nil
+}
+
+func (r ObjectStorageTaskHandler) getPopulatedBucket(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	storageConfig *configv1.ObjectStorageConfig,
+) (taskv1.ObjectStorageBucket, error) {
+	bucket := taskv1.ObjectStorageBucket{}
+	if err := bucket.PopulateFomSite(site, config, storageConfig); err != nil {
+		return bucket, err
+	}
+
+	if err := ctrl.SetControllerReference(site, &bucket, r.Scheme); err != nil {
+		return bucket, err
+	}
+
+	return bucket, nil
+}
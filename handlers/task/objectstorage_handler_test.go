@@ -0,0 +1,148 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/internal/testutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	storageTestNamespace   = "storage-ns"
+	storageTestSiteName    = "storage-site"
+	storageTestServiceName = "storage-service"
+	storageTestShortName   = "ssvc"
+	storageTestEnvName     = "minio"
+)
+
+func newObjectStorageHandler(objs ...client.Object) (ObjectStorageTaskHandler, client.Client) {
+	c := testutil.NewFakeClient(objs...)
+	return ObjectStorageTaskHandler{
+		Reader: c,
+		Writer: c,
+		Scheme: testutil.NewTestScheme(),
+	}, c
+}
+
+func newObjectStorageSite(environment string) *sitev1.StagingSite {
+	return testutil.NewTestStagingSite(storageTestSiteName, storageTestNamespace, map[string]sitev1.StagingSiteService{
+		storageTestServiceName: {
+			ImageTag:                 "latest",
+			Replicas:                 1,
+			ObjectStorageEnvironment: environment,
+		},
+	})
+}
+
+func listObjectStorageBuckets(t *testing.T, c client.Client) []taskv1.ObjectStorageBucket {
+	var list taskv1.ObjectStorageBucketList
+	if err := c.List(
+		context.Background(),
+		&list,
+		client.InNamespace(storageTestNamespace),
+		client.MatchingLabels{labels.Site: storageTestSiteName},
+	); err != nil {
+		t.Fatalf("failed to list buckets: %v", err)
+	}
+	return list.Items
+}
+
+// TestObjectStorageEnsureDatabasesAreCreated_CreatesBucket verifies that a bucket task is created for a service with
+// an object storage environment
+func TestObjectStorageEnsureDatabasesAreCreated_CreatesBucket(t *testing.T) {
+	site := newObjectStorageSite(storageTestEnvName)
+	handler, c := newObjectStorageHandler(
+		site,
+		testutil.NewTestServiceConfig(storageTestServiceName, storageTestNamespace, storageTestShortName),
+		testutil.NewTestObjectStorageConfig(storageTestEnvName, storageTestNamespace),
+	)
+
+	done, err := handler.EnsureDatabasesAreCreated(site, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if done {
+		t.Error("expected done=false because creation was just performed")
+	}
+
+	buckets := listObjectStorageBuckets(t, c)
+	if len(buckets) != 1 {
+		t.Fatalf("expected 1 ObjectStorageBucket, got %d", len(buckets))
+	}
+	if buckets[0].Spec.BucketName != storageTestSiteName+"-"+storageTestShortName {
+		t.Errorf("BucketName = %q, want %q", buckets[0].Spec.BucketName, storageTestSiteName+"-"+storageTestShortName)
+	}
+
+	done, err = handler.EnsureDatabasesAreCreated(site, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !done {
+		t.Error("expected done=true once the bucket exists")
+	}
+}
+
+// TestObjectStorageEnsureDatabasesAreCreated_DeletesBucketOfRemovedService verifies that the bucket task is deleted
+// once the service no longer uses object storage
+func TestObjectStorageEnsureDatabasesAreCreated_DeletesBucketOfRemovedService(t *testing.T) {
+	site := newObjectStorageSite("")
+	existing := testutil.NewTestObjectStorageBucket(
+		"existing",
+		storageTestNamespace,
+		storageTestSiteName,
+		storageTestServiceName,
+		storageTestEnvName,
+	)
+	existing.Labels = map[string]string{labels.Site: storageTestSiteName}
+	handler, c := newObjectStorageHandler(site, existing)
+
+	if _, err := handler.EnsureDatabasesAreCreated(site, context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buckets := listObjectStorageBuckets(t, c); len(buckets) != 0 {
+		t.Errorf("expected the bucket to be deleted, got %d", len(buckets))
+	}
+}
+
+// TestObjectStorageEnsureDatabasesAreReady verifies that the buckets are only ready once every task is complete, and
+// that a failed task fails the site
+func TestObjectStorageEnsureDatabasesAreReady(t *testing.T) {
+	site := newObjectStorageSite(storageTestEnvName)
+	bucket := testutil.NewTestObjectStorageBucket(
+		"bucket",
+		storageTestNamespace,
+		storageTestSiteName,
+		storageTestServiceName,
+		storageTestEnvName,
+	)
+	bucket.Labels = map[string]string{labels.Site: storageTestSiteName}
+	bucket.Status.State = taskv1.Pending
+	handler, c := newObjectStorageHandler(site, bucket)
+
+	ready, err := handler.EnsureDatabasesAreReady(site, context.Background())
+	if err != nil || ready {
+		t.Errorf("EnsureDatabasesAreReady() = %v, %v; want false, nil", ready, err)
+	}
+
+	bucket.Status.State = taskv1.Complete
+	if err := c.Status().Update(context.Background(), bucket); err != nil {
+		t.Fatalf("failed to update the bucket: %v", err)
+	}
+	ready, err = handler.EnsureDatabasesAreReady(site, context.Background())
+	if err != nil || !ready {
+		t.Errorf("EnsureDatabasesAreReady() = %v, %v; want true, nil", ready, err)
+	}
+
+	bucket.Status.State = taskv1.Failed
+	if err := c.Status().Update(context.Background(), bucket); err != nil {
+		t.Fatalf("failed to update the bucket: %v", err)
+	}
+	if _, err = handler.EnsureDatabasesAreReady(site, context.Background()); err == nil {
+		t.Error("expected an error for a failed bucket")
+	}
+}
@@ -0,0 +1,161 @@
+package task
+
+import (
+	"context"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ProvisionerTaskHandler creates a provisioner task for every provisioner config listed in the service configs of a
+// site
+type ProvisionerTaskHandler struct {
+	Reader client.Reader
+	Writer client.Writer
+	Scheme *runtime.Scheme
+}
+
+func (r ProvisionerTaskHandler) EnsureDatabasesAreCreated(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving provisioner task list")
+
+	var list taskv1.ProvisionerTaskList
+	err := r.Reader.List(ctx, &list, client.InNamespace(site.Namespace), client.MatchingLabels{labels.Site: site.Name})
+	if err != nil {
+		return false, err
+	}
+
+	logger.V(1).Info("Retrieved list.", "count", len(list.Items))
+	logger.V(1).Info("Getting changes required to reconcile the provisioner tasks")
+
+	tasksToDelete := make(map[string]taskv1.ProvisionerTask)
+	tasksToUpdate := make(map[string]taskv1.ProvisionerTask)
+	tasksToCreate := make(map[string]taskv1.ProvisionerTask)
+
+	for name := range site.Spec.Services {
+		var serviceConfig configv1.ServiceConfig
+		err = r.Reader.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: name}, &serviceConfig)
+		if err != nil {
+			return false, err
+		}
+		for _, provisionerName := range serviceConfig.Spec.Provisioners {
+			tasksToCreate[makeProvisionerTaskKey(name, provisionerName)], err = r.getPopulatedTask(
+				site,
+				&serviceConfig,
+				provisionerName,
+			)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	for _, task := range list.Items {
+		if !task.DeletionTimestamp.IsZero() {
+			// The task is already being deprovisioned
+			continue
+		}
+		key := makeProvisionerTaskKey(task.Spec.ServiceName, task.Spec.Provisioner)
+
+		if expectedTask, ok := tasksToCreate[key]; ok {
+			if !expectedTask.Matches(task) {
+				task.UpdateFromExpected(expectedTask)
+				tasksToUpdate[key] = task
+			}
+			delete(tasksToCreate, key)
+		} else {
+			tasksToDelete[key] = task
+		}
+	}
+
+	isComplete := len(tasksToDelete) == 0 && len(tasksToCreate) == 0 && len(tasksToUpdate) == 0
+
+	for key, task := range tasksToDelete {
+		logger.V(1).Info("Deleting provisioner task " + key)
+		if err = r.Writer.Delete(ctx, &task); err != nil {
+			return isComplete, err
+		}
+	}
+	for key, task := range tasksToCreate {
+		logger.V(1).Info("Creating provisioner task " + key)
+		if err = r.Writer.Create(ctx, &task); err != nil {
+			return isComplete, err
+		}
+	}
+	for key, task := range tasksToUpdate {
+		logger.V(1).Info("Updating provisioner task " + key)
+		if err = r.Writer.Update(ctx, &task); err != nil {
+			return isComplete, err
+		}
+	}
+
+	logger.V(0).Info("Provisioner tasks created/updated")
+
+	return isComplete, nil
+}
+
+func (r ProvisionerTaskHandler) EnsureDatabasesAreReady(site *sitev1.StagingSite, ctx context.Context) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	logger.V(0).Info("Retrieving provisioner task list")
+
+	var list taskv1.ProvisionerTaskList
+	err := r.Reader.List(ctx, &list, client.InNamespace(site.Namespace), client.MatchingLabels{labels.Site: site.Name})
+	if err != nil {
+		return false, err
+	}
+	logger.V(1).Info("Retrieved list.", "count", len(list.Items))
+
+	isEverythingReady := true
+
+	for _, task := range list.Items {
+		if !task.DeletionTimestamp.IsZero() {
+			// Tasks being deprovisioned are not used by the site anymore
+			continue
+		}
+		if task.Status.State == taskv1.Failed {
+			return false, errors.ProvisioningError{
+				SiteName:    task.Spec.SiteName,
+				ServiceName: task.Spec.ServiceName,
+				Provisioner: task.Spec.Provisioner,
+			}
+		}
+		isEverythingReady = isEverythingReady && task.Status.State == taskv1.Complete
+	}
+
+	if isEverythingReady {
+		logger.V(1).Info("All provisioner tasks are complete")
+	} else {
+		logger.V(0).Info("Not all provisioner tasks are complete yet")
+	}
+
+	return isEverythingReady, nil
+}
+
+func (r ProvisionerTaskHandler) getPopulatedTask(
+	site *sitev1.StagingSite,
+	config *configv1.ServiceConfig,
+	provisionerName string,
+) (taskv1.ProvisionerTask, error) {
+	task := taskv1.ProvisionerTask{}
+	if err := task.PopulateFomSite(site, config, provisionerName); err != nil {
+		return task, err
+	}
+
+	if err := ctrl.SetControllerReference(site, &task, r.Scheme); err != nil {
+		return task, err
+	}
+
+	return task, nil
+}
+
+func makeProvisionerTaskKey(serviceName string, provisionerName string) string {
+	return serviceName + "/" + provisionerName
+}
@@ -0,0 +1,162 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/helpers/errors"
+	"github.com/szeber/kube-stager/helpers/labels"
+	"github.com/szeber/kube-stager/internal/testutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	provisionerTestNamespace   = "provisioner-ns"
+	provisionerTestSiteName    = "provisioner-site"
+	provisionerTestServiceName = "provisioner-service"
+	provisionerTestShortName   = "psvc"
+	provisionerTestName        = "bucket"
+)
+
+func newProvisionerHandler(objs ...client.Object) (ProvisionerTaskHandler, client.Client) {
+	c := testutil.NewFakeClient(objs...)
+	return ProvisionerTaskHandler{
+		Reader: c,
+		Writer: c,
+		Scheme: testutil.NewTestScheme(),
+	}, c
+}
+
+func newProvisionerSite() *sitev1.StagingSite {
+	return testutil.NewTestStagingSite(provisionerTestSiteName, provisionerTestNamespace, map[string]sitev1.StagingSiteService{
+		provisionerTestServiceName: {ImageTag: "latest", Replicas: 1},
+	})
+}
+
+func listProvisionerTasks(t *testing.T, c client.Client) []taskv1.ProvisionerTask {
+	t.Helper()
+	var list taskv1.ProvisionerTaskList
+	if err := c.List(
+		context.Background(),
+		&list,
+		client.InNamespace(provisionerTestNamespace),
+		client.MatchingLabels{labels.Site: provisionerTestSiteName},
+	); err != nil {
+		t.Fatalf("failed to list provisioner tasks: %v", err)
+	}
+	return list.Items
+}
+
+// TestProvisionerEnsureDatabasesAreCreated_CreatesTask verifies that a task is created for every provisioner listed in
+// the service config
+func TestProvisionerEnsureDatabasesAreCreated_CreatesTask(t *testing.T) {
+	site := newProvisionerSite()
+	sc := testutil.NewTestServiceConfig(provisionerTestServiceName, provisionerTestNamespace, provisionerTestShortName)
+	sc.Spec.Provisioners = []string{provisionerTestName}
+
+	handler, c := newProvisionerHandler(site, sc)
+
+	done, err := handler.EnsureDatabasesAreCreated(site, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if done {
+		t.Error("expected done=false because creation was just performed")
+	}
+
+	tasks := listProvisionerTasks(t, c)
+	if len(tasks) != 1 {
+		t.Fatalf("expected 1 ProvisionerTask, got %d", len(tasks))
+	}
+	task := tasks[0]
+	if task.Spec.ServiceName != provisionerTestServiceName {
+		t.Errorf("ServiceName = %q, want %q", task.Spec.ServiceName, provisionerTestServiceName)
+	}
+	if task.Spec.Provisioner != provisionerTestName {
+		t.Errorf("Provisioner = %q, want %q", task.Spec.Provisioner, provisionerTestName)
+	}
+	if task.Labels[labels.Provisioner] != provisionerTestName {
+		t.Errorf("provisioner label = %q, want %q", task.Labels[labels.Provisioner], provisionerTestName)
+	}
+
+	done, err = handler.EnsureDatabasesAreCreated(site, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error on second call: %v", err)
+	}
+	if !done {
+		t.Error("expected done=true when the task already exists")
+	}
+}
+
+// TestProvisionerEnsureDatabasesAreCreated_DeletesRemovedProvisioner verifies that tasks of provisioners no longer
+// listed in the service config are deleted
+func TestProvisionerEnsureDatabasesAreCreated_DeletesRemovedProvisioner(t *testing.T) {
+	site := newProvisionerSite()
+	sc := testutil.NewTestServiceConfig(provisionerTestServiceName, provisionerTestNamespace, provisionerTestShortName)
+	task := testutil.NewTestProvisionerTask(
+		"old-task",
+		provisionerTestNamespace,
+		provisionerTestSiteName,
+		provisionerTestServiceName,
+		"old",
+	)
+	task.Labels = map[string]string{labels.Site: provisionerTestSiteName}
+
+	handler, c := newProvisionerHandler(site, sc, task)
+
+	done, err := handler.EnsureDatabasesAreCreated(site, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if done {
+		t.Error("expected done=false because a deletion was just performed")
+	}
+	if tasks := listProvisionerTasks(t, c); len(tasks) != 0 {
+		t.Errorf("expected the task to be deleted, got %d tasks", len(tasks))
+	}
+}
+
+// TestProvisionerEnsureDatabasesAreReady verifies the readiness of the site depending on the state of the tasks
+func TestProvisionerEnsureDatabasesAreReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     taskv1.TaskState
+		wantReady bool
+		wantErr   bool
+	}{
+		{name: "running", state: taskv1.Running, wantReady: false},
+		{name: "complete", state: taskv1.Complete, wantReady: true},
+		{name: "failed", state: taskv1.Failed, wantReady: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newProvisionerSite()
+			task := testutil.NewTestProvisionerTask(
+				"task",
+				provisionerTestNamespace,
+				provisionerTestSiteName,
+				provisionerTestServiceName,
+				provisionerTestName,
+			)
+			task.Labels = map[string]string{labels.Site: provisionerTestSiteName}
+			task.Status.State = tt.state
+
+			handler, _ := newProvisionerHandler(site, task)
+
+			ready, err := handler.EnsureDatabasesAreReady(site, context.Background())
+			if tt.wantErr {
+				if _, ok := err.(errors.ProvisioningError); !ok {
+					t.Errorf("expected a ProvisioningError, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ready != tt.wantReady {
+				t.Errorf("ready = %v, want %v", ready, tt.wantReady)
+			}
+		})
+	}
+}
@@ -6,19 +6,31 @@ import (
 	api "github.com/szeber/kube-stager/apis"
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/handlers/provider"
+	"github.com/szeber/kube-stager/helpers/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
 )
 
 type DatabaseHandler interface {
 	SetMysql(config map[string]configv1.MysqlConfig)
 	SetMongo(config map[string]configv1.MongoConfig)
 	SetRedis(config map[string]configv1.RedisConfig)
+	SetObjectStorage(config map[string]configv1.ObjectStorageConfig)
 	GetMysql() map[string]configv1.MysqlConfig
 	GetMongo() map[string]configv1.MongoConfig
 	GetRedis() map[string]configv1.RedisConfig
+	GetObjectStorage() map[string]configv1.ObjectStorageConfig
+	SetProviderConfigs(providerName string, configs map[string]client.Object)
+	SetProvisionerTasks(tasks []taskv1.ProvisionerTask)
+	SetObjectStorageBuckets(buckets []taskv1.ObjectStorageBucket)
 	SetServiceConfigs(configs map[string]configv1.ServiceConfig)
 	SetServiceConfig(name string, config configv1.ServiceConfig)
+	SetBaselineSite(site *sitev1.StagingSite)
 	getNamespace() string
+	getSiteName() string
+	getBaselineSiteName() string
 }
 
 type SiteTemplateHandler struct {
@@ -30,6 +42,11 @@ type SiteTemplateHandler struct {
 	mysqlConfigs         map[string]configv1.MysqlConfig
 	mongoConfigs         map[string]configv1.MongoConfig
 	redisConfigs         map[string]configv1.RedisConfig
+	objectStorageConfigs map[string]configv1.ObjectStorageConfig
+	providerConfigs      map[string]map[string]client.Object
+	provisionerTasks     []taskv1.ProvisionerTask
+	objectStorageBuckets []taskv1.ObjectStorageBucket
+	baselineSite         *sitev1.StagingSite
 }
 
 func NewSite(site sitev1.StagingSite, serviceConfig configv1.ServiceConfig) SiteTemplateHandler {
@@ -62,6 +79,49 @@ func LoadConfigs(handler DatabaseHandler, ctx context.Context, reader client.Rea
 	}
 	handler.SetRedis(redisConfigs)
 
+	objectStorageConfigs, err := ListObjectStorageConfigsInNamespace(namespace, ctx, reader)
+	if err != nil {
+		return err
+	}
+	handler.SetObjectStorage(objectStorageConfigs)
+
+	for _, databaseProvider := range provider.All() {
+		if provider.IsBuiltIn(databaseProvider.Name()) {
+			continue
+		}
+		configs, err := provider.ListEnvironments(ctx, reader, databaseProvider, namespace)
+		if err != nil {
+			return err
+		}
+		handler.SetProviderConfigs(databaseProvider.Name(), configs)
+	}
+
+	var provisionerTasks taskv1.ProvisionerTaskList
+	if err := reader.List(
+		ctx,
+		&provisionerTasks,
+		client.InNamespace(namespace),
+		client.MatchingLabels{labels.Site: handler.getSiteName()},
+	); err != nil {
+		return err
+	}
+	handler.SetProvisionerTasks(provisionerTasks.Items)
+
+	var objectStorageBuckets taskv1.ObjectStorageBucketList
+	if err := reader.List(
+		ctx,
+		&objectStorageBuckets,
+		client.InNamespace(namespace),
+		client.MatchingLabels{labels.Site: handler.getSiteName()},
+	); err != nil {
+		return err
+	}
+	handler.SetObjectStorageBuckets(objectStorageBuckets.Items)
+
+	if err := LoadBaselineSite(handler, ctx, reader); err != nil {
+		return err
+	}
+
 	return LoadServiceConfigs(handler, ctx, reader)
 }
 
@@ -109,6 +169,25 @@ func ListRedisConfigsInNamespace(namespace string, ctx context.Context, reader c
 	return configs, nil
 }
 
+func ListObjectStorageConfigsInNamespace(
+	namespace string,
+	ctx context.Context,
+	reader client.Reader,
+) (map[string]configv1.ObjectStorageConfig, error) {
+	list := configv1.ObjectStorageConfigList{}
+	configs := make(map[string]configv1.ObjectStorageConfig)
+
+	if err := reader.List(ctx, &list, &client.ListOptions{Namespace: namespace}); err != nil {
+		return configs, err
+	}
+
+	for _, config := range list.Items {
+		configs[config.Name] = config
+	}
+
+	return configs, nil
+}
+
 func LoadServiceConfigs(handler DatabaseHandler, ctx context.Context, reader client.Reader) error {
 	namespace := handler.getNamespace()
 
@@ -125,6 +204,23 @@ func LoadServiceConfigs(handler DatabaseHandler, ctx context.Context, reader cli
 	return nil
 }
 
+// LoadBaselineSite loads the baseline site of the site. A missing baseline site is ignored, and the values of the
+// services not included in the site point to the site itself in that case
+func LoadBaselineSite(handler DatabaseHandler, ctx context.Context, reader client.Reader) error {
+	name := handler.getBaselineSiteName()
+	if name == "" || name == handler.getSiteName() {
+		return nil
+	}
+
+	baselineSite := &sitev1.StagingSite{}
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: handler.getNamespace(), Name: name}, baselineSite); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	handler.SetBaselineSite(baselineSite)
+
+	return nil
+}
+
 func (r *SiteTemplateHandler) SetMysql(configs map[string]configv1.MysqlConfig) {
 	r.mysqlConfigs = configs
 }
@@ -137,6 +233,10 @@ func (r *SiteTemplateHandler) SetRedis(configs map[string]configv1.RedisConfig)
 	r.redisConfigs = configs
 }
 
+func (r *SiteTemplateHandler) SetObjectStorage(configs map[string]configv1.ObjectStorageConfig) {
+	r.objectStorageConfigs = configs
+}
+
 func (r *SiteTemplateHandler) GetMysql() map[string]configv1.MysqlConfig {
 	return r.mysqlConfigs
 }
@@ -149,6 +249,28 @@ func (r *SiteTemplateHandler) GetRedis() map[string]configv1.RedisConfig {
 	return r.redisConfigs
 }
 
+func (r *SiteTemplateHandler) GetObjectStorage() map[string]configv1.ObjectStorageConfig {
+	return r.objectStorageConfigs
+}
+
+// SetProviderConfigs sets the environment configs of a custom database provider, keyed by their name
+func (r *SiteTemplateHandler) SetProviderConfigs(providerName string, configs map[string]client.Object) {
+	if len(r.providerConfigs) == 0 {
+		r.providerConfigs = make(map[string]map[string]client.Object)
+	}
+	r.providerConfigs[providerName] = configs
+}
+
+// SetProvisionerTasks sets the provisioner tasks of the site, whose outputs are available as template values
+func (r *SiteTemplateHandler) SetProvisionerTasks(tasks []taskv1.ProvisionerTask) {
+	r.provisionerTasks = tasks
+}
+
+// SetObjectStorageBuckets sets the object storage buckets of the site, whose credentials are available as template values
+func (r *SiteTemplateHandler) SetObjectStorageBuckets(buckets []taskv1.ObjectStorageBucket) {
+	r.objectStorageBuckets = buckets
+}
+
 func (r *SiteTemplateHandler) SetServiceConfigs(configs map[string]configv1.ServiceConfig) {
 	r.serviceConfigs = configs
 }
@@ -160,10 +282,36 @@ func (r *SiteTemplateHandler) SetServiceConfig(name string, config configv1.Serv
 	r.serviceConfigs[name] = config
 }
 
+// SetBaselineSite sets the site whose services are used for the services not included in the site
+func (r *SiteTemplateHandler) SetBaselineSite(site *sitev1.StagingSite) {
+	r.baselineSite = site
+}
+
 func (r *SiteTemplateHandler) getNamespace() string {
 	return r.site.Namespace
 }
 
+func (r *SiteTemplateHandler) getSiteName() string {
+	return r.site.Name
+}
+
+func (r *SiteTemplateHandler) getBaselineSiteName() string {
+	return r.site.Spec.BaselineSite
+}
+
+// getServiceSite returns the site serving the service: the baseline site if the service is only included there, and
+// the site itself otherwise
+func (r *SiteTemplateHandler) getServiceSite(serviceName string) *sitev1.StagingSite {
+	if _, ok := r.site.Spec.Services[serviceName]; ok || r.baselineSite == nil {
+		return &r.site
+	}
+	if _, ok := r.baselineSite.Spec.Services[serviceName]; ok {
+		return r.baselineSite
+	}
+
+	return &r.site
+}
+
 func (r *SiteTemplateHandler) GetTemplateValues() map[string]string {
 	result := map[string]string{
 		"site.name":         r.site.Name,
@@ -171,7 +319,7 @@ func (r *SiteTemplateHandler) GetTemplateValues() map[string]string {
 		"site.imageTag":     r.siteServiceSpec.ImageTag,
 	}
 
-	for k, v := range r.getCommonDatabaseConfigTemplateValues(r.siteServiceStatus, r.siteServiceSpec) {
+	for k, v := range r.getCommonDatabaseConfigTemplateValues(&r.site, r.siteServiceStatus, r.siteServiceSpec) {
 		result[k] = v
 	}
 
@@ -179,6 +327,10 @@ func (r *SiteTemplateHandler) GetTemplateValues() map[string]string {
 		result[k] = v
 	}
 
+	for k, v := range r.getMysqlUserTemplateValues(&r.site, r.currentServiceConfig, r.siteServiceSpec.MysqlEnvironment) {
+		result[k] = v
+	}
+
 	for k, v := range r.getMongoConfigTemplateValues(r.mongoConfigs, r.siteServiceSpec.MongoEnvironment, r.currentServiceConfig.Spec.DefaultMongoEnvironment) {
 		result[k] = v
 	}
@@ -187,6 +339,18 @@ func (r *SiteTemplateHandler) GetTemplateValues() map[string]string {
 		result[k] = v
 	}
 
+	for k, v := range r.getObjectStorageTemplateValues(r.currentServiceConfig, r.siteServiceSpec.ObjectStorageEnvironment) {
+		result[k] = v
+	}
+
+	for k, v := range r.getProviderTemplateValues(r.siteServiceSpec) {
+		result[k] = v
+	}
+
+	for k, v := range r.getProvisionerTemplateValues(r.currentServiceConfig.Name) {
+		result[k] = v
+	}
+
 	for name := range r.currentServiceConfig.Spec.ConfigMaps {
 		result["site.configmap."+name] = api.MakeConfigmapName(&r.site, &r.currentServiceConfig, name)
 	}
@@ -200,21 +364,71 @@ func (r *SiteTemplateHandler) GetTemplateValues() map[string]string {
 	}
 
 	for name, config := range r.serviceConfigs {
-		result["service."+name+".clusterUrl"] = api.MakeServiceUrl(&r.site, config.Spec.ShortName)
+		// The services not included in the site are served by the baseline site, if it has them
+		serviceSite := r.getServiceSite(name)
+		serviceSpec := serviceSite.Spec.Services[name]
+
+		result["service."+name+".clusterUrl"] = api.MakeServiceUrl(serviceSite, config.Spec.ShortName)
+		result["service."+name+".externalUrl"] = serviceSite.Status.Services[name].ExternalUrl
 
-		for k, v := range r.getCommonDatabaseConfigTemplateValues(r.site.Status.Services[name], r.site.Spec.Services[name]) {
+		for k, v := range r.getCommonDatabaseConfigTemplateValues(serviceSite, serviceSite.Status.Services[name], serviceSpec) {
 			result[fmt.Sprintf("service.%s.%s", name, k)] = v
 		}
 
-		for k, v := range r.getMysqlConfigTemplateValues(r.mysqlConfigs, r.site.Spec.Services[name].MysqlEnvironment, config.Spec.DefaultMysqlEnvironment) {
+		for k, v := range r.getMysqlConfigTemplateValues(r.mysqlConfigs, serviceSpec.MysqlEnvironment, config.Spec.DefaultMysqlEnvironment) {
+			result[fmt.Sprintf("service.%s.%s", name, k)] = v
+		}
+		for k, v := range r.getMysqlUserTemplateValues(serviceSite, config, serviceSpec.MysqlEnvironment) {
 			result[fmt.Sprintf("service.%s.%s", name, k)] = v
 		}
-		for k, v := range r.getMongoConfigTemplateValues(r.mongoConfigs, r.site.Spec.Services[name].MongoEnvironment, config.Spec.DefaultMongoEnvironment) {
+		for k, v := range r.getMongoConfigTemplateValues(r.mongoConfigs, serviceSpec.MongoEnvironment, config.Spec.DefaultMongoEnvironment) {
 			result[fmt.Sprintf("service.%s.%s", name, k)] = v
 		}
-		for k, v := range r.getRedisConfigTemplateValues(r.redisConfigs, r.site.Spec.Services[name].RedisEnvironment, config.Spec.DefaultRedisEnvironment) {
+		for k, v := range r.getRedisConfigTemplateValues(r.redisConfigs, serviceSpec.RedisEnvironment, config.Spec.DefaultRedisEnvironment) {
 			result[fmt.Sprintf("service.%s.%s", name, k)] = v
 		}
+		for k, v := range r.getProviderTemplateValues(serviceSpec) {
+			result[fmt.Sprintf("service.%s.%s", name, k)] = v
+		}
+		if serviceSite != &r.site {
+			// The buckets and provisioner tasks are only loaded for the site itself
+			continue
+		}
+		for k, v := range r.getObjectStorageTemplateValues(config, serviceSpec.ObjectStorageEnvironment) {
+			result[fmt.Sprintf("service.%s.%s", name, k)] = v
+		}
+		for k, v := range r.getProvisionerTemplateValues(name) {
+			result[fmt.Sprintf("service.%s.%s", name, k)] = v
+		}
+	}
+
+	return result
+}
+
+// GetDatabaseMoveTemplateValues returns the connection details of the environments a database is moved between, as
+// move.source.* and move.target.* values (eg. move.source.host for mysql, move.target.host1 for mongo)
+func (r *SiteTemplateHandler) GetDatabaseMoveTemplateValues(
+	databaseType string,
+	sourceEnvironment string,
+	targetEnvironment string,
+) map[string]string {
+	result := map[string]string{
+		"move.databaseType":       databaseType,
+		"move.source.environment": sourceEnvironment,
+		"move.target.environment": targetEnvironment,
+	}
+
+	for prefix, environment := range map[string]string{"move.source.": sourceEnvironment, "move.target.": targetEnvironment} {
+		var values map[string]string
+		switch databaseType {
+		case "mysql":
+			values = r.getMysqlConfigTemplateValues(r.mysqlConfigs, environment, "")
+		case "mongo":
+			values = r.getMongoConfigTemplateValues(r.mongoConfigs, environment, "")
+		}
+		for k, v := range values {
+			result[prefix+strings.TrimPrefix(k, "database."+databaseType+".")] = v
+		}
 	}
 
 	return result
@@ -239,8 +453,26 @@ func (r *SiteTemplateHandler) getMysqlConfigTemplateValues(
 	}
 
 	mysqlConfig := mysqlConfigs[configName]
-	result["database.mysql.host"] = mysqlConfig.Spec.Host
-	result["database.mysql.port"] = fmt.Sprintf("%d", mysqlConfig.Spec.Port)
+
+	return prefixTemplateValues("database.mysql.", provider.MysqlProvider{}.GetTemplateValues(&mysqlConfig))
+}
+
+// getMysqlUserTemplateValues returns the credentials of the additional mysql users of the service
+func (r *SiteTemplateHandler) getMysqlUserTemplateValues(
+	site *sitev1.StagingSite,
+	serviceConfig configv1.ServiceConfig,
+	siteEnvironmentName string,
+) map[string]string {
+	result := make(map[string]string)
+
+	if siteEnvironmentName == "" && serviceConfig.Spec.DefaultMysqlEnvironment == "" {
+		return result
+	}
+
+	for name := range serviceConfig.Spec.MysqlAdditionalUsers {
+		result["database.mysql.users."+name+".username"] = api.MakeMysqlAdditionalUsername(site, &serviceConfig, name)
+		result["database.mysql.users."+name+".password"] = api.MakeMysqlAdditionalUserPassword(site, &serviceConfig, name)
+	}
 
 	return result
 }
@@ -264,12 +496,8 @@ func (r *SiteTemplateHandler) getMongoConfigTemplateValues(
 	}
 
 	mongoConfig := mongoConfigs[configName]
-	result["database.mongo.host1"] = mongoConfig.Spec.Host1
-	result["database.mongo.host2"] = mongoConfig.Spec.Host2
-	result["database.mongo.host3"] = mongoConfig.Spec.Host3
-	result["database.mongo.port"] = fmt.Sprintf("%d", mongoConfig.Spec.Port)
 
-	return result
+	return prefixTemplateValues("database.mongo.", provider.MongoProvider{}.GetTemplateValues(&mongoConfig))
 }
 
 func (r *SiteTemplateHandler) getRedisConfigTemplateValues(
@@ -291,26 +519,110 @@ func (r *SiteTemplateHandler) getRedisConfigTemplateValues(
 	}
 
 	redisConfig := redisConfigs[configName]
-	scheme := "tcp"
-	if redisConfig.Spec.IsTlsEnabled != nil && *redisConfig.Spec.IsTlsEnabled {
-		scheme = "tls"
-	}
-	result["database.redis.scheme"] = scheme
-	result["database.redis.host"] = redisConfig.Spec.Host
-	result["database.redis.port"] = fmt.Sprintf("%d", redisConfig.Spec.Port)
-	result["database.redis.password"] = redisConfig.Spec.Password
 
-	return result
+	return prefixTemplateValues("database.redis.", provider.RedisProvider{}.GetTemplateValues(&redisConfig))
 }
 
-func (r *SiteTemplateHandler) getCommonDatabaseConfigTemplateValues(serviceStatus sitev1.StagingSiteServiceStatus, serviceSpec sitev1.StagingSiteService) map[string]string {
+func (r *SiteTemplateHandler) getCommonDatabaseConfigTemplateValues(
+	site *sitev1.StagingSite,
+	serviceStatus sitev1.StagingSiteServiceStatus,
+	serviceSpec sitev1.StagingSiteService,
+) map[string]string {
 	result := map[string]string{
 		"database.username":       serviceStatus.Username,
 		"database.name":           serviceStatus.DbName,
-		"database.password":       r.site.Spec.Password,
+		"database.password":       site.Spec.Password,
 		"database.redis.database": fmt.Sprintf("%d", serviceStatus.RedisDatabaseNumber),
 		"database.initSource":     serviceSpec.DbInitSourceEnvironmentName,
 	}
 
 	return result
 }
+
+// getObjectStorageTemplateValues returns the bucket of the service and the endpoint and credentials to access it, as
+// storage.* values. The credentials are empty until the bucket is reconciled
+func (r *SiteTemplateHandler) getObjectStorageTemplateValues(
+	serviceConfig configv1.ServiceConfig,
+	siteEnvironmentName string,
+) map[string]string {
+	result := make(map[string]string)
+
+	configName := siteEnvironmentName
+	if configName == "" {
+		configName = serviceConfig.Spec.DefaultObjectStorageEnvironment
+	}
+	if configName == "" {
+		return result
+	}
+
+	storageConfig := r.objectStorageConfigs[configName]
+	expectedBucket := taskv1.ObjectStorageBucket{}
+	if err := expectedBucket.PopulateFomSite(&r.site, &serviceConfig, &storageConfig); err != nil {
+		return result
+	}
+
+	result["storage.bucket"] = expectedBucket.Spec.BucketName
+	result["storage.prefix"] = expectedBucket.Spec.Prefix
+	result["storage.endpoint"] = storageConfig.Spec.GetEndpointUrl()
+	result["storage.region"] = storageConfig.Spec.Region
+	result["storage.pathStyle"] = fmt.Sprintf("%t", storageConfig.Spec.UsePathStyle)
+	result["storage.accessKey"] = ""
+	result["storage.secretKey"] = ""
+
+	for _, bucket := range r.objectStorageBuckets {
+		if bucket.Spec.EnvironmentConfig.ServiceName != serviceConfig.Name || !bucket.DeletionTimestamp.IsZero() {
+			continue
+		}
+		result["storage.accessKey"] = bucket.Status.AccessKey
+		result["storage.secretKey"] = bucket.Status.SecretKey
+	}
+
+	return result
+}
+
+// getProviderTemplateValues returns the values of the environments used from the custom database providers, as
+// database.<provider>.* values
+func (r *SiteTemplateHandler) getProviderTemplateValues(serviceSpec sitev1.StagingSiteService) map[string]string {
+	result := make(map[string]string)
+
+	for providerName, environment := range serviceSpec.Databases {
+		databaseProvider, ok := provider.Get(providerName)
+		if !ok {
+			continue
+		}
+		config, ok := r.providerConfigs[providerName][environment]
+		if !ok {
+			continue
+		}
+		for k, v := range prefixTemplateValues("database."+providerName+".", databaseProvider.GetTemplateValues(config)) {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// getProvisionerTemplateValues returns the outputs of the provisioner tasks of a service, as provisioner.<name>.* values
+func (r *SiteTemplateHandler) getProvisionerTemplateValues(serviceName string) map[string]string {
+	result := make(map[string]string)
+
+	for _, task := range r.provisionerTasks {
+		if task.Spec.ServiceName != serviceName || !task.DeletionTimestamp.IsZero() {
+			continue
+		}
+		for k, v := range task.GetTemplateValues() {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+func prefixTemplateValues(prefix string, values map[string]string) map[string]string {
+	result := make(map[string]string, len(values))
+	for k, v := range values {
+		result[prefix+k] = v
+	}
+
+	return result
+}
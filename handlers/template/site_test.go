@@ -2,10 +2,14 @@ package template
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"testing"
 
 	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/provider"
+	"github.com/szeber/kube-stager/helpers/labels"
 	"github.com/szeber/kube-stager/internal/testutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -184,6 +188,9 @@ func TestGetTemplateValues(t *testing.T) {
 			CustomTemplateValues: map[string]string{
 				"custom1": "val1",
 			},
+			MysqlAdditionalUsers: map[string]configv1.MysqlPrivilegeProfile{
+				"readonly": {Privileges: []configv1.MysqlPrivilege{"SELECT"}},
+			},
 		},
 	}
 	handler := NewSite(site, config)
@@ -220,4 +227,227 @@ func TestGetTemplateValues(t *testing.T) {
 	if _, ok := values["site.configmap.env"]; !ok {
 		t.Error("missing site.configmap.env key")
 	}
+
+	if values["database.mysql.users.readonly.username"] == "" {
+		t.Error("missing database.mysql.users.readonly.username key")
+	}
+	if values["database.mysql.users.readonly.password"] == "" {
+		t.Error("missing database.mysql.users.readonly.password key")
+	}
+}
+
+func TestGetDatabaseMoveTemplateValues(t *testing.T) {
+	source := configv1.MysqlConfig{Spec: configv1.MysqlConfigSpec{Host: "old.example.com", Port: 3306}}
+	target := configv1.MysqlConfig{Spec: configv1.MysqlConfigSpec{Host: "new.example.com", Port: 3307}}
+
+	handler := NewSite(sitev1.StagingSite{}, configv1.ServiceConfig{})
+	handler.SetMysql(map[string]configv1.MysqlConfig{"old": source, "new": target})
+
+	values := handler.GetDatabaseMoveTemplateValues("mysql", "old", "new")
+
+	expected := map[string]string{
+		"move.databaseType":       "mysql",
+		"move.source.environment": "old",
+		"move.source.host":        "old.example.com",
+		"move.source.port":        "3306",
+		"move.target.environment": "new",
+		"move.target.host":        "new.example.com",
+		"move.target.port":        "3307",
+	}
+	for k, want := range expected {
+		if values[k] != want {
+			t.Errorf("values[%q] = %q, want %q", k, values[k], want)
+		}
+	}
+}
+
+// keydbProvider is a custom database provider reusing the CRDs of the redis provider
+type keydbProvider struct {
+	provider.RedisProvider
+}
+
+func (r keydbProvider) Name() string {
+	return "keydb"
+}
+
+var registerKeydbProvider sync.Once
+
+func TestGetTemplateValues_CustomProvider(t *testing.T) {
+	registerKeydbProvider.Do(func() { provider.Register(keydbProvider{}) })
+
+	keydbConfig := testutil.NewTestRedisConfig("keydb1", "test-ns")
+	keydbConfig.Spec.Host = "keydb.example.com"
+	c := testutil.NewFakeClient(keydbConfig, testutil.NewTestServiceConfig("web", "test-ns", "web"))
+
+	site := sitev1.StagingSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysite", Namespace: "test-ns"},
+		Spec: sitev1.StagingSiteSpec{
+			Services: map[string]sitev1.StagingSiteService{
+				"web": {Databases: map[string]string{"keydb": "keydb1", "cassandra": "c1"}},
+			},
+		},
+	}
+	handler := NewSite(site, configv1.ServiceConfig{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test-ns"}})
+	if err := LoadConfigs(&handler, context.Background(), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := handler.GetTemplateValues()
+
+	for _, key := range []string{"database.keydb.host", "service.web.database.keydb.host"} {
+		if values[key] != "keydb.example.com" {
+			t.Errorf("values[%q] = %q, want %q", key, values[key], "keydb.example.com")
+		}
+	}
+	for key := range values {
+		if strings.HasPrefix(key, "database.cassandra.") {
+			t.Errorf("unexpected value %q for an unregistered provider", key)
+		}
+	}
+}
+
+func TestGetTemplateValues_ProvisionerOutputs(t *testing.T) {
+	task := testutil.NewTestProvisionerTask("mysite-web-bucket", "test-ns", "mysite", "web", "bucket")
+	task.Labels = map[string]string{labels.Site: "mysite"}
+	task.Status.Outputs = map[string]string{"name": "mysite-assets"}
+	c := testutil.NewFakeClient(task, testutil.NewTestServiceConfig("web", "test-ns", "web"))
+
+	site := sitev1.StagingSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysite", Namespace: "test-ns"},
+		Spec: sitev1.StagingSiteSpec{
+			Services: map[string]sitev1.StagingSiteService{"web": {}},
+		},
+	}
+	handler := NewSite(site, configv1.ServiceConfig{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "test-ns"}})
+	if err := LoadConfigs(&handler, context.Background(), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := handler.GetTemplateValues()
+
+	for _, key := range []string{"provisioner.bucket.name", "service.web.provisioner.bucket.name"} {
+		if values[key] != "mysite-assets" {
+			t.Errorf("values[%q] = %q, want %q", key, values[key], "mysite-assets")
+		}
+	}
+}
+
+func TestGetTemplateValues_ObjectStorage(t *testing.T) {
+	storageConfig := testutil.NewTestObjectStorageConfig("minio", "test-ns")
+	storageConfig.Spec.UsePathStyle = true
+	serviceConfig := testutil.NewTestServiceConfig("web", "test-ns", "web")
+	serviceConfig.Spec.DefaultObjectStorageEnvironment = "minio"
+	bucket := testutil.NewTestObjectStorageBucket("mysite-web", "test-ns", "mysite", "web", "minio")
+	bucket.Labels = map[string]string{labels.Site: "mysite"}
+	bucket.Status.AccessKey = "access"
+	bucket.Status.SecretKey = "secret"
+	c := testutil.NewFakeClient(storageConfig, serviceConfig, bucket)
+
+	site := sitev1.StagingSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysite", Namespace: "test-ns"},
+		Spec: sitev1.StagingSiteSpec{
+			Services: map[string]sitev1.StagingSiteService{"web": {ObjectStorageEnvironment: "minio"}},
+		},
+	}
+	handler := NewSite(site, *serviceConfig)
+	if err := LoadConfigs(&handler, context.Background(), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := handler.GetTemplateValues()
+
+	expected := map[string]string{
+		"storage.bucket":             "mysite-web",
+		"storage.prefix":             "",
+		"storage.endpoint":           "https://s3.example.com:9000",
+		"storage.region":             "us-east-1",
+		"storage.pathStyle":          "true",
+		"storage.accessKey":          "access",
+		"storage.secretKey":          "secret",
+		"service.web.storage.bucket": "mysite-web",
+	}
+	for key, want := range expected {
+		if got, ok := values[key]; !ok || got != want {
+			t.Errorf("values[%q] = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestGetTemplateValues_BaselineSite(t *testing.T) {
+	baseline := &sitev1.StagingSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "master", Namespace: "test-ns"},
+		Spec: sitev1.StagingSiteSpec{
+			Password: "masterpass",
+			Services: map[string]sitev1.StagingSiteService{
+				"web": {},
+				"api": {MysqlEnvironment: "mysql1"},
+			},
+		},
+		Status: sitev1.StagingSiteStatus{
+			Services: map[string]sitev1.StagingSiteServiceStatus{
+				"api": {Username: "masteruser", DbName: "masterdb", ExternalUrl: "https://master-api.example.com"},
+			},
+		},
+	}
+	webConfig := testutil.NewTestServiceConfig("web", "test-ns", "web")
+	apiConfig := testutil.NewTestServiceConfig("api", "test-ns", "api")
+	searchConfig := testutil.NewTestServiceConfig("search", "test-ns", "search")
+	c := testutil.NewFakeClient(baseline, webConfig, apiConfig, searchConfig)
+
+	site := sitev1.StagingSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "feature", Namespace: "test-ns"},
+		Spec: sitev1.StagingSiteSpec{
+			Password:     "featurepass",
+			BaselineSite: "master",
+			Services:     map[string]sitev1.StagingSiteService{"web": {}},
+		},
+		Status: sitev1.StagingSiteStatus{
+			Services: map[string]sitev1.StagingSiteServiceStatus{
+				"web": {ExternalUrl: "https://feature-web.example.com"},
+			},
+		},
+	}
+	handler := NewSite(site, *webConfig)
+	if err := LoadConfigs(&handler, context.Background(), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := handler.GetTemplateValues()
+
+	checks := map[string]string{
+		"service.web.clusterUrl":        "feature-web.test-ns.svc.cluster.local",
+		"service.api.clusterUrl":        "master-api.test-ns.svc.cluster.local",
+		"service.api.database.username": "masteruser",
+		"service.api.database.name":     "masterdb",
+		"service.api.database.password": "masterpass",
+		"service.search.clusterUrl":     "feature-search.test-ns.svc.cluster.local",
+		"service.web.externalUrl":       "https://feature-web.example.com",
+		"service.api.externalUrl":       "https://master-api.example.com",
+		"service.search.externalUrl":    "",
+	}
+	for key, expected := range checks {
+		if got := values[key]; got != expected {
+			t.Errorf("values[%q] = %q, want %q", key, got, expected)
+		}
+	}
+}
+
+func TestLoadConfigs_MissingBaselineSite(t *testing.T) {
+	svcCfg := testutil.NewTestServiceConfig("web", "test-ns", "web")
+	c := testutil.NewFakeClient(svcCfg)
+
+	site := sitev1.StagingSite{
+		ObjectMeta: metav1.ObjectMeta{Name: "feature", Namespace: "test-ns"},
+		Spec: sitev1.StagingSiteSpec{
+			BaselineSite: "master",
+			Services:     map[string]sitev1.StagingSiteService{"web": {}},
+		},
+	}
+	handler := NewSite(site, *svcCfg)
+	if err := LoadConfigs(&handler, context.Background(), c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if handler.baselineSite != nil {
+		t.Errorf("expected no baseline site to be loaded, got %v", handler.baselineSite)
+	}
 }
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
+	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+// poolMember is an environment that the databases of a site can be placed on
+type poolMember struct {
+	name     string
+	labels   map[string]string
+	maxSites int32
+	// TRUE if the environment is unhealthy, or doesn't accept new databases because of its mode
+	unavailable bool
+	// The number of databases the environment can hold, 0 if it's unlimited
+	capacity int
+}
+
+// environmentUsage tracks the databases and sites placed on the environments of one database type
+type environmentUsage struct {
+	databaseCounts map[string]int
+	siteNames      map[string]map[string]bool
+}
+
+func newEnvironmentUsage() *environmentUsage {
+	return &environmentUsage{
+		databaseCounts: make(map[string]int),
+		siteNames:      make(map[string]map[string]bool),
+	}
+}
+
+func (u *environmentUsage) add(environment string, siteName string) {
+	if environment == "" {
+		return
+	}
+	u.databaseCounts[environment]++
+	if u.siteNames[environment] == nil {
+		u.siteNames[environment] = make(map[string]bool)
+	}
+	u.siteNames[environment][siteName] = true
+}
+
+// hasRoomForSite returns TRUE if the site can be placed on the member without exceeding its site or database limits
+func (u *environmentUsage) hasRoomForSite(member poolMember, siteName string) bool {
+	if member.capacity > 0 && u.databaseCounts[member.name] >= member.capacity {
+		return false
+	}
+	sites := u.siteNames[member.name]
+	if member.maxSites == 0 || sites[siteName] {
+		return true
+	}
+
+	return int32(len(sites)) < member.maxSites
+}
+
+// siteEnvironmentUsages holds the environment usage of all sites in a namespace, for each database type
+type siteEnvironmentUsages struct {
+	mongo *environmentUsage
+	mysql *environmentUsage
+	redis *environmentUsage
+}
+
+// loadSiteEnvironmentUsages counts the databases of the other sites in the namespace. The current site is excluded,
+// as its services are added while they are processed by the webhook.
+func loadSiteEnvironmentUsages(
+	ctx context.Context,
+	reader client.Reader,
+	site *sitev1.StagingSite,
+) (*siteEnvironmentUsages, error) {
+	siteList := &sitev1.StagingSiteList{}
+	if err := reader.List(ctx, siteList, client.InNamespace(site.Namespace)); err != nil {
+		return nil, err
+	}
+
+	usages := &siteEnvironmentUsages{
+		mongo: newEnvironmentUsage(),
+		mysql: newEnvironmentUsage(),
+		redis: newEnvironmentUsage(),
+	}
+	for _, otherSite := range siteList.Items {
+		if otherSite.Name == site.Name {
+			continue
+		}
+		for _, service := range otherSite.Spec.Services {
+			usages.addService(service, otherSite.Name)
+		}
+	}
+
+	return usages, nil
+}
+
+// selectPoolMember returns the name of the least loaded available pool member matching the selector that has room for
+// the site, or an empty string if there is none. Members with a limited capacity are compared by their free
+// databases, others by their database count. Ties are broken by name to keep the placement deterministic.
+func selectPoolMember(
+	selector *metav1.LabelSelector,
+	members []poolMember,
+	usage *environmentUsage,
+	siteName string,
+) (string, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []poolMember
+	for _, member := range members {
+		if !labelSelector.Matches(labels.Set(member.labels)) || member.unavailable {
+			continue
+		}
+		if !usage.hasRoomForSite(member, siteName) {
+			continue
+		}
+		candidates = append(candidates, member)
+	}
+
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		aCount, bCount := usage.databaseCounts[a.name], usage.databaseCounts[b.name]
+		if a.capacity > 0 && b.capacity > 0 {
+			aFree, bFree := a.capacity-aCount, b.capacity-bCount
+			if aFree != bFree {
+				return aFree > bFree
+			}
+		} else if aCount != bCount {
+			return aCount < bCount
+		}
+		return a.name < b.name
+	})
+
+	return candidates[0].name, nil
+}
+
+func makeMysqlPoolMembers(environments map[string]configv1.MysqlConfig) []poolMember {
+	members := make([]poolMember, 0, len(environments))
+	for name, environment := range environments {
+		members = append(members, poolMember{
+			name:        name,
+			labels:      environment.Labels,
+			maxSites:    environment.Spec.MaxSites,
+			unavailable: environment.Status.IsUnhealthy() || !environment.Spec.Mode.AcceptsNewDatabases(),
+		})
+	}
+	return members
+}
+
+func makeMongoPoolMembers(environments map[string]configv1.MongoConfig) []poolMember {
+	members := make([]poolMember, 0, len(environments))
+	for name, environment := range environments {
+		members = append(members, poolMember{
+			name:        name,
+			labels:      environment.Labels,
+			maxSites:    environment.Spec.MaxSites,
+			unavailable: environment.Status.IsUnhealthy() || !environment.Spec.Mode.AcceptsNewDatabases(),
+		})
+	}
+	return members
+}
+
+func makeRedisPoolMembers(environments map[string]configv1.RedisConfig) []poolMember {
+	members := make([]poolMember, 0, len(environments))
+	for name, environment := range environments {
+		members = append(members, poolMember{
+			name:        name,
+			labels:      environment.Labels,
+			maxSites:    environment.Spec.MaxSites,
+			unavailable: environment.Status.IsUnhealthy() || !environment.Spec.Mode.AcceptsNewDatabases(),
+			capacity:    int(environment.Spec.AvailableDatabaseCount),
+		})
+	}
+	return members
+}
+
+// addService records the databases of a service of the site. It's a no-op if the usages were not loaded.
+func (u *siteEnvironmentUsages) addService(service sitev1.StagingSiteService, siteName string) {
+	if u == nil {
+		return
+	}
+	u.mongo.add(service.MongoEnvironment, siteName)
+	u.mysql.add(service.MysqlEnvironment, siteName)
+	u.redis.add(service.RedisEnvironment, siteName)
+}
+
+// needsPoolPlacement returns TRUE if any of the site's services has a database without an environment, that has to
+// be placed on a pool
+func needsPoolPlacement(site *sitev1.StagingSite, serviceConfigs map[string]configv1.ServiceConfig) bool {
+	for name, service := range site.Spec.Services {
+		spec := serviceConfigs[name].Spec
+		if service.MongoEnvironment == "" && spec.DefaultMongoEnvironment == "" && spec.DefaultMongoEnvironmentPool != nil {
+			return true
+		}
+		if service.MysqlEnvironment == "" && spec.DefaultMysqlEnvironment == "" && spec.DefaultMysqlEnvironmentPool != nil {
+			return true
+		}
+		if service.RedisEnvironment == "" && spec.DefaultRedisEnvironment == "" && spec.DefaultRedisEnvironmentPool != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// selectPoolEnvironment places a database of the service on the pool, returning an error if no member of the pool
+// can take it
+func selectPoolEnvironment(
+	databaseType string,
+	serviceName string,
+	selector *metav1.LabelSelector,
+	members []poolMember,
+	usage *environmentUsage,
+	siteName string,
+) (string, error) {
+	environment, err := selectPoolMember(selector, members, usage, siteName)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s environment pool in service '%s': %w", databaseType, serviceName, err)
+	}
+	if environment == "" {
+		return "", fmt.Errorf(
+			"no %s environment in the pool of service '%s' is ready and has room for the site",
+			databaseType,
+			serviceName,
+		)
+	}
+
+	return environment, nil
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func makePoolSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "shared"}}
+}
+
+func TestSelectPoolMember_FewestDatabases(t *testing.T) {
+	members := []poolMember{
+		{name: "db1", labels: map[string]string{"pool": "shared"}},
+		{name: "db2", labels: map[string]string{"pool": "shared"}},
+		{name: "db3", labels: map[string]string{"pool": "other"}},
+	}
+	usage := newEnvironmentUsage()
+	usage.add("db1", "site-a")
+	usage.add("db1", "site-b")
+	usage.add("db2", "site-a")
+
+	got, err := selectPoolMember(makePoolSelector(), members, usage, "new-site")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "db2" {
+		t.Errorf("selectPoolMember() = %q, want db2", got)
+	}
+}
+
+func TestSelectPoolMember_TieBrokenByName(t *testing.T) {
+	members := []poolMember{
+		{name: "db2", labels: map[string]string{"pool": "shared"}},
+		{name: "db1", labels: map[string]string{"pool": "shared"}},
+	}
+
+	got, err := selectPoolMember(makePoolSelector(), members, newEnvironmentUsage(), "new-site")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "db1" {
+		t.Errorf("selectPoolMember() = %q, want db1", got)
+	}
+}
+
+func TestSelectPoolMember_MostFreeRedisDatabases(t *testing.T) {
+	members := []poolMember{
+		{name: "redis1", labels: map[string]string{"pool": "shared"}, capacity: 16},
+		{name: "redis2", labels: map[string]string{"pool": "shared"}, capacity: 4},
+	}
+	usage := newEnvironmentUsage()
+	for i := 0; i < 10; i++ {
+		usage.add("redis1", "site-a")
+	}
+
+	got, err := selectPoolMember(makePoolSelector(), members, usage, "new-site")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "redis1" {
+		t.Errorf("selectPoolMember() = %q, want redis1 (6 free vs 4 free)", got)
+	}
+}
+
+func TestSelectPoolMember_SkipsFullAndUnhealthyMembers(t *testing.T) {
+	members := []poolMember{
+		{name: "full", labels: map[string]string{"pool": "shared"}, maxSites: 1},
+		{name: "unhealthy", labels: map[string]string{"pool": "shared"}, unavailable: true},
+		{name: "noslots", labels: map[string]string{"pool": "shared"}, capacity: 1},
+	}
+	usage := newEnvironmentUsage()
+	usage.add("full", "site-a")
+	usage.add("noslots", "site-a")
+
+	got, err := selectPoolMember(makePoolSelector(), members, usage, "new-site")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "" {
+		t.Errorf("selectPoolMember() = %q, want no member", got)
+	}
+}
+
+func TestSelectPoolMember_MaxSitesAllowsSiteAlreadyPlaced(t *testing.T) {
+	members := []poolMember{
+		{name: "db1", labels: map[string]string{"pool": "shared"}, maxSites: 1},
+	}
+	usage := newEnvironmentUsage()
+	usage.add("db1", "my-site")
+
+	got, err := selectPoolMember(makePoolSelector(), members, usage, "my-site")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "db1" {
+		t.Errorf("selectPoolMember() = %q, want db1", got)
+	}
+}
+
+func TestSelectPoolMember_InvalidSelector(t *testing.T) {
+	selector := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "pool", Operator: "Bogus"}},
+	}
+
+	if _, err := selectPoolMember(selector, nil, newEnvironmentUsage(), "new-site"); err == nil {
+		t.Error("expected an error for an invalid selector")
+	}
+}
@@ -9,12 +9,30 @@ import (
 	"github.com/szeber/kube-stager/helpers"
 	errorshelpers "github.com/szeber/kube-stager/helpers/errors"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"net/http"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+	"strings"
 )
 
+// The cron job names are suffixed to the site name, so they are kept short to leave room for it within the 52
+// character limit of the cron job names
+const maxCronJobNameLength = 20
+
+// The workload names are suffixed to the site name as well
+const maxWorkloadNameLength = 20
+
+// The names of the extra objects must contain the name of the site
+const extraObjectNamePlaceholder = "${site.name}"
+
 type ServiceConfigCreateOrUpdateHandler struct {
 	Client  client.Client
 	Decoder admission.Decoder
@@ -90,6 +108,121 @@ func (r *ServiceConfigCreateOrUpdateHandler) Handle(ctx context.Context, req adm
 		}
 	}
 
+	if config.Spec.DefaultObjectStorageEnvironment != "" {
+		logger.Info("Validating default object storage environment: " + config.Spec.DefaultObjectStorageEnvironment)
+		if _, ok := templateHandler.GetObjectStorage()[config.Spec.DefaultObjectStorageEnvironment]; !ok {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_environment").Inc()
+			return admission.Denied("Invalid object storage environment: " + config.Spec.DefaultObjectStorageEnvironment)
+		}
+	}
+
+	if config.Spec.DbSanitizePodSpec != nil && config.Spec.DbInitPodSpec == nil {
+		appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_sanitize_job").Inc()
+		return admission.Denied("The db sanitize pod spec requires the db init pod spec to be set")
+	}
+
+	hookNames := make(map[string]bool, len(config.Spec.Hooks))
+	for _, hook := range config.Spec.Hooks {
+		if hookNames[hook.Name] {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "duplicate_hook").Inc()
+			return admission.Denied("Duplicate hook name: " + hook.Name)
+		}
+		hookNames[hook.Name] = true
+	}
+
+	for name := range config.Spec.CronJobs {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 || len(name) > maxCronJobNameLength {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_cron_job").Inc()
+			return admission.Denied(fmt.Sprintf(
+				"Invalid cron job name: %s. It must be a lowercase dns label of at most %d characters",
+				name,
+				maxCronJobNameLength,
+			))
+		}
+	}
+
+	for name := range config.Spec.Workloads {
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 || len(name) > maxWorkloadNameLength {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_workload").Inc()
+			return admission.Denied(fmt.Sprintf(
+				"Invalid workload name: %s. It must be a lowercase dns label of at most %d characters",
+				name,
+				maxWorkloadNameLength,
+			))
+		}
+	}
+
+	if config.Spec.Autoscaling != nil {
+		logger.Info("Validating autoscaling")
+		if config.Spec.DeploymentPodSpec == nil {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_autoscaling").Inc()
+			return admission.Denied("Autoscaling requires the deployment pod spec to be set")
+		}
+		if err = validateAutoscalingReplicas(config.Spec.Autoscaling.MinReplicas, config.Spec.Autoscaling.MaxReplicas); err != nil {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_autoscaling").Inc()
+			return admission.Denied(err.Error())
+		}
+	}
+
+	if config.Spec.HeaderRouting != nil {
+		logger.Info("Validating header routing")
+		if err = validateHeaderRouting(*config); err != nil {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_header_routing").Inc()
+			return admission.Denied(err.Error())
+		}
+	}
+
+	logger.Info("Validating extra objects")
+	if err = validateExtraObjects(*config, r.Client.RESTMapper()); err != nil {
+		appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_extra_object").Inc()
+		return admission.Denied(err.Error())
+	}
+
+	logger.Info("Validating helm chart")
+	if err = validateHelmChart(*config); err != nil {
+		appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_helm_chart").Inc()
+		return admission.Denied(err.Error())
+	}
+
+	logger.Info("Validating environment pools")
+	if err = validateEnvironmentPool(
+		"mongo",
+		config.Spec.DefaultMongoEnvironment,
+		config.Spec.DefaultMongoEnvironmentPool,
+	); err != nil {
+		appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_environment_pool").Inc()
+		return admission.Denied(err.Error())
+	}
+	if err = validateEnvironmentPool(
+		"mysql",
+		config.Spec.DefaultMysqlEnvironment,
+		config.Spec.DefaultMysqlEnvironmentPool,
+	); err != nil {
+		appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_environment_pool").Inc()
+		return admission.Denied(err.Error())
+	}
+	if err = validateEnvironmentPool(
+		"redis",
+		config.Spec.DefaultRedisEnvironment,
+		config.Spec.DefaultRedisEnvironmentPool,
+	); err != nil {
+		appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_environment_pool").Inc()
+		return admission.Denied(err.Error())
+	}
+
+	for _, provisionerName := range config.Spec.Provisioners {
+		logger.Info("Validating provisioner: " + provisionerName)
+		provisionerConfig := &configv1.ProvisionerConfig{}
+		err = r.Client.Get(ctx, client.ObjectKey{Namespace: config.Namespace, Name: provisionerName}, provisionerConfig)
+		if apierrors.IsNotFound(err) {
+			appmetrics.WebhookDenied.WithLabelValues("serviceconfig", "invalid_provisioner").Inc()
+			return admission.Denied("Invalid provisioner: " + provisionerName)
+		} else if err != nil {
+			logger.Error(err, "Failed to load the provisioner config")
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+
 	logger.Info("Validating templates")
 	err = r.validateTemplates(*config, &templateHandler)
 	if errorshelpers.IsControllerError(err) {
@@ -102,32 +235,223 @@ func (r *ServiceConfigCreateOrUpdateHandler) Handle(ctx context.Context, req adm
 	return admission.Allowed("")
 }
 
+// validateHelmChart checks that the service has a deployment pod spec or a helm chart, that the chart has exactly one
+// source, and that its values template is a YAML map. The templates of the values are validated with the rest of the
+// templates
+func validateHelmChart(config configv1.ServiceConfig) error {
+	chart := config.Spec.HelmChart
+	if chart == nil {
+		if config.Spec.DeploymentPodSpec == nil {
+			return fmt.Errorf("either the deployment pod spec or the helm chart must be set")
+		}
+		return nil
+	}
+
+	if (chart.OCIRepository == "") == (chart.ConfigMap == nil) {
+		return fmt.Errorf("exactly one of the OCI repository and the configmap of the helm chart must be set")
+	}
+	if chart.OCIRepository != "" {
+		if !strings.HasPrefix(chart.OCIRepository, "oci://") {
+			return fmt.Errorf("the OCI repository of the helm chart must start with oci://")
+		}
+		if chart.Version == "" {
+			return fmt.Errorf("the version of the helm chart is required with an OCI repository")
+		}
+	} else if chart.Version != "" || chart.PullSecret != "" {
+		return fmt.Errorf("the version and the pull secret of the helm chart are only used with an OCI repository")
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(chart.ValuesTemplate), &values); err != nil {
+		return fmt.Errorf("invalid values template of the helm chart: %w", err)
+	}
+
+	return nil
+}
+
+// validateAutoscalingReplicas checks that the minimum replica count of an autoscaler is not above its maximum
+func validateAutoscalingReplicas(minReplicas *int32, maxReplicas int32) error {
+	if minReplicas != nil && *minReplicas > maxReplicas {
+		return fmt.Errorf(
+			"the minimum replica count (%d) of the autoscaling is greater than the maximum (%d)",
+			*minReplicas,
+			maxReplicas,
+		)
+	}
+
+	return nil
+}
+
+// validateHeaderRouting checks that the service of the config has a port to route the requests of, and that the
+// routing header is a valid header name
+func validateHeaderRouting(config configv1.ServiceConfig) error {
+	if config.Spec.ServiceSpec == nil || len(config.Spec.ServiceSpec.Ports) == 0 {
+		return fmt.Errorf("header routing requires a service spec with at least one port")
+	}
+	if config.Spec.HeaderRouting.HeaderName != "" {
+		if errs := validation.IsHTTPHeaderName(config.Spec.HeaderRouting.HeaderName); len(errs) > 0 {
+			return fmt.Errorf(
+				"invalid header name '%s' for the header routing: %s",
+				config.Spec.HeaderRouting.HeaderName,
+				strings.Join(errs, ", "),
+			)
+		}
+	}
+	if config.Spec.HeaderRouting.Port == nil {
+		return nil
+	}
+	for _, port := range config.Spec.ServiceSpec.Ports {
+		if port.Port == *config.Spec.HeaderRouting.Port {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the header routing port %d is not a port of the service spec", *config.Spec.HeaderRouting.Port)
+}
+
+// validateExtraObjects checks that the extra objects are valid manifests of namespaced objects, that their names contain
+// the name of the site so the objects of the sites don't overwrite each other, and that every object is listed only
+// once. The templates of the manifests are validated with the rest of the templates
+func validateExtraObjects(config configv1.ServiceConfig, mapper meta.RESTMapper) error {
+	objectKeys := make(map[string]bool, len(config.Spec.ExtraObjects))
+	for i, rawObject := range config.Spec.ExtraObjects {
+		object := &unstructured.Unstructured{}
+		if err := object.UnmarshalJSON(rawObject.Raw); err != nil {
+			return fmt.Errorf("invalid extra object #%d: %w", i, err)
+		}
+		if object.GetAPIVersion() == "" || object.GetKind() == "" || object.GetName() == "" {
+			return fmt.Errorf("invalid extra object #%d: the apiVersion, kind and metadata.name fields are required", i)
+		}
+		if !strings.Contains(object.GetName(), extraObjectNamePlaceholder) {
+			return fmt.Errorf(
+				"invalid extra object %s %s: the name must contain %s to be unique per site",
+				object.GetKind(),
+				object.GetName(),
+				extraObjectNamePlaceholder,
+			)
+		}
+		mapping, err := mapper.RESTMapping(object.GroupVersionKind().GroupKind(), object.GroupVersionKind().Version)
+		if err != nil {
+			return fmt.Errorf("invalid extra object %s %s: %w", object.GetKind(), object.GetName(), err)
+		}
+		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+			return fmt.Errorf(
+				"invalid extra object %s %s: only namespaced objects are supported",
+				object.GetKind(),
+				object.GetName(),
+			)
+		}
+		if object.GetNamespace() != "" && object.GetNamespace() != config.Namespace {
+			return fmt.Errorf(
+				"invalid extra object %s %s: the objects are created in the namespace of the site",
+				object.GetKind(),
+				object.GetName(),
+			)
+		}
+
+		key := object.GetAPIVersion() + "/" + object.GetKind() + "/" + object.GetName()
+		if objectKeys[key] {
+			return fmt.Errorf("duplicate extra object: %s %s", object.GetKind(), object.GetName())
+		}
+		objectKeys[key] = true
+	}
+
+	return nil
+}
+
+// validateEnvironmentPool checks that the pool selector is valid, and that it's not set together with a default
+// environment, as the pool would never be used then
+func validateEnvironmentPool(databaseType string, defaultEnvironment string, pool *metav1.LabelSelector) error {
+	if pool == nil {
+		return nil
+	}
+	if defaultEnvironment != "" {
+		return fmt.Errorf(
+			"only one of the default %s environment and the default %s environment pool may be set",
+			databaseType,
+			databaseType,
+		)
+	}
+	if _, err := metav1.LabelSelectorAsSelector(pool); err != nil {
+		return fmt.Errorf("invalid default %s environment pool: %w", databaseType, err)
+	}
+
+	return nil
+}
+
 func (r *ServiceConfigCreateOrUpdateHandler) validateTemplates(
 	config configv1.ServiceConfig,
 	templateHandler *template.SiteTemplateHandler,
 ) error {
 	spec := config.Spec
 
+	// The outputs of the provisioners are only known once they ran, so any output of the listed provisioners is accepted
+	provisionerOutputs, err := getDummyProvisionerOutputs(config)
+	if err != nil {
+		return err
+	}
+	templates := []helpers.TemplateValueGetter{templateHandler, provisionerOutputs}
+
 	if spec.DbInitPodSpec != nil {
-		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.DbInitPodSpec, templateHandler); err != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.DbInitPodSpec, templates...); err != nil {
+			return err
+		}
+	}
+	if spec.DbSanitizePodSpec != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.DbSanitizePodSpec, templates...); err != nil {
 			return err
 		}
 	}
 	if spec.MigrationJobPodSpec != nil {
-		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.MigrationJobPodSpec, templateHandler); err != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.MigrationJobPodSpec, templates...); err != nil {
 			return err
 		}
 	}
 	if spec.BackupPodSpec != nil {
-		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.BackupPodSpec, templateHandler); err != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.BackupPodSpec, templates...); err != nil {
 			return err
 		}
 	}
-	if _, err := helpers.ReplaceTemplateVariablesInPodSpec(spec.DeploymentPodSpec, templateHandler); err != nil {
-		return err
+	for _, workload := range spec.Workloads {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(workload.PodSpec, templates...); err != nil {
+			return err
+		}
+	}
+	for _, cronJob := range spec.CronJobs {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(cronJob.PodSpec, templates...); err != nil {
+			return err
+		}
+	}
+	for _, extraObject := range spec.ExtraObjects {
+		if _, err := helpers.ReplaceTemplateVariablesInRawObject(extraObject, templates...); err != nil {
+			return err
+		}
+	}
+	for _, hook := range spec.Hooks {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(hook.PodSpec, templates...); err != nil {
+			return err
+		}
+	}
+	if spec.DeploymentPodSpec != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInPodSpec(*spec.DeploymentPodSpec, templates...); err != nil {
+			return err
+		}
+	}
+	if spec.HelmChart != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInHelmValues(spec.HelmChart.ValuesTemplate, templates...); err != nil {
+			return err
+		}
 	}
 	if spec.ServiceSpec != nil {
-		if _, err := helpers.ReplaceTemplateVariablesInServiceSpec(*spec.ServiceSpec, templateHandler); err != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInServiceSpec(*spec.ServiceSpec, templates...); err != nil {
+			return err
+		}
+	}
+	if spec.Autoscaling != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInHorizontalPodAutoscalerSpec(
+			autoscalingv2.HorizontalPodAutoscalerSpec{Metrics: spec.Autoscaling.Metrics},
+			templates...,
+		); err != nil {
 			return err
 		}
 	}
@@ -138,17 +462,38 @@ func (r *ServiceConfigCreateOrUpdateHandler) validateTemplates(
 		}
 		if _, err := helpers.ReplaceTemplateVariablesInIngressSpec(
 			*spec.IngressSpec,
-			templateHandler,
-			&helpers.StringMapTemplateValueGetter{StringMap: ingressTemplateValues},
+			append(templates, &helpers.StringMapTemplateValueGetter{StringMap: ingressTemplateValues})...,
 		); err != nil {
 			return err
 		}
 	}
 	for name, v := range spec.ConfigMaps {
-		if _, err := helpers.ReplaceTemplateVariablesInStringMap(v, name+" configmap", templateHandler); err != nil {
+		if _, err := helpers.ReplaceTemplateVariablesInStringMap(v, name+" configmap", templates...); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// getDummyProvisionerOutputs returns a dummy value for every provisioner.<name>.* variable used in the templates of the
+// service config, where the provisioner is listed in the config
+func getDummyProvisionerOutputs(config configv1.ServiceConfig) (helpers.StringMapTemplateValueGetter, error) {
+	result := helpers.StringMapTemplateValueGetter{StringMap: map[string]string{}}
+
+	data, err := yaml.Marshal(config.Spec)
+	if err != nil {
+		return result, err
+	}
+
+	for _, variable := range helpers.GetUnresolvedTemplatesFromString(string(data)) {
+		name := strings.TrimSuffix(strings.TrimPrefix(variable, "${"), "}")
+		for _, provisionerName := range config.Spec.Provisioners {
+			if strings.HasPrefix(name, "provisioner."+provisionerName+".") {
+				result.StringMap[name] = "dummy"
+			}
+		}
+	}
+
+	return result, nil
+}
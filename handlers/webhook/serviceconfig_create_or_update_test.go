@@ -6,6 +6,10 @@ import (
 	"testing"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -101,3 +105,302 @@ func TestServiceConfigCreateOrUpdateHandler_InvalidDefaultRedisEnvironment(t *te
 		t.Error("expected not Allowed for invalid DefaultRedisEnvironment, got Allowed")
 	}
 }
+
+// TestServiceConfigCreateOrUpdateHandler_SanitizeWithoutInit verifies that a ServiceConfig with a db sanitize pod spec,
+// but no db init pod spec is not admitted.
+//
+// NOTE: Same fake-client limitation as TestServiceConfigCreateOrUpdateHandler_InvalidDefaultMysqlEnvironment.
+func TestServiceConfigCreateOrUpdateHandler_SanitizeWithoutInit(t *testing.T) {
+	const ns = "test-ns"
+
+	cfg := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	cfg.Spec.DbSanitizePodSpec = cfg.Spec.DbInitPodSpec
+	cfg.Spec.DbInitPodSpec = nil
+
+	handler := &ServiceConfigCreateOrUpdateHandler{
+		Client:  testutil.NewFakeClient(cfg),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	resp := handler.Handle(context.Background(), makeServiceConfigAdmissionRequest(cfg))
+
+	if resp.Allowed {
+		t.Error("expected not Allowed for a sanitize pod spec without an init pod spec, got Allowed")
+	}
+}
+
+// TestServiceConfigCreateOrUpdateHandler_DuplicateHookName verifies that a ServiceConfig with two hooks with the same
+// name is not admitted.
+//
+// NOTE: Same fake-client limitation as TestServiceConfigCreateOrUpdateHandler_InvalidDefaultMysqlEnvironment.
+func TestServiceConfigCreateOrUpdateHandler_DuplicateHookName(t *testing.T) {
+	const ns = "test-ns"
+
+	cfg := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	cfg.Spec.Hooks = []configv1.ServiceHook{
+		{Name: "seed", Phase: configv1.HookPhasePostMigrate},
+		{Name: "seed", Phase: configv1.HookPhasePostDeploy},
+	}
+
+	handler := &ServiceConfigCreateOrUpdateHandler{
+		Client:  testutil.NewFakeClient(cfg),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	resp := handler.Handle(context.Background(), makeServiceConfigAdmissionRequest(cfg))
+
+	if resp.Allowed {
+		t.Error("expected not Allowed for duplicate hook names, got Allowed")
+	}
+}
+
+// TestServiceConfigCreateOrUpdateHandler_InvalidCronJobName verifies that a ServiceConfig with a cron job name that is
+// not a dns label is not admitted.
+//
+// NOTE: Same fake-client limitation as TestServiceConfigCreateOrUpdateHandler_InvalidDefaultMysqlEnvironment.
+func TestServiceConfigCreateOrUpdateHandler_InvalidCronJobName(t *testing.T) {
+	const ns = "test-ns"
+
+	cfg := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	cfg.Spec.CronJobs = map[string]configv1.ServiceCronJob{
+		"Nightly_Report": {Schedule: "0 3 * * *"},
+	}
+
+	handler := &ServiceConfigCreateOrUpdateHandler{
+		Client:  testutil.NewFakeClient(cfg),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	resp := handler.Handle(context.Background(), makeServiceConfigAdmissionRequest(cfg))
+
+	if resp.Allowed {
+		t.Error("expected not Allowed for an invalid cron job name, got Allowed")
+	}
+}
+
+func TestValidateEnvironmentPool(t *testing.T) {
+	pool := &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "shared"}}
+	invalidPool := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "pool", Operator: "Bogus"}},
+	}
+
+	tests := []struct {
+		name               string
+		defaultEnvironment string
+		pool               *metav1.LabelSelector
+		wantErr            bool
+	}{
+		{name: "no pool", defaultEnvironment: "mydb"},
+		{name: "pool only", pool: pool},
+		{name: "pool and default", defaultEnvironment: "mydb", pool: pool, wantErr: true},
+		{name: "invalid pool", pool: invalidPool, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnvironmentPool("mysql", tt.defaultEnvironment, tt.pool)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateEnvironmentPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateExtraObjects(t *testing.T) {
+	serviceAccount := runtime.RawExtension{
+		Raw: []byte(`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"${site.name}-worker"}}`),
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ServiceAccount"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+
+	tests := []struct {
+		name    string
+		objects []runtime.RawExtension
+		wantErr bool
+	}{
+		{name: "no objects"},
+		{name: "valid object", objects: []runtime.RawExtension{serviceAccount}},
+		{
+			name:    "duplicate object",
+			objects: []runtime.RawExtension{serviceAccount, serviceAccount},
+			wantErr: true,
+		},
+		{
+			name:    "missing kind",
+			objects: []runtime.RawExtension{{Raw: []byte(`{"apiVersion":"v1","metadata":{"name":"${site.name}"}}`)}},
+			wantErr: true,
+		},
+		{
+			name: "other namespace",
+			objects: []runtime.RawExtension{{Raw: []byte(
+				`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"${site.name}","namespace":"other"}}`,
+			)}},
+			wantErr: true,
+		},
+		{
+			name:    "name without the site name",
+			objects: []runtime.RawExtension{{Raw: []byte(`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"worker"}}`)}},
+			wantErr: true,
+		},
+		{
+			name: "cluster scoped object",
+			objects: []runtime.RawExtension{{Raw: []byte(
+				`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"${site.name}-reader"}}`,
+			)}},
+			wantErr: true,
+		},
+		{
+			name: "unknown kind",
+			objects: []runtime.RawExtension{{Raw: []byte(
+				`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"name":"${site.name}"}}`,
+			)}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+			cfg.Spec.ExtraObjects = tt.objects
+			err := validateExtraObjects(*cfg, mapper)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateExtraObjects() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetDummyProvisionerOutputs(t *testing.T) {
+	cfg := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+	cfg.Spec.Provisioners = []string{"bucket"}
+	cfg.Spec.DeploymentPodSpec.Containers[0].Args = []string{"${provisioner.bucket.name}", "${provisioner.queue.url}"}
+
+	outputs, err := getDummyProvisionerOutputs(*cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := outputs.StringMap["provisioner.bucket.name"]; !ok {
+		t.Error("expected a dummy value for the output of a listed provisioner")
+	}
+	if _, ok := outputs.StringMap["provisioner.queue.url"]; ok {
+		t.Error("unexpected dummy value for the output of a provisioner not listed in the config")
+	}
+}
+
+func TestValidateHeaderRouting(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+	serviceSpec := &corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 8080}}}
+
+	tests := []struct {
+		name        string
+		serviceSpec *corev1.ServiceSpec
+		routing     configv1.ServiceHeaderRouting
+		wantErr     bool
+	}{
+		{name: "defaults", serviceSpec: serviceSpec},
+		{
+			name:        "custom header and port",
+			serviceSpec: serviceSpec,
+			routing:     configv1.ServiceHeaderRouting{HeaderName: "X-Branch", Port: int32Ptr(8080)},
+		},
+		{name: "no service spec", wantErr: true},
+		{name: "no ports", serviceSpec: &corev1.ServiceSpec{}, wantErr: true},
+		{
+			name:        "invalid header name",
+			serviceSpec: serviceSpec,
+			routing:     configv1.ServiceHeaderRouting{HeaderName: "X Branch"},
+			wantErr:     true,
+		},
+		{
+			name:        "unknown port",
+			serviceSpec: serviceSpec,
+			routing:     configv1.ServiceHeaderRouting{Port: int32Ptr(9090)},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+			config.Spec.ServiceSpec = tt.serviceSpec
+			config.Spec.HeaderRouting = &tt.routing
+
+			err := validateHeaderRouting(*config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateHeaderRouting() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateHelmChart(t *testing.T) {
+	configMap := &configv1.ServiceHelmChartConfigMap{Name: "chart"}
+
+	tests := []struct {
+		name                 string
+		chart                *configv1.ServiceHelmChart
+		withoutDeploymentPod bool
+		wantErr              bool
+	}{
+		{name: "no chart"},
+		{name: "neither deployment nor chart", withoutDeploymentPod: true, wantErr: true},
+		{
+			name:                 "oci chart",
+			chart:                &configv1.ServiceHelmChart{OCIRepository: "oci://registry.example.com/charts/api", Version: "1.2.3"},
+			withoutDeploymentPod: true,
+		},
+		{
+			name: "configmap chart with values",
+			chart: &configv1.ServiceHelmChart{
+				ConfigMap:      configMap,
+				ValuesTemplate: "ingress:\n  host: ${site.name}.example.com\n",
+			},
+		},
+		{name: "no source", chart: &configv1.ServiceHelmChart{}, wantErr: true},
+		{
+			name: "both sources",
+			chart: &configv1.ServiceHelmChart{
+				OCIRepository: "oci://registry.example.com/charts/api",
+				Version:       "1.2.3",
+				ConfigMap:     configMap,
+			},
+			wantErr: true,
+		},
+		{
+			name:    "not an oci repository",
+			chart:   &configv1.ServiceHelmChart{OCIRepository: "https://charts.example.com/api", Version: "1.2.3"},
+			wantErr: true,
+		},
+		{
+			name:    "missing version",
+			chart:   &configv1.ServiceHelmChart{OCIRepository: "oci://registry.example.com/charts/api"},
+			wantErr: true,
+		},
+		{
+			name:    "version with configmap",
+			chart:   &configv1.ServiceHelmChart{ConfigMap: configMap, Version: "1.2.3"},
+			wantErr: true,
+		},
+		{
+			name:    "values not a map",
+			chart:   &configv1.ServiceHelmChart{ConfigMap: configMap, ValuesTemplate: "- a\n- b\n"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+			config.Spec.HelmChart = tt.chart
+			if tt.withoutDeploymentPod {
+				config.Spec.DeploymentPodSpec = nil
+			}
+
+			err := validateHelmChart(*config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateHelmChart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
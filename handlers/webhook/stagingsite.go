@@ -4,15 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/provider"
 	"github.com/szeber/kube-stager/helpers"
 	"github.com/szeber/kube-stager/helpers/kubernetes"
 	"github.com/szeber/kube-stager/helpers/labels"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"net/http"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sort"
 	"strings"
 )
 
@@ -30,6 +34,14 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	// The previous version of the site on updates, used to tell new databases from existing ones
+	oldSite := &sitev1.StagingSite{}
+	if len(req.OldObject.Raw) > 0 {
+		if err = r.Decoder.DecodeRaw(req.OldObject, oldSite); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
 	serviceConfigs, err := kubernetes.GetServiceConfigsInNamespace(site.Namespace, r.Client, ctx)
 	if err != nil {
 		logger.Error(err, "Failed to list the service configs")
@@ -50,6 +62,11 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 		logger.Error(err, "Failed to list the service configs")
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
+	objectStorageEnvironments, err := kubernetes.GetObjectStorageEnvironmentsInNamespace(site.Namespace, r.Client, ctx)
+	if err != nil {
+		logger.Error(err, "Failed to list the object storage configs")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
 
 	if site.Spec.IncludeAllServices {
 		logger.Info("Adding all services to the site")
@@ -70,9 +87,19 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 		return admission.Denied("There are no services defined in the site")
 	}
 
+	var usages *siteEnvironmentUsages
+	if needsPoolPlacement(site, serviceConfigs) {
+		if usages, err = loadSiteEnvironmentUsages(ctx, r.Client, site); err != nil {
+			logger.Error(err, "Failed to list the staging sites")
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+
 	usedMongoEnvironmentNames := make(map[string]bool)
 	usedMysqlEnvironmentNames := make(map[string]bool)
 	usedRedisEnvironmentNames := make(map[string]bool)
+	providerEnvironments := make(map[string]map[string]client.Object)
+	usedProviderEnvironmentNames := make(map[string]map[string]bool)
 	var serviceNames []string
 
 	for name, serviceSpec := range site.Spec.Services {
@@ -82,6 +109,12 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 			appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_config").Inc()
 			return admission.Denied("The service config '" + name + "' doesn't exist")
 		}
+		if serviceSpec.Autoscaling != nil {
+			if err := validateSiteAutoscaling(config, serviceSpec.Autoscaling); err != nil {
+				appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_autoscaling").Inc()
+				return admission.Denied(err.Error())
+			}
+		}
 		if serviceSpec.ImageTag == "" {
 			serviceSpec.ImageTag = "latest"
 		}
@@ -97,6 +130,45 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 		if serviceSpec.RedisEnvironment == "" {
 			serviceSpec.RedisEnvironment = config.Spec.DefaultRedisEnvironment
 		}
+		if serviceSpec.ObjectStorageEnvironment == "" {
+			serviceSpec.ObjectStorageEnvironment = config.Spec.DefaultObjectStorageEnvironment
+		}
+		if serviceSpec.MongoEnvironment == "" && config.Spec.DefaultMongoEnvironmentPool != nil {
+			if serviceSpec.MongoEnvironment, err = selectPoolEnvironment(
+				"mongo",
+				name,
+				config.Spec.DefaultMongoEnvironmentPool,
+				makeMongoPoolMembers(mongoEnvironments),
+				usages.mongo,
+				site.Name,
+			); err != nil {
+				return denyPoolPlacement(err)
+			}
+		}
+		if serviceSpec.MysqlEnvironment == "" && config.Spec.DefaultMysqlEnvironmentPool != nil {
+			if serviceSpec.MysqlEnvironment, err = selectPoolEnvironment(
+				"mysql",
+				name,
+				config.Spec.DefaultMysqlEnvironmentPool,
+				makeMysqlPoolMembers(mysqlEnvironments),
+				usages.mysql,
+				site.Name,
+			); err != nil {
+				return denyPoolPlacement(err)
+			}
+		}
+		if serviceSpec.RedisEnvironment == "" && config.Spec.DefaultRedisEnvironmentPool != nil {
+			if serviceSpec.RedisEnvironment, err = selectPoolEnvironment(
+				"redis",
+				name,
+				config.Spec.DefaultRedisEnvironmentPool,
+				makeRedisPoolMembers(redisEnvironments),
+				usages.redis,
+				site.Name,
+			); err != nil {
+				return denyPoolPlacement(err)
+			}
+		}
 		if serviceSpec.MongoEnvironment != "" {
 			if mongoEnvironments[serviceSpec.MongoEnvironment].Name == "" {
 				appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_environment").Inc()
@@ -110,6 +182,12 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 			} else {
 				usedMongoEnvironmentNames[serviceSpec.MongoEnvironment] = true
 			}
+			if serviceSpec.MongoEnvironment != oldSite.Spec.Services[name].MongoEnvironment {
+				mode := mongoEnvironments[serviceSpec.MongoEnvironment].Spec.Mode
+				if !mode.AcceptsNewDatabases() {
+					return denyEnvironmentMode("mongo", serviceSpec.MongoEnvironment, name, mode)
+				}
+			}
 		}
 		if serviceSpec.MysqlEnvironment != "" {
 			if mysqlEnvironments[serviceSpec.MysqlEnvironment].Name == "" {
@@ -124,6 +202,12 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 			} else {
 				usedMysqlEnvironmentNames[serviceSpec.MysqlEnvironment] = true
 			}
+			if serviceSpec.MysqlEnvironment != oldSite.Spec.Services[name].MysqlEnvironment {
+				mode := mysqlEnvironments[serviceSpec.MysqlEnvironment].Spec.Mode
+				if !mode.AcceptsNewDatabases() {
+					return denyEnvironmentMode("mysql", serviceSpec.MysqlEnvironment, name, mode)
+				}
+			}
 		}
 		if serviceSpec.RedisEnvironment != "" {
 			if redisEnvironments[serviceSpec.RedisEnvironment].Name == "" {
@@ -138,7 +222,61 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 			} else {
 				usedRedisEnvironmentNames[serviceSpec.RedisEnvironment] = true
 			}
+			if serviceSpec.RedisEnvironment != oldSite.Spec.Services[name].RedisEnvironment {
+				mode := redisEnvironments[serviceSpec.RedisEnvironment].Spec.Mode
+				if !mode.AcceptsNewDatabases() {
+					return denyEnvironmentMode("redis", serviceSpec.RedisEnvironment, name, mode)
+				}
+			}
 		}
+		if serviceSpec.ObjectStorageEnvironment != "" {
+			if _, ok := objectStorageEnvironments[serviceSpec.ObjectStorageEnvironment]; !ok {
+				appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_environment").Inc()
+				return admission.Denied(
+					fmt.Sprintf(
+						"Invalid object storage environment '%s' in service '%s'",
+						serviceSpec.ObjectStorageEnvironment,
+						name,
+					),
+				)
+			}
+		}
+		for providerName, environment := range serviceSpec.Databases {
+			databaseProvider, ok := provider.Get(providerName)
+			if !ok || provider.IsBuiltIn(providerName) {
+				appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_database_provider").Inc()
+				return admission.Denied(
+					fmt.Sprintf("Invalid database provider '%s' in service '%s'", providerName, name),
+				)
+			}
+			if _, ok := providerEnvironments[providerName]; !ok {
+				if providerEnvironments[providerName], err = provider.ListEnvironments(
+					ctx,
+					r.Client,
+					databaseProvider,
+					site.Namespace,
+				); err != nil {
+					logger.Error(err, "Failed to list the environments", "provider", providerName)
+					return admission.Errored(http.StatusInternalServerError, err)
+				}
+				usedProviderEnvironmentNames[providerName] = make(map[string]bool)
+			}
+			config, ok := providerEnvironments[providerName][environment]
+			if !ok {
+				appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_environment").Inc()
+				return admission.Denied(
+					fmt.Sprintf("Invalid %s environment '%s' in service '%s'", providerName, environment, name),
+				)
+			}
+			usedProviderEnvironmentNames[providerName][environment] = true
+			if environment != oldSite.Spec.Services[name].Databases[providerName] {
+				mode := databaseProvider.GetEnvironmentMode(config)
+				if !mode.AcceptsNewDatabases() {
+					return denyEnvironmentMode(providerName, environment, name, mode)
+				}
+			}
+		}
+		usages.addService(serviceSpec, site.Name)
 		site.Spec.Services[name] = serviceSpec
 	}
 
@@ -157,14 +295,159 @@ func (r *StagingsiteHandler) Handle(ctx context.Context, req admission.Request)
 		labels.RedisEnvironmentsPrefix,
 		helpers.GetKeysFromStringBoolMap(usedRedisEnvironmentNames),
 	)
+	for _, databaseProvider := range provider.All() {
+		if provider.IsBuiltIn(databaseProvider.Name()) {
+			continue
+		}
+		r.updatePrefixedLabels(
+			site,
+			labels.MakeEnvironmentsPrefix(databaseProvider.Name()),
+			helpers.GetKeysFromStringBoolMap(usedProviderEnvironmentNames[databaseProvider.Name()]),
+		)
+	}
 	r.updatePrefixedLabels(site, labels.ServicesPrefix, serviceNames)
 
+	var warnings []string
+	for _, name := range helpers.GetKeysFromStringBoolMap(usedMysqlEnvironmentNames) {
+		warnings = appendUnhealthyEnvironmentWarning(warnings, "mysql", name, mysqlEnvironments[name].Status.EnvironmentStatus)
+	}
+	for _, name := range helpers.GetKeysFromStringBoolMap(usedMongoEnvironmentNames) {
+		warnings = appendUnhealthyEnvironmentWarning(warnings, "mongo", name, mongoEnvironments[name].Status.EnvironmentStatus)
+	}
+	for _, name := range helpers.GetKeysFromStringBoolMap(usedRedisEnvironmentNames) {
+		warnings = appendUnhealthyEnvironmentWarning(warnings, "redis", name, redisEnvironments[name].Status.EnvironmentStatus)
+	}
+
+	if site.Spec.BaselineSite != "" {
+		baselineSite := &sitev1.StagingSite{}
+		err = r.Client.Get(ctx, client.ObjectKey{Namespace: site.Namespace, Name: site.Spec.BaselineSite}, baselineSite)
+		if apierrors.IsNotFound(err) {
+			appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_baseline_site").Inc()
+			return admission.Denied("The baseline site '" + site.Spec.BaselineSite + "' doesn't exist")
+		} else if err != nil {
+			logger.Error(err, "Failed to get the baseline site")
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if err = validateBaselineSite(site, baselineSite, serviceConfigs); err != nil {
+			appmetrics.WebhookDenied.WithLabelValues("stagingsite", "invalid_baseline_site").Inc()
+			return admission.Denied(err.Error())
+		}
+		if !baselineSite.Spec.Enabled {
+			warnings = append(warnings, fmt.Sprintf("The baseline site '%s' is disabled", baselineSite.Name))
+		}
+	}
+
 	marshaledSite, err := json.Marshal(site)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledSite)
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledSite).WithWarnings(warnings...)
+}
+
+// validateSiteAutoscaling checks that the autoscaling overrides of a service in the site are valid for its service
+// config
+func validateSiteAutoscaling(config configv1.ServiceConfig, override *sitev1.StagingSiteAutoscaling) error {
+	if override.Enabled != nil && !*override.Enabled {
+		return nil
+	}
+	if config.Spec.Autoscaling == nil {
+		if override.Enabled == nil && override.MinReplicas == nil && override.MaxReplicas == nil {
+			return nil
+		}
+		return fmt.Errorf("autoscaling is not configured in the service config '%s'", config.Name)
+	}
+
+	minReplicas := config.Spec.Autoscaling.MinReplicas
+	if override.MinReplicas != nil {
+		minReplicas = override.MinReplicas
+	}
+	maxReplicas := config.Spec.Autoscaling.MaxReplicas
+	if override.MaxReplicas != nil {
+		maxReplicas = *override.MaxReplicas
+	}
+
+	return validateAutoscalingReplicas(minReplicas, maxReplicas)
+}
+
+// validateBaselineSite checks that the baseline site isn't the site itself or a site with its own baseline, and that it
+// includes every service of the namespace that the site doesn't include
+func validateBaselineSite(
+	site *sitev1.StagingSite,
+	baselineSite *sitev1.StagingSite,
+	serviceConfigs map[string]configv1.ServiceConfig,
+) error {
+	if baselineSite.Name == site.Name {
+		return fmt.Errorf("the site can't be its own baseline site")
+	}
+	if baselineSite.Spec.BaselineSite != "" {
+		return fmt.Errorf(
+			"the baseline site '%s' has a baseline site itself, which is not supported",
+			baselineSite.Name,
+		)
+	}
+
+	var missingServices []string
+	for name := range serviceConfigs {
+		if _, ok := site.Spec.Services[name]; ok {
+			continue
+		}
+		if _, ok := baselineSite.Spec.Services[name]; !ok {
+			missingServices = append(missingServices, name)
+		}
+	}
+	if len(missingServices) > 0 {
+		sort.Strings(missingServices)
+		return fmt.Errorf(
+			"the services %s are not included in the site or its baseline site '%s'",
+			strings.Join(missingServices, ", "),
+			baselineSite.Name,
+		)
+	}
+
+	return nil
+}
+
+func denyPoolPlacement(err error) admission.Response {
+	appmetrics.WebhookDenied.WithLabelValues("stagingsite", "no_environment_available").Inc()
+	return admission.Denied(err.Error())
+}
+
+// denyEnvironmentMode denies placing a new database on an environment that is in maintenance or being drained
+func denyEnvironmentMode(
+	databaseType string,
+	environment string,
+	serviceName string,
+	mode configv1.EnvironmentMode,
+) admission.Response {
+	appmetrics.WebhookDenied.WithLabelValues("stagingsite", "environment_unavailable").Inc()
+	return admission.Denied(
+		fmt.Sprintf(
+			"The %s environment '%s' of service '%s' is in %s mode and doesn't accept new databases",
+			databaseType,
+			environment,
+			serviceName,
+			strings.ToLower(string(mode)),
+		),
+	)
+}
+
+// appendUnhealthyEnvironmentWarning adds a warning if the last probe of the environment failed. The site is still
+// admitted, as the environment may recover before the databases are created.
+func appendUnhealthyEnvironmentWarning(
+	warnings []string,
+	databaseType string,
+	name string,
+	status configv1.EnvironmentStatus,
+) []string {
+	if !status.IsUnhealthy() {
+		return warnings
+	}
+
+	return append(
+		warnings,
+		fmt.Sprintf("The %s environment '%s' is not ready: %s", databaseType, name, status.GetReadyMessage()),
+	)
 }
 
 func (r *StagingsiteHandler) updatePrefixedLabels(site *sitev1.StagingSite, prefix string, values []string) {
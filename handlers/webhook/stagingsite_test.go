@@ -3,15 +3,21 @@ package webhook
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
+	"github.com/szeber/kube-stager/handlers/provider"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 	"github.com/szeber/kube-stager/internal/metricstest"
 	"github.com/szeber/kube-stager/internal/testutil"
@@ -268,6 +274,29 @@ func TestStagingsiteHandler_InvalidRedisEnvironment(t *testing.T) {
 	}
 }
 
+func TestStagingsiteHandler_InvalidObjectStorageEnvironment(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	serviceConfig.Spec.DefaultObjectStorageEnvironment = "mystorage"
+	// No ObjectStorageConfig added — "mystorage" does not exist
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1.0"},
+	})
+
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if resp.Allowed {
+		t.Error("expected Denied due to missing object storage environment, got Allowed")
+	}
+}
+
 func TestStagingsiteHandler_AllEnvironmentLabelsSet(t *testing.T) {
 	const ns = "test-ns"
 
@@ -335,3 +364,423 @@ func TestStagingsiteHandler_ExistingLabelsPreserved(t *testing.T) {
 		t.Errorf("expected Allowed, got Denied: %v", resp.Result)
 	}
 }
+
+func TestStagingsiteHandler_UnhealthyEnvironmentWarning(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	mysqlConfig := testutil.NewTestMysqlConfig("mydb", ns)
+	mysqlConfig.Status.SetProbeResult("", errors.New("connection refused"), metav1.Now(), 1)
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig, mysqlConfig),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {MysqlEnvironment: "mydb"},
+	})
+
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if !resp.Allowed {
+		t.Fatalf("expected Allowed, got Denied: %v", resp.Result)
+	}
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "connection refused") {
+		t.Errorf("Warnings = %v, want a warning about the mysql environment", resp.Warnings)
+	}
+}
+
+func TestStagingsiteHandler_PlacesDatabaseOnPool(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	serviceConfig.Spec.DefaultMysqlEnvironmentPool = &metav1.LabelSelector{
+		MatchLabels: map[string]string{"pool": "shared"},
+	}
+	mysql1 := testutil.NewTestMysqlConfig("mysql1", ns)
+	mysql1.Labels = map[string]string{"pool": "shared"}
+	mysql2 := testutil.NewTestMysqlConfig("mysql2", ns)
+	mysql2.Labels = map[string]string{"pool": "shared"}
+	existingSite := testutil.NewTestStagingSite("othersite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {MysqlEnvironment: "mysql1"},
+	})
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig, mysql1, mysql2, existingSite),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {},
+	})
+
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if !resp.Allowed {
+		t.Fatalf("expected Allowed, got Denied: %v", resp.Result)
+	}
+	found := false
+	for _, patch := range resp.Patches {
+		if patch.Path == "/spec/services/mysvc/mysqlEnvironment" && patch.Value == "mysql2" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the mysql environment to be set to mysql2, patches: %v", resp.Patches)
+	}
+}
+
+func TestStagingsiteHandler_PoolWithoutCapacity(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	serviceConfig.Spec.DefaultMysqlEnvironmentPool = &metav1.LabelSelector{
+		MatchLabels: map[string]string{"pool": "shared"},
+	}
+	mysqlConfig := testutil.NewTestMysqlConfig("mysql1", ns)
+	mysqlConfig.Labels = map[string]string{"pool": "shared"}
+	mysqlConfig.Spec.MaxSites = 1
+	existingSite := testutil.NewTestStagingSite("othersite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {MysqlEnvironment: "mysql1"},
+	})
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig, mysqlConfig, existingSite),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {},
+	})
+
+	before := metricstest.GetCounterValue(appmetrics.WebhookDenied, "stagingsite", "no_environment_available")
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if resp.Allowed {
+		t.Error("expected Denied when the pool is full, got Allowed")
+	}
+	after := metricstest.GetCounterValue(appmetrics.WebhookDenied, "stagingsite", "no_environment_available")
+	if after-before != 1 {
+		t.Errorf("expected webhook_denied_total(stagingsite, no_environment_available) to increment by 1, got delta %v", after-before)
+	}
+}
+
+func TestStagingsiteHandler_DeniesNewSiteOnEnvironmentInMaintenance(t *testing.T) {
+	const ns = "test-ns"
+
+	for _, mode := range []configv1.EnvironmentMode{configv1.EnvironmentModeMaintenance, configv1.EnvironmentModeDraining} {
+		serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+		mysqlConfig := testutil.NewTestMysqlConfig("mydb", ns)
+		mysqlConfig.Spec.Mode = mode
+
+		handler := &StagingsiteHandler{
+			Client:  testutil.NewFakeClient(serviceConfig, mysqlConfig),
+			Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+		}
+
+		site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+			"mysvc": {MysqlEnvironment: "mydb"},
+		})
+
+		resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+		if resp.Allowed {
+			t.Fatalf("expected Denied for an environment in %s mode, got Allowed", mode)
+		}
+		if !strings.Contains(resp.Result.Message, "doesn't accept new databases") {
+			t.Errorf("unexpected denial message: %s", resp.Result.Message)
+		}
+	}
+}
+
+func TestStagingsiteHandler_AllowsExistingSiteOnEnvironmentInMaintenance(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	mysqlConfig := testutil.NewTestMysqlConfig("mydb", ns)
+	mysqlConfig.Spec.Mode = configv1.EnvironmentModeMaintenance
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig, mysqlConfig),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {MysqlEnvironment: "mydb"},
+	})
+	oldRaw, err := json.Marshal(site)
+	if err != nil {
+		t.Fatalf("failed to marshal StagingSite: %v", err)
+	}
+	site.Spec.Services["mysvc"] = sitev1.StagingSiteService{MysqlEnvironment: "mydb", ImageTag: "v2"}
+	req := makeSiteAdmissionRequest(t, site)
+	req.OldObject = runtime.RawExtension{Raw: oldRaw}
+
+	resp := handler.Handle(context.Background(), req)
+
+	if !resp.Allowed {
+		t.Fatalf("expected Allowed for a site already using the environment, got Denied: %v", resp.Result)
+	}
+}
+
+func TestStagingsiteHandler_PoolSkipsDrainingEnvironment(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	serviceConfig.Spec.DefaultMysqlEnvironmentPool = &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "shared"}}
+	drainingConfig := testutil.NewTestMysqlConfig("mysql-a", ns)
+	drainingConfig.Labels = map[string]string{"pool": "shared"}
+	drainingConfig.Spec.Mode = configv1.EnvironmentModeDraining
+	activeConfig := testutil.NewTestMysqlConfig("mysql-b", ns)
+	activeConfig.Labels = map[string]string{"pool": "shared"}
+	activeConfig.Spec.Mode = configv1.EnvironmentModeActive
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig, drainingConfig, activeConfig),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {},
+	})
+
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if !resp.Allowed {
+		t.Fatalf("expected Allowed, got Denied: %v", resp.Result)
+	}
+	found := false
+	for _, patch := range resp.Patches {
+		if strings.Contains(patch.Path, "mysqlEnvironment") && patch.Value == "mysql-b" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the database to be placed on mysql-b, got patches %v", resp.Patches)
+	}
+}
+
+// keydbProvider is a custom database provider reusing the CRDs of the redis provider
+type keydbProvider struct {
+	provider.RedisProvider
+}
+
+func (r keydbProvider) Name() string {
+	return "keydb"
+}
+
+var registerKeydbProvider sync.Once
+
+func newCustomProviderSiteHandler(ns string, objects ...client.Object) *StagingsiteHandler {
+	registerKeydbProvider.Do(func() { provider.Register(keydbProvider{}) })
+
+	return &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(append(objects, testutil.NewTestServiceConfig("mysvc", ns, "svc"))...),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+}
+
+func TestStagingsiteHandler_CustomProviderEnvironment(t *testing.T) {
+	const ns = "test-ns"
+
+	handler := newCustomProviderSiteHandler(ns, testutil.NewTestRedisConfig("keydb1", ns))
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {Databases: map[string]string{"keydb": "keydb1"}},
+	})
+
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if !resp.Allowed {
+		t.Fatalf("expected Allowed, got Denied: %v", resp.Result)
+	}
+	found := false
+	for _, patch := range resp.Patches {
+		labels, ok := patch.Value.(map[string]interface{})
+		if patch.Path == "/metadata/labels" && ok && labels["keydb.environments.operator.kube-stager.io/keydb1"] == "true" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the keydb environment label to be set, patches: %v", resp.Patches)
+	}
+}
+
+func TestStagingsiteHandler_CustomProviderDenials(t *testing.T) {
+	const ns = "test-ns"
+
+	drainingConfig := testutil.NewTestRedisConfig("keydb-draining", ns)
+	drainingConfig.Spec.Mode = configv1.EnvironmentModeDraining
+
+	tests := []struct {
+		name      string
+		databases map[string]string
+		reason    string
+		message   string
+	}{
+		{"unregistered provider", map[string]string{"cassandra": "c1"}, "invalid_database_provider", "cassandra"},
+		{"built-in provider", map[string]string{"mysql": "mysql1"}, "invalid_database_provider", "mysql"},
+		{"missing environment", map[string]string{"keydb": "keydb2"}, "invalid_environment", "keydb2"},
+		{"draining environment", map[string]string{"keydb": "keydb-draining"}, "environment_unavailable", "draining"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newCustomProviderSiteHandler(ns, drainingConfig.DeepCopy())
+			site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+				"mysvc": {Databases: tt.databases},
+			})
+			before := metricstest.GetCounterValue(appmetrics.WebhookDenied, "stagingsite", tt.reason)
+
+			resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+			if resp.Allowed {
+				t.Fatal("expected Denied, got Allowed")
+			}
+			if !strings.Contains(resp.Result.Message, tt.message) {
+				t.Errorf("Message = %q, want it to contain %q", resp.Result.Message, tt.message)
+			}
+			after := metricstest.GetCounterValue(appmetrics.WebhookDenied, "stagingsite", tt.reason)
+			if after != before+1 {
+				t.Errorf("WebhookDenied{%s} = %v, want %v", tt.reason, after, before+1)
+			}
+		})
+	}
+}
+
+func TestValidateSiteAutoscaling(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+	boolPtr := func(v bool) *bool { return &v }
+
+	autoscaled := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+	autoscaled.Spec.Autoscaling = &configv1.ServiceAutoscaling{MinReplicas: int32Ptr(2), MaxReplicas: 5}
+	notAutoscaled := testutil.NewTestServiceConfig("mysvc", "test-ns", "svc")
+
+	tests := []struct {
+		name     string
+		config   *configv1.ServiceConfig
+		override sitev1.StagingSiteAutoscaling
+		wantErr  bool
+	}{
+		{name: "max override", config: autoscaled, override: sitev1.StagingSiteAutoscaling{MaxReplicas: int32Ptr(8)}},
+		{
+			name:     "min above max",
+			config:   autoscaled,
+			override: sitev1.StagingSiteAutoscaling{MinReplicas: int32Ptr(6)},
+			wantErr:  true,
+		},
+		{name: "disabled", config: notAutoscaled, override: sitev1.StagingSiteAutoscaling{Enabled: boolPtr(false)}},
+		{
+			name:     "not configured",
+			config:   notAutoscaled,
+			override: sitev1.StagingSiteAutoscaling{Enabled: boolPtr(true)},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSiteAutoscaling(*tt.config, &tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSiteAutoscaling() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStagingsiteHandler_MissingBaselineSite(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1.0"},
+	})
+	site.Spec.BaselineSite = "master"
+
+	before := metricstest.GetCounterValue(appmetrics.WebhookDenied, "stagingsite", "invalid_baseline_site")
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if resp.Allowed {
+		t.Error("expected Denied, got Allowed")
+	}
+	after := metricstest.GetCounterValue(appmetrics.WebhookDenied, "stagingsite", "invalid_baseline_site")
+	if after-before != 1 {
+		t.Errorf("expected webhook_denied_total(stagingsite, invalid_baseline_site) to increment by 1, got delta %v", after-before)
+	}
+}
+
+func TestStagingsiteHandler_DisabledBaselineSiteWarning(t *testing.T) {
+	const ns = "test-ns"
+
+	serviceConfig := testutil.NewTestServiceConfig("mysvc", ns, "svc")
+	otherConfig := testutil.NewTestServiceConfig("othersvc", ns, "other")
+	baselineSite := testutil.NewTestStagingSite("master", ns, map[string]sitev1.StagingSiteService{
+		"mysvc":    {ImageTag: "latest"},
+		"othersvc": {ImageTag: "latest"},
+	})
+	baselineSite.Spec.Enabled = false
+
+	handler := &StagingsiteHandler{
+		Client:  testutil.NewFakeClient(serviceConfig, otherConfig, baselineSite),
+		Decoder: admission.NewDecoder(testutil.NewTestScheme()),
+	}
+
+	site := testutil.NewTestStagingSite("mysite", ns, map[string]sitev1.StagingSiteService{
+		"mysvc": {ImageTag: "v1.0"},
+	})
+	site.Spec.BaselineSite = "master"
+
+	resp := handler.Handle(context.Background(), makeSiteAdmissionRequest(t, site))
+
+	if !resp.Allowed {
+		t.Fatalf("expected Allowed, got Denied: %v", resp.Result)
+	}
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "master") {
+		t.Errorf("expected a warning about the disabled baseline site, got %v", resp.Warnings)
+	}
+}
+
+func TestValidateBaselineSite(t *testing.T) {
+	serviceConfigs := map[string]configv1.ServiceConfig{
+		"web": *testutil.NewTestServiceConfig("web", "test-ns", "web"),
+		"api": *testutil.NewTestServiceConfig("api", "test-ns", "api"),
+	}
+	site := testutil.NewTestStagingSite("feature", "test-ns", map[string]sitev1.StagingSiteService{"web": {}})
+	site.Spec.BaselineSite = "master"
+
+	makeBaseline := func(name string, services ...string) *sitev1.StagingSite {
+		baseline := testutil.NewTestStagingSite(name, "test-ns", map[string]sitev1.StagingSiteService{})
+		for _, service := range services {
+			baseline.Spec.Services[service] = sitev1.StagingSiteService{}
+		}
+		return baseline
+	}
+	chained := makeBaseline("master", "web", "api")
+	chained.Spec.BaselineSite = "production"
+
+	tests := []struct {
+		name     string
+		baseline *sitev1.StagingSite
+		wantErr  bool
+	}{
+		{name: "valid", baseline: makeBaseline("master", "web", "api")},
+		{name: "valid without the local services", baseline: makeBaseline("master", "api")},
+		{name: "missing service", baseline: makeBaseline("master", "web"), wantErr: true},
+		{name: "own baseline", baseline: makeBaseline("feature", "web", "api"), wantErr: true},
+		{name: "chained baseline", baseline: chained, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBaselineSite(site, tt.baseline, serviceConfigs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateBaselineSite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,7 +1,22 @@
 package helpers
 
 const (
-	MongoFinalizerName = "mongo.task.finalizers.operator.kube-stager.io"
-	MysqlFinalizerName = "mysql.task.finalizers.operator.kube-stager.io"
-	SiteFinalizerName  = "stagingsite.site.finalizers.operator.kube-stager.io"
+	MongoFinalizerName         = "mongo.task.finalizers.operator.kube-stager.io"
+	MysqlFinalizerName         = "mysql.task.finalizers.operator.kube-stager.io"
+	ObjectStorageFinalizerName = "objectstorage.task.finalizers.operator.kube-stager.io"
+	ProvisionerFinalizerName   = "provisioner.task.finalizers.operator.kube-stager.io"
+	SiteFinalizerName          = "stagingsite.site.finalizers.operator.kube-stager.io"
+)
+
+// FieldManager is the field manager used for the objects created with server side apply
+const FieldManager = "kube-stager"
+
+// DefaultRoutingHeader is the header holding the name of the site for the services with header based routing
+const DefaultRoutingHeader = "X-Stager-Site"
+
+const (
+	// CertManagerClusterIssuerAnnotation is the ingress annotation selecting the cert-manager cluster issuer
+	CertManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+	// CertManagerIssuerAnnotation is the ingress annotation selecting the namespaced cert-manager issuer
+	CertManagerIssuerAnnotation = "cert-manager.io/issuer"
 )
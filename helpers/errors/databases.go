@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -34,6 +35,8 @@ const (
 	DatabaseTypeMongo DatabaseType = "Mongo"
 	DatabaseTypeMysql DatabaseType = "Mysql"
 	DatabaseTypeRedis DatabaseType = "Redis"
+	// DatabaseTypeObjectStorage is used for the operations on the S3 compatible endpoints of object storage configs
+	DatabaseTypeObjectStorage DatabaseType = "ObjectStorage"
 )
 
 func (r DatabaseCreationError) Error() string {
@@ -102,3 +105,66 @@ func (r DatabaseMigrationError) Error() string {
 func (r DatabaseMigrationError) IsFinal() bool {
 	return true
 }
+
+type DatabaseMoveError struct {
+	SiteName          string
+	ServiceName       string
+	TargetEnvironment string
+	Reason            string
+}
+
+func (r DatabaseMoveError) Error() string {
+	if r.Reason == "" {
+		return fmt.Sprintf(
+			"Moving the database to environment %s failed for site %s, service %s",
+			r.TargetEnvironment,
+			r.SiteName,
+			r.ServiceName,
+		)
+	} else {
+		return fmt.Sprintf(
+			"Moving the database to environment %s failed for site %s, service %s. Reason: %s",
+			r.TargetEnvironment,
+			r.SiteName,
+			r.ServiceName,
+			r.Reason,
+		)
+	}
+}
+
+func (r DatabaseMoveError) IsFinal() bool {
+	return true
+}
+
+// DatabaseTimeoutError is returned when an operation on a database server didn't complete within the operation timeout
+// of the environment. It's not final, the operation is retried with backoff.
+type DatabaseTimeoutError struct {
+	DatabaseType DatabaseType
+	Environment  string
+	Operation    string
+	Err          error
+}
+
+func (r DatabaseTimeoutError) Error() string {
+	return fmt.Sprintf(
+		"The %s operation timed out on %s environment %s: %s",
+		r.Operation,
+		r.DatabaseType,
+		r.Environment,
+		r.Err,
+	)
+}
+
+func (r DatabaseTimeoutError) IsFinal() bool {
+	return false
+}
+
+func (r DatabaseTimeoutError) Unwrap() error {
+	return r.Err
+}
+
+// IsDatabaseTimeoutError returns TRUE if the error or any error it wraps is a DatabaseTimeoutError
+func IsDatabaseTimeoutError(err error) bool {
+	var timeoutError DatabaseTimeoutError
+	return errors.As(err, &timeoutError)
+}
@@ -101,6 +101,64 @@ func TestDatabaseMigrationError_IsFinal(t *testing.T) {
 	}
 }
 
+func TestDatabaseMoveError_Error(t *testing.T) {
+	t.Run("with reason", func(t *testing.T) {
+		err := DatabaseMoveError{SiteName: "site1", ServiceName: "svc1", TargetEnvironment: "new", Reason: "job failed"}
+		got := err.Error()
+		if !strings.Contains(got, "site1") || !strings.Contains(got, "svc1") || !strings.Contains(got, "new") ||
+			!strings.Contains(got, "job failed") {
+			t.Errorf("unexpected error message: %s", got)
+		}
+	})
+
+	t.Run("without reason", func(t *testing.T) {
+		err := DatabaseMoveError{SiteName: "site1", ServiceName: "svc1", TargetEnvironment: "new"}
+		if strings.Contains(err.Error(), "Reason") {
+			t.Errorf("should not contain Reason when empty: %s", err.Error())
+		}
+	})
+}
+
+func TestDatabaseMoveError_IsFinal(t *testing.T) {
+	if !(DatabaseMoveError{}).IsFinal() {
+		t.Error("DatabaseMoveError.IsFinal() should return true")
+	}
+}
+
+func TestDatabaseTimeoutError_Error(t *testing.T) {
+	err := DatabaseTimeoutError{
+		DatabaseType: DatabaseTypeMysql,
+		Environment:  "env1",
+		Operation:    "reconcile",
+		Err:          fmt.Errorf("i/o timeout"),
+	}
+	got := err.Error()
+	if !strings.Contains(got, "Mysql") || !strings.Contains(got, "env1") || !strings.Contains(got, "reconcile") ||
+		!strings.Contains(got, "i/o timeout") {
+		t.Errorf("unexpected error message: %s", got)
+	}
+}
+
+func TestDatabaseTimeoutError_IsFinal(t *testing.T) {
+	if (DatabaseTimeoutError{}).IsFinal() {
+		t.Error("DatabaseTimeoutError.IsFinal() should return false")
+	}
+}
+
+func TestIsDatabaseTimeoutError(t *testing.T) {
+	timeoutErr := DatabaseTimeoutError{DatabaseType: DatabaseTypeRedis, Err: fmt.Errorf("i/o timeout")}
+
+	if !IsDatabaseTimeoutError(timeoutErr) {
+		t.Error("should detect a DatabaseTimeoutError")
+	}
+	if !IsDatabaseTimeoutError(fmt.Errorf("wrapped: %w", timeoutErr)) {
+		t.Error("should detect a wrapped DatabaseTimeoutError")
+	}
+	if IsDatabaseTimeoutError(fmt.Errorf("i/o timeout")) {
+		t.Error("should not detect a plain error")
+	}
+}
+
 func TestUnresolvedTemplatesError_Error(t *testing.T) {
 	t.Run("without key", func(t *testing.T) {
 		err := UnresolvedTemplatesError{
@@ -138,6 +196,40 @@ func TestUnresolvedTemplatesError_IsFinal(t *testing.T) {
 	}
 }
 
+func TestProvisioningError_Error(t *testing.T) {
+	t.Run("with reason", func(t *testing.T) {
+		err := ProvisioningError{SiteName: "site1", ServiceName: "svc1", Provisioner: "minio", Reason: "job failed"}
+		got := err.Error()
+		if !strings.Contains(got, "site1") ||
+			!strings.Contains(got, "svc1") ||
+			!strings.Contains(got, "minio") ||
+			!strings.Contains(got, "job failed") {
+			t.Errorf("unexpected error message: %s", got)
+		}
+	})
+
+	t.Run("without reason", func(t *testing.T) {
+		err := ProvisioningError{SiteName: "site1", ServiceName: "svc1", Provisioner: "minio"}
+		if strings.Contains(err.Error(), "Reason") {
+			t.Errorf("should not contain Reason when empty: %s", err.Error())
+		}
+	})
+}
+
+func TestHookError_Error(t *testing.T) {
+	err := HookError{SiteName: "site1", ServiceName: "svc1", Hook: "seed", Phase: "postMigrate"}
+	got := err.Error()
+	if !strings.Contains(got, "site1") ||
+		!strings.Contains(got, "svc1") ||
+		!strings.Contains(got, "seed") ||
+		!strings.Contains(got, "postMigrate") {
+		t.Errorf("unexpected error message: %s", got)
+	}
+	if !err.IsFinal() {
+		t.Error("expected HookError to be final")
+	}
+}
+
 func TestIsControllerError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -148,6 +240,9 @@ func TestIsControllerError(t *testing.T) {
 		{"UnresolvedTemplatesError", UnresolvedTemplatesError{}, true},
 		{"DatabaseInitError", DatabaseInitError{}, true},
 		{"DatabaseMigrationError", DatabaseMigrationError{}, true},
+		{"DatabaseTimeoutError", DatabaseTimeoutError{}, true},
+		{"ProvisioningError", ProvisioningError{}, true},
+		{"HookError", HookError{}, true},
 		{"plain error", fmt.Errorf("some error"), false},
 	}
 	for _, tt := range tests {
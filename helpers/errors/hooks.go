@@ -0,0 +1,25 @@
+package errors
+
+import "fmt"
+
+// HookError is returned when a blocking hook job of a service failed for a site
+type HookError struct {
+	SiteName    string
+	ServiceName string
+	Hook        string
+	Phase       string
+}
+
+func (r HookError) Error() string {
+	return fmt.Sprintf(
+		"The %s hook %s failed for site %s, service %s",
+		r.Phase,
+		r.Hook,
+		r.SiteName,
+		r.ServiceName,
+	)
+}
+
+func (r HookError) IsFinal() bool {
+	return true
+}
@@ -0,0 +1,34 @@
+package errors
+
+import "fmt"
+
+// ProvisioningError is returned when the provision job of a provisioner config failed for a service of a site
+type ProvisioningError struct {
+	SiteName    string
+	ServiceName string
+	Provisioner string
+	Reason      string
+}
+
+func (r ProvisioningError) Error() string {
+	if r.Reason == "" {
+		return fmt.Sprintf(
+			"Provisioning %s failed for site %s, service %s",
+			r.Provisioner,
+			r.SiteName,
+			r.ServiceName,
+		)
+	} else {
+		return fmt.Sprintf(
+			"Provisioning %s failed for site %s, service %s. Reason: %s",
+			r.Provisioner,
+			r.SiteName,
+			r.ServiceName,
+			r.Reason,
+		)
+	}
+}
+
+func (r ProvisioningError) IsFinal() bool {
+	return true
+}
@@ -6,4 +6,5 @@ const (
 	DefaultMongoEnvironment = ".spec.defaultMongoEnvironment"
 	DefaultMysqlEnvironment = ".spec.defaultMysqlEnvironment"
 	DefaultRedisEnvironment = ".spec.defaultRedisEnvironment"
+	BaselineSite            = ".spec.baselineSite"
 )
@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"context"
+	"github.com/szeber/kube-stager/helpers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// previousFieldManagers are the field managers of the objects created and updated by the earlier versions of the
+// operator without server side apply. The api server names them after the binary of the operator, which is manager in
+// the image and main when it's run locally
+var previousFieldManagers = sets.New("manager", "main")
+
+// Apply creates or updates the object with server side apply, using the kube-stager field manager. Only the fields set
+// in the object are owned by the operator, so the fields managed by other controllers (eg. the replicas set by an HPA
+// or the sidecars added by a mutating webhook) are left alone. The object is updated with the state returned by the
+// server
+func Apply(
+	ctx context.Context,
+	reader client.Reader,
+	writer client.Writer,
+	scheme *runtime.Scheme,
+	object client.Object,
+) error {
+	gvk, err := apiutil.GVKForObject(object, scheme)
+	if err != nil {
+		return err
+	}
+
+	if err := upgradeManagedFields(ctx, reader, writer, scheme, object, gvk); err != nil {
+		return err
+	}
+
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return err
+	}
+	applied := &unstructured.Unstructured{Object: data}
+	applied.SetGroupVersionKind(gvk)
+	// The status is owned by the controller of the object, and the server sets the metadata fields
+	delete(applied.Object, "status")
+	unstructured.RemoveNestedField(applied.Object, "metadata", "creationTimestamp")
+	applied.SetResourceVersion("")
+	applied.SetManagedFields(nil)
+
+	if err := writer.Apply(
+		ctx,
+		client.ApplyConfigurationFromUnstructured(applied),
+		client.FieldOwner(helpers.FieldManager),
+		client.ForceOwnership,
+	); err != nil {
+		return err
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(applied.Object, object)
+}
+
+// upgradeManagedFields moves the fields owned by the previous field managers of an existing object to the kube-stager
+// field manager, so the fields set by the earlier versions of the operator are removed once they are no longer applied.
+// The object is only written if it still has entries of the previous managers, so this happens once per object
+func upgradeManagedFields(
+	ctx context.Context,
+	reader client.Reader,
+	writer client.Writer,
+	scheme *runtime.Scheme,
+	object client.Object,
+	gvk schema.GroupVersionKind,
+) error {
+	var existing client.Object = &unstructured.Unstructured{}
+	if typed, err := scheme.New(gvk); err == nil {
+		existing = typed.(client.Object)
+	}
+	if existingUnstructured, ok := existing.(*unstructured.Unstructured); ok {
+		existingUnstructured.SetGroupVersionKind(gvk)
+	}
+
+	if err := reader.Get(ctx, client.ObjectKeyFromObject(object), existing); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	original := existing.DeepCopyObject().(client.Object)
+	if err := csaupgrade.UpgradeManagedFields(existing, previousFieldManagers, helpers.FieldManager); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(original.GetManagedFields(), existing.GetManagedFields()) {
+		return nil
+	}
+
+	err := writer.Patch(ctx, existing, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/szeber/kube-stager/internal/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	c := testutil.NewFakeClient()
+	scheme := testutil.NewTestScheme()
+
+	newConfigMap := func(value string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "test-ns"},
+			Data:       map[string]string{"key": value},
+		}
+	}
+
+	t.Run("creates the object", func(t *testing.T) {
+		configMap := newConfigMap("first")
+		if err := Apply(ctx, c, c, scheme, configMap); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configMap.ResourceVersion == "" {
+			t.Error("expected the object to be updated from the server response")
+		}
+	})
+
+	t.Run("keeps the fields of other managers", func(t *testing.T) {
+		other := &unstructured.Unstructured{}
+		other.SetAPIVersion("v1")
+		other.SetKind("ConfigMap")
+		other.SetNamespace("test-ns")
+		other.SetName("config")
+		other.SetAnnotations(map[string]string{"other": "value"})
+		if err := c.Apply(
+			ctx,
+			client.ApplyConfigurationFromUnstructured(other),
+			client.FieldOwner("other-controller"),
+		); err != nil {
+			t.Fatalf("failed to apply with the other manager: %v", err)
+		}
+
+		if err := Apply(ctx, c, c, scheme, newConfigMap("second")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: "test-ns", Name: "config"}, configMap); err != nil {
+			t.Fatalf("failed to get the configmap: %v", err)
+		}
+		if configMap.Data["key"] != "second" {
+			t.Errorf("data = %q, want %q", configMap.Data["key"], "second")
+		}
+		if configMap.Annotations["other"] != "value" {
+			t.Errorf("expected the annotation of the other manager to be kept, got %v", configMap.Annotations)
+		}
+	})
+	t.Run("removes the fields of the previous field manager", func(t *testing.T) {
+		// The configmap was created by an earlier version of the operator with an update
+		legacy := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "legacy",
+				Namespace: "test-ns",
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{
+						Manager:    "manager",
+						Operation:  metav1.ManagedFieldsOperationUpdate,
+						APIVersion: "v1",
+						FieldsType: "FieldsV1",
+						FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:data":{".":{},"f:key":{},"f:removed":{}}}`)},
+					},
+				},
+			},
+			Data: map[string]string{"key": "first", "removed": "value"},
+		}
+		legacyClient := fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(legacy).
+			WithReturnManagedFields().
+			Build()
+
+		applied := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "test-ns"},
+			Data:       map[string]string{"key": "second"},
+		}
+		if err := Apply(ctx, legacyClient, legacyClient, scheme, applied); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		configMap := &corev1.ConfigMap{}
+		if err := legacyClient.Get(ctx, client.ObjectKey{Namespace: "test-ns", Name: "legacy"}, configMap); err != nil {
+			t.Fatalf("failed to get the configmap: %v", err)
+		}
+		if configMap.Data["key"] != "second" {
+			t.Errorf("data = %q, want %q", configMap.Data["key"], "second")
+		}
+		if _, ok := configMap.Data["removed"]; ok {
+			t.Errorf("expected the field set by the previous field manager to be removed, got %v", configMap.Data)
+		}
+		for _, entry := range configMap.ManagedFields {
+			if entry.Manager == "manager" {
+				t.Errorf("expected the entry of the previous field manager to be removed, got %v", configMap.ManagedFields)
+			}
+		}
+	})
+}
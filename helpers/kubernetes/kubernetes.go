@@ -110,3 +110,29 @@ func GetRedisEnvironmentsInNamespace(
 
 	return result, nil
 }
+
+func GetObjectStorageEnvironmentsInNamespace(
+	namespace string,
+	kubeClient client.Reader,
+	ctx context.Context,
+) (map[string]configv1.ObjectStorageConfig, error) {
+	result := make(map[string]configv1.ObjectStorageConfig)
+	var list configv1.ObjectStorageConfigList
+
+	for ok := true; ok; ok = (list.RemainingItemCount != nil && *list.RemainingItemCount > int64(0)) {
+		listOptions := []client.ListOption{
+			client.InNamespace(namespace),
+		}
+		if list.Continue != "" {
+			listOptions = append(listOptions, client.Continue(list.Continue))
+		}
+		if err := kubeClient.List(ctx, &list, listOptions...); err != nil {
+			return result, err
+		}
+		for _, config := range list.Items {
+			result[config.Name] = config
+		}
+	}
+
+	return result, nil
+}
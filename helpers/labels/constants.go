@@ -1,16 +1,28 @@
 package labels
 
 const (
-	Site             = "operator.kube-stager.io/site"
-	Service          = "operator.kube-stager.io/service"
-	MysqlEnvironment = "operator.kube-stager.io/mysql-environment"
-	MongoEnvironment = "operator.kube-stager.io/mongo-environment"
-	RedisEnvironment = "operator.kube-stager.io/redis-environment"
-	Type             = "operator.kube-stager.io/type"
-	JobName          = "operator.kube-stager.io/job-name"
+	Site                     = "operator.kube-stager.io/site"
+	Service                  = "operator.kube-stager.io/service"
+	MysqlEnvironment         = "operator.kube-stager.io/mysql-environment"
+	MongoEnvironment         = "operator.kube-stager.io/mongo-environment"
+	RedisEnvironment         = "operator.kube-stager.io/redis-environment"
+	ObjectStorageEnvironment = "operator.kube-stager.io/object-storage-environment"
+	Type                     = "operator.kube-stager.io/type"
+	JobName                  = "operator.kube-stager.io/job-name"
+	DatabaseMove             = "operator.kube-stager.io/database-move"
+	Provisioner              = "operator.kube-stager.io/provisioner"
+	HookPhase                = "operator.kube-stager.io/hook-phase"
+	CronJob                  = "operator.kube-stager.io/cron-job"
+	Workload                 = "operator.kube-stager.io/workload"
+	HelmRelease              = "operator.kube-stager.io/helm-release"
 
 	MongoEnvironmentsPrefix = "mongo.environments.operator.kube-stager.io/"
 	MysqlEnvironmentsPrefix = "mysql.environments.operator.kube-stager.io/"
 	RedisEnvironmentsPrefix = "redis.environments.operator.kube-stager.io/"
 	ServicesPrefix          = "services.operator.kube-stager.io/"
 )
+
+// MakeEnvironmentsPrefix returns the prefix of the site labels listing the environments of a database provider
+func MakeEnvironmentsPrefix(provider string) string {
+	return provider + ".environments.operator.kube-stager.io/"
+}
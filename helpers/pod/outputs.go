@@ -0,0 +1,66 @@
+package pod
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var outputKeyPattern = regexp.MustCompile(`^[-_a-zA-Z0-9.]+$`)
+
+// GetOutputsFromPod returns the outputs written by the containers of a finished pod to their termination messages
+func GetOutputsFromPod(pod corev1.Pod) (map[string]string, error) {
+	result := make(map[string]string)
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil || status.State.Terminated.Message == "" {
+			continue
+		}
+		outputs, err := ParseOutputs(status.State.Terminated.Message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid termination message in container %s: %w", status.Name, err)
+		}
+		for k, v := range outputs {
+			result[k] = v
+		}
+	}
+
+	return result, nil
+}
+
+// ParseOutputs parses a termination message holding either a JSON object with string values, or key=value lines. Empty
+// lines and lines starting with # are ignored in the latter. The keys may only contain characters allowed in template
+// variable names.
+func ParseOutputs(message string) (map[string]string, error) {
+	result := make(map[string]string)
+	message = strings.TrimSpace(message)
+
+	if strings.HasPrefix(message, "{") {
+		if err := json.Unmarshal([]byte(message), &result); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, line := range strings.Split(message, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				return nil, fmt.Errorf("line %q is not in the key=value format", line)
+			}
+			result[strings.TrimSpace(key)] = value
+		}
+	}
+
+	for key := range result {
+		if !outputKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid output key %q", key)
+		}
+	}
+
+	return result, nil
+}
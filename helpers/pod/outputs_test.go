@@ -0,0 +1,84 @@
+package pod
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseOutputs(t *testing.T) {
+	t.Run("parses a JSON object", func(t *testing.T) {
+		got, err := ParseOutputs(`{"bucket": "site1-assets", "endpoint": "http://minio:9000"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]string{"bucket": "site1-assets", "endpoint": "http://minio:9000"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseOutputs() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("parses key=value lines", func(t *testing.T) {
+		got, err := ParseOutputs("# outputs\nbucket=site1-assets\n\nurl=http://minio:9000/?a=b\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]string{"bucket": "site1-assets", "url": "http://minio:9000/?a=b"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseOutputs() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("rejects lines without a value", func(t *testing.T) {
+		if _, err := ParseOutputs("bucket"); err == nil {
+			t.Error("expected an error for a line without =")
+		}
+	})
+
+	t.Run("rejects non-string JSON values", func(t *testing.T) {
+		if _, err := ParseOutputs(`{"port": 9000}`); err == nil {
+			t.Error("expected an error for a numeric value")
+		}
+	})
+
+	t.Run("rejects keys that can't be used as template variables", func(t *testing.T) {
+		if _, err := ParseOutputs("my key=value"); err == nil {
+			t.Error("expected an error for a key with a space")
+		}
+	})
+}
+
+func TestGetOutputsFromPod(t *testing.T) {
+	pod := corev1.Pod{
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "provision",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{Message: "bucket=site1-assets"},
+					},
+				},
+				{
+					Name: "sidecar",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{},
+					},
+				},
+				{
+					Name:  "running",
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				},
+			},
+		},
+	}
+
+	got, err := GetOutputsFromPod(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"bucket": "site1-assets"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetOutputsFromPod() = %v, want %v", got, want)
+	}
+}
@@ -40,13 +40,18 @@ func UpdatePodSpecWithOverrides(
 	site *sitev1.StagingSite,
 	serviceConfig *configv1.ServiceConfig,
 ) corev1.PodSpec {
-	service := site.Spec.Services[serviceConfig.Name]
-	if len(service.ResourceOverrides) == 0 {
+	return ApplyResourceOverrides(spec, site.Spec.Services[serviceConfig.Name].ResourceOverrides)
+}
+
+// ApplyResourceOverrides replaces the resource requirements of the containers that have an override, keyed by the
+// container name
+func ApplyResourceOverrides(spec corev1.PodSpec, overrides map[string]corev1.ResourceRequirements) corev1.PodSpec {
+	if len(overrides) == 0 {
 		return spec
 	}
 
 	for index, container := range spec.Containers {
-		if override, ok := service.ResourceOverrides[container.Name]; ok {
+		if override, ok := overrides[container.Name]; ok {
 			spec.Containers[index].Resources = override
 		}
 	}
@@ -3,8 +3,11 @@ package helpers
 import (
 	"fmt"
 	"github.com/szeber/kube-stager/helpers/errors"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"regexp"
 	"sigs.k8s.io/yaml"
 	"strings"
@@ -167,3 +170,89 @@ func ReplaceTemplateVariablesInIngressSpec(
 
 	return ingress.Spec, nil
 }
+
+func ReplaceTemplateVariablesInHorizontalPodAutoscalerSpec(
+	spec autoscalingv2.HorizontalPodAutoscalerSpec,
+	templates ...TemplateValueGetter,
+) (autoscalingv2.HorizontalPodAutoscalerSpec, error) {
+	autoscaler := autoscalingv2.HorizontalPodAutoscaler{Spec: spec}
+
+	data, err := yaml.Marshal(autoscaler)
+	if err != nil {
+		return spec, err
+	}
+
+	replacedMarshalledSpec := ReplaceTemplateVariablesInString(string(data), templates...)
+	unresolvedTemplates := GetUnresolvedTemplatesFromString(replacedMarshalledSpec)
+
+	if len(unresolvedTemplates) > 0 {
+		return spec, errors.UnresolvedTemplatesError{
+			UnresolvedTemplateVariables: unresolvedTemplates,
+			EntityType:                  "autoscaler spec",
+			AvailableTemplateVariables:  GetTemplateVariables(templates...),
+		}
+	}
+
+	err = yaml.Unmarshal([]byte(replacedMarshalledSpec), &autoscaler)
+	if err != nil {
+		return spec, err
+	}
+
+	return autoscaler.Spec, nil
+}
+
+// ReplaceTemplateVariablesInRawObject replaces the template variables in a raw kubernetes manifest, and returns the
+// resulting object
+func ReplaceTemplateVariablesInRawObject(
+	object runtime.RawExtension,
+	templates ...TemplateValueGetter,
+) (*unstructured.Unstructured, error) {
+	data, err := yaml.JSONToYAML(object.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	replacedMarshalledObject := ReplaceTemplateVariablesInString(string(data), templates...)
+	unresolvedTemplates := GetUnresolvedTemplatesFromString(replacedMarshalledObject)
+
+	if len(unresolvedTemplates) > 0 {
+		return nil, errors.UnresolvedTemplatesError{
+			UnresolvedTemplateVariables: unresolvedTemplates,
+			EntityType:                  "extra object",
+			AvailableTemplateVariables:  GetTemplateVariables(templates...),
+		}
+	}
+
+	result := &unstructured.Unstructured{}
+	err = yaml.Unmarshal([]byte(replacedMarshalledObject), &result.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ReplaceTemplateVariablesInHelmValues replaces the template variables in the YAML values of a helm chart, and returns
+// the resulting values
+func ReplaceTemplateVariablesInHelmValues(
+	values string,
+	templates ...TemplateValueGetter,
+) (map[string]interface{}, error) {
+	replacedValues := ReplaceTemplateVariablesInString(values, templates...)
+	unresolvedTemplates := GetUnresolvedTemplatesFromString(replacedValues)
+
+	if len(unresolvedTemplates) > 0 {
+		return nil, errors.UnresolvedTemplatesError{
+			UnresolvedTemplateVariables: unresolvedTemplates,
+			EntityType:                  "helm values",
+			AvailableTemplateVariables:  GetTemplateVariables(templates...),
+		}
+	}
+
+	result := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(replacedValues), &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
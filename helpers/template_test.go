@@ -5,8 +5,11 @@ import (
 	"testing"
 
 	"github.com/szeber/kube-stager/helpers/errors"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestReplaceTemplateVariablesInString(t *testing.T) {
@@ -237,6 +240,118 @@ func TestReplaceTemplateVariablesInIngressSpec(t *testing.T) {
 	})
 }
 
+func TestReplaceTemplateVariablesInHorizontalPodAutoscalerSpec(t *testing.T) {
+	getter := StringMapTemplateValueGetter{StringMap: map[string]string{
+		"site.name": "mysite",
+	}}
+
+	newSpec := func(siteLabel string) autoscalingv2.HorizontalPodAutoscalerSpec {
+		return autoscalingv2.HorizontalPodAutoscalerSpec{
+			MaxReplicas: 3,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ExternalMetricSourceType,
+				External: &autoscalingv2.ExternalMetricSource{
+					Metric: autoscalingv2.MetricIdentifier{
+						Name:     "queue_length",
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"site": siteLabel}},
+					},
+				},
+			}},
+		}
+	}
+
+	t.Run("replaces in metrics", func(t *testing.T) {
+		got, err := ReplaceTemplateVariablesInHorizontalPodAutoscalerSpec(newSpec("${site.name}"), getter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if site := got.Metrics[0].External.Metric.Selector.MatchLabels["site"]; site != "mysite" {
+			t.Errorf("selector = %q, want %q", site, "mysite")
+		}
+	})
+
+	t.Run("error on unresolved", func(t *testing.T) {
+		_, err := ReplaceTemplateVariablesInHorizontalPodAutoscalerSpec(newSpec("${unknown.var}"), getter)
+		if err == nil {
+			t.Fatal("expected error for unresolved template")
+		}
+		if !strings.Contains(err.Error(), "autoscaler spec") {
+			t.Errorf("error should mention autoscaler spec: %v", err)
+		}
+	})
+}
+
+func TestReplaceTemplateVariablesInRawObject(t *testing.T) {
+	getter := StringMapTemplateValueGetter{StringMap: map[string]string{
+		"site.name": "mysite",
+	}}
+
+	t.Run("replaces in manifest", func(t *testing.T) {
+		object := runtime.RawExtension{Raw: []byte(
+			`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"${site.name}-worker"}}`,
+		)}
+		got, err := ReplaceTemplateVariablesInRawObject(object, getter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.GetName() != "mysite-worker" {
+			t.Errorf("name = %q, want %q", got.GetName(), "mysite-worker")
+		}
+		if got.GetKind() != "ServiceAccount" || got.GetAPIVersion() != "v1" {
+			t.Errorf("unexpected type meta: %s %s", got.GetAPIVersion(), got.GetKind())
+		}
+	})
+
+	t.Run("error on unresolved", func(t *testing.T) {
+		object := runtime.RawExtension{Raw: []byte(
+			`{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"${unknown.var}"}}`,
+		)}
+		_, err := ReplaceTemplateVariablesInRawObject(object, getter)
+		if err == nil {
+			t.Fatal("expected error for unresolved template")
+		}
+		if !strings.Contains(err.Error(), "extra object") {
+			t.Errorf("error should mention extra object: %v", err)
+		}
+	})
+}
+
+func TestReplaceTemplateVariablesInHelmValues(t *testing.T) {
+	getter := StringMapTemplateValueGetter{StringMap: map[string]string{
+		"site.name": "mysite",
+	}}
+
+	t.Run("replaces in values", func(t *testing.T) {
+		got, err := ReplaceTemplateVariablesInHelmValues("ingress:\n  host: ${site.name}.example.com\nreplicas: 2\n", getter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ingress, ok := got["ingress"].(map[string]interface{})
+		if !ok || ingress["host"] != "mysite.example.com" {
+			t.Errorf("ingress = %v, want the host to be mysite.example.com", got["ingress"])
+		}
+		if got["replicas"] != float64(2) {
+			t.Errorf("replicas = %v, want 2", got["replicas"])
+		}
+	})
+
+	t.Run("error on unresolved", func(t *testing.T) {
+		_, err := ReplaceTemplateVariablesInHelmValues("host: ${unknown.var}", getter)
+		if err == nil {
+			t.Fatal("expected error for unresolved template")
+		}
+		if !strings.Contains(err.Error(), "helm values") {
+			t.Errorf("error should mention helm values: %v", err)
+		}
+	})
+
+	t.Run("error on invalid yaml", func(t *testing.T) {
+		if _, err := ReplaceTemplateVariablesInHelmValues("- ${site.name}", getter); err == nil {
+			t.Fatal("expected error for values that are not a map")
+		}
+	})
+}
+
 func TestStringMapTemplateValueGetter_GetTemplateValues(t *testing.T) {
 	m := map[string]string{"a": "1", "b": "2"}
 	getter := StringMapTemplateValueGetter{StringMap: m}
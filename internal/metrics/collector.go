@@ -9,6 +9,7 @@ import (
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -36,12 +37,17 @@ var (
 // ResourceCollector implements prometheus.Collector to provide resource inventory
 // gauges by reading from the informer cache on each scrape.
 type ResourceCollector struct {
-	reader client.Reader
+	reader               client.Reader
+	newDatabaseTaskLists func() map[string]client.ObjectList
 }
 
-// NewResourceCollector creates a new ResourceCollector.
-func NewResourceCollector(reader client.Reader) *ResourceCollector {
-	return &ResourceCollector{reader: reader}
+// NewResourceCollector creates a new ResourceCollector. The newDatabaseTaskLists function returns an empty list of the
+// database tasks of every database provider, keyed by the provider name.
+func NewResourceCollector(
+	reader client.Reader,
+	newDatabaseTaskLists func() map[string]client.ObjectList,
+) *ResourceCollector {
+	return &ResourceCollector{reader: reader, newDatabaseTaskLists: newDatabaseTaskLists}
 }
 
 func (c *ResourceCollector) Describe(ch chan<- *prometheus.Desc) {
@@ -101,43 +107,27 @@ func (c *ResourceCollector) collectDatabases(ctx context.Context, ch chan<- prom
 
 	counts := map[dbEntry]float64{}
 
-	var mysqlList taskv1.MysqlDatabaseList
-	if err := c.reader.List(ctx, &mysqlList); err != nil {
-		ch <- prometheus.NewInvalidMetric(databasesDesc, err)
-		return
-	}
-	for _, db := range mysqlList.Items {
-		state := string(db.Status.State)
-		if state == "" {
-			state = string(taskv1.Pending)
+	for dbType, list := range c.newDatabaseTaskLists() {
+		if err := c.reader.List(ctx, list); err != nil {
+			ch <- prometheus.NewInvalidMetric(databasesDesc, err)
+			return
 		}
-		counts[dbEntry{db.Namespace, "mysql", state}]++
-	}
-
-	var mongoList taskv1.MongoDatabaseList
-	if err := c.reader.List(ctx, &mongoList); err != nil {
-		ch <- prometheus.NewInvalidMetric(databasesDesc, err)
-		return
-	}
-	for _, db := range mongoList.Items {
-		state := string(db.Status.State)
-		if state == "" {
-			state = string(taskv1.Pending)
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(databasesDesc, err)
+			return
 		}
-		counts[dbEntry{db.Namespace, "mongo", state}]++
-	}
-
-	var redisList taskv1.RedisDatabaseList
-	if err := c.reader.List(ctx, &redisList); err != nil {
-		ch <- prometheus.NewInvalidMetric(databasesDesc, err)
-		return
-	}
-	for _, db := range redisList.Items {
-		state := string(db.Status.State)
-		if state == "" {
-			state = string(taskv1.Pending)
+		for _, item := range items {
+			db, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			state := string(taskv1.Pending)
+			if task, ok := item.(taskv1.StatefulTask); ok && task.GetTaskState() != "" {
+				state = string(task.GetTaskState())
+			}
+			counts[dbEntry{db.GetNamespace(), dbType, state}]++
 		}
-		counts[dbEntry{db.Namespace, "redis", state}]++
 	}
 
 	for entry, count := range counts {
@@ -185,6 +175,19 @@ func (c *ResourceCollector) collectJobs(ctx context.Context, ch chan<- prometheu
 		counts[jobEntry{j.Namespace, "dbmigration", state}]++
 	}
 
+	var hookList jobv1.HookJobList
+	if err := c.reader.List(ctx, &hookList); err != nil {
+		ch <- prometheus.NewInvalidMetric(jobsDesc, err)
+		return
+	}
+	for _, j := range hookList.Items {
+		state := string(j.Status.State)
+		if state == "" {
+			state = string(jobv1.Pending)
+		}
+		counts[jobEntry{j.Namespace, "hook", state}]++
+	}
+
 	var backupList jobv1.BackupList
 	if err := c.reader.List(ctx, &backupList); err != nil {
 		ch <- prometheus.NewInvalidMetric(jobsDesc, err)
@@ -15,6 +15,7 @@ import (
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	"github.com/szeber/kube-stager/handlers/provider"
 	"github.com/szeber/kube-stager/internal/metrics"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -88,7 +89,7 @@ func getLabelValue(metric *dto.Metric, name string) string {
 }
 
 func TestCollector_Describe(t *testing.T) {
-	collector := metrics.NewResourceCollector(newFakeClient())
+	collector := metrics.NewResourceCollector(newFakeClient(), provider.NewTaskLists)
 	ch := make(chan *prometheus.Desc, 10)
 	go func() {
 		collector.Describe(ch)
@@ -106,7 +107,7 @@ func TestCollector_Describe(t *testing.T) {
 }
 
 func TestCollector_EmptyCluster(t *testing.T) {
-	collector := metrics.NewResourceCollector(newFakeClient())
+	collector := metrics.NewResourceCollector(newFakeClient(), provider.NewTaskLists)
 	m := collectMetrics(t, collector)
 
 	if len(m["kube_stager_staging_sites"]) != 0 {
@@ -139,7 +140,7 @@ func TestCollector_StagingSites(t *testing.T) {
 	}
 
 	fakeClient := newFakeClient(site1, site2, site3, site4)
-	collector := metrics.NewResourceCollector(fakeClient)
+	collector := metrics.NewResourceCollector(fakeClient, provider.NewTaskLists)
 	m := collectMetrics(t, collector)
 
 	siteMetrics := m["kube_stager_staging_sites"]
@@ -167,15 +168,15 @@ func TestCollector_StagingSites(t *testing.T) {
 func TestCollector_Databases(t *testing.T) {
 	mysql1 := &taskv1.MysqlDatabase{
 		ObjectMeta: metav1.ObjectMeta{Name: "mysql1", Namespace: "default"},
-		Status:     taskv1.TaskStatus{State: taskv1.Complete},
+		Status:     taskv1.MysqlDatabaseStatus{TaskStatus: taskv1.TaskStatus{State: taskv1.Complete}},
 	}
 	mysql2 := &taskv1.MysqlDatabase{
 		ObjectMeta: metav1.ObjectMeta{Name: "mysql2", Namespace: "default"},
-		Status:     taskv1.TaskStatus{State: taskv1.Complete},
+		Status:     taskv1.MysqlDatabaseStatus{TaskStatus: taskv1.TaskStatus{State: taskv1.Complete}},
 	}
 	mongo1 := &taskv1.MongoDatabase{
 		ObjectMeta: metav1.ObjectMeta{Name: "mongo1", Namespace: "default"},
-		Status:     taskv1.TaskStatus{State: taskv1.Pending},
+		Status:     taskv1.MongoDatabaseStatus{TaskStatus: taskv1.TaskStatus{State: taskv1.Pending}},
 	}
 	redis1 := &taskv1.RedisDatabase{
 		ObjectMeta: metav1.ObjectMeta{Name: "redis1", Namespace: "default"},
@@ -183,7 +184,7 @@ func TestCollector_Databases(t *testing.T) {
 	}
 
 	fakeClient := newFakeClient(mysql1, mysql2, mongo1, redis1)
-	collector := metrics.NewResourceCollector(fakeClient)
+	collector := metrics.NewResourceCollector(fakeClient, provider.NewTaskLists)
 	m := collectMetrics(t, collector)
 
 	dbMetrics := m["kube_stager_databases"]
@@ -215,7 +216,7 @@ func TestCollector_Jobs(t *testing.T) {
 	}
 	migrationJob := &jobv1.DbMigrationJob{
 		ObjectMeta: metav1.ObjectMeta{Name: "migration1", Namespace: "default"},
-		Status:     jobv1.DbMigrationJobStatus{State: jobv1.Complete},
+		Status:     jobv1.DbMigrationJobStatus{BatchJobStatus: jobv1.BatchJobStatus{State: jobv1.Complete}},
 	}
 	backup1 := &jobv1.Backup{
 		ObjectMeta: metav1.ObjectMeta{Name: "backup1", Namespace: "default"},
@@ -229,7 +230,7 @@ func TestCollector_Jobs(t *testing.T) {
 	}
 
 	fakeClient := newFakeClient(initJob, migrationJob, backup1, backup2)
-	collector := metrics.NewResourceCollector(fakeClient)
+	collector := metrics.NewResourceCollector(fakeClient, provider.NewTaskLists)
 	m := collectMetrics(t, collector)
 
 	jobMetrics := m["kube_stager_jobs"]
@@ -260,7 +261,7 @@ func TestCollector_EmptyStateTreatedAsPending(t *testing.T) {
 	}
 
 	fakeClient := newFakeClient(site)
-	collector := metrics.NewResourceCollector(fakeClient)
+	collector := metrics.NewResourceCollector(fakeClient, provider.NewTaskLists)
 	m := collectMetrics(t, collector)
 
 	siteMetrics := m["kube_stager_staging_sites"]
@@ -286,7 +287,7 @@ func TestCollector_MultipleNamespaces(t *testing.T) {
 	}
 
 	fakeClient := newFakeClient(objs...)
-	collector := metrics.NewResourceCollector(fakeClient)
+	collector := metrics.NewResourceCollector(fakeClient, provider.NewTaskLists)
 	m := collectMetrics(t, collector)
 
 	siteMetrics := m["kube_stager_staging_sites"]
@@ -314,7 +315,7 @@ func (f failingReader) List(_ context.Context, _ client.ObjectList, _ ...client.
 }
 
 func TestCollector_ListError_EmitsInvalidMetrics(t *testing.T) {
-	collector := metrics.NewResourceCollector(failingReader{})
+	collector := metrics.NewResourceCollector(failingReader{}, provider.NewTaskLists)
 
 	ch := make(chan prometheus.Metric, 100)
 	go func() {
@@ -98,3 +98,45 @@ var WebhookDenied = factory.NewCounterVec(prometheus.CounterOpts{
 	Name:      "webhook_denied_total",
 	Help:      "Total admission requests denied by webhook validation logic.",
 }, []string{"webhook", "reason"})
+
+// OrphanedDatabaseResources tracks the databases and users found by the orphan sweeper on an environment server.
+var OrphanedDatabaseResources = factory.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "orphaned_database_resources",
+	Help:      "Number of orphaned databases and users found on an environment server by the last sweep.",
+}, []string{"namespace", "environment", "type", "resource"})
+
+// OrphanedDatabaseResourcesDropped counts the orphaned databases and users dropped by the orphan sweeper.
+var OrphanedDatabaseResourcesDropped = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "orphaned_database_resources_dropped_total",
+	Help:      "Total orphaned databases and users dropped by the orphan sweeper.",
+}, []string{"namespace", "environment", "type", "resource"})
+
+// EnvironmentUp reports whether the last probe of an environment server succeeded.
+var EnvironmentUp = factory.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "environment_up",
+	Help:      "Whether the last probe of the environment server succeeded (1) or failed (0).",
+}, []string{"namespace", "environment", "type"})
+
+// EnvironmentProvisionedDatabases tracks the number of site databases provisioned in an environment.
+var EnvironmentProvisionedDatabases = factory.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "environment_provisioned_databases",
+	Help:      "Number of site databases provisioned in the environment.",
+}, []string{"namespace", "environment", "type"})
+
+// EnvironmentFreeDatabases tracks the number of databases that can still be reserved in an environment.
+var EnvironmentFreeDatabases = factory.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "environment_free_databases",
+	Help:      "Number of databases that can still be reserved in the environment. Only reported for redis.",
+}, []string{"namespace", "environment", "type"})
+
+// DatabaseConnectionPools tracks the number of open admin connection pools to environment servers.
+var DatabaseConnectionPools = factory.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "database_connection_pools",
+	Help:      "Number of open admin connection pools to environment servers.",
+}, []string{"type"})
@@ -0,0 +1,47 @@
+package testutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"sort"
+)
+
+// NewTestChartArchive returns a packaged helm chart named app with the given values and templates, keyed by their path
+// within the templates directory
+func NewTestChartArchive(values string, templates map[string]string) []byte {
+	files := map[string]string{
+		"app/Chart.yaml":  "apiVersion: v2\nname: app\nversion: 1.0.0\n",
+		"app/values.yaml": values,
+	}
+	for name, content := range templates {
+		files["app/templates/"+name] = content
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, name := range names {
+		content := []byte(files[name])
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			panic(err)
+		}
+		if _, err := tarWriter.Write(content); err != nil {
+			panic(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		panic(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		panic(err)
+	}
+
+	return buffer.Bytes()
+}
@@ -1,6 +1,7 @@
 package testutil
 
 import (
+	configv1 "github.com/szeber/kube-stager/apis/config/v1"
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
@@ -15,13 +16,20 @@ func NewFakeClient(initObjs ...client.Object) client.Client {
 		WithScheme(NewTestScheme()).
 		WithObjects(initObjs...).
 		WithStatusSubresource(
+			&configv1.MysqlConfig{},
+			&configv1.MongoConfig{},
+			&configv1.RedisConfig{},
 			&sitev1.StagingSite{},
 			&taskv1.MysqlDatabase{},
 			&taskv1.MongoDatabase{},
 			&taskv1.RedisDatabase{},
+			&taskv1.ProvisionerTask{},
+			&taskv1.ObjectStorageBucket{},
 			&jobv1.DbInitJob{},
 			&jobv1.DbMigrationJob{},
+			&jobv1.HookJob{},
 			&jobv1.Backup{},
+			&jobv1.DatabaseMove{},
 		).
 		Build()
 }
@@ -41,7 +41,7 @@ func NewTestServiceConfig(name, namespace, shortName string) *configv1.ServiceCo
 		},
 		Spec: configv1.ServiceConfigSpec{
 			ShortName: shortName,
-			DeploymentPodSpec: corev1.PodSpec{
+			DeploymentPodSpec: &corev1.PodSpec{
 				Containers: []corev1.Container{
 					{
 						Name:  "nginx",
@@ -135,6 +135,42 @@ func NewTestRedisConfig(name, namespace string) *configv1.RedisConfig {
 	}
 }
 
+func NewTestProvisionerConfig(name, namespace string) *configv1.ProvisionerConfig {
+	return &configv1.ProvisionerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: configv1.ProvisionerConfigSpec{
+			ProvisionPodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "provision",
+						Image: "provisioner:latest",
+						Args:  []string{"create", "${site.name}"},
+					},
+				},
+				RestartPolicy: corev1.RestartPolicyNever,
+			},
+		},
+	}
+}
+
+func NewTestObjectStorageConfig(name, namespace string) *configv1.ObjectStorageConfig {
+	return &configv1.ObjectStorageConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: configv1.ObjectStorageConfigSpec{
+			Endpoint:        "s3.example.com:9000",
+			Region:          "us-east-1",
+			AccessKeySecret: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "accessKey"},
+			SecretKeySecret: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "secretKey"},
+		},
+	}
+}
+
 func NewTestMysqlDatabase(name, namespace, siteName, serviceName, environment string) *taskv1.MysqlDatabase {
 	return &taskv1.MysqlDatabase{
 		ObjectMeta: metav1.ObjectMeta{
@@ -190,6 +226,38 @@ func NewTestRedisDatabase(name, namespace, siteName, serviceName, environment st
 	}
 }
 
+func NewTestObjectStorageBucket(name, namespace, siteName, serviceName, environment string) *taskv1.ObjectStorageBucket {
+	return &taskv1.ObjectStorageBucket{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: taskv1.ObjectStorageBucketSpec{
+			EnvironmentConfig: taskv1.EnvironmentConfig{
+				ServiceName: serviceName,
+				SiteName:    siteName,
+				Environment: environment,
+			},
+			BucketName: name,
+		},
+	}
+}
+
+func NewTestProvisionerTask(name, namespace, siteName, serviceName, provisioner string) *taskv1.ProvisionerTask {
+	return &taskv1.ProvisionerTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: taskv1.ProvisionerTaskSpec{
+			SiteName:        siteName,
+			ServiceName:     serviceName,
+			Provisioner:     provisioner,
+			DeadlineSeconds: 600,
+		},
+	}
+}
+
 func NewTestDbInitJob(name, namespace, siteName, serviceName string) *jobv1.DbInitJob {
 	return &jobv1.DbInitJob{
 		ObjectMeta: metav1.ObjectMeta{
@@ -222,6 +290,23 @@ func NewTestDbMigrationJob(name, namespace, siteName, serviceName, imageTag stri
 	}
 }
 
+func NewTestHookJob(name, namespace, siteName, serviceName, hookName string, phase configv1.HookPhase) *jobv1.HookJob {
+	return &jobv1.HookJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: jobv1.HookJobSpec{
+			SiteName:        siteName,
+			ServiceName:     serviceName,
+			HookName:        hookName,
+			Phase:           phase,
+			ImageTag:        "latest",
+			DeadlineSeconds: 600,
+		},
+	}
+}
+
 func NewTestBackup(name, namespace, siteName string, backupType jobv1.BackupType) *jobv1.Backup {
 	return &jobv1.Backup{
 		ObjectMeta: metav1.ObjectMeta{
@@ -234,3 +319,19 @@ func NewTestBackup(name, namespace, siteName string, backupType jobv1.BackupType
 		},
 	}
 }
+
+func NewTestDatabaseMove(name, namespace, siteName, serviceName, targetEnvironment string) *jobv1.DatabaseMove {
+	return &jobv1.DatabaseMove{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: jobv1.DatabaseMoveSpec{
+			SiteName:          siteName,
+			ServiceName:       serviceName,
+			DatabaseType:      jobv1.DatabaseMoveTypeMysql,
+			TargetEnvironment: targetEnvironment,
+			DeadlineSeconds:   3600,
+		},
+	}
+}
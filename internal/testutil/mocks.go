@@ -1,6 +1,7 @@
 package testutil
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -13,6 +14,10 @@ import (
 var _ database.MysqlReconciler = (*MockMysqlReconciler)(nil)
 var _ database.MongoReconciler = (*MockMongoReconciler)(nil)
 var _ database.RedisReconciler = (*MockRedisReconciler)(nil)
+var _ database.ObjectStorageReconciler = (*MockObjectStorageReconciler)(nil)
+var _ database.MysqlEnvironmentHandler = (*MockMysqlEnvironmentHandler)(nil)
+var _ database.MongoEnvironmentHandler = (*MockMongoEnvironmentHandler)(nil)
+var _ database.RedisEnvironmentHandler = (*MockRedisEnvironmentHandler)(nil)
 
 type MockClock struct {
 	mu  sync.Mutex
@@ -33,37 +38,37 @@ func (m *MockClock) SetNow(t time.Time) {
 
 type MockMysqlReconciler struct {
 	mu            sync.RWMutex
-	reconcileFunc func(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error)
-	deleteFunc    func(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error
+	reconcileFunc func(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error)
+	deleteFunc    func(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error
 }
 
-func (m *MockMysqlReconciler) Reconcile(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error) {
+func (m *MockMysqlReconciler) Reconcile(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error) {
 	m.mu.RLock()
 	f := m.reconcileFunc
 	m.mu.RUnlock()
 	if f != nil {
-		return f(database, config, logger)
+		return f(ctx, database, config, logger)
 	}
 	return false, nil
 }
 
-func (m *MockMysqlReconciler) Delete(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error {
+func (m *MockMysqlReconciler) Delete(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error {
 	m.mu.RLock()
 	f := m.deleteFunc
 	m.mu.RUnlock()
 	if f != nil {
-		return f(database, config, logger)
+		return f(ctx, database, config, logger)
 	}
 	return nil
 }
 
-func (m *MockMysqlReconciler) SetReconcileFunc(f func(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error)) {
+func (m *MockMysqlReconciler) SetReconcileFunc(f func(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) (bool, error)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.reconcileFunc = f
 }
 
-func (m *MockMysqlReconciler) SetDeleteFunc(f func(database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error) {
+func (m *MockMysqlReconciler) SetDeleteFunc(f func(ctx context.Context, database *taskv1.MysqlDatabase, config configv1.MysqlConfig, logger logr.Logger) error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.deleteFunc = f
@@ -71,37 +76,37 @@ func (m *MockMysqlReconciler) SetDeleteFunc(f func(database *taskv1.MysqlDatabas
 
 type MockMongoReconciler struct {
 	mu            sync.RWMutex
-	reconcileFunc func(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error)
-	deleteFunc    func(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error
+	reconcileFunc func(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error)
+	deleteFunc    func(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error
 }
 
-func (m *MockMongoReconciler) Reconcile(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error) {
+func (m *MockMongoReconciler) Reconcile(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error) {
 	m.mu.RLock()
 	f := m.reconcileFunc
 	m.mu.RUnlock()
 	if f != nil {
-		return f(database, config, logger)
+		return f(ctx, database, config, logger)
 	}
 	return false, nil
 }
 
-func (m *MockMongoReconciler) Delete(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error {
+func (m *MockMongoReconciler) Delete(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error {
 	m.mu.RLock()
 	f := m.deleteFunc
 	m.mu.RUnlock()
 	if f != nil {
-		return f(database, config, logger)
+		return f(ctx, database, config, logger)
 	}
 	return nil
 }
 
-func (m *MockMongoReconciler) SetReconcileFunc(f func(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error)) {
+func (m *MockMongoReconciler) SetReconcileFunc(f func(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) (bool, error)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.reconcileFunc = f
 }
 
-func (m *MockMongoReconciler) SetDeleteFunc(f func(database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error) {
+func (m *MockMongoReconciler) SetDeleteFunc(f func(ctx context.Context, database *taskv1.MongoDatabase, config configv1.MongoConfig, logger logr.Logger) error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.deleteFunc = f
@@ -109,21 +114,178 @@ func (m *MockMongoReconciler) SetDeleteFunc(f func(database *taskv1.MongoDatabas
 
 type MockRedisReconciler struct {
 	mu            sync.RWMutex
-	reconcileFunc func(database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error)
+	reconcileFunc func(ctx context.Context, database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error)
 }
 
-func (m *MockRedisReconciler) Reconcile(database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error) {
+func (m *MockRedisReconciler) Reconcile(ctx context.Context, database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error) {
 	m.mu.RLock()
 	f := m.reconcileFunc
 	m.mu.RUnlock()
 	if f != nil {
-		return f(database, config, logger)
+		return f(ctx, database, config, logger)
 	}
 	return false, nil
 }
 
-func (m *MockRedisReconciler) SetReconcileFunc(f func(database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error)) {
+func (m *MockRedisReconciler) SetReconcileFunc(f func(ctx context.Context, database *taskv1.RedisDatabase, config configv1.RedisConfig, logger logr.Logger) (bool, error)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.reconcileFunc = f
 }
+
+// MockEnvironmentServer is the server state shared by the mock environment handlers. It returns the configured
+// databases and users and records the dropped ones.
+type MockEnvironmentServer struct {
+	mu               sync.Mutex
+	ServerVersion    string
+	ProbeErr         error
+	Databases        []string
+	Users            []string
+	ListErr          error
+	DropErr          error
+	droppedDatabases []string
+	droppedUsers     []string
+}
+
+func (m *MockEnvironmentServer) probe() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ProbeErr != nil {
+		return "", m.ProbeErr
+	}
+	return m.ServerVersion, nil
+}
+
+func (m *MockEnvironmentServer) SetProbeResult(serverVersion string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ServerVersion = serverVersion
+	m.ProbeErr = err
+}
+
+func (m *MockEnvironmentServer) listServerResources() ([]string, []string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ListErr != nil {
+		return nil, nil, m.ListErr
+	}
+	return append([]string{}, m.Databases...), append([]string{}, m.Users...), nil
+}
+
+func (m *MockEnvironmentServer) dropDatabase(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DropErr != nil {
+		return m.DropErr
+	}
+	m.droppedDatabases = append(m.droppedDatabases, name)
+	return nil
+}
+
+func (m *MockEnvironmentServer) dropUser(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DropErr != nil {
+		return m.DropErr
+	}
+	m.droppedUsers = append(m.droppedUsers, name)
+	return nil
+}
+
+func (m *MockEnvironmentServer) DroppedDatabases() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.droppedDatabases...)
+}
+
+func (m *MockEnvironmentServer) DroppedUsers() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.droppedUsers...)
+}
+
+type MockMysqlEnvironmentHandler struct {
+	MockEnvironmentServer
+}
+
+func (m *MockMysqlEnvironmentHandler) Probe(ctx context.Context, config configv1.MysqlConfig, logger logr.Logger) (string, error) {
+	return m.probe()
+}
+
+func (m *MockMysqlEnvironmentHandler) ListServerResources(ctx context.Context, config configv1.MysqlConfig, logger logr.Logger) ([]string, []string, error) {
+	return m.listServerResources()
+}
+
+func (m *MockMysqlEnvironmentHandler) DropDatabase(ctx context.Context, config configv1.MysqlConfig, name string, logger logr.Logger) error {
+	return m.dropDatabase(name)
+}
+
+func (m *MockMysqlEnvironmentHandler) DropUser(ctx context.Context, config configv1.MysqlConfig, name string, logger logr.Logger) error {
+	return m.dropUser(name)
+}
+
+type MockMongoEnvironmentHandler struct {
+	MockEnvironmentServer
+}
+
+func (m *MockMongoEnvironmentHandler) Probe(ctx context.Context, config configv1.MongoConfig, logger logr.Logger) (string, error) {
+	return m.probe()
+}
+
+func (m *MockMongoEnvironmentHandler) ListServerResources(ctx context.Context, config configv1.MongoConfig, logger logr.Logger) ([]string, []string, error) {
+	return m.listServerResources()
+}
+
+func (m *MockMongoEnvironmentHandler) DropDatabase(ctx context.Context, config configv1.MongoConfig, name string, logger logr.Logger) error {
+	return m.dropDatabase(name)
+}
+
+func (m *MockMongoEnvironmentHandler) DropUser(ctx context.Context, config configv1.MongoConfig, name string, logger logr.Logger) error {
+	return m.dropUser(name)
+}
+
+type MockRedisEnvironmentHandler struct {
+	MockEnvironmentServer
+}
+
+func (m *MockRedisEnvironmentHandler) Probe(ctx context.Context, config configv1.RedisConfig, logger logr.Logger) (string, error) {
+	return m.probe()
+}
+
+type MockObjectStorageReconciler struct {
+	mu            sync.RWMutex
+	reconcileFunc func(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) (bool, error)
+	deleteFunc    func(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) error
+}
+
+func (m *MockObjectStorageReconciler) Reconcile(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) (bool, error) {
+	m.mu.RLock()
+	f := m.reconcileFunc
+	m.mu.RUnlock()
+	if f != nil {
+		return f(ctx, bucket, config, logger)
+	}
+	return false, nil
+}
+
+func (m *MockObjectStorageReconciler) Delete(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) error {
+	m.mu.RLock()
+	f := m.deleteFunc
+	m.mu.RUnlock()
+	if f != nil {
+		return f(ctx, bucket, config, logger)
+	}
+	return nil
+}
+
+func (m *MockObjectStorageReconciler) SetReconcileFunc(f func(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) (bool, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconcileFunc = f
+}
+
+func (m *MockObjectStorageReconciler) SetDeleteFunc(f func(ctx context.Context, bucket *taskv1.ObjectStorageBucket, config configv1.ObjectStorageConfig, logger logr.Logger) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteFunc = f
+}
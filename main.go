@@ -22,6 +22,7 @@ import (
 	"github.com/getsentry/sentry-go"
 	"os"
 	goruntime "runtime"
+	"strings"
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/cache"
@@ -35,13 +36,18 @@ import (
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"github.com/szeber/kube-stager/handlers/chart"
+	"github.com/szeber/kube-stager/handlers/database"
+	"github.com/szeber/kube-stager/handlers/provider"
 	webhook2 "github.com/szeber/kube-stager/handlers/webhook"
 	appmetrics "github.com/szeber/kube-stager/internal/metrics"
 
@@ -50,6 +56,7 @@ import (
 	jobv1 "github.com/szeber/kube-stager/apis/job/v1"
 	sitev1 "github.com/szeber/kube-stager/apis/site/v1"
 	taskv1 "github.com/szeber/kube-stager/apis/task/v1"
+	environmentcontrollers "github.com/szeber/kube-stager/controllers/environment"
 	jobcontrollers "github.com/szeber/kube-stager/controllers/job"
 	sitecontrollers "github.com/szeber/kube-stager/controllers/site"
 	taskcontrollers "github.com/szeber/kube-stager/controllers/task"
@@ -110,6 +117,60 @@ func validateConfig(config *controllerconfigv1.ProjectConfig) error {
 		return fmt.Errorf("invalid backupJobConfig.backoffLimit: %d (must be >= 0)", config.BackupJobConfig.BackoffLimit)
 	}
 
+	if config.ProvisionJobConfig.DeadlineSeconds < 0 {
+		return fmt.Errorf("invalid provisionJobConfig.deadlineSeconds: %d (must be >= 0)", config.ProvisionJobConfig.DeadlineSeconds)
+	}
+	if config.ProvisionJobConfig.TtlSeconds < 0 {
+		return fmt.Errorf("invalid provisionJobConfig.ttlSeconds: %d (must be >= 0)", config.ProvisionJobConfig.TtlSeconds)
+	}
+	if config.ProvisionJobConfig.BackoffLimit < 0 {
+		return fmt.Errorf("invalid provisionJobConfig.backoffLimit: %d (must be >= 0)", config.ProvisionJobConfig.BackoffLimit)
+	}
+
+	if config.HookJobConfig.TtlSeconds < 0 {
+		return fmt.Errorf("invalid hookJobConfig.ttlSeconds: %d (must be >= 0)", config.HookJobConfig.TtlSeconds)
+	}
+	if config.HookJobConfig.BackoffLimit < 0 {
+		return fmt.Errorf("invalid hookJobConfig.backoffLimit: %d (must be >= 0)", config.HookJobConfig.BackoffLimit)
+	}
+
+	if config.DatabaseConnectionPool.MaxOpenConnections < 1 {
+		return fmt.Errorf("invalid databaseConnectionPool.maxOpenConnections: %d (must be >= 1)", config.DatabaseConnectionPool.MaxOpenConnections)
+	}
+	if config.DatabaseConnectionPool.IdleTimeoutSeconds < 1 {
+		return fmt.Errorf("invalid databaseConnectionPool.idleTimeoutSeconds: %d (must be >= 1)", config.DatabaseConnectionPool.IdleTimeoutSeconds)
+	}
+
+	for i, selector := range config.NetworkIsolation.AllowedPodSelectors {
+		if _, err := metav1.LabelSelectorAsSelector(&selector); err != nil {
+			return fmt.Errorf("invalid networkIsolation.allowedPodSelectors[%d]: %w", i, err)
+		}
+	}
+
+	if config.Ingress.BaseDomain != "" {
+		if errs := validation.IsDNS1123Subdomain(config.Ingress.BaseDomain); len(errs) > 0 {
+			return fmt.Errorf("invalid ingress.baseDomain: %s", strings.Join(errs, ", "))
+		}
+	}
+	switch config.Ingress.TLS.Mode {
+	case "", controllerconfigv1.IngressTLSModeDisabled:
+	case controllerconfigv1.IngressTLSModeCertManager:
+		if config.Ingress.TLS.CertManagerIssuer == "" {
+			return fmt.Errorf("ingress.tls.certManagerIssuer is required in CertManager mode")
+		}
+		switch config.Ingress.TLS.CertManagerIssuerKind {
+		case "", controllerconfigv1.CertManagerIssuerKindClusterIssuer, controllerconfigv1.CertManagerIssuerKindIssuer:
+		default:
+			return fmt.Errorf("invalid ingress.tls.certManagerIssuerKind: %s", config.Ingress.TLS.CertManagerIssuerKind)
+		}
+	case controllerconfigv1.IngressTLSModeWildcardSecret:
+		if config.Ingress.TLS.WildcardSecretName == "" {
+			return fmt.Errorf("ingress.tls.wildcardSecretName is required in WildcardSecret mode")
+		}
+	default:
+		return fmt.Errorf("invalid ingress.tls.mode: %s", config.Ingress.TLS.Mode)
+	}
+
 	return nil
 }
 
@@ -146,6 +207,20 @@ func main() {
 			TtlSeconds:      600,
 			BackoffLimit:    3,
 		},
+		ProvisionJobConfig: controllerconfigv1.JobConfig{
+			DeadlineSeconds: 600,
+			TtlSeconds:      600,
+			BackoffLimit:    3,
+		},
+		HookJobConfig: controllerconfigv1.JobConfig{
+			DeadlineSeconds: 600,
+			TtlSeconds:      600,
+			BackoffLimit:    0,
+		},
+		DatabaseConnectionPool: controllerconfigv1.DatabaseConnectionPoolConfig{
+			MaxOpenConnections: 5,
+			IdleTimeoutSeconds: 300,
+		},
 	}
 	options := ctrl.Options{
 		Scheme:                 scheme,
@@ -218,18 +293,30 @@ func main() {
 	}
 
 	appmetrics.BuildInfo.WithLabelValues(version, goruntime.Version()).Set(1)
-	metrics.Registry.MustRegister(appmetrics.NewResourceCollector(mgr.GetClient()))
+	metrics.Registry.MustRegister(appmetrics.NewResourceCollector(mgr.GetClient(), provider.NewTaskLists))
+
+	// The admin connections to the environments are shared by the task and environment controllers
+	databaseConnections := database.NewConnectionManager(
+		int(ctrlConfig.DatabaseConnectionPool.MaxOpenConnections),
+		time.Duration(ctrlConfig.DatabaseConnectionPool.IdleTimeoutSeconds)*time.Second,
+	)
+	if err = mgr.Add(databaseConnections); err != nil {
+		setupLog.Error(err, "unable to add the database connection manager")
+		os.Exit(1)
+	}
 
 	if err = (&taskcontrollers.MysqlDatabaseReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Connections: databaseConnections,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MysqlDatabase")
 		os.Exit(1)
 	}
 	if err = (&taskcontrollers.MongoDatabaseReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Connections: databaseConnections,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MongoDatabase")
 		os.Exit(1)
@@ -241,6 +328,21 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "RedisDatabase")
 		os.Exit(1)
 	}
+	if err = (&taskcontrollers.ProvisionerTaskReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Config: ctrlConfig,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProvisionerTask")
+		os.Exit(1)
+	}
+	if err = (&taskcontrollers.ObjectStorageBucketReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ObjectStorageBucket")
+		os.Exit(1)
+	}
 	if err = (&jobcontrollers.DbInitJobReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -249,6 +351,14 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "DbInitJob")
 		os.Exit(1)
 	}
+	if err = (&jobcontrollers.DatabaseMoveReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Config: ctrlConfig,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DatabaseMove")
+		os.Exit(1)
+	}
 	if err = (&jobcontrollers.DbMigrationJobReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -257,9 +367,19 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "DbMigrationJob")
 		os.Exit(1)
 	}
+	if err = (&jobcontrollers.HookJobReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Config: ctrlConfig,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HookJob")
+		os.Exit(1)
+	}
 	if err = (&sitecontrollers.StagingSiteReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
+		Config: ctrlConfig,
+		Charts: chart.NewLoader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "StagingSite")
 		os.Exit(1)
@@ -276,6 +396,29 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Backup")
 		os.Exit(1)
 	}
+	if err = (&environmentcontrollers.MysqlConfigReconciler{
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Connections: databaseConnections,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MysqlConfig")
+		os.Exit(1)
+	}
+	if err = (&environmentcontrollers.MongoConfigReconciler{
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Connections: databaseConnections,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MongoConfig")
+		os.Exit(1)
+	}
+	if err = (&environmentcontrollers.RedisConfigReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RedisConfig")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	setupLog.Info("registering advanced webhooks to the webhook server")